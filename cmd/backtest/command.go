@@ -0,0 +1,197 @@
+package cmd_backtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"msa/pkg/logic/backtest"
+	"msa/pkg/logic/calendar"
+	"msa/pkg/logic/finsvc"
+	"msa/pkg/logic/marketdata"
+	"msa/pkg/logic/marketdata/klinecache"
+	"msa/pkg/logic/tools/stock"
+	"msa/pkg/model"
+)
+
+// warmupBars 回测起始日之前额外获取的K线数，用于计算均线等指标
+const warmupBars = 70
+
+var (
+	backtestStrategy string
+	backtestFrom     string
+	backtestTo       string
+	backtestCodes    []string
+	backtestCapital  float64
+	backtestTrades   int
+	backtestJSON     bool
+)
+
+// NewCommand 创建 backtest 子命令
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backtest",
+		Short: "用历史日K线回测交易策略",
+		Long: `按交易日回放前复权日K线：每日收盘按策略的买卖条件（与 msa screen 相同的表达式，只支持日K线可计算的字段）产生信号，
+次日开盘经模拟交易规则（涨跌幅、整手、T+1、手续费）成交，盘中按止损、止盈检查，输出收益、回撤、胜率、换手率与成交记录。
+回测在临时数据库中进行，不影响真实账户。
+
+策略文件（YAML）示例：
+  name: ma20-trend
+  buy: price > ma20 AND ma5 > ma20 AND rsi14 < 70
+  sell: price < ma20
+  rank_by: change_20d
+  position_pct: 20
+  max_positions: 5
+  stop_loss_pct: 8
+  take_profit_pct: 25
+  max_volume_pct: 5      # 单笔最多成交当日成交量的 5%，超出部分撤销（模拟小盘股流动性）
+
+示例：msa backtest --strategy ma20.yaml --from 2025-01-02 --to 2025-06-30 --codes sh600519,sz000858,sz300750`,
+		RunE: runBacktest,
+	}
+
+	cmd.Flags().StringVar(&backtestStrategy, "strategy", "", "策略文件路径（YAML）")
+	cmd.Flags().StringVar(&backtestFrom, "from", "", "起始日期（YYYY-MM-DD）")
+	cmd.Flags().StringVar(&backtestTo, "to", "", "结束日期（YYYY-MM-DD，默认今天）")
+	cmd.Flags().StringSliceVar(&backtestCodes, "codes", nil, "股票代码，可逗号分隔多个")
+	cmd.Flags().Float64Var(&backtestCapital, "capital", 0, "初始资金（元，覆盖策略文件 initial_capital）")
+	cmd.Flags().IntVar(&backtestTrades, "trades", 50, "报告中列出的最近成交笔数，0 表示全部")
+	cmd.Flags().BoolVar(&backtestJSON, "json", false, "以 JSON 输出完整报告（含每日净值）")
+	_ = cmd.MarkFlagRequired("strategy")
+	_ = cmd.MarkFlagRequired("from")
+	_ = cmd.MarkFlagRequired("codes")
+
+	return cmd
+}
+
+func runBacktest(cmd *cobra.Command, args []string) error {
+	strategy, err := backtest.LoadStrategy(backtestStrategy)
+	if err != nil {
+		return err
+	}
+	if backtestCapital < 0 {
+		return fmt.Errorf("--capital 必须大于 0")
+	}
+	if backtestCapital > 0 {
+		strategy.InitialCapital = backtestCapital
+	}
+
+	from, err := time.ParseInLocation(calendar.DateLayout, backtestFrom, calendar.Location)
+	if err != nil {
+		return fmt.Errorf("--from 日期格式无效: %s", backtestFrom)
+	}
+	to := backtestTo
+	if to == "" {
+		to = calendar.DateOf(time.Now())
+	}
+
+	var codes []string
+	for _, code := range backtestCodes {
+		if code = strings.ToLower(strings.TrimSpace(code)); code != "" {
+			codes = append(codes, code)
+		}
+	}
+	if len(codes) == 0 {
+		return fmt.Errorf("--codes 不能为空")
+	}
+
+	feed, err := loadFeed(strategy, codes, from)
+	if err != nil {
+		return err
+	}
+	if !backtestJSON {
+		fmt.Printf("回测 %s：%d 只股票，%s ~ %s ...\n\n", strategy.Name, len(codes), backtestFrom, to)
+	}
+
+	report, err := backtest.Run(strategy, feed, backtestFrom, to)
+	if err != nil {
+		return err
+	}
+	if backtestJSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	fmt.Print(report.Format(backtestTrades))
+	return nil
+}
+
+// loadFeed 经本地K线缓存获取覆盖回测区间与预热期的日K线，并尽量获取股票名称与港股每手股数
+func loadFeed(strategy *backtest.Strategy, codes []string, from time.Time) (*backtest.Feed, error) {
+	count := tradingDaysSince(from) + warmupBars
+	if count > klinecache.MaxBars {
+		log.Warnf("回测区间过长，最多获取 %d 根K线", klinecache.MaxBars)
+		count = klinecache.MaxBars
+	}
+
+	feed := &backtest.Feed{Bars: map[string][]model.KLineBar{}, Names: map[string]string{}}
+	for _, code := range codes {
+		bars, err := stock.FetchStockHistoryK(code, "day", count, "qfq")
+		if err != nil {
+			return nil, fmt.Errorf("获取 %s 日K线失败: %w", code, err)
+		}
+		feed.Bars[code] = bars
+	}
+
+	if provider, err := marketdata.GetProvider(); err == nil {
+		if quotes, err := provider.GetQuotes(codes); err == nil {
+			for code, quote := range quotes {
+				feed.Names[code] = quote.StockName
+			}
+		} else {
+			log.Warnf("获取股票名称失败: %v", err)
+		}
+		// 设置行业仓位限制时预先获取行业，回测期间风控不再查询
+		if finsvc.GetRiskPolicy().MaxIndustryPct > 0 {
+			feed.Industries = map[string]string{}
+			for _, code := range codes {
+				resp, err := provider.GetIndustry(code)
+				if err != nil {
+					log.Warnf("获取 %s 行业失败: %v", code, err)
+					continue
+				}
+				if level1, _ := resp.IndustryLevels(); level1 != nil {
+					feed.Industries[code] = level1.Name
+				}
+			}
+		}
+	}
+
+	for _, code := range codes {
+		if finsvc.DetectBoard(code) != finsvc.BoardHK || strategy.LotSizes[code] > 0 {
+			continue
+		}
+		resp, err := stock.FetchStockData(code)
+		if err != nil {
+			return nil, fmt.Errorf("获取 %s 每手股数失败，请在策略文件 lot_sizes 中指定: %w", code, err)
+		}
+		lot, err := strconv.ParseInt(strings.TrimSpace(resp.Lot2Share), 10, 64)
+		if err != nil || lot <= 0 {
+			return nil, fmt.Errorf("%s 每手股数未知，请在策略文件 lot_sizes 中指定", code)
+		}
+		if strategy.LotSizes == nil {
+			strategy.LotSizes = map[string]int64{}
+		}
+		strategy.LotSizes[code] = lot
+	}
+	return feed, nil
+}
+
+// tradingDaysSince 从 from 到今天的A股交易日数
+func tradingDaysSince(from time.Time) int {
+	n := 0
+	now := time.Now()
+	for day := calendar.LatestTradingDay(calendar.MarketCN, from); !day.After(now); day = calendar.NextTradingDay(calendar.MarketCN, day) {
+		n++
+	}
+	return n
+}
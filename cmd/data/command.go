@@ -0,0 +1,38 @@
+package cmd_data
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	msadb "msa/pkg/db"
+)
+
+// NewCommand 创建 data 子命令
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "data",
+		Short: "管理本地行情缓存",
+		Long:  `查看与预取本地 SQLite 中缓存的历史K线，回测与指标计算可直接使用缓存数据。`,
+		RunE:  runData,
+	}
+
+	// 添加子命令
+	cmd.AddCommand(newSyncCmd())
+	cmd.AddCommand(newStatusCmd())
+
+	return cmd
+}
+
+func runData(cmd *cobra.Command, args []string) error {
+	// 默认执行 status 命令
+	return runStatus(cmd, args)
+}
+
+// requireDB 检查数据库是否可用
+func requireDB() error {
+	if msadb.GetDB() == nil {
+		return fmt.Errorf("数据库未初始化")
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package cmd_data
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	msadb "msa/pkg/db"
+)
+
+func newStatusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status [code]",
+		Short: "查看K线缓存",
+		Long:  `列出本地缓存的K线序列：周期、复权方式、日期范围、K线数与最近同步时间。`,
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runStatus,
+	}
+}
+
+func runStatus(cmd *cobra.Command, args []string) error {
+	if err := requireDB(); err != nil {
+		return err
+	}
+
+	stockCode := ""
+	if len(args) > 0 {
+		stockCode = strings.ToLower(strings.TrimSpace(args[0]))
+	}
+	series, err := msadb.ListKLineSeries(msadb.GetDB(), stockCode)
+	if err != nil {
+		return err
+	}
+	if len(series) == 0 {
+		fmt.Println("暂无K线缓存，使用 msa data sync <codes> 预取")
+		return nil
+	}
+
+	fmt.Printf("%-10s %-6s %-4s %-10s %-10s %6s %-4s %s\n", "代码", "周期", "复权", "起始", "最新", "K线数", "完整", "同步时间")
+	for _, s := range series {
+		adjust := s.Adjust
+		if adjust == "" {
+			adjust = "-"
+		}
+		complete := "否"
+		if s.HistoryComplete {
+			complete = "是"
+		}
+		fmt.Printf("%-10s %-6s %-4s %-10s %-10s %6d %-4s %s\n",
+			s.StockCode, s.Period, adjust, s.FirstDate, s.LastDate, s.BarCount, complete, s.SyncedAt.Local().Format("2006-01-02 15:04"))
+	}
+	return nil
+}
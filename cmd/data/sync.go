@@ -0,0 +1,85 @@
+package cmd_data
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	msadb "msa/pkg/db"
+	"msa/pkg/logic/marketdata"
+	"msa/pkg/logic/marketdata/klinecache"
+)
+
+var (
+	syncPeriods []string
+	syncCount   int
+	syncAdjust  string
+)
+
+func newSyncCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync <codes...>",
+		Short: "预取历史K线到本地缓存",
+		Long: `按股票代码预取历史K线到本地缓存，已缓存的序列只请求最新日期之后的K线。
+示例：msa data sync sh600519 sz000001 --period day,week`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: runSync,
+	}
+
+	cmd.Flags().StringSliceVar(&syncPeriods, "period", []string{"day"}, "K线周期：day/week/month，可逗号分隔多个")
+	cmd.Flags().IntVar(&syncCount, "count", klinecache.MaxBars, fmt.Sprintf("至少缓存的K线数（最多 %d）", klinecache.MaxBars))
+	cmd.Flags().StringVar(&syncAdjust, "adjust", "qfq", "复权方式：qfq/hfq/none")
+
+	return cmd
+}
+
+func runSync(cmd *cobra.Command, args []string) error {
+	if err := requireDB(); err != nil {
+		return err
+	}
+	for _, period := range syncPeriods {
+		if period != "day" && period != "week" && period != "month" {
+			return fmt.Errorf("不支持的K线周期: %s", period)
+		}
+	}
+	adjust := syncAdjust
+	if adjust == "none" {
+		adjust = ""
+	}
+
+	provider, err := marketdata.GetProvider()
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, arg := range args {
+		for _, code := range strings.Split(arg, ",") {
+			code = strings.ToLower(strings.TrimSpace(code))
+			if code == "" {
+				continue
+			}
+			for _, period := range syncPeriods {
+				if cmd.Context() != nil && cmd.Context().Err() != nil {
+					return cmd.Context().Err()
+				}
+				result, err := klinecache.Sync(msadb.GetDB(), provider, code, period, syncCount, adjust, time.Now())
+				if err != nil {
+					failed++
+					fmt.Printf("❌ %s %s: %v\n", code, period, err)
+					continue
+				}
+				s := result.Series
+				fmt.Printf("✅ %s %s: %s, 获取 %d 根, 缓存 %d 根 (%s ~ %s)\n",
+					code, period, result.Mode, result.Fetched, s.BarCount, s.FirstDate, s.LastDate)
+			}
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d 个K线序列同步失败", failed)
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+package cmd_monitor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+
+	"msa/pkg/config"
+	coreagent "msa/pkg/core/agent"
+	"msa/pkg/core/runner"
+	msadb "msa/pkg/db"
+	"msa/pkg/logic/calendar"
+	"msa/pkg/logic/finsvc"
+	"msa/pkg/logic/monitor"
+	"msa/pkg/logic/skills"
+	"msa/pkg/logic/tools/finance"
+	"msa/pkg/logic/tools/stock"
+	"msa/pkg/logic/watchlist"
+	"msa/pkg/model"
+	"msa/pkg/renderer"
+	"msa/pkg/session"
+)
+
+// defaultSkillCooldown 两次调用 skill 的默认最小间隔
+const defaultSkillCooldown = 15 * time.Minute
+
+var (
+	monitorInterval      string
+	monitorSkill         string
+	monitorSkillCooldown string
+	monitorOnce          bool
+)
+
+// NewCommand 创建 monitor 子命令
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "monitor",
+		Short: "盘中监控持仓条件单与自选股提醒",
+		Long: `常驻运行，交易时段内按间隔获取持仓与自选股的分时数据，评估条件单（止损/止盈/跟踪止损）与自选股提醒规则并记录触发。
+配置 skill 后，有触发时调用该 skill 分析（两次调用之间至少间隔 --skill-cooldown）。
+每个交易日收盘后为各账户记录一次净值快照（与 msa portfolio snapshot 相同），供收益曲线使用。
+参数未指定时使用配置文件 monitor 段（interval / skill / skillCooldown），按 Ctrl+C 退出。
+示例：msa monitor --interval 30s --skill stock-analysis`,
+		RunE: runMonitor,
+	}
+
+	cmd.Flags().StringVar(&monitorInterval, "interval", "", fmt.Sprintf("检查间隔（默认 %s，最小 %s）", monitor.DefaultInterval, config.MinMonitorInterval))
+	cmd.Flags().StringVar(&monitorSkill, "skill", "", "有触发时调用的 skill 名称")
+	cmd.Flags().StringVar(&monitorSkillCooldown, "skill-cooldown", "", fmt.Sprintf("两次调用 skill 的最小间隔（默认 %s）", defaultSkillCooldown))
+	cmd.Flags().BoolVar(&monitorOnce, "once", false, "只检查一次后退出")
+
+	return cmd
+}
+
+func runMonitor(cmd *cobra.Command, args []string) error {
+	database := msadb.GetDB()
+	if database == nil {
+		return fmt.Errorf("数据库未初始化")
+	}
+
+	opts, err := resolveOptions(config.GetLocalStoreConfig())
+	if err != nil {
+		return err
+	}
+
+	daemon := &monitor.Daemon{
+		Watch: watchlist.NewMonitor(stock.FetchStockData, func(stockCode string, count int) ([]model.KLineBar, error) {
+			return stock.FetchStockHistoryK(stockCode, "day", count, "qfq")
+		}).WithMinute(stock.FetchStockMinuteData),
+		SyncAccount: func(database *gorm.DB, accountID uint) ([]uint, []uint) {
+			result := finance.SyncPendingOrders(database, accountID)
+			return result.Triggered, result.Filled
+		},
+		Snapshot: func(database *gorm.DB, accountID uint, at time.Time) error {
+			_, err := finance.RecordPortfolioSnapshot(database, accountID, at)
+			return err
+		},
+		Interval: opts.interval,
+	}
+
+	var analyst *skillAnalyst
+	if opts.skill != "" {
+		if analyst, err = newSkillAnalyst(cmd.Context(), opts.skill, opts.cooldown); err != nil {
+			return err
+		}
+	}
+
+	ctx := cmd.Context()
+	if monitorOnce {
+		now := time.Now()
+		triggers, err := daemon.Cycle(database, now)
+		if err != nil {
+			return err
+		}
+		if !monitor.InSession(now) {
+			fmt.Println(idleMessage(daemon, now))
+			return nil
+		}
+		report(triggers)
+		if analyst != nil {
+			analyst.analyze(ctx, triggers)
+		}
+		return nil
+	}
+
+	daemon.OnCycle = func(ctx context.Context, triggers []monitor.Trigger) {
+		report(triggers)
+		if analyst != nil {
+			analyst.analyze(ctx, triggers)
+		}
+	}
+	fmt.Printf("盘中监控已启动，检查间隔 %s，按 Ctrl+C 退出\n", daemon.Interval)
+	if err := daemon.Run(ctx, database); err != nil {
+		return err
+	}
+	fmt.Println("盘中监控已停止")
+	return nil
+}
+
+// idleMessage 非交易时段单次检查的提示，仅在本次实际记录了收盘快照时提示已记录
+func idleMessage(daemon *monitor.Daemon, now time.Time) string {
+	switch {
+	case daemon.SnapshotRecorded(now):
+		return "当前不在交易时段，未检查（收盘后已记录当日净值快照）"
+	case finsvc.IsAfterMarketClose(now):
+		return "当前已收盘，未检查（未记录净值快照，详见日志）"
+	case calendar.IsTradingDay(calendar.MarketCN, now):
+		return "当前未开盘或处于午间休市，未检查"
+	default:
+		return "今日非交易日，未检查"
+	}
+}
+
+// options 命令参数与配置合并后的监控参数
+type options struct {
+	interval time.Duration
+	skill    string
+	cooldown time.Duration
+}
+
+// resolveOptions 合并命令行参数与配置文件，命令行参数优先
+func resolveOptions(cfg *config.LocalStoreConfig) (options, error) {
+	monitorCfg := model.MonitorConfig{}
+	if cfg != nil && cfg.Monitor != nil {
+		monitorCfg = *cfg.Monitor
+	}
+	if monitorInterval != "" {
+		monitorCfg.Interval = monitorInterval
+	}
+	if monitorSkill != "" {
+		monitorCfg.Skill = monitorSkill
+	}
+	if monitorSkillCooldown != "" {
+		monitorCfg.SkillCooldown = monitorSkillCooldown
+	}
+	if errs := config.ValidateMonitor(&monitorCfg); len(errs) > 0 {
+		return options{}, fmt.Errorf("%s: %s", errs[0].Field, errs[0].Message)
+	}
+
+	opts := options{interval: monitor.DefaultInterval, skill: monitorCfg.Skill, cooldown: defaultSkillCooldown}
+	if monitorCfg.Interval != "" {
+		opts.interval, _ = time.ParseDuration(monitorCfg.Interval)
+	}
+	if monitorCfg.SkillCooldown != "" {
+		opts.cooldown, _ = time.ParseDuration(monitorCfg.SkillCooldown)
+	}
+	return opts, nil
+}
+
+// report 输出本周期的触发
+func report(triggers []monitor.Trigger) {
+	for _, t := range triggers {
+		fmt.Printf("%s %s\n", t.At.Format("15:04:05"), t)
+	}
+}
+
+// skillAnalyst 有触发时调用 skill 分析，两次调用之间至少间隔 cooldown
+// 冷却期内的触发累积到下次调用
+type skillAnalyst struct {
+	skill    string
+	cooldown time.Duration
+	runner   *runner.Runner
+	session  *session.Manager
+	sess     *session.Session
+
+	pending []monitor.Trigger
+	lastRun time.Time
+}
+
+func newSkillAnalyst(ctx context.Context, skill string, cooldown time.Duration) (*skillAnalyst, error) {
+	cfg := config.GetLocalStoreConfig()
+	if cfg == nil || cfg.APIKey == "" || cfg.BaseURL == "" || cfg.Model == "" {
+		return nil, fmt.Errorf("调用 skill 需要先配置 API Key、Base URL 与模型，运行 'msa config'")
+	}
+
+	manager := skills.GetManager()
+	if err := manager.Initialize(); err != nil {
+		log.Warnf("skills initialize warning: %v", err)
+	}
+	if _, err := manager.GetSkill(skill); err != nil || manager.IsDisabled(skill) {
+		return nil, fmt.Errorf("skill 不存在或已禁用: %s", skill)
+	}
+
+	ag, err := coreagent.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("创建 Agent 失败: %w", err)
+	}
+	sessionMgr := session.GetManager()
+	sess := sessionMgr.NewSession(session.ModeCLI)
+	if err := sessionMgr.CreateSessionFile(sess); err != nil {
+		log.Warnf("创建会话文件失败: %v", err)
+	}
+	sessionMgr.SetCurrent(sess)
+
+	return &skillAnalyst{
+		skill:    skill,
+		cooldown: cooldown,
+		runner:   runner.New(ag, sessionMgr, renderer.NewCLI(os.Stdout, false)),
+		session:  sessionMgr,
+		sess:     sess,
+	}, nil
+}
+
+// analyze 累积触发，冷却结束后调用 skill；调用失败只记录日志
+func (a *skillAnalyst) analyze(ctx context.Context, triggers []monitor.Trigger) {
+	a.pending = append(a.pending, triggers...)
+	if len(a.pending) == 0 || ctx.Err() != nil {
+		return
+	}
+	if !a.lastRun.IsZero() && time.Since(a.lastRun) < a.cooldown {
+		return
+	}
+
+	prompt := a.prompt()
+	a.pending = nil
+	a.lastRun = time.Now()
+
+	fmt.Printf("\n调用 skill %s 分析盘中触发...\n", a.skill)
+	a.session.AppendMessage(a.sess, "user", prompt)
+	if err := a.runner.Ask(ctx, prompt, nil); err != nil && ctx.Err() == nil {
+		log.Errorf("调用 skill 失败: %v", err)
+	}
+	fmt.Println()
+}
+
+// prompt 生成调用 skill 的问题
+func (a *skillAnalyst) prompt() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("请使用 %s skill 分析以下盘中监控触发，评估对持仓与自选股的影响并给出操作建议：\n", a.skill))
+	for _, t := range a.pending {
+		sb.WriteString(fmt.Sprintf("- %s %s\n", t.At.Format("2006-01-02 15:04"), t))
+	}
+	return sb.String()
+}
@@ -0,0 +1,52 @@
+package cmd_portfolio
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+
+	msadb "msa/pkg/db"
+	"msa/pkg/logic/tools/finance"
+	"msa/pkg/model"
+)
+
+// accountName --account 参数的值
+var accountName string
+
+// NewCommand 创建 portfolio 子命令
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "portfolio",
+		Short: "查看账户净值",
+		Long:  `查看账户每日收盘净值快照与收益曲线，或手动记录当日净值快照。`,
+		RunE:  runPortfolio,
+	}
+
+	cmd.PersistentFlags().StringVar(&accountName, "account", "", "账户名称（默认使用当前账户）")
+
+	// 添加子命令
+	cmd.AddCommand(newHistoryCmd())
+	cmd.AddCommand(newSnapshotCmd())
+
+	return cmd
+}
+
+func runPortfolio(cmd *cobra.Command, args []string) error {
+	// 默认执行 history 命令
+	return historyRunE(cmd, args)
+}
+
+// resolveAccount 获取数据库连接与 --account 指定的账户
+func resolveAccount() (*gorm.DB, *model.Account, error) {
+	database := msadb.GetDB()
+	if database == nil {
+		return nil, nil, fmt.Errorf("数据库未初始化")
+	}
+
+	account, err := finance.ResolveAccount(database, accountName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return database, account, nil
+}
@@ -0,0 +1,96 @@
+package cmd_portfolio
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	msadb "msa/pkg/db"
+	"msa/pkg/logic/finsvc"
+	"msa/pkg/model"
+)
+
+var (
+	historyDays  int
+	riskFreeRate float64
+	outputJSON   bool
+)
+
+func newHistoryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "查看净值曲线",
+		Long:  `按交易日列出账户收盘净值、日收益率、累计收益率与回撤，并汇总最大回撤、年化波动率和夏普比率。`,
+		RunE:  runHistory,
+	}
+
+	cmd.Flags().IntVar(&historyDays, "days", 30, "最近 N 个交易日（0 表示全部）")
+	cmd.Flags().Float64Var(&riskFreeRate, "risk-free", 0, "年化无风险利率（%）")
+	cmd.Flags().BoolVar(&outputJSON, "json", false, "以 JSON 格式输出")
+
+	return cmd
+}
+
+var historyRunE = runHistory
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	database, account, err := resolveAccount()
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := msadb.GetPortfolioSnapshots(database, account.ID, "", "")
+	if err != nil {
+		return err
+	}
+	if historyDays > 0 && len(snapshots) > historyDays {
+		snapshots = snapshots[len(snapshots)-historyDays:]
+	}
+
+	curve := finsvc.ComputeEquityCurve(snapshots, riskFreeRate/100)
+	if outputJSON {
+		data, err := json.MarshalIndent(curve, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	return outputTable(account, curve)
+}
+
+func outputTable(account *model.Account, curve *finsvc.EquityCurve) error {
+	if len(curve.Points) == 0 {
+		fmt.Printf("账户 %s 暂无净值快照，收盘后执行 msa portfolio snapshot 记录。\n", account.Name)
+		return nil
+	}
+
+	fmt.Printf("账户: %s\n\n", account.Name)
+
+	// 表格头
+	fmt.Printf("%-12s %16s %14s %10s %10s %10s\n", "Date", "NAV", "NetFlow", "Daily", "Cumul.", "Drawdown")
+	fmt.Println("──────────────────────────────────────────────────────────────────────────────")
+
+	// 表格内容
+	for _, p := range curve.Points {
+		netFlow := "-"
+		if p.NetFlow != 0 {
+			netFlow = model.FormatAmount(p.NetFlow)
+		}
+		fmt.Printf("%-12s %16s %14s %10s %10s %10s\n",
+			p.TradeDate, model.FormatAmount(p.NAV), netFlow,
+			formatPercent(p.DailyReturn), formatPercent(p.CumulativeReturn), formatPercent(p.Drawdown))
+	}
+
+	fmt.Printf("\n累计收益: %s  最大回撤: %s  年化波动率: %s  夏普比率: %.2f\n",
+		formatPercent(curve.CumulativeReturn), formatPercent(curve.MaxDrawdown),
+		formatPercent(curve.Volatility), curve.Sharpe)
+	return nil
+}
+
+// formatPercent 小数格式化为百分比
+func formatPercent(v float64) string {
+	return fmt.Sprintf("%.2f%%", v*100)
+}
@@ -0,0 +1,48 @@
+package cmd_portfolio
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"msa/pkg/logic/finsvc"
+	"msa/pkg/logic/tools/finance"
+	"msa/pkg/model"
+)
+
+var forceSnapshot bool
+
+func newSnapshotCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "记录当日净值快照",
+		Long:  `按实时行情记录账户当日净值快照，收盘后执行时即为收盘净值；同一交易日重复执行会覆盖旧快照。`,
+		RunE:  runSnapshot,
+	}
+
+	cmd.Flags().BoolVar(&forceSnapshot, "force", false, "盘中也记录快照")
+
+	return cmd
+}
+
+func runSnapshot(cmd *cobra.Command, args []string) error {
+	now := time.Now()
+	if !forceSnapshot && !finsvc.IsAfterMarketClose(now) {
+		return fmt.Errorf("尚未收盘，盘中记录请使用 --force")
+	}
+
+	database, account, err := resolveAccount()
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := finance.RecordPortfolioSnapshot(database, account.ID, now)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("已记录净值快照: 账户=%s, 交易日=%s, 净值=%s 元, 持仓 %d 只\n",
+		account.Name, snapshot.TradeDate, model.FormatAmount(snapshot.NAV), len(snapshot.Holdings))
+	return nil
+}
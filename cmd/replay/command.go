@@ -0,0 +1,239 @@
+package cmd_replay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"msa/pkg/config"
+	coreagent "msa/pkg/core/agent"
+	"msa/pkg/core/event"
+	"msa/pkg/core/runner"
+	msadb "msa/pkg/db"
+	"msa/pkg/logic/calendar"
+	"msa/pkg/logic/marketdata"
+	"msa/pkg/logic/replay"
+	"msa/pkg/logic/scheduler"
+	"msa/pkg/logic/skills"
+	"msa/pkg/renderer"
+	"msa/pkg/session"
+)
+
+// maxToolOutput 决策日志中保留的工具输出长度（字符）
+const maxToolOutput = 2000
+
+var (
+	replaySkills  []string
+	replayFrom    string
+	replayTo      string
+	replayAt      string
+	replayCapital float64
+	replayData    string
+	replayDB      string
+	replayOut     string
+	replayJSON    bool
+	replayTools   bool
+)
+
+// NewCommand 创建 replay 子命令
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "replay",
+		Short: "在历史交易日回放 skill，记录模型的决策",
+		Long: `把时钟固定在过去交易日的 skill 触发时刻（triggers.time 的开始时间，可用 --at 指定），通过对话流程执行 skill：
+提示词中的时间、交易日与交易阶段按回放时刻生成，行情工具只返回该时刻之前的数据（K线来自K线缓存，
+分时、行业与板块数据来自 --data 目录下按日期录制的文件），交易在临时数据库的回放账户中模拟成交，不影响真实账户。
+每个交易日收盘撤销未成交挂单并按收盘价估值，输出逐日决策日志，并附上订单当日与次日的实际收盘价用于对照。
+
+录制数据目录结构：<data>/<YYYY-MM-DD>/{quote,minute,industry,board_rank,board_stocks}/...（与本地行情数据源相同）
+
+示例：msa replay --from 2025-06-02 --to 2025-06-06 --skill morning-analysis,afternoon-trade --out replay.md`,
+		RunE: runReplay,
+	}
+
+	cmd.Flags().StringSliceVar(&replaySkills, "skill", []string{"morning-analysis", "afternoon-trade"}, "回放的 skill，可逗号分隔多个")
+	cmd.Flags().StringVar(&replayFrom, "from", "", "起始日期（YYYY-MM-DD）")
+	cmd.Flags().StringVar(&replayTo, "to", "", "结束日期（YYYY-MM-DD，默认与起始日期相同）")
+	cmd.Flags().StringVar(&replayAt, "at", "", "执行时刻（HH:MM），覆盖 skill 的触发时间，只能与单个 skill 一起使用")
+	cmd.Flags().Float64Var(&replayCapital, "capital", replay.DefaultInitialCapital, "回放账户初始资金（元）")
+	cmd.Flags().StringVar(&replayData, "data", "", "按日期录制的行情目录（默认 ~/.msa/replay）")
+	cmd.Flags().StringVar(&replayDB, "db", "", "保留回放数据库到指定路径（默认使用临时数据库）")
+	cmd.Flags().StringVar(&replayOut, "out", "", "决策日志输出文件，.json 结尾时输出 JSON（默认输出到终端）")
+	cmd.Flags().BoolVar(&replayJSON, "json", false, "以 JSON 输出决策日志")
+	cmd.Flags().BoolVar(&replayTools, "tools", false, "决策日志中列出每次工具调用的参数")
+	_ = cmd.MarkFlagRequired("from")
+
+	return cmd
+}
+
+func runReplay(cmd *cobra.Command, args []string) error {
+	cfg := config.GetLocalStoreConfig()
+	if cfg == nil || cfg.APIKey == "" || cfg.BaseURL == "" || cfg.Model == "" {
+		return fmt.Errorf("回放需要先配置 API Key、Base URL 与模型，运行 'msa config'")
+	}
+
+	jobs, err := buildJobs()
+	if err != nil {
+		return err
+	}
+	to := replayTo
+	if to == "" {
+		to = replayFrom
+	}
+
+	base, err := marketdata.GetProvider()
+	if err != nil {
+		return err
+	}
+	dataDir := replayData
+	if dataDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("获取用户主目录失败: %w", err)
+		}
+		dataDir = filepath.Join(homeDir, ".msa", "replay")
+	}
+
+	ctx := cmd.Context()
+	ag, err := coreagent.New(ctx)
+	if err != nil {
+		return fmt.Errorf("创建 Agent 失败: %w", err)
+	}
+	toJSON := replayJSON || strings.HasSuffix(replayOut, ".json")
+	live := !(replayJSON && replayOut == "")
+	execute := func(ctx context.Context, job replay.Job, question string) (*replay.Transcript, error) {
+		return executeSkill(ctx, ag, job, question, live)
+	}
+
+	result, runErr := replay.Run(ctx, replay.Options{
+		From:           replayFrom,
+		To:             to,
+		Jobs:           jobs,
+		InitialCapital: replayCapital,
+		DBPath:         replayDB,
+		Provider:       &replay.Provider{Base: base, Cache: msadb.GetDB(), Dir: dataDir},
+	}, execute)
+	if result == nil {
+		return runErr
+	}
+	if runErr != nil {
+		log.Warnf("回放未完成: %v", runErr)
+	}
+
+	output := result.Format(replayTools)
+	if toJSON {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		output = string(data) + "\n"
+	}
+	if replayOut == "" {
+		fmt.Print(output)
+	} else {
+		if err := os.WriteFile(replayOut, []byte(output), 0644); err != nil {
+			return fmt.Errorf("写入决策日志失败: %w", err)
+		}
+		fmt.Printf("\n决策日志已写入 %s\n", replayOut)
+	}
+	return runErr
+}
+
+// buildJobs 按 skill 的触发时间段开始时间（或 --at）生成回放任务
+func buildJobs() ([]replay.Job, error) {
+	manager := skills.GetManager()
+	if err := manager.Initialize(); err != nil {
+		log.Warnf("skills initialize warning: %v", err)
+	}
+	if len(replaySkills) == 0 {
+		return nil, fmt.Errorf("--skill 不能为空")
+	}
+	if replayAt != "" && len(replaySkills) > 1 {
+		return nil, fmt.Errorf("--at 只能与单个 skill 一起使用")
+	}
+
+	windows := map[string]scheduler.Window{}
+	for _, job := range scheduler.JobsFromSkills(manager.ListSkills(), replaySkills) {
+		windows[job.Skill] = job.Window
+	}
+
+	jobs := make([]replay.Job, 0, len(replaySkills))
+	for _, name := range replaySkills {
+		if _, err := manager.GetSkill(name); err != nil || manager.IsDisabled(name) {
+			return nil, fmt.Errorf("skill 不存在或已禁用: %s", name)
+		}
+		if replayAt != "" {
+			window, err := scheduler.ParseWindow(replayAt + "+")
+			if err != nil {
+				return nil, fmt.Errorf("--at 时间格式无效: %s", replayAt)
+			}
+			jobs = append(jobs, replay.Job{Skill: name, Minute: window.Start})
+			continue
+		}
+		window, ok := windows[name]
+		if !ok {
+			return nil, fmt.Errorf("skill %s 没有声明触发时间，请用 --at 指定执行时刻", name)
+		}
+		jobs = append(jobs, replay.Job{Skill: name, Minute: window.Start})
+	}
+	return jobs, nil
+}
+
+// executeSkill 在新的回放会话中通过 runner 执行 skill，会话不写入 memory 目录
+func executeSkill(ctx context.Context, ag *coreagent.Agent, job replay.Job, question string, live bool) (*replay.Transcript, error) {
+	sessionMgr := session.GetManager()
+	sessionMgr.SetCurrent(sessionMgr.NewSession(session.ModeReplay))
+	defer sessionMgr.Clear()
+
+	rec := &recorder{calls: map[string]int{}}
+	if live {
+		fmt.Printf("\n===== 回放 %s %s =====\n", calendar.Now().In(calendar.Location).Format("2006-01-02 15:04"), job.Skill)
+		rec.inner = renderer.NewCLI(os.Stdout, false)
+	}
+	err := runner.New(ag, sessionMgr, rec).Ask(ctx, question, nil)
+	if err == nil && ctx.Err() != nil {
+		err = fmt.Errorf("执行中断: %w", ctx.Err())
+	}
+	return &replay.Transcript{Reply: strings.TrimSpace(rec.reply.String()), Tools: rec.tools}, err
+}
+
+// recorder 记录回复与工具调用，live 时同时转发到终端渲染器
+type recorder struct {
+	inner renderer.Renderer
+	reply strings.Builder
+	tools []replay.ToolCall
+	calls map[string]int // 工具调用ID → tools 下标
+}
+
+func (r *recorder) Handle(ctx context.Context, e event.Event) error {
+	switch e.Type {
+	case event.EventTextChunk:
+		r.reply.WriteString(e.Text)
+	case event.EventToolStart:
+		r.calls[e.Tool.ID] = len(r.tools)
+		r.tools = append(r.tools, replay.ToolCall{Name: e.Tool.Name, Input: e.Tool.Input})
+	case event.EventToolResult, event.EventToolError:
+		if i, ok := r.calls[e.Result.ToolCallID]; ok {
+			output := []rune(e.Result.Output)
+			if len(output) > maxToolOutput {
+				output = append(output[:maxToolOutput], []rune("…")...)
+			}
+			r.tools[i].Output = string(output)
+			r.tools[i].IsError = e.Result.IsError || e.Type == event.EventToolError
+		}
+	case event.EventError:
+		if r.inner == nil {
+			return e.Err
+		}
+	}
+	if r.inner != nil {
+		return r.inner.Handle(ctx, e)
+	}
+	return nil
+}
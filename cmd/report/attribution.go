@@ -0,0 +1,143 @@
+package cmd_report
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"msa/pkg/logic/attribution"
+	"msa/pkg/logic/calendar"
+	"msa/pkg/logic/marketdata"
+	"msa/pkg/model"
+)
+
+var (
+	attrFrom      string
+	attrTo        string
+	attrDays      int
+	attrBenchmark string
+	attrRiskFree  float64
+	attrTop       int
+	attrJSON      bool
+)
+
+func newAttributionCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "attribution",
+		Short: "绩效归因",
+		Long: `按成交记录、资金流水与公司行动重建每日持仓，结合K线缓存中的不复权日K线分析区间收益来源：
+相对基准指数的 Beta 与年化 Alpha、按申万一级行业的配置效应与选股效应（Brinson，现金单列），以及个股和逐笔交易的收益贡献。
+行业指数K线获取失败时以基准指数代替。
+
+示例：msa report attribution --days 60 --benchmark sh000300`,
+		RunE: runAttribution,
+	}
+
+	cmd.Flags().StringVar(&attrFrom, "from", "", "开始日期（YYYY-MM-DD）")
+	cmd.Flags().StringVar(&attrTo, "to", "", "结束日期（YYYY-MM-DD，默认最近一个已收盘的交易日）")
+	cmd.Flags().IntVar(&attrDays, "days", attribution.DefaultDays, "未指定 --from 时的交易日数")
+	cmd.Flags().StringVar(&attrBenchmark, "benchmark", attribution.DefaultBenchmark, "基准指数代码")
+	cmd.Flags().Float64Var(&attrRiskFree, "risk-free", 0, "年化无风险利率（%）")
+	cmd.Flags().IntVar(&attrTop, "top", 10, "显示贡献最大的个股与交易数量（0 表示全部）")
+	cmd.Flags().BoolVar(&attrJSON, "json", false, "以 JSON 格式输出")
+
+	return cmd
+}
+
+func runAttribution(cmd *cobra.Command, args []string) error {
+	database, account, err := resolveAccount()
+	if err != nil {
+		return err
+	}
+	provider, err := marketdata.GetProvider()
+	if err != nil {
+		return err
+	}
+
+	report, err := attribution.Analyze(database, provider, account.ID, attribution.Options{
+		From:         attrFrom,
+		To:           attrTo,
+		Days:         attrDays,
+		Benchmark:    attrBenchmark,
+		RiskFreeRate: attrRiskFree / 100,
+	}, calendar.Now())
+	if err != nil {
+		return err
+	}
+
+	if attrJSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	outputAttribution(account, report)
+	return nil
+}
+
+func outputAttribution(account *model.Account, r *attribution.Report) {
+	fmt.Printf("账户: %s  区间: %s ~ %s（%d 个交易日）  基准: %s\n\n", account.Name, r.From, r.To, r.Days, r.Benchmark)
+	fmt.Printf("期初净值: %s  期末净值: %s  净入金: %s  盈亏: %s\n",
+		model.FormatAmount(r.StartNAV), model.FormatAmount(r.EndNAV), model.FormatAmount(r.NetFlow), model.FormatAmount(r.PnL))
+	fmt.Printf("组合收益: %s  基准收益: %s  超额收益: %s\n",
+		formatPercent(r.PortfolioReturn), formatPercent(r.BenchmarkReturn), formatPercent(r.ExcessReturn))
+	fmt.Printf("Beta: %.2f  Alpha(年化): %s  相关系数: %.2f\n", r.Beta, formatPercent(r.Alpha), r.Correlation)
+	fmt.Printf("配置效应: %s  选股效应: %s  残差: %s\n\n",
+		formatPercent(r.Allocation), formatPercent(r.Selection), formatPercent(r.Residual))
+
+	fmt.Printf("%-16s %9s %16s %10s %10s %10s %10s\n", "Industry", "Weight", "PnL", "Contrib.", "IndexRet", "Alloc.", "Select.")
+	fmt.Println("────────────────────────────────────────────────────────────────────────────────────────")
+	for _, e := range r.Industries {
+		fmt.Printf("%-16s %9s %16s %10s %10s %10s %10s\n", e.Industry, formatPercent(e.Weight), model.FormatAmount(e.PnL),
+			formatPercent(e.Contribution), formatPercent(e.BenchmarkReturn), formatPercent(e.Allocation), formatPercent(e.Selection))
+	}
+
+	fmt.Printf("\n%-10s %-12s %-12s %16s %10s\n", "Code", "Name", "Industry", "PnL", "Contrib.")
+	fmt.Println("──────────────────────────────────────────────────────────────────")
+	for i, s := range r.Stocks {
+		if attrTop > 0 && i >= attrTop {
+			fmt.Printf("... 共 %d 只股票\n", len(r.Stocks))
+			break
+		}
+		fmt.Printf("%-10s %-12s %-12s %16s %10s\n", s.Code, s.Name, s.Industry, model.FormatAmount(s.PnL), formatPercent(s.Contribution))
+	}
+
+	fmt.Printf("\n%-6s %-10s %-12s %-11s %12s %8s %-11s %16s %9s %10s\n",
+		"ID", "Code", "Name", "BuyDate", "BuyPrice", "Qty", "CloseDate", "PnL", "Return", "Contrib.")
+	fmt.Println("──────────────────────────────────────────────────────────────────────────────────────────────────────────────────────")
+	for i, t := range r.Trades {
+		if attrTop > 0 && i >= attrTop {
+			fmt.Printf("... 共 %d 笔买入\n", len(r.Trades))
+			break
+		}
+		buyDate, closeDate := t.BuyDate, t.CloseDate
+		if t.Opening {
+			buyDate += "*"
+		}
+		if closeDate == "" {
+			closeDate = "持有"
+		}
+		fmt.Printf("%-6d %-10s %-12s %-11s %12s %8d %-11s %16s %9s %10s\n",
+			t.TransactionID, t.Code, t.Name, buyDate, model.FormatAmount(t.BuyPrice), t.Quantity, closeDate,
+			model.FormatAmount(t.PnL), formatPercent(t.Return), formatPercent(t.Contribution))
+	}
+	for _, t := range r.Trades {
+		if t.Opening {
+			fmt.Println("\n* 区间开始前买入，成本与收益按期初市值计算")
+			break
+		}
+	}
+
+	for _, w := range r.Warnings {
+		fmt.Printf("提示: %s\n", w)
+	}
+}
+
+// formatPercent 小数格式化为百分比
+func formatPercent(v float64) string {
+	return fmt.Sprintf("%.2f%%", v*100)
+}
@@ -0,0 +1,45 @@
+package cmd_report
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+
+	msadb "msa/pkg/db"
+	"msa/pkg/logic/tools/finance"
+	"msa/pkg/model"
+)
+
+// accountName --account 参数的值
+var accountName string
+
+// NewCommand 创建 report 子命令
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "账户绩效报告",
+		Long:  `基于成交记录与历史K线生成账户绩效报告。`,
+	}
+
+	cmd.PersistentFlags().StringVar(&accountName, "account", "", "账户名称（默认使用当前账户）")
+
+	// 添加子命令
+	cmd.AddCommand(newAttributionCmd())
+
+	return cmd
+}
+
+// resolveAccount 获取数据库连接与 --account 指定的账户
+func resolveAccount() (*gorm.DB, *model.Account, error) {
+	database := msadb.GetDB()
+	if database == nil {
+		return nil, nil, fmt.Errorf("数据库未初始化")
+	}
+
+	account, err := finance.ResolveAccount(database, accountName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return database, account, nil
+}
@@ -10,7 +10,15 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
+	"msa/cmd/backtest"
 	"msa/cmd/config"
+	"msa/cmd/data"
+	"msa/cmd/monitor"
+	"msa/cmd/portfolio"
+	"msa/cmd/replay"
+	"msa/cmd/report"
+	"msa/cmd/schedule"
+	"msa/cmd/screen"
 	"msa/cmd/skill"
 	"msa/cmd/update"
 	"msa/cmd/version"
@@ -18,6 +26,8 @@ import (
 	"msa/pkg/config"
 	"msa/pkg/db"
 	"msa/pkg/extcli"
+	"msa/pkg/logic/finsvc"
+	"msa/pkg/logic/tools/finance"
 	"msa/pkg/session"
 	"msa/pkg/tui"
 	"msa/pkg/tui/style"
@@ -57,6 +67,14 @@ func init() {
 	AddCommand(cmd_skill.NewCommand())
 	AddCommand(cmd_version.NewCommand())
 	AddCommand(cmd_update.NewCommand())
+	AddCommand(cmd_portfolio.NewCommand())
+	AddCommand(cmd_data.NewCommand())
+	AddCommand(cmd_screen.NewCommand())
+	AddCommand(cmd_monitor.NewCommand())
+	AddCommand(cmd_schedule.NewCommand())
+	AddCommand(cmd_backtest.NewCommand())
+	AddCommand(cmd_replay.NewCommand())
+	AddCommand(cmd_report.NewCommand())
 }
 
 // runRoot 根命令执行函数，仅做路由调用
@@ -94,6 +112,9 @@ func runResume(ctx context.Context, sessionID string) error {
 
 	// 启动 TUI 并注入恢复的会话
 	p := tea.NewProgram(tui.NewChat(ctx, tui.WithResumeSession(parsed)))
+	monitorCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	app.StartWatchMonitor(monitorCtx, p)
 	if _, err := p.Run(); err != nil {
 		log.Errorf("运行 TUI 失败: %v", err)
 		return err
@@ -129,6 +150,29 @@ func ExecuteWithSignal(rootCmd *cobra.Command) {
 		log.Info("配置初始化成功")
 	}
 
+	// 注入交易费率配置
+	if cfg := config.GetLocalStoreConfig(); cfg != nil && cfg.FeeSchedule != nil {
+		finsvc.SetFeeSchedule(*cfg.FeeSchedule)
+		log.Infof("已加载自定义交易费率: %+v", *cfg.FeeSchedule)
+	}
+
+	// 注入持仓成本计算方法
+	if cfg := config.GetLocalStoreConfig(); cfg != nil && cfg.CostMethod != "" {
+		if method, err := finsvc.ParseCostMethod(cfg.CostMethod); err != nil {
+			log.Warnf("成本计算方法配置无效，使用默认加权平均: %v", err)
+		} else {
+			finsvc.SetCostMethod(method)
+			log.Infof("已加载成本计算方法: %s", method)
+		}
+	}
+
+	// 注入风控限制
+	if cfg := config.GetLocalStoreConfig(); cfg != nil && cfg.RiskPolicy != nil {
+		finsvc.SetRiskPolicy(*cfg.RiskPolicy)
+		log.Infof("已加载风控限制: %+v", *cfg.RiskPolicy)
+	}
+	finsvc.SetRiskDataSource(finance.RiskDataSource{})
+
 	// 注册数据库清理函数
 	defer func() {
 		if err := db.CloseGlobalDB(); err != nil {
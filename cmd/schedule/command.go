@@ -0,0 +1,232 @@
+package cmd_schedule
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"msa/pkg/config"
+	coreagent "msa/pkg/core/agent"
+	"msa/pkg/core/event"
+	"msa/pkg/core/runner"
+	msadb "msa/pkg/db"
+	"msa/pkg/logic/scheduler"
+	"msa/pkg/logic/skills"
+	"msa/pkg/model"
+	"msa/pkg/renderer"
+	"msa/pkg/session"
+)
+
+var (
+	scheduleSkills   []string
+	scheduleInterval time.Duration
+	scheduleOnce     bool
+)
+
+// NewCommand 创建 schedule 子命令
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schedule",
+		Short: "按 skill 触发时间段定时执行 skill",
+		Long: `读取 skill 声明的触发时间段（triggers.time，如 "13:00-14:30"、"16:00+"），
+在交易日的时间段内执行一次 skill，周末与交易所休市日跳过。
+每次执行保存为一个会话，执行记录写入数据库，重启后同一交易日不会重复执行。
+配置文件 schedule 段可指定参与调度的 skills 与额外的 holidays。`,
+		RunE: runList,
+	}
+
+	cmd.AddCommand(newRunCmd())
+	cmd.AddCommand(newListCmd())
+
+	return cmd
+}
+
+func newRunCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: "启动定时 skill 调度（常驻运行）",
+		Long: `常驻运行，按间隔检查各 skill 的触发时间段，到期且当日未执行的 skill 通过对话流程执行。按 Ctrl+C 退出。
+示例：msa schedule run --skill afternoon-trade,market-close-summary`,
+		RunE: runSchedule,
+	}
+
+	cmd.Flags().StringSliceVar(&scheduleSkills, "skill", nil, "只调度指定的 skill，可逗号分隔多个（默认使用配置文件 schedule.skills）")
+	cmd.Flags().DurationVar(&scheduleInterval, "interval", scheduler.DefaultInterval, "检查间隔")
+	cmd.Flags().BoolVar(&scheduleOnce, "once", false, "只检查一次后退出")
+
+	return cmd
+}
+
+func newListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "查看定时 skill 与执行状态",
+		RunE:  runList,
+	}
+
+	cmd.Flags().StringSliceVar(&scheduleSkills, "skill", nil, "只查看指定的 skill，可逗号分隔多个")
+
+	return cmd
+}
+
+// newScheduler 按已启用的 skill 与配置创建调度器
+func newScheduler(execute scheduler.ExecuteFunc) (*scheduler.Scheduler, error) {
+	manager := skills.GetManager()
+	if err := manager.Initialize(); err != nil {
+		log.Warnf("skills initialize warning: %v", err)
+	}
+
+	var scheduleCfg model.ScheduleConfig
+	if cfg := config.GetLocalStoreConfig(); cfg != nil && cfg.Schedule != nil {
+		scheduleCfg = *cfg.Schedule
+	}
+	if errs := config.ValidateSchedule(&scheduleCfg); len(errs) > 0 {
+		return nil, fmt.Errorf("%s: %s", errs[0].Field, errs[0].Message)
+	}
+
+	only := scheduleSkills
+	if len(only) == 0 {
+		only = scheduleCfg.Skills
+	}
+	for _, name := range only {
+		if _, err := manager.GetSkill(name); err != nil || manager.IsDisabled(name) {
+			return nil, fmt.Errorf("skill 不存在或已禁用: %s", name)
+		}
+	}
+
+	jobs := scheduler.JobsFromSkills(manager.ListSkills(), only)
+	if len(jobs) == 0 {
+		return nil, fmt.Errorf("没有声明触发时间段的 skill")
+	}
+	return scheduler.New(jobs, scheduleCfg.Holidays, execute), nil
+}
+
+func runList(cmd *cobra.Command, args []string) error {
+	database := msadb.GetDB()
+	if database == nil {
+		return fmt.Errorf("数据库未初始化")
+	}
+	s, err := newScheduler(nil)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	statuses, err := s.Status(database, now)
+	if err != nil {
+		return err
+	}
+	if !s.IsTradingDay(now) {
+		fmt.Println("今日非交易日")
+	}
+	fmt.Printf("%-24s %-12s %-20s %-16s %s\n", "Skill", "时间段", "Session", "今日状态", "最近执行")
+	for _, st := range statuses {
+		lastRun := "-"
+		if st.LastRun != nil {
+			lastRun = fmt.Sprintf("%s %s", st.LastRun.TradeDate, st.LastRun.Status)
+			if st.LastRun.SessionID != "" {
+				lastRun += "（会话 " + st.LastRun.SessionID + "）"
+			}
+		}
+		sessionTag := st.Job.Session
+		if sessionTag == "" {
+			sessionTag = "-"
+		}
+		fmt.Printf("%-24s %-12s %-20s %-16s %s\n", st.Job.Skill, st.Job.Window, sessionTag, st.State, lastRun)
+	}
+	return nil
+}
+
+func runSchedule(cmd *cobra.Command, args []string) error {
+	database := msadb.GetDB()
+	if database == nil {
+		return fmt.Errorf("数据库未初始化")
+	}
+	cfg := config.GetLocalStoreConfig()
+	if cfg == nil || cfg.APIKey == "" || cfg.BaseURL == "" || cfg.Model == "" {
+		return fmt.Errorf("执行 skill 需要先配置 API Key、Base URL 与模型，运行 'msa config'")
+	}
+
+	s, err := newScheduler(executeSkill)
+	if err != nil {
+		return err
+	}
+
+	ctx := cmd.Context()
+	onRun := func(run *model.ScheduleRun) {
+		fmt.Printf("\n[%s] %s %s", run.StartedAt.Format("15:04:05"), run.SkillName, run.Status)
+		if run.SessionID != "" {
+			fmt.Printf("，会话ID: %s", run.SessionID)
+		}
+		fmt.Println()
+	}
+
+	if scheduleOnce {
+		runs, err := s.Tick(ctx, database, time.Now())
+		for _, run := range runs {
+			onRun(run)
+		}
+		if err == nil && len(runs) == 0 {
+			fmt.Println("当前没有到期的 skill")
+		}
+		return err
+	}
+
+	fmt.Printf("定时 skill 调度已启动（%d 个 skill），按 Ctrl+C 退出\n", len(s.Jobs))
+	for _, job := range s.Jobs {
+		fmt.Printf("  %s  %s\n", job.Window, job.Skill)
+	}
+	if err := s.Run(ctx, database, scheduleInterval, onRun); err != nil {
+		return err
+	}
+	fmt.Println("定时 skill 调度已停止")
+	return nil
+}
+
+// executeSkill 在新会话中通过 runner 执行 skill，问题与回复写入会话文件
+func executeSkill(ctx context.Context, job scheduler.Job) (string, error) {
+	ag, err := coreagent.New(ctx)
+	if err != nil {
+		return "", fmt.Errorf("创建 Agent 失败: %w", err)
+	}
+
+	sessionMgr := session.GetManager()
+	sess := sessionMgr.NewSession(session.ModeSchedule)
+	if err := sessionMgr.CreateSessionFile(sess); err != nil {
+		log.Warnf("创建会话文件失败: %v", err)
+	}
+	sessionMgr.SetCurrent(sess)
+
+	question := fmt.Sprintf("定时任务（%s，触发时间段 %s）：请调用 get_skill_content 获取 %s 技能，并严格按其流程执行。",
+		time.Now().Format("2006-01-02 15:04"), job.Window, job.Skill)
+	sessionMgr.AppendMessage(sess, "user", question)
+
+	fmt.Printf("\n===== %s（%s）=====\n", job.Skill, job.Window)
+	rec := &recorder{inner: renderer.NewCLI(os.Stdout, false)}
+	askErr := runner.New(ag, sessionMgr, rec).Ask(ctx, question, nil)
+	if reply := strings.TrimSpace(rec.reply.String()); reply != "" {
+		sessionMgr.AppendMessage(sess, "assistant", reply)
+	}
+	if askErr == nil && ctx.Err() != nil {
+		askErr = fmt.Errorf("执行中断: %w", ctx.Err())
+	}
+	return sess.SessionID(), askErr
+}
+
+// recorder 转发事件到终端渲染器，同时记录完整回复用于写入会话
+type recorder struct {
+	inner renderer.Renderer
+	reply strings.Builder
+}
+
+func (r *recorder) Handle(ctx context.Context, e event.Event) error {
+	if e.Type == event.EventTextChunk {
+		r.reply.WriteString(e.Text)
+	}
+	return r.inner.Handle(ctx, e)
+}
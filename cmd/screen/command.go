@@ -0,0 +1,139 @@
+package cmd_screen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"msa/pkg/logic/screener"
+	"msa/pkg/logic/tools/stock"
+)
+
+var (
+	screenBoards    []string
+	screenCodes     []string
+	screenBoardType string
+	screenSortBy    string
+	screenAsc       bool
+	screenPage      int
+	screenPageSize  int
+	screenFields    bool
+)
+
+// NewCommand 创建 screen 子命令
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "screen [expression]",
+		Short: "按条件筛选股票",
+		Long: `以板块成分股（或指定代码）为股票池，按条件表达式筛选股票并排序分页。
+条件用 AND / OR / NOT 与括号组合，文本值需加引号；使用 --fields 查看可用字段。
+未指定 --board 与 --code 时，使用表达式中 board / industry 条件对应的板块，再没有时使用涨幅前 10 的行业板块。
+示例：msa screen 'pe < 20 AND change_5d > 3 AND industry = "半导体"' --sort change_5d`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runScreen,
+	}
+
+	cmd.Flags().StringSliceVar(&screenBoards, "board", nil, "板块名称或板块代码，可逗号分隔多个")
+	cmd.Flags().StringSliceVar(&screenCodes, "code", nil, "加入股票池的股票代码，可逗号分隔多个")
+	cmd.Flags().StringVar(&screenBoardType, "board-type", screener.DefaultBoardType, "板块类型：01 申万行业/02 概念/03 地域")
+	cmd.Flags().StringVar(&screenSortBy, "sort", screener.DefaultSortBy, "排序字段（数值字段）")
+	cmd.Flags().BoolVar(&screenAsc, "asc", false, "升序排序（默认降序）")
+	cmd.Flags().IntVar(&screenPage, "page", 1, "页码")
+	cmd.Flags().IntVar(&screenPageSize, "size", screener.DefaultPageSize, fmt.Sprintf("每页数量（最多 %d）", screener.MaxPageSize))
+	cmd.Flags().BoolVar(&screenFields, "fields", false, "列出可用字段")
+
+	return cmd
+}
+
+func runScreen(cmd *cobra.Command, args []string) error {
+	if screenFields {
+		printFields()
+		return nil
+	}
+
+	expression := ""
+	if len(args) > 0 {
+		expression = args[0]
+	}
+	result, err := stock.RunScreen(screener.Options{
+		Expression: expression,
+		Codes:      screenCodes,
+		Boards:     screenBoards,
+		BoardType:  screenBoardType,
+		SortBy:     screenSortBy,
+		Ascending:  screenAsc,
+		Page:       screenPage,
+		PageSize:   screenPageSize,
+	})
+	if err != nil {
+		return err
+	}
+
+	printResult(result, columns(expression, result.SortBy))
+	return nil
+}
+
+// columns 结果表中显示的数值字段：价格、涨跌幅、表达式引用的数值字段与排序字段
+func columns(expression, sortBy string) []string {
+	cols := []string{"price", "change_pct"}
+	var referenced []string
+	if expr, err := screener.Parse(expression); err == nil {
+		referenced = screener.Fields(expr)
+	}
+	for _, name := range append(referenced, sortBy) {
+		f, ok := screener.LookupField(name)
+		if !ok || f.Kind != screener.KindNumber {
+			continue
+		}
+		exists := false
+		for _, c := range cols {
+			exists = exists || c == name
+		}
+		if !exists {
+			cols = append(cols, name)
+		}
+	}
+	return cols
+}
+
+func printResult(result *screener.Result, cols []string) {
+	if len(result.Boards) > 0 {
+		fmt.Printf("板块: %s\n", strings.Join(result.Boards, "、"))
+	}
+	fmt.Printf("股票池 %d 只，符合条件 %d 只，按 %s %s 排序，第 %d/%d 页\n",
+		result.Universe, result.Matched, result.SortBy, result.Order, result.Page, max(result.TotalPages, 1))
+	for _, w := range result.Warnings {
+		fmt.Printf("提示: %s\n", w)
+	}
+	if len(result.Items) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("%-4s %-10s %-10s", "排名", "代码", "名称")
+	for _, c := range cols {
+		fmt.Printf(" %12s", c)
+	}
+	fmt.Println("  板块")
+	for _, item := range result.Items {
+		fmt.Printf("%-4d %-10s %-10s", item.Rank, item.Code, item.Name)
+		for _, c := range cols {
+			v, ok := item.Values[c]
+			text := "-"
+			if ok {
+				text = strconv.FormatFloat(v, 'f', 2, 64)
+			}
+			fmt.Printf(" %12s", text)
+		}
+		fmt.Printf("  %s\n", strings.Join(item.Boards, "、"))
+	}
+}
+
+func printFields() {
+	fmt.Printf("%-14s %-6s %-8s %s\n", "字段", "类型", "来源", "说明")
+	for _, f := range screener.ListFields() {
+		fmt.Printf("%-14s %-6s %-8s %s\n", f.Name, f.Kind, f.Source, f.Description)
+	}
+}
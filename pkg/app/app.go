@@ -16,6 +16,9 @@ import (
 func Run(ctx context.Context) error {
 	// 启动 TUI 程序
 	p := tea.NewProgram(tui.NewChat(ctx))
+	monitorCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	StartWatchMonitor(monitorCtx, p)
 	if _, err := p.Run(); err != nil {
 		log.Errorf("运行 TUI 失败: %v", err)
 		return err
@@ -0,0 +1,23 @@
+package app
+
+import (
+	"context"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"msa/pkg/core/event"
+	"msa/pkg/logic/tools/stock"
+	"msa/pkg/logic/watchlist"
+	"msa/pkg/model"
+)
+
+// StartWatchMonitor 在后台启动自选股提醒监控，触发的提醒作为事件发送给 TUI
+// ctx 结束时停止
+func StartWatchMonitor(ctx context.Context, p *tea.Program) {
+	monitor := watchlist.NewMonitor(stock.FetchStockData, func(stockCode string, count int) ([]model.KLineBar, error) {
+		return stock.FetchStockHistoryK(stockCode, "day", count, "qfq")
+	}).WithMinute(stock.FetchStockMinuteData)
+	go monitor.Run(ctx, watchlist.DefaultInterval, func(t watchlist.Triggered) {
+		p.Send(event.Event{Type: event.EventAlert, Text: t.Message})
+	})
+}
@@ -8,7 +8,8 @@ import (
 )
 
 // LoadFromEnv 从环境变量加载配置
-// 环境变量：MSA_PROVIDER, MSA_API_KEY, MSA_BASE_URL, MSA_LOG_LEVEL, MSA_LOG_FILE
+// 环境变量：MSA_PROVIDER, MSA_API_KEY, MSA_BASE_URL, MSA_LOG_LEVEL, MSA_LOG_FILE,
+// MSA_MARKET_DATA_SOURCE, MSA_MARKET_DATA_DIR
 func LoadFromEnv() *LocalStoreConfig {
 	cfg := &LocalStoreConfig{}
 
@@ -48,8 +49,22 @@ func LoadFromEnv() *LocalStoreConfig {
 		cfg.LogConfig.File = logFile
 	}
 
+	// MSA_MARKET_DATA_SOURCE / MSA_MARKET_DATA_DIR
+	source := os.Getenv("MSA_MARKET_DATA_SOURCE")
+	dataDir := os.Getenv("MSA_MARKET_DATA_DIR")
+	if source != "" || dataDir != "" {
+		cfg.MarketData = &model.MarketDataConfig{
+			Source: model.MarketDataSource(strings.ToLower(source)),
+			Dir:    dataDir,
+		}
+		// 只指定目录时默认使用本地数据源
+		if cfg.MarketData.Source == "" {
+			cfg.MarketData.Source = model.MarketDataLocal
+		}
+	}
+
 	// 如果没有任何环境变量被设置，返回 nil
-	if cfg.Provider == "" && cfg.APIKey == "" && cfg.BaseURL == "" && cfg.LogConfig == nil {
+	if cfg.Provider == "" && cfg.APIKey == "" && cfg.BaseURL == "" && cfg.LogConfig == nil && cfg.MarketData == nil {
 		return nil
 	}
 
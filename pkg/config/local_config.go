@@ -29,6 +29,18 @@ type LocalStoreConfig struct {
 	Provider  model.LlmProvider `json:"provider"`
 	Model     string            `json:"model"`
 	LogConfig *LogConfig        `json:"logConfig,omitempty"`
+	// FeeSchedule 交易费率，为空时使用默认费率
+	FeeSchedule *model.FeeSchedule `json:"feeSchedule,omitempty"`
+	// CostMethod 持仓成本计算方法（FIFO / AVERAGE），为空时使用加权平均
+	CostMethod string `json:"costMethod,omitempty"`
+	// MarketData 行情数据源，为空时使用腾讯行情
+	MarketData *model.MarketDataConfig `json:"marketData,omitempty"`
+	// Monitor 盘中监控配置，为空时使用默认间隔且不调用 skill
+	Monitor *model.MonitorConfig `json:"monitor,omitempty"`
+	// Schedule 定时 skill 配置，为空时调度所有声明了触发时间段的 skill
+	Schedule *model.ScheduleConfig `json:"schedule,omitempty"`
+	// RiskPolicy 下单前风控硬限制，为空时不限制
+	RiskPolicy *model.RiskPolicy `json:"riskPolicy,omitempty"`
 }
 
 // GetLocalStoreConfig 获取本地存储配置（带缓存）
@@ -329,6 +341,30 @@ func (b *ConfigBuilder) merge(base, override *LocalStoreConfig) *LocalStoreConfi
 		result.Model = override.Model
 	}
 
+	// 交易费率整体覆盖
+	if override.FeeSchedule != nil {
+		result.FeeSchedule = override.FeeSchedule
+	}
+	if override.CostMethod != "" {
+		result.CostMethod = override.CostMethod
+	}
+	// 行情数据源整体覆盖
+	if override.MarketData != nil {
+		result.MarketData = override.MarketData
+	}
+	// 盘中监控配置整体覆盖
+	if override.Monitor != nil {
+		result.Monitor = override.Monitor
+	}
+	// 定时 skill 配置整体覆盖
+	if override.Schedule != nil {
+		result.Schedule = override.Schedule
+	}
+	// 风控限制整体覆盖
+	if override.RiskPolicy != nil {
+		result.RiskPolicy = override.RiskPolicy
+	}
+
 	// 合并 LogConfig
 	if override.LogConfig != nil {
 		if result.LogConfig == nil {
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"msa/pkg/model"
 )
@@ -209,6 +210,198 @@ func ValidateProvider(provider string) []*ValidationError {
 	return errors
 }
 
+// ValidateFeeSchedule 验证交易费率
+// 费率与最低佣金不能为负数，费率超过 1% 时给出警告（可能误填为百分数）
+func ValidateFeeSchedule(schedule *model.FeeSchedule) []*ValidationError {
+	var errors []*ValidationError
+
+	rates := []struct {
+		name  string
+		value float64
+	}{
+		{"佣金费率", schedule.CommissionRate},
+		{"印花税费率", schedule.StampDutyRate},
+		{"过户费费率", schedule.TransferFeeRate},
+		{"港股印花税费率", schedule.HKStampDutyRate},
+		{"港股交易征费费率", schedule.HKTradingLevyRate},
+		{"港股交易费费率", schedule.HKTradingFeeRate},
+		{"港股会财局征费费率", schedule.HKFRCLevyRate},
+	}
+	for _, r := range rates {
+		if r.value < 0 {
+			errors = append(errors, &ValidationError{
+				Field:    "交易费率",
+				Message:  fmt.Sprintf("%s不能为负数（当前值: %v）", r.name, r.value),
+				Severity: SeverityError,
+			})
+		} else if r.value > 0.01 {
+			errors = append(errors, &ValidationError{
+				Field:    "交易费率",
+				Message:  fmt.Sprintf("%s超过 1%%，请确认是否填写为小数（当前值: %v）", r.name, r.value),
+				Severity: SeverityWarning,
+			})
+		}
+	}
+
+	if schedule.MinCommission < 0 {
+		errors = append(errors, &ValidationError{
+			Field:    "交易费率",
+			Message:  fmt.Sprintf("最低佣金不能为负数（当前值: %v）", schedule.MinCommission),
+			Severity: SeverityError,
+		})
+	}
+
+	return errors
+}
+
+// ValidateCostMethod 验证持仓成本计算方法
+func ValidateCostMethod(method string) []*ValidationError {
+	switch strings.ToUpper(strings.TrimSpace(method)) {
+	case "FIFO", "AVERAGE":
+		return nil
+	default:
+		return []*ValidationError{{
+			Field:    "成本计算方法",
+			Message:  fmt.Sprintf("不支持的成本计算方法: %s（可选: FIFO, AVERAGE）", method),
+			Severity: SeverityError,
+		}}
+	}
+}
+
+// ValidateMarketData 验证行情数据源
+func ValidateMarketData(cfg *model.MarketDataConfig) []*ValidationError {
+	switch cfg.Source {
+	case "", model.MarketDataTencent:
+		return nil
+	case model.MarketDataLocal:
+		if cfg.Dir == "" {
+			return nil
+		}
+		dir := cfg.Dir
+		if strings.HasPrefix(dir, "~") {
+			if homeDir, err := os.UserHomeDir(); err == nil {
+				dir = filepath.Join(homeDir, dir[1:])
+			}
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return []*ValidationError{{
+				Field:    "行情数据目录",
+				Message:  fmt.Sprintf("目录不存在: %s", cfg.Dir),
+				Severity: SeverityWarning,
+			}}
+		}
+		return nil
+	default:
+		return []*ValidationError{{
+			Field:    "行情数据源",
+			Message:  fmt.Sprintf("不支持的行情数据源: %s（可选: %s, %s）", cfg.Source, model.MarketDataTencent, model.MarketDataLocal),
+			Severity: SeverityError,
+		}}
+	}
+}
+
+// MinMonitorInterval 盘中监控的最小检查间隔，避免频繁请求行情接口
+const MinMonitorInterval = 10 * time.Second
+
+// ValidateMonitor 验证盘中监控配置
+func ValidateMonitor(cfg *model.MonitorConfig) []*ValidationError {
+	var errs []*ValidationError
+	if cfg.Interval != "" {
+		interval, err := time.ParseDuration(cfg.Interval)
+		if err != nil {
+			errs = append(errs, &ValidationError{
+				Field:    "监控间隔",
+				Message:  fmt.Sprintf("时长格式无效: %s（示例: 30s, 1m）", cfg.Interval),
+				Severity: SeverityError,
+			})
+		} else if interval < MinMonitorInterval {
+			errs = append(errs, &ValidationError{
+				Field:    "监控间隔",
+				Message:  fmt.Sprintf("监控间隔不能小于 %s", MinMonitorInterval),
+				Severity: SeverityError,
+			})
+		}
+	}
+	if cfg.SkillCooldown != "" {
+		if cooldown, err := time.ParseDuration(cfg.SkillCooldown); err != nil || cooldown < 0 {
+			errs = append(errs, &ValidationError{
+				Field:    "skill 调用间隔",
+				Message:  fmt.Sprintf("时长格式无效: %s（示例: 15m）", cfg.SkillCooldown),
+				Severity: SeverityError,
+			})
+		}
+	}
+	return errs
+}
+
+// ValidateSchedule 验证定时 skill 配置
+func ValidateSchedule(cfg *model.ScheduleConfig) []*ValidationError {
+	var errs []*ValidationError
+	for _, day := range cfg.Holidays {
+		if _, err := time.Parse(time.DateOnly, day); err != nil {
+			errs = append(errs, &ValidationError{
+				Field:    "休市日期",
+				Message:  fmt.Sprintf("日期格式无效: %s（格式: YYYY-MM-DD）", day),
+				Severity: SeverityError,
+			})
+		}
+	}
+	return errs
+}
+
+// ValidateRiskPolicy 验证风控限制
+// 比例为百分数，须在 0-100 之间；小于 1 时给出警告（可能误填为小数）
+func ValidateRiskPolicy(policy *model.RiskPolicy) []*ValidationError {
+	var errs []*ValidationError
+
+	percents := []struct {
+		name  string
+		value float64
+	}{
+		{"单股仓位上限", policy.MaxPositionPct},
+		{"行业仓位上限", policy.MaxIndustryPct},
+		{"最低现金保留比例", policy.MinCashReservePct},
+	}
+	for _, p := range percents {
+		if p.value < 0 || p.value > 100 {
+			errs = append(errs, &ValidationError{
+				Field:    "风控限制",
+				Message:  fmt.Sprintf("%s须在 0-100 之间（当前值: %v）", p.name, p.value),
+				Severity: SeverityError,
+			})
+		} else if p.value > 0 && p.value < 1 {
+			errs = append(errs, &ValidationError{
+				Field:    "风控限制",
+				Message:  fmt.Sprintf("%s小于 1%%，请确认是否填写为百分数（当前值: %v）", p.name, p.value),
+				Severity: SeverityWarning,
+			})
+		}
+	}
+
+	if policy.MaxDailyTurnoverPct < 0 {
+		errs = append(errs, &ValidationError{
+			Field:    "风控限制",
+			Message:  fmt.Sprintf("当日成交额上限不能为负数（当前值: %v）", policy.MaxDailyTurnoverPct),
+			Severity: SeverityError,
+		})
+	}
+	if policy.MaxOrdersPerDay < 0 {
+		errs = append(errs, &ValidationError{
+			Field:    "风控限制",
+			Message:  fmt.Sprintf("当日委托笔数上限不能为负数（当前值: %d）", policy.MaxOrdersPerDay),
+			Severity: SeverityError,
+		})
+	}
+	if policy.MaxPositionPct > 0 && policy.MaxIndustryPct > 0 && policy.MaxIndustryPct < policy.MaxPositionPct {
+		errs = append(errs, &ValidationError{
+			Field:    "风控限制",
+			Message:  fmt.Sprintf("行业仓位上限 %v%% 小于单股仓位上限 %v%%", policy.MaxIndustryPct, policy.MaxPositionPct),
+			Severity: SeverityWarning,
+		})
+	}
+	return errs
+}
+
 // ValidateConfig 验证完整配置
 func ValidateConfig(cfg *LocalStoreConfig) []*ValidationError {
 	var allErrors []*ValidationError
@@ -230,6 +423,36 @@ func ValidateConfig(cfg *LocalStoreConfig) []*ValidationError {
 		allErrors = append(allErrors, ValidateLogPath(cfg.LogConfig.File)...)
 	}
 
+	// 验证交易费率
+	if cfg.FeeSchedule != nil {
+		allErrors = append(allErrors, ValidateFeeSchedule(cfg.FeeSchedule)...)
+	}
+
+	// 验证成本计算方法
+	if cfg.CostMethod != "" {
+		allErrors = append(allErrors, ValidateCostMethod(cfg.CostMethod)...)
+	}
+
+	// 验证行情数据源
+	if cfg.MarketData != nil {
+		allErrors = append(allErrors, ValidateMarketData(cfg.MarketData)...)
+	}
+
+	// 验证盘中监控配置
+	if cfg.Monitor != nil {
+		allErrors = append(allErrors, ValidateMonitor(cfg.Monitor)...)
+	}
+
+	// 验证定时 skill 配置
+	if cfg.Schedule != nil {
+		allErrors = append(allErrors, ValidateSchedule(cfg.Schedule)...)
+	}
+
+	// 验证风控限制
+	if cfg.RiskPolicy != nil {
+		allErrors = append(allErrors, ValidateRiskPolicy(cfg.RiskPolicy)...)
+	}
+
 	// 按严重程度排序（错误在前，警告在后）
 	sortErrors(allErrors)
 
@@ -7,6 +7,7 @@ import (
 	"msa/pkg/core/event"
 	"msa/pkg/logic/skills"
 	"msa/pkg/utils"
+	"strings"
 	"testing"
 	"time"
 )
@@ -27,10 +28,13 @@ func TestAgent_Run(t *testing.T) {
 	// Build query messages (system prompt + history + user input)
 	now := time.Now()
 	messages, err := BuildQueryMessages(ctx, "查看一下我的账户", []*schema.Message{}, map[string]any{
-		"role":    "专业股票分析助手",
-		"style":   "理性、专业、客观且严谨",
-		"time":    now.Format("2006-01-02 15:04:05"),
-		"weekday": now.Format("2006年01月02日 星期Monday"),
+		"role":               "专业股票分析助手",
+		"style":              "理性、专业、客观且严谨",
+		"time":               now.Format("2006-01-02 15:04:05"),
+		"weekday":            now.Format("2006年01月02日 星期Monday"),
+		"is_trading_day":     true,
+		"session_phase":      "上午连续交易",
+		"latest_trading_day": now.Format("2006-01-02"),
 	})
 	if err != nil {
 		t.Fatalf("build query messages error: %v", err)
@@ -57,3 +61,29 @@ func TestAgent_Run(t *testing.T) {
 
 	log.Infof("process: %v", utils.ToJSONString(process))
 }
+
+func TestBuildQueryMessages_TradingDay(t *testing.T) {
+	vars := map[string]any{
+		"role":               "专业股票分析助手",
+		"style":              "理性、专业、客观且严谨",
+		"time":               "2025-09-28 10:00:00",
+		"weekday":            "2025年09月28日 星期Sunday",
+		"is_trading_day":     false,
+		"session_phase":      "休市",
+		"latest_trading_day": "2025-09-26",
+	}
+	messages, err := BuildQueryMessages(context.Background(), "今日复盘", nil, vars)
+	if err != nil || len(messages) == 0 {
+		t.Fatalf("build query messages error: %v", err)
+	}
+	if system := messages[0].Content; !strings.Contains(system, "A股交易日：否（今日休市，最近交易日为 2025-09-26）") {
+		t.Errorf("Expected non-trading day notice in system prompt")
+	}
+
+	vars["is_trading_day"] = true
+	vars["session_phase"] = "午间休市"
+	messages, _ = BuildQueryMessages(context.Background(), "今日复盘", nil, vars)
+	if !strings.Contains(messages[0].Content, "A股交易日：是，当前交易阶段：午间休市") {
+		t.Errorf("Expected session phase in system prompt")
+	}
+}
@@ -22,13 +22,16 @@ const (
 	// 会话控制
 	EventRoundDone // 一轮对话完成（agent 不再调用工具）
 	EventError     // 不可恢复的错误，pipeline 终止
+
+	// 后台通知（不属于对话流）
+	EventAlert // 自选股提醒触发（Text 为提醒内容）
 )
 
 // Event 是 pipeline 中流动的最小单元
 type Event struct {
 	Type EventType
 
-	// EventTextChunk / EventThinking
+	// EventTextChunk / EventThinking / EventAlert
 	Text string
 
 	// EventToolStart
@@ -7,13 +7,13 @@ import (
 	"fmt"
 	"msa/pkg/utils"
 	"strings"
-	"time"
 
 	"github.com/cloudwego/eino/schema"
 
 	"msa/pkg/core/agent"
 	"msa/pkg/core/event"
 	corelogger "msa/pkg/core/logger"
+	"msa/pkg/logic/calendar"
 	"msa/pkg/logic/skills"
 	"msa/pkg/model"
 	"msa/pkg/renderer"
@@ -58,13 +58,16 @@ func (r *Runner) Ask(ctx context.Context, input string, history []model.Message)
 	schemaHistory := convertToSchemaMessages(filteredHistory)
 
 	// Build query messages (system prompt + history + user input)
-	now := time.Now()
+	now := calendar.Now()
 	log.Infof("[Runner] 构建消息开始 ，历史消息 %d 条", len(schemaHistory))
 	messages, err := agent.BuildQueryMessages(ctx, input, schemaHistory, map[string]any{
-		"role":    "专业股票分析助手",
-		"style":   "理性、专业、客观且严谨",
-		"time":    now.Format("2006-01-02 15:04:05"),
-		"weekday": now.Format("2006年01月02日 星期Monday"),
+		"role":               "专业股票分析助手",
+		"style":              "理性、专业、客观且严谨",
+		"time":               now.Format("2006-01-02 15:04:05"),
+		"weekday":            now.Format("2006年01月02日 星期Monday"),
+		"is_trading_day":     calendar.IsTradingDay(calendar.MarketCN, now),
+		"session_phase":      calendar.PhaseAt(calendar.MarketCN, now).Label(),
+		"latest_trading_day": calendar.DateOf(calendar.LatestTradingDay(calendar.MarketCN, now)),
 	})
 	if err != nil {
 		return fmt.Errorf("构建消息失败: %w", err)
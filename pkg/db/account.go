@@ -9,11 +9,24 @@ import (
 	"msa/pkg/model"
 )
 
-// CreateAccount 创建新账户
+// CreateAccount 创建新账户，账户名称与用户ID相同
 // 返回新创建的账户ID和可能的错误
 func CreateAccount(db *gorm.DB, userID string, initialAmount int64) (uint, error) {
+	return CreateNamedAccount(db, userID, userID, initialAmount)
+}
+
+// CreateNamedAccount 创建指定名称的账户
+// 账户名称全局唯一；不存在当前账户时，新账户自动成为当前账户
+func CreateNamedAccount(db *gorm.DB, userID string, name string, initialAmount int64) (uint, error) {
+	var currentCount int64
+	if err := db.Model(&model.Account{}).Where("is_current = ?", true).Count(&currentCount).Error; err != nil {
+		return 0, fmt.Errorf("failed to count current account: %w", err)
+	}
+
 	account := &model.Account{
 		UserID:        userID,
+		Name:          name,
+		IsCurrent:     currentCount == 0,
 		InitialAmount: initialAmount,
 		AvailableAmt:  initialAmount,
 		LockedAmt:     0,
@@ -42,6 +55,48 @@ func GetAccountByUserID(db *gorm.DB, userID string) (*model.Account, error) {
 	return &account, nil
 }
 
+// GetAccountByName 根据账户名称查询账户
+func GetAccountByName(db *gorm.DB, name string) (*model.Account, error) {
+	var account model.Account
+	result := db.Where("name = ?", name).First(&account)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("account not found: %s", name)
+		}
+		return nil, fmt.Errorf("failed to get account: %w", result.Error)
+	}
+
+	return &account, nil
+}
+
+// ListAccounts 查询所有账户，按创建顺序排列
+func ListAccounts(db *gorm.DB) ([]*model.Account, error) {
+	var accounts []*model.Account
+	if err := db.Order("id ASC").Find(&accounts).Error; err != nil {
+		return nil, fmt.Errorf("failed to list accounts: %w", err)
+	}
+	return accounts, nil
+}
+
+// SetCurrentAccount 将指定账户设为当前账户，其余账户取消当前标记
+func SetCurrentAccount(db *gorm.DB, id uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Account{}).Where("is_current = ?", true).Update("is_current", false).Error; err != nil {
+			return fmt.Errorf("failed to reset current account: %w", err)
+		}
+
+		result := tx.Model(&model.Account{}).Where("id = ?", id).Update("is_current", true)
+		if result.Error != nil {
+			return fmt.Errorf("failed to set current account: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("account not found: %d", id)
+		}
+		return nil
+	})
+}
+
 // GetAccountByID 根据账户ID查询账户
 func GetAccountByID(db *gorm.DB, id uint) (*model.Account, error) {
 	var account model.Account
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"msa/pkg/model"
+)
+
+// CreateCashFlow 在事务中创建资金流水
+func CreateCashFlow(tx *gorm.DB, flow *model.CashFlow) (uint, error) {
+	if err := tx.Create(flow).Error; err != nil {
+		return 0, fmt.Errorf("failed to create cash flow: %w", err)
+	}
+
+	return flow.ID, nil
+}
+
+// GetCashFlowsByAccount 按账户ID查询资金流水
+func GetCashFlowsByAccount(db *gorm.DB, accountID uint) ([]*model.CashFlow, error) {
+	var flows []*model.CashFlow
+	result := db.Where("account_id = ?", accountID).Order("created_at DESC").Find(&flows)
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to query cash flows: %w", result.Error)
+	}
+
+	return flows, nil
+}
+
+// CreateCorporateAction 在事务中创建公司行动记录
+func CreateCorporateAction(tx *gorm.DB, action *model.CorporateAction) (uint, error) {
+	if err := tx.Create(action).Error; err != nil {
+		return 0, fmt.Errorf("failed to create corporate action: %w", err)
+	}
+
+	return action.ID, nil
+}
+
+// GetCorporateActionsByAccount 按账户ID查询公司行动记录
+func GetCorporateActionsByAccount(db *gorm.DB, accountID uint) ([]*model.CorporateAction, error) {
+	var actions []*model.CorporateAction
+	result := db.Where("account_id = ?", accountID).Order("created_at DESC").Find(&actions)
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to query corporate actions: %w", result.Error)
+	}
+
+	return actions, nil
+}
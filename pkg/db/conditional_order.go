@@ -0,0 +1,52 @@
+package db
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"msa/pkg/model"
+)
+
+// CreateConditionalOrder 在事务中创建条件单
+func CreateConditionalOrder(tx *gorm.DB, order *model.ConditionalOrder) (uint, error) {
+	if err := tx.Create(order).Error; err != nil {
+		return 0, fmt.Errorf("failed to create conditional order: %w", err)
+	}
+
+	return order.ID, nil
+}
+
+// GetConditionalOrderByID 按ID查询条件单
+func GetConditionalOrderByID(db *gorm.DB, id uint) (*model.ConditionalOrder, error) {
+	var order model.ConditionalOrder
+	if err := db.First(&order, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to get conditional order: %w", err)
+	}
+
+	return &order, nil
+}
+
+// GetConditionalOrdersByAccount 按账户ID查询条件单，status 为空时查询全部
+func GetConditionalOrdersByAccount(db *gorm.DB, accountID uint, status model.ConditionalOrderStatus) ([]*model.ConditionalOrder, error) {
+	query := db.Where("account_id = ?", accountID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var orders []*model.ConditionalOrder
+	if err := query.Order("id ASC").Find(&orders).Error; err != nil {
+		return nil, fmt.Errorf("failed to query conditional orders: %w", err)
+	}
+
+	return orders, nil
+}
+
+// SaveConditionalOrder 保存条件单全部字段
+func SaveConditionalOrder(db *gorm.DB, order *model.ConditionalOrder) error {
+	if err := db.Save(order).Error; err != nil {
+		return fmt.Errorf("failed to save conditional order: %w", err)
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package db
 import (
 	"path/filepath"
 	"testing"
+	"time"
 
 	"gorm.io/gorm"
 
@@ -209,3 +210,207 @@ func TestUpdateAccountStatus(t *testing.T) {
 		t.Errorf("expected status %s, got %s", model.AccountStatusFrozen, account.Status)
 	}
 }
+
+// TestCreateNamedAccount 测试同一用户创建多个命名账户
+func TestCreateNamedAccount(t *testing.T) {
+	database := setupTestDB(t)
+	defer CloseDB(database)
+
+	conservativeID, err := CreateNamedAccount(database, "default", "conservative", 100000)
+	if err != nil {
+		t.Fatalf("CreateNamedAccount failed: %v", err)
+	}
+	momentumID, err := CreateNamedAccount(database, "default", "momentum", 200000)
+	if err != nil {
+		t.Fatalf("CreateNamedAccount with same user failed: %v", err)
+	}
+
+	// 名称唯一
+	if _, err := CreateNamedAccount(database, "other", "momentum", 100000); err == nil {
+		t.Error("expected error when creating duplicate account name, got nil")
+	}
+
+	// 第一个账户自动成为当前账户
+	conservative, _ := GetAccountByID(database, conservativeID)
+	momentum, _ := GetAccountByName(database, "momentum")
+	if !conservative.IsCurrent || momentum.IsCurrent {
+		t.Errorf("expected only first account to be current, got %v/%v", conservative.IsCurrent, momentum.IsCurrent)
+	}
+
+	// 切换当前账户
+	if err := SetCurrentAccount(database, momentumID); err != nil {
+		t.Fatalf("SetCurrentAccount failed: %v", err)
+	}
+	conservative, _ = GetAccountByID(database, conservativeID)
+	momentum, _ = GetAccountByID(database, momentumID)
+	if conservative.IsCurrent || !momentum.IsCurrent {
+		t.Errorf("expected momentum to be current, got %v/%v", conservative.IsCurrent, momentum.IsCurrent)
+	}
+
+	accounts, err := ListAccounts(database)
+	if err != nil {
+		t.Fatalf("ListAccounts failed: %v", err)
+	}
+	if len(accounts) != 2 || accounts[0].Name != "conservative" || accounts[1].Name != "momentum" {
+		t.Errorf("unexpected accounts: %+v", accounts)
+	}
+}
+
+// TestMigrate_LegacyAccounts 测试单账户版本账户表的迁移
+func TestMigrate_LegacyAccounts(t *testing.T) {
+	database, err := InitDBWithPath(filepath.Join(t.TempDir(), "legacy.sqlite"))
+	if err != nil {
+		t.Fatalf("InitDBWithPath failed: %v", err)
+	}
+	defer CloseDB(database)
+
+	// 旧版表结构：user_id 唯一，无账户名称
+	database.Exec(`CREATE TABLE accounts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT, created_at DATETIME, updated_at DATETIME, deleted_at DATETIME,
+		user_id TEXT NOT NULL, initial_amount INTEGER NOT NULL, available_amt INTEGER NOT NULL DEFAULT 0,
+		locked_amt INTEGER NOT NULL DEFAULT 0, status TEXT NOT NULL DEFAULT 'ACTIVE')`)
+	database.Exec("CREATE UNIQUE INDEX idx_accounts_user_id ON accounts(user_id)")
+	database.Exec("INSERT INTO accounts (user_id, initial_amount, available_amt) VALUES ('default', 100000, 100000)")
+
+	if err := Migrate(database); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	legacy, err := GetAccountByName(database, "default")
+	if err != nil {
+		t.Fatalf("expected legacy account to be named by user_id: %v", err)
+	}
+	if legacy.AvailableAmt != 100000 {
+		t.Errorf("expected legacy balance kept, got %d", legacy.AvailableAmt)
+	}
+
+	// user_id 不再唯一
+	if _, err := CreateNamedAccount(database, "default", "momentum", 100000); err != nil {
+		t.Errorf("expected second account for same user, got %v", err)
+	}
+
+	// 重复迁移幂等
+	if err := Migrate(database); err != nil {
+		t.Errorf("second Migrate failed: %v", err)
+	}
+}
+
+// TestKLineCache 测试K线缓存的写入、覆盖与查询
+func TestKLineCache(t *testing.T) {
+	database := setupTestDB(t)
+	defer CloseDB(database)
+
+	series, err := GetKLineSeries(database, "sh600519", "day", "qfq")
+	if err != nil || series != nil {
+		t.Fatalf("expected no series, got %+v, %v", series, err)
+	}
+
+	bar := func(date, closePrice string) model.KLineBar {
+		return model.KLineBar{Date: date, Open: "10", Close: closePrice, High: "11", Low: "9", Volume: "100"}
+	}
+	series = &model.KLineSeries{StockCode: "sh600519", Period: "day", Adjust: "qfq"}
+	bars := []model.KLineBar{bar("2025-03-03", "10"), bar("2025-03-04", "10.5"), bar("2025-03-05", "10.2")}
+	if err := SaveKLineBars(database, series, bars, ""); err != nil {
+		t.Fatalf("SaveKLineBars failed: %v", err)
+	}
+	if series.BarCount != 3 || series.FirstDate != "2025-03-03" || series.LastDate != "2025-03-05" {
+		t.Errorf("unexpected series: %+v", series)
+	}
+
+	// 覆盖锚点之后的K线
+	if err := SaveKLineBars(database, series, []model.KLineBar{bar("2025-03-05", "10.3"), bar("2025-03-06", "10.8")}, "2025-03-04"); err != nil {
+		t.Fatalf("SaveKLineBars failed: %v", err)
+	}
+	got, err := GetKLineBars(database, "sh600519", "day", "qfq", 2)
+	if err != nil {
+		t.Fatalf("GetKLineBars failed: %v", err)
+	}
+	if len(got) != 2 || got[0].Date != "2025-03-05" || got[0].Close != "10.3" || got[1].Date != "2025-03-06" {
+		t.Errorf("unexpected bars: %+v", got)
+	}
+
+	stored, _ := GetKLineSeries(database, "sh600519", "day", "qfq")
+	if stored == nil || stored.BarCount != 4 || stored.LastDate != "2025-03-06" {
+		t.Errorf("unexpected stored series: %+v", stored)
+	}
+
+	// 其他复权方式互不影响
+	if other, _ := GetKLineBars(database, "sh600519", "day", "", 0); len(other) != 0 {
+		t.Errorf("expected no unadjusted bars, got %d", len(other))
+	}
+	list, err := ListKLineSeries(database, "")
+	if err != nil || len(list) != 1 {
+		t.Errorf("expected 1 series, got %d, %v", len(list), err)
+	}
+}
+
+// TestWatchlist 测试自选股与提醒规则
+func TestWatchlist(t *testing.T) {
+	database := setupTestDB(t)
+	defer CloseDB(database)
+
+	if item, err := GetWatchlistByCode(database, "sh600519"); err != nil || item != nil {
+		t.Fatalf("expected no item, got %+v, %v", item, err)
+	}
+
+	item := &model.Watchlist{StockCode: "sh600519", StockName: "贵州茅台", Tags: "白酒,消费"}
+	if err := SaveWatchlist(database, item); err != nil {
+		t.Fatalf("SaveWatchlist failed: %v", err)
+	}
+	if err := SaveWatchlist(database, &model.Watchlist{StockCode: "sh600519", StockName: "贵州茅台"}); err == nil {
+		t.Error("expected error for duplicate stock code")
+	}
+	if _, err := CreateWatchAlert(database, &model.WatchAlert{StockCode: "sh600519", Type: model.WatchAlertTypePriceBelow, Price: 15000000}); err != nil {
+		t.Fatalf("CreateWatchAlert failed: %v", err)
+	}
+	alerts, err := GetWatchAlerts(database, "sh600519", model.WatchAlertStatusActive)
+	if err != nil || len(alerts) != 1 {
+		t.Fatalf("expected 1 active alert, got %d, %v", len(alerts), err)
+	}
+
+	// 删除后可重新加入，提醒规则一并删除
+	if err := DeleteWatchlist(database, "sh600519"); err != nil {
+		t.Fatalf("DeleteWatchlist failed: %v", err)
+	}
+	if alerts, _ := GetWatchAlerts(database, "", ""); len(alerts) != 0 {
+		t.Errorf("expected alerts deleted, got %d", len(alerts))
+	}
+	if err := SaveWatchlist(database, &model.Watchlist{StockCode: "sh600519", StockName: "贵州茅台"}); err != nil {
+		t.Errorf("expected re-add after delete, got %v", err)
+	}
+	if items, _ := ListWatchlist(database); len(items) != 1 || len(item.TagList()) != 2 {
+		t.Errorf("unexpected watchlist: %d items, tags %v", len(items), item.TagList())
+	}
+}
+
+func TestScheduleRun(t *testing.T) {
+	database := setupTestDB(t)
+	defer CloseDB(database)
+
+	if run, err := GetScheduleRun(database, "afternoon-trade", "2025-03-03"); err != nil || run != nil {
+		t.Fatalf("expected no run, got %+v, %v", run, err)
+	}
+
+	run := &model.ScheduleRun{SkillName: "afternoon-trade", TradeDate: "2025-03-03", Window: "13:00-14:30", StartedAt: time.Now()}
+	if err := CreateScheduleRun(database, run); err != nil {
+		t.Fatalf("CreateScheduleRun failed: %v", err)
+	}
+	if err := CreateScheduleRun(database, &model.ScheduleRun{SkillName: "afternoon-trade", TradeDate: "2025-03-03", Window: "13:00-14:30", StartedAt: time.Now()}); err == nil {
+		t.Error("expected error for duplicate run on same trade date")
+	}
+	run.Status = model.ScheduleRunStatusSuccess
+	if err := SaveScheduleRun(database, run); err != nil {
+		t.Fatalf("SaveScheduleRun failed: %v", err)
+	}
+	CreateScheduleRun(database, &model.ScheduleRun{SkillName: "afternoon-trade", TradeDate: "2025-03-04", Window: "13:00-14:30", StartedAt: time.Now()})
+	CreateScheduleRun(database, &model.ScheduleRun{SkillName: "morning-analysis", TradeDate: "2025-03-03", Window: "9:30-11:30", StartedAt: time.Now()})
+
+	last, err := GetLastScheduleRuns(database)
+	if err != nil || len(last) != 2 || last["afternoon-trade"].TradeDate != "2025-03-04" {
+		t.Errorf("unexpected last runs: %+v, %v", last, err)
+	}
+	stored, _ := GetScheduleRun(database, "afternoon-trade", "2025-03-03")
+	if stored == nil || stored.Status != model.ScheduleRunStatusSuccess {
+		t.Errorf("unexpected stored run: %+v", stored)
+	}
+}
@@ -56,6 +56,18 @@ func GetDB() *gorm.DB {
 	return globalDB.db
 }
 
+// SetGlobalDB 替换全局数据库连接并返回原连接，传 nil 表示数据库不可用
+// 用于历史回放：工具的账户与交易读写重定向到临时数据库，结束后恢复原连接
+func SetGlobalDB(db *gorm.DB) *gorm.DB {
+	prev := GetDB()
+	if db == nil {
+		globalDB = nil
+	} else {
+		globalDB = &globalDBConn{db: db}
+	}
+	return prev
+}
+
 // IsDBAvailable 检查数据库是否可用
 func IsDBAvailable() bool {
 	return globalDB != nil
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"msa/pkg/model"
+)
+
+// GetKLineSeries 查询K线缓存序列，不存在时返回 nil
+func GetKLineSeries(db *gorm.DB, stockCode, period, adjust string) (*model.KLineSeries, error) {
+	var series model.KLineSeries
+	err := db.Where("stock_code = ? AND period = ? AND adjust = ?", stockCode, period, adjust).First(&series).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kline series: %w", err)
+	}
+
+	return &series, nil
+}
+
+// ListKLineSeries 查询全部K线缓存序列，stockCode 为空时不限制
+func ListKLineSeries(db *gorm.DB, stockCode string) ([]*model.KLineSeries, error) {
+	query := db.Model(&model.KLineSeries{})
+	if stockCode != "" {
+		query = query.Where("stock_code = ?", stockCode)
+	}
+
+	var series []*model.KLineSeries
+	if err := query.Order("stock_code ASC, period ASC, adjust ASC").Find(&series).Error; err != nil {
+		return nil, fmt.Errorf("failed to query kline series: %w", err)
+	}
+
+	return series, nil
+}
+
+// GetKLineBars 按日期升序查询缓存中最近 count 根K线，count <= 0 时返回全部
+func GetKLineBars(db *gorm.DB, stockCode, period, adjust string, count int) ([]model.KLineBar, error) {
+	query := db.Where("stock_code = ? AND period = ? AND adjust = ?", stockCode, period, adjust).Order("date DESC")
+	if count > 0 {
+		query = query.Limit(count)
+	}
+
+	var rows []model.KLineCacheBar
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to query kline bars: %w", err)
+	}
+
+	bars := make([]model.KLineBar, len(rows))
+	for i := range rows {
+		bars[len(rows)-1-i] = rows[i].ToKLineBar()
+	}
+	return bars, nil
+}
+
+// SaveKLineBars 在事务中写入K线并更新缓存序列
+// 先删除日期晚于 replaceAfter 的缓存再写入 bars，replaceAfter 为空时清空该序列的全部缓存
+// bars 须按日期升序排列且日期均晚于 replaceAfter
+func SaveKLineBars(tx *gorm.DB, series *model.KLineSeries, bars []model.KLineBar, replaceAfter string) error {
+	err := tx.Unscoped().
+		Where("stock_code = ? AND period = ? AND adjust = ? AND date > ?", series.StockCode, series.Period, series.Adjust, replaceAfter).
+		Delete(&model.KLineCacheBar{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to delete kline bars: %w", err)
+	}
+
+	if len(bars) > 0 {
+		rows := make([]model.KLineCacheBar, len(bars))
+		for i, bar := range bars {
+			rows[i] = model.KLineCacheBar{
+				StockCode: series.StockCode, Period: series.Period, Adjust: series.Adjust, Date: bar.Date,
+				Open: bar.Open, Close: bar.Close, High: bar.High, Low: bar.Low, Volume: bar.Volume,
+			}
+		}
+		if err := tx.CreateInBatches(rows, 200).Error; err != nil {
+			return fmt.Errorf("failed to create kline bars: %w", err)
+		}
+	}
+
+	// 以实际缓存内容刷新序列范围
+	var stats struct {
+		FirstDate string
+		LastDate  string
+		BarCount  int
+	}
+	err = tx.Model(&model.KLineCacheBar{}).
+		Select("COALESCE(MIN(date), '') AS first_date, COALESCE(MAX(date), '') AS last_date, COUNT(*) AS bar_count").
+		Where("stock_code = ? AND period = ? AND adjust = ?", series.StockCode, series.Period, series.Adjust).
+		Scan(&stats).Error
+	if err != nil {
+		return fmt.Errorf("failed to count kline bars: %w", err)
+	}
+	series.FirstDate, series.LastDate, series.BarCount = stats.FirstDate, stats.LastDate, stats.BarCount
+
+	if err := tx.Save(series).Error; err != nil {
+		return fmt.Errorf("failed to save kline series: %w", err)
+	}
+
+	return nil
+}
@@ -1,6 +1,8 @@
 package db
 
 import (
+	"fmt"
+
 	"gorm.io/gorm"
 
 	"msa/pkg/model"
@@ -9,8 +11,53 @@ import (
 // Migrate 使用 GORM AutoMigrate 创建数据库表和索引
 // 自动根据模型结构体定义生成表结构
 func Migrate(db *gorm.DB) error {
+	if err := migrateLegacyAccounts(db); err != nil {
+		return err
+	}
 	return db.AutoMigrate(
 		&model.Account{},
 		&model.Transaction{},
+		&model.CashFlow{},
+		&model.CorporateAction{},
+		&model.PortfolioSnapshot{},
+		&model.PortfolioSnapshotHolding{},
+		&model.ConditionalOrder{},
+		&model.KLineSeries{},
+		&model.KLineCacheBar{},
+		&model.Watchlist{},
+		&model.WatchAlert{},
+		&model.ScheduleRun{},
 	)
 }
+
+// migrateLegacyAccounts 兼容单账户版本的账户表
+// 旧版 user_id 为唯一索引且没有账户名称：删除唯一索引（由 AutoMigrate 重建为普通索引），
+// 并用 user_id 回填账户名称，避免新增唯一列时冲突
+func migrateLegacyAccounts(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&model.Account{}) {
+		return nil
+	}
+
+	indexes, err := migrator.GetIndexes(&model.Account{})
+	if err != nil {
+		return fmt.Errorf("failed to get account indexes: %w", err)
+	}
+	for _, idx := range indexes {
+		if unique, _ := idx.Unique(); unique && idx.Name() == "idx_accounts_user_id" {
+			if err := migrator.DropIndex(&model.Account{}, idx.Name()); err != nil {
+				return fmt.Errorf("failed to drop legacy user_id index: %w", err)
+			}
+		}
+	}
+
+	if !migrator.HasColumn(&model.Account{}, "name") {
+		if err := db.Exec("ALTER TABLE accounts ADD COLUMN name TEXT NOT NULL DEFAULT ''").Error; err != nil {
+			return fmt.Errorf("failed to add account name column: %w", err)
+		}
+		if err := db.Exec("UPDATE accounts SET name = user_id").Error; err != nil {
+			return fmt.Errorf("failed to backfill account name: %w", err)
+		}
+	}
+	return nil
+}
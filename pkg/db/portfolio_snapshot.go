@@ -0,0 +1,54 @@
+package db
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"msa/pkg/model"
+)
+
+// SavePortfolioSnapshot 在事务中保存净值快照
+// 同一账户同一交易日已有快照时，先删除旧快照及其持仓明细再写入
+func SavePortfolioSnapshot(tx *gorm.DB, snapshot *model.PortfolioSnapshot) (uint, error) {
+	var existing []uint
+	err := tx.Model(&model.PortfolioSnapshot{}).Unscoped().
+		Where("account_id = ? AND trade_date = ?", snapshot.AccountID, snapshot.TradeDate).
+		Pluck("id", &existing).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to query portfolio snapshot: %w", err)
+	}
+	if len(existing) > 0 {
+		if err := tx.Unscoped().Where("snapshot_id IN ?", existing).Delete(&model.PortfolioSnapshotHolding{}).Error; err != nil {
+			return 0, fmt.Errorf("failed to delete snapshot holdings: %w", err)
+		}
+		if err := tx.Unscoped().Delete(&model.PortfolioSnapshot{}, existing).Error; err != nil {
+			return 0, fmt.Errorf("failed to delete portfolio snapshot: %w", err)
+		}
+	}
+
+	if err := tx.Create(snapshot).Error; err != nil {
+		return 0, fmt.Errorf("failed to create portfolio snapshot: %w", err)
+	}
+
+	return snapshot.ID, nil
+}
+
+// GetPortfolioSnapshots 按交易日升序查询账户净值快照（含持仓明细）
+// from、to 为 YYYY-MM-DD 格式，为空表示不限制
+func GetPortfolioSnapshots(db *gorm.DB, accountID uint, from, to string) ([]*model.PortfolioSnapshot, error) {
+	query := db.Preload("Holdings").Where("account_id = ?", accountID)
+	if from != "" {
+		query = query.Where("trade_date >= ?", from)
+	}
+	if to != "" {
+		query = query.Where("trade_date <= ?", to)
+	}
+
+	var snapshots []*model.PortfolioSnapshot
+	if err := query.Order("trade_date ASC").Find(&snapshots).Error; err != nil {
+		return nil, fmt.Errorf("failed to query portfolio snapshots: %w", err)
+	}
+
+	return snapshots, nil
+}
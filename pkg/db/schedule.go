@@ -0,0 +1,58 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"msa/pkg/model"
+)
+
+// GetScheduleRun 查询 skill 在交易日的执行记录，不存在时返回 nil
+func GetScheduleRun(db *gorm.DB, skillName, tradeDate string) (*model.ScheduleRun, error) {
+	var run model.ScheduleRun
+	err := db.Where("skill_name = ? AND trade_date = ?", skillName, tradeDate).First(&run).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedule run: %w", err)
+	}
+
+	return &run, nil
+}
+
+// CreateScheduleRun 创建执行记录
+// skill 与交易日唯一，已存在时返回错误，用于防止重复执行
+func CreateScheduleRun(db *gorm.DB, run *model.ScheduleRun) error {
+	if err := db.Create(run).Error; err != nil {
+		return fmt.Errorf("failed to create schedule run: %w", err)
+	}
+
+	return nil
+}
+
+// SaveScheduleRun 保存执行记录全部字段
+func SaveScheduleRun(db *gorm.DB, run *model.ScheduleRun) error {
+	if err := db.Save(run).Error; err != nil {
+		return fmt.Errorf("failed to save schedule run: %w", err)
+	}
+
+	return nil
+}
+
+// GetLastScheduleRuns 查询每个 skill 最近一次执行记录，key 为 skill 名称
+func GetLastScheduleRuns(db *gorm.DB) (map[string]*model.ScheduleRun, error) {
+	var runs []*model.ScheduleRun
+	latest := db.Model(&model.ScheduleRun{}).Select("MAX(id)").Group("skill_name")
+	if err := db.Where("id IN (?)", latest).Find(&runs).Error; err != nil {
+		return nil, fmt.Errorf("failed to query schedule runs: %w", err)
+	}
+
+	last := make(map[string]*model.ScheduleRun, len(runs))
+	for _, run := range runs {
+		last[run.SkillName] = run
+	}
+	return last, nil
+}
@@ -49,6 +49,24 @@ func UpdateTransactionStatus(db *gorm.DB, id uint, status model.TransactionStatu
 	return nil
 }
 
+// ErrTransactionNotPending 交易记录不是挂单状态（已被其他撮合、撤单流程处理）
+var ErrTransactionNotPending = errors.New("transaction is not pending")
+
+// UpdatePendingTransaction 仅当交易记录仍为 PENDING 时更新字段
+// 条件更新保证并发撮合、撤单时同一挂单只会被处理一次，未更新任何记录时返回 ErrTransactionNotPending
+func UpdatePendingTransaction(tx *gorm.DB, id uint, updates map[string]interface{}) error {
+	result := tx.Model(&model.Transaction{}).
+		Where("id = ? AND status = ?", id, model.TransactionStatusPending).
+		Updates(updates)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update transaction status: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: %d", ErrTransactionNotPending, id)
+	}
+	return nil
+}
+
 // GetTransactionsByAccount 按账户ID查询交易记录
 func GetTransactionsByAccount(db *gorm.DB, accountID uint) ([]*model.Transaction, error) {
 	var transactions []*model.Transaction
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"errors"
 	"testing"
 
 	"gorm.io/gorm"
@@ -284,3 +285,23 @@ func TestGetTransactionsByParent(t *testing.T) {
 		t.Errorf("child transaction should have parent_id %d", parentID)
 	}
 }
+
+func TestUpdatePendingTransaction(t *testing.T) {
+	database, accountID := setupTestDBWithAccount(t)
+	defer CloseDB(database)
+
+	trans := &model.Transaction{
+		AccountID: accountID, StockCode: "600519", StockName: "贵州茅台", Type: model.TransactionTypeBuy,
+		Quantity: 100, Price: 180000, Amount: 18000000, Status: model.TransactionStatusPending,
+	}
+	transID, _ := CreateTransaction(database, trans)
+
+	updates := map[string]interface{}{"status": model.TransactionStatusFilled}
+	if err := UpdatePendingTransaction(database, transID, updates); err != nil {
+		t.Fatalf("UpdatePendingTransaction failed: %v", err)
+	}
+	// 已成交的记录不能再次更新，模拟另一个撮合流程读到过期状态
+	if err := UpdatePendingTransaction(database, transID, updates); !errors.Is(err, ErrTransactionNotPending) {
+		t.Errorf("expected ErrTransactionNotPending, got %v", err)
+	}
+}
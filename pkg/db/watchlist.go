@@ -0,0 +1,111 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"msa/pkg/model"
+)
+
+// GetWatchlistByCode 按股票代码查询自选股，不存在时返回 nil
+func GetWatchlistByCode(db *gorm.DB, stockCode string) (*model.Watchlist, error) {
+	var item model.Watchlist
+	err := db.Where("stock_code = ?", stockCode).First(&item).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get watchlist item: %w", err)
+	}
+
+	return &item, nil
+}
+
+// ListWatchlist 查询全部自选股，按加入顺序排列
+func ListWatchlist(db *gorm.DB) ([]*model.Watchlist, error) {
+	var items []*model.Watchlist
+	if err := db.Order("id ASC").Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to query watchlist: %w", err)
+	}
+
+	return items, nil
+}
+
+// SaveWatchlist 创建或保存自选股全部字段
+func SaveWatchlist(db *gorm.DB, item *model.Watchlist) error {
+	if err := db.Save(item).Error; err != nil {
+		return fmt.Errorf("failed to save watchlist item: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteWatchlist 删除自选股及其全部提醒规则
+// 硬删除，之后可以重新加入同一只股票
+func DeleteWatchlist(tx *gorm.DB, stockCode string) error {
+	if err := tx.Unscoped().Where("stock_code = ?", stockCode).Delete(&model.WatchAlert{}).Error; err != nil {
+		return fmt.Errorf("failed to delete watch alerts: %w", err)
+	}
+	if err := tx.Unscoped().Where("stock_code = ?", stockCode).Delete(&model.Watchlist{}).Error; err != nil {
+		return fmt.Errorf("failed to delete watchlist item: %w", err)
+	}
+
+	return nil
+}
+
+// CreateWatchAlert 创建提醒规则
+func CreateWatchAlert(db *gorm.DB, alert *model.WatchAlert) (uint, error) {
+	if err := db.Create(alert).Error; err != nil {
+		return 0, fmt.Errorf("failed to create watch alert: %w", err)
+	}
+
+	return alert.ID, nil
+}
+
+// GetWatchAlertByID 按ID查询提醒规则
+func GetWatchAlertByID(db *gorm.DB, id uint) (*model.WatchAlert, error) {
+	var alert model.WatchAlert
+	if err := db.First(&alert, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to get watch alert: %w", err)
+	}
+
+	return &alert, nil
+}
+
+// GetWatchAlerts 查询提醒规则，stockCode 或 status 为空时不按该条件筛选
+func GetWatchAlerts(db *gorm.DB, stockCode string, status model.WatchAlertStatus) ([]*model.WatchAlert, error) {
+	query := db.Model(&model.WatchAlert{})
+	if stockCode != "" {
+		query = query.Where("stock_code = ?", stockCode)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var alerts []*model.WatchAlert
+	if err := query.Order("id ASC").Find(&alerts).Error; err != nil {
+		return nil, fmt.Errorf("failed to query watch alerts: %w", err)
+	}
+
+	return alerts, nil
+}
+
+// SaveWatchAlert 保存提醒规则全部字段
+func SaveWatchAlert(db *gorm.DB, alert *model.WatchAlert) error {
+	if err := db.Save(alert).Error; err != nil {
+		return fmt.Errorf("failed to save watch alert: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteWatchAlert 删除提醒规则
+func DeleteWatchAlert(db *gorm.DB, id uint) error {
+	if err := db.Unscoped().Delete(&model.WatchAlert{}, id).Error; err != nil {
+		return fmt.Errorf("failed to delete watch alert: %w", err)
+	}
+
+	return nil
+}
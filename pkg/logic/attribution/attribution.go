@@ -0,0 +1,617 @@
+// Package attribution 绩效归因：按成交记录、资金流水与公司行动重建每日持仓，结合不复权日K线
+// 计算相对基准指数的 Beta/Alpha、按行业的配置与选股效应（Brinson）以及个股、逐笔交易的收益贡献
+package attribution
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"msa/pkg/logic/calendar"
+	"msa/pkg/logic/finsvc"
+	"msa/pkg/model"
+)
+
+const (
+	// DefaultBenchmark 默认基准指数（沪深300）
+	DefaultBenchmark = "sh000300"
+	// DefaultDays 未指定开始日期时归因的交易日数
+	DefaultDays = 60
+	// CashIndustry 现金在行业归因中的名称
+	CashIndustry = "现金"
+	// UnknownIndustry 行业未知的股票
+	UnknownIndustry = "未分类"
+)
+
+// Industry 股票所属行业
+type Industry struct {
+	Name string
+	Code string // 行业指数K线代码，为空时以基准指数代替
+}
+
+// Input 归因计算输入，金额单位为毫
+type Input struct {
+	From, To      string // 归因区间（YYYY-MM-DD），按基准指数的交易日计算
+	BenchmarkCode string
+	Benchmark     []model.KLineBar // 基准指数日K线，按日期升序，须包含 From 之前的一根
+	InitialAmount int64            // 账户初始金额
+	Transactions  []*model.Transaction
+	CashFlows     []*model.CashFlow
+	Actions       []*model.CorporateAction
+	Bars          map[string][]model.KLineBar // 股票代码 → 不复权日K线，按日期升序
+	Industries    map[string]Industry         // 股票代码 → 行业
+	IndustryBars  map[string][]model.KLineBar // 行业指数代码 → 日K线，按日期升序
+	RiskFreeRate  float64                     // 年化无风险利率（小数），用于计算 Alpha
+}
+
+// Report 归因结果
+// 收益率与效应均为小数；配置、选股效应与贡献为每日值的算术累加，与复利计算的超额收益之差计入 Residual
+type Report struct {
+	From            string  `json:"from"`
+	To              string  `json:"to"`
+	Benchmark       string  `json:"benchmark"`
+	Days            int     `json:"days"`
+	StartNAV        int64   `json:"start_nav"` // 期初净值（毫）
+	EndNAV          int64   `json:"end_nav"`   // 期末净值（毫）
+	NetFlow         int64   `json:"net_flow"`  // 区间净入金（毫）
+	PnL             int64   `json:"pnl"`       // 区间盈亏（毫），剔除入金出金
+	PortfolioReturn float64 `json:"portfolio_return"`
+	BenchmarkReturn float64 `json:"benchmark_return"`
+	ExcessReturn    float64 `json:"excess_return"`
+	Beta            float64 `json:"beta"`
+	Alpha           float64 `json:"alpha"` // 年化 Jensen's Alpha
+	Correlation     float64 `json:"correlation"`
+	Allocation      float64 `json:"allocation"` // 行业配置效应合计（含现金）
+	Selection       float64 `json:"selection"`  // 个股选择效应合计（含现金利息）
+	Residual        float64 `json:"residual"`   // 超额收益 - 配置 - 选股（复利与算术累加的差）
+
+	Industries []IndustryEffect    `json:"industries"`
+	Stocks     []StockContribution `json:"stocks"`
+	Trades     []TradeContribution `json:"trades"`
+	Warnings   []string            `json:"warnings,omitempty"`
+}
+
+// IndustryEffect 单个行业的 Brinson 归因
+type IndustryEffect struct {
+	Industry        string  `json:"industry"`
+	Weight          float64 `json:"weight"` // 区间平均权重
+	PnL             int64   `json:"pnl"`    // 盈亏（毫）
+	Contribution    float64 `json:"contribution"`
+	BenchmarkReturn float64 `json:"benchmark_return"` // 行业指数区间收益，无行业指数时为基准收益
+	Allocation      float64 `json:"allocation"`
+	Selection       float64 `json:"selection"`
+}
+
+// StockContribution 个股收益贡献
+type StockContribution struct {
+	Code         string  `json:"code"`
+	Name         string  `json:"name,omitempty"`
+	Industry     string  `json:"industry"`
+	PnL          int64   `json:"pnl"` // 盈亏（毫）
+	Contribution float64 `json:"contribution"`
+}
+
+// TradeContribution 逐笔买入的收益贡献，卖出按先进先出匹配到买入批次
+type TradeContribution struct {
+	TransactionID uint    `json:"transaction_id"`
+	Code          string  `json:"code"`
+	Name          string  `json:"name,omitempty"`
+	Industry      string  `json:"industry"`
+	BuyDate       string  `json:"buy_date"`
+	BuyPrice      int64   `json:"buy_price"` // 成交价（毫）
+	Quantity      int64   `json:"quantity"`  // 买入数量
+	Opening       bool    `json:"opening,omitempty"`
+	Cost          int64   `json:"cost"`                 // 成本（毫，含手续费）；期初持仓为期初市值
+	Proceeds      int64   `json:"proceeds"`             // 区间内卖出净收入与分红（毫）
+	Value         int64   `json:"value"`                // 期末剩余市值（毫）
+	CloseDate     string  `json:"close_date,omitempty"` // 全部卖出日期，未清仓为空
+	PnL           int64   `json:"pnl"`                  // 盈亏（毫）= 卖出净收入 + 分红 + 期末市值 - 成本
+	Return        float64 `json:"return"`
+	Contribution  float64 `json:"contribution"`
+}
+
+// lot 买入批次
+type lot struct {
+	id        uint
+	code      string
+	name      string
+	buyDate   string
+	buyPrice  int64
+	bought    int64
+	quantity  int64 // 剩余数量
+	cost      int64
+	proceeds  int64
+	closeDate string
+	opening   bool
+
+	value   int64 // 最近一个交易日收盘市值
+	prev    int64 // 当日开始时的市值
+	flow    int64 // 当日现金流：买入为负，卖出与分红为正
+	pnl     int64
+	contrib float64
+}
+
+// event 按时间排序的账户事件
+type event struct {
+	date   string
+	trade  *model.Transaction
+	action *model.CorporateAction
+	flow   *model.CashFlow
+}
+
+// state 重放事件得到的账户状态
+type state struct {
+	in       *Input
+	cash     int64
+	lots     []*lot // 所有批次，按买入顺序
+	netFlow  int64  // 当日入金出金
+	interest int64  // 当日利息
+	warned   map[string]bool
+	warnings []string
+}
+
+// Compute 计算区间绩效归因
+func Compute(in *Input) (*Report, error) {
+	bench := in.Benchmark
+	start := sort.Search(len(bench), func(i int) bool { return bench[i].Date >= in.From })
+	end := sort.Search(len(bench), func(i int) bool { return bench[i].Date > in.To })
+	if start == 0 {
+		return nil, fmt.Errorf("基准 %s 在 %s 之前没有K线", in.BenchmarkCode, in.From)
+	}
+	if end <= start {
+		return nil, fmt.Errorf("%s 至 %s 没有交易日", in.From, in.To)
+	}
+
+	s := &state{in: in, cash: in.InitialAmount, warned: map[string]bool{}}
+	events := buildEvents(in)
+	prevDate := bench[start-1].Date
+	next := 0
+	for ; next < len(events) && events[next].date <= prevDate; next++ {
+		s.apply(events[next])
+	}
+
+	// 区间开始前买入的持仓按期初市值计成本
+	navPrev := s.cash
+	for _, l := range s.open() {
+		l.opening = true
+		l.value = l.quantity * s.price(l.code, prevDate)
+		l.cost, l.proceeds = l.value, 0
+		navPrev += l.value
+	}
+
+	report := &Report{From: bench[start].Date, To: bench[end-1].Date, Benchmark: in.BenchmarkCode, StartNAV: navPrev}
+	industries := map[string]*IndustryEffect{}
+	effect := func(name string) *IndustryEffect {
+		if industries[name] == nil {
+			industries[name] = &IndustryEffect{Industry: name}
+		}
+		return industries[name]
+	}
+	var portfolio, benchmark []float64
+
+	for d := start; d < end; d++ {
+		date := bench[d].Date
+		active := s.open()
+		cashPrev := navPrev
+		for _, l := range active {
+			l.prev, l.flow = l.value, 0
+			cashPrev -= l.prev
+		}
+		s.netFlow, s.interest = 0, 0
+		created := len(s.lots)
+		for ; next < len(events) && events[next].date <= date; next++ {
+			s.apply(events[next])
+		}
+		active = append(active, s.lots[created:]...)
+
+		nav := s.cash
+		type group struct{ prev, pnl int64 }
+		groups := map[string]*group{}
+		for _, l := range active {
+			l.value = l.quantity * s.price(l.code, date)
+			nav += l.value
+			name := s.industry(l.code).Name
+			g := groups[name]
+			if g == nil {
+				g = &group{}
+				groups[name] = g
+			}
+			pnl := l.value - l.prev + l.flow
+			g.prev += l.prev
+			g.pnl += pnl
+			l.pnl += pnl
+			if navPrev > 0 {
+				l.contrib += float64(pnl) / float64(navPrev)
+			}
+		}
+
+		report.NetFlow += s.netFlow
+		report.PnL += nav - s.netFlow - navPrev
+		if navPrev <= 0 {
+			s.warn("nav", "存在净值为 0 的交易日，这些交易日不计算收益")
+			navPrev = nav
+			continue
+		}
+
+		rp := float64(nav-s.netFlow-navPrev) / float64(navPrev)
+		rb := closeReturn(bench, d)
+		portfolio = append(portfolio, rp)
+		benchmark = append(benchmark, rb)
+
+		for name, g := range groups {
+			e := effect(name)
+			w := float64(g.prev) / float64(navPrev)
+			c := float64(g.pnl) / float64(navPrev)
+			ri := s.industryReturn(name, date, rb)
+			e.Weight += w
+			e.PnL += g.pnl
+			e.Contribution += c
+			e.Allocation += w * (ri - rb)
+			e.Selection += c - w*ri
+		}
+		cash := effect(CashIndustry)
+		w := float64(cashPrev) / float64(navPrev)
+		cash.Weight += w
+		cash.PnL += s.interest
+		cash.Contribution += float64(s.interest) / float64(navPrev)
+		cash.Allocation -= w * rb
+		cash.Selection += float64(s.interest) / float64(navPrev)
+
+		navPrev = nav
+	}
+
+	report.Days = len(portfolio)
+	report.EndNAV = navPrev
+	report.PortfolioReturn = compound(portfolio)
+	report.BenchmarkReturn = compound(benchmark)
+	report.ExcessReturn = report.PortfolioReturn - report.BenchmarkReturn
+	if len(portfolio) < 2 {
+		s.warn("beta", "区间交易日不足 2 天，无法计算 Beta 与 Alpha")
+	} else {
+		report.Beta, report.Alpha, report.Correlation = regress(portfolio, benchmark, in.RiskFreeRate)
+	}
+
+	for _, e := range industries {
+		if report.Days > 0 {
+			e.Weight /= float64(report.Days)
+		}
+		e.BenchmarkReturn = report.BenchmarkReturn
+		if e.Industry != CashIndustry {
+			e.BenchmarkReturn = s.industryPeriodReturn(e.Industry, prevDate, report.To, report.BenchmarkReturn)
+		}
+		report.Allocation += e.Allocation
+		report.Selection += e.Selection
+		report.Industries = append(report.Industries, *e)
+	}
+	report.Residual = report.ExcessReturn - report.Allocation - report.Selection
+	sort.Slice(report.Industries, func(i, j int) bool {
+		a, b := report.Industries[i], report.Industries[j]
+		if (a.Industry == CashIndustry) != (b.Industry == CashIndustry) {
+			return b.Industry == CashIndustry
+		}
+		return a.Contribution > b.Contribution
+	})
+
+	report.Stocks, report.Trades = s.contributions(report.From)
+	report.Warnings = s.warnings
+	return report, nil
+}
+
+// buildEvents 按时间排序成交、公司行动与资金流水；同一时刻交易在前，分红流水由公司行动记录
+func buildEvents(in *Input) []event {
+	type timed struct {
+		event
+		at    int64
+		order int
+		id    uint
+	}
+	var list []timed
+	for _, t := range in.Transactions {
+		if t.Status == model.TransactionStatusFilled {
+			list = append(list, timed{event{date: calendar.DateOf(t.CreatedAt), trade: t}, t.CreatedAt.UnixNano(), 0, t.ID})
+		}
+	}
+	for _, a := range in.Actions {
+		list = append(list, timed{event{date: calendar.DateOf(a.CreatedAt), action: a}, a.CreatedAt.UnixNano(), 1, a.ID})
+	}
+	for _, f := range in.CashFlows {
+		if f.Type != model.CashFlowTypeDividend {
+			list = append(list, timed{event{date: calendar.DateOf(f.CreatedAt), flow: f}, f.CreatedAt.UnixNano(), 2, f.ID})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].at != list[j].at {
+			return list[i].at < list[j].at
+		}
+		if list[i].order != list[j].order {
+			return list[i].order < list[j].order
+		}
+		return list[i].id < list[j].id
+	})
+
+	events := make([]event, len(list))
+	for i, e := range list {
+		events[i] = e.event
+	}
+	return events
+}
+
+// apply 重放单个事件，更新现金、批次与当日现金流
+func (s *state) apply(e event) {
+	switch {
+	case e.trade != nil && e.trade.Type == model.TransactionTypeBuy:
+		t := e.trade
+		cost := t.Amount + t.Fee
+		s.cash -= cost
+		s.lots = append(s.lots, &lot{
+			id: t.ID, code: t.StockCode, name: t.StockName, buyDate: e.date, buyPrice: t.Price,
+			bought: t.Quantity, quantity: t.Quantity, cost: cost, flow: -cost,
+		})
+	case e.trade != nil && e.trade.Type == model.TransactionTypeSell:
+		s.sell(e)
+	case e.action != nil:
+		s.adjust(e.action)
+	case e.flow != nil:
+		s.cash += e.flow.Amount
+		if e.flow.IsContribution() {
+			s.netFlow += e.flow.Amount
+		} else {
+			s.interest += e.flow.Amount
+		}
+	}
+}
+
+// sell 卖出按先进先出扣减批次，卖出净收入按数量分配到批次
+func (s *state) sell(e event) {
+	t := e.trade
+	net := t.Amount - t.Fee
+	s.cash += net
+	remaining, allocated := t.Quantity, int64(0)
+	for _, l := range s.holding(t.StockCode) {
+		if remaining == 0 {
+			break
+		}
+		take := min(remaining, l.quantity)
+		share := net * take / t.Quantity
+		remaining -= take
+		if remaining == 0 {
+			share = net - allocated
+		}
+		allocated += share
+		l.quantity -= take
+		l.flow += share
+		l.proceeds += share
+		if l.quantity == 0 {
+			l.closeDate = e.date
+		}
+	}
+	if remaining > 0 {
+		s.warn("oversell:"+t.StockCode, fmt.Sprintf("%s 卖出数量超过买入批次，超出部分不计入逐笔贡献", t.StockCode))
+	}
+}
+
+// adjust 公司行动：现金分红与送转/拆合股数量按批次数量比例分配
+func (s *state) adjust(a *model.CorporateAction) {
+	s.cash += a.CashAmount
+	lots := s.holding(a.StockCode)
+	var total int64
+	for _, l := range lots {
+		total += l.quantity
+	}
+	if total == 0 {
+		return
+	}
+	var cash, qty int64
+	for i, l := range lots {
+		c, q := a.CashAmount*l.quantity/total, a.QuantityDelta*l.quantity/total
+		if i == len(lots)-1 {
+			c, q = a.CashAmount-cash, a.QuantityDelta-qty
+		}
+		cash += c
+		qty += q
+		l.flow += c
+		l.proceeds += c
+		l.quantity = max(l.quantity+q, 0)
+		if l.quantity == 0 {
+			l.closeDate = calendar.DateOf(a.CreatedAt)
+		}
+	}
+}
+
+// open 仍有持仓的批次
+func (s *state) open() []*lot {
+	var list []*lot
+	for _, l := range s.lots {
+		if l.quantity > 0 {
+			list = append(list, l)
+		}
+	}
+	return list
+}
+
+// holding 指定股票仍有持仓的批次，按买入顺序
+func (s *state) holding(code string) []*lot {
+	var list []*lot
+	for _, l := range s.lots {
+		if l.code == code && l.quantity > 0 {
+			list = append(list, l)
+		}
+	}
+	return list
+}
+
+// price 股票在 date 的收盘价（毫），停牌时取之前最近的收盘价，没有K线时取最近成交价
+func (s *state) price(code, date string) int64 {
+	if bar := barAt(s.in.Bars[code], date); bar != nil {
+		if price := parseYuan(bar.Close); price > 0 {
+			return price
+		}
+	}
+	s.warn("price:"+code, fmt.Sprintf("%s 缺少 %s 之前的日K线，按最近成交价估值", code, date))
+	for i := len(s.lots) - 1; i >= 0; i-- {
+		if s.lots[i].code == code {
+			return s.lots[i].buyPrice
+		}
+	}
+	return 0
+}
+
+// industry 股票所属行业
+func (s *state) industry(code string) Industry {
+	if ind, ok := s.in.Industries[code]; ok && ind.Name != "" {
+		return ind
+	}
+	return Industry{Name: UnknownIndustry}
+}
+
+// industryBars 行业名称对应的行业指数K线
+func (s *state) industryBars(name string) []model.KLineBar {
+	for _, ind := range s.in.Industries {
+		if ind.Name == name && ind.Code != "" {
+			if bars := s.in.IndustryBars[ind.Code]; len(bars) > 0 {
+				return bars
+			}
+		}
+	}
+	return nil
+}
+
+// industryReturn 行业指数在 date 的日收益，没有行业指数时使用基准收益
+func (s *state) industryReturn(name, date string, fallback float64) float64 {
+	bars := s.industryBars(name)
+	i := sort.Search(len(bars), func(i int) bool { return bars[i].Date >= date })
+	if i == 0 || i >= len(bars) || bars[i].Date != date {
+		s.warn("industry:"+name, fmt.Sprintf("行业 %s 缺少行业指数K线，以基准指数收益代替", name))
+		return fallback
+	}
+	return closeReturn(bars, i)
+}
+
+// industryPeriodReturn 行业指数区间收益，没有行业指数时使用基准收益
+func (s *state) industryPeriodReturn(name, from, to string, fallback float64) float64 {
+	bars := s.industryBars(name)
+	first, last := barAt(bars, from), barAt(bars, to)
+	if first == nil || last == nil {
+		return fallback
+	}
+	base := parseYuan(first.Close)
+	if base <= 0 {
+		return fallback
+	}
+	return float64(parseYuan(last.Close))/float64(base) - 1
+}
+
+// contributions 汇总个股与逐笔贡献，只包含区间内有持仓的批次，按贡献从高到低排序
+func (s *state) contributions(from string) ([]StockContribution, []TradeContribution) {
+	stocks := map[string]*StockContribution{}
+	var trades []TradeContribution
+	for _, l := range s.lots {
+		if l.closeDate != "" && l.closeDate < from {
+			continue
+		}
+		industry := s.industry(l.code).Name
+		if stocks[l.code] == nil {
+			stocks[l.code] = &StockContribution{Code: l.code, Name: l.name, Industry: industry}
+		}
+		stocks[l.code].PnL += l.pnl
+		stocks[l.code].Contribution += l.contrib
+
+		value := int64(0)
+		if l.quantity > 0 {
+			value = l.value
+		}
+		trade := TradeContribution{
+			TransactionID: l.id, Code: l.code, Name: l.name, Industry: industry,
+			BuyDate: l.buyDate, BuyPrice: l.buyPrice, Quantity: l.bought, Opening: l.opening,
+			Cost: l.cost, Proceeds: l.proceeds, Value: value, CloseDate: l.closeDate,
+			PnL: l.pnl, Contribution: l.contrib,
+		}
+		if l.cost > 0 {
+			trade.Return = float64(l.pnl) / float64(l.cost)
+		}
+		trades = append(trades, trade)
+	}
+
+	list := make([]StockContribution, 0, len(stocks))
+	for _, c := range stocks {
+		list = append(list, *c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Contribution > list[j].Contribution })
+	sort.SliceStable(trades, func(i, j int) bool { return trades[i].Contribution > trades[j].Contribution })
+	return list, trades
+}
+
+func (s *state) warn(key, msg string) {
+	if !s.warned[key] {
+		s.warned[key] = true
+		s.warnings = append(s.warnings, msg)
+	}
+}
+
+// regress 日收益回归：Beta = Cov(rp, rb) / Var(rb)，年化 Alpha = (超额均值 - Beta × 基准超额均值) × 年交易日数
+func regress(rp, rb []float64, riskFreeRate float64) (beta, alpha, correlation float64) {
+	n := float64(len(rp))
+	rf := riskFreeRate / finsvc.TradingDaysPerYear
+	var meanP, meanB float64
+	for i := range rp {
+		meanP += rp[i]
+		meanB += rb[i]
+	}
+	meanP /= n
+	meanB /= n
+
+	var cov, varP, varB float64
+	for i := range rp {
+		cov += (rp[i] - meanP) * (rb[i] - meanB)
+		varP += (rp[i] - meanP) * (rp[i] - meanP)
+		varB += (rb[i] - meanB) * (rb[i] - meanB)
+	}
+	if varB > 0 {
+		beta = cov / varB
+	}
+	if varP > 0 && varB > 0 {
+		correlation = cov / math.Sqrt(varP*varB)
+	}
+	alpha = ((meanP - rf) - beta*(meanB-rf)) * finsvc.TradingDaysPerYear
+	return beta, alpha, correlation
+}
+
+// compound 日收益率连乘得到区间收益率
+func compound(returns []float64) float64 {
+	index := 1.0
+	for _, r := range returns {
+		index *= 1 + r
+	}
+	return index - 1
+}
+
+// closeReturn 第 i 根K线相对前一根的收盘涨跌幅
+func closeReturn(bars []model.KLineBar, i int) float64 {
+	if i == 0 {
+		return 0
+	}
+	prev, cur := parseYuan(bars[i-1].Close), parseYuan(bars[i].Close)
+	if prev <= 0 || cur <= 0 {
+		return 0
+	}
+	return float64(cur)/float64(prev) - 1
+}
+
+// barAt date 当天或之前最近的K线
+func barAt(bars []model.KLineBar, date string) *model.KLineBar {
+	i := sort.Search(len(bars), func(i int) bool { return bars[i].Date > date })
+	if i == 0 {
+		return nil
+	}
+	return &bars[i-1]
+}
+
+// parseYuan 解析元为单位的价格（毫），无效时为 0
+func parseYuan(s string) int64 {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return int64(math.Round(v * 10000))
+}
@@ -0,0 +1,169 @@
+package attribution
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"msa/pkg/logic/calendar"
+	"msa/pkg/model"
+)
+
+func bars(closes ...string) []model.KLineBar {
+	var list []model.KLineBar
+	for i := 0; i+1 < len(closes); i += 2 {
+		list = append(list, model.KLineBar{Date: closes[i], Close: closes[i+1]})
+	}
+	return list
+}
+
+func at(t *testing.T, date string) time.Time {
+	t.Helper()
+	day, err := time.ParseInLocation(calendar.DateLayout, date, calendar.Location)
+	if err != nil {
+		t.Fatalf("parse date failed: %v", err)
+	}
+	return day.Add(10 * time.Hour)
+}
+
+func trade(t *testing.T, id uint, date, code, name string, side model.TransactionType, qty int64, price float64, fee int64) *model.Transaction {
+	t.Helper()
+	return &model.Transaction{
+		Model:     gorm.Model{ID: id, CreatedAt: at(t, date)},
+		StockCode: code, StockName: name, Type: side, Quantity: qty,
+		Price: int64(math.Round(price * 10000)), Amount: qty * int64(math.Round(price*10000)), Fee: fee,
+		Status: model.TransactionStatusFilled,
+	}
+}
+
+// newInput 05-30 买入浦发银行（区间前），06-03 买入平安银行，06-04 卖出浦发银行，06-05 入金
+func newInput(t *testing.T) *Input {
+	deposit := &model.CashFlow{Model: gorm.Model{ID: 1, CreatedAt: at(t, "2025-06-05")}, Type: model.CashFlowTypeDeposit, Amount: model.YuanToHao(10000)}
+	return &Input{
+		From: "2025-06-03", To: "2025-06-05", BenchmarkCode: DefaultBenchmark,
+		Benchmark:     bars("2025-05-30", "99", "2025-06-02", "100", "2025-06-03", "101", "2025-06-04", "99", "2025-06-05", "102", "2025-06-06", "103"),
+		InitialAmount: model.YuanToHao(100000),
+		Transactions: []*model.Transaction{
+			trade(t, 1, "2025-05-30", "sh600000", "浦发银行", model.TransactionTypeBuy, 1000, 10, model.YuanToHao(5)),
+			trade(t, 2, "2025-06-03", "sz000001", "平安银行", model.TransactionTypeBuy, 1000, 20, model.YuanToHao(5)),
+			trade(t, 3, "2025-06-04", "sh600000", "浦发银行", model.TransactionTypeSell, 1000, 11, model.YuanToHao(10)),
+			trade(t, 4, "2025-06-06", "sz000001", "平安银行", model.TransactionTypeSell, 1000, 21, 0),
+		},
+		CashFlows: []*model.CashFlow{deposit},
+		Bars: map[string][]model.KLineBar{
+			"sh600000": bars("2025-05-30", "10.0", "2025-06-02", "10.5", "2025-06-03", "10.8", "2025-06-04", "11.0", "2025-06-05", "11.2"),
+			"sz000001": bars("2025-06-03", "20.5", "2025-06-04", "20.0", "2025-06-05", "21.0"),
+		},
+		Industries: map[string]Industry{
+			"sh600000": {Name: "银行", Code: "pt01"},
+			"sz000001": {Name: "电子", Code: "pt02"},
+		},
+		IndustryBars: map[string][]model.KLineBar{
+			"pt01": bars("2025-06-02", "1000", "2025-06-03", "1010", "2025-06-04", "1020", "2025-06-05", "1030"),
+		},
+	}
+}
+
+func TestCompute(t *testing.T) {
+	report, err := Compute(newInput(t))
+	if err != nil {
+		t.Fatalf("Compute failed: %v", err)
+	}
+
+	if report.From != "2025-06-03" || report.To != "2025-06-05" || report.Days != 3 {
+		t.Errorf("Unexpected period: %s ~ %s, %d days", report.From, report.To, report.Days)
+	}
+	// 期初：现金 89995 + 浦发 1000 × 10.5；期末：现金 90980 + 平安 1000 × 21
+	if report.StartNAV != model.YuanToHao(100495) || report.EndNAV != model.YuanToHao(111980) {
+		t.Errorf("Unexpected nav: start=%d end=%d", report.StartNAV, report.EndNAV)
+	}
+	if report.NetFlow != model.YuanToHao(10000) || report.PnL != model.YuanToHao(1485) {
+		t.Errorf("Unexpected flow/pnl: flow=%d pnl=%d", report.NetFlow, report.PnL)
+	}
+	if math.Abs(report.BenchmarkReturn-0.02) > 1e-9 {
+		t.Errorf("Unexpected benchmark return: %v", report.BenchmarkReturn)
+	}
+	if sum := report.Allocation + report.Selection + report.Residual; math.Abs(sum-report.ExcessReturn) > 1e-12 {
+		t.Errorf("Effects %v do not add up to excess return %v", sum, report.ExcessReturn)
+	}
+	if report.Beta == 0 || report.Correlation < -1 || report.Correlation > 1 {
+		t.Errorf("Unexpected beta/correlation: %v %v", report.Beta, report.Correlation)
+	}
+
+	var allocation, selection, contribution float64
+	for _, e := range report.Industries {
+		allocation += e.Allocation
+		selection += e.Selection
+		contribution += e.Contribution
+	}
+	if math.Abs(allocation-report.Allocation) > 1e-12 || math.Abs(selection-report.Selection) > 1e-12 {
+		t.Errorf("Industry effects do not add up: %+v", report.Industries)
+	}
+	if last := report.Industries[len(report.Industries)-1]; last.Industry != CashIndustry || last.Weight <= 0 {
+		t.Errorf("Expected cash row last: %+v", report.Industries)
+	}
+	for _, e := range report.Industries {
+		if e.Industry == "银行" && math.Abs(e.BenchmarkReturn-0.03) > 1e-9 {
+			t.Errorf("Unexpected industry index return: %+v", e)
+		}
+		if e.Industry == "电子" && e.BenchmarkReturn != report.BenchmarkReturn {
+			t.Errorf("Expected benchmark fallback: %+v", e)
+		}
+	}
+	if len(report.Warnings) != 1 || !strings.Contains(report.Warnings[0], "电子") {
+		t.Errorf("Unexpected warnings: %v", report.Warnings)
+	}
+
+	if len(report.Stocks) != 2 || report.Stocks[0].Code != "sz000001" || report.Stocks[0].PnL != model.YuanToHao(995) || report.Stocks[1].PnL != model.YuanToHao(490) {
+		t.Errorf("Unexpected stocks: %+v", report.Stocks)
+	}
+	var stockContribution float64
+	for _, s := range report.Stocks {
+		stockContribution += s.Contribution
+	}
+	if math.Abs(stockContribution-contribution) > 1e-12 {
+		t.Errorf("Stock contributions %v do not match industries %v", stockContribution, contribution)
+	}
+
+	if len(report.Trades) != 2 {
+		t.Fatalf("Unexpected trades: %+v", report.Trades)
+	}
+	opening := report.Trades[1]
+	if opening.TransactionID != 1 || !opening.Opening || opening.Cost != model.YuanToHao(10500) ||
+		opening.Proceeds != model.YuanToHao(10990) || opening.CloseDate != "2025-06-04" || opening.Value != 0 {
+		t.Errorf("Unexpected opening trade: %+v", opening)
+	}
+	if open := report.Trades[0]; open.TransactionID != 2 || open.Value != model.YuanToHao(21000) || open.CloseDate != "" ||
+		math.Abs(open.Return-995.0/20005) > 1e-9 {
+		t.Errorf("Unexpected open trade: %+v", open)
+	}
+}
+
+func TestCompute_Errors(t *testing.T) {
+	in := newInput(t)
+	in.From = "2025-05-30"
+	if _, err := Compute(in); err == nil {
+		t.Errorf("Expected error without benchmark bar before period")
+	}
+
+	in = newInput(t)
+	in.From, in.To = "2025-06-07", "2025-06-08"
+	if _, err := Compute(in); err == nil {
+		t.Errorf("Expected error for period without trading days")
+	}
+}
+
+func TestActiveCodes(t *testing.T) {
+	in := newInput(t)
+	in.Transactions = append(in.Transactions,
+		trade(t, 5, "2025-05-20", "sh600519", "贵州茅台", model.TransactionTypeBuy, 100, 1500, 0),
+		trade(t, 6, "2025-05-21", "sh600519", "贵州茅台", model.TransactionTypeSell, 100, 1510, 0),
+	)
+	codes := activeCodes(in, "2025-06-02")
+	if len(codes) != 2 || codes[0] != "sh600000" || codes[1] != "sz000001" {
+		t.Errorf("Unexpected active codes: %v", codes)
+	}
+}
@@ -0,0 +1,180 @@
+package attribution
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"msa/pkg/db"
+	"msa/pkg/logic/calendar"
+	"msa/pkg/logic/finsvc"
+	"msa/pkg/logic/marketdata"
+	"msa/pkg/logic/marketdata/klinecache"
+	"msa/pkg/model"
+)
+
+// Options 归因参数
+type Options struct {
+	From, To     string  // 归因区间（YYYY-MM-DD），From 为空时取 To 之前 Days 个交易日，To 为空时取最近一个已收盘的交易日
+	Days         int     // 未指定 From 时的交易日数，默认 DefaultDays
+	Benchmark    string  // 基准指数代码，默认 DefaultBenchmark
+	RiskFreeRate float64 // 年化无风险利率（小数）
+}
+
+// Analyze 读取账户成交记录、资金流水与公司行动，经K线缓存获取基准指数、个股与行业指数的日K线后计算归因
+// 行业取数据源行业分类（get_stock_industry 的 plate）中的一级行业，行业指数K线代码为 pt + 行业板块ID
+func Analyze(database *gorm.DB, provider marketdata.MarketDataProvider, accountID uint, opts Options, now time.Time) (*Report, error) {
+	account, err := db.GetAccountByID(database, accountID)
+	if err != nil {
+		return nil, err
+	}
+	for _, date := range []string{opts.From, opts.To} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse(calendar.DateLayout, date); err != nil {
+			return nil, fmt.Errorf("日期格式错误: %s，应为 YYYY-MM-DD", date)
+		}
+	}
+
+	in := &Input{
+		From:          opts.From,
+		To:            opts.To,
+		BenchmarkCode: strings.ToLower(strings.TrimSpace(opts.Benchmark)),
+		InitialAmount: account.InitialAmount,
+		Bars:          map[string][]model.KLineBar{},
+		Industries:    map[string]Industry{},
+		IndustryBars:  map[string][]model.KLineBar{},
+		RiskFreeRate:  opts.RiskFreeRate,
+	}
+	if in.BenchmarkCode == "" {
+		in.BenchmarkCode = DefaultBenchmark
+	}
+	if in.To == "" {
+		latest := now
+		if !finsvc.IsAfterMarketClose(now) {
+			latest = calendar.PrevTradingDay(calendar.MarketCN, now)
+		}
+		in.To = calendar.DateOf(calendar.LatestTradingDay(calendar.MarketCN, latest))
+	}
+
+	if in.Benchmark, err = klinecache.GetKLine(database, provider, in.BenchmarkCode, "day", klinecache.MaxBars, "", now); err != nil {
+		return nil, fmt.Errorf("获取基准 %s K线失败: %w", in.BenchmarkCode, err)
+	}
+	end := sort.Search(len(in.Benchmark), func(i int) bool { return in.Benchmark[i].Date > in.To })
+	if in.From == "" {
+		days := opts.Days
+		if days <= 0 {
+			days = DefaultDays
+		}
+		if end < 2 {
+			return nil, fmt.Errorf("基准 %s 在 %s 之前的K线不足", in.BenchmarkCode, in.To)
+		}
+		in.From = in.Benchmark[max(end-days, 1)].Date
+	}
+	start := sort.Search(len(in.Benchmark), func(i int) bool { return in.Benchmark[i].Date >= in.From })
+	if start == 0 {
+		return nil, fmt.Errorf("基准 %s 的K线从 %s 开始，无法覆盖 %s", in.BenchmarkCode, in.Benchmark[0].Date, in.From)
+	}
+
+	if in.Transactions, err = db.GetTransactionsByAccount(database, accountID); err != nil {
+		return nil, err
+	}
+	if in.CashFlows, err = db.GetCashFlowsByAccount(database, accountID); err != nil {
+		return nil, err
+	}
+	if in.Actions, err = db.GetCorporateActionsByAccount(database, accountID); err != nil {
+		return nil, err
+	}
+
+	// 覆盖区间开始前一个交易日至今的K线
+	count := min(len(in.Benchmark)-start+2, klinecache.MaxBars)
+	var warnings []string
+	for _, code := range activeCodes(in, in.Benchmark[start-1].Date) {
+		bars, err := klinecache.GetKLine(database, provider, code, "day", count, "", now)
+		if err != nil {
+			log.Warnf("attribution: 获取 %s K线失败: %v", code, err)
+		}
+		in.Bars[code] = bars
+
+		industry, err := industryOf(provider, code)
+		if err != nil {
+			log.Warnf("attribution: 获取 %s 行业失败: %v", code, err)
+			warnings = append(warnings, fmt.Sprintf("%s 行业分类获取失败，归入%s", code, UnknownIndustry))
+			continue
+		}
+		in.Industries[code] = industry
+		if _, ok := in.IndustryBars[industry.Code]; ok || industry.Code == "" {
+			continue
+		}
+		bars, err = klinecache.GetKLine(database, provider, industry.Code, "day", count, "", now)
+		if err != nil {
+			log.Warnf("attribution: 获取行业 %s(%s) K线失败: %v", industry.Name, industry.Code, err)
+		}
+		in.IndustryBars[industry.Code] = bars
+	}
+
+	report, err := Compute(in)
+	if err != nil {
+		return nil, err
+	}
+	report.Warnings = append(warnings, report.Warnings...)
+	return report, nil
+}
+
+// activeCodes 区间开始时仍有持仓或区间内有成交、公司行动的股票
+func activeCodes(in *Input, prevDate string) []string {
+	positions := map[string]int64{}
+	active := map[string]bool{}
+	for _, t := range in.Transactions {
+		if t.Status != model.TransactionStatusFilled {
+			continue
+		}
+		date := calendar.DateOf(t.CreatedAt)
+		if date > prevDate {
+			active[t.StockCode] = active[t.StockCode] || date <= in.To
+			continue
+		}
+		if t.Type == model.TransactionTypeBuy {
+			positions[t.StockCode] += t.Quantity
+		} else {
+			positions[t.StockCode] -= t.Quantity
+		}
+	}
+	for _, a := range in.Actions {
+		if calendar.DateOf(a.CreatedAt) <= prevDate {
+			positions[a.StockCode] += a.QuantityDelta
+		}
+	}
+	for code, qty := range positions {
+		if qty > 0 {
+			active[code] = true
+		}
+	}
+
+	codes := make([]string, 0, len(active))
+	for code, ok := range active {
+		if ok {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// industryOf 股票所属申万一级行业
+func industryOf(provider marketdata.MarketDataProvider, code string) (Industry, error) {
+	resp, err := provider.GetIndustry(code)
+	if err != nil {
+		return Industry{}, err
+	}
+	level1, _ := resp.IndustryLevels()
+	if level1 == nil {
+		return Industry{}, fmt.Errorf("没有行业分类")
+	}
+	return Industry{Name: level1.Name, Code: level1.BoardCode()}, nil
+}
@@ -0,0 +1,264 @@
+package backtest
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	msadb "msa/pkg/db"
+	"msa/pkg/logic/calendar"
+	"msa/pkg/logic/finsvc"
+	"msa/pkg/model"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	database, err := msadb.InitDBWithPath(filepath.Join(t.TempDir(), "test.sqlite"))
+	if err != nil {
+		t.Fatalf("InitDBWithPath failed: %v", err)
+	}
+	if err := msadb.Migrate(database); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	t.Cleanup(func() { msadb.CloseDB(database) })
+	return database
+}
+
+// ohlc 单日开高低收
+type ohlc struct{ open, high, low, close float64 }
+
+// buildBars 生成 warmup 根 10 元平盘K线，之后按 days 依次生成K线，日期为连续的A股交易日
+// 返回K线与回测起始日（第一根非预热K线的日期）
+func buildBars(warmup int, days []ohlc) ([]model.KLineBar, string) {
+	day := time.Date(2025, 1, 2, 0, 0, 0, 0, calendar.Location)
+	format := func(v float64) string { return fmt.Sprintf("%.2f", v) }
+	var bars []model.KLineBar
+	add := func(d ohlc) {
+		bars = append(bars, model.KLineBar{
+			Date: calendar.DateOf(day), Open: format(d.open), High: format(d.high), Low: format(d.low), Close: format(d.close), Volume: "1000",
+		})
+		day = calendar.NextTradingDay(calendar.MarketCN, day)
+	}
+	for i := 0; i < warmup; i++ {
+		add(ohlc{10, 10, 10, 10})
+	}
+	for _, d := range days {
+		add(d)
+	}
+	return bars, bars[warmup].Date
+}
+
+func mustStrategy(t *testing.T, src string) *Strategy {
+	t.Helper()
+	s, err := ParseStrategy([]byte(src))
+	if err != nil {
+		t.Fatalf("ParseStrategy failed: %v", err)
+	}
+	return s
+}
+
+func TestParseStrategy(t *testing.T) {
+	s := mustStrategy(t, "name: trend\nbuy: price > ma20 AND rsi14 < 70\nsell: price < ma20\nrank_by: change_5d\n")
+	if s.InitialCapital != DefaultInitialCapital || s.PositionPct != DefaultPositionPct || s.MaxPositions != DefaultMaxPositions {
+		t.Errorf("Unexpected defaults: %+v", s)
+	}
+	if s.lotSize("sh600519") != 100 {
+		t.Errorf("Unexpected lot size: %d", s.lotSize("sh600519"))
+	}
+
+	for name, src := range map[string]string{
+		"缺少买入条件": "sell: price < ma20",
+		"快照字段":   "buy: pe < 20",
+		"行业字段":   `buy: industry = "银行"`,
+		"语法错误":   "buy: price >",
+		"文本排序字段": "buy: price > ma5\nrank_by: name",
+		"仓位超限":   "buy: price > ma5\nposition_pct: 120",
+	} {
+		if _, err := ParseStrategy([]byte(src)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestRunWithDB_Signals(t *testing.T) {
+	database := setupTestDB(t)
+	bars, from := buildBars(70, []ohlc{
+		{10, 10.5, 10, 10.5},    // D0 收盘站上 ma5，产生买入信号
+		{10.6, 11, 10.5, 11},    // D1 开盘买入
+		{11, 11.5, 11, 11.5},    // D2
+		{11.5, 11.5, 10, 10},    // D3 收盘跌破 ma5，产生卖出信号
+		{10.1, 10.2, 9.9, 10},   // D4 开盘卖出
+		{10, 10.1, 9.95, 10.05}, // D5
+	})
+	strategy := mustStrategy(t, "name: ma5\nbuy: price > ma5\nsell: price < ma5\ninitial_capital: 100000\nposition_pct: 50\nmax_positions: 1\n")
+
+	report, err := RunWithDB(database, strategy, &Feed{Bars: map[string][]model.KLineBar{"sh600000": bars}, Names: map[string]string{"sh600000": "浦发银行"}}, from, "2099-12-31")
+	if err != nil {
+		t.Fatalf("RunWithDB failed: %v", err)
+	}
+	if report.TradingDays != 6 || report.From != from || len(report.Curve) != 6 {
+		t.Fatalf("Unexpected report range: %+v", report)
+	}
+	if len(report.Trades) != 2 {
+		t.Fatalf("Expected 2 trades, got %+v", report.Trades)
+	}
+	buy, sell := report.Trades[0], report.Trades[1]
+	if buy.Side != model.TransactionTypeBuy || buy.Date != bars[71].Date || buy.Price != model.YuanToHao(10.6) || buy.Quantity != 4700 || buy.Reason != reasonBuy {
+		t.Errorf("Unexpected buy: %+v", buy)
+	}
+	if sell.Side != model.TransactionTypeSell || sell.Date != bars[74].Date || sell.Price != model.YuanToHao(10.1) || sell.HoldDays != 3 || sell.Reason != reasonSell {
+		t.Errorf("Unexpected sell: %+v", sell)
+	}
+	wantPnL := sell.Amount - sell.Fee - buy.Amount - buy.Fee
+	if sell.PnL != wantPnL || sell.PnL >= 0 {
+		t.Errorf("Unexpected pnl: %d, want %d", sell.PnL, wantPnL)
+	}
+
+	if report.RoundTrips != 1 || report.Wins != 0 || report.WinRate != 0 || report.TotalFees != buy.Fee+sell.Fee {
+		t.Errorf("Unexpected trade stats: %+v", report)
+	}
+	if report.FinalNAV != report.InitialCapital+sell.PnL || report.TotalReturn >= 0 || report.MaxDrawdown <= 0 || report.Turnover <= 0 {
+		t.Errorf("Unexpected performance: nav=%d return=%f drawdown=%f turnover=%f", report.FinalNAV, report.TotalReturn, report.MaxDrawdown, report.Turnover)
+	}
+	if len(report.Holdings) != 0 {
+		t.Errorf("Expected no holdings, got %+v", report.Holdings)
+	}
+
+	text := report.Format(0)
+	for _, want := range []string{"策略: ma5", "平仓笔数: 1", "浦发银行", "盈亏"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Format missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestRunWithDB_VolumeLimit(t *testing.T) {
+	database := setupTestDB(t)
+	bars, from := buildBars(70, []ohlc{
+		{10, 10.5, 10, 10.5},
+		{10.6, 11, 10.5, 11},
+	})
+	// 日成交量 1000 手，单笔最多成交 2% 即 2000 股，目标仓位 4700 股
+	strategy := mustStrategy(t, "name: thin\nbuy: price > ma5\ninitial_capital: 100000\nposition_pct: 50\nmax_positions: 1\nmax_volume_pct: 2\n")
+
+	report, err := RunWithDB(database, strategy, &Feed{Bars: map[string][]model.KLineBar{"sh600000": bars}}, from, "2099-12-31")
+	if err != nil {
+		t.Fatalf("RunWithDB failed: %v", err)
+	}
+	if len(report.Trades) != 1 || report.Trades[0].Quantity != 2000 {
+		t.Fatalf("Expected one partial buy of 2000, got %+v", report.Trades)
+	}
+	if len(report.Holdings) != 1 || report.Holdings[0].Quantity != 2000 {
+		t.Errorf("Unexpected holdings: %+v", report.Holdings)
+	}
+
+	var children []*model.Transaction
+	database.Where("parent_id IS NOT NULL").Order("id").Find(&children)
+	if len(children) != 2 || children[0].Status != model.TransactionStatusFilled || children[1].Status != model.TransactionStatusCancelled {
+		t.Errorf("Expected filled and cancelled children, got %+v", children)
+	}
+
+	var account model.Account
+	database.First(&account)
+	if account.LockedAmt != 0 {
+		t.Errorf("Expected no locked amount after cancelling remainder, got %d", account.LockedAmt)
+	}
+
+	if _, err := ParseStrategy([]byte("buy: price > ma5\nmax_volume_pct: 120")); err == nil {
+		t.Error("Expected error for max_volume_pct over 100")
+	}
+}
+
+func TestRunWithDB_StopsAndLimits(t *testing.T) {
+	database := setupTestDB(t)
+	bars, from := buildBars(70, []ohlc{
+		{10, 10.5, 10, 10.5},      // D0 买入信号
+		{10.6, 10.8, 9, 10.6},     // D1 开盘买入，当日跌破止损价但受 T+1 限制
+		{10.5, 10.6, 9.9, 10.2},   // D2 收阴，盘中触及止损
+		{10.2, 10.3, 10.1, 10.25}, // D3
+	})
+	limitBars, _ := buildBars(70, []ohlc{
+		{10, 10.5, 10, 10.5},         // D0 买入信号
+		{11.55, 11.55, 11.55, 11.55}, // D1 一字涨停，无法买入
+		{11, 11, 10, 10},
+		{10, 10, 10, 10},
+	})
+	strategy := mustStrategy(t, "buy: price > ma5 AND change_pct < 8\nstop_loss_pct: 5\ninitial_capital: 100000\nposition_pct: 40\n")
+
+	report, err := RunWithDB(database, strategy, &Feed{Bars: map[string][]model.KLineBar{"sh600000": bars, "sh600036": limitBars}}, from, bars[73].Date)
+	if err != nil {
+		t.Fatalf("RunWithDB failed: %v", err)
+	}
+
+	var trades []string
+	for _, tr := range report.Trades {
+		trades = append(trades, fmt.Sprintf("%s %s %s %s", tr.Date, tr.Time, tr.Code, tr.Reason))
+	}
+	want := []string{
+		bars[71].Date + " 09:30 sh600000 " + reasonBuy,
+		bars[72].Date + " 13:30 sh600000 " + reasonStopLoss,
+	}
+	if strings.Join(trades, "|") != strings.Join(want, "|") {
+		t.Fatalf("Unexpected trades:\n%s\nwant:\n%s", strings.Join(trades, "\n"), strings.Join(want, "\n"))
+	}
+
+	// 止损价按持仓成本（含手续费）计算，模拟最低价途经止损价时按止损价成交
+	buy, stop := report.Trades[0], report.Trades[1]
+	cost := float64(buy.Amount+buy.Fee) / float64(buy.Quantity) / 10000
+	if stop.Price != priceHao(cost*0.95) {
+		t.Errorf("Unexpected stop price %d, cost %.4f", stop.Price, cost)
+	}
+
+	warned := false
+	for _, w := range report.Warnings {
+		warned = warned || strings.Contains(w, "sh600036 开盘涨停")
+	}
+	if !warned {
+		t.Errorf("Expected limit-up warning, got %v", report.Warnings)
+	}
+}
+
+func TestRunWithDB_MinutePath(t *testing.T) {
+	database := setupTestDB(t)
+	bars, from := buildBars(70, []ohlc{
+		{10, 10.5, 10, 10.5},
+		{10.6, 10.8, 10.5, 10.6},
+		{10.6, 11.6, 10.5, 11.5}, // D2 分时价格突破止盈价
+		{12, 12, 12, 12},
+	})
+	strategy := mustStrategy(t, "buy: price > ma5\ntake_profit_pct: 8\nmax_positions: 1\n")
+	feed := &Feed{
+		Bars: map[string][]model.KLineBar{"sz000001": bars},
+		Minute: func(stockCode, date string) ([]finsvc.PricePoint, error) {
+			if date != bars[72].Date {
+				return nil, nil
+			}
+			c, _ := newClock(date)
+			return []finsvc.PricePoint{
+				{At: c.at(9, 31), Price: priceHao(10.6)},
+				{At: c.at(10, 5), Price: priceHao(11.3)},
+				{At: c.at(10, 6), Price: priceHao(11.6)},
+			}, nil
+		},
+	}
+	report, err := RunWithDB(database, strategy, feed, from, bars[73].Date)
+	if err != nil {
+		t.Fatalf("RunWithDB failed: %v", err)
+	}
+	if len(report.Trades) < 2 {
+		t.Fatalf("Expected take-profit trade, got %+v", report.Trades)
+	}
+	// 分时价格跳过止盈价时按实际分时价成交
+	tp := report.Trades[1]
+	if tp.Reason != reasonTakeProfit || tp.Time != "10:06" || tp.Price != priceHao(11.6) || tp.PnL <= 0 {
+		t.Errorf("Unexpected take-profit trade: %+v", tp)
+	}
+	if report.Wins != 1 || report.WinRate != 1 {
+		t.Errorf("Unexpected win stats: %+v", report)
+	}
+}
@@ -0,0 +1,684 @@
+package backtest
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"msa/pkg/db"
+	"msa/pkg/logic/calendar"
+	"msa/pkg/logic/finsvc"
+	"msa/pkg/logic/indicator"
+	"msa/pkg/logic/screener"
+	"msa/pkg/model"
+)
+
+// warmupBars 回测起始日之前建议的K线数，不足时 ma60 等指标在前期缺失
+const warmupBars = 60
+
+// 止损、止盈卖出原因与收盘信号卖出原因
+const (
+	reasonBuy        = "买入条件"
+	reasonSell       = "卖出条件"
+	reasonStopLoss   = "止损"
+	reasonTakeProfit = "止盈"
+	reasonHoldDays   = "持有到期"
+)
+
+// MinuteFunc 获取某只股票某个交易日（YYYY-MM-DD）的分时价格，按时间升序；没有数据时返回空
+type MinuteFunc func(stockCode, date string) ([]finsvc.PricePoint, error)
+
+// Feed 回测行情
+type Feed struct {
+	// Bars 前复权日K线（按日期升序），需包含回测起始日之前用于计算指标的K线
+	Bars map[string][]model.KLineBar
+	// Names 股票名称，用于 ST 涨跌幅限制判断与报告展示
+	Names map[string]string
+	// Minute 分时价格，为空或某日没有数据时按 开盘 → 最低/最高 → 收盘 的顺序模拟盘中价格
+	Minute MinuteFunc
+	// Industries 股票所属行业，用于风控行业仓位限制，缺失时按风控规则拒绝买入
+	Industries map[string]string
+}
+
+// clock 模拟时钟：按交易日推进，盘中各环节使用交易所时区的固定时刻
+type clock struct {
+	date string
+	day  time.Time
+}
+
+func newClock(date string) (clock, error) {
+	day, err := time.ParseInLocation(calendar.DateLayout, date, calendar.Location)
+	if err != nil {
+		return clock{}, fmt.Errorf("日期格式无效: %s", date)
+	}
+	return clock{date: date, day: day}, nil
+}
+
+// at 当日 hour:minute 的时间
+func (c clock) at(hour, minute int) time.Time {
+	return c.day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+}
+
+// engine 单次回测的状态
+type engine struct {
+	db        *gorm.DB
+	accountID uint
+	strategy  *Strategy
+	feed      *Feed
+	codes     []string
+	bars      map[string][]indicator.Bar
+	report    *Report
+
+	pendingBuys  []string          // 收盘产生、次日开盘买入的候选，按 rank_by 排序
+	pendingSells map[string]string // 收盘产生、次日开盘卖出的股票：代码 → 原因
+	holdDays     map[string]int    // 持有交易日数
+	lastNAV      int64             // 前一交易日收盘净值（毫），用于计算目标仓位
+	lastSnapshot *model.PortfolioSnapshot
+}
+
+// Run 在临时 SQLite 数据库中运行回测，结束后删除数据库
+func Run(strategy *Strategy, feed *Feed, from, to string) (*Report, error) {
+	dir, err := os.MkdirTemp("", "msa-backtest-*")
+	if err != nil {
+		return nil, fmt.Errorf("创建临时目录失败: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	database, err := db.InitDBWithPath(filepath.Join(dir, "backtest.sqlite"))
+	if err != nil {
+		return nil, err
+	}
+	defer db.CloseDB(database)
+	if err := db.Migrate(database); err != nil {
+		return nil, err
+	}
+	return RunWithDB(database, strategy, feed, from, to)
+}
+
+// RunWithDB 在指定数据库中创建回测账户，逐个交易日回放K线
+// 每个交易日：开盘执行前一日收盘产生的卖出、买入 → 盘中检查止损止盈 → 收盘评估买卖条件并记录净值快照
+// 订单经 finsvc 下单（交易时段、涨跌幅、整手、T+1、余额校验与手续费计算与实盘模拟一致）后按委托价成交
+// 风控只使用回放收盘价与 feed.Industries，回测期间不使用风控数据源查询实时行情
+func RunWithDB(database *gorm.DB, strategy *Strategy, feed *Feed, from, to string) (*Report, error) {
+	if strategy == nil || strategy.buyExpr == nil {
+		return nil, fmt.Errorf("策略未加载")
+	}
+	if _, err := time.Parse(calendar.DateLayout, from); err != nil {
+		return nil, fmt.Errorf("起始日期格式无效: %s", from)
+	}
+	if _, err := time.Parse(calendar.DateLayout, to); err != nil {
+		return nil, fmt.Errorf("结束日期格式无效: %s", to)
+	}
+	if from > to {
+		return nil, fmt.Errorf("起始日期 %s 晚于结束日期 %s", from, to)
+	}
+
+	prevSource := finsvc.SetRiskDataSource(nil)
+	defer finsvc.SetRiskDataSource(prevSource)
+
+	e := &engine{
+		db:           database,
+		strategy:     strategy,
+		feed:         feed,
+		bars:         map[string][]indicator.Bar{},
+		pendingSells: map[string]string{},
+		holdDays:     map[string]int{},
+		report:       &Report{Strategy: strategy.Name},
+	}
+	if err := e.load(from); err != nil {
+		return nil, err
+	}
+	days := e.tradingDays(from, to)
+	if len(days) == 0 {
+		return nil, fmt.Errorf("%s 至 %s 没有K线数据", from, to)
+	}
+
+	capital := model.YuanToHao(strategy.InitialCapital)
+	accountID, err := db.CreateNamedAccount(database, "backtest", fmt.Sprintf("backtest-%s-%d", strategy.Name, time.Now().UnixNano()), capital)
+	if err != nil {
+		return nil, err
+	}
+	e.accountID = accountID
+	e.lastNAV = capital
+	e.report.InitialCapital = capital
+
+	log.Infof("回测开始: 策略=%s, 区间=%s ~ %s, 股票=%v, 交易日=%d", strategy.Name, days[0], days[len(days)-1], e.codes, len(days))
+	for _, date := range days {
+		c, err := newClock(date)
+		if err != nil {
+			return nil, err
+		}
+		if err := e.step(c); err != nil {
+			return nil, fmt.Errorf("%s 回放失败: %w", date, err)
+		}
+	}
+	return e.finish(days)
+}
+
+// load 转换K线并检查预热数据
+func (e *engine) load(from string) error {
+	for code, raw := range e.feed.Bars {
+		bars, err := indicator.FromKLineBars(raw)
+		if err != nil {
+			return fmt.Errorf("%s K线数据无效: %w", code, err)
+		}
+		sort.SliceStable(bars, func(i, j int) bool { return bars[i].Date < bars[j].Date })
+		e.bars[code] = bars
+		e.codes = append(e.codes, code)
+
+		warmup := sort.Search(len(bars), func(i int) bool { return bars[i].Date >= from })
+		if warmup == len(bars) {
+			e.warnf("%s 在回测区间内没有K线", code)
+		} else if warmup < warmupBars {
+			e.warnf("%s 在 %s 之前只有 %d 根K线，前期 ma60 等指标缺失", code, from, warmup)
+		}
+	}
+	sort.Strings(e.codes)
+	e.report.Codes = e.codes
+	return nil
+}
+
+// tradingDays 区间内任一股票有K线的日期
+func (e *engine) tradingDays(from, to string) []string {
+	seen := map[string]bool{}
+	var days []string
+	for _, bars := range e.bars {
+		for _, b := range bars {
+			if b.Date >= from && b.Date <= to && !seen[b.Date] {
+				seen[b.Date] = true
+				days = append(days, b.Date)
+			}
+		}
+	}
+	sort.Strings(days)
+	return days
+}
+
+// barOn 获取股票在 date 的K线及其下标，停牌或没有数据时返回 false
+func (e *engine) barOn(code, date string) (indicator.Bar, int, bool) {
+	bars := e.bars[code]
+	i := sort.Search(len(bars), func(i int) bool { return bars[i].Date >= date })
+	if i == len(bars) || bars[i].Date != date {
+		return indicator.Bar{}, 0, false
+	}
+	return bars[i], i, true
+}
+
+// prevClose 第 i 根K线的昨收价（毫），没有前一根K线时为 0
+func (e *engine) prevClose(code string, i int) int64 {
+	if i == 0 {
+		return 0
+	}
+	return priceHao(e.bars[code][i-1].Close)
+}
+
+// lastClose 不晚于 date 的最近收盘价（毫），用于停牌股票估值
+func (e *engine) lastClose(code, date string) int64 {
+	bars := e.bars[code]
+	i := sort.Search(len(bars), func(i int) bool { return bars[i].Date > date })
+	if i == 0 {
+		return 0
+	}
+	return priceHao(bars[i-1].Close)
+}
+
+func (e *engine) name(code string) string {
+	if name := e.feed.Names[code]; name != "" {
+		return name
+	}
+	return code
+}
+
+func (e *engine) warnf(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	log.Warnf("回测: %s", msg)
+	e.report.Warnings = append(e.report.Warnings, msg)
+}
+
+// step 回放一个交易日
+func (e *engine) step(c clock) error {
+	if err := e.executeOpen(c); err != nil {
+		return err
+	}
+	if err := e.checkStops(c); err != nil {
+		return err
+	}
+	return e.evaluateClose(c)
+}
+
+// holdings 当前持仓股票（按代码排序）
+func (e *engine) holdings() ([]string, error) {
+	codes, err := finsvc.GetActiveStockCodes(e.db, e.accountID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(codes)
+	return codes, nil
+}
+
+// executeOpen 开盘按开盘价执行前一日收盘产生的订单，卖出先于买入，释放的资金可用于当日买入
+// 停牌或开盘跌停无法卖出时顺延到下一交易日；开盘涨停或停牌时放弃买入
+func (e *engine) executeOpen(c clock) error {
+	at := c.at(9, 30)
+
+	sells := make([]string, 0, len(e.pendingSells))
+	for code := range e.pendingSells {
+		sells = append(sells, code)
+	}
+	sort.Strings(sells)
+	for _, code := range sells {
+		bar, i, ok := e.barOn(code, c.date)
+		if !ok {
+			continue
+		}
+		price, prevClose := priceHao(bar.Open), e.prevClose(code, i)
+		if lower, _, limited := e.limitBand(code, prevClose); limited && price <= lower {
+			e.warnf("%s %s 开盘跌停，卖出顺延", c.date, code)
+			continue
+		}
+		qty, err := finsvc.GetSellableQuantity(e.db, e.accountID, code, at)
+		if err != nil {
+			return err
+		}
+		if qty > 0 {
+			filled, err := e.trade(c, model.TransactionTypeSell, code, qty, price, prevClose, at, e.pendingSells[code])
+			if err != nil {
+				return err
+			}
+			// 受成交量限制未卖完的部分顺延到下一交易日
+			if filled < qty {
+				continue
+			}
+		}
+		delete(e.pendingSells, code)
+	}
+
+	held, err := e.holdings()
+	if err != nil {
+		return err
+	}
+	holding := map[string]bool{}
+	for _, code := range held {
+		holding[code] = true
+	}
+	for _, code := range e.pendingBuys {
+		if len(holding) >= e.strategy.MaxPositions {
+			break
+		}
+		if holding[code] {
+			continue
+		}
+		bar, i, ok := e.barOn(code, c.date)
+		if !ok {
+			continue
+		}
+		price, prevClose := priceHao(bar.Open), e.prevClose(code, i)
+		if _, upper, limited := e.limitBand(code, prevClose); limited && price >= upper {
+			e.warnf("%s %s 开盘涨停，放弃买入", c.date, code)
+			continue
+		}
+		qty, err := e.buyQuantity(code, price)
+		if err != nil {
+			return err
+		}
+		if qty == 0 {
+			continue
+		}
+		filled, err := e.trade(c, model.TransactionTypeBuy, code, qty, price, prevClose, at, reasonBuy)
+		if err != nil {
+			return err
+		}
+		if filled > 0 {
+			holding[code] = true
+			e.holdDays[code] = 0
+		}
+	}
+	e.pendingBuys = nil
+	return nil
+}
+
+// limitBand 涨跌停价（毫），无涨跌幅限制或昨收未知时 limited 为 false
+func (e *engine) limitBand(code string, prevClose int64) (lower, upper int64, limited bool) {
+	percent := finsvc.PriceLimitPercent(code, e.feed.Names[code])
+	if percent == 0 || prevClose <= 0 {
+		return 0, 0, false
+	}
+	lower, upper = finsvc.PriceLimitBand(prevClose, percent)
+	return lower, upper, true
+}
+
+// buyQuantity 按目标仓位（前一日净值 × position_pct）与可用资金计算买入数量，向下取整到整手，含手续费
+func (e *engine) buyQuantity(code string, price int64) (int64, error) {
+	account, err := db.GetAccountByID(e.db, e.accountID)
+	if err != nil {
+		return 0, err
+	}
+	budget := min(int64(float64(e.lastNAV)*e.strategy.PositionPct/100), account.AvailableAmt)
+	lot := e.strategy.lotSize(code)
+	qty := budget / (price * lot) * lot
+	for qty > 0 && qty*price+finsvc.CalculateFees(model.TransactionTypeBuy, code, qty*price).Total() > account.AvailableAmt {
+		qty -= lot
+	}
+	return qty, nil
+}
+
+// checkStops 盘中按价格路径检查止损、止盈，触发即按触发价卖出；当日买入的股票受 T+1 限制不检查
+func (e *engine) checkStops(c clock) error {
+	if e.strategy.StopLossPct == 0 && e.strategy.TakeProfitPct == 0 {
+		return nil
+	}
+	held, err := e.holdings()
+	if err != nil {
+		return err
+	}
+	for _, code := range held {
+		bar, i, ok := e.barOn(code, c.date)
+		if !ok {
+			continue
+		}
+		qty, err := finsvc.GetSellableQuantity(e.db, e.accountID, code, c.at(9, 30))
+		if err != nil {
+			return err
+		}
+		if qty == 0 {
+			continue
+		}
+		book, err := finsvc.BuildLotBook(e.db, e.accountID, code, finsvc.GetCostMethod())
+		if err != nil {
+			return err
+		}
+		cost := book.AvgCost()
+		var stop, take int64
+		if e.strategy.StopLossPct > 0 {
+			stop = priceHao(model.HaoToYuan(cost) * (1 - e.strategy.StopLossPct/100))
+		}
+		if e.strategy.TakeProfitPct > 0 {
+			take = priceHao(model.HaoToYuan(cost) * (1 + e.strategy.TakeProfitPct/100))
+		}
+
+		for _, p := range e.pricePath(code, c, bar) {
+			var price int64
+			var reason string
+			switch {
+			case stop > 0 && p.Price <= stop:
+				price, reason = stop, reasonStopLoss
+			case take > 0 && p.Price >= take:
+				price, reason = take, reasonTakeProfit
+			default:
+				continue
+			}
+			// 分时价格或开盘跳空越过触发价时按该价格成交，模拟的最高/最低价途经触发价时按触发价成交
+			if p.exact {
+				price = p.Price
+			}
+			if _, err := e.trade(c, model.TransactionTypeSell, code, qty, price, e.prevClose(code, i), p.At, reason); err != nil {
+				return err
+			}
+			delete(e.pendingSells, code)
+			break
+		}
+	}
+	return nil
+}
+
+// pathPoint 盘中价格路径上的点，exact 表示该价格真实成交过
+type pathPoint struct {
+	finsvc.PricePoint
+	exact bool
+}
+
+// pricePath 当日盘中价格路径：优先使用分时价格；否则收阳按 开盘 → 最低 → 最高 → 收盘，收阴按 开盘 → 最高 → 最低 → 收盘 模拟
+func (e *engine) pricePath(code string, c clock, bar indicator.Bar) []pathPoint {
+	if e.feed.Minute != nil {
+		points, err := e.feed.Minute(code, c.date)
+		if err != nil {
+			log.Warnf("回测: 获取 %s %s 分时数据失败，按日K线模拟: %v", code, c.date, err)
+		}
+		if len(points) > 0 {
+			path := make([]pathPoint, 0, len(points))
+			for _, p := range points {
+				path = append(path, pathPoint{PricePoint: p, exact: true})
+			}
+			return path
+		}
+	}
+
+	first, second := bar.Low, bar.High
+	if bar.Close < bar.Open {
+		first, second = bar.High, bar.Low
+	}
+	return []pathPoint{
+		{PricePoint: finsvc.PricePoint{At: c.at(9, 30), Price: priceHao(bar.Open)}, exact: true},
+		{PricePoint: finsvc.PricePoint{At: c.at(10, 30), Price: priceHao(first)}},
+		{PricePoint: finsvc.PricePoint{At: c.at(13, 30), Price: priceHao(second)}},
+		{PricePoint: finsvc.PricePoint{At: c.at(14, 56), Price: priceHao(bar.Close)}},
+	}
+}
+
+// evaluateClose 收盘按截至当日的日K线评估卖出、买入条件，产生次日开盘的订单，并按收盘价记录净值快照
+func (e *engine) evaluateClose(c clock) error {
+	held, err := e.holdings()
+	if err != nil {
+		return err
+	}
+	holding := map[string]bool{}
+	for _, code := range held {
+		holding[code] = true
+		e.holdDays[code]++
+	}
+
+	records := map[string]*screener.Record{}
+	for _, code := range e.codes {
+		if _, i, ok := e.barOn(code, c.date); ok {
+			records[code] = screener.BarRecord(code, e.feed.Names[code], e.bars[code][:i+1])
+		}
+	}
+
+	for _, code := range held {
+		if _, pending := e.pendingSells[code]; pending {
+			continue
+		}
+		r := records[code]
+		switch {
+		case e.strategy.MaxHoldDays > 0 && e.holdDays[code] >= e.strategy.MaxHoldDays:
+			e.pendingSells[code] = reasonHoldDays
+		case r != nil && e.strategy.sellExpr != nil && e.strategy.sellExpr.Match(r):
+			e.pendingSells[code] = reasonSell
+		}
+	}
+
+	var candidates []*screener.Record
+	for _, code := range e.codes {
+		if r := records[code]; r != nil && !holding[code] && e.strategy.buyExpr.Match(r) {
+			candidates = append(candidates, r)
+		}
+	}
+	e.rank(candidates)
+	e.pendingBuys = e.pendingBuys[:0]
+	for _, r := range candidates {
+		e.pendingBuys = append(e.pendingBuys, r.Code)
+	}
+
+	held, err = e.holdings()
+	if err != nil {
+		return err
+	}
+	prices := finsvc.PriceMap{}
+	for _, code := range held {
+		prices[code] = e.lastClose(code, c.date)
+	}
+	snapshot, err := finsvc.TakePortfolioSnapshot(e.db, e.accountID, prices, c.at(15, 0))
+	if err != nil {
+		return err
+	}
+	e.lastNAV = snapshot.NAV
+	e.lastSnapshot = snapshot
+	return nil
+}
+
+// rank 按 rank_by 排序买入候选，缺少该字段的排在最后，相同时按代码排序
+func (e *engine) rank(records []*screener.Record) {
+	field := e.strategy.RankBy
+	sort.SliceStable(records, func(i, j int) bool {
+		if field == "" {
+			return records[i].Code < records[j].Code
+		}
+		a, okA := records[i].Number(field)
+		b, okB := records[j].Number(field)
+		if okA != okB {
+			return okA
+		}
+		if !okA || a == b {
+			return records[i].Code < records[j].Code
+		}
+		if e.strategy.Ascending {
+			return a < b
+		}
+		return a > b
+	})
+}
+
+// trade 经 finsvc 下单并立即按委托价成交，返回成交数量；被交易规则拒绝时记录原因
+// 设置 max_volume_pct 时成交数量不超过当日K线成交量的该比例，超出部分经 finsvc 部分成交后撤销
+func (e *engine) trade(c clock, side model.TransactionType, code string, qty, price, prevClose int64, at time.Time, reason string) (int64, error) {
+	order := finsvc.Order{
+		StockCode:  code,
+		StockName:  e.name(code),
+		Quantity:   qty,
+		Price:      price,
+		Note:       reason,
+		Time:       at,
+		PrevClose:  prevClose,
+		LotSize:    e.strategy.lotSize(code),
+		Prices:     finsvc.PriceMap{},
+		Industries: map[string]string{},
+	}
+	// 风控按回放收盘价估值持仓、按 feed 行业计算行业仓位，不查询实时行情
+	held, err := e.holdings()
+	if err != nil {
+		return 0, err
+	}
+	for _, h := range held {
+		if price := e.lastClose(h, c.date); price > 0 {
+			order.Prices[h] = price
+		}
+	}
+	for _, h := range append(held, code) {
+		if industry := e.feed.Industries[h]; industry != "" {
+			order.Industries[h] = industry
+		}
+	}
+
+	var realizedBefore int64
+	var transID uint
+	if side == model.TransactionTypeSell {
+		book, err := finsvc.BuildLotBook(e.db, e.accountID, code, finsvc.GetCostMethod())
+		if err != nil {
+			return 0, err
+		}
+		realizedBefore = book.RealizedPnL
+		transID, err = finsvc.SubmitSellOrder(e.db, e.accountID, order)
+		if err != nil {
+			return 0, err
+		}
+	} else {
+		transID, err = finsvc.SubmitBuyOrder(e.db, e.accountID, order)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	trans, err := db.GetTransactionByID(e.db, transID)
+	if err != nil {
+		return 0, err
+	}
+	if trans.Status == model.TransactionStatusRejected {
+		e.warnf("%s %s %s %d 股被拒绝: %s", c.date, sideLabel(side), code, qty, trans.Note)
+		return 0, nil
+	}
+	if trans, err = e.fill(c, trans); err != nil || trans == nil {
+		return 0, err
+	}
+
+	t := Trade{
+		Date:     c.date,
+		Time:     at.In(calendar.Location).Format("15:04"),
+		Code:     code,
+		Name:     e.feed.Names[code],
+		Side:     side,
+		Quantity: trans.Quantity,
+		Price:    price,
+		Amount:   trans.Amount,
+		Fee:      trans.Fee,
+		Reason:   reason,
+	}
+	if side == model.TransactionTypeSell {
+		book, err := finsvc.BuildLotBook(e.db, e.accountID, code, finsvc.GetCostMethod())
+		if err != nil {
+			return 0, err
+		}
+		t.PnL = book.RealizedPnL - realizedBefore
+		t.HoldDays = e.holdDays[code]
+		if trans.Quantity == qty {
+			delete(e.holdDays, code)
+		}
+	}
+	e.report.Trades = append(e.report.Trades, t)
+	return trans.Quantity, nil
+}
+
+// fill 成交挂单，返回成交记录；受当日成交量限制时部分成交并撤销剩余部分，完全无法成交时撤单并返回 nil
+func (e *engine) fill(c clock, trans *model.Transaction) (*model.Transaction, error) {
+	limit, limited := e.volumeLimit(trans.StockCode, c.date)
+	if !limited || limit >= trans.Quantity {
+		if err := finsvc.FillOrder(e.db, trans.ID); err != nil {
+			return nil, err
+		}
+		return db.GetTransactionByID(e.db, trans.ID)
+	}
+
+	const cancelReason = "超过当日成交量上限，剩余部分撤销"
+	if limit == 0 {
+		e.warnf("%s %s %s 当日成交量不足一手，放弃%s", c.date, sideLabel(trans.Type), trans.StockCode, sideLabel(trans.Type))
+		return nil, finsvc.CancelOrder(e.db, trans.ID, cancelReason)
+	}
+	filledID, remainderID, err := finsvc.PartialFillOrder(e.db, trans.ID, limit)
+	if err != nil {
+		return nil, err
+	}
+	if err := finsvc.CancelOrder(e.db, remainderID, cancelReason); err != nil {
+		return nil, err
+	}
+	e.warnf("%s %s %s 受当日成交量限制，%d 股中成交 %d 股", c.date, sideLabel(trans.Type), trans.StockCode, trans.Quantity, limit)
+	return db.GetTransactionByID(e.db, filledID)
+}
+
+// volumeLimit 按 max_volume_pct 计算当日可成交数量（日K线成交量按手换算为股），向下取整到整手
+func (e *engine) volumeLimit(code, date string) (int64, bool) {
+	if e.strategy.MaxVolumePct <= 0 {
+		return 0, false
+	}
+	bar, _, ok := e.barOn(code, date)
+	if !ok {
+		return 0, false
+	}
+	return int64(bar.Volume*e.strategy.MaxVolumePct/100) * e.strategy.lotSize(code), true
+}
+
+// priceHao 元转换为毫并四舍五入到分
+func priceHao(yuan float64) int64 {
+	return int64(math.Round(yuan*100)) * 100
+}
+
+func sideLabel(side model.TransactionType) string {
+	if side == model.TransactionTypeSell {
+		return "卖出"
+	}
+	return "买入"
+}
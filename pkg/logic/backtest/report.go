@@ -0,0 +1,194 @@
+package backtest
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"msa/pkg/logic/finsvc"
+	"msa/pkg/model"
+)
+
+// Trade 回测成交记录
+type Trade struct {
+	Date     string                `json:"date"`
+	Time     string                `json:"time"`
+	Code     string                `json:"code"`
+	Name     string                `json:"name,omitempty"`
+	Side     model.TransactionType `json:"side"`
+	Quantity int64                 `json:"quantity"`
+	Price    int64                 `json:"price"`  // 成交价（毫）
+	Amount   int64                 `json:"amount"` // 成交额（毫）
+	Fee      int64                 `json:"fee"`    // 手续费（毫）
+	Reason   string                `json:"reason"`
+	PnL      int64                 `json:"pnl,omitempty"`       // 卖出实现盈亏（毫，已扣买卖手续费）
+	HoldDays int                   `json:"hold_days,omitempty"` // 卖出时持有的交易日数
+}
+
+// Holding 回测结束时的持仓，按最后收盘价估值
+type Holding struct {
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+	Quantity int64  `json:"quantity"`
+	Price    int64  `json:"price"` // 收盘价（毫）
+	Value    int64  `json:"value"` // 市值（毫）
+	Cost     int64  `json:"cost"`  // 成本（毫）
+}
+
+// CurvePoint 每日净值
+type CurvePoint struct {
+	Date     string  `json:"date"`
+	NAV      int64   `json:"nav"`      // 净值（毫）
+	Return   float64 `json:"return"`   // 累计收益率（小数）
+	Drawdown float64 `json:"drawdown"` // 相对历史最高点的回撤（小数，≤ 0）
+}
+
+// Report 回测报告，收益率、回撤等比率均为小数（0.01 表示 1%）
+type Report struct {
+	Strategy       string   `json:"strategy"`
+	From           string   `json:"from"` // 实际回放的首个交易日
+	To             string   `json:"to"`   // 实际回放的最后一个交易日
+	Codes          []string `json:"codes"`
+	TradingDays    int      `json:"trading_days"`
+	InitialCapital int64    `json:"initial_capital"` // 初始资金（毫）
+	FinalNAV       int64    `json:"final_nav"`       // 期末净值（毫）
+
+	TotalReturn  float64 `json:"total_return"`
+	AnnualReturn float64 `json:"annual_return"` // 按每年 252 个交易日年化
+	MaxDrawdown  float64 `json:"max_drawdown"`  // 最大回撤（正数）
+	Volatility   float64 `json:"volatility"`    // 年化波动率
+	Sharpe       float64 `json:"sharpe"`        // 年化夏普比率（无风险利率为 0）
+
+	RoundTrips   int     `json:"round_trips"`   // 已平仓交易笔数，每次卖出清仓为一笔
+	Wins         int     `json:"wins"`          // 盈利笔数
+	WinRate      float64 `json:"win_rate"`      // 胜率，没有平仓交易时为 0
+	ProfitFactor float64 `json:"profit_factor"` // 盈亏比：盈利合计 / 亏损合计，没有亏损时为 0
+	AvgHoldDays  float64 `json:"avg_hold_days"` // 平均持有交易日数
+	Turnover     float64 `json:"turnover"`      // 换手率：(买入成交额 + 卖出成交额) / 2 / 平均净值
+	TotalFees    int64   `json:"total_fees"`    // 手续费合计（毫）
+
+	Trades   []Trade      `json:"trades"`
+	Holdings []Holding    `json:"holdings,omitempty"`
+	Curve    []CurvePoint `json:"curve"`
+	Warnings []string     `json:"warnings,omitempty"`
+}
+
+// finish 按净值快照计算收益与风险指标，统计成交
+func (e *engine) finish(days []string) (*Report, error) {
+	r := e.report
+	r.From, r.To = days[0], days[len(days)-1]
+	r.TradingDays = len(days)
+
+	curve, err := finsvc.GetEquityCurve(e.db, e.accountID, "", "", 0)
+	if err != nil {
+		return nil, err
+	}
+	var navSum int64
+	for _, p := range curve.Points {
+		navSum += p.NAV
+		r.Curve = append(r.Curve, CurvePoint{Date: p.TradeDate, NAV: p.NAV, Return: p.CumulativeReturn, Drawdown: p.Drawdown})
+	}
+	r.FinalNAV = e.lastNAV
+	if r.InitialCapital > 0 {
+		r.TotalReturn = float64(r.FinalNAV-r.InitialCapital) / float64(r.InitialCapital)
+	}
+	if r.TradingDays > 0 && r.TotalReturn > -1 {
+		r.AnnualReturn = math.Pow(1+r.TotalReturn, float64(finsvc.TradingDaysPerYear)/float64(r.TradingDays)) - 1
+	}
+	r.MaxDrawdown = curve.MaxDrawdown
+	r.Volatility = curve.Volatility
+	r.Sharpe = curve.Sharpe
+
+	var traded, gains, losses int64
+	var holdDays int
+	for _, t := range r.Trades {
+		traded += t.Amount
+		r.TotalFees += t.Fee
+		if t.Side != model.TransactionTypeSell {
+			continue
+		}
+		r.RoundTrips++
+		holdDays += t.HoldDays
+		if t.PnL > 0 {
+			r.Wins++
+			gains += t.PnL
+		} else {
+			losses -= t.PnL
+		}
+	}
+	if r.RoundTrips > 0 {
+		r.WinRate = float64(r.Wins) / float64(r.RoundTrips)
+		r.AvgHoldDays = float64(holdDays) / float64(r.RoundTrips)
+	}
+	if losses > 0 {
+		r.ProfitFactor = float64(gains) / float64(losses)
+	}
+	if len(curve.Points) > 0 && navSum > 0 {
+		r.Turnover = float64(traded) / 2 / (float64(navSum) / float64(len(curve.Points)))
+	}
+
+	if e.lastSnapshot != nil {
+		for _, h := range e.lastSnapshot.Holdings {
+			r.Holdings = append(r.Holdings, Holding{
+				Code: h.StockCode, Name: h.StockName, Quantity: h.Quantity, Price: h.Price, Value: h.Value, Cost: h.Cost,
+			})
+		}
+	}
+	return r, nil
+}
+
+// Format 渲染文本报告，maxTrades 限制列出的成交笔数（取最近的成交），≤ 0 表示全部列出
+func (r *Report) Format(maxTrades int) string {
+	var sb strings.Builder
+	pct := func(v float64) string { return fmt.Sprintf("%.2f%%", v*100) }
+
+	fmt.Fprintf(&sb, "策略: %s\n", r.Strategy)
+	fmt.Fprintf(&sb, "区间: %s ~ %s（%d 个交易日）\n", r.From, r.To, r.TradingDays)
+	fmt.Fprintf(&sb, "股票: %s\n", strings.Join(r.Codes, ", "))
+	fmt.Fprintf(&sb, "\n初始资金: %s 元\n", model.FormatAmount(r.InitialCapital))
+	fmt.Fprintf(&sb, "期末净值: %s 元\n", model.FormatAmount(r.FinalNAV))
+	fmt.Fprintf(&sb, "累计收益: %s    年化收益: %s\n", pct(r.TotalReturn), pct(r.AnnualReturn))
+	fmt.Fprintf(&sb, "最大回撤: %s    年化波动: %s    夏普比率: %.2f\n", pct(r.MaxDrawdown), pct(r.Volatility), r.Sharpe)
+	fmt.Fprintf(&sb, "平仓笔数: %d    胜率: %s    盈亏比: %.2f    平均持有: %.1f 天\n", r.RoundTrips, pct(r.WinRate), r.ProfitFactor, r.AvgHoldDays)
+	fmt.Fprintf(&sb, "换手率: %.2f 倍    手续费: %s 元\n", r.Turnover, model.FormatAmount(r.TotalFees))
+
+	if len(r.Holdings) > 0 {
+		sb.WriteString("\n期末持仓:\n")
+		for _, h := range r.Holdings {
+			fmt.Fprintf(&sb, "  %s %d 股  收盘 %s  市值 %s  成本 %s\n",
+				stockLabel(h.Code, h.Name), h.Quantity, model.FormatAmount(h.Price), model.FormatAmount(h.Value), model.FormatAmount(h.Cost))
+		}
+	}
+
+	trades := r.Trades
+	if maxTrades > 0 && len(trades) > maxTrades {
+		fmt.Fprintf(&sb, "\n成交记录（共 %d 笔，显示最近 %d 笔）:\n", len(trades), maxTrades)
+		trades = trades[len(trades)-maxTrades:]
+	} else {
+		fmt.Fprintf(&sb, "\n成交记录（共 %d 笔）:\n", len(trades))
+	}
+	for _, t := range trades {
+		fmt.Fprintf(&sb, "  %s %s %s %s %d 股 @ %s  %s",
+			t.Date, t.Time, sideLabel(t.Side), stockLabel(t.Code, t.Name), t.Quantity, model.FormatAmount(t.Price), t.Reason)
+		if t.Side == model.TransactionTypeSell {
+			fmt.Fprintf(&sb, "  盈亏 %s 元（持有 %d 天）", model.FormatAmount(t.PnL), t.HoldDays)
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(r.Warnings) > 0 {
+		sb.WriteString("\n提示:\n")
+		for _, w := range r.Warnings {
+			fmt.Fprintf(&sb, "  %s\n", w)
+		}
+	}
+	return sb.String()
+}
+
+// stockLabel 股票代码与名称，名称未知时只显示代码
+func stockLabel(code, name string) string {
+	if name == "" || name == code {
+		return code
+	}
+	return code + " " + name
+}
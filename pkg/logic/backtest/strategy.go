@@ -0,0 +1,150 @@
+package backtest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"msa/pkg/logic/finsvc"
+	"msa/pkg/logic/screener"
+)
+
+const (
+	// DefaultInitialCapital 默认初始资金（元）
+	DefaultInitialCapital = 1000000
+	// DefaultPositionPct 默认单只股票目标仓位（占净值 %）
+	DefaultPositionPct = 20
+	// DefaultMaxPositions 默认最多同时持有的股票数
+	DefaultMaxPositions = 5
+)
+
+// Strategy 回测策略
+// 买卖条件使用筛选表达式（与 msa screen 相同），每个交易日收盘后按日K线评估，次日开盘成交；
+// 止损、止盈按持仓成本在盘中检查，触及即按触发价卖出
+type Strategy struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+
+	Buy       string `yaml:"buy"`       // 买入条件，必填
+	Sell      string `yaml:"sell"`      // 卖出条件，为空时只按止损、止盈与持有天数卖出
+	RankBy    string `yaml:"rank_by"`   // 买入候选排序字段（数值字段），为空时按代码排序
+	Ascending bool   `yaml:"ascending"` // 候选按 rank_by 升序排序，默认降序
+
+	InitialCapital float64 `yaml:"initial_capital"` // 初始资金（元）
+	PositionPct    float64 `yaml:"position_pct"`    // 单只股票目标仓位（占前一日净值 %）
+	MaxPositions   int     `yaml:"max_positions"`   // 最多同时持有的股票数
+	StopLossPct    float64 `yaml:"stop_loss_pct"`   // 止损：较持仓成本下跌 %，0 表示不启用
+	TakeProfitPct  float64 `yaml:"take_profit_pct"` // 止盈：较持仓成本上涨 %，0 表示不启用
+	MaxHoldDays    int     `yaml:"max_hold_days"`   // 最长持有交易日数，0 表示不限
+	MaxVolumePct   float64 `yaml:"max_volume_pct"`  // 单笔成交数量上限：占当日K线成交量 %，超出部分撤销，0 表示不限
+
+	// LotSizes 港股每手股数（代码 → 股数），A股固定 100 股
+	LotSizes map[string]int64 `yaml:"lot_sizes"`
+
+	buyExpr  screener.Expr
+	sellExpr screener.Expr
+}
+
+// LoadStrategy 读取 YAML（或 JSON）策略文件并校验
+func LoadStrategy(path string) (*Strategy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取策略文件失败: %w", err)
+	}
+	s, err := ParseStrategy(data)
+	if err != nil {
+		return nil, fmt.Errorf("策略文件 %s: %w", path, err)
+	}
+	if s.Name == "" {
+		base := filepath.Base(path)
+		s.Name = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+	return s, nil
+}
+
+// ParseStrategy 解析策略内容，补全默认值并编译买卖条件
+func ParseStrategy(data []byte) (*Strategy, error) {
+	var s Strategy
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("解析策略失败: %w", err)
+	}
+	if err := s.compile(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// compile 校验参数并解析买卖条件，条件只能使用日K线可得的字段
+func (s *Strategy) compile() error {
+	if s.InitialCapital == 0 {
+		s.InitialCapital = DefaultInitialCapital
+	}
+	if s.PositionPct == 0 {
+		s.PositionPct = DefaultPositionPct
+	}
+	if s.MaxPositions == 0 {
+		s.MaxPositions = DefaultMaxPositions
+	}
+	switch {
+	case s.InitialCapital < 0:
+		return fmt.Errorf("initial_capital 必须大于 0")
+	case s.PositionPct < 0 || s.PositionPct > 100:
+		return fmt.Errorf("position_pct 必须在 0 ~ 100 之间")
+	case s.MaxPositions < 0:
+		return fmt.Errorf("max_positions 必须大于 0")
+	case s.StopLossPct < 0 || s.StopLossPct >= 100:
+		return fmt.Errorf("stop_loss_pct 必须在 0 ~ 100 之间")
+	case s.TakeProfitPct < 0:
+		return fmt.Errorf("take_profit_pct 不能为负数")
+	case s.MaxHoldDays < 0:
+		return fmt.Errorf("max_hold_days 不能为负数")
+	case s.MaxVolumePct < 0 || s.MaxVolumePct > 100:
+		return fmt.Errorf("max_volume_pct 必须在 0 ~ 100 之间")
+	}
+
+	if strings.TrimSpace(s.Buy) == "" {
+		return fmt.Errorf("缺少买入条件 buy")
+	}
+	var err error
+	if s.buyExpr, err = parseCondition("buy", s.Buy); err != nil {
+		return err
+	}
+	if strings.TrimSpace(s.Sell) != "" {
+		if s.sellExpr, err = parseCondition("sell", s.Sell); err != nil {
+			return err
+		}
+	}
+
+	if s.RankBy != "" {
+		f, ok := screener.LookupField(s.RankBy)
+		if !ok || f.Kind != screener.KindNumber || !screener.AvailableFromBars(s.RankBy) {
+			return fmt.Errorf("rank_by 字段 %s 不可用：须为日K线可计算的数值字段", s.RankBy)
+		}
+	}
+	return nil
+}
+
+// parseCondition 解析条件表达式，拒绝行情快照、行业等回测中没有历史数据的字段
+func parseCondition(key, src string) (screener.Expr, error) {
+	expr, err := screener.Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("%s 条件无效: %w", key, err)
+	}
+	for _, name := range screener.Fields(expr) {
+		if !screener.AvailableFromBars(name) {
+			return nil, fmt.Errorf("%s 条件中的字段 %s 没有历史数据，回测只支持日K线可计算的字段", key, name)
+		}
+	}
+	return expr, nil
+}
+
+// lotSize 每手股数
+func (s *Strategy) lotSize(stockCode string) int64 {
+	if size := s.LotSizes[stockCode]; size > 0 {
+		return size
+	}
+	return finsvc.DefaultLotSize
+}
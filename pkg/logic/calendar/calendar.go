@@ -0,0 +1,170 @@
+// Package calendar 交易日历：沪深（SSE/SZSE）与港股（HKEX）的交易日、休市日、半日市与交易时段
+// 休市数据内嵌在 data/ 目录，按交易所年度公告维护；未收录的年份只排除周末
+package calendar
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed data/*.txt
+var dataFS embed.FS
+
+// Location 交易所所在时区（UTC+8），日期与时段均按该时区判断
+var Location = time.FixedZone("CST", 8*3600)
+
+// DateLayout 日期格式
+const DateLayout = "2006-01-02"
+
+// Market 交易所日历
+type Market string
+
+const (
+	// MarketCN 沪深交易所（上交所、深交所休市安排相同）
+	MarketCN Market = "CN"
+	// MarketHK 香港交易所
+	MarketHK Market = "HK"
+)
+
+// MarketOf 股票代码所在市场，hk 前缀为港股，其余按沪深处理
+func MarketOf(stockCode string) Market {
+	if strings.HasPrefix(strings.ToLower(strings.TrimSpace(stockCode)), "hk") {
+		return MarketHK
+	}
+	return MarketCN
+}
+
+// ParseMarket 解析市场名称，支持 cn/a/sse/szse/sh/sz 与 hk/hkex，空字符串为 CN
+func ParseMarket(name string) (Market, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "cn", "a", "sse", "szse", "sh", "sz":
+		return MarketCN, nil
+	case "hk", "hkex":
+		return MarketHK, nil
+	default:
+		return "", fmt.Errorf("不支持的市场: %s（可选: CN, HK）", name)
+	}
+}
+
+// holiday 休市或半日市
+type holiday struct {
+	name string
+	half bool // 半日市，只有上午交易
+}
+
+// table 单个市场的休市数据
+type table struct {
+	days  map[string]holiday
+	years map[int]bool // 已收录的年份
+}
+
+var tables = map[Market]*table{
+	MarketCN: mustLoad("data/cn.txt"),
+	MarketHK: mustLoad("data/hk.txt"),
+}
+
+// mustLoad 解析内嵌的休市数据，格式错误时 panic（数据随代码发布，测试覆盖）
+func mustLoad(path string) *table {
+	data, err := dataFS.ReadFile(path)
+	if err != nil {
+		panic(fmt.Sprintf("calendar: read %s: %v", path, err))
+	}
+	t, err := parseTable(string(data))
+	if err != nil {
+		panic(fmt.Sprintf("calendar: parse %s: %v", path, err))
+	}
+	return t
+}
+
+// parseTable 解析休市数据：years 行声明收录年份，其余每行 "日期 名称 [half]"，# 开头为注释
+func parseTable(text string) (*table, error) {
+	t := &table{days: map[string]holiday{}, years: map[int]bool{}}
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if fields[0] == "years" {
+			for _, y := range fields[1:] {
+				year, err := strconv.Atoi(y)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid year %q", line, y)
+				}
+				t.years[year] = true
+			}
+			continue
+		}
+		if _, err := time.Parse(DateLayout, fields[0]); err != nil || len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: invalid entry %q", line, scanner.Text())
+		}
+		t.days[fields[0]] = holiday{name: fields[1], half: len(fields) > 2 && fields[2] == "half"}
+	}
+	return t, scanner.Err()
+}
+
+// DateOf 获取 t 在交易所时区的日期
+func DateOf(t time.Time) string {
+	return t.In(Location).Format(DateLayout)
+}
+
+// DayStart 获取 t 所在日期零点（交易所时区）
+func DayStart(t time.Time) time.Time {
+	local := t.In(Location)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, Location)
+}
+
+// Covered 判断 t 所在年份的休市数据是否已收录；未收录时只按周末判断交易日
+func Covered(market Market, t time.Time) bool {
+	return tables[market].years[t.In(Location).Year()]
+}
+
+// HolidayName 获取 t 所在日期的休市（或半日市）名称，非节假日返回空字符串
+func HolidayName(market Market, t time.Time) string {
+	return tables[market].days[DateOf(t)].name
+}
+
+// IsTradingDay 判断 t 所在日期是否为交易日：排除周末与休市日，半日市为交易日
+func IsTradingDay(market Market, t time.Time) bool {
+	local := t.In(Location)
+	if local.Weekday() == time.Saturday || local.Weekday() == time.Sunday {
+		return false
+	}
+	h, ok := tables[market].days[DateOf(t)]
+	return !ok || h.half
+}
+
+// IsHalfDay 判断 t 所在日期是否为半日市（只有上午交易）
+func IsHalfDay(market Market, t time.Time) bool {
+	return IsTradingDay(market, t) && tables[market].days[DateOf(t)].half
+}
+
+// NextTradingDay 获取 t 所在日期之后的下一个交易日（零点，交易所时区）
+func NextTradingDay(market Market, t time.Time) time.Time {
+	day := DayStart(t).AddDate(0, 0, 1)
+	for !IsTradingDay(market, day) {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+// PrevTradingDay 获取 t 所在日期之前的上一个交易日（零点，交易所时区）
+func PrevTradingDay(market Market, t time.Time) time.Time {
+	day := DayStart(t).AddDate(0, 0, -1)
+	for !IsTradingDay(market, day) {
+		day = day.AddDate(0, 0, -1)
+	}
+	return day
+}
+
+// LatestTradingDay 获取 t 所在日期（为交易日时）或之前最近的交易日（零点，交易所时区）
+func LatestTradingDay(market Market, t time.Time) time.Time {
+	if IsTradingDay(market, t) {
+		return DayStart(t)
+	}
+	return PrevTradingDay(market, t)
+}
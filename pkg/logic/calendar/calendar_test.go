@@ -0,0 +1,141 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day, hour, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, Location)
+}
+
+func TestParseTable(t *testing.T) {
+	tbl, err := parseTable("# comment\nyears 2030\n\n2030-01-01 元旦\n2030-12-24 圣诞节前夕 half\n")
+	if err != nil {
+		t.Fatalf("parseTable failed: %v", err)
+	}
+	if !tbl.years[2030] || tbl.days["2030-01-01"].name != "元旦" || !tbl.days["2030-12-24"].half {
+		t.Errorf("Unexpected table: %+v", tbl)
+	}
+	if _, err := parseTable("2030/01/01 元旦"); err == nil {
+		t.Error("Expected error for invalid date")
+	}
+	if _, err := parseTable("2030-01-01"); err == nil {
+		t.Error("Expected error for missing name")
+	}
+}
+
+func TestIsTradingDay(t *testing.T) {
+	tests := []struct {
+		name     string
+		market   Market
+		t        time.Time
+		expected bool
+	}{
+		{"A股工作日", MarketCN, date(2025, 3, 3, 10, 0), true},
+		{"A股周日", MarketCN, date(2025, 3, 2, 10, 0), false},
+		{"A股国庆休市", MarketCN, date(2025, 10, 8, 10, 0), false},
+		{"A股节后开市", MarketCN, date(2025, 10, 9, 10, 0), true},
+		{"UTC跨日换算", MarketCN, time.Date(2025, 9, 30, 17, 0, 0, 0, time.UTC), false},
+		{"港股复活节", MarketHK, date(2025, 4, 21, 10, 0), false},
+		{"港股复活节A股开市", MarketCN, date(2025, 4, 21, 10, 0), true},
+		{"港股半日市", MarketHK, date(2025, 12, 24, 10, 0), true},
+		{"未收录年份只排除周末", MarketCN, date(2030, 1, 1, 10, 0), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTradingDay(tt.market, tt.t); got != tt.expected {
+				t.Errorf("IsTradingDay(%s, %v) = %v, want %v", tt.market, tt.t, got, tt.expected)
+			}
+		})
+	}
+
+	if !IsHalfDay(MarketHK, date(2025, 12, 24, 10, 0)) || IsHalfDay(MarketCN, date(2025, 12, 24, 10, 0)) {
+		t.Error("Unexpected half day result")
+	}
+	if HolidayName(MarketCN, date(2025, 10, 1, 10, 0)) != "国庆节、中秋节" {
+		t.Errorf("Unexpected holiday name: %s", HolidayName(MarketCN, date(2025, 10, 1, 10, 0)))
+	}
+	if !Covered(MarketHK, date(2026, 6, 1, 0, 0)) || Covered(MarketHK, date(2030, 6, 1, 0, 0)) {
+		t.Error("Unexpected coverage")
+	}
+}
+
+func TestNextPrevTradingDay(t *testing.T) {
+	// 国庆长假前后
+	if got := NextTradingDay(MarketCN, date(2025, 9, 30, 15, 0)); DateOf(got) != "2025-10-09" {
+		t.Errorf("NextTradingDay = %s, want 2025-10-09", DateOf(got))
+	}
+	if got := PrevTradingDay(MarketCN, date(2025, 10, 9, 9, 0)); DateOf(got) != "2025-09-30" {
+		t.Errorf("PrevTradingDay = %s, want 2025-09-30", DateOf(got))
+	}
+	// 周日的最近交易日为周五，交易日为当天
+	if got := LatestTradingDay(MarketCN, date(2025, 3, 9, 10, 0)); DateOf(got) != "2025-03-07" {
+		t.Errorf("LatestTradingDay = %s, want 2025-03-07", DateOf(got))
+	}
+	if got := LatestTradingDay(MarketCN, date(2025, 3, 7, 10, 0)); DateOf(got) != "2025-03-07" {
+		t.Errorf("LatestTradingDay = %s, want 2025-03-07", DateOf(got))
+	}
+}
+
+func TestPhaseAt(t *testing.T) {
+	tests := []struct {
+		name     string
+		market   Market
+		t        time.Time
+		expected Phase
+	}{
+		{"A股周末", MarketCN, date(2025, 3, 8, 10, 0), PhaseClosed},
+		{"A股盘前", MarketCN, date(2025, 3, 3, 8, 30), PhasePreMarket},
+		{"A股集合竞价", MarketCN, date(2025, 3, 3, 9, 20), PhaseCallAuction},
+		{"A股上午", MarketCN, date(2025, 3, 3, 9, 30), PhaseMorning},
+		{"A股午休", MarketCN, date(2025, 3, 3, 11, 30), PhaseLunchBreak},
+		{"A股下午", MarketCN, date(2025, 3, 3, 14, 0), PhaseAfternoon},
+		{"A股收盘集合竞价", MarketCN, date(2025, 3, 3, 14, 58), PhaseClosingAuction},
+		{"A股收盘后", MarketCN, date(2025, 3, 3, 15, 0), PhaseAfterHours},
+		{"港股开市前时段", MarketHK, date(2025, 3, 3, 9, 10), PhaseCallAuction},
+		{"港股收市竞价", MarketHK, date(2025, 3, 3, 16, 5), PhaseClosingAuction},
+		{"港股半日市下午", MarketHK, date(2025, 12, 24, 14, 0), PhaseAfterHours},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PhaseAt(tt.market, tt.t); got != tt.expected {
+				t.Errorf("PhaseAt(%s, %v) = %s, want %s", tt.market, tt.t, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSessions(t *testing.T) {
+	open, closeAt, ok := OpenClose(MarketCN, date(2025, 3, 3, 0, 0))
+	if !ok || open.Format("15:04") != "09:30" || closeAt.Format("15:04") != "15:00" {
+		t.Errorf("Unexpected CN open/close: %v %v %v", open, closeAt, ok)
+	}
+	if sessions := Sessions(MarketHK, date(2025, 12, 24, 0, 0)); len(sessions) != 1 || sessions[0].Close.Format("15:04") != "12:00" {
+		t.Errorf("Unexpected HK half day sessions: %+v", sessions)
+	}
+	if _, _, ok := OpenClose(MarketCN, date(2025, 10, 1, 0, 0)); ok {
+		t.Error("Expected no session on holiday")
+	}
+
+	if !InSession(MarketCN, date(2025, 3, 3, 15, 0)) || InSession(MarketCN, date(2025, 3, 3, 15, 0).Add(time.Second)) {
+		t.Error("Unexpected close boundary")
+	}
+	// 节前收盘后的下一个时段在节后开盘
+	if got := NextSessionOpen(MarketCN, date(2025, 9, 30, 15, 30)); !got.Equal(date(2025, 10, 9, 9, 30)) {
+		t.Errorf("NextSessionOpen = %v", got)
+	}
+
+	// 收盘后的行情在下一时段开盘前有效，港股在 A 股收盘后仍在交易
+	if !NoTradingBetween(MarketOf("sh600519"), date(2025, 3, 7, 16, 0), date(2025, 3, 10, 9, 0)) {
+		t.Error("Expected no trading over the weekend")
+	}
+	if NoTradingBetween(MarketOf("HK00700"), date(2025, 3, 3, 15, 30), date(2025, 3, 3, 15, 31)) {
+		t.Error("Expected HK trading after CN close")
+	}
+	if MarketOf("600519") != MarketCN || MarketOf(" hk00700") != MarketHK {
+		t.Error("Unexpected MarketOf result")
+	}
+}
@@ -0,0 +1,30 @@
+package calendar
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	clockMu sync.RWMutex
+	clock   func() time.Time
+)
+
+// SetClock 指定 Now 使用的时钟，传 nil 恢复系统时间
+// 用于历史回放：提示词、交易规则与工具按回放时刻判断交易日和交易时段
+func SetClock(now func() time.Time) {
+	clockMu.Lock()
+	defer clockMu.Unlock()
+	clock = now
+}
+
+// Now 获取当前时间，SetClock 指定时钟时返回该时钟的时间
+func Now() time.Time {
+	clockMu.RLock()
+	now := clock
+	clockMu.RUnlock()
+	if now != nil {
+		return now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,176 @@
+package calendar
+
+import "time"
+
+// Phase 交易阶段
+type Phase string
+
+const (
+	// PhaseClosed 非交易日
+	PhaseClosed Phase = "closed"
+	// PhasePreMarket 交易日开盘集合竞价之前
+	PhasePreMarket Phase = "pre_market"
+	// PhaseCallAuction 开盘集合竞价（A股 9:15-9:30，港股开市前时段 9:00-9:30）
+	PhaseCallAuction Phase = "call_auction"
+	// PhaseMorning 上午连续交易
+	PhaseMorning Phase = "morning"
+	// PhaseLunchBreak 午间休市
+	PhaseLunchBreak Phase = "lunch_break"
+	// PhaseAfternoon 下午连续交易
+	PhaseAfternoon Phase = "afternoon"
+	// PhaseClosingAuction 收盘集合竞价（A股 14:57-15:00，港股收市竞价 16:00-16:10）
+	PhaseClosingAuction Phase = "closing_auction"
+	// PhaseAfterHours 当日已收盘
+	PhaseAfterHours Phase = "after_hours"
+)
+
+var phaseLabels = map[Phase]string{
+	PhaseClosed:         "休市",
+	PhasePreMarket:      "盘前",
+	PhaseCallAuction:    "开盘集合竞价",
+	PhaseMorning:        "上午连续交易",
+	PhaseLunchBreak:     "午间休市",
+	PhaseAfternoon:      "下午连续交易",
+	PhaseClosingAuction: "收盘集合竞价",
+	PhaseAfterHours:     "已收盘",
+}
+
+// Label 中文名称
+func (p Phase) Label() string {
+	return phaseLabels[p]
+}
+
+// phaseSpan 交易阶段（自零点起的分钟数，左闭右开）
+type phaseSpan struct {
+	phase Phase
+	start int
+	end   int
+}
+
+var (
+	// cnPhases A股交易日阶段：连续竞价 9:30-11:30、13:00-14:57，收盘集合竞价至 15:00
+	cnPhases = []phaseSpan{
+		{PhaseCallAuction, 9*60 + 15, 9*60 + 30},
+		{PhaseMorning, 9*60 + 30, 11*60 + 30},
+		{PhaseLunchBreak, 11*60 + 30, 13 * 60},
+		{PhaseAfternoon, 13 * 60, 14*60 + 57},
+		{PhaseClosingAuction, 14*60 + 57, 15 * 60},
+	}
+	// hkPhases 港股交易日阶段：持续交易 9:30-12:00、13:00-16:00，收市竞价至 16:10
+	hkPhases = []phaseSpan{
+		{PhaseCallAuction, 9 * 60, 9*60 + 30},
+		{PhaseMorning, 9*60 + 30, 12 * 60},
+		{PhaseLunchBreak, 12 * 60, 13 * 60},
+		{PhaseAfternoon, 13 * 60, 16 * 60},
+		{PhaseClosingAuction, 16 * 60, 16*60 + 10},
+	}
+	// hkHalfDayPhases 港股半日市：只有上午持续交易，收市竞价 12:00-12:10
+	hkHalfDayPhases = []phaseSpan{
+		{PhaseCallAuction, 9 * 60, 9*60 + 30},
+		{PhaseMorning, 9*60 + 30, 12 * 60},
+		{PhaseClosingAuction, 12 * 60, 12*60 + 10},
+	}
+)
+
+// phases 获取 t 所在交易日的阶段表，非交易日返回 nil
+func phases(market Market, t time.Time) []phaseSpan {
+	if !IsTradingDay(market, t) {
+		return nil
+	}
+	if market == MarketHK {
+		if IsHalfDay(market, t) {
+			return hkHalfDayPhases
+		}
+		return hkPhases
+	}
+	return cnPhases
+}
+
+// PhaseAt 获取 t 所处的交易阶段
+func PhaseAt(market Market, t time.Time) Phase {
+	spans := phases(market, t)
+	if spans == nil {
+		return PhaseClosed
+	}
+	local := t.In(Location)
+	minute := local.Hour()*60 + local.Minute()
+	if minute < spans[0].start {
+		return PhasePreMarket
+	}
+	for _, s := range spans {
+		if minute >= s.start && minute < s.end {
+			return s.phase
+		}
+	}
+	return PhaseAfterHours
+}
+
+// Session 可成交时段（连续交易，A股含收盘集合竞价），Close 时刻本身仍在时段内
+type Session struct {
+	Open  time.Time
+	Close time.Time
+}
+
+// Sessions 获取 t 所在日期的可成交时段，非交易日返回 nil
+// A股 9:30-11:30、13:00-15:00；港股 9:30-12:00、13:00-16:00，半日市只有 9:30-12:00
+func Sessions(market Market, t time.Time) []Session {
+	spans := phases(market, t)
+	if spans == nil {
+		return nil
+	}
+	day := DayStart(t)
+	at := func(minute int) time.Time { return day.Add(time.Duration(minute) * time.Minute) }
+
+	var sessions []Session
+	for _, s := range spans {
+		switch s.phase {
+		case PhaseMorning, PhaseAfternoon:
+			sessions = append(sessions, Session{Open: at(s.start), Close: at(s.end)})
+		case PhaseClosingAuction:
+			// A股收盘集合竞价接在下午连续竞价之后，可以成交
+			if market == MarketCN {
+				sessions[len(sessions)-1].Close = at(s.end)
+			}
+		}
+	}
+	return sessions
+}
+
+// OpenClose 获取 t 所在日期的开盘与收盘时间，非交易日 ok 为 false
+func OpenClose(market Market, t time.Time) (open, close time.Time, ok bool) {
+	sessions := Sessions(market, t)
+	if len(sessions) == 0 {
+		return time.Time{}, time.Time{}, false
+	}
+	return sessions[0].Open, sessions[len(sessions)-1].Close, true
+}
+
+// InSession 判断 t 是否处于可成交时段内（收盘时刻本身计入）
+func InSession(market Market, t time.Time) bool {
+	for _, s := range Sessions(market, t) {
+		if !t.Before(s.Open) && !t.After(s.Close) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextSessionOpen 获取 t 之后下一个可成交时段的开始时间
+func NextSessionOpen(market Market, t time.Time) time.Time {
+	for day := DayStart(t); ; day = day.AddDate(0, 0, 1) {
+		for _, s := range Sessions(market, day) {
+			if s.Open.After(t) {
+				return s.Open
+			}
+		}
+	}
+}
+
+// NoTradingBetween 判断 from 至 to 之间是否没有交易：from 不在交易时段内，且 to 早于 from 之后的下一个交易时段开始
+// 用于判断 from 时刻获取的行情在 to 时刻是否仍然有效
+func NoTradingBetween(market Market, from, to time.Time) bool {
+	if InSession(market, from) {
+		return false
+	}
+	return to.Before(NextSessionOpen(market, from))
+}
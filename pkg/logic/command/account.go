@@ -0,0 +1,110 @@
+package command
+
+import (
+	"context"
+	"fmt"
+
+	msadb "msa/pkg/db"
+	"msa/pkg/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SwitchAccountCommand 切换当前交易账户命令
+// /switch_account 打开账户选择器，/switch_account <name> 直接切换
+type SwitchAccountCommand struct{}
+
+func (s *SwitchAccountCommand) Name() string {
+	return "switch_account"
+}
+
+func (s *SwitchAccountCommand) Description() string {
+	return "Switch the current trading account used by finance tools"
+}
+
+func (s *SwitchAccountCommand) Run(ctx context.Context, args []string) (*model.CmdResult, error) {
+	database := msadb.GetDB()
+	if database == nil {
+		return nil, fmt.Errorf("数据库未初始化")
+	}
+
+	if len(args) > 0 && args[0] != "" {
+		if err := switchAccount(args[0]); err != nil {
+			return nil, err
+		}
+		return &model.CmdResult{
+			Code: 0,
+			Msg:  "success",
+			Type: "message",
+			Data: fmt.Sprintf("已切换到账户: %s", args[0]),
+		}, nil
+	}
+
+	accounts, err := msadb.ListAccounts(database)
+	if err != nil {
+		log.Errorf("查询账户列表失败: %v", err)
+		return nil, err
+	}
+	if len(accounts) == 0 {
+		return &model.CmdResult{
+			Code: 0,
+			Msg:  "success",
+			Type: "message",
+			Data: "暂无账户，请先让助手创建账户",
+		}, nil
+	}
+
+	var items []*model.SelectorItem
+	for _, account := range accounts {
+		desc := fmt.Sprintf("可用 %s 元 · %s", model.FormatAmount(account.AvailableAmt), account.Status)
+		if account.IsCurrent {
+			desc += " · 当前"
+		}
+		items = append(items, &model.SelectorItem{
+			Name:        account.Name,
+			Description: desc,
+		})
+	}
+
+	return &model.CmdResult{
+		Code: 0,
+		Msg:  "success",
+		Type: "selector",
+		Data: items,
+	}, nil
+}
+
+func (s *SwitchAccountCommand) ToSelect(items []*model.SelectorItem) (*model.BaseSelector, error) {
+	return &model.BaseSelector{
+		Items:         items,
+		FilteredItems: items,
+		Cursor:        0,
+		ViewportTop:   0,
+		ViewportSize:  15,
+		SearchQuery:   "",
+		OnConfirm:     switchAccount,
+	}, nil
+}
+
+// switchAccount 将指定名称的账户设为当前账户，已关闭的账户不可切换
+func switchAccount(name string) error {
+	database := msadb.GetDB()
+	if database == nil {
+		return fmt.Errorf("数据库未初始化")
+	}
+
+	account, err := msadb.GetAccountByName(database, name)
+	if err != nil {
+		return fmt.Errorf("账户 %s 不存在", name)
+	}
+	if account.Status == model.AccountStatusClosed {
+		return fmt.Errorf("账户 %s 已关闭，无法切换", name)
+	}
+
+	if err := msadb.SetCurrentAccount(database, account.ID); err != nil {
+		log.Errorf("切换账户失败: %v", err)
+		return err
+	}
+	log.Infof("已切换当前账户: %s", name)
+	return nil
+}
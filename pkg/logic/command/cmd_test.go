@@ -305,3 +305,20 @@ func TestMsaCommand_Interface(t *testing.T) {
 		t.Error("ToSelect() returned nil selector")
 	}
 }
+
+// TestAddWatchAlert_InvalidArgs tests /watch alert argument validation
+func TestAddWatchAlert_InvalidArgs(t *testing.T) {
+	for _, args := range [][]string{
+		{"sh600519"},
+		{"sh600519", "cross", "10"},
+		{"sh600519", "above", "abc"},
+	} {
+		if _, err := addWatchAlert(args); err == nil {
+			t.Errorf("addWatchAlert(%v) expected error", args)
+		}
+	}
+
+	if GetCommand("/watch") == nil {
+		t.Error("watch command not registered")
+	}
+}
@@ -18,6 +18,8 @@ func init() {
 	RegisterCommand(&ListModel{})
 	RegisterCommand(&ConfigCommand{})
 	RegisterCommand(&SkillsCommand{})
+	RegisterCommand(&SwitchAccountCommand{})
+	RegisterCommand(&WatchCommand{})
 	// SetModel 命令已被交互式选择器替代，使用 /models 或 /model 命令
 	// RegisterCommand(&SetModel{})
 }
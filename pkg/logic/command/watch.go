@@ -0,0 +1,197 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	msadb "msa/pkg/db"
+	"msa/pkg/logic/watchlist"
+	"msa/pkg/model"
+)
+
+const watchUsage = `用法:
+  /watch [标签]                         查看自选股
+  /watch add <代码> [备注]              加入自选股
+  /watch rm <代码>                      移出自选股（同时删除提醒）
+  /watch alert <代码> above|below <价格> [repeat]
+  /watch alert <代码> change [涨跌幅%]  当日涨跌幅超过阈值提醒（默认 5）
+  /watch alert <代码> volume [倍数]     成交量达到 5 日均量倍数提醒（默认 2）
+  /watch unalert <提醒ID>               删除提醒规则`
+
+// alertTypeAliases /watch alert 支持的提醒类型写法
+var alertTypeAliases = map[string]model.WatchAlertType{
+	"above":  model.WatchAlertTypePriceAbove,
+	"below":  model.WatchAlertTypePriceBelow,
+	"change": model.WatchAlertTypeChangePct,
+	"volume": model.WatchAlertTypeVolumeSpike,
+}
+
+// WatchCommand 自选股命令
+// /watch 查看自选股，add / rm / alert / unalert 子命令维护自选股与提醒规则
+type WatchCommand struct{}
+
+func (w *WatchCommand) Name() string {
+	return "watch"
+}
+
+func (w *WatchCommand) Description() string {
+	return "Show the watchlist or manage watched stocks and alerts"
+}
+
+func (w *WatchCommand) Run(ctx context.Context, args []string) (*model.CmdResult, error) {
+	database := msadb.GetDB()
+	if database == nil {
+		return nil, fmt.Errorf("数据库未初始化")
+	}
+
+	// TUI 按单个空格拆分参数，忽略多余空格产生的空参数
+	var fields []string
+	for _, arg := range args {
+		if arg = strings.TrimSpace(arg); arg != "" {
+			fields = append(fields, arg)
+		}
+	}
+
+	var text string
+	var err error
+	sub := ""
+	if len(fields) > 0 {
+		sub = strings.ToLower(fields[0])
+	}
+	switch sub {
+	case "add":
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%s", watchUsage)
+		}
+		var item *model.Watchlist
+		var created bool
+		item, created, err = watchlist.Upsert(database, watchlist.EntrySpec{
+			StockCode: fields[1],
+			Notes:     strings.Join(fields[2:], " "),
+		})
+		if err == nil {
+			text = fmt.Sprintf("已更新自选股 %s", item.StockCode)
+			if created {
+				text = fmt.Sprintf("已加入自选股 %s", item.StockCode)
+			}
+		}
+	case "rm", "remove":
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%s", watchUsage)
+		}
+		if err = watchlist.Remove(database, fields[1]); err == nil {
+			text = fmt.Sprintf("已将 %s 移出自选股", watchlist.NormalizeCode(fields[1]))
+		}
+	case "alert":
+		text, err = addWatchAlert(fields[1:])
+	case "unalert":
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%s", watchUsage)
+		}
+		id, parseErr := strconv.ParseUint(strings.TrimPrefix(fields[1], "#"), 10, 64)
+		if parseErr != nil {
+			return nil, fmt.Errorf("提醒ID无效: %s", fields[1])
+		}
+		var alert *model.WatchAlert
+		if alert, err = watchlist.RemoveAlert(database, uint(id)); err == nil {
+			text = fmt.Sprintf("已删除 %s 的提醒 #%d", alert.StockCode, alert.ID)
+		}
+	case "help":
+		text = watchUsage
+	default:
+		tag := ""
+		if len(fields) > 0 {
+			tag = fields[0]
+		}
+		text, err = renderWatchlist(tag)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.CmdResult{
+		Code: 0,
+		Msg:  "success",
+		Type: "message",
+		Data: text,
+	}, nil
+}
+
+func (w *WatchCommand) ToSelect(items []*model.SelectorItem) (*model.BaseSelector, error) {
+	return nil, fmt.Errorf("watch 命令不支持选择器")
+}
+
+// addWatchAlert 解析 /watch alert 参数并创建提醒规则
+func addWatchAlert(fields []string) (string, error) {
+	if len(fields) < 2 {
+		return "", fmt.Errorf("%s", watchUsage)
+	}
+	alertType, ok := alertTypeAliases[strings.ToLower(fields[1])]
+	if !ok {
+		return "", fmt.Errorf("不支持的提醒类型: %s（可选 above / below / change / volume）", fields[1])
+	}
+
+	spec := watchlist.AlertSpec{StockCode: fields[0], Type: alertType}
+	rest := fields[2:]
+	if len(rest) > 0 {
+		value, err := strconv.ParseFloat(rest[0], 64)
+		if err != nil {
+			return "", fmt.Errorf("数值无效: %s", rest[0])
+		}
+		if alertType == model.WatchAlertTypePriceAbove || alertType == model.WatchAlertTypePriceBelow {
+			spec.Price = model.YuanToHao(value)
+		} else {
+			spec.Value = value
+		}
+		spec.Repeat = len(rest) > 1 && strings.EqualFold(rest[1], "repeat")
+	}
+
+	alert, err := watchlist.AddAlert(msadb.GetDB(), spec)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("已添加提醒 #%d: %s %s", alert.ID, alert.StockCode, watchlist.DescribeAlert(alert)), nil
+}
+
+// renderWatchlist 渲染自选股列表文本
+func renderWatchlist(tag string) (string, error) {
+	entries, err := watchlist.List(msadb.GetDB(), tag)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		if tag != "" {
+			return fmt.Sprintf("没有标签为 %s 的自选股", tag), nil
+		}
+		return "自选股为空，使用 /watch add <代码> 加入", nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("自选股 %d 只\n", len(entries)))
+	for _, e := range entries {
+		item := e.Item
+		sb.WriteString(fmt.Sprintf("\n%s %s", item.StockCode, item.StockName))
+		if tags := item.TagList(); len(tags) > 0 {
+			sb.WriteString(" [" + strings.Join(tags, ", ") + "]")
+		}
+		if item.TargetBuyPrice > 0 {
+			sb.WriteString(fmt.Sprintf("  目标买入 %s", strconv.FormatFloat(model.HaoToYuan(item.TargetBuyPrice), 'f', -1, 64)))
+		}
+		if item.TargetSellPrice > 0 {
+			sb.WriteString(fmt.Sprintf("  目标卖出 %s", strconv.FormatFloat(model.HaoToYuan(item.TargetSellPrice), 'f', -1, 64)))
+		}
+		if item.Notes != "" {
+			sb.WriteString("\n  备注: " + item.Notes)
+		}
+		for _, alert := range e.Alerts {
+			status := ""
+			if alert.Status != model.WatchAlertStatusActive {
+				status = "（已触发）"
+			}
+			sb.WriteString(fmt.Sprintf("\n  #%d %s%s", alert.ID, watchlist.DescribeAlert(alert), status))
+		}
+	}
+	return sb.String(), nil
+}
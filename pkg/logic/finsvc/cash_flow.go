@@ -0,0 +1,100 @@
+package finsvc
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"msa/pkg/db"
+	"msa/pkg/model"
+)
+
+// RecordCashFlow 记录入金、出金或利息，并更新可用金额
+// amount 为正数金额（毫），出金时可用金额不足返回错误
+// 现金分红通过 ApplyCorporateAction 记录，以便计入对应股票的已实现盈亏
+func RecordCashFlow(database *gorm.DB, accountID uint, flowType model.CashFlowType, amount int64, note string) (uint, error) {
+	log.Infof("记录资金流水: 账户=%d, 类型=%s, 金额=%d", accountID, flowType, amount)
+
+	if amount <= 0 {
+		return 0, fmt.Errorf("cash flow amount must be positive: %d", amount)
+	}
+
+	delta := amount
+	switch flowType {
+	case model.CashFlowTypeDeposit, model.CashFlowTypeInterest:
+	case model.CashFlowTypeWithdrawal:
+		delta = -amount
+	default:
+		return 0, fmt.Errorf("unsupported cash flow type: %s", flowType)
+	}
+
+	tx := database.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			log.Errorf("记录资金流水 panic: %v", r)
+		}
+	}()
+
+	account, err := db.GetAccountByID(tx, accountID)
+	if err != nil {
+		tx.Rollback()
+		log.Errorf("查询账户失败: %v", err)
+		return 0, fmt.Errorf("failed to get account: %w", err)
+	}
+
+	if account.AvailableAmt+delta < 0 {
+		tx.Rollback()
+		log.Warnf("可用金额不足: 可用=%d, 出金=%d", account.AvailableAmt, amount)
+		return 0, fmt.Errorf("insufficient available amount: available=%s, withdrawal=%s",
+			model.FormatAmount(account.AvailableAmt), model.FormatAmount(amount))
+	}
+
+	flowID, err := db.CreateCashFlow(tx, &model.CashFlow{
+		AccountID: accountID,
+		Type:      flowType,
+		Amount:    delta,
+		Note:      note,
+	})
+	if err != nil {
+		tx.Rollback()
+		log.Errorf("创建资金流水失败: %v", err)
+		return 0, err
+	}
+
+	if err := db.UpdateAccountAmounts(tx, accountID, delta, 0); err != nil {
+		tx.Rollback()
+		log.Errorf("更新账户金额失败: %v", err)
+		return 0, fmt.Errorf("failed to update account amounts: %w", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Errorf("提交事务失败: %v", err)
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Infof("资金流水已记录: 流水ID=%d", flowID)
+	return flowID, nil
+}
+
+// GetNetContribution 获取账户净投入本金（毫）
+// 计算：初始金额 + 入金 - 出金，分红与利息属于收益，不计入本金
+func GetNetContribution(database *gorm.DB, accountID uint) (int64, error) {
+	account, err := db.GetAccountByID(database, accountID)
+	if err != nil {
+		return 0, err
+	}
+
+	var contributed int64
+	err = database.Model(&model.CashFlow{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("account_id = ? AND type IN ?", accountID, []model.CashFlowType{model.CashFlowTypeDeposit, model.CashFlowTypeWithdrawal}).
+		Scan(&contributed).Error
+	if err != nil {
+		log.Errorf("查询入金出金失败: %v", err)
+		return 0, fmt.Errorf("failed to query contributions: %w", err)
+	}
+
+	return account.InitialAmount + contributed, nil
+}
@@ -0,0 +1,66 @@
+package finsvc
+
+import (
+	"testing"
+
+	msadb "msa/pkg/db"
+	"msa/pkg/model"
+)
+
+func TestRecordCashFlow(t *testing.T) {
+	db := setupTestDB(t)
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(10000))
+
+	if _, err := RecordCashFlow(db, accountID, model.CashFlowTypeDeposit, model.YuanToHao(5000), "追加本金"); err != nil {
+		t.Fatalf("RecordCashFlow deposit failed: %v", err)
+	}
+	if _, err := RecordCashFlow(db, accountID, model.CashFlowTypeInterest, model.YuanToHao(10), "活期利息"); err != nil {
+		t.Fatalf("RecordCashFlow interest failed: %v", err)
+	}
+	if _, err := RecordCashFlow(db, accountID, model.CashFlowTypeWithdrawal, model.YuanToHao(2000), ""); err != nil {
+		t.Fatalf("RecordCashFlow withdrawal failed: %v", err)
+	}
+
+	account, _ := msadb.GetAccountByID(db, accountID)
+	expected := model.YuanToHao(10000 + 5000 + 10 - 2000)
+	if account.AvailableAmt != expected {
+		t.Errorf("Expected available %d, got %d", expected, account.AvailableAmt)
+	}
+
+	// 净投入本金不含利息
+	contribution, err := GetNetContribution(db, accountID)
+	if err != nil {
+		t.Fatalf("GetNetContribution failed: %v", err)
+	}
+	if contribution != model.YuanToHao(13000) {
+		t.Errorf("Expected net contribution %d, got %d", model.YuanToHao(13000), contribution)
+	}
+
+	flows, _ := msadb.GetCashFlowsByAccount(db, accountID)
+	if len(flows) != 3 {
+		t.Fatalf("Expected 3 cash flows, got %d", len(flows))
+	}
+}
+
+func TestRecordCashFlow_Invalid(t *testing.T) {
+	db := setupTestDB(t)
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(1000))
+
+	// 出金超过可用金额
+	if _, err := RecordCashFlow(db, accountID, model.CashFlowTypeWithdrawal, model.YuanToHao(1000.01), ""); err == nil {
+		t.Error("Expected error for withdrawal exceeding available amount")
+	}
+	// 金额必须为正
+	if _, err := RecordCashFlow(db, accountID, model.CashFlowTypeDeposit, 0, ""); err == nil {
+		t.Error("Expected error for zero amount")
+	}
+	// 分红须通过公司行动记录
+	if _, err := RecordCashFlow(db, accountID, model.CashFlowTypeDividend, model.YuanToHao(10), ""); err == nil {
+		t.Error("Expected error for dividend cash flow")
+	}
+
+	account, _ := msadb.GetAccountByID(db, accountID)
+	if account.AvailableAmt != model.YuanToHao(1000) {
+		t.Errorf("Expected available unchanged, got %d", account.AvailableAmt)
+	}
+}
@@ -0,0 +1,448 @@
+package finsvc
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"msa/pkg/db"
+	"msa/pkg/logic/calendar"
+	"msa/pkg/logic/indicator"
+	"msa/pkg/model"
+)
+
+// DefaultATRMultiplier ATR 止损默认倍数
+const DefaultATRMultiplier = 2.0
+
+// DefaultATRPeriod ATR 默认计算周期
+const DefaultATRPeriod = 14
+
+// ConditionalOrderSpec 条件单创建参数
+type ConditionalOrderSpec struct {
+	StockCode      string
+	StockName      string
+	Type           model.ConditionalOrderType
+	Quantity       int64   // 触发后卖出数量，0 表示全部持仓
+	LotSize        int64   // 每手股数（港股）
+	TriggerPrice   int64   // 固定止损/止盈触发价（毫）
+	TrailPercent   float64 // 跟踪止损回撤比例（%）
+	ATR            int64   // ATR 值（毫）
+	ATRMultiplier  float64 // ATR 倍数，为 0 时使用 DefaultATRMultiplier
+	ReferencePrice int64   // 当前价（毫）：跟踪止损与 ATR 止损的初始最高价，并用于校验触发价方向
+	Time           time.Time
+	Note           string
+}
+
+// PricePoint 分时价格点
+type PricePoint struct {
+	At    time.Time
+	Price int64 // 价格（毫）
+}
+
+// ConditionalQuote 条件单评估使用的行情
+type ConditionalQuote struct {
+	Price     int64        // 最新价（毫），触发后按此价格委托卖出
+	PrevClose int64        // 昨收价（毫），用于涨跌幅校验
+	Path      []PricePoint // 分时价格（按时间升序），只评估上次检查之后的价格点
+}
+
+// MinutePricePath 分钟K线转换为分时价格点（交易所时区）
+// 无法解析的K线忽略
+func MinutePricePath(resp *model.StockMinuteKResp) []PricePoint {
+	if resp == nil {
+		return nil
+	}
+	points := make([]PricePoint, 0, len(resp.Bars))
+	for _, bar := range resp.Bars {
+		at, err := time.ParseInLocation("200601021504", resp.Date+bar.Time, chinaLocation)
+		if err != nil || bar.Price <= 0 {
+			continue
+		}
+		points = append(points, PricePoint{At: at, Price: PriceToHao(resp.StockCode, bar.Price)})
+	}
+	return points
+}
+
+// CreateConditionalOrder 在持仓上创建条件单
+// 跟踪止损与 ATR 止损以 ReferencePrice 为初始最高价计算触发价
+func CreateConditionalOrder(database *gorm.DB, accountID uint, spec ConditionalOrderSpec) (uint, error) {
+	log.Infof("创建条件单: 账户=%d, 股票=%s, 类型=%s, 数量=%d", accountID, spec.StockCode, spec.Type, spec.Quantity)
+
+	position, err := GetPosition(database, accountID, spec.StockCode)
+	if err != nil {
+		return 0, err
+	}
+	if position <= 0 {
+		return 0, fmt.Errorf("no position in %s", spec.StockCode)
+	}
+	quantity := spec.Quantity
+	if quantity == 0 {
+		quantity = position
+	}
+	if quantity < 0 || quantity > position {
+		return 0, fmt.Errorf("invalid quantity %d: position is %d", spec.Quantity, position)
+	}
+
+	order := &model.ConditionalOrder{
+		AccountID:    accountID,
+		StockCode:    spec.StockCode,
+		StockName:    spec.StockName,
+		Type:         spec.Type,
+		Status:       model.ConditionalOrderStatusActive,
+		Quantity:     quantity,
+		LotSize:      spec.LotSize,
+		HighestPrice: spec.ReferencePrice,
+		Note:         spec.Note,
+	}
+
+	switch spec.Type {
+	case model.ConditionalOrderTypeStopLoss:
+		if spec.TriggerPrice <= 0 {
+			return 0, fmt.Errorf("stop price must be positive")
+		}
+		if spec.ReferencePrice > 0 && spec.TriggerPrice >= spec.ReferencePrice {
+			return 0, fmt.Errorf("stop price %s must be below current price %s",
+				model.FormatAmount(spec.TriggerPrice), model.FormatAmount(spec.ReferencePrice))
+		}
+		order.TriggerPrice = spec.TriggerPrice
+	case model.ConditionalOrderTypeTakeProfit:
+		if spec.TriggerPrice <= 0 {
+			return 0, fmt.Errorf("take-profit price must be positive")
+		}
+		if spec.ReferencePrice > 0 && spec.TriggerPrice <= spec.ReferencePrice {
+			return 0, fmt.Errorf("take-profit price %s must be above current price %s",
+				model.FormatAmount(spec.TriggerPrice), model.FormatAmount(spec.ReferencePrice))
+		}
+		order.TriggerPrice = spec.TriggerPrice
+	case model.ConditionalOrderTypeTrailingStop:
+		if spec.TrailPercent <= 0 || spec.TrailPercent >= 100 {
+			return 0, fmt.Errorf("trail percent must be between 0 and 100: %v", spec.TrailPercent)
+		}
+		if spec.ReferencePrice <= 0 {
+			return 0, fmt.Errorf("reference price is required for trailing stop")
+		}
+		order.TrailPercent = spec.TrailPercent
+		order.TriggerPrice = trailingTrigger(order)
+	case model.ConditionalOrderTypeATRStop:
+		if spec.ATR <= 0 {
+			return 0, fmt.Errorf("ATR must be positive")
+		}
+		if spec.ReferencePrice <= 0 {
+			return 0, fmt.Errorf("reference price is required for ATR stop")
+		}
+		order.ATR = spec.ATR
+		order.ATRMultiplier = spec.ATRMultiplier
+		if order.ATRMultiplier <= 0 {
+			order.ATRMultiplier = DefaultATRMultiplier
+		}
+		order.TriggerPrice = trailingTrigger(order)
+		if order.TriggerPrice <= 0 {
+			return 0, fmt.Errorf("ATR stop price is not positive: ATR=%s, multiplier=%v",
+				model.FormatAmount(spec.ATR), order.ATRMultiplier)
+		}
+	default:
+		return 0, fmt.Errorf("unsupported conditional order type: %s", spec.Type)
+	}
+
+	at := spec.Time
+	if at.IsZero() {
+		at = calendar.Now()
+	}
+	order.CreatedAt = at
+	order.CheckedAt = at
+
+	orderID, err := db.CreateConditionalOrder(database, order)
+	if err != nil {
+		log.Errorf("创建条件单失败: %v", err)
+		return 0, err
+	}
+
+	log.Infof("条件单已创建: ID=%d, 触发价=%d", orderID, order.TriggerPrice)
+	return orderID, nil
+}
+
+// CancelConditionalOrder 撤销监控中的条件单
+func CancelConditionalOrder(database *gorm.DB, accountID uint, orderID uint, reason string) error {
+	order, err := db.GetConditionalOrderByID(database, orderID)
+	if err != nil {
+		return err
+	}
+	if order.AccountID != accountID {
+		return fmt.Errorf("conditional order %d does not belong to account %d", orderID, accountID)
+	}
+	if order.Status != model.ConditionalOrderStatusActive {
+		return fmt.Errorf("conditional order %d is %s, cannot cancel", orderID, order.Status)
+	}
+
+	order.Status = model.ConditionalOrderStatusCancelled
+	order.Note = appendNote(order.Note, reason)
+	if err := db.SaveConditionalOrder(database, order); err != nil {
+		log.Errorf("撤销条件单失败: %v", err)
+		return err
+	}
+
+	log.Infof("条件单已撤销: ID=%d, 原因=%s", orderID, reason)
+	return nil
+}
+
+// EvaluateConditionalOrders 按行情评估账户所有监控中的条件单
+// 依次用上次检查之后的分时价格与最新价更新最高价、上移跟踪止损触发价并判断是否触发
+// 触发后按最新价提交卖出订单，返回新建的卖出交易ID；非交易时段或无可卖数量时只更新最高价
+// 已提交卖出订单的条件单在卖单全部成交后标记为 TRIGGERED，卖单未成交即被撤销或过期时恢复监控
+// 持仓已清空的条件单自动撤销
+func EvaluateConditionalOrders(database *gorm.DB, accountID uint, quotes map[string]ConditionalQuote, at time.Time) ([]uint, error) {
+	orders, err := db.GetConditionalOrdersByAccount(database, accountID, model.ConditionalOrderStatusActive)
+	if err != nil {
+		log.Errorf("查询条件单失败: %v", err)
+		return nil, err
+	}
+
+	var transIDs []uint
+	for _, order := range orders {
+		if order.TransactionID != nil {
+			settled, err := settleConditionalSell(database, order)
+			if err != nil {
+				return transIDs, err
+			}
+			if settled {
+				continue
+			}
+		}
+
+		quote, ok := quotes[order.StockCode]
+		if !ok || quote.Price <= 0 {
+			continue
+		}
+
+		position, err := GetPosition(database, accountID, order.StockCode)
+		if err != nil {
+			return transIDs, err
+		}
+		if position <= 0 {
+			if err := CancelConditionalOrder(database, accountID, order.ID, "持仓已清空，条件单自动撤销"); err != nil {
+				return transIDs, err
+			}
+			continue
+		}
+
+		triggered := observeQuote(order, quote, at)
+		if triggered {
+			transID, err := triggerConditionalOrder(database, order, position, quote, at)
+			if err != nil {
+				return transIDs, err
+			}
+			if transID > 0 {
+				transIDs = append(transIDs, transID)
+				continue
+			}
+		}
+
+		if err := db.SaveConditionalOrder(database, order); err != nil {
+			log.Errorf("更新条件单失败: %v", err)
+			return transIDs, err
+		}
+	}
+	return transIDs, nil
+}
+
+// settleConditionalSell 跟进条件单已提交的卖出订单（内部函数）
+// 卖单仍在挂单时不再评估；全部成交后标记为 TRIGGERED；未成交部分被撤销或过期时解除关联、恢复监控
+// 返回 true 表示本次无需继续评估该条件单
+func settleConditionalSell(database *gorm.DB, order *model.ConditionalOrder) (bool, error) {
+	transID := *order.TransactionID
+	status, err := conditionalSellStatus(database, transID)
+	if err != nil {
+		return false, err
+	}
+
+	switch status {
+	case model.TransactionStatusPending:
+		return true, nil
+	case model.TransactionStatusFilled:
+		order.Status = model.ConditionalOrderStatusTriggered
+		if err := db.SaveConditionalOrder(database, order); err != nil {
+			log.Errorf("更新条件单失败: %v", err)
+			return false, err
+		}
+		log.Infof("条件单卖出订单已成交: ID=%d, 卖出交易ID=%d", order.ID, transID)
+		return true, nil
+	default:
+		order.TransactionID = nil
+		order.TriggeredAt = nil
+		order.Note = appendNote(order.Note, fmt.Sprintf("卖出订单 #%d 未成交（%s），恢复监控", transID, status))
+		if err := db.SaveConditionalOrder(database, order); err != nil {
+			log.Errorf("更新条件单失败: %v", err)
+			return false, err
+		}
+		log.Warnf("条件单卖出订单未成交，恢复监控: ID=%d, 卖出交易ID=%d, 状态=%s", order.ID, transID, status)
+		return false, nil
+	}
+}
+
+// conditionalSellStatus 卖出订单的结果状态，部分成交时按拆分出的子记录判断（内部函数）
+// 仍有剩余挂单时为 PENDING，全部成交为 FILLED，未成交部分已撤销或过期时为 CANCELLED
+func conditionalSellStatus(database *gorm.DB, transID uint) (model.TransactionStatus, error) {
+	trans, err := db.GetTransactionByID(database, transID)
+	if err != nil {
+		return "", err
+	}
+	if trans.Status != model.TransactionStatusObsolete {
+		return trans.Status, nil
+	}
+
+	children, err := db.GetTransactionsByParent(database, transID)
+	if err != nil {
+		return "", err
+	}
+	status := model.TransactionStatusFilled
+	for _, child := range children {
+		childStatus, err := conditionalSellStatus(database, child.ID)
+		if err != nil {
+			return "", err
+		}
+		switch childStatus {
+		case model.TransactionStatusPending:
+			return model.TransactionStatusPending, nil
+		case model.TransactionStatusFilled:
+		default:
+			status = model.TransactionStatusCancelled
+		}
+	}
+	return status, nil
+}
+
+// observeQuote 按时间顺序用上次检查之后的价格更新最高价与触发价，返回是否触发
+// 触发时检查时间停在触发前，条件单未能成交时下次评估会再次触发
+func observeQuote(order *model.ConditionalOrder, quote ConditionalQuote, at time.Time) bool {
+	points := make([]PricePoint, 0, len(quote.Path)+1)
+	for _, p := range quote.Path {
+		if p.At.After(order.CheckedAt) && p.At.Before(at) {
+			points = append(points, p)
+		}
+	}
+	points = append(points, PricePoint{At: at, Price: quote.Price})
+
+	for _, p := range points {
+		if p.Price > order.HighestPrice {
+			order.HighestPrice = p.Price
+			if order.Type == model.ConditionalOrderTypeTrailingStop || order.Type == model.ConditionalOrderTypeATRStop {
+				// 触发价只能上移
+				order.TriggerPrice = max(order.TriggerPrice, trailingTrigger(order))
+			}
+		}
+
+		if order.IsStop() && p.Price <= order.TriggerPrice || !order.IsStop() && p.Price >= order.TriggerPrice {
+			log.Infof("条件单触发: ID=%d, 类型=%s, 价格=%d, 触发价=%d, 时间=%s",
+				order.ID, order.Type, p.Price, order.TriggerPrice, p.At.Format(time.DateTime))
+			return true
+		}
+		order.CheckedAt = p.At
+	}
+	return false
+}
+
+// trailingTrigger 按最高价计算跟踪止损与 ATR 止损的触发价
+func trailingTrigger(order *model.ConditionalOrder) int64 {
+	switch order.Type {
+	case model.ConditionalOrderTypeTrailingStop:
+		return int64(float64(order.HighestPrice) * (1 - order.TrailPercent/100))
+	case model.ConditionalOrderTypeATRStop:
+		return order.HighestPrice - int64(order.ATRMultiplier*float64(order.ATR))
+	default:
+		return order.TriggerPrice
+	}
+}
+
+// CalculateATR 根据日K线计算 ATR（毫）
+// TR = max(H-L, |H-Cp|, |L-Cp|)，首根K线 TR = H-L；ATR 以前 period 个 TR 的均值为初值按 Wilder 平滑
+// K线不足 period 根时使用全部 TR 的简单平均作为近似值，approximate 返回 true
+func CalculateATR(bars []model.KLineBar, period int) (atr int64, approximate bool, err error) {
+	if len(bars) == 0 {
+		return 0, false, fmt.Errorf("no K-line data for ATR")
+	}
+	if period <= 0 {
+		period = DefaultATRPeriod
+	}
+
+	series, err := indicator.FromKLineBars(bars)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if len(series) < period {
+		var sum float64
+		for _, tr := range indicator.TrueRange(series) {
+			sum += tr
+		}
+		return model.YuanToHao(sum / float64(len(series))), true, nil
+	}
+
+	value, _ := indicator.Last(indicator.ATR(series, period))
+	return model.YuanToHao(value), false, nil
+}
+
+// triggerConditionalOrder 条件单触发后提交卖出订单
+// 卖出数量不超过可卖数量，部分卖出时按整手向下取整；无可卖数量或订单被拒绝时条件单保持监控
+// 提交成功后关联卖出订单，条件单在卖单成交前仍为 ACTIVE，由 settleConditionalSell 跟进
+// 返回卖出交易ID，0 表示未提交
+func triggerConditionalOrder(database *gorm.DB, order *model.ConditionalOrder, position int64, quote ConditionalQuote, at time.Time) (uint, error) {
+	board := DetectBoard(order.StockCode)
+	if !IsTradingSession(board, at) {
+		log.Infof("条件单已触发但不在交易时段，等待下次评估: ID=%d", order.ID)
+		return 0, nil
+	}
+
+	sellable, err := GetSellableQuantity(database, order.AccountID, order.StockCode, at)
+	if err != nil {
+		return 0, err
+	}
+	quantity := min(order.Quantity, sellable)
+	lotSize := DefaultLotSize
+	if board == BoardHK {
+		lotSize = order.LotSize
+	}
+	if lotSize > 0 && quantity < position && quantity%lotSize != position%lotSize {
+		quantity -= quantity % lotSize
+	}
+	if quantity <= 0 {
+		log.Infof("条件单已触发但无可卖数量，等待下次评估: ID=%d, 可卖=%d", order.ID, sellable)
+		return 0, nil
+	}
+
+	transID, err := SubmitSellOrder(database, order.AccountID, Order{
+		StockCode:  order.StockCode,
+		StockName:  order.StockName,
+		Quantity:   quantity,
+		Price:      quote.Price,
+		Note:       fmt.Sprintf("条件单 #%d %s 触发（触发价 %s）", order.ID, order.Type, model.FormatAmount(order.TriggerPrice)),
+		Time:       at,
+		PrevClose:  quote.PrevClose,
+		LotSize:    order.LotSize,
+		Protective: true,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	trans, err := db.GetTransactionByID(database, transID)
+	if err != nil {
+		return 0, err
+	}
+	if trans.Status == model.TransactionStatusRejected {
+		log.Warnf("条件单卖出订单被拒绝，条件单保持监控: ID=%d, 原因=%s", order.ID, trans.Note)
+		return 0, nil
+	}
+
+	triggeredAt := at
+	order.TriggeredAt = &triggeredAt
+	order.TransactionID = &transID
+	if err := db.SaveConditionalOrder(database, order); err != nil {
+		log.Errorf("更新条件单失败: %v", err)
+		return 0, err
+	}
+
+	log.Infof("条件单已触发，卖出订单待成交: ID=%d, 卖出交易ID=%d, 数量=%d, 价格=%d", order.ID, transID, quantity, quote.Price)
+	return transID, nil
+}
@@ -0,0 +1,319 @@
+package finsvc
+
+import (
+	"testing"
+	"time"
+
+	msadb "msa/pkg/db"
+	"msa/pkg/model"
+)
+
+func TestCreateConditionalOrder(t *testing.T) {
+	db := setupTestDB(t)
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(100000))
+
+	// 无持仓
+	_, err := CreateConditionalOrder(db, accountID, ConditionalOrderSpec{
+		StockCode: "sh600519", StockName: "贵州茅台", Type: model.ConditionalOrderTypeStopLoss, TriggerPrice: model.YuanToHao(9),
+	})
+	if err == nil {
+		t.Error("Expected error for no position")
+	}
+
+	buyID, _ := SubmitBuyOrder(db, accountID, Order{
+		StockCode: "sh600519", StockName: "贵州茅台", Quantity: 1000, Price: model.YuanToHao(10), Time: testTradeTime,
+	})
+	FillOrder(db, buyID)
+
+	tests := []struct {
+		name    string
+		spec    ConditionalOrderSpec
+		wantErr bool
+		trigger int64
+	}{
+		{
+			name:    "止损价高于现价",
+			spec:    ConditionalOrderSpec{Type: model.ConditionalOrderTypeStopLoss, TriggerPrice: model.YuanToHao(11), ReferencePrice: model.YuanToHao(10)},
+			wantErr: true,
+		},
+		{
+			name:    "止盈价低于现价",
+			spec:    ConditionalOrderSpec{Type: model.ConditionalOrderTypeTakeProfit, TriggerPrice: model.YuanToHao(9), ReferencePrice: model.YuanToHao(10)},
+			wantErr: true,
+		},
+		{
+			name:    "超过持仓数量",
+			spec:    ConditionalOrderSpec{Type: model.ConditionalOrderTypeStopLoss, Quantity: 2000, TriggerPrice: model.YuanToHao(9)},
+			wantErr: true,
+		},
+		{
+			name:    "跟踪止损比例无效",
+			spec:    ConditionalOrderSpec{Type: model.ConditionalOrderTypeTrailingStop, TrailPercent: 0, ReferencePrice: model.YuanToHao(10)},
+			wantErr: true,
+		},
+		{
+			name:    "跟踪止损",
+			spec:    ConditionalOrderSpec{Type: model.ConditionalOrderTypeTrailingStop, TrailPercent: 5, ReferencePrice: model.YuanToHao(10)},
+			trigger: model.YuanToHao(9.5),
+		},
+		{
+			name:    "ATR 止损默认 2 倍",
+			spec:    ConditionalOrderSpec{Type: model.ConditionalOrderTypeATRStop, ATR: model.YuanToHao(0.35), ReferencePrice: model.YuanToHao(10)},
+			trigger: model.YuanToHao(9.3),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.spec.StockCode = "sh600519"
+			tt.spec.StockName = "贵州茅台"
+			tt.spec.Time = testTradeTime
+			orderID, err := CreateConditionalOrder(db, accountID, tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateConditionalOrder failed: %v", err)
+			}
+			order, _ := msadb.GetConditionalOrderByID(db, orderID)
+			if order.TriggerPrice != tt.trigger || order.Quantity != 1000 {
+				t.Errorf("Expected trigger %d qty 1000, got %d qty %d", tt.trigger, order.TriggerPrice, order.Quantity)
+			}
+		})
+	}
+}
+
+func TestEvaluateConditionalOrders_TrailingStop(t *testing.T) {
+	db := setupTestDB(t)
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(100000))
+
+	buyID, _ := SubmitBuyOrder(db, accountID, Order{
+		StockCode: "sh600519", StockName: "贵州茅台", Quantity: 1000, Price: model.YuanToHao(10), Time: testTradeTime,
+	})
+	FillOrder(db, buyID)
+
+	orderID, err := CreateConditionalOrder(db, accountID, ConditionalOrderSpec{
+		StockCode: "sh600519", StockName: "贵州茅台", Type: model.ConditionalOrderTypeTrailingStop,
+		TrailPercent: 10, ReferencePrice: model.YuanToHao(10), Time: testTradeTime,
+	})
+	if err != nil {
+		t.Fatalf("CreateConditionalOrder failed: %v", err)
+	}
+
+	// 次日上涨到 12 元，止损价上移至 10.8 元
+	at := testNextTradeTime
+	quote := ConditionalQuote{
+		Price: model.YuanToHao(11.5),
+		Path: []PricePoint{
+			{At: at.Add(-30 * time.Minute), Price: model.YuanToHao(11)},
+			{At: at.Add(-10 * time.Minute), Price: model.YuanToHao(12)},
+		},
+	}
+	transIDs, err := EvaluateConditionalOrders(db, accountID, map[string]ConditionalQuote{"sh600519": quote}, at)
+	if err != nil || len(transIDs) != 0 {
+		t.Fatalf("Expected no trigger, got %v, %v", transIDs, err)
+	}
+	order, _ := msadb.GetConditionalOrderByID(db, orderID)
+	if order.HighestPrice != model.YuanToHao(12) || order.TriggerPrice != model.YuanToHao(10.8) {
+		t.Errorf("Expected highest 12 and trigger 10.8, got %d / %d", order.HighestPrice, order.TriggerPrice)
+	}
+
+	// 回落到 11 元止损价不下移；跌破 10.8 元触发卖出
+	at = at.Add(20 * time.Minute)
+	quote = ConditionalQuote{
+		Price: model.YuanToHao(10.7),
+		Path:  []PricePoint{{At: at.Add(-5 * time.Minute), Price: model.YuanToHao(11)}},
+	}
+	transIDs, err = EvaluateConditionalOrders(db, accountID, map[string]ConditionalQuote{"sh600519": quote}, at)
+	if err != nil || len(transIDs) != 1 {
+		t.Fatalf("Expected one sell order, got %v, %v", transIDs, err)
+	}
+
+	trans, _ := msadb.GetTransactionByID(db, transIDs[0])
+	if trans.Type != model.TransactionTypeSell || trans.Quantity != 1000 || trans.Price != model.YuanToHao(10.7) {
+		t.Errorf("Unexpected sell order: %+v", trans)
+	}
+	order, _ = msadb.GetConditionalOrderByID(db, orderID)
+	if order.Status != model.ConditionalOrderStatusActive || order.TransactionID == nil || *order.TransactionID != transIDs[0] {
+		t.Errorf("Expected active order linked to pending sell, got %+v", order)
+	}
+
+	// 卖单挂单期间不重复触发
+	sellID := transIDs[0]
+	transIDs, err = EvaluateConditionalOrders(db, accountID, map[string]ConditionalQuote{"sh600519": quote}, at.Add(time.Minute))
+	if err != nil || len(transIDs) != 0 {
+		t.Fatalf("Expected no new sell order while pending, got %v, %v", transIDs, err)
+	}
+
+	// 卖单成交后标记为已触发
+	if err := FillOrder(db, sellID); err != nil {
+		t.Fatalf("FillOrder failed: %v", err)
+	}
+	if _, err := EvaluateConditionalOrders(db, accountID, map[string]ConditionalQuote{"sh600519": quote}, at.Add(2*time.Minute)); err != nil {
+		t.Fatalf("EvaluateConditionalOrders failed: %v", err)
+	}
+	order, _ = msadb.GetConditionalOrderByID(db, orderID)
+	if order.Status != model.ConditionalOrderStatusTriggered || order.TransactionID == nil || *order.TransactionID != sellID {
+		t.Errorf("Expected triggered order linked to filled sell, got %+v", order)
+	}
+}
+
+func TestEvaluateConditionalOrders_SellExpired(t *testing.T) {
+	db := setupTestDB(t)
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(100000))
+
+	buyID, _ := SubmitBuyOrder(db, accountID, Order{
+		StockCode: "sh600519", StockName: "贵州茅台", Quantity: 1000, Price: model.YuanToHao(10), Time: testTradeTime,
+	})
+	FillOrder(db, buyID)
+	stopID, _ := CreateConditionalOrder(db, accountID, ConditionalOrderSpec{
+		StockCode: "sh600519", StockName: "贵州茅台", Type: model.ConditionalOrderTypeStopLoss,
+		TriggerPrice: model.YuanToHao(9), Time: testTradeTime,
+	})
+
+	quotes := map[string]ConditionalQuote{"sh600519": {Price: model.YuanToHao(8.9)}}
+	transIDs, err := EvaluateConditionalOrders(db, accountID, quotes, testNextTradeTime)
+	if err != nil || len(transIDs) != 1 {
+		t.Fatalf("Expected one sell order, got %v, %v", transIDs, err)
+	}
+
+	// 价格跳空下跌，部分成交后剩余挂单过期：条件单恢复监控并再次触发
+	_, remainderID, err := PartialFillOrder(db, transIDs[0], 400)
+	if err != nil {
+		t.Fatalf("PartialFillOrder failed: %v", err)
+	}
+	if err := CancelOrder(db, remainderID, "当日有效订单已过期"); err != nil {
+		t.Fatalf("CancelOrder failed: %v", err)
+	}
+	quotes = map[string]ConditionalQuote{"sh600519": {Price: model.YuanToHao(8.5)}}
+	transIDs, err = EvaluateConditionalOrders(db, accountID, quotes, testNextTradeTime.Add(time.Hour))
+	if err != nil || len(transIDs) != 1 {
+		t.Fatalf("Expected re-armed stop to submit a new sell order, got %v, %v", transIDs, err)
+	}
+	stop, _ := msadb.GetConditionalOrderByID(db, stopID)
+	if stop.Status != model.ConditionalOrderStatusActive || stop.TransactionID == nil || *stop.TransactionID != transIDs[0] {
+		t.Errorf("Expected stop linked to new sell, got %+v", stop)
+	}
+	if trans, _ := msadb.GetTransactionByID(db, transIDs[0]); trans.Quantity != 600 {
+		t.Errorf("Expected remaining 600 shares to be sold, got %d", trans.Quantity)
+	}
+}
+
+func TestEvaluateConditionalOrders_RiskLimitReached(t *testing.T) {
+	db := setupTestDB(t)
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(100000))
+
+	buyID, _ := SubmitBuyOrder(db, accountID, Order{
+		StockCode: "sh600519", StockName: "贵州茅台", Quantity: 1000, Price: model.YuanToHao(10), Time: testTradeTime,
+	})
+	FillOrder(db, buyID)
+	stopID, _ := CreateConditionalOrder(db, accountID, ConditionalOrderSpec{
+		StockCode: "sh600519", StockName: "贵州茅台", Type: model.ConditionalOrderTypeStopLoss,
+		TriggerPrice: model.YuanToHao(9), Time: testTradeTime,
+	})
+
+	// 当日委托笔数已用完：手动卖出被拒绝，止损仍然触发
+	setRiskPolicy(t, model.RiskPolicy{MaxOrdersPerDay: 1, MaxDailyTurnoverPct: 1})
+	setRiskDataSource(t, fakeRiskDataSource{prices: PriceMap{"sh600519": model.YuanToHao(9.5)}})
+	buyID, err := SubmitBuyOrder(db, accountID, Order{
+		StockCode: "sh600000", StockName: "浦发银行", Quantity: 100, Price: model.YuanToHao(8), Time: testNextTradeTime,
+	})
+	if trans, _ := msadb.GetTransactionByID(db, buyID); err != nil || trans.Status != model.TransactionStatusPending {
+		t.Fatalf("Expected buy within limits to be pending, got %+v, %v", trans, err)
+	}
+	sellID, _ := SubmitSellOrder(db, accountID, Order{
+		StockCode: "sh600519", StockName: "贵州茅台", Quantity: 100, Price: model.YuanToHao(9.5), Time: testNextTradeTime,
+	})
+	if trans, _ := msadb.GetTransactionByID(db, sellID); trans.Status != model.TransactionStatusRejected {
+		t.Fatalf("Expected manual sell rejected by risk policy, got %s", trans.Status)
+	}
+
+	quotes := map[string]ConditionalQuote{"sh600519": {Price: model.YuanToHao(8.9)}}
+	transIDs, err := EvaluateConditionalOrders(db, accountID, quotes, testNextTradeTime.Add(time.Minute))
+	if err != nil || len(transIDs) != 1 {
+		t.Fatalf("Expected stop loss to submit a sell order, got %v, %v", transIDs, err)
+	}
+	if trans, _ := msadb.GetTransactionByID(db, transIDs[0]); trans.Status != model.TransactionStatusPending || trans.Quantity != 1000 {
+		t.Errorf("Expected pending sell of 1000 shares, got %s %d", trans.Status, trans.Quantity)
+	}
+	stop, _ := msadb.GetConditionalOrderByID(db, stopID)
+	if stop.TransactionID == nil || *stop.TransactionID != transIDs[0] {
+		t.Errorf("Expected stop linked to sell order, got %+v", stop)
+	}
+}
+
+func TestEvaluateConditionalOrders_T1AndClosedPosition(t *testing.T) {
+	db := setupTestDB(t)
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(100000))
+
+	buyID, _ := SubmitBuyOrder(db, accountID, Order{
+		StockCode: "sh600519", StockName: "贵州茅台", Quantity: 1000, Price: model.YuanToHao(10), Time: testTradeTime,
+	})
+	FillOrder(db, buyID)
+
+	stopID, _ := CreateConditionalOrder(db, accountID, ConditionalOrderSpec{
+		StockCode: "sh600519", StockName: "贵州茅台", Type: model.ConditionalOrderTypeStopLoss,
+		TriggerPrice: model.YuanToHao(9), Time: testTradeTime,
+	})
+	profitID, _ := CreateConditionalOrder(db, accountID, ConditionalOrderSpec{
+		StockCode: "sh600519", StockName: "贵州茅台", Type: model.ConditionalOrderTypeTakeProfit,
+		TriggerPrice: model.YuanToHao(12), Time: testTradeTime,
+	})
+
+	// 当日买入 T+1 不可卖：止损触发但保持监控，不生成卖出订单
+	at := testTradeTime.Add(time.Hour)
+	quotes := map[string]ConditionalQuote{"sh600519": {Price: model.YuanToHao(8.9)}}
+	transIDs, err := EvaluateConditionalOrders(db, accountID, quotes, at)
+	if err != nil || len(transIDs) != 0 {
+		t.Fatalf("Expected no sell order under T+1, got %v, %v", transIDs, err)
+	}
+	stop, _ := msadb.GetConditionalOrderByID(db, stopID)
+	if stop.Status != model.ConditionalOrderStatusActive {
+		t.Errorf("Expected stop order still active, got %s", stop.Status)
+	}
+
+	// 次日价格仍低于止损价，再次触发并卖出
+	transIDs, err = EvaluateConditionalOrders(db, accountID, quotes, testNextTradeTime)
+	if err != nil || len(transIDs) != 1 {
+		t.Fatalf("Expected one sell order, got %v, %v", transIDs, err)
+	}
+	if err := FillOrder(db, transIDs[0]); err != nil {
+		t.Fatalf("FillOrder failed: %v", err)
+	}
+
+	// 持仓清空后止盈单自动撤销
+	if _, err := EvaluateConditionalOrders(db, accountID, quotes, testNextTradeTime.Add(time.Minute)); err != nil {
+		t.Fatalf("EvaluateConditionalOrders failed: %v", err)
+	}
+	profit, _ := msadb.GetConditionalOrderByID(db, profitID)
+	if profit.Status != model.ConditionalOrderStatusCancelled {
+		t.Errorf("Expected take-profit order cancelled, got %s", profit.Status)
+	}
+}
+
+func TestCalculateATR(t *testing.T) {
+	bars := []model.KLineBar{
+		{Date: "2025-03-03", High: "10.5", Low: "9.5", Close: "10"},
+		{Date: "2025-03-04", High: "11", Low: "10.2", Close: "10.8"},
+		{Date: "2025-03-05", High: "10.9", Low: "10.1", Close: "10.3"},
+	}
+
+	// TR: 1.0, max(0.8, 1.0, 0.2)=1.0, max(0.8, 0.1, 0.7)=0.8
+	atr, approximate, err := CalculateATR(bars, 2)
+	if err != nil {
+		t.Fatalf("CalculateATR failed: %v", err)
+	}
+	// 初值 (1.0+1.0)/2=1.0，平滑 (1.0×1+0.8)/2=0.9
+	if approximate || atr != 9000 {
+		t.Errorf("Expected ATR 9000 (exact), got %d (approximate=%v)", atr, approximate)
+	}
+
+	// K线不足时使用简单平均
+	atr, approximate, _ = CalculateATR(bars, 14)
+	if !approximate || atr != 9333 {
+		t.Errorf("Expected approximate ATR 9333, got %d (approximate=%v)", atr, approximate)
+	}
+}
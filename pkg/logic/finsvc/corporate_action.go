@@ -0,0 +1,218 @@
+package finsvc
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"msa/pkg/db"
+	"msa/pkg/logic/calendar"
+	"msa/pkg/model"
+)
+
+// CorporateAction 公司行动参数
+type CorporateAction struct {
+	StockCode    string
+	StockName    string
+	Type         model.CorporateActionType
+	CashPerShare int64     // 每股派息（毫），现金分红使用（10派5元 → 0.5 元）
+	Ratio        float64   // 送转：每股送转股数（10送3转2 → 0.5）；拆合股：每股变为股数（1拆2 → 2，10合1 → 0.1）
+	ExDate       time.Time // 除权除息日，为空时使用当前时间
+	Note         string
+}
+
+// exDate 获取除权除息日，未指定时使用当前时间
+func (a CorporateAction) exDate() time.Time {
+	if a.ExDate.IsZero() {
+		return calendar.Now()
+	}
+	return a.ExDate
+}
+
+// ApplyCorporateAction 按除权除息日的持仓处理公司行动
+// 持仓取除权除息日之前成交的买卖与此前已处理的送转、拆合股，除权除息日之后的交易不影响分红与送转数量
+// 现金分红：增加可用金额并记录 DIVIDEND 资金流水，分红金额计入已实现盈亏
+// 送转/拆合股：调整持仓数量，不改变持仓成本；不足 1 股的部分舍去
+// 同一股票同一类型在同一除权日只能处理一次
+func ApplyCorporateAction(database *gorm.DB, accountID uint, action CorporateAction) (uint, error) {
+	log.Infof("处理公司行动: 账户=%d, 股票=%s(%s), 类型=%s, 每股派息=%d, 比例=%v",
+		accountID, action.StockName, action.StockCode, action.Type, action.CashPerShare, action.Ratio)
+
+	switch action.Type {
+	case model.CorporateActionTypeDividend:
+		if action.CashPerShare <= 0 {
+			return 0, fmt.Errorf("cash per share must be positive: %d", action.CashPerShare)
+		}
+	case model.CorporateActionTypeBonus, model.CorporateActionTypeSplit:
+		if action.Ratio <= 0 {
+			return 0, fmt.Errorf("ratio must be positive: %v", action.Ratio)
+		}
+	default:
+		return 0, fmt.Errorf("unsupported corporate action type: %s", action.Type)
+	}
+
+	tx := database.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			log.Errorf("公司行动 panic: %v", r)
+		}
+	}()
+
+	at := action.exDate()
+	applied, err := hasCorporateActionOn(tx, accountID, action.StockCode, action.Type, at)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if applied {
+		tx.Rollback()
+		return 0, fmt.Errorf("corporate action %s for %s already applied on %s",
+			action.Type, action.StockCode, at.In(chinaLocation).Format("2006-01-02"))
+	}
+
+	position, err := getPositionOnExDate(tx, accountID, action.StockCode, at)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if position <= 0 {
+		tx.Rollback()
+		return 0, fmt.Errorf("no position in %s before ex-date %s", action.StockCode, at.In(chinaLocation).Format("2006-01-02"))
+	}
+
+	record := &model.CorporateAction{
+		AccountID:      accountID,
+		StockCode:      action.StockCode,
+		StockName:      action.StockName,
+		Type:           action.Type,
+		CashPerShare:   action.CashPerShare,
+		Ratio:          action.Ratio,
+		PositionBefore: position,
+		Note:           action.Note,
+	}
+	record.CreatedAt = at
+
+	switch action.Type {
+	case model.CorporateActionTypeDividend:
+		record.CashAmount = position * action.CashPerShare
+	case model.CorporateActionTypeBonus:
+		record.QuantityDelta = int64(math.Floor(float64(position) * action.Ratio))
+	case model.CorporateActionTypeSplit:
+		record.QuantityDelta = int64(math.Floor(float64(position)*action.Ratio)) - position
+	}
+
+	actionID, err := db.CreateCorporateAction(tx, record)
+	if err != nil {
+		tx.Rollback()
+		log.Errorf("创建公司行动记录失败: %v", err)
+		return 0, err
+	}
+
+	if record.CashAmount > 0 {
+		flow := &model.CashFlow{
+			AccountID: accountID,
+			Type:      model.CashFlowTypeDividend,
+			Amount:    record.CashAmount,
+			StockCode: action.StockCode,
+			Note:      fmt.Sprintf("%s 现金分红，持仓 %d 股", action.StockName, position),
+		}
+		flow.CreatedAt = at
+		if _, err := db.CreateCashFlow(tx, flow); err != nil {
+			tx.Rollback()
+			log.Errorf("创建分红流水失败: %v", err)
+			return 0, err
+		}
+		if err := db.UpdateAccountAmounts(tx, accountID, record.CashAmount, 0); err != nil {
+			tx.Rollback()
+			log.Errorf("更新账户金额失败: %v", err)
+			return 0, fmt.Errorf("failed to update account amounts: %w", err)
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Errorf("提交事务失败: %v", err)
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Infof("公司行动已处理: ID=%d, 持仓变化=%d, 分红=%d", actionID, record.QuantityDelta, record.CashAmount)
+	return actionID, nil
+}
+
+// hasCorporateActionOn 判断同一股票同一类型的公司行动在 at 所在交易日是否已处理
+// 时间比较在内存中按时区换算进行，与 T+1 判断保持一致
+func hasCorporateActionOn(database *gorm.DB, accountID uint, stockCode string, actionType model.CorporateActionType, at time.Time) (bool, error) {
+	var actions []*model.CorporateAction
+	err := database.Where("account_id = ? AND stock_code = ? AND type = ?", accountID, stockCode, actionType).
+		Find(&actions).Error
+	if err != nil {
+		log.Errorf("查询公司行动失败: %v", err)
+		return false, fmt.Errorf("failed to query corporate actions: %w", err)
+	}
+
+	day := tradingDayStart(at)
+	for _, a := range actions {
+		if tradingDayStart(a.CreatedAt).Equal(day) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// getPositionOnExDate 获取除权除息日享有权益的持仓数量（内部函数）
+// 成交记录取 exDate 所在交易日零点之前的买卖（除权除息日当天买入不享有权益），
+// 公司行动取不晚于 exDate 的记录（同一除权日先送转、后拆合股时按顺序累计）
+// 时间比较在内存中按时区换算进行，与 T+1 判断保持一致
+func getPositionOnExDate(database *gorm.DB, accountID uint, stockCode string, exDate time.Time) (int64, error) {
+	var transactions []*model.Transaction
+	err := database.Where("account_id = ? AND stock_code = ? AND status = ?", accountID, stockCode, model.TransactionStatusFilled).
+		Find(&transactions).Error
+	if err != nil {
+		log.Errorf("查询成交记录失败: %v", err)
+		return 0, fmt.Errorf("failed to query filled transactions: %w", err)
+	}
+
+	var actions []*model.CorporateAction
+	err = database.Where("account_id = ? AND stock_code = ?", accountID, stockCode).Find(&actions).Error
+	if err != nil {
+		log.Errorf("查询公司行动失败: %v", err)
+		return 0, fmt.Errorf("failed to query corporate actions: %w", err)
+	}
+
+	day := tradingDayStart(exDate)
+	var qty int64
+	for _, t := range transactions {
+		if !t.CreatedAt.Before(day) {
+			continue
+		}
+		switch t.Type {
+		case model.TransactionTypeBuy:
+			qty += t.Quantity
+		case model.TransactionTypeSell:
+			qty -= t.Quantity
+		}
+	}
+	for _, a := range actions {
+		if !a.CreatedAt.After(exDate) {
+			qty += a.QuantityDelta
+		}
+	}
+	return qty, nil
+}
+
+// getCorporateQuantityDelta 统计公司行动带来的持仓数量变化（内部函数）
+func getCorporateQuantityDelta(database *gorm.DB, accountID uint, stockCode string) (int64, error) {
+	var delta int64
+	err := database.Model(&model.CorporateAction{}).
+		Select("COALESCE(SUM(quantity_delta), 0)").
+		Where("account_id = ? AND stock_code = ?", accountID, stockCode).
+		Scan(&delta).Error
+	if err != nil {
+		log.Errorf("查询送转数量失败: %v", err)
+		return 0, fmt.Errorf("failed to query corporate quantity delta: %w", err)
+	}
+	return delta, nil
+}
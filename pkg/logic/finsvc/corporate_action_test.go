@@ -0,0 +1,192 @@
+package finsvc
+
+import (
+	"testing"
+	"time"
+
+	msadb "msa/pkg/db"
+	"msa/pkg/model"
+)
+
+func TestApplyCorporateAction_Dividend(t *testing.T) {
+	db := setupTestDB(t)
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(100000))
+
+	buyID, _ := SubmitBuyOrder(db, accountID, Order{
+		StockCode: "sh600519", StockName: "贵州茅台", Quantity: 1000, Price: model.YuanToHao(10), Time: testTradeTime,
+	})
+	FillOrder(db, buyID)
+	bookBefore, _ := BuildLotBook(db, accountID, "sh600519", CostMethodAverage)
+	account, _ := msadb.GetAccountByID(db, accountID)
+	availableBefore := account.AvailableAmt
+
+	// 10派5元
+	_, err := ApplyCorporateAction(db, accountID, CorporateAction{
+		StockCode: "sh600519", StockName: "贵州茅台", Type: model.CorporateActionTypeDividend,
+		CashPerShare: model.YuanToHao(0.5), ExDate: testNextTradeTime,
+	})
+	if err != nil {
+		t.Fatalf("ApplyCorporateAction failed: %v", err)
+	}
+
+	account, _ = msadb.GetAccountByID(db, accountID)
+	if account.AvailableAmt != availableBefore+model.YuanToHao(500) {
+		t.Errorf("Expected available %d, got %d", availableBefore+model.YuanToHao(500), account.AvailableAmt)
+	}
+
+	// 持仓数量与成本不变，分红计入已实现盈亏
+	position, _ := GetPosition(db, accountID, "sh600519")
+	if position != 1000 {
+		t.Errorf("Expected position 1000, got %d", position)
+	}
+	book, _ := BuildLotBook(db, accountID, "sh600519", CostMethodAverage)
+	if book.TotalCost() != bookBefore.TotalCost() {
+		t.Errorf("Expected cost %d, got %d", bookBefore.TotalCost(), book.TotalCost())
+	}
+	if book.RealizedPnL != model.YuanToHao(500) {
+		t.Errorf("Expected realized PnL %d, got %d", model.YuanToHao(500), book.RealizedPnL)
+	}
+
+	// 分红记入资金流水，但不计入本金
+	flows, _ := msadb.GetCashFlowsByAccount(db, accountID)
+	if len(flows) != 1 || flows[0].Type != model.CashFlowTypeDividend || flows[0].StockCode != "sh600519" {
+		t.Errorf("Expected one dividend cash flow, got %+v", flows)
+	}
+	contribution, _ := GetNetContribution(db, accountID)
+	if contribution != model.YuanToHao(100000) {
+		t.Errorf("Expected net contribution unchanged, got %d", contribution)
+	}
+
+	// 同一除息日不能重复处理
+	_, err = ApplyCorporateAction(db, accountID, CorporateAction{
+		StockCode: "sh600519", StockName: "贵州茅台", Type: model.CorporateActionTypeDividend,
+		CashPerShare: model.YuanToHao(0.5), ExDate: testNextTradeTime.Add(time.Hour),
+	})
+	if err == nil {
+		t.Error("Expected error for duplicate corporate action")
+	}
+}
+
+func TestApplyCorporateAction_BonusAndSplit(t *testing.T) {
+	db := setupTestDB(t)
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(100000))
+
+	buyID, _ := SubmitBuyOrder(db, accountID, Order{
+		StockCode: "sz000001", StockName: "平安银行", Quantity: 1000, Price: model.YuanToHao(10), Time: testTradeTime,
+	})
+	FillOrder(db, buyID)
+	bookBefore, _ := BuildLotBook(db, accountID, "sz000001", CostMethodAverage)
+
+	// 10送3转2：1000 股 → 1500 股
+	if _, err := ApplyCorporateAction(db, accountID, CorporateAction{
+		StockCode: "sz000001", StockName: "平安银行", Type: model.CorporateActionTypeBonus, Ratio: 0.5, ExDate: testNextTradeTime,
+	}); err != nil {
+		t.Fatalf("ApplyCorporateAction bonus failed: %v", err)
+	}
+	position, _ := GetPosition(db, accountID, "sz000001")
+	if position != 1500 {
+		t.Errorf("Expected position 1500 after bonus, got %d", position)
+	}
+
+	// 10合1：1500 股 → 150 股
+	if _, err := ApplyCorporateAction(db, accountID, CorporateAction{
+		StockCode: "sz000001", StockName: "平安银行", Type: model.CorporateActionTypeSplit, Ratio: 0.1, ExDate: testNextTradeTime,
+	}); err != nil {
+		t.Fatalf("ApplyCorporateAction split failed: %v", err)
+	}
+	position, _ = GetPosition(db, accountID, "sz000001")
+	if position != 150 {
+		t.Errorf("Expected position 150 after reverse split, got %d", position)
+	}
+
+	// 送转与拆合股不改变持仓成本
+	book, _ := BuildLotBook(db, accountID, "sz000001", CostMethodAverage)
+	if book.Quantity() != 150 || book.TotalCost() != bookBefore.TotalCost() {
+		t.Errorf("Expected 150 shares at cost %d, got %d at %d", bookBefore.TotalCost(), book.Quantity(), book.TotalCost())
+	}
+
+	// 全部卖出后不再计入持仓
+	sellID, _ := SubmitSellOrder(db, accountID, Order{
+		StockCode: "sz000001", StockName: "平安银行", Quantity: 150, Price: model.YuanToHao(100), Time: testNextTradeTime.AddDate(0, 0, 1),
+	})
+	if err := FillOrder(db, sellID); err != nil {
+		t.Fatalf("FillOrder failed: %v", err)
+	}
+	codes, _ := GetActiveStockCodes(db, accountID)
+	if len(codes) != 0 {
+		t.Errorf("Expected no active stock codes, got %v", codes)
+	}
+}
+
+func TestApplyCorporateAction_BackdatedExDate(t *testing.T) {
+	db := setupTestDB(t)
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(100000))
+
+	buyID, _ := SubmitBuyOrder(db, accountID, Order{
+		StockCode: "sz000001", StockName: "平安银行", Quantity: 1000, Price: model.YuanToHao(10), Time: testTradeTime,
+	})
+	FillOrder(db, buyID)
+
+	// 除权日之后又买入 500 股、卖出 200 股，补录除权日时按除权日之前的 1000 股计算
+	exDate := tradingDayStart(testNextTradeTime)
+	laterBuy, _ := SubmitBuyOrder(db, accountID, Order{
+		StockCode: "sz000001", StockName: "平安银行", Quantity: 500, Price: model.YuanToHao(10), Time: testNextTradeTime,
+	})
+	FillOrder(db, laterBuy)
+	laterSell, _ := SubmitSellOrder(db, accountID, Order{
+		StockCode: "sz000001", StockName: "平安银行", Quantity: 200, Price: model.YuanToHao(10), Time: testNextTradeTime.AddDate(0, 0, 1),
+	})
+	FillOrder(db, laterSell)
+
+	dividendID, err := ApplyCorporateAction(db, accountID, CorporateAction{
+		StockCode: "sz000001", StockName: "平安银行", Type: model.CorporateActionTypeDividend,
+		CashPerShare: model.YuanToHao(0.5), ExDate: exDate,
+	})
+	if err != nil {
+		t.Fatalf("ApplyCorporateAction dividend failed: %v", err)
+	}
+	bonusID, err := ApplyCorporateAction(db, accountID, CorporateAction{
+		StockCode: "sz000001", StockName: "平安银行", Type: model.CorporateActionTypeBonus, Ratio: 0.5, ExDate: exDate,
+	})
+	if err != nil {
+		t.Fatalf("ApplyCorporateAction bonus failed: %v", err)
+	}
+
+	var dividend, bonus model.CorporateAction
+	db.First(&dividend, dividendID)
+	db.First(&bonus, bonusID)
+	if dividend.PositionBefore != 1000 || dividend.CashAmount != model.YuanToHao(500) {
+		t.Errorf("Expected dividend on 1000 shares, got position %d cash %d", dividend.PositionBefore, dividend.CashAmount)
+	}
+	if bonus.QuantityDelta != 500 {
+		t.Errorf("Expected bonus delta 500, got %d", bonus.QuantityDelta)
+	}
+
+	// 1000 + 500（送转）+ 500 - 200
+	position, _ := GetPosition(db, accountID, "sz000001")
+	book, _ := BuildLotBook(db, accountID, "sz000001", CostMethodFIFO)
+	if position != 1800 || book.Quantity() != 1800 {
+		t.Errorf("Expected position 1800, got %d (lot book %d)", position, book.Quantity())
+	}
+}
+
+func TestApplyCorporateAction_Invalid(t *testing.T) {
+	db := setupTestDB(t)
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(100000))
+
+	// 无持仓
+	_, err := ApplyCorporateAction(db, accountID, CorporateAction{
+		StockCode: "sh600519", StockName: "贵州茅台", Type: model.CorporateActionTypeDividend, CashPerShare: model.YuanToHao(1),
+	})
+	if err == nil {
+		t.Error("Expected error for no position")
+	}
+
+	// 比例必须为正
+	_, err = ApplyCorporateAction(db, accountID, CorporateAction{
+		StockCode: "sh600519", StockName: "贵州茅台", Type: model.CorporateActionTypeSplit,
+	})
+	if err == nil {
+		t.Error("Expected error for zero ratio")
+	}
+}
@@ -0,0 +1,95 @@
+package finsvc
+
+import (
+	"math"
+	"strings"
+	"sync"
+
+	"msa/pkg/model"
+)
+
+var (
+	feeSchedule   = model.DefaultFeeSchedule()
+	feeScheduleMu sync.RWMutex
+)
+
+// SetFeeSchedule 设置全局交易费率（启动时由配置注入）
+func SetFeeSchedule(schedule model.FeeSchedule) {
+	feeScheduleMu.Lock()
+	defer feeScheduleMu.Unlock()
+	feeSchedule = schedule
+}
+
+// GetFeeSchedule 获取当前交易费率
+func GetFeeSchedule() model.FeeSchedule {
+	feeScheduleMu.RLock()
+	defer feeScheduleMu.RUnlock()
+	return feeSchedule
+}
+
+// CalculateFees 按当前费率计算订单手续费明细
+// A股：佣金（双向，有最低收费）+ 印花税（仅卖出）+ 过户费（仅沪市）
+// 港股：佣金 + 印花税（双向，按元向上取整）+ 交易征费/交易费/会财局征费
+func CalculateFees(orderType model.TransactionType, stockCode string, amount int64) model.FeeBreakdown {
+	schedule := GetFeeSchedule()
+	if amount <= 0 {
+		return model.FeeBreakdown{}
+	}
+
+	fees := model.FeeBreakdown{
+		Commission: max(rateToFen(amount, schedule.CommissionRate), model.YuanToHao(schedule.MinCommission)),
+	}
+
+	if DetectBoard(stockCode) == BoardHK {
+		fees.StampDuty = rateToYuanCeil(amount, schedule.HKStampDutyRate)
+		fees.Levy = rateToFen(amount, schedule.HKTradingLevyRate) +
+			rateToFen(amount, schedule.HKTradingFeeRate) +
+			rateToFen(amount, schedule.HKFRCLevyRate)
+		return fees
+	}
+
+	if orderType == model.TransactionTypeSell {
+		fees.StampDuty = rateToFen(amount, schedule.StampDutyRate)
+	}
+	if isShanghaiStock(stockCode) {
+		fees.TransferFee = rateToFen(amount, schedule.TransferFeeRate)
+	}
+	return fees
+}
+
+// splitFees 按数量比例拆分手续费明细，返回拆出部分与剩余部分
+// 各项向下取整，余数归入剩余部分，保证两部分合计等于原手续费
+func splitFees(fees model.FeeBreakdown, partQty, totalQty int64) (part model.FeeBreakdown, rest model.FeeBreakdown) {
+	split := func(v int64) (int64, int64) {
+		p := v * partQty / totalQty
+		return p, v - p
+	}
+	part.Commission, rest.Commission = split(fees.Commission)
+	part.StampDuty, rest.StampDuty = split(fees.StampDuty)
+	part.TransferFee, rest.TransferFee = split(fees.TransferFee)
+	part.Levy, rest.Levy = split(fees.Levy)
+	return part, rest
+}
+
+// isShanghaiStock 判断是否为沪市股票（sh 前缀或 6 开头的代码）
+func isShanghaiStock(stockCode string) bool {
+	code := strings.ToLower(strings.TrimSpace(stockCode))
+	if strings.HasPrefix(code, "sh") {
+		return true
+	}
+	if strings.HasPrefix(code, "sz") || strings.HasPrefix(code, "hk") {
+		return false
+	}
+	return strings.HasPrefix(code, "6")
+}
+
+// rateToFen 按费率计算费用（毫），四舍五入到分
+func rateToFen(amount int64, rate float64) int64 {
+	return int64(math.Round(float64(amount)*rate/100)) * 100
+}
+
+// rateToYuanCeil 按费率计算费用（毫），不足 1 元按 1 元计
+func rateToYuanCeil(amount int64, rate float64) int64 {
+	hao := int64(math.Round(float64(amount) * rate))
+	return (hao + 9999) / 10000 * 10000
+}
@@ -0,0 +1,113 @@
+package finsvc
+
+import (
+	"testing"
+
+	msadb "msa/pkg/db"
+	"msa/pkg/model"
+)
+
+func TestCalculateFees(t *testing.T) {
+	tests := []struct {
+		name      string
+		orderType model.TransactionType
+		code      string
+		amount    float64
+		expected  model.FeeBreakdown
+	}{
+		{
+			name:      "沪市买入-最低佣金",
+			orderType: model.TransactionTypeBuy,
+			code:      "sh600519",
+			amount:    10000,
+			expected:  model.FeeBreakdown{Commission: model.YuanToHao(5), TransferFee: model.YuanToHao(0.1)},
+		},
+		{
+			name:      "沪市卖出-印花税",
+			orderType: model.TransactionTypeSell,
+			code:      "600519",
+			amount:    100000,
+			expected: model.FeeBreakdown{
+				Commission:  model.YuanToHao(25),
+				StampDuty:   model.YuanToHao(50),
+				TransferFee: model.YuanToHao(1),
+			},
+		},
+		{
+			name:      "深市买入-无过户费",
+			orderType: model.TransactionTypeBuy,
+			code:      "sz000001",
+			amount:    100000,
+			expected:  model.FeeBreakdown{Commission: model.YuanToHao(25)},
+		},
+		{
+			name:      "深市卖出",
+			orderType: model.TransactionTypeSell,
+			code:      "sz300750",
+			amount:    100000,
+			expected:  model.FeeBreakdown{Commission: model.YuanToHao(25), StampDuty: model.YuanToHao(50)},
+		},
+		{
+			name:      "港股买入-印花税向上取整",
+			orderType: model.TransactionTypeBuy,
+			code:      "hk00700",
+			amount:    40050,
+			expected: model.FeeBreakdown{
+				Commission: model.YuanToHao(10.01),
+				StampDuty:  model.YuanToHao(41),
+				// 交易征费 1.08 + 交易费 2.26 + 会财局征费 0.06
+				Levy: model.YuanToHao(3.40),
+			},
+		},
+		{
+			name:      "零金额",
+			orderType: model.TransactionTypeBuy,
+			code:      "sh600519",
+			amount:    0,
+			expected:  model.FeeBreakdown{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CalculateFees(tt.orderType, tt.code, model.YuanToHao(tt.amount))
+			if got != tt.expected {
+				t.Errorf("CalculateFees() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSetFeeSchedule(t *testing.T) {
+	original := GetFeeSchedule()
+	t.Cleanup(func() { SetFeeSchedule(original) })
+
+	SetFeeSchedule(model.FeeSchedule{CommissionRate: 0.0001, MinCommission: 0})
+	got := CalculateFees(model.TransactionTypeSell, "sh600519", model.YuanToHao(100000))
+	if got != (model.FeeBreakdown{Commission: model.YuanToHao(10)}) {
+		t.Errorf("Expected only commission 10, got %+v", got)
+	}
+}
+
+func TestSubmitSellOrder_StoresFeeBreakdown(t *testing.T) {
+	db := setupTestDB(t)
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(100000))
+
+	buyID, _ := SubmitBuyOrder(db, accountID, Order{
+		StockCode: "sh600519", StockName: "贵州茅台", Quantity: 1000, Price: model.YuanToHao(50), Time: testTradeTime,
+	})
+	FillOrder(db, buyID)
+
+	sellID, _ := SubmitSellOrder(db, accountID, Order{
+		StockCode: "sh600519", StockName: "贵州茅台", Quantity: 1000, Price: model.YuanToHao(50), Time: testNextTradeTime,
+	})
+	trans, _ := msadb.GetTransactionByID(db, sellID)
+
+	expected := CalculateFees(model.TransactionTypeSell, "sh600519", model.YuanToHao(50000))
+	if trans.GetFeeBreakdown() != expected {
+		t.Errorf("Expected fee breakdown %+v, got %+v", expected, trans.GetFeeBreakdown())
+	}
+	if trans.Fee != expected.Total() {
+		t.Errorf("Expected total fee %d, got %d", expected.Total(), trans.Fee)
+	}
+}
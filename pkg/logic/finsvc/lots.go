@@ -0,0 +1,253 @@
+package finsvc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"msa/pkg/model"
+)
+
+// CostMethod 持仓成本计算方法
+type CostMethod string
+
+const (
+	// CostMethodFIFO 先进先出：卖出时优先扣减最早买入的批次
+	CostMethodFIFO CostMethod = "FIFO"
+	// CostMethodAverage 移动加权平均：所有批次合并为一个平均成本
+	CostMethodAverage CostMethod = "AVERAGE"
+)
+
+var (
+	costMethod   = CostMethodAverage
+	costMethodMu sync.RWMutex
+)
+
+// ParseCostMethod 解析成本计算方法（不区分大小写）
+func ParseCostMethod(s string) (CostMethod, error) {
+	switch CostMethod(strings.ToUpper(strings.TrimSpace(s))) {
+	case CostMethodFIFO:
+		return CostMethodFIFO, nil
+	case CostMethodAverage:
+		return CostMethodAverage, nil
+	default:
+		return "", fmt.Errorf("unsupported cost method: %s", s)
+	}
+}
+
+// SetCostMethod 设置全局成本计算方法（启动时由配置注入）
+func SetCostMethod(method CostMethod) {
+	costMethodMu.Lock()
+	defer costMethodMu.Unlock()
+	costMethod = method
+}
+
+// GetCostMethod 获取当前成本计算方法
+func GetCostMethod() CostMethod {
+	costMethodMu.RLock()
+	defer costMethodMu.RUnlock()
+	return costMethod
+}
+
+// Lot 持仓批次
+type Lot struct {
+	TransactionID uint      // 买入交易ID（加权平均法下为首笔买入）
+	AcquiredAt    time.Time // 买入时间
+	Quantity      int64     // 剩余数量
+	Cost          int64     // 剩余成本（毫，含买入手续费）
+}
+
+// LotBook 单只股票的批次账簿
+// 按时间顺序重放已成交交易与公司行动得到
+type LotBook struct {
+	StockCode   string
+	Method      CostMethod
+	Lots        []*Lot
+	RealizedPnL int64 // 已实现盈亏（毫）= 卖出净收入 - 卖出部分成本 + 现金分红
+}
+
+// Quantity 剩余持仓数量
+func (b *LotBook) Quantity() int64 {
+	var qty int64
+	for _, lot := range b.Lots {
+		qty += lot.Quantity
+	}
+	return qty
+}
+
+// TotalCost 剩余持仓成本（毫）
+func (b *LotBook) TotalCost() int64 {
+	var cost int64
+	for _, lot := range b.Lots {
+		cost += lot.Cost
+	}
+	return cost
+}
+
+// AvgCost 每股平均成本（毫），无持仓时为 0
+func (b *LotBook) AvgCost() int64 {
+	qty := b.Quantity()
+	if qty <= 0 {
+		return 0
+	}
+	return b.TotalCost() / qty
+}
+
+// buy 买入：新增批次，加权平均法下并入唯一批次
+func (b *LotBook) buy(trans *model.Transaction) {
+	cost := trans.Amount + trans.Fee
+	if b.Method == CostMethodAverage && len(b.Lots) > 0 {
+		b.Lots[0].Quantity += trans.Quantity
+		b.Lots[0].Cost += cost
+		return
+	}
+	b.Lots = append(b.Lots, &Lot{
+		TransactionID: trans.ID,
+		AcquiredAt:    trans.CreatedAt,
+		Quantity:      trans.Quantity,
+		Cost:          cost,
+	})
+}
+
+// sell 卖出：按批次顺序扣减数量与成本，差额计入已实现盈亏
+func (b *LotBook) sell(trans *model.Transaction) {
+	remaining := trans.Quantity
+	var costRemoved int64
+	for len(b.Lots) > 0 && remaining > 0 {
+		lot := b.Lots[0]
+		take := min(remaining, lot.Quantity)
+		removed := lot.Cost * take / lot.Quantity
+		lot.Quantity -= take
+		lot.Cost -= removed
+		costRemoved += removed
+		remaining -= take
+		if lot.Quantity == 0 {
+			b.Lots = b.Lots[1:]
+		}
+	}
+	if remaining > 0 {
+		log.Warnf("卖出数量超过批次持仓，超出部分按零成本计算: 股票=%s, 交易ID=%d, 超出=%d", b.StockCode, trans.ID, remaining)
+	}
+	b.RealizedPnL += trans.Amount - trans.Fee - costRemoved
+}
+
+// adjust 公司行动：送转/拆合股按批次数量比例分配数量变化，现金分红计入已实现盈亏
+func (b *LotBook) adjust(action *model.CorporateAction) {
+	b.RealizedPnL += action.CashAmount
+	if action.QuantityDelta == 0 || len(b.Lots) == 0 {
+		return
+	}
+
+	total := b.Quantity()
+	var allocated int64
+	for i, lot := range b.Lots {
+		delta := action.QuantityDelta * lot.Quantity / total
+		if i == len(b.Lots)-1 {
+			delta = action.QuantityDelta - allocated
+		}
+		lot.Quantity += delta
+		allocated += delta
+	}
+
+	// 合股后数量归零的批次，成本并入相邻批次
+	kept := b.Lots[:0]
+	var carry int64
+	for _, lot := range b.Lots {
+		if lot.Quantity <= 0 {
+			carry += lot.Cost
+			continue
+		}
+		lot.Cost += carry
+		carry = 0
+		kept = append(kept, lot)
+	}
+	if carry > 0 && len(kept) > 0 {
+		kept[len(kept)-1].Cost += carry
+	}
+	b.Lots = kept
+}
+
+// BuildLotBook 按成本计算方法重放指定股票的已成交交易与公司行动
+// 交易与公司行动按时间排序，同一时刻交易在前；时间比较在内存中进行
+func BuildLotBook(database *gorm.DB, accountID uint, stockCode string, method CostMethod) (*LotBook, error) {
+	var trades []*model.Transaction
+	err := database.Where("account_id = ? AND stock_code = ? AND status = ?", accountID, stockCode, model.TransactionStatusFilled).
+		Find(&trades).Error
+	if err != nil {
+		log.Errorf("查询成交记录失败: %v", err)
+		return nil, fmt.Errorf("failed to query filled transactions: %w", err)
+	}
+
+	var actions []*model.CorporateAction
+	err = database.Where("account_id = ? AND stock_code = ?", accountID, stockCode).
+		Find(&actions).Error
+	if err != nil {
+		log.Errorf("查询公司行动失败: %v", err)
+		return nil, fmt.Errorf("failed to query corporate actions: %w", err)
+	}
+
+	type event struct {
+		at     time.Time
+		order  int // 同一时刻：交易 0，公司行动 1
+		id     uint
+		trade  *model.Transaction
+		action *model.CorporateAction
+	}
+	events := make([]event, 0, len(trades)+len(actions))
+	for _, t := range trades {
+		events = append(events, event{at: t.CreatedAt, order: 0, id: t.ID, trade: t})
+	}
+	for _, a := range actions {
+		events = append(events, event{at: a.CreatedAt, order: 1, id: a.ID, action: a})
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].at.Equal(events[j].at) {
+			return events[i].at.Before(events[j].at)
+		}
+		if events[i].order != events[j].order {
+			return events[i].order < events[j].order
+		}
+		return events[i].id < events[j].id
+	})
+
+	book := &LotBook{StockCode: stockCode, Method: method}
+	for _, e := range events {
+		switch {
+		case e.action != nil:
+			book.adjust(e.action)
+		case e.trade.Type == model.TransactionTypeBuy:
+			book.buy(e.trade)
+		case e.trade.Type == model.TransactionTypeSell:
+			book.sell(e.trade)
+		}
+	}
+	return book, nil
+}
+
+// GetRealizedPnL 获取账户所有股票（含已清仓）的已实现盈亏合计（毫）
+func GetRealizedPnL(database *gorm.DB, accountID uint, method CostMethod) (int64, error) {
+	var codes []string
+	err := database.Model(&model.Transaction{}).
+		Distinct("stock_code").
+		Where("account_id = ? AND status = ?", accountID, model.TransactionStatusFilled).
+		Pluck("stock_code", &codes).Error
+	if err != nil {
+		log.Errorf("查询股票列表失败: %v", err)
+		return 0, fmt.Errorf("failed to query stocks: %w", err)
+	}
+
+	var realized int64
+	for _, code := range codes {
+		book, err := BuildLotBook(database, accountID, code, method)
+		if err != nil {
+			return 0, err
+		}
+		realized += book.RealizedPnL
+	}
+	return realized, nil
+}
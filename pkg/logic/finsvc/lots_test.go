@@ -0,0 +1,137 @@
+package finsvc
+
+import (
+	"testing"
+
+	"gorm.io/gorm"
+
+	msadb "msa/pkg/db"
+	"msa/pkg/model"
+)
+
+func TestParseCostMethod(t *testing.T) {
+	if m, err := ParseCostMethod("fifo"); err != nil || m != CostMethodFIFO {
+		t.Errorf("Expected FIFO, got %s (%v)", m, err)
+	}
+	if m, err := ParseCostMethod(" Average "); err != nil || m != CostMethodAverage {
+		t.Errorf("Expected AVERAGE, got %s (%v)", m, err)
+	}
+	if _, err := ParseCostMethod("LIFO"); err == nil {
+		t.Error("Expected error for unsupported cost method")
+	}
+}
+
+func TestLotBook_PartialSell(t *testing.T) {
+	// 两笔买入：100 股 @10 元、100 股 @20 元（不含手续费），卖出 100 股 @30 元
+	trades := []*model.Transaction{
+		{Model: gorm.Model{ID: 1}, Type: model.TransactionTypeBuy, Quantity: 100, Amount: model.YuanToHao(1000)},
+		{Model: gorm.Model{ID: 2}, Type: model.TransactionTypeBuy, Quantity: 100, Amount: model.YuanToHao(2000)},
+		{Model: gorm.Model{ID: 3}, Type: model.TransactionTypeSell, Quantity: 100, Amount: model.YuanToHao(3000)},
+	}
+
+	tests := []struct {
+		method       CostMethod
+		wantCost     int64
+		wantRealized int64
+	}{
+		{method: CostMethodFIFO, wantCost: model.YuanToHao(2000), wantRealized: model.YuanToHao(2000)},
+		{method: CostMethodAverage, wantCost: model.YuanToHao(1500), wantRealized: model.YuanToHao(1500)},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.method), func(t *testing.T) {
+			book := &LotBook{StockCode: "sh600519", Method: tt.method}
+			for _, trans := range trades {
+				if trans.Type == model.TransactionTypeBuy {
+					book.buy(trans)
+				} else {
+					book.sell(trans)
+				}
+			}
+			if book.Quantity() != 100 {
+				t.Errorf("Expected quantity 100, got %d", book.Quantity())
+			}
+			if book.TotalCost() != tt.wantCost {
+				t.Errorf("Expected cost %d, got %d", tt.wantCost, book.TotalCost())
+			}
+			if book.RealizedPnL != tt.wantRealized {
+				t.Errorf("Expected realized PnL %d, got %d", tt.wantRealized, book.RealizedPnL)
+			}
+		})
+	}
+}
+
+func TestLotBook_AdjustSplit(t *testing.T) {
+	book := &LotBook{StockCode: "sz000001", Method: CostMethodFIFO}
+	book.buy(&model.Transaction{Model: gorm.Model{ID: 1}, Quantity: 100, Amount: model.YuanToHao(1000)})
+	book.buy(&model.Transaction{Model: gorm.Model{ID: 2}, Quantity: 300, Amount: model.YuanToHao(6000)})
+
+	// 1 拆 2：数量按批次比例增加，成本不变
+	book.adjust(&model.CorporateAction{QuantityDelta: 400})
+	if book.Lots[0].Quantity != 200 || book.Lots[1].Quantity != 600 {
+		t.Errorf("Expected lots 200/600, got %d/%d", book.Lots[0].Quantity, book.Lots[1].Quantity)
+	}
+	if book.TotalCost() != model.YuanToHao(7000) {
+		t.Errorf("Expected cost unchanged, got %d", book.TotalCost())
+	}
+	if book.AvgCost() != model.YuanToHao(8.75) {
+		t.Errorf("Expected avg cost %d, got %d", model.YuanToHao(8.75), book.AvgCost())
+	}
+}
+
+func TestGetAllPositions_RealizedAndUnrealized(t *testing.T) {
+	db := setupTestDB(t)
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(100000))
+
+	for _, price := range []float64{10, 20} {
+		buyID, _ := SubmitBuyOrder(db, accountID, Order{
+			StockCode: "sz000001", StockName: "平安银行", Quantity: 1000, Price: model.YuanToHao(price), Time: testTradeTime,
+		})
+		FillOrder(db, buyID)
+	}
+	sellID, _ := SubmitSellOrder(db, accountID, Order{
+		StockCode: "sz000001", StockName: "平安银行", Quantity: 1000, Price: model.YuanToHao(30), Time: testNextTradeTime,
+	})
+	if err := FillOrder(db, sellID); err != nil {
+		t.Fatalf("FillOrder failed: %v", err)
+	}
+
+	original := GetCostMethod()
+	t.Cleanup(func() { SetCostMethod(original) })
+
+	for _, method := range []CostMethod{CostMethodFIFO, CostMethodAverage} {
+		SetCostMethod(method)
+		book, _ := BuildLotBook(db, accountID, "sz000001", method)
+
+		positions, err := GetAllPositions(db, accountID, PriceMap{"sz000001": model.YuanToHao(25)})
+		if err != nil {
+			t.Fatalf("GetAllPositions failed: %v", err)
+		}
+		if len(positions) != 1 {
+			t.Fatalf("Expected 1 position, got %d", len(positions))
+		}
+		pos := positions[0]
+		if pos.Cost != book.TotalCost() || pos.RealizedPnL != book.RealizedPnL {
+			t.Errorf("%s: position does not match lot book: %+v", method, pos)
+		}
+		if pos.UnrealizedPnL != pos.Value-pos.Cost {
+			t.Errorf("%s: expected unrealized %d, got %d", method, pos.Value-pos.Cost, pos.UnrealizedPnL)
+		}
+
+		result, _ := GetAccountTotalValue(db, accountID, PriceMap{"sz000001": model.YuanToHao(25)})
+		if result.RealizedPnL != pos.RealizedPnL || result.UnrealizedPnL != pos.UnrealizedPnL {
+			t.Errorf("%s: account totals do not match position: %+v", method, result)
+		}
+	}
+
+	// 先进先出卖出低成本批次，已实现盈亏高于加权平均
+	fifo, _ := BuildLotBook(db, accountID, "sz000001", CostMethodFIFO)
+	avg, _ := BuildLotBook(db, accountID, "sz000001", CostMethodAverage)
+	if fifo.RealizedPnL <= avg.RealizedPnL {
+		t.Errorf("Expected FIFO realized %d > AVERAGE realized %d", fifo.RealizedPnL, avg.RealizedPnL)
+	}
+	// 已实现盈亏 - 剩余成本 = 卖出净收入 - 买入总成本，与计算方法无关
+	if fifo.RealizedPnL-fifo.TotalCost() != avg.RealizedPnL-avg.TotalCost() {
+		t.Error("Expected realized PnL - remaining cost to be method independent")
+	}
+}
@@ -0,0 +1,304 @@
+package finsvc
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"msa/pkg/db"
+	"msa/pkg/model"
+)
+
+// GetPendingOrders 获取账户所有挂单中的订单（按提交时间升序）
+func GetPendingOrders(database *gorm.DB, accountID uint) ([]*model.Transaction, error) {
+	var orders []*model.Transaction
+	err := database.Where("account_id = ? AND status = ?", accountID, model.TransactionStatusPending).
+		Order("created_at ASC, id ASC").
+		Find(&orders).Error
+	if err != nil {
+		log.Errorf("查询挂单失败: %v", err)
+		return nil, fmt.Errorf("failed to query pending orders: %w", err)
+	}
+	return orders, nil
+}
+
+// IsPriceCrossed 判断行情价格是否触及限价
+// 买入：行情价 <= 限价；卖出：行情价 >= 限价
+func IsPriceCrossed(orderType model.TransactionType, limitPrice, quotePrice int64) bool {
+	if quotePrice <= 0 {
+		return false
+	}
+	switch orderType {
+	case model.TransactionTypeBuy:
+		return quotePrice <= limitPrice
+	case model.TransactionTypeSell:
+		return quotePrice >= limitPrice
+	default:
+		return false
+	}
+}
+
+// MatchOrder 使用行情价格撮合单个挂单
+// 行情价触及限价时按限价全部成交，否则保持 PENDING
+// 返回是否成交
+func MatchOrder(database *gorm.DB, transID uint, quotePrice int64) (bool, error) {
+	filledQty, err := MatchOrderWithLiquidity(database, transID, quotePrice, 0)
+	if err != nil {
+		return false, err
+	}
+	return filledQty > 0, nil
+}
+
+// MatchOrderWithLiquidity 在可成交数量受限时撮合单个挂单
+// availableQty 为行情可成交的数量（<= 0 表示不限），小于订单数量时部分成交，剩余部分继续挂单
+// 返回本次成交数量
+func MatchOrderWithLiquidity(database *gorm.DB, transID uint, quotePrice int64, availableQty int64) (int64, error) {
+	trans, err := db.GetTransactionByID(database, transID)
+	if err != nil {
+		log.Errorf("查询交易记录失败: %v", err)
+		return 0, fmt.Errorf("failed to get transaction: %w", err)
+	}
+
+	if trans.Status != model.TransactionStatusPending {
+		return 0, fmt.Errorf("transaction %d is not pending: %s", transID, trans.Status)
+	}
+
+	if !IsPriceCrossed(trans.Type, trans.Price, quotePrice) {
+		log.Infof("挂单未触及限价: 交易ID=%d, 类型=%s, 限价=%d, 行情价=%d",
+			transID, trans.Type, trans.Price, quotePrice)
+		return 0, nil
+	}
+
+	if availableQty > 0 && availableQty < trans.Quantity {
+		if _, _, err := PartialFillOrder(database, transID, availableQty); err != nil {
+			return 0, err
+		}
+		return availableQty, nil
+	}
+
+	if err := FillOrder(database, transID); err != nil {
+		return 0, err
+	}
+	return trans.Quantity, nil
+}
+
+// MatchPendingOrders 使用行情价格撮合账户内所有挂单
+// 没有行情价格的股票跳过；depths 中有盘口数据的股票按盘口可成交数量部分成交，同一股票同方向的挂单按提交顺序依次消耗盘口
+// 返回本次成交（含部分成交）的交易ID列表
+func MatchPendingOrders(database *gorm.DB, accountID uint, prices PriceMap, depths map[string]QuoteDepth) ([]uint, error) {
+	orders, err := GetPendingOrders(database, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	var filled []uint
+	consumed := make(map[string]int64)
+	for _, order := range orders {
+		price, ok := prices[order.StockCode]
+		if !ok {
+			continue
+		}
+
+		var availableQty int64
+		key := order.StockCode + "/" + string(order.Type)
+		if depth, ok := depths[order.StockCode]; ok {
+			availableQty = depth.AvailableQuantity(order.Type, order.Price) - consumed[key]
+			if availableQty <= 0 {
+				continue
+			}
+		}
+
+		filledQty, err := MatchOrderWithLiquidity(database, order.ID, price, availableQty)
+		if err != nil {
+			return filled, err
+		}
+		if filledQty > 0 {
+			consumed[key] += filledQty
+			filled = append(filled, order.ID)
+		}
+	}
+
+	if len(filled) > 0 {
+		log.Infof("挂单撮合完成: 账户=%d, 成交=%v", accountID, filled)
+	}
+	return filled, nil
+}
+
+// DepthLevel 盘口档位
+type DepthLevel struct {
+	Price    int64 // 价格（毫）
+	Quantity int64 // 挂单数量（股）
+}
+
+// QuoteDepth 盘口深度，用于按挂单量模拟流动性不足时的部分成交
+type QuoteDepth struct {
+	Bids []DepthLevel // 买一至买五
+	Asks []DepthLevel // 卖一至卖五
+}
+
+// AvailableQuantity 限价单按盘口可成交的数量
+// 买入：卖盘中价格不高于限价的挂单量之和；卖出：买盘中价格不低于限价的挂单量之和
+func (d QuoteDepth) AvailableQuantity(orderType model.TransactionType, limitPrice int64) int64 {
+	var total int64
+	switch orderType {
+	case model.TransactionTypeBuy:
+		for _, level := range d.Asks {
+			if level.Price > 0 && level.Price <= limitPrice {
+				total += level.Quantity
+			}
+		}
+	case model.TransactionTypeSell:
+		for _, level := range d.Bids {
+			if level.Price > 0 && level.Price >= limitPrice {
+				total += level.Quantity
+			}
+		}
+	}
+	return total
+}
+
+// DepthFromQuote 由行情快照解析盘口深度，盘口挂单量单位为手，按 A 股每手 100 股换算
+// 港股盘口单位与 A 股不同、行情快照没有有效盘口时返回 false，撮合时不限制成交数量
+func DepthFromQuote(stockCode string, quote *model.StockQuote) (QuoteDepth, bool) {
+	if quote == nil || DetectBoard(stockCode) == BoardHK {
+		return QuoteDepth{}, false
+	}
+
+	parse := func(levels []model.QuoteLevel) []DepthLevel {
+		var result []DepthLevel
+		for _, level := range levels {
+			price, err1 := strconv.ParseFloat(level.Price, 64)
+			lots, err2 := strconv.ParseInt(level.Volume, 10, 64)
+			if err1 != nil || err2 != nil || price <= 0 || lots <= 0 {
+				continue
+			}
+			result = append(result, DepthLevel{Price: PriceToHao(stockCode, price), Quantity: lots * DefaultLotSize})
+		}
+		return result
+	}
+
+	depth := QuoteDepth{Bids: parse(quote.Bids), Asks: parse(quote.Asks)}
+	if len(depth.Bids) == 0 && len(depth.Asks) == 0 {
+		return QuoteDepth{}, false
+	}
+	return depth, true
+}
+
+// CancelOrder 撤销挂单
+// 买入：返还锁定金额到可用金额；卖出：不调整金额
+// reason 会追加到交易备注中，可为空
+func CancelOrder(database *gorm.DB, transID uint, reason string) error {
+	log.Infof("撤销订单: 交易ID=%d, 原因=%s", transID, reason)
+
+	tx := database.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			log.Errorf("撤销订单 panic: %v", r)
+		}
+	}()
+
+	trans, err := db.GetTransactionByID(tx, transID)
+	if err != nil {
+		tx.Rollback()
+		log.Errorf("查询交易记录失败: %v", err)
+		return fmt.Errorf("failed to get transaction: %w", err)
+	}
+
+	if trans.Status != model.TransactionStatusPending {
+		tx.Rollback()
+		return fmt.Errorf("只能撤销挂单中的订单，当前状态: %s", trans.Status)
+	}
+
+	updates := map[string]interface{}{"status": model.TransactionStatusCancelled}
+	if reason != "" {
+		updates["note"] = appendNote(trans.Note, reason)
+	}
+	// 条件更新防止与并发撮合同时处理同一挂单
+	if err := db.UpdatePendingTransaction(tx, transID, updates); err != nil {
+		tx.Rollback()
+		log.Errorf("更新交易状态失败: %v", err)
+		return fmt.Errorf("failed to update transaction status: %w", err)
+	}
+
+	if trans.Type == model.TransactionTypeBuy {
+		// 买入撤单：锁定金额全额返还到可用金额
+		totalAmount := trans.GetTotalAmount()
+		if err := db.UpdateAccountAmounts(tx, trans.AccountID, totalAmount, -totalAmount); err != nil {
+			tx.Rollback()
+			log.Errorf("释放锁定金额失败: %v", err)
+			return fmt.Errorf("failed to release locked amount: %w", err)
+		}
+		log.Infof("买入撤单: 返还锁定金额=%d", totalAmount)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Errorf("提交事务失败: %v", err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Infof("订单已撤销: 交易ID=%d", transID)
+	return nil
+}
+
+// ExpirePendingOrders 撤销 before 之前提交的挂单
+// A股限价单为当日有效，跨日未成交的挂单需要撤销并释放锁定金额
+// 返回被撤销的交易ID列表
+func ExpirePendingOrders(database *gorm.DB, accountID uint, before time.Time) ([]uint, error) {
+	orders, err := GetPendingOrders(database, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	var expired []uint
+	for _, order := range orders {
+		if !order.CreatedAt.Before(before) {
+			continue
+		}
+		if err := CancelOrder(database, order.ID, "当日有效订单已过期"); err != nil {
+			return expired, err
+		}
+		expired = append(expired, order.ID)
+	}
+	return expired, nil
+}
+
+// TransactionNode 交易记录树节点
+// 部分成交时原记录（OBSOLETE）作为父节点，成交与剩余子记录作为子节点
+type TransactionNode struct {
+	*model.Transaction
+	Children []*TransactionNode
+}
+
+// BuildTransactionTree 按 ParentID 将交易记录组装为树
+// 父记录不在列表中的记录作为根节点，保持输入顺序
+func BuildTransactionTree(transactions []*model.Transaction) []*TransactionNode {
+	nodes := make(map[uint]*TransactionNode, len(transactions))
+	for _, trans := range transactions {
+		nodes[trans.ID] = &TransactionNode{Transaction: trans}
+	}
+
+	var roots []*TransactionNode
+	for _, trans := range transactions {
+		node := nodes[trans.ID]
+		if trans.ParentID != nil {
+			if parent, ok := nodes[*trans.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots
+}
+
+// appendNote 在原备注后追加内容
+func appendNote(note, extra string) string {
+	if note == "" {
+		return extra
+	}
+	return note + "；" + extra
+}
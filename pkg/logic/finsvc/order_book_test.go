@@ -0,0 +1,450 @@
+package finsvc
+
+import (
+	"testing"
+	"time"
+
+	msadb "msa/pkg/db"
+	"msa/pkg/model"
+)
+
+func TestIsPriceCrossed(t *testing.T) {
+	tests := []struct {
+		name     string
+		txType   model.TransactionType
+		limit    int64
+		quote    int64
+		expected bool
+	}{
+		{"买入-行情低于限价", model.TransactionTypeBuy, 100, 99, true},
+		{"买入-行情等于限价", model.TransactionTypeBuy, 100, 100, true},
+		{"买入-行情高于限价", model.TransactionTypeBuy, 100, 101, false},
+		{"卖出-行情高于限价", model.TransactionTypeSell, 100, 101, true},
+		{"卖出-行情等于限价", model.TransactionTypeSell, 100, 100, true},
+		{"卖出-行情低于限价", model.TransactionTypeSell, 100, 99, false},
+		{"无效行情", model.TransactionTypeBuy, 100, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsPriceCrossed(tt.txType, tt.limit, tt.quote); got != tt.expected {
+				t.Errorf("IsPriceCrossed(%s, %d, %d) = %v, want %v", tt.txType, tt.limit, tt.quote, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMatchOrder_BuyStaysPendingUntilCrossed(t *testing.T) {
+	db := setupTestDB(t)
+
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(10000))
+
+	order := Order{
+		StockCode: "600519",
+		StockName: "贵州茅台",
+		Quantity:  100,
+		Price:     model.YuanToHao(10),
+		Time:      testTradeTime,
+	}
+	txID, _ := SubmitBuyOrder(db, accountID, order)
+
+	// 行情高于限价，不成交
+	filled, err := MatchOrder(db, txID, model.YuanToHao(10.2))
+	if err != nil {
+		t.Fatalf("MatchOrder failed: %v", err)
+	}
+	if filled {
+		t.Error("Expected order to stay pending")
+	}
+	tx, _ := msadb.GetTransactionByID(db, txID)
+	if tx.Status != model.TransactionStatusPending {
+		t.Errorf("Expected status %s, got %s", model.TransactionStatusPending, tx.Status)
+	}
+
+	// 行情触及限价，成交
+	filled, err = MatchOrder(db, txID, model.YuanToHao(9.98))
+	if err != nil {
+		t.Fatalf("MatchOrder failed: %v", err)
+	}
+	if !filled {
+		t.Error("Expected order to be filled")
+	}
+	tx, _ = msadb.GetTransactionByID(db, txID)
+	if tx.Status != model.TransactionStatusFilled {
+		t.Errorf("Expected status %s, got %s", model.TransactionStatusFilled, tx.Status)
+	}
+
+	account, _ := msadb.GetAccountByID(db, accountID)
+	if account.LockedAmt != 0 {
+		t.Errorf("Expected locked 0, got %d", account.LockedAmt)
+	}
+}
+
+func TestFillOrder_RejectsNonPending(t *testing.T) {
+	db := setupTestDB(t)
+
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(10000))
+
+	order := Order{
+		StockCode: "600519",
+		StockName: "贵州茅台",
+		Quantity:  100,
+		Price:     model.YuanToHao(10),
+		Time:      testTradeTime,
+	}
+	txID, _ := SubmitBuyOrder(db, accountID, order)
+	if err := FillOrder(db, txID); err != nil {
+		t.Fatalf("FillOrder failed: %v", err)
+	}
+
+	// 重复成交必须失败，且不能再次扣减锁定金额
+	if err := FillOrder(db, txID); err == nil {
+		t.Error("Expected error when filling an already filled order")
+	}
+	account, _ := msadb.GetAccountByID(db, accountID)
+	if account.LockedAmt != 0 {
+		t.Errorf("Expected locked 0, got %d", account.LockedAmt)
+	}
+}
+
+func TestCancelOrder_ReleasesLockedAmount(t *testing.T) {
+	db := setupTestDB(t)
+
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(10000))
+
+	order := Order{
+		StockCode: "600519",
+		StockName: "贵州茅台",
+		Quantity:  100,
+		Price:     model.YuanToHao(10),
+		Time:      testTradeTime,
+	}
+	txID, _ := SubmitBuyOrder(db, accountID, order)
+
+	if err := CancelOrder(db, txID, "用户撤单"); err != nil {
+		t.Fatalf("CancelOrder failed: %v", err)
+	}
+
+	tx, _ := msadb.GetTransactionByID(db, txID)
+	if tx.Status != model.TransactionStatusCancelled {
+		t.Errorf("Expected status %s, got %s", model.TransactionStatusCancelled, tx.Status)
+	}
+	if tx.Note != "用户撤单" {
+		t.Errorf("Expected note 用户撤单, got %q", tx.Note)
+	}
+
+	account, _ := msadb.GetAccountByID(db, accountID)
+	if account.AvailableAmt != model.YuanToHao(10000) {
+		t.Errorf("Expected available %d, got %d", model.YuanToHao(10000), account.AvailableAmt)
+	}
+	if account.LockedAmt != 0 {
+		t.Errorf("Expected locked 0, got %d", account.LockedAmt)
+	}
+
+	// 已撤销的订单不能再次撤销
+	if err := CancelOrder(db, txID, ""); err == nil {
+		t.Error("Expected error when cancelling a cancelled order")
+	}
+}
+
+func TestMatchPendingOrders(t *testing.T) {
+	db := setupTestDB(t)
+
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(10000))
+
+	buyA, _ := SubmitBuyOrder(db, accountID, Order{
+		StockCode: "600519", StockName: "贵州茅台", Quantity: 100, Price: model.YuanToHao(10), Time: testTradeTime,
+	})
+	buyB, _ := SubmitBuyOrder(db, accountID, Order{
+		StockCode: "000001", StockName: "平安银行", Quantity: 100, Price: model.YuanToHao(15), Time: testTradeTime,
+	})
+
+	filled, err := MatchPendingOrders(db, accountID, PriceMap{
+		"600519": model.YuanToHao(9.5),
+		"000001": model.YuanToHao(15.5),
+	}, nil)
+	if err != nil {
+		t.Fatalf("MatchPendingOrders failed: %v", err)
+	}
+	if len(filled) != 1 || filled[0] != buyA {
+		t.Errorf("Expected only %d filled, got %v", buyA, filled)
+	}
+
+	pending, _ := GetPendingOrders(db, accountID)
+	if len(pending) != 1 || pending[0].ID != buyB {
+		t.Errorf("Expected %d still pending, got %v", buyB, pending)
+	}
+}
+
+func TestExpirePendingOrders(t *testing.T) {
+	db := setupTestDB(t)
+
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(10000))
+	txID, _ := SubmitBuyOrder(db, accountID, Order{
+		StockCode: "600519", StockName: "贵州茅台", Quantity: 100, Price: model.YuanToHao(10), Time: testTradeTime,
+	})
+
+	// 当前挂单不早于截止时间，不应过期
+	expired, err := ExpirePendingOrders(db, accountID, testTradeTime.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("ExpirePendingOrders failed: %v", err)
+	}
+	if len(expired) != 0 {
+		t.Errorf("Expected no expired orders, got %v", expired)
+	}
+
+	expired, err = ExpirePendingOrders(db, accountID, testTradeTime.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("ExpirePendingOrders failed: %v", err)
+	}
+	if len(expired) != 1 || expired[0] != txID {
+		t.Errorf("Expected [%d] expired, got %v", txID, expired)
+	}
+
+	account, _ := msadb.GetAccountByID(db, accountID)
+	if account.LockedAmt != 0 || account.AvailableAmt != model.YuanToHao(10000) {
+		t.Errorf("Expected funds released, got available=%d locked=%d", account.AvailableAmt, account.LockedAmt)
+	}
+}
+
+func TestPartialFillOrder_Buy(t *testing.T) {
+	db := setupTestDB(t)
+
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(10000))
+	txID, _ := SubmitBuyOrder(db, accountID, Order{
+		StockCode: "300999", StockName: "小盘股", Quantity: 300, Price: model.YuanToHao(10), Time: testTradeTime,
+	})
+
+	filledID, remainderID, err := PartialFillOrder(db, txID, 100)
+	if err != nil {
+		t.Fatalf("PartialFillOrder failed: %v", err)
+	}
+
+	origin, _ := msadb.GetTransactionByID(db, txID)
+	if origin.Status != model.TransactionStatusObsolete {
+		t.Errorf("Expected origin status %s, got %s", model.TransactionStatusObsolete, origin.Status)
+	}
+
+	// 原手续费为最低佣金 5 元，按 1/3 拆分：成交部分 1.6666，剩余部分 3.3334
+	filled, _ := msadb.GetTransactionByID(db, filledID)
+	if filled.Status != model.TransactionStatusFilled || filled.Quantity != 100 || filled.Fee != 16666 {
+		t.Errorf("Unexpected filled child: status=%s qty=%d fee=%d", filled.Status, filled.Quantity, filled.Fee)
+	}
+	if filled.ParentID == nil || *filled.ParentID != txID {
+		t.Errorf("Expected filled child parent %d, got %v", txID, filled.ParentID)
+	}
+
+	remainder, _ := msadb.GetTransactionByID(db, remainderID)
+	if remainder.Status != model.TransactionStatusPending || remainder.Quantity != 200 || remainder.Fee != 33334 {
+		t.Errorf("Unexpected remainder child: status=%s qty=%d fee=%d", remainder.Status, remainder.Quantity, remainder.Fee)
+	}
+	if remainder.ParentID == nil || *remainder.ParentID != txID {
+		t.Errorf("Expected remainder child parent %d, got %v", txID, remainder.ParentID)
+	}
+
+	// 剩余部分继续锁定
+	account, _ := msadb.GetAccountByID(db, accountID)
+	if account.LockedAmt != remainder.GetTotalAmount() {
+		t.Errorf("Expected locked %d, got %d", remainder.GetTotalAmount(), account.LockedAmt)
+	}
+	if account.AvailableAmt != model.YuanToHao(10000)-origin.GetTotalAmount() {
+		t.Errorf("Expected available %d, got %d", model.YuanToHao(10000)-origin.GetTotalAmount(), account.AvailableAmt)
+	}
+
+	qty, _ := GetPosition(db, accountID, "300999")
+	if qty != 100 {
+		t.Errorf("Expected position 100, got %d", qty)
+	}
+
+	// 撤销剩余部分，锁定金额全部释放
+	if err := CancelOrder(db, remainderID, "用户撤单"); err != nil {
+		t.Fatalf("CancelOrder failed: %v", err)
+	}
+	account, _ = msadb.GetAccountByID(db, accountID)
+	if account.LockedAmt != 0 {
+		t.Errorf("Expected locked 0, got %d", account.LockedAmt)
+	}
+	if account.AvailableAmt != model.YuanToHao(10000)-filled.GetTotalAmount() {
+		t.Errorf("Expected available %d, got %d", model.YuanToHao(10000)-filled.GetTotalAmount(), account.AvailableAmt)
+	}
+}
+
+func TestPartialFillOrder_Sell(t *testing.T) {
+	db := setupTestDB(t)
+
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(10000))
+	buyID, _ := SubmitBuyOrder(db, accountID, Order{
+		StockCode: "300999", StockName: "小盘股", Quantity: 300, Price: model.YuanToHao(10), Time: testTradeTime,
+	})
+	FillOrder(db, buyID)
+	buy, _ := msadb.GetTransactionByID(db, buyID)
+
+	sellID, _ := SubmitSellOrder(db, accountID, Order{
+		StockCode: "300999", StockName: "小盘股", Quantity: 300, Price: model.YuanToHao(11), Time: testNextTradeTime,
+	})
+	filledID, remainderID, err := PartialFillOrder(db, sellID, 200)
+	if err != nil {
+		t.Fatalf("PartialFillOrder failed: %v", err)
+	}
+
+	// 手续费明细逐项按比例拆分，两部分合计等于原手续费
+	sell, _ := msadb.GetTransactionByID(db, sellID)
+	filled, _ := msadb.GetTransactionByID(db, filledID)
+	remainder, _ := msadb.GetTransactionByID(db, remainderID)
+	if filled.StampDuty+remainder.StampDuty != sell.StampDuty || filled.Fee+remainder.Fee != sell.Fee {
+		t.Errorf("Expected split fees to sum to %d, got %d + %d", sell.Fee, filled.Fee, remainder.Fee)
+	}
+	if filled.StampDuty != sell.StampDuty*2/3 {
+		t.Errorf("Expected filled stamp duty %d, got %d", sell.StampDuty*2/3, filled.StampDuty)
+	}
+
+	account, _ := msadb.GetAccountByID(db, accountID)
+	expected := model.YuanToHao(10000) - buy.GetTotalAmount() + model.YuanToHao(11)*200 - filled.Fee
+	if account.AvailableAmt != expected {
+		t.Errorf("Expected available %d, got %d", expected, account.AvailableAmt)
+	}
+
+	qty, _ := GetPosition(db, accountID, "300999")
+	if qty != 100 {
+		t.Errorf("Expected position 100, got %d", qty)
+	}
+}
+
+func TestPartialFillOrder_InvalidQuantity(t *testing.T) {
+	db := setupTestDB(t)
+
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(10000))
+	txID, _ := SubmitBuyOrder(db, accountID, Order{
+		StockCode: "600519", StockName: "贵州茅台", Quantity: 100, Price: model.YuanToHao(10), Time: testTradeTime,
+	})
+
+	for _, qty := range []int64{0, -1, 101} {
+		if _, _, err := PartialFillOrder(db, txID, qty); err == nil {
+			t.Errorf("Expected error for fill quantity %d", qty)
+		}
+	}
+
+	// 全部成交时不拆分
+	filledID, remainderID, err := PartialFillOrder(db, txID, 100)
+	if err != nil {
+		t.Fatalf("PartialFillOrder failed: %v", err)
+	}
+	if filledID != txID || remainderID != 0 {
+		t.Errorf("Expected (%d, 0), got (%d, %d)", txID, filledID, remainderID)
+	}
+}
+
+func TestMatchOrderWithLiquidity(t *testing.T) {
+	db := setupTestDB(t)
+
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(10000))
+	txID, _ := SubmitBuyOrder(db, accountID, Order{
+		StockCode: "300999", StockName: "小盘股", Quantity: 500, Price: model.YuanToHao(10), Time: testTradeTime,
+	})
+
+	filledQty, err := MatchOrderWithLiquidity(db, txID, model.YuanToHao(9.9), 200)
+	if err != nil {
+		t.Fatalf("MatchOrderWithLiquidity failed: %v", err)
+	}
+	if filledQty != 200 {
+		t.Errorf("Expected filled 200, got %d", filledQty)
+	}
+
+	pending, _ := GetPendingOrders(db, accountID)
+	if len(pending) != 1 || pending[0].Quantity != 300 {
+		t.Fatalf("Expected one pending remainder of 300, got %v", pending)
+	}
+
+	// 剩余部分再次撮合，流动性充足时全部成交
+	filledQty, err = MatchOrderWithLiquidity(db, pending[0].ID, model.YuanToHao(9.9), 0)
+	if err != nil {
+		t.Fatalf("MatchOrderWithLiquidity failed: %v", err)
+	}
+	if filledQty != 300 {
+		t.Errorf("Expected filled 300, got %d", filledQty)
+	}
+
+	qty, _ := GetPosition(db, accountID, "300999")
+	if qty != 500 {
+		t.Errorf("Expected position 500, got %d", qty)
+	}
+}
+
+func TestMatchPendingOrders_Depth(t *testing.T) {
+	db := setupTestDB(t)
+
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(100000))
+	first, _ := SubmitBuyOrder(db, accountID, Order{
+		StockCode: "300999", StockName: "小盘股", Quantity: 300, Price: model.YuanToHao(10), Time: testTradeTime,
+	})
+	second, _ := SubmitBuyOrder(db, accountID, Order{
+		StockCode: "300999", StockName: "小盘股", Quantity: 200, Price: model.YuanToHao(10), Time: testTradeTime,
+	})
+
+	// 卖一、卖二在限价内共 400 股，卖三高于限价
+	depth, ok := DepthFromQuote("sz300999", &model.StockQuote{
+		Asks: []model.QuoteLevel{{Price: "9.98", Volume: "1"}, {Price: "10.00", Volume: "3"}, {Price: "10.01", Volume: "50"}},
+	})
+	if !ok {
+		t.Fatal("Expected depth parsed from quote")
+	}
+	filled, err := MatchPendingOrders(db, accountID, PriceMap{"300999": model.YuanToHao(9.98)}, map[string]QuoteDepth{"300999": depth})
+	if err != nil {
+		t.Fatalf("MatchPendingOrders failed: %v", err)
+	}
+	if len(filled) != 2 || filled[0] != first || filled[1] != second {
+		t.Errorf("Expected both orders matched, got %v", filled)
+	}
+
+	// 第一笔全部成交 300 股，第二笔只剩 100 股盘口
+	qty, _ := GetPosition(db, accountID, "300999")
+	if qty != 400 {
+		t.Errorf("Expected position 400, got %d", qty)
+	}
+	pending, _ := GetPendingOrders(db, accountID)
+	if len(pending) != 1 || pending[0].Quantity != 100 || pending[0].ParentID == nil || *pending[0].ParentID != second {
+		t.Errorf("Expected remainder of 100 split from %d, got %v", second, pending)
+	}
+
+	// 盘口没有限价内的挂单时保持挂单
+	filled, _ = MatchPendingOrders(db, accountID, PriceMap{"300999": model.YuanToHao(9.98)}, map[string]QuoteDepth{
+		"300999": {Asks: []DepthLevel{{Price: model.YuanToHao(10.5), Quantity: 1000}}},
+	})
+	if len(filled) != 0 {
+		t.Errorf("Expected nothing filled without depth, got %v", filled)
+	}
+
+	if _, ok := DepthFromQuote("hk00700", &model.StockQuote{Asks: []model.QuoteLevel{{Price: "300", Volume: "10"}}}); ok {
+		t.Error("Expected HK depth to be ignored")
+	}
+}
+
+func TestBuildTransactionTree(t *testing.T) {
+	db := setupTestDB(t)
+
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(10000))
+	txID, _ := SubmitBuyOrder(db, accountID, Order{
+		StockCode: "300999", StockName: "小盘股", Quantity: 300, Price: model.YuanToHao(10), Time: testTradeTime,
+	})
+	_, remainderID, _ := PartialFillOrder(db, txID, 100)
+	PartialFillOrder(db, remainderID, 100)
+
+	transactions, _ := msadb.GetTransactionsByAccount(db, accountID)
+	roots := BuildTransactionTree(transactions)
+	if len(roots) != 1 || roots[0].ID != txID {
+		t.Fatalf("Expected single root %d, got %d roots", txID, len(roots))
+	}
+	if len(roots[0].Children) != 2 {
+		t.Fatalf("Expected 2 children, got %d", len(roots[0].Children))
+	}
+
+	var nested *TransactionNode
+	for _, child := range roots[0].Children {
+		if child.ID == remainderID {
+			nested = child
+		}
+	}
+	if nested == nil || nested.Status != model.TransactionStatusObsolete || len(nested.Children) != 2 {
+		t.Errorf("Expected remainder %d to be split into 2 children", remainderID)
+	}
+}
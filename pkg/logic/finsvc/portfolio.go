@@ -0,0 +1,179 @@
+package finsvc
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"msa/pkg/db"
+	"msa/pkg/logic/calendar"
+	"msa/pkg/model"
+)
+
+// TradingDaysPerYear 年化计算使用的交易日数
+const TradingDaysPerYear = 252
+
+// SnapshotDateLayout 快照交易日格式
+const SnapshotDateLayout = "2006-01-02"
+
+// IsAfterMarketClose 判断 t 是否为 A股交易日收盘（15:00）之后
+func IsAfterMarketClose(t time.Time) bool {
+	_, closeAt, ok := calendar.OpenClose(calendar.MarketCN, t)
+	return ok && !t.Before(closeAt)
+}
+
+// SnapshotTradeDate 获取 t 对应的快照交易日（交易所时区）
+func SnapshotTradeDate(t time.Time) string {
+	return t.In(chinaLocation).Format(SnapshotDateLayout)
+}
+
+// TakePortfolioSnapshot 按收盘价记录账户净值快照
+// prices 须包含所有持仓的价格，任意持仓缺少价格时返回错误，避免写入不完整的净值
+// 同一交易日重复记录时覆盖旧快照
+func TakePortfolioSnapshot(database *gorm.DB, accountID uint, prices PriceMap, at time.Time) (*model.PortfolioSnapshot, error) {
+	tradeDate := SnapshotTradeDate(at)
+	log.Infof("记录净值快照: 账户=%d, 交易日=%s", accountID, tradeDate)
+
+	positions, err := GetAllPositions(database, accountID, prices)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := GetAccountTotalValue(database, accountID, prices)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.FailedStocks) > 0 {
+		return nil, fmt.Errorf("missing prices for snapshot: %v", result.FailedStocks)
+	}
+
+	contribution, err := GetNetContribution(database, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &model.PortfolioSnapshot{
+		AccountID:       accountID,
+		TradeDate:       tradeDate,
+		AvailableAmt:    result.AvailableAmt,
+		LockedAmt:       result.LockedAmt,
+		PositionValue:   result.PositionValue,
+		NAV:             result.TotalValue,
+		NetContribution: contribution,
+	}
+	for _, pos := range positions {
+		snapshot.Holdings = append(snapshot.Holdings, model.PortfolioSnapshotHolding{
+			StockCode: pos.StockCode,
+			StockName: pos.StockName,
+			Quantity:  pos.Quantity,
+			Price:     pos.CurrentPrice,
+			Value:     pos.Value,
+			Cost:      pos.Cost,
+		})
+	}
+
+	tx := database.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			log.Errorf("记录净值快照 panic: %v", r)
+		}
+	}()
+
+	if _, err := db.SavePortfolioSnapshot(tx, snapshot); err != nil {
+		tx.Rollback()
+		log.Errorf("保存净值快照失败: %v", err)
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Errorf("提交事务失败: %v", err)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Infof("净值快照已记录: 交易日=%s, 净值=%d, 持仓=%d 只", tradeDate, snapshot.NAV, len(snapshot.Holdings))
+	return snapshot, nil
+}
+
+// EquityPoint 净值曲线上的单个交易日
+type EquityPoint struct {
+	TradeDate        string
+	NAV              int64   // 净值（毫）
+	NetFlow          int64   // 当日净入金（毫）= 当日净投入本金 - 前一日净投入本金
+	DailyReturn      float64 // 日收益率（小数），剔除当日入金出金
+	CumulativeReturn float64 // 累计收益率（小数），按日收益率连乘（时间加权）
+	Drawdown         float64 // 相对历史最高点的回撤（小数，≤ 0）
+}
+
+// EquityCurve 净值曲线及风险收益指标
+// 收益率均为小数（0.01 表示 1%），波动率与夏普比率按 TradingDaysPerYear 年化
+type EquityCurve struct {
+	Points           []EquityPoint
+	CumulativeReturn float64 // 区间累计收益率
+	MaxDrawdown      float64 // 最大回撤（正数）
+	Volatility       float64 // 年化波动率
+	Sharpe           float64 // 年化夏普比率，样本不足或波动率为 0 时为 0
+}
+
+// ComputeEquityCurve 根据按交易日升序排列的快照计算净值曲线
+// 日收益率 = (当日净值 - 当日净入金 - 前一日净值) / 前一日净值，首日收益率为 0
+// riskFreeRate 为年化无风险利率（小数）
+func ComputeEquityCurve(snapshots []*model.PortfolioSnapshot, riskFreeRate float64) *EquityCurve {
+	curve := &EquityCurve{Points: make([]EquityPoint, 0, len(snapshots))}
+
+	var returns []float64
+	index, peak := 1.0, 1.0
+	for i, s := range snapshots {
+		point := EquityPoint{TradeDate: s.TradeDate, NAV: s.NAV}
+		if i > 0 {
+			prev := snapshots[i-1]
+			point.NetFlow = s.NetContribution - prev.NetContribution
+			if prev.NAV > 0 {
+				point.DailyReturn = float64(s.NAV-point.NetFlow-prev.NAV) / float64(prev.NAV)
+			}
+			returns = append(returns, point.DailyReturn)
+		}
+
+		index *= 1 + point.DailyReturn
+		peak = math.Max(peak, index)
+		point.CumulativeReturn = index - 1
+		point.Drawdown = index/peak - 1
+		curve.MaxDrawdown = math.Max(curve.MaxDrawdown, -point.Drawdown)
+		curve.Points = append(curve.Points, point)
+	}
+	curve.CumulativeReturn = index - 1
+
+	if len(returns) < 2 {
+		return curve
+	}
+	var mean float64
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	var variance float64
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	std := math.Sqrt(variance / float64(len(returns)-1))
+
+	curve.Volatility = std * math.Sqrt(TradingDaysPerYear)
+	if std > 0 {
+		curve.Sharpe = (mean - riskFreeRate/TradingDaysPerYear) / std * math.Sqrt(TradingDaysPerYear)
+	}
+	return curve
+}
+
+// GetEquityCurve 查询账户区间快照并计算净值曲线
+// from、to 为 YYYY-MM-DD 格式，为空表示不限制
+func GetEquityCurve(database *gorm.DB, accountID uint, from, to string, riskFreeRate float64) (*EquityCurve, error) {
+	snapshots, err := db.GetPortfolioSnapshots(database, accountID, from, to)
+	if err != nil {
+		log.Errorf("查询净值快照失败: %v", err)
+		return nil, err
+	}
+	return ComputeEquityCurve(snapshots, riskFreeRate), nil
+}
@@ -0,0 +1,111 @@
+package finsvc
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	msadb "msa/pkg/db"
+	"msa/pkg/model"
+)
+
+func TestIsAfterMarketClose(t *testing.T) {
+	tests := []struct {
+		name     string
+		at       time.Time
+		expected bool
+	}{
+		{"盘中", time.Date(2025, 3, 3, 14, 59, 0, 0, chinaLocation), false},
+		{"收盘", time.Date(2025, 3, 3, 15, 0, 0, 0, chinaLocation), true},
+		{"晚间", time.Date(2025, 3, 3, 21, 0, 0, 0, chinaLocation), true},
+		{"周六", time.Date(2025, 3, 8, 16, 0, 0, 0, chinaLocation), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsAfterMarketClose(tt.at); got != tt.expected {
+				t.Errorf("IsAfterMarketClose() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTakePortfolioSnapshot(t *testing.T) {
+	db := setupTestDB(t)
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(100000))
+
+	buyID, _ := SubmitBuyOrder(db, accountID, Order{
+		StockCode: "sh600519", StockName: "贵州茅台", Quantity: 1000, Price: model.YuanToHao(10), Time: testTradeTime,
+	})
+	FillOrder(db, buyID)
+
+	// 缺少持仓价格时不记录
+	if _, err := TakePortfolioSnapshot(db, accountID, PriceMap{}, testTradeTime); err == nil {
+		t.Error("Expected error for missing prices")
+	}
+
+	closeTime := time.Date(2025, 3, 3, 15, 30, 0, 0, chinaLocation)
+	snapshot, err := TakePortfolioSnapshot(db, accountID, PriceMap{"sh600519": model.YuanToHao(11)}, closeTime)
+	if err != nil {
+		t.Fatalf("TakePortfolioSnapshot failed: %v", err)
+	}
+	if snapshot.TradeDate != "2025-03-03" {
+		t.Errorf("Expected trade date 2025-03-03, got %s", snapshot.TradeDate)
+	}
+	if snapshot.PositionValue != model.YuanToHao(11000) || snapshot.NAV != snapshot.AvailableAmt+snapshot.LockedAmt+snapshot.PositionValue {
+		t.Errorf("Unexpected snapshot values: %+v", snapshot)
+	}
+
+	// 同一交易日重复记录覆盖旧快照
+	if _, err := TakePortfolioSnapshot(db, accountID, PriceMap{"sh600519": model.YuanToHao(12)}, closeTime.Add(time.Hour)); err != nil {
+		t.Fatalf("TakePortfolioSnapshot failed: %v", err)
+	}
+	snapshots, _ := msadb.GetPortfolioSnapshots(db, accountID, "", "")
+	if len(snapshots) != 1 {
+		t.Fatalf("Expected 1 snapshot, got %d", len(snapshots))
+	}
+	if len(snapshots[0].Holdings) != 1 || snapshots[0].Holdings[0].Price != model.YuanToHao(12) {
+		t.Errorf("Expected overwritten holding price 12, got %+v", snapshots[0].Holdings)
+	}
+}
+
+func TestComputeEquityCurve(t *testing.T) {
+	snapshots := []*model.PortfolioSnapshot{
+		{TradeDate: "2025-03-03", NAV: model.YuanToHao(100000), NetContribution: model.YuanToHao(100000)},
+		{TradeDate: "2025-03-04", NAV: model.YuanToHao(110000), NetContribution: model.YuanToHao(100000)},
+		// 入金 50000 不计入收益：(154000 - 50000 - 110000) / 110000
+		{TradeDate: "2025-03-05", NAV: model.YuanToHao(154000), NetContribution: model.YuanToHao(150000)},
+		{TradeDate: "2025-03-06", NAV: model.YuanToHao(161700), NetContribution: model.YuanToHao(150000)},
+	}
+
+	curve := ComputeEquityCurve(snapshots, 0)
+	if len(curve.Points) != 4 {
+		t.Fatalf("Expected 4 points, got %d", len(curve.Points))
+	}
+
+	expectedDaily := []float64{0, 0.1, -6.0 / 110, 0.05}
+	for i, expected := range expectedDaily {
+		if math.Abs(curve.Points[i].DailyReturn-expected) > 1e-9 {
+			t.Errorf("Point %d: expected daily return %v, got %v", i, expected, curve.Points[i].DailyReturn)
+		}
+	}
+	if curve.Points[2].NetFlow != model.YuanToHao(50000) {
+		t.Errorf("Expected net flow 50000, got %d", curve.Points[2].NetFlow)
+	}
+
+	// 1.1 × (104/110) × 1.05 - 1 = 0.092
+	if math.Abs(curve.CumulativeReturn-0.092) > 1e-9 {
+		t.Errorf("Expected cumulative return 0.092, got %v", curve.CumulativeReturn)
+	}
+	if math.Abs(curve.MaxDrawdown-6.0/110) > 1e-9 {
+		t.Errorf("Expected max drawdown %v, got %v", 6.0/110, curve.MaxDrawdown)
+	}
+	if curve.Volatility <= 0 || curve.Sharpe == 0 {
+		t.Errorf("Expected positive volatility and non-zero sharpe, got %v / %v", curve.Volatility, curve.Sharpe)
+	}
+
+	// 样本不足时不计算波动率与夏普
+	short := ComputeEquityCurve(snapshots[:2], 0)
+	if short.Volatility != 0 || short.Sharpe != 0 {
+		t.Errorf("Expected zero volatility and sharpe for single return, got %v / %v", short.Volatility, short.Sharpe)
+	}
+}
@@ -2,6 +2,7 @@ package finsvc
 
 import (
 	"fmt"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"gorm.io/gorm"
@@ -12,13 +13,15 @@ import (
 
 // Position 持仓信息
 type Position struct {
-	StockCode    string
-	StockName    string
-	Quantity     int64 // 持仓数量
-	Cost         int64 // 持仓成本（毫）
-	CurrentPrice int64 // 当前价格（毫，需要外部传入）
-	Value        int64 // 持仓市值（毫）
-	PnL          int64 // 盈亏（毫）
+	StockCode     string
+	StockName     string
+	Quantity      int64 // 持仓数量
+	Cost          int64 // 持仓成本（毫，按成本计算方法得到的剩余批次成本）
+	AvgCost       int64 // 每股平均成本（毫）
+	CurrentPrice  int64 // 当前价格（毫，需要外部传入）
+	Value         int64 // 持仓市值（毫）
+	UnrealizedPnL int64 // 浮动盈亏（毫）= 市值 - 持仓成本
+	RealizedPnL   int64 // 已实现盈亏（毫）= 卖出净收入 - 卖出部分成本 + 现金分红
 }
 
 // PriceMap 价格映射
@@ -30,11 +33,13 @@ type AccountValueResult struct {
 	AvailableAmt  int64    // 可用余额
 	LockedAmt     int64    // 锁定金额
 	PositionValue int64    // 持仓市值
+	UnrealizedPnL int64    // 浮动盈亏（仅含获取到价格的持仓）
+	RealizedPnL   int64    // 已实现盈亏（含已清仓股票）
 	FailedStocks  []string // 价格获取失败的股票代码列表
 }
 
 // GetActiveStockCodes 获取当前实际有持仓（净持仓 > 0）的股票代码列表
-// 通过 SQL 聚合计算买入量 - 卖出量，过滤掉已平仓的股票
+// 通过 SQL 聚合计算买入量 - 卖出量 + 送转/拆合股数量变化，过滤掉已平仓的股票
 func GetActiveStockCodes(database *gorm.DB, accountID uint) ([]string, error) {
 	type StockQty struct {
 		StockCode string
@@ -48,20 +53,37 @@ func GetActiveStockCodes(database *gorm.DB, accountID uint) ([]string, error) {
 			model.TransactionTypeBuy, model.TransactionTypeSell).
 		Where("account_id = ? AND status = ?", accountID, model.TransactionStatusFilled).
 		Group("stock_code").
-		Having("net_qty > 0").
+		Order("stock_code").
 		Scan(&rows).Error
 	if err != nil {
 		return nil, fmt.Errorf("failed to query active stock codes: %w", err)
 	}
+
+	var deltas []StockQty
+	err = database.Model(&model.CorporateAction{}).
+		Select("stock_code, COALESCE(SUM(quantity_delta), 0) AS net_qty").
+		Where("account_id = ?", accountID).
+		Group("stock_code").
+		Scan(&deltas).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to query corporate quantity delta: %w", err)
+	}
+	deltaMap := make(map[string]int64, len(deltas))
+	for _, d := range deltas {
+		deltaMap[d.StockCode] = d.NetQty
+	}
+
 	codes := make([]string, 0, len(rows))
 	for _, r := range rows {
-		codes = append(codes, r.StockCode)
+		if r.NetQty+deltaMap[r.StockCode] > 0 {
+			codes = append(codes, r.StockCode)
+		}
 	}
 	return codes, nil
 }
 
 // GetPosition 获取指定股票的持仓数量
-// 计算：SUM(BUY) - SUM(SELL)
+// 计算：SUM(BUY) - SUM(SELL) + 送转/拆合股数量变化
 func GetPosition(database *gorm.DB, accountID uint, stockCode string) (int64, error) {
 	log.Debugf("查询持仓: 账户=%d, 股票=%s", accountID, stockCode)
 
@@ -87,11 +109,55 @@ func GetPosition(database *gorm.DB, accountID uint, stockCode string) (int64, er
 		return 0, fmt.Errorf("failed to query sell quantity: %w", err)
 	}
 
-	qty := buyQty - sellQty
+	// 送转、拆合股带来的数量变化
+	delta, err := getCorporateQuantityDelta(database, accountID, stockCode)
+	if err != nil {
+		return 0, err
+	}
+
+	qty := buyQty - sellQty + delta
 	log.Debugf("持仓数量: %d", qty)
 	return qty, nil
 }
 
+// GetSellableQuantity 获取指定股票在 at 时刻的可卖数量
+// 计算：持仓 - 挂单中的卖出数量 - 当日买入数量（A股 T+1 冻结，港股不冻结）
+func GetSellableQuantity(database *gorm.DB, accountID uint, stockCode string, at time.Time) (int64, error) {
+	position, err := GetPosition(database, accountID, stockCode)
+	if err != nil {
+		return 0, err
+	}
+
+	pendingSell, err := getPendingSellQuantity(database, accountID, stockCode)
+	if err != nil {
+		return 0, err
+	}
+
+	var frozen int64
+	if DetectBoard(stockCode) != BoardHK {
+		frozen, err = getBoughtQuantitySince(database, accountID, stockCode, tradingDayStart(at))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return max(position-pendingSell-frozen, 0), nil
+}
+
+// getPendingSellQuantity 统计挂单中的卖出数量（内部函数）
+func getPendingSellQuantity(database *gorm.DB, accountID uint, stockCode string) (int64, error) {
+	var qty int64
+	err := database.Model(&model.Transaction{}).
+		Select("COALESCE(SUM(quantity), 0)").
+		Where("account_id = ? AND stock_code = ? AND type = ? AND status = ?", accountID, stockCode, model.TransactionTypeSell, model.TransactionStatusPending).
+		Scan(&qty).Error
+	if err != nil {
+		log.Errorf("查询挂单卖出数量失败: %v", err)
+		return 0, fmt.Errorf("failed to query pending sell quantity: %w", err)
+	}
+	return qty, nil
+}
+
 // GetAllPositions 获取所有持仓
 func GetAllPositions(database *gorm.DB, accountID uint, prices PriceMap) ([]*Position, error) {
 	log.Debugf("查询所有持仓: 账户=%d", accountID)
@@ -125,10 +191,11 @@ func GetAllPositions(database *gorm.DB, accountID uint, prices PriceMap) ([]*Pos
 			continue
 		}
 
-		cost, err := getPositionCost(database, accountID, info.StockCode)
+		book, err := BuildLotBook(database, accountID, info.StockCode, GetCostMethod())
 		if err != nil {
 			return nil, err
 		}
+		cost := book.TotalCost()
 
 		price, ok := prices[info.StockCode]
 		if !ok {
@@ -136,16 +203,17 @@ func GetAllPositions(database *gorm.DB, accountID uint, prices PriceMap) ([]*Pos
 		}
 
 		value := qty * price
-		pnl := value - cost
 
 		positions = append(positions, &Position{
-			StockCode:    info.StockCode,
-			StockName:    info.StockName,
-			Quantity:     qty,
-			Cost:         cost,
-			CurrentPrice: price,
-			Value:        value,
-			PnL:          pnl,
+			StockCode:     info.StockCode,
+			StockName:     info.StockName,
+			Quantity:      qty,
+			Cost:          cost,
+			AvgCost:       book.AvgCost(),
+			CurrentPrice:  price,
+			Value:         value,
+			UnrealizedPnL: value - cost,
+			RealizedPnL:   book.RealizedPnL,
 		})
 	}
 
@@ -154,7 +222,7 @@ func GetAllPositions(database *gorm.DB, accountID uint, prices PriceMap) ([]*Pos
 }
 
 // GetAccountTotalValue 获取账户总市值
-// 返回完整的账户价值计算结果，包含持仓市值和价格获取失败的股票列表
+// 返回完整的账户价值计算结果，包含持仓市值、已实现/浮动盈亏和价格获取失败的股票列表
 func GetAccountTotalValue(database *gorm.DB, accountID uint, prices PriceMap) (*AccountValueResult, error) {
 	log.Debugf("计算账户总市值: 账户=%d", accountID)
 
@@ -173,6 +241,7 @@ func GetAccountTotalValue(database *gorm.DB, accountID uint, prices PriceMap) (*
 	}
 
 	positionValue := int64(0)
+	unrealizedPnL := int64(0)
 	var failedStocks []string
 
 	for _, stockCode := range stockCodes {
@@ -193,7 +262,18 @@ func GetAccountTotalValue(database *gorm.DB, accountID uint, prices PriceMap) (*
 			continue
 		}
 
+		book, err := BuildLotBook(database, accountID, stockCode, GetCostMethod())
+		if err != nil {
+			return nil, err
+		}
+
 		positionValue += qty * price
+		unrealizedPnL += qty*price - book.TotalCost()
+	}
+
+	realizedPnL, err := GetRealizedPnL(database, accountID, GetCostMethod())
+	if err != nil {
+		return nil, err
 	}
 
 	totalValue := account.AvailableAmt + account.LockedAmt + positionValue
@@ -205,34 +285,8 @@ func GetAccountTotalValue(database *gorm.DB, accountID uint, prices PriceMap) (*
 		AvailableAmt:  account.AvailableAmt,
 		LockedAmt:     account.LockedAmt,
 		PositionValue: positionValue,
+		UnrealizedPnL: unrealizedPnL,
+		RealizedPnL:   realizedPnL,
 		FailedStocks:  failedStocks,
 	}, nil
 }
-
-// getPositionCost 获取指定股票的持仓成本（内部函数）
-// 计算：SUM(BUY金额) - SUM(SELL金额)
-func getPositionCost(database *gorm.DB, accountID uint, stockCode string) (int64, error) {
-	// 查询已成交的买入金额（含手续费）
-	var buyAmount int64
-	err := database.Model(&model.Transaction{}).
-		Select("COALESCE(SUM(amount + fee), 0)").
-		Where("account_id = ? AND stock_code = ? AND type = ? AND status = ?", accountID, stockCode, model.TransactionTypeBuy, model.TransactionStatusFilled).
-		Scan(&buyAmount).Error
-	if err != nil {
-		log.Errorf("查询买入金额失败: %v", err)
-		return 0, fmt.Errorf("failed to query buy amount: %w", err)
-	}
-
-	// 查询已成交的卖出金额（净收入）
-	var sellAmount int64
-	err = database.Model(&model.Transaction{}).
-		Select("COALESCE(SUM(amount - fee), 0)").
-		Where("account_id = ? AND stock_code = ? AND type = ? AND status = ?", accountID, stockCode, model.TransactionTypeSell, model.TransactionStatusFilled).
-		Scan(&sellAmount).Error
-	if err != nil {
-		log.Errorf("查询卖出金额失败: %v", err)
-		return 0, fmt.Errorf("failed to query sell amount: %w", err)
-	}
-
-	return buyAmount - sellAmount, nil
-}
@@ -2,6 +2,7 @@ package finsvc
 
 import (
 	"testing"
+	"time"
 
 	msadb "msa/pkg/db"
 	"msa/pkg/model"
@@ -22,13 +23,13 @@ func TestGetPosition(t *testing.T) {
 		t.Errorf("Expected 0, got %d", qty)
 	}
 
-	// 买入 100 股
+	// 买入 200 股
 	buyOrder := Order{
 		StockCode: "600519",
 		StockName: "贵州茅台",
-		Quantity:  100,
+		Quantity:  200,
 		Price:     model.YuanToHao(10),
-		Fee:       model.YuanToHao(0.05),
+		Time:      testTradeTime,
 	}
 	txID, _ := SubmitBuyOrder(db, account.ID, buyOrder)
 	FillOrder(db, txID)
@@ -37,17 +38,17 @@ func TestGetPosition(t *testing.T) {
 	if err != nil {
 		t.Fatalf("GetPosition failed: %v", err)
 	}
-	if qty != 100 {
-		t.Errorf("Expected 100, got %d", qty)
+	if qty != 200 {
+		t.Errorf("Expected 200, got %d", qty)
 	}
 
-	// 卖出 30 股
+	// 次日卖出 100 股
 	sellOrder := Order{
 		StockCode: "600519",
 		StockName: "贵州茅台",
-		Quantity:  30,
+		Quantity:  100,
 		Price:     model.YuanToHao(11),
-		Fee:       model.YuanToHao(0.05),
+		Time:      testNextTradeTime,
 	}
 	sellTxID, _ := SubmitSellOrder(db, account.ID, sellOrder)
 	FillOrder(db, sellTxID)
@@ -56,8 +57,8 @@ func TestGetPosition(t *testing.T) {
 	if err != nil {
 		t.Fatalf("GetPosition failed: %v", err)
 	}
-	if qty != 70 {
-		t.Errorf("Expected 70, got %d", qty)
+	if qty != 100 {
+		t.Errorf("Expected 100, got %d", qty)
 	}
 }
 
@@ -82,7 +83,7 @@ func TestGetAllPositions(t *testing.T) {
 		StockName: "贵州茅台",
 		Quantity:  100,
 		Price:     model.YuanToHao(10),
-		Fee:       model.YuanToHao(0.05),
+		Time:      testTradeTime,
 	}
 	txID1, _ := SubmitBuyOrder(db, account.ID, order1)
 	FillOrder(db, txID1)
@@ -92,7 +93,7 @@ func TestGetAllPositions(t *testing.T) {
 		StockName: "平安银行",
 		Quantity:  200,
 		Price:     model.YuanToHao(15),
-		Fee:       model.YuanToHao(0.05),
+		Time:      testTradeTime,
 	}
 	txID2, _ := SubmitBuyOrder(db, account.ID, order2)
 	FillOrder(db, txID2)
@@ -152,7 +153,7 @@ func TestGetAccountTotalValue(t *testing.T) {
 		StockName: "贵州茅台",
 		Quantity:  100,
 		Price:     model.YuanToHao(10),
-		Fee:       model.YuanToHao(0.05),
+		Time:      testTradeTime,
 	}
 	txID, _ := SubmitBuyOrder(db, account.ID, order)
 	FillOrder(db, txID)
@@ -168,10 +169,10 @@ func TestGetAccountTotalValue(t *testing.T) {
 	}
 
 	// 总价值 = 可用余额 + 持仓市值
-	// 可用 = 10000 - 1000 - 0.05 = 8999.95
+	// 可用 = 10000 - 1000 - 手续费 5.01 = 8994.99
 	// 持仓市值 = 100 * 12 = 1200
 	// 总计 = 10199.95
-	expectedAvailable := model.YuanToHao(10000) - model.YuanToHao(10)*100 - model.YuanToHao(0.05)
+	expectedAvailable := model.YuanToHao(10000) - model.YuanToHao(10)*100 - model.YuanToHao(5.01)
 	expectedValue := expectedAvailable + model.YuanToHao(12)*100
 	if result.TotalValue != expectedValue {
 		t.Errorf("Expected total %d, got %d", expectedValue, result.TotalValue)
@@ -205,7 +206,7 @@ func TestGetAllPositions_ZeroQuantity(t *testing.T) {
 		StockName: "贵州茅台",
 		Quantity:  100,
 		Price:     model.YuanToHao(10),
-		Fee:       model.YuanToHao(0.05),
+		Time:      testTradeTime,
 	}
 	buyTxID, _ := SubmitBuyOrder(db, account.ID, order)
 	FillOrder(db, buyTxID)
@@ -215,7 +216,7 @@ func TestGetAllPositions_ZeroQuantity(t *testing.T) {
 		StockName: "贵州茅台",
 		Quantity:  100,
 		Price:     model.YuanToHao(11),
-		Fee:       model.YuanToHao(0.05),
+		Time:      testNextTradeTime,
 	}
 	sellTxID, _ := SubmitSellOrder(db, account.ID, sellOrder)
 	FillOrder(db, sellTxID)
@@ -229,3 +230,44 @@ func TestGetAllPositions_ZeroQuantity(t *testing.T) {
 		t.Errorf("Expected 0 positions after sell all, got %d", len(positions))
 	}
 }
+
+func TestGetSellableQuantity(t *testing.T) {
+	db := setupTestDB(t)
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(100000))
+
+	// 昨日买入 300 股，今日买入 100 股
+	buyID, _ := SubmitBuyOrder(db, accountID, Order{
+		StockCode: "600519", StockName: "贵州茅台", Quantity: 300, Price: model.YuanToHao(10), Time: testTradeTime,
+	})
+	FillOrder(db, buyID)
+	buyID, _ = SubmitBuyOrder(db, accountID, Order{
+		StockCode: "600519", StockName: "贵州茅台", Quantity: 100, Price: model.YuanToHao(10), Time: testNextTradeTime,
+	})
+	FillOrder(db, buyID)
+
+	// 今日挂单卖出 100 股
+	SubmitSellOrder(db, accountID, Order{
+		StockCode: "600519", StockName: "贵州茅台", Quantity: 100, Price: model.YuanToHao(20), Time: testNextTradeTime,
+	})
+
+	// 可卖 = 持仓 400 - 挂单 100 - 今日买入 100
+	sellable, err := GetSellableQuantity(db, accountID, "600519", testNextTradeTime)
+	if err != nil {
+		t.Fatalf("GetSellableQuantity failed: %v", err)
+	}
+	if sellable != 200 {
+		t.Errorf("Expected sellable 200, got %d", sellable)
+	}
+
+	// 次日 T+1 冻结解除
+	sellable, _ = GetSellableQuantity(db, accountID, "600519", testNextTradeTime.Add(24*time.Hour))
+	if sellable != 300 {
+		t.Errorf("Expected sellable 300 on next day, got %d", sellable)
+	}
+
+	// 无持仓
+	sellable, _ = GetSellableQuantity(db, accountID, "000001", testNextTradeTime)
+	if sellable != 0 {
+		t.Errorf("Expected sellable 0, got %d", sellable)
+	}
+}
@@ -0,0 +1,303 @@
+package finsvc
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"msa/pkg/model"
+)
+
+var (
+	riskPolicy   model.RiskPolicy
+	riskData     RiskDataSource
+	riskPolicyMu sync.RWMutex
+)
+
+// RiskDataSource 风控估值数据源，订单未携带持仓价格或行业时在开启下单事务前查询
+type RiskDataSource interface {
+	// Prices 批量获取估值价格（毫），未返回的股票视为无法估值
+	Prices(stockCodes []string) (PriceMap, error)
+	// Industry 获取股票所属申万一级行业，未知时返回空字符串
+	Industry(stockCode string) (string, error)
+}
+
+// SetRiskPolicy 设置全局风控限制（启动时由配置注入）
+func SetRiskPolicy(policy model.RiskPolicy) {
+	blacklist := make([]string, 0, len(policy.Blacklist))
+	for _, code := range policy.Blacklist {
+		if code = strings.ToLower(strings.TrimSpace(code)); code != "" {
+			blacklist = append(blacklist, code)
+		}
+	}
+	policy.Blacklist = blacklist
+
+	riskPolicyMu.Lock()
+	defer riskPolicyMu.Unlock()
+	riskPolicy = policy
+}
+
+// GetRiskPolicy 获取当前风控限制
+func GetRiskPolicy() model.RiskPolicy {
+	riskPolicyMu.RLock()
+	defer riskPolicyMu.RUnlock()
+	policy := riskPolicy
+	policy.Blacklist = slices.Clone(riskPolicy.Blacklist)
+	return policy
+}
+
+// SetRiskDataSource 设置风控估值数据源（启动时注入），为 nil 时只使用订单携带的价格与行业
+// 返回之前的数据源，回测等场景临时替换后用于恢复
+func SetRiskDataSource(source RiskDataSource) RiskDataSource {
+	riskPolicyMu.Lock()
+	defer riskPolicyMu.Unlock()
+	prev := riskData
+	riskData = source
+	return prev
+}
+
+// getRiskDataSource 获取风控估值数据源（内部函数）
+func getRiskDataSource() RiskDataSource {
+	riskPolicyMu.RLock()
+	defer riskPolicyMu.RUnlock()
+	return riskData
+}
+
+// IsBlacklisted 股票是否在风控黑名单中，sh/sz 前缀可省略
+func IsBlacklisted(policy model.RiskPolicy, stockCode string) bool {
+	code := bareStockCode(stockCode)
+	return slices.ContainsFunc(policy.Blacklist, func(c string) bool { return bareStockCode(c) == code })
+}
+
+// bareStockCode 去掉 A 股 sh/sz 前缀的小写代码，港股保留 hk 前缀
+func bareStockCode(stockCode string) string {
+	code := strings.ToLower(strings.TrimSpace(stockCode))
+	return strings.TrimPrefix(strings.TrimPrefix(code, "sh"), "sz")
+}
+
+// RiskUsage 账户当日风控额度使用情况
+type RiskUsage struct {
+	Orders   int   // 当日委托笔数（不含被拒绝的订单，部分成交拆分的子记录不重复计数）
+	Turnover int64 // 当日挂单中与已成交的买卖委托金额合计（毫）
+}
+
+// GetRiskUsage 统计 at 所在交易日的委托笔数与委托金额
+// 时间比较在内存中按时区换算进行，与 T+1 判断一致
+func GetRiskUsage(database *gorm.DB, accountID uint, at time.Time) (RiskUsage, error) {
+	var transactions []*model.Transaction
+	if err := database.Where("account_id = ?", accountID).Find(&transactions).Error; err != nil {
+		log.Errorf("查询交易记录失败: %v", err)
+		return RiskUsage{}, fmt.Errorf("failed to query transactions: %w", err)
+	}
+
+	start := tradingDayStart(at)
+	end := start.AddDate(0, 0, 1)
+	var usage RiskUsage
+	for _, t := range transactions {
+		if t.CreatedAt.Before(start) || !t.CreatedAt.Before(end) || t.Status == model.TransactionStatusRejected {
+			continue
+		}
+		if t.ParentID == nil {
+			usage.Orders++
+		}
+		if t.Status == model.TransactionStatusPending || t.Status == model.TransactionStatusFilled {
+			usage.Turnover += t.Amount
+		}
+	}
+	return usage, nil
+}
+
+// fillRiskContext 经风控数据源补充订单未携带的持仓估值价格与行业（内部函数）
+// 在开启下单事务之前调用，避免在事务中进行网络查询；获取失败只记录日志，由 CheckRiskPolicy 按缺失数据拒绝
+func fillRiskContext(database *gorm.DB, accountID uint, orderType model.TransactionType, order *Order) {
+	policy := GetRiskPolicy()
+	if policy.IsEmpty() || riskExempt(orderType, *order) || valuationRule(policy, orderType) == "" {
+		return
+	}
+	source := getRiskDataSource()
+	if source == nil {
+		return
+	}
+	codes, err := GetActiveStockCodes(database, accountID)
+	if err != nil {
+		log.Warnf("风控: 查询持仓失败: %v", err)
+		return
+	}
+
+	order.Prices = maps.Clone(order.Prices)
+	var missing []string
+	for _, code := range codes {
+		if code != order.StockCode && order.Prices[code] <= 0 {
+			missing = append(missing, code)
+		}
+	}
+	if len(missing) > 0 {
+		if prices, err := source.Prices(missing); err != nil {
+			log.Warnf("风控: 获取持仓价格失败: %v", err)
+		} else {
+			if order.Prices == nil {
+				order.Prices = make(PriceMap, len(prices))
+			}
+			maps.Copy(order.Prices, prices)
+		}
+	}
+
+	if orderType != model.TransactionTypeBuy || policy.MaxIndustryPct <= 0 {
+		return
+	}
+	order.Industries = maps.Clone(order.Industries)
+	if order.Industries == nil {
+		order.Industries = make(map[string]string, len(codes)+1)
+	}
+	for _, code := range append(codes, order.StockCode) {
+		if order.Industries[code] != "" {
+			continue
+		}
+		industry, err := source.Industry(code)
+		if err != nil {
+			log.Warnf("风控: 获取 %s 行业失败: %v", code, err)
+			continue
+		}
+		order.Industries[code] = industry
+	}
+}
+
+// riskExempt 条件单触发的保护性卖出不受风控限制，避免止损止盈因当日额度用尽无法执行
+func riskExempt(orderType model.TransactionType, order Order) bool {
+	return orderType == model.TransactionTypeSell && order.Protective
+}
+
+// CheckRiskPolicy 按风控限制校验订单
+// 买入校验全部限制；卖出只校验当日委托笔数与当日委托金额，黑名单与仓位类限制不限制卖出；条件单触发的卖出不受限制
+// 总资产 = 可用 + 锁定 + 持仓市值，持仓按 order.Prices 估值，缺失时本订单股票用委托价；行业取 order.Industries
+// 只使用订单携带的数据，不进行网络查询（由 fillRiskContext 在事务前补充）
+// 估值价格或行业缺失、总资产不为正时按需要该数据的规则拒绝，不放行
+// 返回拒绝原因（含违反的规则名），空字符串表示校验通过；error 仅表示查询失败
+func CheckRiskPolicy(database *gorm.DB, account *model.Account, orderType model.TransactionType, order Order) (string, error) {
+	policy := GetRiskPolicy()
+	if policy.IsEmpty() || riskExempt(orderType, order) {
+		return "", nil
+	}
+	buy := orderType == model.TransactionTypeBuy
+
+	if buy && IsBlacklisted(policy, order.StockCode) {
+		return riskReject(model.RiskRuleBlacklist, "%s 在风控黑名单中，禁止买入", order.StockCode), nil
+	}
+
+	usage, err := GetRiskUsage(database, account.ID, order.orderTime())
+	if err != nil {
+		return "", err
+	}
+	if policy.MaxOrdersPerDay > 0 && usage.Orders >= policy.MaxOrdersPerDay {
+		return riskReject(model.RiskRuleMaxOrdersPerDay, "当日已委托 %d 笔，达到上限 %d 笔", usage.Orders, policy.MaxOrdersPerDay), nil
+	}
+
+	rule := valuationRule(policy, orderType)
+	if rule == "" {
+		return "", nil
+	}
+	nav, values, reason, err := riskValuation(database, account, order, rule)
+	if err != nil || reason != "" {
+		return reason, err
+	}
+
+	amount := order.Quantity * order.Price
+	percentOf := func(v int64) float64 { return float64(v) / float64(nav) * 100 }
+
+	if limit := policy.MaxDailyTurnoverPct; limit > 0 && percentOf(usage.Turnover+amount) > limit {
+		return riskReject(model.RiskRuleMaxDailyTurnoverPct, "当日委托金额 %s（含本单 %s）占总资产 %.1f%%，超过上限 %.1f%%",
+			model.FormatAmount(usage.Turnover+amount), model.FormatAmount(amount), percentOf(usage.Turnover+amount), limit), nil
+	}
+	if !buy {
+		return "", nil
+	}
+
+	cost := amount + CalculateFees(model.TransactionTypeBuy, order.StockCode, amount).Total()
+	if limit := policy.MaxPositionPct; limit > 0 && percentOf(values[order.StockCode]+amount) > limit {
+		return riskReject(model.RiskRuleMaxPositionPct, "买入后 %s 市值 %s 占总资产 %.1f%%，超过上限 %.1f%%",
+			order.StockCode, model.FormatAmount(values[order.StockCode]+amount), percentOf(values[order.StockCode]+amount), limit), nil
+	}
+	if limit := policy.MaxIndustryPct; limit > 0 {
+		industry := order.Industries[order.StockCode]
+		if industry == "" {
+			return riskReject(model.RiskRuleMaxIndustryPct, "%s 行业未知，无法校验行业仓位", order.StockCode), nil
+		}
+		exposure := amount
+		for code, value := range values {
+			switch order.Industries[code] {
+			case industry:
+				exposure += value
+			case "":
+				return riskReject(model.RiskRuleMaxIndustryPct, "持仓 %s 行业未知，无法校验行业仓位", code), nil
+			}
+		}
+		if percentOf(exposure) > limit {
+			return riskReject(model.RiskRuleMaxIndustryPct, "买入后行业 %s 市值 %s 占总资产 %.1f%%，超过上限 %.1f%%",
+				industry, model.FormatAmount(exposure), percentOf(exposure), limit), nil
+		}
+	}
+	if limit := policy.MinCashReservePct; limit > 0 && percentOf(account.AvailableAmt-cost) < limit {
+		return riskReject(model.RiskRuleMinCashReservePct, "买入后可用现金 %s 占总资产 %.1f%%，低于下限 %.1f%%",
+			model.FormatAmount(account.AvailableAmt-cost), percentOf(account.AvailableAmt-cost), limit), nil
+	}
+	return "", nil
+}
+
+// valuationRule 需要按总资产校验的第一条规则，估值失败时以该规则拒绝；为空表示无需估值
+func valuationRule(policy model.RiskPolicy, orderType model.TransactionType) model.RiskRule {
+	switch {
+	case policy.MaxDailyTurnoverPct > 0:
+		return model.RiskRuleMaxDailyTurnoverPct
+	case orderType != model.TransactionTypeBuy:
+		return ""
+	case policy.MaxPositionPct > 0:
+		return model.RiskRuleMaxPositionPct
+	case policy.MaxIndustryPct > 0:
+		return model.RiskRuleMaxIndustryPct
+	case policy.MinCashReservePct > 0:
+		return model.RiskRuleMinCashReservePct
+	}
+	return ""
+}
+
+// riskValuation 计算风控总资产与各持仓市值（内部函数）
+// 持仓按 order.Prices 估值，本订单股票缺失时用委托价；仍无法估值或总资产不为正时以 rule 返回拒绝原因
+func riskValuation(database *gorm.DB, account *model.Account, order Order, rule model.RiskRule) (int64, map[string]int64, string, error) {
+	positions, err := GetAllPositions(database, account.ID, order.Prices)
+	if err != nil {
+		return 0, nil, "", err
+	}
+
+	nav := account.AvailableAmt + account.LockedAmt
+	values := make(map[string]int64, len(positions))
+	for _, pos := range positions {
+		value := pos.Value
+		if pos.CurrentPrice <= 0 {
+			var price int64
+			if pos.StockCode == order.StockCode {
+				price = order.Price
+			}
+			if price <= 0 {
+				return 0, nil, riskReject(rule, "持仓 %s 缺少估值价格，无法计算总资产", pos.StockCode), nil
+			}
+			value = pos.Quantity * price
+		}
+		values[pos.StockCode] = value
+		nav += value
+	}
+	if nav <= 0 {
+		return 0, nil, riskReject(rule, "总资产 %s 不为正，无法按比例校验", model.FormatAmount(nav)), nil
+	}
+	return nav, values, "", nil
+}
+
+// riskReject 风控拒绝原因，以违反的规则名开头便于识别
+func riskReject(rule model.RiskRule, format string, args ...any) string {
+	return fmt.Sprintf("风控限制 %s：%s", rule, fmt.Sprintf(format, args...))
+}
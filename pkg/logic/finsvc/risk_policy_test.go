@@ -0,0 +1,218 @@
+package finsvc
+
+import (
+	"strings"
+	"testing"
+
+	msadb "msa/pkg/db"
+	"msa/pkg/model"
+)
+
+// setRiskPolicy 设置测试用风控限制，测试结束后恢复
+func setRiskPolicy(t *testing.T, policy model.RiskPolicy) {
+	t.Helper()
+	original := GetRiskPolicy()
+	t.Cleanup(func() { SetRiskPolicy(original) })
+	SetRiskPolicy(policy)
+}
+
+// fakeRiskDataSource 测试用风控估值数据源
+type fakeRiskDataSource struct {
+	prices     PriceMap
+	industries map[string]string
+}
+
+func (f fakeRiskDataSource) Prices(stockCodes []string) (PriceMap, error) {
+	prices := PriceMap{}
+	for _, code := range stockCodes {
+		if price, ok := f.prices[code]; ok {
+			prices[code] = price
+		}
+	}
+	return prices, nil
+}
+
+func (f fakeRiskDataSource) Industry(stockCode string) (string, error) {
+	return f.industries[stockCode], nil
+}
+
+// setRiskDataSource 设置测试用风控估值数据源，测试结束后清除
+func setRiskDataSource(t *testing.T, source RiskDataSource) {
+	t.Helper()
+	t.Cleanup(func() { SetRiskDataSource(nil) })
+	SetRiskDataSource(source)
+}
+
+func TestCheckRiskPolicy(t *testing.T) {
+	db := setupTestDB(t)
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(100000))
+	setRiskPolicy(t, model.RiskPolicy{
+		MaxPositionPct:  20,
+		MaxIndustryPct:  30,
+		MaxOrdersPerDay: 3,
+		Blacklist:       []string{" SH600000 "},
+	})
+	industries := map[string]string{"sh600519": "食品饮料", "sz000858": "食品饮料"}
+
+	buy := func(code string, qty int64, price float64) *model.Transaction {
+		t.Helper()
+		id, err := SubmitBuyOrder(db, accountID, Order{
+			StockCode: code, StockName: code, Quantity: qty, Price: model.YuanToHao(price), Time: testTradeTime,
+			Prices: PriceMap{"sh600519": model.YuanToHao(150)}, Industries: industries,
+		})
+		if err != nil {
+			t.Fatalf("SubmitBuyOrder failed: %v", err)
+		}
+		trans, _ := msadb.GetTransactionByID(db, id)
+		return trans
+	}
+	expectRejected := func(trans *model.Transaction, rule model.RiskRule) {
+		t.Helper()
+		if trans.Status != model.TransactionStatusRejected || !strings.Contains(trans.Note, string(rule)) {
+			t.Errorf("Expected rejection by %s, got %s: %s", rule, trans.Status, trans.Note)
+		}
+	}
+
+	expectRejected(buy("600000", 100, 10), model.RiskRuleBlacklist)
+	// 25000 / 100000 = 25% > 20%
+	expectRejected(buy("sh600519", 100, 250), model.RiskRuleMaxPositionPct)
+
+	accepted := buy("sh600519", 100, 150)
+	if accepted.Status != model.TransactionStatusPending {
+		t.Fatalf("Expected pending order, got %s: %s", accepted.Status, accepted.Note)
+	}
+	if err := FillOrder(db, accepted.ID); err != nil {
+		t.Fatalf("FillOrder failed: %v", err)
+	}
+
+	// 食品饮料 15000 + 16000 = 31% > 30%
+	expectRejected(buy("sz000858", 100, 160), model.RiskRuleMaxIndustryPct)
+	// 行业未知时拒绝，不跳过行业限制
+	expectRejected(buy("sz000001", 1000, 10), model.RiskRuleMaxIndustryPct)
+	// 订单未携带的行业经风控数据源查询
+	setRiskDataSource(t, fakeRiskDataSource{industries: map[string]string{"sz000001": "银行", "sz000002": "房地产"}})
+	if trans := buy("sz000001", 1000, 10); trans.Status != model.TransactionStatusPending {
+		t.Errorf("Expected pending order, got %s: %s", trans.Status, trans.Note)
+	}
+	if trans := buy("sz000002", 100, 10); trans.Status != model.TransactionStatusPending {
+		t.Errorf("Expected pending order, got %s: %s", trans.Status, trans.Note)
+	}
+	expectRejected(buy("sz000002", 100, 10), model.RiskRuleMaxOrdersPerDay)
+
+	usage, err := GetRiskUsage(db, accountID, testTradeTime)
+	if err != nil {
+		t.Fatalf("GetRiskUsage failed: %v", err)
+	}
+	if usage.Orders != 3 || usage.Turnover != model.YuanToHao(26000) {
+		t.Errorf("Unexpected usage: %+v", usage)
+	}
+	if usage, _ := GetRiskUsage(db, accountID, testNextTradeTime); usage.Orders != 0 {
+		t.Errorf("Expected no orders on next day: %+v", usage)
+	}
+}
+
+func TestCheckRiskPolicy_TurnoverAndCashReserve(t *testing.T) {
+	db := setupTestDB(t)
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(100000))
+	setRiskPolicy(t, model.RiskPolicy{MaxDailyTurnoverPct: 20, MinCashReservePct: 85})
+
+	buy := func(qty int64) *model.Transaction {
+		t.Helper()
+		id, err := SubmitBuyOrder(db, accountID, Order{
+			StockCode: "sz000001", StockName: "平安银行", Quantity: qty, Price: model.YuanToHao(10), Time: testTradeTime,
+		})
+		if err != nil {
+			t.Fatalf("SubmitBuyOrder failed: %v", err)
+		}
+		trans, _ := msadb.GetTransactionByID(db, id)
+		return trans
+	}
+
+	// 买入 10000 后可用现金约 89995，占 90% ≥ 85%
+	if trans := buy(1000); trans.Status != model.TransactionStatusPending {
+		t.Fatalf("Expected pending order, got %s: %s", trans.Status, trans.Note)
+	}
+	// 再买 6000 后可用现金约 83990 < 85%
+	if trans := buy(600); !strings.Contains(trans.Note, string(model.RiskRuleMinCashReservePct)) {
+		t.Errorf("Expected cash reserve rejection, got %s: %s", trans.Status, trans.Note)
+	}
+
+	SetRiskPolicy(model.RiskPolicy{MaxDailyTurnoverPct: 20})
+	// 当日委托 10000 + 11000 = 21% > 20%
+	if trans := buy(1100); !strings.Contains(trans.Note, string(model.RiskRuleMaxDailyTurnoverPct)) {
+		t.Errorf("Expected turnover rejection, got %s: %s", trans.Status, trans.Note)
+	}
+}
+
+func TestCheckRiskPolicy_Valuation(t *testing.T) {
+	db := setupTestDB(t)
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(100000))
+	buyID, _ := SubmitBuyOrder(db, accountID, Order{
+		StockCode: "sh600519", StockName: "贵州茅台", Quantity: 100, Price: model.YuanToHao(150), Time: testTradeTime,
+	})
+	FillOrder(db, buyID)
+	setRiskPolicy(t, model.RiskPolicy{MaxPositionPct: 20})
+
+	buy := func() *model.Transaction {
+		t.Helper()
+		id, err := SubmitBuyOrder(db, accountID, Order{
+			StockCode: "sz000001", StockName: "平安银行", Quantity: 100, Price: model.YuanToHao(10), Time: testNextTradeTime,
+		})
+		if err != nil {
+			t.Fatalf("SubmitBuyOrder failed: %v", err)
+		}
+		trans, _ := msadb.GetTransactionByID(db, id)
+		return trans
+	}
+
+	// 持仓缺少估值价格时拒绝，不按持仓均价估值
+	if trans := buy(); trans.Status != model.TransactionStatusRejected || !strings.Contains(trans.Note, string(model.RiskRuleMaxPositionPct)) {
+		t.Errorf("Expected rejection without valuation price, got %s: %s", trans.Status, trans.Note)
+	}
+	setRiskDataSource(t, fakeRiskDataSource{prices: PriceMap{"sh600519": model.YuanToHao(160)}})
+	if trans := buy(); trans.Status != model.TransactionStatusPending {
+		t.Errorf("Expected pending order with resolved price, got %s: %s", trans.Status, trans.Note)
+	}
+}
+
+func TestCheckRiskPolicy_Sell(t *testing.T) {
+	db := setupTestDB(t)
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(100000))
+	buyID, _ := SubmitBuyOrder(db, accountID, Order{
+		StockCode: "sh600000", StockName: "浦发银行", Quantity: 3000, Price: model.YuanToHao(10), Time: testTradeTime,
+	})
+	FillOrder(db, buyID)
+
+	sell := func(qty int64, prices PriceMap) *model.Transaction {
+		t.Helper()
+		id, err := SubmitSellOrder(db, accountID, Order{
+			StockCode: "sh600000", StockName: "浦发银行", Quantity: qty, Price: model.YuanToHao(11), Time: testNextTradeTime,
+			Prices: prices,
+		})
+		if err != nil {
+			t.Fatalf("SubmitSellOrder failed: %v", err)
+		}
+		trans, _ := msadb.GetTransactionByID(db, id)
+		return trans
+	}
+
+	// 黑名单与仓位类限制不限制卖出
+	setRiskPolicy(t, model.RiskPolicy{Blacklist: []string{"sh600000"}, MaxOrdersPerDay: 2, MaxPositionPct: 1, MaxIndustryPct: 1})
+	if trans := sell(1000, nil); trans.Status != model.TransactionStatusPending {
+		t.Fatalf("Expected pending sell order, got %s: %s", trans.Status, trans.Note)
+	}
+	if !IsBlacklisted(GetRiskPolicy(), "600000") || IsBlacklisted(GetRiskPolicy(), "sh600001") {
+		t.Errorf("Unexpected blacklist matching")
+	}
+	// 当日委托笔数同样限制卖出
+	SetRiskPolicy(model.RiskPolicy{MaxOrdersPerDay: 1})
+	if trans := sell(1000, nil); trans.Status != model.TransactionStatusRejected || !strings.Contains(trans.Note, string(model.RiskRuleMaxOrdersPerDay)) {
+		t.Errorf("Expected orders-per-day rejection, got %s: %s", trans.Status, trans.Note)
+	}
+
+	// 总资产约 100000，当日委托 11000 + 11000 = 22% > 20%
+	SetRiskPolicy(model.RiskPolicy{MaxDailyTurnoverPct: 20})
+	if trans := sell(1000, PriceMap{"sh600000": model.YuanToHao(10)}); trans.Status != model.TransactionStatusRejected || !strings.Contains(trans.Note, string(model.RiskRuleMaxDailyTurnoverPct)) {
+		t.Errorf("Expected turnover rejection, got %s: %s", trans.Status, trans.Note)
+	}
+}
@@ -0,0 +1,231 @@
+package finsvc
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"msa/pkg/logic/calendar"
+	"msa/pkg/model"
+)
+
+// Board 股票所属板块
+type Board string
+
+const (
+	// BoardMain 沪深主板
+	BoardMain Board = "MAIN"
+	// BoardChiNext 创业板（300/301）
+	BoardChiNext Board = "CHINEXT"
+	// BoardSTAR 科创板（688/689）
+	BoardSTAR Board = "STAR"
+	// BoardHK 港股（hk 前缀）
+	BoardHK Board = "HK"
+)
+
+// DefaultLotSize A股每手股数
+const DefaultLotSize int64 = 100
+
+// chinaLocation 交易所所在时区（UTC+8），交易时段与 T+1 均按该时区判断
+var chinaLocation = time.FixedZone("CST", 8*3600)
+
+// DetectBoard 根据股票代码判断所属板块
+// 支持 sh600000 / sz300750 / 688981 / hk00700 等写法
+func DetectBoard(stockCode string) Board {
+	code := strings.ToLower(strings.TrimSpace(stockCode))
+	if strings.HasPrefix(code, "hk") {
+		return BoardHK
+	}
+	code = strings.TrimPrefix(strings.TrimPrefix(code, "sh"), "sz")
+
+	switch {
+	case strings.HasPrefix(code, "688"), strings.HasPrefix(code, "689"):
+		return BoardSTAR
+	case strings.HasPrefix(code, "300"), strings.HasPrefix(code, "301"):
+		return BoardChiNext
+	default:
+		return BoardMain
+	}
+}
+
+// IsSTStock 根据股票名称判断是否为 ST / *ST 股票
+func IsSTStock(stockName string) bool {
+	return strings.Contains(strings.ToUpper(stockName), "ST")
+}
+
+// PriceLimitPercent 获取涨跌幅限制百分比，0 表示无涨跌幅限制
+// 主板 ±10%，ST 主板 ±5%，创业板/科创板（含 ST）±20%，港股无限制
+func PriceLimitPercent(stockCode, stockName string) int64 {
+	switch DetectBoard(stockCode) {
+	case BoardHK:
+		return 0
+	case BoardChiNext, BoardSTAR:
+		return 20
+	default:
+		if IsSTStock(stockName) {
+			return 5
+		}
+		return 10
+	}
+}
+
+// PriceToHao 将行情或委托价格（元）换算为毫并按最小报价单位四舍五入
+// A股四舍五入到分，港股到厘；避免浮点截断使价格少 1 毫（如 1.13 → 11299），导致跌停价委托被判超出涨跌幅
+func PriceToHao(stockCode string, yuan float64) int64 {
+	unit := 100.0
+	if DetectBoard(stockCode) == BoardHK {
+		unit = 1000
+	}
+	return int64(math.Round(yuan*unit)) * int64(10000/unit)
+}
+
+// PriceLimitBand 根据昨收价计算跌停价和涨停价（毫）
+// 涨跌停价四舍五入到分
+func PriceLimitBand(prevClose int64, percent int64) (lower int64, upper int64) {
+	roundToFen := func(v int64) int64 {
+		// v 为 毫 × 100，先换算到分再四舍五入，结果转回毫
+		return (v + 5000) / 10000 * 100
+	}
+	return roundToFen(prevClose * (100 - percent)), roundToFen(prevClose * (100 + percent))
+}
+
+// marketOf 板块所属交易所日历
+func marketOf(board Board) calendar.Market {
+	if board == BoardHK {
+		return calendar.MarketHK
+	}
+	return calendar.MarketCN
+}
+
+// IsTradingSession 判断时间是否处于对应板块的交易时段
+// 按交易日历排除周末、休市日，港股半日市只有上午时段
+func IsTradingSession(board Board, t time.Time) bool {
+	return calendar.InSession(marketOf(board), t)
+}
+
+// NoTradingBetween 判断 from 至 to 之间是否没有交易：from 不在交易时段内，且 to 早于 from 之后的下一个交易时段开始
+// 用于判断 from 时刻获取的行情在 to 时刻是否仍然有效
+func NoTradingBetween(board Board, from, to time.Time) bool {
+	return calendar.NoTradingBetween(marketOf(board), from, to)
+}
+
+// tradingDayStart 获取交易日零点（交易所时区）
+func tradingDayStart(t time.Time) time.Time {
+	local := t.In(chinaLocation)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, chinaLocation)
+}
+
+// orderTime 获取订单时间，未指定时使用当前时间
+func (o Order) orderTime() time.Time {
+	if o.Time.IsZero() {
+		return calendar.Now()
+	}
+	return o.Time
+}
+
+// ValidateOrder 按交易规则校验订单
+// 卖出时同时校验可卖数量（持仓 - 挂单卖出 - T+1 冻结）
+// database 传入下单事务，保证校验与创建记录在同一事务内
+// 返回拒绝原因，空字符串表示校验通过；error 仅表示查询失败
+func ValidateOrder(database *gorm.DB, accountID uint, orderType model.TransactionType, order Order) (string, error) {
+	board := DetectBoard(order.StockCode)
+	at := order.orderTime()
+
+	if order.Quantity <= 0 {
+		return "委托数量必须大于 0", nil
+	}
+	if order.Price <= 0 {
+		return "委托价格必须大于 0", nil
+	}
+
+	// 交易时段
+	if !IsTradingSession(board, at) {
+		if board == BoardHK {
+			return "非交易时段：港股交易时间为 9:30-12:00、13:00-16:00", nil
+		}
+		return "非交易时段：A股交易时间为 9:30-11:30、13:00-15:00", nil
+	}
+
+	// 涨跌幅限制（昨收价未知时跳过）
+	if percent := PriceLimitPercent(order.StockCode, order.StockName); percent > 0 && order.PrevClose > 0 {
+		lower, upper := PriceLimitBand(order.PrevClose, percent)
+		if order.Price < lower || order.Price > upper {
+			return fmt.Sprintf("委托价格 %s 超出涨跌幅限制 ±%d%%（%s ~ %s）",
+				model.FormatAmount(order.Price), percent, model.FormatAmount(lower), model.FormatAmount(upper)), nil
+		}
+	}
+
+	// 每手股数：A股固定 100 股，港股按个股每手股数（未知时跳过）
+	lotSize := DefaultLotSize
+	if board == BoardHK {
+		lotSize = order.LotSize
+		if lotSize <= 0 {
+			log.Warnf("港股 %s 每手股数未知，跳过整手校验", order.StockCode)
+		}
+	}
+
+	if orderType == model.TransactionTypeBuy {
+		if lotSize > 0 && order.Quantity%lotSize != 0 {
+			return fmt.Sprintf("买入数量须为 %d 股的整数倍", lotSize), nil
+		}
+		return "", nil
+	}
+
+	// 卖出：持仓须扣除挂单中的卖出数量
+	position, err := GetPosition(database, accountID, order.StockCode)
+	if err != nil {
+		return "", err
+	}
+	if position <= 0 {
+		return "无持仓：请先查询持仓", nil
+	}
+	pendingSell, err := getPendingSellQuantity(database, accountID, order.StockCode)
+	if err != nil {
+		return "", err
+	}
+	if order.Quantity > position-pendingSell {
+		return fmt.Sprintf("持仓不足：当前持仓 %d 股，挂单卖出 %d 股，要卖出 %d 股",
+			position, pendingSell, order.Quantity), nil
+	}
+
+	// 不足一手的零股须一次性卖出
+	if lotSize > 0 && order.Quantity%lotSize != 0 && order.Quantity%lotSize != position%lotSize {
+		return fmt.Sprintf("卖出数量须为 %d 股的整数倍（零股 %d 股须一次性卖出）", lotSize, position%lotSize), nil
+	}
+
+	// T+1：A股当日买入的股票当日不可卖出，港股 T+0
+	sellable, err := GetSellableQuantity(database, accountID, order.StockCode, at)
+	if err != nil {
+		return "", err
+	}
+	if order.Quantity > sellable {
+		return fmt.Sprintf("T+1 限制：今日买入的股票不可卖出，当前可卖 %d 股", sellable), nil
+	}
+
+	return "", nil
+}
+
+// getBoughtQuantitySince 统计 since 之后提交并已成交的买入数量
+// 时间比较在内存中按时区换算进行，避免数据库中不同时区的时间字符串比较出错
+func getBoughtQuantitySince(database *gorm.DB, accountID uint, stockCode string, since time.Time) (int64, error) {
+	var buys []*model.Transaction
+	err := database.Where("account_id = ? AND stock_code = ? AND type = ? AND status = ?",
+		accountID, stockCode, model.TransactionTypeBuy, model.TransactionStatusFilled).
+		Find(&buys).Error
+	if err != nil {
+		log.Errorf("查询买入记录失败: %v", err)
+		return 0, fmt.Errorf("failed to query buy transactions: %w", err)
+	}
+
+	var qty int64
+	for _, buy := range buys {
+		if !buy.CreatedAt.Before(since) {
+			qty += buy.Quantity
+		}
+	}
+	return qty, nil
+}
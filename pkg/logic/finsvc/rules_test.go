@@ -0,0 +1,319 @@
+package finsvc
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	msadb "msa/pkg/db"
+	"msa/pkg/model"
+)
+
+func TestDetectBoard(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected Board
+	}{
+		{"sh600519", BoardMain},
+		{"000001", BoardMain},
+		{"sz300750", BoardChiNext},
+		{"301236", BoardChiNext},
+		{"sh688981", BoardSTAR},
+		{"hk00700", BoardHK},
+		{"HK09988", BoardHK},
+	}
+
+	for _, tt := range tests {
+		if got := DetectBoard(tt.code); got != tt.expected {
+			t.Errorf("DetectBoard(%s) = %s, want %s", tt.code, got, tt.expected)
+		}
+	}
+}
+
+func TestPriceLimitPercent(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		stock    string
+		expected int64
+	}{
+		{"主板", "sh600519", "贵州茅台", 10},
+		{"主板ST", "sz000004", "*ST国华", 5},
+		{"创业板", "sz300750", "宁德时代", 20},
+		{"创业板ST", "sz300023", "ST宝德", 20},
+		{"科创板", "sh688981", "中芯国际", 20},
+		{"港股", "hk00700", "腾讯控股", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PriceLimitPercent(tt.code, tt.stock); got != tt.expected {
+				t.Errorf("PriceLimitPercent(%s, %s) = %d, want %d", tt.code, tt.stock, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPriceLimitBand(t *testing.T) {
+	// 昨收 10.05，±10% 为 9.05 ~ 11.06（四舍五入到分）
+	lower, upper := PriceLimitBand(model.YuanToHao(10.05), 10)
+	if lower != model.YuanToHao(9.05) || upper != model.YuanToHao(11.06) {
+		t.Errorf("Expected 9.05 ~ 11.06, got %s ~ %s", model.FormatAmount(lower), model.FormatAmount(upper))
+	}
+}
+
+func TestPriceToHao(t *testing.T) {
+	// 浮点截断会把 1.13 变为 11299 毫
+	if got := PriceToHao("sh600000", 1.13); got != 11300 {
+		t.Errorf("PriceToHao(1.13) = %d, want 11300", got)
+	}
+	if got := PriceToHao("hk00700", 0.455); got != 4550 {
+		t.Errorf("PriceToHao(hk 0.455) = %d, want 4550", got)
+	}
+}
+
+func TestSubmitBuyOrder_AtLimitDown(t *testing.T) {
+	db := setupTestDB(t)
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(100000))
+
+	// 昨收 1.26，跌停价 1.13
+	txID, _ := SubmitBuyOrder(db, accountID, Order{
+		StockCode: "sh600000", StockName: "浦发银行", Quantity: 100,
+		Price: PriceToHao("sh600000", 1.13), PrevClose: PriceToHao("sh600000", 1.26), Time: testTradeTime,
+	})
+	trans, _ := msadb.GetTransactionByID(db, txID)
+	if trans.Status != model.TransactionStatusPending {
+		t.Errorf("Expected order at limit-down price to be pending, got %s (%s)", trans.Status, trans.Note)
+	}
+}
+
+func TestIsTradingSession(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2025, 3, day, hour, minute, 0, 0, chinaLocation)
+	}
+
+	tests := []struct {
+		name     string
+		board    Board
+		t        time.Time
+		expected bool
+	}{
+		{"A股早盘开盘", BoardMain, at(3, 9, 30), true},
+		{"A股集合竞价", BoardMain, at(3, 9, 20), false},
+		{"A股午休", BoardMain, at(3, 12, 0), false},
+		{"A股收盘", BoardMain, at(3, 15, 0), true},
+		{"A股收盘后", BoardMain, at(3, 15, 1), false},
+		{"A股周六", BoardMain, at(8, 10, 0), false},
+		{"A股节假日", BoardMain, time.Date(2025, 10, 8, 10, 0, 0, 0, chinaLocation), false},
+		{"港股半日市下午", BoardHK, time.Date(2025, 12, 24, 13, 30, 0, 0, chinaLocation), false},
+		{"港股午盘前", BoardHK, at(3, 11, 45), true},
+		{"港股下午", BoardHK, at(3, 15, 30), true},
+		{"港股收盘后", BoardHK, at(3, 16, 1), false},
+		{"UTC时间换算", BoardMain, time.Date(2025, 3, 3, 2, 0, 0, 0, time.UTC), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTradingSession(tt.board, tt.t); got != tt.expected {
+				t.Errorf("IsTradingSession(%s, %v) = %v, want %v", tt.board, tt.t, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNoTradingBetween(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2025, 3, day, hour, minute, 0, 0, chinaLocation)
+	}
+
+	tests := []struct {
+		name     string
+		board    Board
+		from, to time.Time
+		expected bool
+	}{
+		{"收盘后到当晚", BoardMain, at(3, 15, 30), at(3, 22, 0), true},
+		{"收盘后到次日开盘", BoardMain, at(3, 15, 30), at(4, 9, 30), false},
+		{"周五收盘后到周一盘前", BoardMain, at(7, 16, 0), at(10, 9, 0), true},
+		{"盘前到开盘前", BoardMain, at(3, 8, 0), at(3, 9, 29), true},
+		{"午休到下午开盘", BoardMain, at(3, 12, 0), at(3, 13, 0), false},
+		{"盘中获取", BoardMain, at(3, 10, 0), at(3, 10, 1), false},
+		{"港股A股收盘后仍在交易", BoardHK, at(3, 15, 30), at(3, 15, 31), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NoTradingBetween(tt.board, tt.from, tt.to); got != tt.expected {
+				t.Errorf("NoTradingBetween(%s, %v, %v) = %v, want %v", tt.board, tt.from, tt.to, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSubmitBuyOrder_RuleRejections(t *testing.T) {
+	tests := []struct {
+		name   string
+		order  Order
+		reason string
+	}{
+		{
+			name:   "非整手买入",
+			order:  Order{StockCode: "sh600519", StockName: "贵州茅台", Quantity: 150, Price: model.YuanToHao(10), Time: testTradeTime},
+			reason: "整数倍",
+		},
+		{
+			name:   "非交易时段",
+			order:  Order{StockCode: "sh600519", StockName: "贵州茅台", Quantity: 100, Price: model.YuanToHao(10), Time: testTradeTime.Add(6 * time.Hour)},
+			reason: "非交易时段",
+		},
+		{
+			name:   "超出主板涨停价",
+			order:  Order{StockCode: "sh600519", StockName: "贵州茅台", Quantity: 100, Price: model.YuanToHao(11.01), PrevClose: model.YuanToHao(10), Time: testTradeTime},
+			reason: "涨跌幅限制",
+		},
+		{
+			name:   "超出ST跌停价",
+			order:  Order{StockCode: "sz000004", StockName: "*ST国华", Quantity: 100, Price: model.YuanToHao(9.49), PrevClose: model.YuanToHao(10), Time: testTradeTime},
+			reason: "±5%",
+		},
+		{
+			name:   "港股非整手",
+			order:  Order{StockCode: "hk00700", StockName: "腾讯控股", Quantity: 100, Price: model.YuanToHao(400), LotSize: 200, Time: testTradeTime},
+			reason: "200 股的整数倍",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupTestDB(t)
+			accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(100000))
+
+			txID, err := SubmitBuyOrder(db, accountID, tt.order)
+			if err != nil {
+				t.Fatalf("SubmitBuyOrder failed: %v", err)
+			}
+
+			trans, _ := msadb.GetTransactionByID(db, txID)
+			if trans.Status != model.TransactionStatusRejected {
+				t.Fatalf("Expected status %s, got %s", model.TransactionStatusRejected, trans.Status)
+			}
+			if !strings.Contains(trans.Note, tt.reason) {
+				t.Errorf("Expected note containing %q, got %q", tt.reason, trans.Note)
+			}
+
+			// 被拒绝的订单不锁定资金
+			account, _ := msadb.GetAccountByID(db, accountID)
+			if account.LockedAmt != 0 || account.AvailableAmt != model.YuanToHao(100000) {
+				t.Errorf("Expected funds untouched, got available=%d locked=%d", account.AvailableAmt, account.LockedAmt)
+			}
+		})
+	}
+}
+
+func TestSubmitBuyOrder_WithinRules(t *testing.T) {
+	db := setupTestDB(t)
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(100000))
+
+	// 创业板 +20% 以内
+	txID, _ := SubmitBuyOrder(db, accountID, Order{
+		StockCode: "sz300750", StockName: "宁德时代", Quantity: 100, Price: model.YuanToHao(11.9), PrevClose: model.YuanToHao(10), Time: testTradeTime,
+	})
+	trans, _ := msadb.GetTransactionByID(db, txID)
+	if trans.Status != model.TransactionStatusPending {
+		t.Errorf("Expected status %s, got %s (%s)", model.TransactionStatusPending, trans.Status, trans.Note)
+	}
+
+	// 港股整手，无涨跌幅限制
+	txID, _ = SubmitBuyOrder(db, accountID, Order{
+		StockCode: "hk00700", StockName: "腾讯控股", Quantity: 200, Price: model.YuanToHao(50), PrevClose: model.YuanToHao(30), LotSize: 100, Time: testTradeTime,
+	})
+	trans, _ = msadb.GetTransactionByID(db, txID)
+	if trans.Status != model.TransactionStatusPending {
+		t.Errorf("Expected status %s, got %s (%s)", model.TransactionStatusPending, trans.Status, trans.Note)
+	}
+}
+
+func TestSubmitSellOrder_TPlusOne(t *testing.T) {
+	db := setupTestDB(t)
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(100000))
+
+	buyID, _ := SubmitBuyOrder(db, accountID, Order{
+		StockCode: "sh600519", StockName: "贵州茅台", Quantity: 100, Price: model.YuanToHao(10), Time: testTradeTime,
+	})
+	FillOrder(db, buyID)
+
+	// 当日卖出被拒绝
+	sellID, _ := SubmitSellOrder(db, accountID, Order{
+		StockCode: "sh600519", StockName: "贵州茅台", Quantity: 100, Price: model.YuanToHao(10), Time: testTradeTime.Add(time.Hour),
+	})
+	trans, _ := msadb.GetTransactionByID(db, sellID)
+	if trans.Status != model.TransactionStatusRejected || !strings.Contains(trans.Note, "T+1") {
+		t.Errorf("Expected T+1 rejection, got %s (%s)", trans.Status, trans.Note)
+	}
+
+	// 次日可以卖出
+	sellID, _ = SubmitSellOrder(db, accountID, Order{
+		StockCode: "sh600519", StockName: "贵州茅台", Quantity: 100, Price: model.YuanToHao(10), Time: testNextTradeTime,
+	})
+	trans, _ = msadb.GetTransactionByID(db, sellID)
+	if trans.Status != model.TransactionStatusPending {
+		t.Errorf("Expected status %s, got %s (%s)", model.TransactionStatusPending, trans.Status, trans.Note)
+	}
+}
+
+func TestSubmitSellOrder_HKNoTPlusOne(t *testing.T) {
+	db := setupTestDB(t)
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(100000))
+
+	buyID, _ := SubmitBuyOrder(db, accountID, Order{
+		StockCode: "hk00700", StockName: "腾讯控股", Quantity: 100, Price: model.YuanToHao(400), LotSize: 100, Time: testTradeTime,
+	})
+	FillOrder(db, buyID)
+
+	// 港股 T+0，当日可卖
+	sellID, _ := SubmitSellOrder(db, accountID, Order{
+		StockCode: "hk00700", StockName: "腾讯控股", Quantity: 100, Price: model.YuanToHao(401), LotSize: 100, Time: testTradeTime.Add(time.Hour),
+	})
+	trans, _ := msadb.GetTransactionByID(db, sellID)
+	if trans.Status != model.TransactionStatusPending {
+		t.Errorf("Expected status %s, got %s (%s)", model.TransactionStatusPending, trans.Status, trans.Note)
+	}
+}
+
+func TestSubmitSellOrder_OddLot(t *testing.T) {
+	db := setupTestDB(t)
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(100000))
+
+	// 通过部分成交构造 150 股持仓（含 50 股零股）
+	buyID, _ := SubmitBuyOrder(db, accountID, Order{
+		StockCode: "sh600519", StockName: "贵州茅台", Quantity: 200, Price: model.YuanToHao(10), Time: testTradeTime,
+	})
+	_, remainderID, _ := PartialFillOrder(db, buyID, 150)
+	CancelOrder(db, remainderID, "")
+
+	tests := []struct {
+		name     string
+		quantity int64
+		accepted bool
+	}{
+		{"整手卖出", 100, true},
+		{"零股一次性卖出", 50, true},
+		{"整手加零股", 150, true},
+		{"零股拆分卖出", 30, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sellID, _ := SubmitSellOrder(db, accountID, Order{
+				StockCode: "sh600519", StockName: "贵州茅台", Quantity: tt.quantity, Price: model.YuanToHao(10), Time: testNextTradeTime,
+			})
+			trans, _ := msadb.GetTransactionByID(db, sellID)
+			if accepted := trans.Status == model.TransactionStatusPending; accepted != tt.accepted {
+				t.Errorf("Sell %d accepted = %v, want %v (%s)", tt.quantity, accepted, tt.accepted, trans.Note)
+			}
+			if trans.Status == model.TransactionStatusPending {
+				CancelOrder(db, sellID, "")
+			}
+		})
+	}
+}
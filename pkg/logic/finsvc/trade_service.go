@@ -2,6 +2,7 @@ package finsvc
 
 import (
 	"fmt"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"gorm.io/gorm"
@@ -16,16 +17,25 @@ type Order struct {
 	StockName string
 	Quantity  int64
 	Price     int64 // 价格（毫）
-	Fee       int64 // 手续费（毫）
 	Note      string
+	Time      time.Time // 下单时间，为空时使用当前时间；用于交易时段与 T+1 校验
+	PrevClose int64     // 昨收价（毫），用于涨跌幅校验，为 0 时跳过
+	LotSize   int64     // 每手股数，港股必填，A股固定 100 股
+	// Protective 条件单触发的保护性卖出，不受风控限制（仍计入当日委托笔数与成交额）
+	Protective bool
+	// 以下字段仅用于风控限制（CheckRiskPolicy），可为空，缺失部分在开启事务前经风控数据源补充
+	Prices     PriceMap          // 持仓股票估值价格（毫）
+	Industries map[string]string // 股票代码 → 申万一级行业（含本订单股票）
 }
 
 // SubmitBuyOrder 提交买入订单
-// 在事务中执行：检查余额 → 创建交易记录 → 锁定金额
-// 余额不足时创建 REJECTED 记录
+// 事务前补充风控所需的持仓价格与行业，在事务中执行：交易规则校验 → 风控限制校验 → 检查余额 → 创建交易记录 → 锁定金额
+// 不符合交易规则、违反风控限制或余额不足时创建 REJECTED 记录
 func SubmitBuyOrder(database *gorm.DB, accountID uint, order Order) (uint, error) {
-	log.Infof("提交买入订单: 账户=%d, 股票=%s(%s), 数量=%d, 价格=%d, 手续费=%d",
-		accountID, order.StockName, order.StockCode, order.Quantity, order.Price, order.Fee)
+	log.Infof("提交买入订单: 账户=%d, 股票=%s(%s), 数量=%d, 价格=%d",
+		accountID, order.StockName, order.StockCode, order.Quantity, order.Price)
+
+	fillRiskContext(database, accountID, model.TransactionTypeBuy, &order)
 
 	// 开始事务
 	tx := database.Begin()
@@ -44,37 +54,36 @@ func SubmitBuyOrder(database *gorm.DB, accountID uint, order Order) (uint, error
 		return 0, fmt.Errorf("failed to get account: %w", err)
 	}
 
-	// 计算总金额
-	totalAmount := order.Quantity*order.Price + order.Fee
+	// 交易规则校验
+	reason, err := ValidateOrder(tx, accountID, model.TransactionTypeBuy, order)
+	if err != nil {
+		tx.Rollback()
+		log.Errorf("交易规则校验失败: %v", err)
+		return 0, fmt.Errorf("failed to validate order: %w", err)
+	}
+	if reason != "" {
+		return rejectOrder(tx, accountID, model.TransactionTypeBuy, order, reason)
+	}
+
+	// 风控限制校验
+	reason, err = CheckRiskPolicy(tx, account, model.TransactionTypeBuy, order)
+	if err != nil {
+		tx.Rollback()
+		log.Errorf("风控限制校验失败: %v", err)
+		return 0, fmt.Errorf("failed to check risk policy: %w", err)
+	}
+	if reason != "" {
+		return rejectOrder(tx, accountID, model.TransactionTypeBuy, order, reason)
+	}
+
+	// 按费率计算手续费与总金额
+	fees := CalculateFees(model.TransactionTypeBuy, order.StockCode, order.Quantity*order.Price)
+	totalAmount := order.Quantity*order.Price + fees.Total()
 
 	// 检查余额
 	if account.AvailableAmt < totalAmount {
 		log.Warnf("余额不足: 可用=%d, 需要=%d", account.AvailableAmt, totalAmount)
-		// 余额不足，创建 REJECTED 记录
-		trans := &model.Transaction{
-			AccountID: accountID,
-			StockCode: order.StockCode,
-			StockName: order.StockName,
-			Type:      model.TransactionTypeBuy,
-			Quantity:  order.Quantity,
-			Price:     order.Price,
-			Amount:    order.Quantity * order.Price,
-			Fee:       order.Fee,
-			Status:    model.TransactionStatusRejected,
-			Note:      "余额不足",
-		}
-		transID, err := db.CreateTransaction(tx, trans)
-		if err != nil {
-			tx.Rollback()
-			log.Errorf("创建拒绝记录失败: %v", err)
-			return 0, fmt.Errorf("failed to create rejected transaction: %w", err)
-		}
-		if err := tx.Commit().Error; err != nil {
-			log.Errorf("提交事务失败: %v", err)
-			return 0, fmt.Errorf("failed to commit transaction: %w", err)
-		}
-		log.Infof("买入订单已拒绝: 交易ID=%d", transID)
-		return transID, nil
+		return rejectOrder(tx, accountID, model.TransactionTypeBuy, order, "余额不足")
 	}
 
 	// 创建交易记录
@@ -86,10 +95,11 @@ func SubmitBuyOrder(database *gorm.DB, accountID uint, order Order) (uint, error
 		Quantity:  order.Quantity,
 		Price:     order.Price,
 		Amount:    order.Quantity * order.Price,
-		Fee:       order.Fee,
 		Status:    model.TransactionStatusPending,
 		Note:      order.Note,
 	}
+	trans.SetFeeBreakdown(fees)
+	trans.CreatedAt = order.orderTime()
 
 	transID, err := db.CreateTransaction(tx, trans)
 	if err != nil {
@@ -117,10 +127,13 @@ func SubmitBuyOrder(database *gorm.DB, accountID uint, order Order) (uint, error
 }
 
 // SubmitSellOrder 提交卖出订单
-// 验证持仓充足，不锁定金额
+// 事务前补充风控所需的持仓价格，在事务中执行：交易规则校验（可卖数量、整手、T+1、涨跌幅、交易时段）→ 风控限制校验 → 创建交易记录，不锁定金额
+// 持仓不足、不符合交易规则或违反风控限制时创建 REJECTED 记录
 func SubmitSellOrder(database *gorm.DB, accountID uint, order Order) (uint, error) {
-	log.Infof("提交卖出订单: 账户=%d, 股票=%s(%s), 数量=%d, 价格=%d, 手续费=%d",
-		accountID, order.StockName, order.StockCode, order.Quantity, order.Price, order.Fee)
+	log.Infof("提交卖出订单: 账户=%d, 股票=%s(%s), 数量=%d, 价格=%d",
+		accountID, order.StockName, order.StockCode, order.Quantity, order.Price)
+
+	fillRiskContext(database, accountID, model.TransactionTypeSell, &order)
 
 	tx := database.Begin()
 	defer func() {
@@ -130,6 +143,34 @@ func SubmitSellOrder(database *gorm.DB, accountID uint, order Order) (uint, erro
 		}
 	}()
 
+	// 交易规则校验
+	reason, err := ValidateOrder(tx, accountID, model.TransactionTypeSell, order)
+	if err != nil {
+		tx.Rollback()
+		log.Errorf("交易规则校验失败: %v", err)
+		return 0, fmt.Errorf("failed to validate order: %w", err)
+	}
+	if reason != "" {
+		return rejectOrder(tx, accountID, model.TransactionTypeSell, order, reason)
+	}
+
+	// 风控限制校验（当日委托笔数与委托金额）
+	account, err := db.GetAccountByID(tx, accountID)
+	if err != nil {
+		tx.Rollback()
+		log.Errorf("查询账户失败: %v", err)
+		return 0, fmt.Errorf("failed to get account: %w", err)
+	}
+	reason, err = CheckRiskPolicy(tx, account, model.TransactionTypeSell, order)
+	if err != nil {
+		tx.Rollback()
+		log.Errorf("风控限制校验失败: %v", err)
+		return 0, fmt.Errorf("failed to check risk policy: %w", err)
+	}
+	if reason != "" {
+		return rejectOrder(tx, accountID, model.TransactionTypeSell, order, reason)
+	}
+
 	// 创建交易记录
 	trans := &model.Transaction{
 		AccountID: accountID,
@@ -139,10 +180,11 @@ func SubmitSellOrder(database *gorm.DB, accountID uint, order Order) (uint, erro
 		Quantity:  order.Quantity,
 		Price:     order.Price,
 		Amount:    order.Quantity * order.Price,
-		Fee:       order.Fee,
 		Status:    model.TransactionStatusPending,
 		Note:      order.Note,
 	}
+	trans.SetFeeBreakdown(CalculateFees(trans.Type, order.StockCode, trans.Amount))
+	trans.CreatedAt = order.orderTime()
 
 	transID, err := db.CreateTransaction(tx, trans)
 	if err != nil {
@@ -160,6 +202,39 @@ func SubmitSellOrder(database *gorm.DB, accountID uint, order Order) (uint, erro
 	return transID, nil
 }
 
+// rejectOrder 创建 REJECTED 交易记录并提交事务
+// 拒绝原因记录在备注中，返回拒绝记录的交易ID
+func rejectOrder(tx *gorm.DB, accountID uint, orderType model.TransactionType, order Order, reason string) (uint, error) {
+	log.Warnf("订单被拒绝: 股票=%s, 类型=%s, 原因=%s", order.StockCode, orderType, reason)
+
+	trans := &model.Transaction{
+		AccountID: accountID,
+		StockCode: order.StockCode,
+		StockName: order.StockName,
+		Type:      orderType,
+		Quantity:  order.Quantity,
+		Price:     order.Price,
+		Amount:    order.Quantity * order.Price,
+		Status:    model.TransactionStatusRejected,
+		Note:      reason,
+	}
+	trans.SetFeeBreakdown(CalculateFees(orderType, order.StockCode, trans.Amount))
+	trans.CreatedAt = order.orderTime()
+
+	transID, err := db.CreateTransaction(tx, trans)
+	if err != nil {
+		tx.Rollback()
+		log.Errorf("创建拒绝记录失败: %v", err)
+		return 0, fmt.Errorf("failed to create rejected transaction: %w", err)
+	}
+	if err := tx.Commit().Error; err != nil {
+		log.Errorf("提交事务失败: %v", err)
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	log.Infof("订单已拒绝: 交易ID=%d", transID)
+	return transID, nil
+}
+
 // FillOrder 订单成交处理
 // 买入：减少 locked_amt
 // 卖出：增加 available_amt
@@ -175,15 +250,21 @@ func FillOrder(database *gorm.DB, transID uint) error {
 	}()
 
 	// 查询交易记录
-	trans, err := db.GetTransactionByID(database, transID)
+	trans, err := db.GetTransactionByID(tx, transID)
 	if err != nil {
 		tx.Rollback()
 		log.Errorf("查询交易记录失败: %v", err)
 		return fmt.Errorf("failed to get transaction: %w", err)
 	}
 
-	// 更新交易状态
-	if err := db.UpdateTransactionStatus(tx, transID, model.TransactionStatusFilled); err != nil {
+	// 只有挂单中的订单可以成交，避免重复成交
+	if trans.Status != model.TransactionStatusPending {
+		tx.Rollback()
+		return fmt.Errorf("transaction %d is not pending: %s", transID, trans.Status)
+	}
+
+	// 更新交易状态，条件更新防止并发撮合重复成交
+	if err := db.UpdatePendingTransaction(tx, transID, map[string]interface{}{"status": model.TransactionStatusFilled}); err != nil {
 		tx.Rollback()
 		log.Errorf("更新交易状态失败: %v", err)
 		return fmt.Errorf("failed to update transaction status: %w", err)
@@ -217,3 +298,125 @@ func FillOrder(database *gorm.DB, transID uint) error {
 	log.Infof("订单成交完成: 交易ID=%d", transID)
 	return nil
 }
+
+// PartialFillOrder 订单部分成交处理
+// 原记录标记为 OBSOLETE，拆分为 FILLED 子记录（fillQty 股）和 PENDING 剩余子记录
+// 手续费明细按数量比例拆分；买入：按成交部分释放锁定金额，剩余部分继续锁定；卖出：按成交部分增加可用金额
+// fillQty 等于订单数量时按全部成交处理，此时 remainderID 为 0
+func PartialFillOrder(database *gorm.DB, transID uint, fillQty int64) (filledID uint, remainderID uint, err error) {
+	log.Infof("处理订单部分成交: 交易ID=%d, 成交数量=%d", transID, fillQty)
+
+	tx := database.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			log.Errorf("订单部分成交 panic: %v", r)
+		}
+	}()
+
+	trans, err := db.GetTransactionByID(tx, transID)
+	if err != nil {
+		tx.Rollback()
+		log.Errorf("查询交易记录失败: %v", err)
+		return 0, 0, fmt.Errorf("failed to get transaction: %w", err)
+	}
+
+	if trans.Status != model.TransactionStatusPending {
+		tx.Rollback()
+		return 0, 0, fmt.Errorf("transaction %d is not pending: %s", transID, trans.Status)
+	}
+	if fillQty <= 0 || fillQty > trans.Quantity {
+		tx.Rollback()
+		return 0, 0, fmt.Errorf("invalid fill quantity %d for transaction %d (quantity %d)", fillQty, transID, trans.Quantity)
+	}
+	if fillQty == trans.Quantity {
+		tx.Rollback()
+		if err := FillOrder(database, transID); err != nil {
+			return 0, 0, err
+		}
+		return transID, 0, nil
+	}
+
+	// 原记录作废，条件更新防止并发撮合重复拆分
+	if err := db.UpdatePendingTransaction(tx, transID, map[string]interface{}{"status": model.TransactionStatusObsolete}); err != nil {
+		tx.Rollback()
+		log.Errorf("更新交易状态失败: %v", err)
+		return 0, 0, fmt.Errorf("failed to update transaction status: %w", err)
+	}
+
+	// 手续费明细按数量比例拆分，余数归入剩余部分
+	fillFees, remainFees := splitFees(trans.GetFeeBreakdown(), fillQty, trans.Quantity)
+	remainQty := trans.Quantity - fillQty
+
+	filled := &model.Transaction{
+		AccountID: trans.AccountID,
+		StockCode: trans.StockCode,
+		StockName: trans.StockName,
+		Type:      trans.Type,
+		Quantity:  fillQty,
+		Price:     trans.Price,
+		Amount:    fillQty * trans.Price,
+		Status:    model.TransactionStatusFilled,
+		Note:      trans.Note,
+	}
+	filled.SetFeeBreakdown(fillFees)
+	filled.CreatedAt = trans.CreatedAt
+	filledID, err = db.CreateTransaction(tx, filled)
+	if err != nil {
+		tx.Rollback()
+		log.Errorf("创建成交子记录失败: %v", err)
+		return 0, 0, fmt.Errorf("failed to create filled transaction: %w", err)
+	}
+
+	// 子记录沿用原订单提交时间，保证当日有效、T+1 判断与撮合顺序不变
+	remainder := &model.Transaction{
+		AccountID: trans.AccountID,
+		StockCode: trans.StockCode,
+		StockName: trans.StockName,
+		Type:      trans.Type,
+		Quantity:  remainQty,
+		Price:     trans.Price,
+		Amount:    remainQty * trans.Price,
+		Status:    model.TransactionStatusPending,
+		Note:      trans.Note,
+	}
+	remainder.SetFeeBreakdown(remainFees)
+	remainder.CreatedAt = trans.CreatedAt
+	remainderID, err = db.CreateTransaction(tx, remainder)
+	if err != nil {
+		tx.Rollback()
+		log.Errorf("创建剩余子记录失败: %v", err)
+		return 0, 0, fmt.Errorf("failed to create remainder transaction: %w", err)
+	}
+
+	if err := db.UpdateTransactionsWithParent(tx, transID, []uint{filledID, remainderID}); err != nil {
+		tx.Rollback()
+		log.Errorf("关联子记录失败: %v", err)
+		return 0, 0, err
+	}
+
+	// 处理金额
+	if trans.Type == model.TransactionTypeBuy {
+		// 买入部分成交：释放成交部分的锁定金额，剩余部分继续锁定
+		err = db.UpdateAccountAmounts(tx, trans.AccountID, 0, -filled.GetTotalAmount())
+		log.Infof("买入部分成交: 释放锁定金额=%d", filled.GetTotalAmount())
+	} else {
+		// 卖出部分成交：增加成交部分的可用金额
+		sellAmount := filled.Amount - filled.Fee
+		err = db.UpdateAccountAmounts(tx, trans.AccountID, sellAmount, 0)
+		log.Infof("卖出部分成交: 增加可用金额=%d", sellAmount)
+	}
+	if err != nil {
+		tx.Rollback()
+		log.Errorf("更新账户金额失败: %v", err)
+		return 0, 0, fmt.Errorf("failed to update account amounts: %w", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Errorf("提交事务失败: %v", err)
+		return 0, 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Infof("订单部分成交完成: 原交易ID=%d, 成交ID=%d, 剩余ID=%d", transID, filledID, remainderID)
+	return filledID, remainderID, nil
+}
@@ -3,7 +3,9 @@ package finsvc
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/glebarez/sqlite"
 	"gorm.io/gorm"
@@ -12,6 +14,13 @@ import (
 	"msa/pkg/model"
 )
 
+var (
+	// testTradeTime 测试用下单时间（周一交易时段内）
+	testTradeTime = time.Date(2025, 3, 3, 10, 0, 0, 0, chinaLocation)
+	// testNextTradeTime 测试用下一交易日时间，用于满足 T+1 卖出
+	testNextTradeTime = testTradeTime.AddDate(0, 0, 1)
+)
+
 // setupTestDB 创建测试用的临时数据库
 func setupTestDB(t *testing.T) *gorm.DB {
 	t.Helper()
@@ -24,7 +33,8 @@ func setupTestDB(t *testing.T) *gorm.DB {
 		t.Fatalf("Failed to create test database: %v", err)
 	}
 
-	if err := db.AutoMigrate(&model.Account{}, &model.Transaction{}); err != nil {
+	if err := db.AutoMigrate(&model.Account{}, &model.Transaction{}, &model.CashFlow{}, &model.CorporateAction{},
+		&model.PortfolioSnapshot{}, &model.PortfolioSnapshotHolding{}, &model.ConditionalOrder{}); err != nil {
 		t.Fatalf("Failed to migrate test database: %v", err)
 	}
 
@@ -48,7 +58,7 @@ func TestSubmitBuyOrder_Success(t *testing.T) {
 		StockName: "贵州茅台",
 		Quantity:  100,
 		Price:     model.YuanToHao(10.50),
-		Fee:       model.YuanToHao(0.05),
+		Time:      testTradeTime,
 	}
 
 	txID, err := SubmitBuyOrder(db, account.ID, order)
@@ -64,12 +74,13 @@ func TestSubmitBuyOrder_Success(t *testing.T) {
 
 	// 验证账户余额锁定
 	account, _ = msadb.GetAccountByID(db, accountID)
-	expectedAvailable := model.YuanToHao(10000) - model.YuanToHao(10.50)*100 - model.YuanToHao(0.05)
+	// 手续费 = 佣金 5（最低收费）+ 沪市过户费 0.01 = 5.01
+	expectedAvailable := model.YuanToHao(10000) - model.YuanToHao(10.50)*100 - model.YuanToHao(5.01)
 	if account.AvailableAmt != expectedAvailable {
 		t.Errorf("Expected available %d, got %d", expectedAvailable, account.AvailableAmt)
 	}
-	if account.LockedAmt != model.YuanToHao(10.50)*100+model.YuanToHao(0.05) {
-		t.Errorf("Expected locked %d, got %d", model.YuanToHao(10.50)*100+model.YuanToHao(0.05), account.LockedAmt)
+	if account.LockedAmt != model.YuanToHao(10.50)*100+model.YuanToHao(5.01) {
+		t.Errorf("Expected locked %d, got %d", model.YuanToHao(10.50)*100+model.YuanToHao(5.01), account.LockedAmt)
 	}
 }
 
@@ -84,7 +95,7 @@ func TestSubmitBuyOrder_InsufficientBalance(t *testing.T) {
 		StockName: "贵州茅台",
 		Quantity:  1000,
 		Price:     model.YuanToHao(10),
-		Fee:       model.YuanToHao(0.05),
+		Time:      testTradeTime,
 	}
 
 	txID, err := SubmitBuyOrder(db, account.ID, order)
@@ -115,9 +126,9 @@ func TestSubmitSellOrder_Success(t *testing.T) {
 	buyOrder := Order{
 		StockCode: "600519",
 		StockName: "贵州茅台",
-		Quantity:  100,
+		Quantity:  200,
 		Price:     model.YuanToHao(10),
-		Fee:       model.YuanToHao(0.05),
+		Time:      testTradeTime,
 	}
 	txID, _ := SubmitBuyOrder(db, account.ID, buyOrder)
 	FillOrder(db, txID)
@@ -126,9 +137,9 @@ func TestSubmitSellOrder_Success(t *testing.T) {
 	sellOrder := Order{
 		StockCode: "600519",
 		StockName: "贵州茅台",
-		Quantity:  50,
+		Quantity:  100,
 		Price:     model.YuanToHao(11),
-		Fee:       model.YuanToHao(0.05),
+		Time:      testNextTradeTime,
 	}
 
 	sellTxID, err := SubmitSellOrder(db, account.ID, sellOrder)
@@ -142,6 +153,53 @@ func TestSubmitSellOrder_Success(t *testing.T) {
 	}
 }
 
+func TestSubmitSellOrder_InsufficientPosition(t *testing.T) {
+	db := setupTestDB(t)
+
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(10000))
+
+	// 无持仓时卖出被拒绝，可用余额不变
+	sellTxID, err := SubmitSellOrder(db, accountID, Order{
+		StockCode: "600519", StockName: "贵州茅台", Quantity: 100, Price: model.YuanToHao(11), Time: testNextTradeTime,
+	})
+	if err != nil {
+		t.Fatalf("SubmitSellOrder should not error on insufficient position: %v", err)
+	}
+	tx, _ := msadb.GetTransactionByID(db, sellTxID)
+	if tx.Status != model.TransactionStatusRejected || !strings.Contains(tx.Note, "无持仓") {
+		t.Errorf("Expected rejection for no position, got %s (%s)", tx.Status, tx.Note)
+	}
+	account, _ := msadb.GetAccountByID(db, accountID)
+	if account.AvailableAmt != model.YuanToHao(10000) {
+		t.Errorf("Expected available %d, got %d", model.YuanToHao(10000), account.AvailableAmt)
+	}
+
+	// 持仓 200 股，挂单卖出 100 股后只能再卖 100 股
+	buyTxID, _ := SubmitBuyOrder(db, accountID, Order{
+		StockCode: "600519", StockName: "贵州茅台", Quantity: 200, Price: model.YuanToHao(10), Time: testTradeTime,
+	})
+	FillOrder(db, buyTxID)
+	SubmitSellOrder(db, accountID, Order{
+		StockCode: "600519", StockName: "贵州茅台", Quantity: 100, Price: model.YuanToHao(11), Time: testNextTradeTime,
+	})
+
+	sellTxID, _ = SubmitSellOrder(db, accountID, Order{
+		StockCode: "600519", StockName: "贵州茅台", Quantity: 200, Price: model.YuanToHao(11), Time: testNextTradeTime,
+	})
+	tx, _ = msadb.GetTransactionByID(db, sellTxID)
+	if tx.Status != model.TransactionStatusRejected || !strings.Contains(tx.Note, "持仓不足") {
+		t.Errorf("Expected rejection for insufficient position, got %s (%s)", tx.Status, tx.Note)
+	}
+
+	sellTxID, _ = SubmitSellOrder(db, accountID, Order{
+		StockCode: "600519", StockName: "贵州茅台", Quantity: 100, Price: model.YuanToHao(11), Time: testNextTradeTime,
+	})
+	tx, _ = msadb.GetTransactionByID(db, sellTxID)
+	if tx.Status != model.TransactionStatusPending {
+		t.Errorf("Expected status %s, got %s (%s)", model.TransactionStatusPending, tx.Status, tx.Note)
+	}
+}
+
 func TestFillOrder_Buy(t *testing.T) {
 	db := setupTestDB(t)
 
@@ -153,7 +211,7 @@ func TestFillOrder_Buy(t *testing.T) {
 		StockName: "贵州茅台",
 		Quantity:  100,
 		Price:     model.YuanToHao(10),
-		Fee:       model.YuanToHao(0.05),
+		Time:      testTradeTime,
 	}
 	txID, _ := SubmitBuyOrder(db, account.ID, order)
 
@@ -175,7 +233,7 @@ func TestFillOrder_Buy(t *testing.T) {
 	}
 
 	// 验证可用余额
-	expectedAvailable := model.YuanToHao(10000) - model.YuanToHao(10)*100 - model.YuanToHao(0.05)
+	expectedAvailable := model.YuanToHao(10000) - model.YuanToHao(10)*100 - model.YuanToHao(5.01)
 	if account.AvailableAmt != expectedAvailable {
 		t.Errorf("Expected available %d, got %d", expectedAvailable, account.AvailableAmt)
 	}
@@ -191,9 +249,9 @@ func TestFillOrder_Sell(t *testing.T) {
 	buyOrder := Order{
 		StockCode: "600519",
 		StockName: "贵州茅台",
-		Quantity:  100,
+		Quantity:  200,
 		Price:     model.YuanToHao(10),
-		Fee:       model.YuanToHao(0.05),
+		Time:      testTradeTime,
 	}
 	buyTxID, _ := SubmitBuyOrder(db, account.ID, buyOrder)
 	FillOrder(db, buyTxID)
@@ -202,9 +260,9 @@ func TestFillOrder_Sell(t *testing.T) {
 	sellOrder := Order{
 		StockCode: "600519",
 		StockName: "贵州茅台",
-		Quantity:  50,
+		Quantity:  100,
 		Price:     model.YuanToHao(11),
-		Fee:       model.YuanToHao(0.05),
+		Time:      testNextTradeTime,
 	}
 	sellTxID, _ := SubmitSellOrder(db, account.ID, sellOrder)
 
@@ -215,8 +273,8 @@ func TestFillOrder_Sell(t *testing.T) {
 
 	// 验证可用余额增加
 	account, _ = msadb.GetAccountByID(db, accountID)
-	// 初始 10000 - 买入 1000.05 + 卖出收入 (550 - 0.05)
-	expectedAvailable := model.YuanToHao(10000) - model.YuanToHao(10)*100 - model.YuanToHao(0.05) + model.YuanToHao(11)*50 - model.YuanToHao(0.05)
+	// 初始 10000 - 买入 (2000 + 佣金 5 + 过户费 0.02) + 卖出收入 (1100 - 佣金 5 - 印花税 0.55 - 过户费 0.01)
+	expectedAvailable := model.YuanToHao(10000) - model.YuanToHao(10)*200 - 50200 + model.YuanToHao(11)*100 - 55600
 	if account.AvailableAmt != expectedAvailable {
 		t.Errorf("Expected available %d, got %d", expectedAvailable, account.AvailableAmt)
 	}
@@ -0,0 +1,308 @@
+package indicator
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// RSIOverbought RSI 超买阈值
+	RSIOverbought = 70
+	// RSIOversold RSI 超卖阈值
+	RSIOversold = 30
+	// KDJOverbought J 值超买阈值
+	KDJOverbought = 100
+	// KDJOversold J 值超卖阈值
+	KDJOversold = 0
+)
+
+// Config 指标参数
+type Config struct {
+	MAPeriods        []int   // 均线周期
+	EMAPeriods       []int   // 指数均线周期
+	MACDFast         int     // MACD 快线周期
+	MACDSlow         int     // MACD 慢线周期
+	MACDSignal       int     // MACD 信号线周期
+	RSIPeriods       []int   // RSI 周期，首个周期用于超买超卖与背离判断
+	KDJN             int     // KDJ RSV 周期
+	KDJM1            int     // K 平滑周期
+	KDJM2            int     // D 平滑周期
+	BOLLPeriod       int     // 布林带周期
+	BOLLWidth        float64 // 布林带标准差倍数
+	ATRPeriod        int     // ATR 周期
+	SignalLookback   int     // 交叉信号回看K线数
+	DivergenceWindow int     // 背离检测窗口K线数
+}
+
+// DefaultConfig 默认指标参数
+func DefaultConfig() Config {
+	return Config{
+		MAPeriods:        []int{5, 10, 20, 60},
+		EMAPeriods:       []int{12, 26},
+		MACDFast:         12,
+		MACDSlow:         26,
+		MACDSignal:       9,
+		RSIPeriods:       []int{6, 14},
+		KDJN:             9,
+		KDJM1:            3,
+		KDJM2:            3,
+		BOLLPeriod:       20,
+		BOLLWidth:        2,
+		ATRPeriod:        14,
+		SignalLookback:   3,
+		DivergenceWindow: 60,
+	}
+}
+
+// withDefaults 未设置的参数使用默认值
+func (c Config) withDefaults() Config {
+	d := DefaultConfig()
+	if len(c.MAPeriods) == 0 {
+		c.MAPeriods = d.MAPeriods
+	}
+	if len(c.EMAPeriods) == 0 {
+		c.EMAPeriods = d.EMAPeriods
+	}
+	if c.MACDFast <= 0 {
+		c.MACDFast = d.MACDFast
+	}
+	if c.MACDSlow <= 0 {
+		c.MACDSlow = d.MACDSlow
+	}
+	if c.MACDSignal <= 0 {
+		c.MACDSignal = d.MACDSignal
+	}
+	if len(c.RSIPeriods) == 0 {
+		c.RSIPeriods = d.RSIPeriods
+	}
+	if c.KDJN <= 0 {
+		c.KDJN = d.KDJN
+	}
+	if c.KDJM1 <= 0 {
+		c.KDJM1 = d.KDJM1
+	}
+	if c.KDJM2 <= 0 {
+		c.KDJM2 = d.KDJM2
+	}
+	if c.BOLLPeriod <= 0 {
+		c.BOLLPeriod = d.BOLLPeriod
+	}
+	if c.BOLLWidth <= 0 {
+		c.BOLLWidth = d.BOLLWidth
+	}
+	if c.ATRPeriod <= 0 {
+		c.ATRPeriod = d.ATRPeriod
+	}
+	if c.SignalLookback <= 0 {
+		c.SignalLookback = d.SignalLookback
+	}
+	if c.DivergenceWindow <= 0 {
+		c.DivergenceWindow = d.DivergenceWindow
+	}
+	return c
+}
+
+// MACDValue MACD 最新值
+type MACDValue struct {
+	DIF  float64 `json:"dif"`
+	DEA  float64 `json:"dea"`
+	Hist float64 `json:"hist"` // MACD 柱 = 2 × (DIF - DEA)
+}
+
+// KDJValue KDJ 最新值
+type KDJValue struct {
+	K float64 `json:"k"`
+	D float64 `json:"d"`
+	J float64 `json:"j"`
+}
+
+// BOLLValue 布林带最新值
+type BOLLValue struct {
+	Upper float64 `json:"upper"`
+	Mid   float64 `json:"mid"`
+	Lower float64 `json:"lower"`
+	// PercentB 收盘价在布林带中的位置，0 为下轨，1 为上轨
+	PercentB float64 `json:"percent_b"`
+}
+
+// Result 指标计算结果，只包含最新一根K线的指标值与信号
+// K线不足以形成的指标不出现在结果中
+type Result struct {
+	Date       string             `json:"date"`
+	Close      float64            `json:"close"`
+	Bars       int                `json:"bars"` // 参与计算的K线数
+	MA         map[string]float64 `json:"ma,omitempty"`
+	EMA        map[string]float64 `json:"ema,omitempty"`
+	MACD       *MACDValue         `json:"macd,omitempty"`
+	RSI        map[string]float64 `json:"rsi,omitempty"`
+	KDJ        *KDJValue          `json:"kdj,omitempty"`
+	BOLL       *BOLLValue         `json:"boll,omitempty"`
+	ATR        *float64           `json:"atr,omitempty"`
+	ATRPercent *float64           `json:"atr_percent,omitempty"` // ATR / 收盘价（%）
+	VWAP       *float64           `json:"vwap,omitempty"`        // 自首根K线起累计的成交量加权均价
+	OBV        *float64           `json:"obv,omitempty"`
+	Signals    []Signal           `json:"signals"`
+	Warnings   []string           `json:"warnings,omitempty"`
+}
+
+// Compute 计算全部指标的最新值与信号
+func Compute(bars []Bar, cfg Config) (*Result, error) {
+	if len(bars) == 0 {
+		return nil, fmt.Errorf("no K-line data for indicators")
+	}
+	cfg = cfg.withDefaults()
+
+	closes := Closes(bars)
+	last := bars[len(bars)-1]
+	result := &Result{
+		Date:    last.Date,
+		Close:   last.Close,
+		Bars:    len(bars),
+		Signals: []Signal{},
+	}
+
+	// 均线及均线交叉（相邻周期两两比较）
+	maSeries := make([][]float64, len(cfg.MAPeriods))
+	for i, period := range cfg.MAPeriods {
+		maSeries[i] = SMA(closes, period)
+		if v, ok := Last(maSeries[i]); ok {
+			if result.MA == nil {
+				result.MA = map[string]float64{}
+			}
+			result.MA[fmt.Sprintf("ma%d", period)] = round(v, 4)
+		}
+	}
+	for i := 1; i < len(maSeries); i++ {
+		name := fmt.Sprintf("MA%d/MA%d", cfg.MAPeriods[i-1], cfg.MAPeriods[i])
+		result.addCross(name, maSeries[i-1], maSeries[i], cfg.SignalLookback)
+	}
+
+	for _, period := range cfg.EMAPeriods {
+		if v, ok := Last(EMA(closes, period)); ok && len(bars) >= period {
+			if result.EMA == nil {
+				result.EMA = map[string]float64{}
+			}
+			result.EMA[fmt.Sprintf("ema%d", period)] = round(v, 4)
+		}
+	}
+
+	// MACD：EMA 需要足够K线才能收敛
+	macd := MACD(closes, cfg.MACDFast, cfg.MACDSlow, cfg.MACDSignal)
+	if len(bars) >= cfg.MACDSlow+cfg.MACDSignal {
+		dif, _ := Last(macd.DIF)
+		dea, _ := Last(macd.DEA)
+		hist, _ := Last(macd.Hist)
+		result.MACD = &MACDValue{DIF: round(dif, 4), DEA: round(dea, 4), Hist: round(hist, 4)}
+		result.addCross("MACD", macd.DIF, macd.DEA, cfg.SignalLookback)
+		result.addDivergence("MACD", closes, macd.DIF, cfg.DivergenceWindow)
+	} else {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("K线不足 %d 根，未计算 MACD", cfg.MACDSlow+cfg.MACDSignal))
+	}
+
+	// RSI
+	for i, period := range cfg.RSIPeriods {
+		series := RSI(closes, period)
+		v, ok := Last(series)
+		if !ok {
+			continue
+		}
+		if result.RSI == nil {
+			result.RSI = map[string]float64{}
+		}
+		result.RSI[fmt.Sprintf("rsi%d", period)] = round(v, 2)
+		if i != 0 {
+			continue
+		}
+		name := fmt.Sprintf("RSI%d", period)
+		switch {
+		case v >= RSIOverbought:
+			result.addSignal(name, SignalOverbought, 0, fmt.Sprintf("%s=%.2f，进入超买区（≥%d）", name, v, RSIOverbought))
+		case v <= RSIOversold:
+			result.addSignal(name, SignalOversold, 0, fmt.Sprintf("%s=%.2f，进入超卖区（≤%d）", name, v, RSIOversold))
+		}
+		result.addDivergence(name, closes, series, cfg.DivergenceWindow)
+	}
+
+	// KDJ
+	if len(bars) >= cfg.KDJN {
+		kdj := KDJ(bars, cfg.KDJN, cfg.KDJM1, cfg.KDJM2)
+		k, _ := Last(kdj.K)
+		d, _ := Last(kdj.D)
+		j, _ := Last(kdj.J)
+		result.KDJ = &KDJValue{K: round(k, 2), D: round(d, 2), J: round(j, 2)}
+		result.addCross("KDJ", kdj.K, kdj.D, cfg.SignalLookback)
+		switch {
+		case j > KDJOverbought:
+			result.addSignal("KDJ", SignalOverbought, 0, fmt.Sprintf("J=%.2f，超买（>%d）", j, KDJOverbought))
+		case j < KDJOversold:
+			result.addSignal("KDJ", SignalOversold, 0, fmt.Sprintf("J=%.2f，超卖（<%d）", j, KDJOversold))
+		}
+	}
+
+	// 布林带
+	boll := BOLL(closes, cfg.BOLLPeriod, cfg.BOLLWidth)
+	if upper, ok := Last(boll.Upper); ok {
+		mid, _ := Last(boll.Mid)
+		lower, _ := Last(boll.Lower)
+		value := &BOLLValue{Upper: round(upper, 4), Mid: round(mid, 4), Lower: round(lower, 4)}
+		if upper > lower {
+			value.PercentB = round((last.Close-lower)/(upper-lower), 4)
+		}
+		result.BOLL = value
+		switch {
+		case last.Close > upper:
+			result.addSignal("BOLL", SignalBreakUpper, 0, fmt.Sprintf("收盘价 %.2f 突破上轨 %.2f", last.Close, upper))
+		case last.Close < lower:
+			result.addSignal("BOLL", SignalBreakLower, 0, fmt.Sprintf("收盘价 %.2f 跌破下轨 %.2f", last.Close, lower))
+		}
+	}
+
+	// ATR
+	if v, ok := Last(ATR(bars, cfg.ATRPeriod)); ok {
+		atr := round(v, 4)
+		result.ATR = &atr
+		if last.Close > 0 {
+			pct := round(v/last.Close*100, 2)
+			result.ATRPercent = &pct
+		}
+	}
+
+	// VWAP 与 OBV
+	if v, ok := Last(VWAP(bars)); ok {
+		vwap := round(v, 4)
+		result.VWAP = &vwap
+	}
+	if v, ok := Last(OBV(bars)); ok {
+		obv := math.Round(v)
+		result.OBV = &obv
+	}
+
+	return result, nil
+}
+
+// addSignal 追加信号
+func (r *Result) addSignal(indicator string, signalType SignalType, barsAgo int, description string) {
+	r.Signals = append(r.Signals, Signal{Indicator: indicator, Type: signalType, BarsAgo: barsAgo, Description: description})
+}
+
+// addCross 检测并追加交叉信号
+func (r *Result) addCross(indicator string, fast, slow []float64, lookback int) {
+	cross, barsAgo := LastCross(fast, slow, lookback)
+	switch cross {
+	case SignalGoldenCross:
+		r.addSignal(indicator, cross, barsAgo, fmt.Sprintf("%s 金叉（%d 根K线前）", indicator, barsAgo))
+	case SignalDeathCross:
+		r.addSignal(indicator, cross, barsAgo, fmt.Sprintf("%s 死叉（%d 根K线前）", indicator, barsAgo))
+	}
+}
+
+// addDivergence 检测并追加背离信号
+func (r *Result) addDivergence(indicator string, prices, osc []float64, window int) {
+	divergence, barsAgo := DetectDivergence(prices, osc, window)
+	switch divergence {
+	case SignalBearishDivergence:
+		r.addSignal(indicator, divergence, barsAgo, fmt.Sprintf("%s 顶背离：价格创新高而指标走低", indicator))
+	case SignalBullishDivergence:
+		r.addSignal(indicator, divergence, barsAgo, fmt.Sprintf("%s 底背离：价格创新低而指标走高", indicator))
+	}
+}
@@ -0,0 +1,217 @@
+package indicator
+
+import (
+	"math"
+	"testing"
+
+	"msa/pkg/model"
+)
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-3
+}
+
+func assertSeries(t *testing.T, name string, got, want []float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: expected %d values, got %d", name, len(want), len(got))
+	}
+	for i := range want {
+		if math.IsNaN(want[i]) {
+			if !math.IsNaN(got[i]) {
+				t.Errorf("%s[%d]: expected NaN, got %v", name, i, got[i])
+			}
+			continue
+		}
+		if !almostEqual(got[i], want[i]) {
+			t.Errorf("%s[%d]: expected %v, got %v", name, i, want[i], got[i])
+		}
+	}
+}
+
+// flatBars 生成开高低收均为收盘价的K线
+func flatBars(closes ...float64) []Bar {
+	bars := make([]Bar, len(closes))
+	for i, c := range closes {
+		bars[i] = Bar{Open: c, High: c, Low: c, Close: c, Volume: 100}
+	}
+	return bars
+}
+
+func TestFromKLineBars(t *testing.T) {
+	bars, err := FromKLineBars([]model.KLineBar{
+		{Date: "2025-03-03", Open: "10", High: "10.5", Low: "9.5", Close: "10.2", Volume: "1200"},
+		{Date: "2025-03-04", High: "11", Low: "10.2", Close: "10.8"},
+	})
+	if err != nil {
+		t.Fatalf("FromKLineBars failed: %v", err)
+	}
+	if bars[0].Open != 10 || bars[0].Volume != 1200 {
+		t.Errorf("Unexpected bar: %+v", bars[0])
+	}
+	// 缺失开盘价取收盘价，缺失成交量为 0
+	if bars[1].Open != 10.8 || bars[1].Volume != 0 {
+		t.Errorf("Unexpected bar: %+v", bars[1])
+	}
+
+	if _, err := FromKLineBars([]model.KLineBar{{Date: "2025-03-03", High: "x", Low: "1", Close: "1"}}); err == nil {
+		t.Error("Expected error for invalid bar")
+	}
+}
+
+func TestTrendIndicators(t *testing.T) {
+	nan := math.NaN()
+	assertSeries(t, "SMA", SMA([]float64{1, 2, 3, 4, 5}, 3), []float64{nan, nan, 2, 3, 4})
+	// alpha = 2/(3+1) = 0.5
+	assertSeries(t, "EMA", EMA([]float64{1, 2, 3}, 3), []float64{1, 1.5, 2.25})
+
+	// 中轨 2，总体标准差 sqrt(2/3)=0.8165
+	boll := BOLL([]float64{1, 2, 3}, 3, 2)
+	assertSeries(t, "BOLL.Upper", boll.Upper, []float64{nan, nan, 3.633})
+	assertSeries(t, "BOLL.Lower", boll.Lower, []float64{nan, nan, 0.367})
+
+	// 常数序列 DIF、DEA、柱均为 0
+	macd := MACD([]float64{10, 10, 10, 10}, 2, 3, 2)
+	assertSeries(t, "MACD.Hist", macd.Hist, []float64{0, 0, 0, 0})
+	// EMA2: 1, 1.6667；EMA3: 1, 1.5；DIF: 0, 0.1667；DEA(2): 0, 0.1111；柱 2×(DIF-DEA)
+	macd = MACD([]float64{1, 2}, 2, 3, 2)
+	assertSeries(t, "MACD.DIF", macd.DIF, []float64{0, 0.1667})
+	assertSeries(t, "MACD.Hist", macd.Hist, []float64{0, 0.1111})
+}
+
+func TestOscillators(t *testing.T) {
+	nan := math.NaN()
+	// 涨跌：+1 +1 -1 +1；初值 gain=1 loss=0，之后 Wilder 平滑
+	assertSeries(t, "RSI", RSI([]float64{1, 2, 3, 2, 3}, 2), []float64{nan, nan, 100, 50, 75})
+
+	kdj := KDJ([]Bar{
+		{High: 10, Low: 8, Close: 9},
+		{High: 11, Low: 9, Close: 11},
+	}, 3, 3, 3)
+	// 首根 RSV=50；第二根 RSV=(11-8)/(11-8)×100=100，K=66.667，D=55.556，J=88.889
+	assertSeries(t, "KDJ.K", kdj.K, []float64{50, 66.667})
+	assertSeries(t, "KDJ.D", kdj.D, []float64{50, 55.556})
+	assertSeries(t, "KDJ.J", kdj.J, []float64{50, 88.889})
+}
+
+func TestVolatilityAndVolume(t *testing.T) {
+	nan := math.NaN()
+	bars := []Bar{
+		{High: 10.5, Low: 9.5, Close: 10},
+		{High: 11, Low: 10.2, Close: 10.8},
+		{High: 10.9, Low: 10.1, Close: 10.3},
+	}
+	assertSeries(t, "TR", TrueRange(bars), []float64{1, 1, 0.8})
+	assertSeries(t, "ATR", ATR(bars, 2), []float64{nan, 1, 0.9})
+	assertSeries(t, "ATR(short)", ATR(bars, 5), []float64{nan, nan, nan})
+
+	volumeBars := []Bar{
+		{High: 10, Low: 10, Close: 10, Volume: 100},
+		{High: 20, Low: 20, Close: 20, Volume: 300},
+		{High: 15, Low: 15, Close: 15, Volume: 200},
+		{High: 15, Low: 15, Close: 15, Volume: 400},
+	}
+	// (10×100 + 20×300) / 400 = 17.5
+	assertSeries(t, "VWAP", VWAP(volumeBars), []float64{10, 17.5, 16.667, 16})
+	assertSeries(t, "OBV", OBV(volumeBars), []float64{0, 300, 100, 100})
+}
+
+func TestLastCross(t *testing.T) {
+	slow := []float64{2, 2, 2}
+	if cross, ago := LastCross([]float64{1, 1, 3}, slow, 3); cross != SignalGoldenCross || ago != 0 {
+		t.Errorf("Expected golden cross 0 bars ago, got %s %d", cross, ago)
+	}
+	if cross, ago := LastCross([]float64{3, 1, 1}, slow, 3); cross != SignalDeathCross || ago != 1 {
+		t.Errorf("Expected death cross 1 bar ago, got %s %d", cross, ago)
+	}
+	// 超出回看范围
+	if cross, _ := LastCross([]float64{3, 1, 1}, slow, 1); cross != "" {
+		t.Errorf("Expected no cross within lookback, got %s", cross)
+	}
+	// 数据不足（NaN）不产生信号
+	if cross, _ := LastCross([]float64{math.NaN(), 3}, []float64{math.NaN(), 2}, 3); cross != "" {
+		t.Errorf("Expected no cross on NaN, got %s", cross)
+	}
+}
+
+func TestDetectDivergence(t *testing.T) {
+	// 后段价格高点 11 高于前段高点 10，指标 60 低于前段 80：顶背离
+	prices := []float64{5, 6, 10, 7, 6, 5, 6, 11, 8}
+	osc := []float64{50, 60, 80, 60, 50, 40, 50, 60, 55}
+	if div, ago := DetectDivergence(prices, osc, 9); div != SignalBearishDivergence || ago != 1 {
+		t.Errorf("Expected bearish divergence 1 bar ago, got %s %d", div, ago)
+	}
+
+	// 价格创新低而指标抬高：底背离
+	prices = []float64{10, 8, 4, 7, 8, 9, 8, 3, 5}
+	osc = []float64{50, 40, 20, 40, 50, 60, 50, 30, 40}
+	if div, ago := DetectDivergence(prices, osc, 9); div != SignalBullishDivergence || ago != 1 {
+		t.Errorf("Expected bullish divergence 1 bar ago, got %s %d", div, ago)
+	}
+
+	// 指标同步创新高：无背离
+	osc = []float64{50, 60, 80, 60, 50, 40, 50, 90, 55}
+	if div, _ := DetectDivergence([]float64{5, 6, 10, 7, 6, 5, 6, 11, 8}, osc, 9); div != "" {
+		t.Errorf("Expected no divergence, got %s", div)
+	}
+}
+
+func TestCompute(t *testing.T) {
+	if _, err := Compute(nil, DefaultConfig()); err == nil {
+		t.Error("Expected error for empty bars")
+	}
+
+	// 横盘 40 根后放量大涨：MA5 上穿 MA10、收盘突破布林上轨
+	closes := make([]float64, 41)
+	for i := range closes {
+		closes[i] = 10
+	}
+	closes[40] = 11
+	bars := flatBars(closes...)
+	bars[40].Date = "2025-03-03"
+
+	result, err := Compute(bars, Config{MAPeriods: []int{5, 10}})
+	if err != nil {
+		t.Fatalf("Compute failed: %v", err)
+	}
+	if result.Date != "2025-03-03" || result.Close != 11 || result.Bars != 41 {
+		t.Errorf("Unexpected result header: %+v", result)
+	}
+	if result.MA["ma5"] != 10.2 || result.MA["ma10"] != 10.1 {
+		t.Errorf("Unexpected MA: %+v", result.MA)
+	}
+	if result.MACD == nil || result.KDJ == nil || result.BOLL == nil || result.ATR == nil || result.VWAP == nil || result.OBV == nil {
+		t.Fatalf("Expected all indicators present: %+v", result)
+	}
+	if *result.OBV != 100 || result.RSI["rsi6"] != 100 {
+		t.Errorf("Unexpected OBV/RSI: %v %+v", *result.OBV, result.RSI)
+	}
+
+	want := map[string]SignalType{
+		"MA5/MA10": SignalGoldenCross,
+		"MACD":     SignalGoldenCross,
+		"RSI6":     SignalOverbought,
+		"KDJ":      SignalGoldenCross,
+		"BOLL":     SignalBreakUpper,
+	}
+	if len(result.Signals) != len(want) {
+		t.Errorf("Expected %d signals, got %+v", len(want), result.Signals)
+	}
+	for _, s := range result.Signals {
+		if want[s.Indicator] != s.Type || s.BarsAgo != 0 {
+			t.Errorf("Unexpected signal: %+v", s)
+		}
+	}
+
+	// K线不足时省略指标并给出提示
+	result, err = Compute(flatBars(10, 11, 12), DefaultConfig())
+	if err != nil {
+		t.Fatalf("Compute failed: %v", err)
+	}
+	if result.MACD != nil || result.BOLL != nil || result.ATR != nil || len(result.MA) != 0 {
+		t.Errorf("Expected indicators omitted for short series: %+v", result)
+	}
+	if len(result.Warnings) == 0 {
+		t.Error("Expected warning for short series")
+	}
+}
@@ -0,0 +1,80 @@
+package indicator
+
+import "math"
+
+// RSI 相对强弱指标，按 Wilder 平滑
+// 首个值位于第 period 根（以前 period 个涨跌幅的均值为初值），之前为 NaN
+func RSI(closes []float64, period int) []float64 {
+	out := newSeries(len(closes))
+	if period <= 0 || len(closes) <= period {
+		return out
+	}
+
+	var avgGain, avgLoss float64
+	for i := 1; i <= period; i++ {
+		change := closes[i] - closes[i-1]
+		if change > 0 {
+			avgGain += change
+		} else {
+			avgLoss -= change
+		}
+	}
+	avgGain /= float64(period)
+	avgLoss /= float64(period)
+	out[period] = rsiValue(avgGain, avgLoss)
+
+	for i := period + 1; i < len(closes); i++ {
+		change := closes[i] - closes[i-1]
+		gain, loss := math.Max(change, 0), math.Max(-change, 0)
+		avgGain = (avgGain*float64(period-1) + gain) / float64(period)
+		avgLoss = (avgLoss*float64(period-1) + loss) / float64(period)
+		out[i] = rsiValue(avgGain, avgLoss)
+	}
+	return out
+}
+
+func rsiValue(avgGain, avgLoss float64) float64 {
+	if avgGain+avgLoss == 0 {
+		return 50
+	}
+	return 100 * avgGain / (avgGain + avgLoss)
+}
+
+// KDJSeries KDJ 指标序列
+type KDJSeries struct {
+	K []float64
+	D []float64
+	J []float64
+}
+
+// KDJ 随机指标
+// RSV = (C - n 日最低) / (n 日最高 - n 日最低) × 100，不足 n 根时使用已有K线
+// K = ((m1-1) × K' + RSV) / m1，D = ((m2-1) × D' + K) / m2，K、D 初值为 50，J = 3K - 2D
+func KDJ(bars []Bar, n, m1, m2 int) KDJSeries {
+	k := newSeries(len(bars))
+	d := newSeries(len(bars))
+	j := newSeries(len(bars))
+	if n <= 0 || m1 <= 0 || m2 <= 0 {
+		return KDJSeries{K: k, D: d, J: j}
+	}
+
+	prevK, prevD := 50.0, 50.0
+	for i := range bars {
+		start := max(0, i-n+1)
+		highest, lowest := bars[start].High, bars[start].Low
+		for _, bar := range bars[start : i+1] {
+			highest = math.Max(highest, bar.High)
+			lowest = math.Min(lowest, bar.Low)
+		}
+
+		rsv := 50.0
+		if highest > lowest {
+			rsv = (bars[i].Close - lowest) / (highest - lowest) * 100
+		}
+		k[i] = (float64(m1-1)*prevK + rsv) / float64(m1)
+		d[i] = (float64(m2-1)*prevD + k[i]) / float64(m2)
+		j[i] = 3*k[i] - 2*d[i]
+		prevK, prevD = k[i], d[i]
+	}
+	return KDJSeries{K: k, D: d, J: j}
+}
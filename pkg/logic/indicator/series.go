@@ -0,0 +1,93 @@
+package indicator
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"msa/pkg/model"
+)
+
+// Bar 数值化的K线
+// 成交量单位与数据源一致（A股为手）
+type Bar struct {
+	Date   string
+	Open   float64
+	High   float64
+	Low    float64
+	Close  float64
+	Volume float64
+}
+
+// FromKLineBars 将历史K线转换为数值序列
+// 最高、最低、收盘价必须有效；开盘价缺失时取收盘价，成交量缺失时为 0
+func FromKLineBars(bars []model.KLineBar) ([]Bar, error) {
+	result := make([]Bar, 0, len(bars))
+	for _, bar := range bars {
+		high, err1 := strconv.ParseFloat(bar.High, 64)
+		low, err2 := strconv.ParseFloat(bar.Low, 64)
+		closePrice, err3 := strconv.ParseFloat(bar.Close, 64)
+		if err1 != nil || err2 != nil || err3 != nil {
+			return nil, fmt.Errorf("invalid K-line bar on %s", bar.Date)
+		}
+		open, err := strconv.ParseFloat(bar.Open, 64)
+		if err != nil {
+			open = closePrice
+		}
+		volume, err := strconv.ParseFloat(bar.Volume, 64)
+		if err != nil {
+			volume = 0
+		}
+		result = append(result, Bar{Date: bar.Date, Open: open, High: high, Low: low, Close: closePrice, Volume: volume})
+	}
+	return result, nil
+}
+
+// FromMinuteBars 将分钟K线转换为数值序列
+// 分钟数据只有成交价，开高低收均取成交价
+func FromMinuteBars(bars []model.StockMinuteBar) []Bar {
+	result := make([]Bar, 0, len(bars))
+	for _, bar := range bars {
+		result = append(result, Bar{
+			Date:   bar.Time,
+			Open:   bar.Price,
+			High:   bar.Price,
+			Low:    bar.Price,
+			Close:  bar.Price,
+			Volume: float64(bar.Volume),
+		})
+	}
+	return result
+}
+
+// Closes 提取收盘价序列
+func Closes(bars []Bar) []float64 {
+	values := make([]float64, len(bars))
+	for i, bar := range bars {
+		values[i] = bar.Close
+	}
+	return values
+}
+
+// Last 获取序列最后一个有效值
+func Last(values []float64) (float64, bool) {
+	if len(values) == 0 || math.IsNaN(values[len(values)-1]) {
+		return 0, false
+	}
+	return values[len(values)-1], true
+}
+
+// newSeries 创建以 NaN 填充的序列，NaN 表示数据不足尚未形成指标值
+func newSeries(n int) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = math.NaN()
+	}
+	return values
+}
+
+// round 保留指定位数小数
+func round(v float64, digits int) float64 {
+	p := math.Pow10(digits)
+	return math.Round(v*p) / p
+}
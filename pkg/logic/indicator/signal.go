@@ -0,0 +1,100 @@
+package indicator
+
+import "math"
+
+// SignalType 信号类型
+type SignalType string
+
+const (
+	// SignalGoldenCross 金叉：快线上穿慢线
+	SignalGoldenCross SignalType = "GOLDEN_CROSS"
+	// SignalDeathCross 死叉：快线下穿慢线
+	SignalDeathCross SignalType = "DEATH_CROSS"
+	// SignalOverbought 超买
+	SignalOverbought SignalType = "OVERBOUGHT"
+	// SignalOversold 超卖
+	SignalOversold SignalType = "OVERSOLD"
+	// SignalBreakUpper 收盘突破布林上轨
+	SignalBreakUpper SignalType = "BREAK_UPPER"
+	// SignalBreakLower 收盘跌破布林下轨
+	SignalBreakLower SignalType = "BREAK_LOWER"
+	// SignalBearishDivergence 顶背离：价格创新高而指标未创新高
+	SignalBearishDivergence SignalType = "BEARISH_DIVERGENCE"
+	// SignalBullishDivergence 底背离：价格创新低而指标未创新低
+	SignalBullishDivergence SignalType = "BULLISH_DIVERGENCE"
+)
+
+// Signal 指标信号
+type Signal struct {
+	Indicator   string     `json:"indicator"`   // 指标名称，如 MACD、MA5/MA10
+	Type        SignalType `json:"type"`        // 信号类型
+	BarsAgo     int        `json:"bars_ago"`    // 信号出现在最近第几根K线之前，0 表示最新一根
+	Description string     `json:"description"` // 信号说明
+}
+
+// LastCross 查找最近 lookback 根K线内快线与慢线的最后一次交叉
+// 返回交叉类型与距最新K线的根数；没有交叉时返回空类型
+func LastCross(fast, slow []float64, lookback int) (SignalType, int) {
+	n := min(len(fast), len(slow))
+	for i := n - 1; i >= 1 && i >= n-lookback; i-- {
+		if anyNaN(fast[i], slow[i], fast[i-1], slow[i-1]) {
+			break
+		}
+		prevDiff := fast[i-1] - slow[i-1]
+		diff := fast[i] - slow[i]
+		if prevDiff <= 0 && diff > 0 {
+			return SignalGoldenCross, n - 1 - i
+		}
+		if prevDiff >= 0 && diff < 0 {
+			return SignalDeathCross, n - 1 - i
+		}
+	}
+	return "", 0
+}
+
+// DetectDivergence 检测价格与指标的背离
+// 将最近 window 根K线分为前后两段（后段为最近 window/3 根）：
+// 后段最高价高于前段最高价，而后段高点处的指标值低于前段高点处的指标值，为顶背离；底背离反之
+// 返回背离类型与后段极值点距最新K线的根数
+func DetectDivergence(prices, osc []float64, window int) (SignalType, int) {
+	n := min(len(prices), len(osc))
+	if window < 9 || n < window {
+		return "", 0
+	}
+	recent := window / 3
+	priorStart, recentStart := n-window, n-recent
+
+	priorHigh, priorLow := extremes(prices, priorStart, recentStart)
+	recentHigh, recentLow := extremes(prices, recentStart, n)
+
+	if prices[recentHigh] > prices[priorHigh] && !anyNaN(osc[recentHigh], osc[priorHigh]) && osc[recentHigh] < osc[priorHigh] {
+		return SignalBearishDivergence, n - 1 - recentHigh
+	}
+	if prices[recentLow] < prices[priorLow] && !anyNaN(osc[recentLow], osc[priorLow]) && osc[recentLow] > osc[priorLow] {
+		return SignalBullishDivergence, n - 1 - recentLow
+	}
+	return "", 0
+}
+
+// extremes 返回 [from, to) 区间内最高值与最低值的下标
+func extremes(values []float64, from, to int) (high, low int) {
+	high, low = from, from
+	for i := from; i < to; i++ {
+		if values[i] > values[high] {
+			high = i
+		}
+		if values[i] < values[low] {
+			low = i
+		}
+	}
+	return high, low
+}
+
+func anyNaN(values ...float64) bool {
+	for _, v := range values {
+		if math.IsNaN(v) {
+			return true
+		}
+	}
+	return false
+}
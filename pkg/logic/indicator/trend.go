@@ -0,0 +1,97 @@
+package indicator
+
+import "math"
+
+// SMA 简单移动平均，前 period-1 个值为 NaN
+func SMA(values []float64, period int) []float64 {
+	out := newSeries(len(values))
+	if period <= 0 {
+		return out
+	}
+	var sum float64
+	for i, v := range values {
+		sum += v
+		if i >= period {
+			sum -= values[i-period]
+		}
+		if i >= period-1 {
+			out[i] = sum / float64(period)
+		}
+	}
+	return out
+}
+
+// EMA 指数移动平均，以首个值为初值：EMA = (2 × X + (N-1) × EMA') / (N+1)
+// 与通达信、同花顺的 EMA 口径一致
+func EMA(values []float64, period int) []float64 {
+	out := newSeries(len(values))
+	if period <= 0 || len(values) == 0 {
+		return out
+	}
+	alpha := 2 / float64(period+1)
+	prev := math.NaN()
+	for i, v := range values {
+		if math.IsNaN(v) {
+			continue
+		}
+		if math.IsNaN(prev) {
+			prev = v
+		} else {
+			prev = alpha*v + (1-alpha)*prev
+		}
+		out[i] = prev
+	}
+	return out
+}
+
+// MACDSeries MACD 指标序列
+type MACDSeries struct {
+	DIF  []float64
+	DEA  []float64
+	Hist []float64 // MACD 柱 = 2 × (DIF - DEA)
+}
+
+// MACD 计算 MACD：DIF = EMA(fast) - EMA(slow)，DEA = EMA(DIF, signal)，柱 = 2 × (DIF - DEA)
+func MACD(closes []float64, fast, slow, signal int) MACDSeries {
+	emaFast := EMA(closes, fast)
+	emaSlow := EMA(closes, slow)
+
+	dif := make([]float64, len(closes))
+	for i := range closes {
+		dif[i] = emaFast[i] - emaSlow[i]
+	}
+	dea := EMA(dif, signal)
+
+	hist := make([]float64, len(closes))
+	for i := range closes {
+		hist[i] = 2 * (dif[i] - dea[i])
+	}
+	return MACDSeries{DIF: dif, DEA: dea, Hist: hist}
+}
+
+// BOLLSeries 布林带序列
+type BOLLSeries struct {
+	Upper []float64
+	Mid   []float64
+	Lower []float64
+}
+
+// BOLL 计算布林带：中轨 = MA(period)，上下轨 = 中轨 ± width × 标准差（总体标准差）
+func BOLL(closes []float64, period int, width float64) BOLLSeries {
+	mid := SMA(closes, period)
+	upper := newSeries(len(closes))
+	lower := newSeries(len(closes))
+	for i := range closes {
+		if math.IsNaN(mid[i]) {
+			continue
+		}
+		var variance float64
+		for _, v := range closes[i-period+1 : i+1] {
+			variance += (v - mid[i]) * (v - mid[i])
+		}
+		std := math.Sqrt(variance / float64(period))
+		upper[i] = mid[i] + width*std
+		lower[i] = mid[i] - width*std
+	}
+	return BOLLSeries{Upper: upper, Mid: mid, Lower: lower}
+}
@@ -0,0 +1,72 @@
+package indicator
+
+import "math"
+
+// TrueRange 真实波幅 TR = max(H-L, |H-Cp|, |L-Cp|)，首根K线 TR = H-L
+func TrueRange(bars []Bar) []float64 {
+	trs := make([]float64, len(bars))
+	for i, bar := range bars {
+		tr := bar.High - bar.Low
+		if i > 0 {
+			prevClose := bars[i-1].Close
+			tr = math.Max(tr, math.Max(math.Abs(bar.High-prevClose), math.Abs(bar.Low-prevClose)))
+		}
+		trs[i] = tr
+	}
+	return trs
+}
+
+// ATR 平均真实波幅
+// 以前 period 个 TR 的均值为初值（位于第 period-1 根），之后按 Wilder 平滑：ATR = (ATR' × (N-1) + TR) / N
+func ATR(bars []Bar, period int) []float64 {
+	out := newSeries(len(bars))
+	if period <= 0 || len(bars) < period {
+		return out
+	}
+
+	trs := TrueRange(bars)
+	var value float64
+	for _, tr := range trs[:period] {
+		value += tr
+	}
+	value /= float64(period)
+	out[period-1] = value
+
+	for i := period; i < len(trs); i++ {
+		value = (value*float64(period-1) + trs[i]) / float64(period)
+		out[i] = value
+	}
+	return out
+}
+
+// VWAP 成交量加权平均价，从首根K线起累计：Σ(典型价 × 成交量) / Σ成交量，典型价 = (H+L+C)/3
+// 用于分钟K线时即为当日均价线；成交量为 0 之前的值为 NaN
+func VWAP(bars []Bar) []float64 {
+	out := newSeries(len(bars))
+	var pv, volume float64
+	for i, bar := range bars {
+		typical := (bar.High + bar.Low + bar.Close) / 3
+		pv += typical * bar.Volume
+		volume += bar.Volume
+		if volume > 0 {
+			out[i] = pv / volume
+		}
+	}
+	return out
+}
+
+// OBV 能量潮：收盘上涨累加成交量，下跌累减，持平不变，首根为 0
+func OBV(bars []Bar) []float64 {
+	out := make([]float64, len(bars))
+	for i := 1; i < len(bars); i++ {
+		switch {
+		case bars[i].Close > bars[i-1].Close:
+			out[i] = out[i-1] + bars[i].Volume
+		case bars[i].Close < bars[i-1].Close:
+			out[i] = out[i-1] - bars[i].Volume
+		default:
+			out[i] = out[i-1]
+		}
+	}
+	return out
+}
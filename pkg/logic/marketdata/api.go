@@ -0,0 +1,92 @@
+package marketdata
+
+import (
+	"fmt"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"msa/pkg/config"
+	"msa/pkg/model"
+)
+
+// MarketDataProvider 行情数据源接口
+// 股票工具只通过该接口获取行情，不直接访问具体厂商的接口
+type MarketDataProvider interface {
+	// GetSource 获取数据源标识
+	GetSource() model.MarketDataSource
+
+	// GetQuote 获取实时行情（含当日分时原始数据）
+	GetQuote(stockCode string) (*model.StockCurrentResp, error)
+
+	// GetQuotes 批量获取行情快照，返回 map[stockCode]quote；获取失败的股票不在结果中
+	GetQuotes(stockCodes []string) (map[string]*model.StockQuote, error)
+
+	// GetKLine 获取历史K线，period: day/week/month，adjust: qfq/hfq/空（不复权）
+	GetKLine(stockCode, period string, count int, adjust string) ([]model.KLineBar, error)
+
+	// GetMinuteBars 获取当日分钟K线（成交量、成交额已差分）
+	GetMinuteBars(stockCode string) (*model.StockMinuteKResp, error)
+
+	// GetIndustry 获取行业分类、主要财务指标与公司简介
+	GetIndustry(stockCode string) (*model.StockIndustryResp, error)
+
+	// GetBoardRank 获取板块排行，boardType: 01 申万行业/02 概念/03 地域，order: 0 降序/1 升序
+	GetBoardRank(boardType string, order int, count int) ([]model.BoardItem, error)
+
+	// GetBoardStocks 获取板块成分股，boardCode 为板块排行返回的 bd_code，按涨跌幅降序返回前 count 只
+	GetBoardStocks(boardCode string, count int) ([]model.BoardStock, error)
+}
+
+var (
+	providerMap = map[model.MarketDataSource]MarketDataProvider{}
+
+	overrideMu sync.RWMutex
+	override   MarketDataProvider
+)
+
+// RegisterProvider 注册行情数据源
+func RegisterProvider(source model.MarketDataSource, p MarketDataProvider) {
+	providerMap[source] = p
+	log.Infof("market data provider registered: %v", source)
+}
+
+// SetProvider 指定当前使用的行情数据源，优先于配置；传 nil 恢复按配置选择
+// 用于测试与回放场景注入数据源，返回之前指定的数据源（未指定时为 nil），便于恢复
+func SetProvider(p MarketDataProvider) MarketDataProvider {
+	overrideMu.Lock()
+	defer overrideMu.Unlock()
+	prev := override
+	override = p
+	return prev
+}
+
+// GetProvider 获取当前行情数据源
+// 优先使用 SetProvider 指定的数据源，否则按配置 marketData.source 选择，未配置时使用 tencent
+func GetProvider() (MarketDataProvider, error) {
+	overrideMu.RLock()
+	p := override
+	overrideMu.RUnlock()
+	if p != nil {
+		return p, nil
+	}
+
+	source := model.MarketDataTencent
+	if cfg := config.GetLocalStoreConfig(); cfg != nil && cfg.MarketData != nil && cfg.MarketData.Source != "" {
+		source = cfg.MarketData.Source
+	}
+
+	p, ok := providerMap[source]
+	if !ok {
+		return nil, fmt.Errorf("unknown market data source: %s", source)
+	}
+	return p, nil
+}
+
+// GetRegisteredSources 获取所有已注册的行情数据源
+func GetRegisteredSources() []model.MarketDataSource {
+	sources := make([]model.MarketDataSource, 0, len(providerMap))
+	for s := range providerMap {
+		sources = append(sources, s)
+	}
+	return sources
+}
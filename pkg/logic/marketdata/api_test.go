@@ -0,0 +1,51 @@
+package marketdata
+
+import (
+	"testing"
+
+	"msa/pkg/logic/marketdata/local"
+	"msa/pkg/model"
+)
+
+func TestRegisteredSources(t *testing.T) {
+	registered := map[model.MarketDataSource]bool{}
+	for _, s := range GetRegisteredSources() {
+		registered[s] = true
+	}
+	if !registered[model.MarketDataTencent] || !registered[model.MarketDataLocal] {
+		t.Errorf("Expected tencent and local registered, got %v", GetRegisteredSources())
+	}
+}
+
+func TestGetProvider_Default(t *testing.T) {
+	// 使用空的用户目录，避免读取本机配置
+	t.Setenv("HOME", t.TempDir())
+
+	p, err := GetProvider()
+	if err != nil {
+		t.Fatalf("GetProvider failed: %v", err)
+	}
+	if p.GetSource() != model.MarketDataTencent {
+		t.Errorf("Expected default source tencent, got %s", p.GetSource())
+	}
+}
+
+func TestSetProvider(t *testing.T) {
+	fixture := &local.LocalProvider{Dir: t.TempDir()}
+	SetProvider(fixture)
+	defer SetProvider(nil)
+
+	p, err := GetProvider()
+	if err != nil {
+		t.Fatalf("GetProvider failed: %v", err)
+	}
+	if p != fixture {
+		t.Error("Expected overridden provider")
+	}
+
+	SetProvider(nil)
+	p, _ = GetProvider()
+	if p == fixture {
+		t.Error("Expected provider reset to configured source")
+	}
+}
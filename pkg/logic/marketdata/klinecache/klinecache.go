@@ -0,0 +1,199 @@
+package klinecache
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"msa/pkg/db"
+	"msa/pkg/logic/calendar"
+	"msa/pkg/logic/marketdata"
+	"msa/pkg/model"
+)
+
+// MaxBars 单次向数据源请求的最大K线数
+const MaxBars = 640
+
+// overlapBars 增量同步时与缓存重叠的K线数
+// 重叠部分用于校验前复权价格是否因除权除息而整体变化，并覆盖盘中未走完的最新K线
+const overlapBars = 2
+
+// SyncMode 同步方式
+type SyncMode string
+
+const (
+	// SyncModeFresh 缓存仍然有效，未请求数据源
+	SyncModeFresh SyncMode = "fresh"
+	// SyncModeIncremental 只请求缓存最新日期之后的K线
+	SyncModeIncremental SyncMode = "incremental"
+	// SyncModeFull 全量请求并替换缓存
+	SyncModeFull SyncMode = "full"
+)
+
+// SyncResult 同步结果
+type SyncResult struct {
+	Mode    SyncMode           // 同步方式
+	Fetched int                // 本次从数据源获取的K线数
+	Series  *model.KLineSeries // 同步后的缓存序列
+}
+
+// GetKLine 获取历史K线，优先使用本地缓存，只向数据源请求缓存之后的新K线
+// 数据库不可用或使用本地、回放数据源时直接读取数据源；同步失败但缓存中有数据时返回缓存数据
+func GetKLine(database *gorm.DB, provider marketdata.MarketDataProvider, stockCode, period string, count int, adjust string, now time.Time) ([]model.KLineBar, error) {
+	if database == nil || provider.GetSource() == model.MarketDataLocal || provider.GetSource() == model.MarketDataReplay {
+		return provider.GetKLine(stockCode, period, count, adjust)
+	}
+
+	if _, err := Sync(database, provider, stockCode, period, count, adjust, now); err != nil {
+		cached, cacheErr := db.GetKLineBars(database, stockCode, period, adjust, count)
+		if cacheErr != nil || len(cached) == 0 {
+			return nil, err
+		}
+		log.Warnf("K线同步失败，使用缓存数据: %s %s, %v", stockCode, period, err)
+		return cached, nil
+	}
+
+	return db.GetKLineBars(database, stockCode, period, adjust, count)
+}
+
+// Sync 同步K线缓存，保证缓存至少包含最近 count 根K线（上市不足 count 根时为全部K线）
+// 缓存获取后没有经过交易时段时不请求数据源；缓存数量不足、与最新K线之间间隔过大或复权价格变化时全量替换
+func Sync(database *gorm.DB, provider marketdata.MarketDataProvider, stockCode, period string, count int, adjust string, now time.Time) (*SyncResult, error) {
+	if stockCode == "" {
+		return nil, fmt.Errorf("stock code is empty")
+	}
+	if count <= 0 || count > MaxBars {
+		count = MaxBars
+	}
+
+	series, err := db.GetKLineSeries(database, stockCode, period, adjust)
+	if err != nil {
+		return nil, err
+	}
+
+	enough := series != nil && (series.BarCount >= count || series.HistoryComplete)
+	if enough && calendar.NoTradingBetween(calendar.MarketOf(stockCode), series.SyncedAt, now) {
+		return &SyncResult{Mode: SyncModeFresh, Series: series}, nil
+	}
+
+	if enough {
+		result, err := syncIncremental(database, provider, series, now)
+		if err != nil {
+			return nil, err
+		}
+		if result != nil {
+			return result, nil
+		}
+	}
+
+	fetchCount := count
+	if series != nil {
+		fetchCount = min(max(count, series.BarCount), MaxBars)
+	} else {
+		series = &model.KLineSeries{StockCode: stockCode, Period: period, Adjust: adjust}
+	}
+	return syncFull(database, provider, series, fetchCount, now)
+}
+
+// syncIncremental 增量同步，需要全量同步时返回 nil
+func syncIncremental(database *gorm.DB, provider marketdata.MarketDataProvider, series *model.KLineSeries, now time.Time) (*SyncResult, error) {
+	fetchCount := estimateNewBars(series.Period, series.LastDate, now) + overlapBars
+	if fetchCount > MaxBars {
+		log.Infof("K线缓存间隔过大，全量同步: %s %s, 最新缓存=%s", series.StockCode, series.Period, series.LastDate)
+		return nil, nil
+	}
+
+	bars, err := provider.GetKLine(series.StockCode, series.Period, fetchCount, series.Adjust)
+	if err != nil {
+		return nil, err
+	}
+
+	cached, err := db.GetKLineBars(database, series.StockCode, series.Period, series.Adjust, fetchCount)
+	if err != nil {
+		return nil, err
+	}
+	cachedClose := make(map[string]string, len(cached))
+	for _, bar := range cached {
+		cachedClose[bar.Date] = bar.Close
+	}
+
+	// 以缓存最新日期之前、新旧数据都有的最后一根K线为锚点，替换锚点之后的缓存
+	// 周K、月K未走完时日期随交易日后移，因此不能只按缓存最新日期覆盖
+	anchor := -1
+	for i, bar := range bars {
+		if bar.Date >= series.LastDate {
+			break
+		}
+		c, ok := cachedClose[bar.Date]
+		if !ok {
+			continue
+		}
+		// 已走完的K线收盘价变化说明复权价格整体调整，缓存失效
+		if c != bar.Close {
+			log.Infof("复权价格已变化，全量同步: %s %s, %s 收盘 %s -> %s", series.StockCode, series.Period, bar.Date, c, bar.Close)
+			return nil, nil
+		}
+		anchor = i
+	}
+	if anchor < 0 {
+		log.Infof("K线缓存与新数据不连续，全量同步: %s %s", series.StockCode, series.Period)
+		return nil, nil
+	}
+	newBars := bars[anchor+1:]
+
+	series.SyncedAt = now
+	err = database.Transaction(func(tx *gorm.DB) error {
+		return db.SaveKLineBars(tx, series, newBars, bars[anchor].Date)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("K线缓存增量同步: %s %s, 新增/更新 %d 根, 最新=%s", series.StockCode, series.Period, len(newBars), series.LastDate)
+	return &SyncResult{Mode: SyncModeIncremental, Fetched: len(bars), Series: series}, nil
+}
+
+// syncFull 全量获取 fetchCount 根K线并替换缓存
+func syncFull(database *gorm.DB, provider marketdata.MarketDataProvider, series *model.KLineSeries, fetchCount int, now time.Time) (*SyncResult, error) {
+	bars, err := provider.GetKLine(series.StockCode, series.Period, fetchCount, series.Adjust)
+	if err != nil {
+		return nil, err
+	}
+
+	// 数据源返回的K线少于请求数量，说明已包含上市以来的全部K线
+	series.HistoryComplete = len(bars) < fetchCount
+	series.SyncedAt = now
+	err = database.Transaction(func(tx *gorm.DB) error {
+		return db.SaveKLineBars(tx, series, bars, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("K线缓存全量同步: %s %s, %d 根, %s ~ %s", series.StockCode, series.Period, series.BarCount, series.FirstDate, series.LastDate)
+	return &SyncResult{Mode: SyncModeFull, Fetched: len(bars), Series: series}, nil
+}
+
+// estimateNewBars 估算 lastDate 之后新增的K线数（按自然日向上取整，不少于实际数量）
+func estimateNewBars(period, lastDate string, now time.Time) int {
+	last, err := time.ParseInLocation("2006-01-02", lastDate, now.Location())
+	if err != nil {
+		return MaxBars + 1
+	}
+	days := int(math.Ceil(now.Sub(last).Hours() / 24))
+	if days < 0 {
+		days = 0
+	}
+
+	switch period {
+	case "week":
+		return days/7 + 1
+	case "month":
+		return days/28 + 1
+	default:
+		return days
+	}
+}
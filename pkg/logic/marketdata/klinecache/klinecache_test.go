@@ -0,0 +1,179 @@
+package klinecache
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"msa/pkg/db"
+	"msa/pkg/logic/marketdata"
+	"msa/pkg/model"
+)
+
+var cst = time.FixedZone("CST", 8*3600)
+
+// fakeProvider 记录K线请求数量的数据源
+type fakeProvider struct {
+	marketdata.MarketDataProvider
+	source model.MarketDataSource
+	bars   []model.KLineBar
+	err    error
+	counts []int
+}
+
+func (p *fakeProvider) GetSource() model.MarketDataSource {
+	if p.source == "" {
+		return model.MarketDataTencent
+	}
+	return p.source
+}
+
+func (p *fakeProvider) GetKLine(stockCode, period string, count int, adjust string) ([]model.KLineBar, error) {
+	p.counts = append(p.counts, count)
+	if p.err != nil {
+		return nil, p.err
+	}
+	bars := p.bars
+	if count > 0 && len(bars) > count {
+		bars = bars[len(bars)-count:]
+	}
+	return append([]model.KLineBar(nil), bars...), nil
+}
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	database, err := db.InitDBWithPath(filepath.Join(t.TempDir(), "test.sqlite"))
+	if err != nil {
+		t.Fatalf("InitDBWithPath failed: %v", err)
+	}
+	if err := db.Migrate(database); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	t.Cleanup(func() { db.CloseDB(database) })
+	return database
+}
+
+func dailyBar(date string, closePrice float64) model.KLineBar {
+	c := fmt.Sprintf("%.2f", closePrice)
+	return model.KLineBar{Date: date, Open: c, Close: c, High: c, Low: c, Volume: "100"}
+}
+
+func TestGetKLine_IncrementalRefresh(t *testing.T) {
+	database := setupTestDB(t)
+	dates := []string{"2025-02-24", "2025-02-25", "2025-02-26", "2025-02-27", "2025-02-28", "2025-03-03"}
+	provider := &fakeProvider{}
+	for i, d := range dates {
+		provider.bars = append(provider.bars, dailyBar(d, 10+float64(i)))
+	}
+
+	// 首次请求全量获取
+	bars, err := GetKLine(database, provider, "sh600519", "day", 5, "qfq", time.Date(2025, 3, 3, 16, 0, 0, 0, cst))
+	if err != nil {
+		t.Fatalf("GetKLine failed: %v", err)
+	}
+	if len(bars) != 5 || bars[0].Date != "2025-02-25" || bars[4].Date != "2025-03-03" {
+		t.Errorf("Unexpected bars: %+v", bars)
+	}
+
+	// 收盘后再次请求，缓存仍然有效
+	if _, err := GetKLine(database, provider, "sh600519", "day", 5, "qfq", time.Date(2025, 3, 3, 20, 0, 0, 0, cst)); err != nil {
+		t.Fatalf("GetKLine failed: %v", err)
+	}
+	if len(provider.counts) != 1 {
+		t.Errorf("Expected cached read without request, got requests %v", provider.counts)
+	}
+
+	// 需要更多K线时全量获取；返回数量不足说明已是全部历史
+	result, err := Sync(database, provider, "sh600519", "day", 10, "qfq", time.Date(2025, 3, 3, 20, 0, 0, 0, cst))
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if result.Mode != SyncModeFull || !result.Series.HistoryComplete || result.Series.BarCount != 6 {
+		t.Errorf("Unexpected full sync: mode=%s series=%+v", result.Mode, result.Series)
+	}
+
+	// 次日收盘后只请求新增K线
+	provider.bars = append(provider.bars, dailyBar("2025-03-04", 16))
+	result, err = Sync(database, provider, "sh600519", "day", 10, "qfq", time.Date(2025, 3, 4, 15, 30, 0, 0, cst))
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if result.Mode != SyncModeIncremental || result.Series.BarCount != 7 || result.Series.LastDate != "2025-03-04" {
+		t.Errorf("Unexpected incremental sync: mode=%s series=%+v", result.Mode, result.Series)
+	}
+	if last := provider.counts[len(provider.counts)-1]; last > 5 {
+		t.Errorf("Expected small incremental request, got count %d", last)
+	}
+
+	// 除权后前复权价格整体变化，全量替换
+	for i := range provider.bars {
+		provider.bars[i] = dailyBar(provider.bars[i].Date, 5+float64(i)/2)
+	}
+	result, err = Sync(database, provider, "sh600519", "day", 10, "qfq", time.Date(2025, 3, 5, 10, 0, 0, 0, cst))
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if result.Mode != SyncModeFull {
+		t.Errorf("Expected full sync after adjustment change, got %s", result.Mode)
+	}
+	cached, _ := db.GetKLineBars(database, "sh600519", "day", "qfq", 0)
+	if len(cached) != 7 || cached[0].Close != "5.00" {
+		t.Errorf("Expected re-adjusted bars, got %+v", cached)
+	}
+
+	// 数据源不可用时返回缓存
+	provider.err = fmt.Errorf("network down")
+	bars, err = GetKLine(database, provider, "sh600519", "day", 3, "qfq", time.Date(2025, 3, 6, 10, 0, 0, 0, cst))
+	if err != nil || len(bars) != 3 || bars[2].Date != "2025-03-04" {
+		t.Errorf("Expected cached bars on provider error, got %+v, %v", bars, err)
+	}
+	if _, err := GetKLine(database, provider, "sz000001", "day", 3, "qfq", time.Date(2025, 3, 6, 10, 0, 0, 0, cst)); err == nil {
+		t.Error("Expected error without cache")
+	}
+}
+
+func TestSync_PartialWeekBar(t *testing.T) {
+	database := setupTestDB(t)
+	provider := &fakeProvider{bars: []model.KLineBar{
+		dailyBar("2025-02-21", 10), dailyBar("2025-02-28", 11), dailyBar("2025-03-03", 12),
+	}}
+	if _, err := Sync(database, provider, "sh600519", "week", 3, "qfq", time.Date(2025, 3, 3, 16, 0, 0, 0, cst)); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+
+	// 本周K线日期随交易日后移，旧的未走完K线被替换
+	provider.bars[2] = dailyBar("2025-03-04", 12.5)
+	result, err := Sync(database, provider, "sh600519", "week", 3, "qfq", time.Date(2025, 3, 4, 16, 0, 0, 0, cst))
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if result.Mode != SyncModeIncremental {
+		t.Errorf("Expected incremental sync, got %s", result.Mode)
+	}
+	cached, _ := db.GetKLineBars(database, "sh600519", "week", "qfq", 0)
+	if len(cached) != 3 || cached[2].Date != "2025-03-04" || cached[1].Date != "2025-02-28" {
+		t.Errorf("Unexpected weekly bars: %+v", cached)
+	}
+}
+
+func TestGetKLine_BypassCache(t *testing.T) {
+	database := setupTestDB(t)
+	provider := &fakeProvider{source: model.MarketDataLocal, bars: []model.KLineBar{dailyBar("2025-03-03", 10)}}
+
+	bars, err := GetKLine(database, provider, "sh600519", "day", 5, "qfq", time.Now())
+	if err != nil || len(bars) != 1 {
+		t.Fatalf("GetKLine failed: %+v, %v", bars, err)
+	}
+	if series, _ := db.GetKLineSeries(database, "sh600519", "day", "qfq"); series != nil {
+		t.Errorf("Expected local source not cached, got %+v", series)
+	}
+
+	// 数据库不可用时直接读取数据源
+	provider.source = ""
+	if bars, err := GetKLine(nil, provider, "sh600519", "day", 5, "qfq", time.Now()); err != nil || len(bars) != 1 {
+		t.Errorf("Expected direct read without database, got %+v, %v", bars, err)
+	}
+}
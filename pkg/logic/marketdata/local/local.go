@@ -0,0 +1,297 @@
+package local
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"msa/pkg/config"
+	"msa/pkg/model"
+)
+
+// DefaultDirName 默认数据目录（位于 ~/.msa 下）
+const DefaultDirName = "marketdata"
+
+// LocalProvider 本地文件行情数据源
+// 读取录制好的 JSON/CSV 行情文件，用于离线运行、测试与回放。目录结构：
+//
+//	quote/<code>.json                    实时行情（get_stock_quote 返回的 data）
+//	kline/<code>_<period>[_<adjust>].json K线数组，或同名 .csv（date,open,close,high,low,volume）
+//	minute/<code>.json                   分钟K线（get_stock_minute_k 返回的 data），或 .csv（time,price,volume,turnover）
+//	industry/<code>.json                 行业分类接口原始响应
+//	board_rank/<board_type>.json         板块排行数组
+//	board_stocks/<board_code>.json       板块成分股数组
+//
+// 缺少分钟K线文件时，使用行情文件中的分时数据生成
+type LocalProvider struct {
+	// Dir 数据目录，为空时使用配置 marketData.dir，再为空时使用 ~/.msa/marketdata
+	Dir string
+}
+
+// GetSource 获取数据源标识
+func (p *LocalProvider) GetSource() model.MarketDataSource {
+	return model.MarketDataLocal
+}
+
+// GetQuote 读取实时行情
+func (p *LocalProvider) GetQuote(stockCode string) (*model.StockCurrentResp, error) {
+	if stockCode == "" {
+		return nil, fmt.Errorf("stock code is empty")
+	}
+
+	var quote model.StockCurrentResp
+	if err := p.readJSON(&quote, "quote", stockCode+".json"); err != nil {
+		return nil, fmt.Errorf("no recorded quote for %s: %w", stockCode, err)
+	}
+	return &quote, nil
+}
+
+// GetQuotes 批量读取行情快照，缺少录制文件的股票不在结果中
+func (p *LocalProvider) GetQuotes(stockCodes []string) (map[string]*model.StockQuote, error) {
+	quotes := make(map[string]*model.StockQuote, len(stockCodes))
+	for _, code := range stockCodes {
+		resp, err := p.GetQuote(code)
+		if err != nil {
+			log.Warnf("local market data: %v", err)
+			continue
+		}
+		quotes[code] = resp.ToStockQuote(code)
+	}
+	return quotes, nil
+}
+
+// GetKLine 读取历史K线，返回最近 count 根
+// 优先读取对应复权方式的文件，不存在时读取不区分复权的文件
+func (p *LocalProvider) GetKLine(stockCode, period string, count int, adjust string) ([]model.KLineBar, error) {
+	if stockCode == "" {
+		return nil, fmt.Errorf("stock code is empty")
+	}
+
+	names := []string{fmt.Sprintf("%s_%s", stockCode, period)}
+	if adjust != "" {
+		names = append([]string{fmt.Sprintf("%s_%s_%s", stockCode, period, adjust)}, names...)
+	}
+
+	for _, name := range names {
+		var bars []model.KLineBar
+		err := p.readJSON(&bars, "kline", name+".json")
+		if os.IsNotExist(err) {
+			bars, err = p.readKLineCSV(name + ".csv")
+		}
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(bars) == 0 {
+			return nil, fmt.Errorf("no K-line data found")
+		}
+		if count > 0 && len(bars) > count {
+			bars = bars[len(bars)-count:]
+		}
+		return bars, nil
+	}
+	return nil, fmt.Errorf("no recorded K-line data for %s (%s) in %s", stockCode, period, p.dir())
+}
+
+// GetMinuteBars 读取当日分钟K线
+func (p *LocalProvider) GetMinuteBars(stockCode string) (*model.StockMinuteKResp, error) {
+	if stockCode == "" {
+		return nil, fmt.Errorf("stock code is empty")
+	}
+
+	var resp model.StockMinuteKResp
+	err := p.readJSON(&resp, "minute", stockCode+".json")
+	if err == nil {
+		resp.StockCode = stockCode
+		resp.Count = len(resp.Bars)
+		return &resp, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	bars, err := p.readMinuteCSV(stockCode + ".csv")
+	if err == nil {
+		return &model.StockMinuteKResp{StockCode: stockCode, Bars: bars, Count: len(bars)}, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	// 使用行情文件中的分时数据
+	quote, err := p.GetQuote(stockCode)
+	if err != nil {
+		return nil, err
+	}
+	return model.ParseMinuteBars(stockCode, quote.Date, quote.Data), nil
+}
+
+// GetIndustry 读取行业分类
+func (p *LocalProvider) GetIndustry(stockCode string) (*model.StockIndustryResp, error) {
+	if stockCode == "" {
+		return nil, fmt.Errorf("stock code is empty")
+	}
+
+	var resp model.StockIndustryResp
+	if err := p.readJSON(&resp, "industry", stockCode+".json"); err != nil {
+		return nil, fmt.Errorf("no recorded industry data for %s: %w", stockCode, err)
+	}
+	return &resp, nil
+}
+
+// GetBoardRank 读取板块排行，按涨跌幅排序后返回前 count 个
+func (p *LocalProvider) GetBoardRank(boardType string, order int, count int) ([]model.BoardItem, error) {
+	var items []model.BoardItem
+	if err := p.readJSON(&items, "board_rank", boardType+".json"); err != nil {
+		return nil, fmt.Errorf("no recorded board rank for %s: %w", boardType, err)
+	}
+
+	changeOf := func(item model.BoardItem) float64 {
+		v, _ := strconv.ParseFloat(item.BdZdf, 64)
+		return v
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if order == 1 {
+			return changeOf(items[i]) < changeOf(items[j])
+		}
+		return changeOf(items[i]) > changeOf(items[j])
+	})
+
+	if count > 0 && len(items) > count {
+		items = items[:count]
+	}
+	return items, nil
+}
+
+// GetBoardStocks 读取板块成分股，按涨跌幅降序返回前 count 只
+func (p *LocalProvider) GetBoardStocks(boardCode string, count int) ([]model.BoardStock, error) {
+	var stocks []model.BoardStock
+	if err := p.readJSON(&stocks, "board_stocks", boardCode+".json"); err != nil {
+		return nil, fmt.Errorf("no recorded board stocks for %s: %w", boardCode, err)
+	}
+
+	changeOf := func(stock model.BoardStock) float64 {
+		v, _ := strconv.ParseFloat(stock.Zdf, 64)
+		return v
+	}
+	sort.SliceStable(stocks, func(i, j int) bool {
+		return changeOf(stocks[i]) > changeOf(stocks[j])
+	})
+
+	if count > 0 && len(stocks) > count {
+		stocks = stocks[:count]
+	}
+	return stocks, nil
+}
+
+// dir 获取数据目录
+func (p *LocalProvider) dir() string {
+	if p.Dir != "" {
+		return p.Dir
+	}
+	if cfg := config.GetLocalStoreConfig(); cfg != nil && cfg.MarketData != nil && cfg.MarketData.Dir != "" {
+		return expandHome(cfg.MarketData.Dir)
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return DefaultDirName
+	}
+	return filepath.Join(homeDir, ".msa", DefaultDirName)
+}
+
+// readJSON 读取 JSON 文件；文件不存在时返回的错误满足 os.IsNotExist
+func (p *LocalProvider) readJSON(v interface{}, elem ...string) error {
+	path := filepath.Join(append([]string{p.dir()}, elem...)...)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("JSON解析失败 %s: %w", path, err)
+	}
+	log.Debugf("local market data loaded: %s", path)
+	return nil
+}
+
+// readCSV 读取 CSV 文件，跳过表头
+func (p *LocalProvider) readCSV(elem ...string) ([][]string, error) {
+	path := filepath.Join(append([]string{p.dir()}, elem...)...)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("CSV解析失败 %s: %w", path, err)
+	}
+	if len(records) > 0 && len(records[0]) > 0 && isHeader(records[0][0]) {
+		records = records[1:]
+	}
+	return records, nil
+}
+
+// readKLineCSV 读取K线 CSV：date,open,close,high,low,volume
+func (p *LocalProvider) readKLineCSV(name string) ([]model.KLineBar, error) {
+	records, err := p.readCSV("kline", name)
+	if err != nil {
+		return nil, err
+	}
+	return model.ToKLineBars(records), nil
+}
+
+// readMinuteCSV 读取分钟K线 CSV：time,price,volume,turnover（成交量、成交额为每分钟值）
+func (p *LocalProvider) readMinuteCSV(name string) ([]model.StockMinuteBar, error) {
+	records, err := p.readCSV("minute", name)
+	if err != nil {
+		return nil, err
+	}
+
+	bars := make([]model.StockMinuteBar, 0, len(records))
+	for _, r := range records {
+		if len(r) < 4 {
+			continue
+		}
+		price, err1 := strconv.ParseFloat(r[1], 64)
+		volume, err2 := strconv.ParseInt(r[2], 10, 64)
+		turnover, err3 := strconv.ParseFloat(r[3], 64)
+		if err1 != nil || err2 != nil || err3 != nil {
+			return nil, fmt.Errorf("分钟K线数据格式错误: %v", r)
+		}
+		bars = append(bars, model.StockMinuteBar{Time: r[0], Price: price, Volume: volume, Turnover: turnover})
+	}
+	return bars, nil
+}
+
+// isHeader 判断 CSV 首行是否为表头
+func isHeader(first string) bool {
+	switch strings.ToLower(strings.TrimSpace(first)) {
+	case "date", "time":
+		return true
+	}
+	return false
+}
+
+// expandHome 展开 ~ 开头的路径
+func expandHome(path string) string {
+	if !strings.HasPrefix(path, "~") {
+		return path
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(homeDir, path[1:])
+}
@@ -0,0 +1,149 @@
+package local
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFixture 在数据目录下写入录制文件
+func writeFixture(t *testing.T, dir, rel, content string) {
+	t.Helper()
+	path := filepath.Join(dir, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("mkdir failed: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("write fixture failed: %v", err)
+	}
+}
+
+func TestLocalProvider_Quote(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, "quote/sh600519.json", `{
+		"date": "20250303",
+		"current_price": "10.50",
+		"prev_close": "10.00",
+		"data": ["0930 10.10 100 101000.00", "0931 10.20 250 254000.00"]
+	}`)
+	p := &LocalProvider{Dir: dir}
+
+	quote, err := p.GetQuote("sh600519")
+	if err != nil {
+		t.Fatalf("GetQuote failed: %v", err)
+	}
+	if quote.CurrentPrice != "10.50" || quote.PrevClose != "10.00" {
+		t.Errorf("Unexpected quote: %+v", quote)
+	}
+
+	if _, err := p.GetQuote("sz000001"); err == nil {
+		t.Error("Expected error for missing quote")
+	}
+
+	// 批量行情跳过缺少录制文件的股票，并计算涨跌幅
+	quotes, err := p.GetQuotes([]string{"sh600519", "sz000001"})
+	if err != nil {
+		t.Fatalf("GetQuotes failed: %v", err)
+	}
+	if len(quotes) != 1 || quotes["sh600519"].ChangePercent != "5.00" || quotes["sh600519"].Change != "0.50" {
+		t.Errorf("Unexpected quotes: %+v", quotes)
+	}
+
+	// 无分钟K线文件时使用行情分时数据，成交量差分
+	minute, err := p.GetMinuteBars("sh600519")
+	if err != nil {
+		t.Fatalf("GetMinuteBars failed: %v", err)
+	}
+	if minute.Count != 2 || minute.Date != "20250303" || minute.Bars[1].Volume != 150 {
+		t.Errorf("Unexpected minute bars: %+v", minute)
+	}
+}
+
+func TestLocalProvider_MinuteCSV(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, "minute/sh600519.csv", "time,price,volume,turnover\n0930,10.1,100,101000\n0931,10.2,150,153000\n")
+	p := &LocalProvider{Dir: dir}
+
+	minute, err := p.GetMinuteBars("sh600519")
+	if err != nil {
+		t.Fatalf("GetMinuteBars failed: %v", err)
+	}
+	if minute.Count != 2 || minute.Bars[1].Time != "0931" || minute.Bars[1].Price != 10.2 || minute.Bars[1].Volume != 150 {
+		t.Errorf("Unexpected minute bars: %+v", minute)
+	}
+}
+
+func TestLocalProvider_KLine(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, "kline/sh600519_day.csv", "date,open,close,high,low,volume\n"+
+		"2025-03-03,10,10.5,10.8,9.9,1000\n"+
+		"2025-03-04,10.5,10.2,10.6,10.1,800\n"+
+		"2025-03-05,10.2,10.9,11,10.2,1200\n")
+	writeFixture(t, dir, "kline/sh600519_day_hfq.json", `[{"date":"2025-03-05","open":"20","close":"21","high":"22","low":"19","volume":"1200"}]`)
+	p := &LocalProvider{Dir: dir}
+
+	// 只返回最近 count 根
+	bars, err := p.GetKLine("sh600519", "day", 2, "qfq")
+	if err != nil {
+		t.Fatalf("GetKLine failed: %v", err)
+	}
+	if len(bars) != 2 || bars[0].Date != "2025-03-04" || bars[1].Close != "10.9" {
+		t.Errorf("Unexpected bars: %+v", bars)
+	}
+
+	// 优先使用对应复权方式的文件
+	bars, err = p.GetKLine("sh600519", "day", 30, "hfq")
+	if err != nil {
+		t.Fatalf("GetKLine failed: %v", err)
+	}
+	if len(bars) != 1 || bars[0].Close != "21" {
+		t.Errorf("Expected hfq bars, got %+v", bars)
+	}
+
+	if _, err := p.GetKLine("sh600519", "week", 30, "qfq"); err == nil {
+		t.Error("Expected error for missing week K-line")
+	}
+}
+
+func TestLocalProvider_BoardRank(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, "board_rank/01.json", `[
+		{"bd_name": "银行", "bd_zdf": "0.50"},
+		{"bd_name": "半导体", "bd_zdf": "3.20"},
+		{"bd_name": "煤炭", "bd_zdf": "-1.10"}
+	]`)
+	p := &LocalProvider{Dir: dir}
+
+	items, err := p.GetBoardRank("01", 0, 2)
+	if err != nil {
+		t.Fatalf("GetBoardRank failed: %v", err)
+	}
+	if len(items) != 2 || items[0].BdName != "半导体" || items[1].BdName != "银行" {
+		t.Errorf("Unexpected descending rank: %+v", items)
+	}
+
+	items, _ = p.GetBoardRank("01", 1, 20)
+	if len(items) != 3 || items[0].BdName != "煤炭" {
+		t.Errorf("Unexpected ascending rank: %+v", items)
+	}
+}
+
+func TestLocalProvider_BoardStocks(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, "board_stocks/pt01801081.json", `[
+		{"code": "sh688981", "name": "中芯国际", "zdf": "1.20"},
+		{"code": "sh603501", "name": "韦尔股份", "zdf": "4.50"}
+	]`)
+	p := &LocalProvider{Dir: dir}
+
+	stocks, err := p.GetBoardStocks("pt01801081", 1)
+	if err != nil {
+		t.Fatalf("GetBoardStocks failed: %v", err)
+	}
+	if len(stocks) != 1 || stocks[0].Code != "sh603501" {
+		t.Errorf("Unexpected board stocks: %+v", stocks)
+	}
+	if _, err := p.GetBoardStocks("pt000000", 10); err == nil {
+		t.Error("Expected error for missing board")
+	}
+}
@@ -0,0 +1,16 @@
+package marketdata
+
+import (
+	"msa/pkg/logic/marketdata/local"
+	"msa/pkg/logic/marketdata/tencent"
+	"msa/pkg/model"
+)
+
+var _ MarketDataProvider = (*tencent.TencentProvider)(nil)
+
+var _ MarketDataProvider = (*local.LocalProvider)(nil)
+
+func init() {
+	RegisterProvider(model.MarketDataTencent, &tencent.TencentProvider{})
+	RegisterProvider(model.MarketDataLocal, &local.LocalProvider{})
+}
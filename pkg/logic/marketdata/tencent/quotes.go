@@ -0,0 +1,129 @@
+package tencent
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"msa/pkg/model"
+	mas_utils "msa/pkg/utils"
+)
+
+const (
+	// quoteBatchSize 单次请求的股票数量
+	quoteBatchSize = 50
+	// quoteConcurrency 同时进行的批量请求数
+	quoteConcurrency = 4
+)
+
+// batchQuoteURL 多股票行情接口地址（测试时替换）
+var batchQuoteURL = model.FinanceBatchQuote
+
+// GetQuotes 批量获取行情快照
+// 按 quoteBatchSize 分批调用多股票行情接口，最多 quoteConcurrency 个请求并发
+// 部分批次失败时返回已获取的结果；全部失败时返回错误
+func (p *TencentProvider) GetQuotes(stockCodes []string) (map[string]*model.StockQuote, error) {
+	quotes := make(map[string]*model.StockQuote, len(stockCodes))
+	if len(stockCodes) == 0 {
+		return quotes, nil
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+		failed   int
+	)
+	sem := make(chan struct{}, quoteConcurrency)
+	batches := 0
+
+	for start := 0; start < len(stockCodes); start += quoteBatchSize {
+		end := start + quoteBatchSize
+		if end > len(stockCodes) {
+			end = len(stockCodes)
+		}
+		batch := stockCodes[start:end]
+		batches++
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(batch []string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			result, err := fetchQuoteBatch(batch)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				log.Errorf("批量获取行情失败: codes=%v, err=%v", batch, err)
+				failed++
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			for code, quote := range result {
+				quotes[code] = quote
+			}
+		}(batch)
+	}
+	wg.Wait()
+
+	if failed == batches {
+		return nil, firstErr
+	}
+	return quotes, nil
+}
+
+// fetchQuoteBatch 调用多股票行情接口
+// 响应为 GBK 编码文本，每行形如 v_sh600519="1~贵州茅台~600519~...";
+// 非 200 响应（限流、服务端错误页）返回错误，避免被当作空批次静默丢弃行情
+func fetchQuoteBatch(stockCodes []string) (map[string]*model.StockQuote, error) {
+	resp, err := mas_utils.GetRestyClient().R().Get(batchQuoteURL + strings.Join(stockCodes, ","))
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return nil, fmt.Errorf("batch quote request failed with status %d", resp.StatusCode())
+	}
+
+	body, err := simplifiedchinese.GBK.NewDecoder().Bytes(resp.Body())
+	if err != nil {
+		// 解码失败时按原始字节解析，仅股票名称可能乱码
+		body = resp.Body()
+	}
+	return parseQuoteResponse(string(body)), nil
+}
+
+// parseQuoteResponse 解析多股票行情接口响应
+func parseQuoteResponse(body string) map[string]*model.StockQuote {
+	quotes := make(map[string]*model.StockQuote)
+	for _, line := range strings.Split(body, ";") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "v_") {
+			continue
+		}
+		eq := strings.Index(line, "=")
+		if eq < 0 {
+			continue
+		}
+		code := line[2:eq]
+		value := strings.Trim(line[eq+1:], `"`)
+		if value == "" || strings.HasPrefix(code, "pv_none_match") {
+			log.Warnf("行情接口未返回数据: %s", code)
+			continue
+		}
+
+		quote, err := model.ParseStockQuote(code, strings.Split(value, "~"))
+		if err != nil {
+			log.Warnf("解析行情失败: %v", err)
+			continue
+		}
+		quotes[code] = quote
+	}
+	return quotes
+}
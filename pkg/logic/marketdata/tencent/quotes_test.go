@@ -0,0 +1,70 @@
+package tencent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// quoteLine 构造行情接口单行响应，fields 为 下标→值
+func quoteLine(code string, fields map[int]string) string {
+	arr := make([]string, 70)
+	for i, v := range fields {
+		arr[i] = v
+	}
+	return "v_" + code + `="` + strings.Join(arr, "~") + `";`
+}
+
+func TestParseQuoteResponse(t *testing.T) {
+	body := quoteLine("sh600519", map[int]string{
+		1: "贵州茅台", 3: "1500.00", 4: "1480.00", 6: "12345",
+		9: "1499.99", 10: "12", 19: "1500.01", 20: "8",
+		30: "20250303103000", 31: "20.00", 32: "1.35", 33: "1510.00", 34: "1478.00",
+		37: "185000.50", 44: "18800.12", 45: "18843.50", 46: "8.12", 47: "1628.00", 48: "1332.00",
+	}) + "\n" + `v_pv_none_match="1";` + "\n"
+
+	quotes := parseQuoteResponse(body)
+	if len(quotes) != 1 {
+		t.Fatalf("Expected 1 quote, got %d", len(quotes))
+	}
+	q := quotes["sh600519"]
+	if q == nil {
+		t.Fatal("Expected quote for sh600519")
+	}
+	if q.StockName != "贵州茅台" || q.CurrentPrice != "1500.00" || q.PrevClose != "1480.00" || q.ChangePercent != "1.35" {
+		t.Errorf("Unexpected quote: %+v", q)
+	}
+	if q.LimitUp != "1628.00" || q.LimitDown != "1332.00" || q.Turnover != "185000.50" {
+		t.Errorf("Unexpected limit/turnover: %+v", q)
+	}
+	if q.FloatCap != "18800.12" || q.MarketCap != "18843.50" || q.PBRatio != "8.12" {
+		t.Errorf("Unexpected market cap/PB: %+v", q)
+	}
+	if len(q.Bids) != 5 || q.Bids[0].Price != "1499.99" || q.Asks[0].Volume != "8" {
+		t.Errorf("Unexpected order book: bids=%+v asks=%+v", q.Bids, q.Asks)
+	}
+}
+
+func TestFetchQuoteBatch_HTTPStatus(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(quoteLine("sh600519", map[int]string{1: "Maotai", 2: "600519", 3: "1500.00", 4: "1490.00"})))
+	}))
+	defer server.Close()
+
+	original := batchQuoteURL
+	batchQuoteURL = server.URL + "/q="
+	defer func() { batchQuoteURL = original }()
+
+	if _, err := fetchQuoteBatch([]string{"sh600519"}); err == nil {
+		t.Error("fetchQuoteBatch() with 503 response should return error")
+	}
+
+	status = http.StatusOK
+	quotes, err := fetchQuoteBatch([]string{"sh600519"})
+	if err != nil || quotes["sh600519"] == nil {
+		t.Errorf("fetchQuoteBatch() = %v, %v, want quote for sh600519", quotes, err)
+	}
+}
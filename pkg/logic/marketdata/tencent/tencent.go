@@ -0,0 +1,210 @@
+package tencent
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"msa/pkg/model"
+	mas_utils "msa/pkg/utils"
+)
+
+// TencentProvider 腾讯证券行情数据源
+type TencentProvider struct{}
+
+// GetSource 获取数据源标识
+func (p *TencentProvider) GetSource() model.MarketDataSource {
+	return model.MarketDataTencent
+}
+
+// GetQuote 获取实时行情
+func (p *TencentProvider) GetQuote(stockCode string) (*model.StockCurrentResp, error) {
+	if stockCode == "" {
+		return nil, fmt.Errorf("stock code is empty")
+	}
+
+	// 发起HTTP请求
+	getResp, err := mas_utils.GetRestyClient().R().Get(model.FinanceSearchCurrentKLine + stockCode)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("API Response: %v", getResp.String())
+
+	// 解析响应
+	var rawResp map[string]interface{}
+	if err := json.Unmarshal(getResp.Body(), &rawResp); err != nil {
+		return nil, fmt.Errorf("JSON解析失败: %w", err)
+	}
+
+	// 检查返回码
+	if code, ok := rawResp["code"].(float64); ok && int(code) != 0 {
+		return nil, fmt.Errorf("API返回错误, code=%d, msg=%v", int(code), rawResp["msg"])
+	}
+
+	// 检查data字段
+	dataRaw, ok := rawResp["data"]
+	if !ok || dataRaw == nil {
+		return nil, fmt.Errorf("请求失败，没有返回数据 data")
+	}
+
+	// 将data转换为map
+	dataMap, ok := dataRaw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("data字段格式错误,期望map[string]interface{}, 实际%T", dataRaw)
+	}
+
+	// 创建StockKLineDataWrapper
+	wrapper := &model.StockKLineDataWrapper{
+		StockData: make(map[string]*model.StockKLineDetail),
+	}
+
+	// 解析每个股票的数据
+	for code, stockData := range dataMap {
+		stockDataBytes, err := json.Marshal(stockData)
+		if err != nil {
+			return nil, fmt.Errorf("序列化股票数据失败: %w", err)
+		}
+
+		var detail model.StockKLineDetail
+		if err := json.Unmarshal(stockDataBytes, &detail); err != nil {
+			return nil, fmt.Errorf("解析股票详细数据失败: %w", err)
+		}
+
+		wrapper.StockData[code] = &detail
+		log.Infof("成功解析股票代码: %s", code)
+	}
+
+	// 获取股票代码并转换为StockCurrentResp
+	actualStockCode := wrapper.GetStockCode()
+	log.Infof("股票代码: %s", actualStockCode)
+
+	stockCurrentResp, err := wrapper.GetStockCurrentResp(actualStockCode)
+	if err != nil {
+		return nil, fmt.Errorf("获取股票行情数据失败: %w", err)
+	}
+	return stockCurrentResp, nil
+}
+
+// GetKLine 获取历史K线
+func (p *TencentProvider) GetKLine(stockCode, period string, count int, adjust string) ([]model.KLineBar, error) {
+	paramStr := fmt.Sprintf("%s,%s,,,%d,%s", stockCode, period, count, adjust)
+	apiURL := model.FinanceKLineAPI + url.QueryEscape(paramStr)
+
+	resp, err := mas_utils.GetRestyClient().R().Get(apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+
+	var klineResp model.StockKLineResp
+	if err := json.Unmarshal(resp.Body(), &klineResp); err != nil {
+		return nil, fmt.Errorf("JSON解析失败: %w", err)
+	}
+	if klineResp.Code != 0 {
+		return nil, fmt.Errorf("API error: code=%d, msg=%s", klineResp.Code, klineResp.Msg)
+	}
+
+	for _, stockData := range klineResp.Data {
+		var rawData [][]string
+		switch period {
+		case "day":
+			if len(stockData.QfqDay) > 0 {
+				rawData = stockData.QfqDay
+			} else {
+				rawData = stockData.Day
+			}
+		case "week":
+			if len(stockData.QfqWeek) > 0 {
+				rawData = stockData.QfqWeek
+			} else {
+				rawData = stockData.Week
+			}
+		case "month":
+			if len(stockData.QfqMonth) > 0 {
+				rawData = stockData.QfqMonth
+			} else {
+				rawData = stockData.Month
+			}
+		}
+		bars := model.ToKLineBars(rawData)
+		if len(bars) > 0 {
+			return bars, nil
+		}
+	}
+	return nil, fmt.Errorf("no K-line data found")
+}
+
+// GetMinuteBars 获取当日分钟K线
+// 复用实时行情接口返回的分时数据
+func (p *TencentProvider) GetMinuteBars(stockCode string) (*model.StockMinuteKResp, error) {
+	resp, err := p.GetQuote(stockCode)
+	if err != nil {
+		return nil, err
+	}
+	return model.ParseMinuteBars(stockCode, resp.Date, resp.Data), nil
+}
+
+// GetIndustry 获取行业分类与公司简介
+func (p *TencentProvider) GetIndustry(stockCode string) (*model.StockIndustryResp, error) {
+	resp, err := mas_utils.GetRestyClient().R().Get(model.FinanceStockIndustry + stockCode)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+
+	var indResp model.StockIndustryResp
+	if err := json.Unmarshal(resp.Body(), &indResp); err != nil {
+		return nil, fmt.Errorf("JSON解析失败: %w", err)
+	}
+	if indResp.Code != 0 {
+		return nil, fmt.Errorf("API error: code=%d, msg=%s", indResp.Code, indResp.Msg)
+	}
+	return &indResp, nil
+}
+
+// GetBoardRank 获取板块排行
+func (p *TencentProvider) GetBoardRank(boardType string, order int, count int) ([]model.BoardItem, error) {
+	apiURL := fmt.Sprintf("%s?l=%d&p=1&t=%s/averatio&ordertype=&o=%d",
+		model.FinanceBoardRank, count, boardType, order)
+
+	resp, err := mas_utils.GetRestyClient().R().Get(apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+
+	var brResp model.BoardRankResp
+	if err := json.Unmarshal(resp.Body(), &brResp); err != nil {
+		return nil, fmt.Errorf("JSON解析失败: %w", err)
+	}
+	if brResp.Code != 0 {
+		return nil, fmt.Errorf("API error: code=%d, msg=%s", brResp.Code, brResp.Msg)
+	}
+	return brResp.Data, nil
+}
+
+// GetBoardStocks 获取板块成分股
+// 与板块排行使用同一接口，t 参数为 pt 前缀的板块代码
+func (p *TencentProvider) GetBoardStocks(boardCode string, count int) ([]model.BoardStock, error) {
+	if boardCode == "" {
+		return nil, fmt.Errorf("board code is empty")
+	}
+	if !strings.HasPrefix(boardCode, "pt") {
+		boardCode = "pt" + boardCode
+	}
+	apiURL := fmt.Sprintf("%s?l=%d&p=1&t=%s/chr&ordertype=&o=0",
+		model.FinanceBoardRank, count, url.QueryEscape(boardCode))
+
+	resp, err := mas_utils.GetRestyClient().R().Get(apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+
+	var bsResp model.BoardStocksResp
+	if err := json.Unmarshal(resp.Body(), &bsResp); err != nil {
+		return nil, fmt.Errorf("JSON解析失败: %w", err)
+	}
+	if bsResp.Code != 0 {
+		return nil, fmt.Errorf("API error: code=%d, msg=%s", bsResp.Code, bsResp.Msg)
+	}
+	return bsResp.Data, nil
+}
@@ -0,0 +1,203 @@
+// Package monitor 盘中监控：交易时段内定时检查持仓条件单与自选股提醒
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"msa/pkg/db"
+	"msa/pkg/logic/finsvc"
+	"msa/pkg/logic/watchlist"
+	"msa/pkg/model"
+)
+
+// DefaultInterval 默认检查间隔
+const DefaultInterval = time.Minute
+
+// 触发来源
+const (
+	// SourceConditionalOrder 持仓条件单（止损/止盈/跟踪止损）触发卖出
+	SourceConditionalOrder = "条件单"
+	// SourceOrderFilled 挂单成交
+	SourceOrderFilled = "成交"
+	// SourceWatchAlert 自选股提醒规则
+	SourceWatchAlert = "自选股提醒"
+)
+
+// SyncFunc 同步账户挂单：评估条件单并撮合挂单，返回条件单触发与本次成交的交易ID
+type SyncFunc func(database *gorm.DB, accountID uint) (triggered, filled []uint)
+
+// SnapshotFunc 记录账户收盘净值快照
+type SnapshotFunc func(database *gorm.DB, accountID uint, at time.Time) error
+
+// Trigger 一次监控触发
+type Trigger struct {
+	Source    string
+	StockCode string
+	StockName string
+	Message   string
+	At        time.Time
+}
+
+// String 单行文本，用于日志与终端输出
+func (t Trigger) String() string {
+	return fmt.Sprintf("[%s] %s", t.Source, t.Message)
+}
+
+// Daemon 盘中监控
+// 每个周期依次同步各账户挂单（条件单按分时价格评估）并检查自选股提醒规则；
+// 交易日收盘（A 股与港股均已收盘）后为各账户记录一次净值快照
+type Daemon struct {
+	Watch       *watchlist.Monitor // 自选股提醒检查，nil 表示不检查
+	SyncAccount SyncFunc           // 账户挂单同步，nil 表示不检查持仓
+	Snapshot    SnapshotFunc       // 收盘净值快照，nil 表示不记录
+	Interval    time.Duration      // 检查间隔，<=0 时使用 DefaultInterval
+	// OnCycle 每个周期结束后回调本周期的全部触发（可能为空），在 Run 的协程中同步调用
+	OnCycle func(ctx context.Context, triggers []Trigger)
+
+	snapshotDates map[uint]string // 各账户已记录快照的交易日，失败时下个周期重试
+}
+
+// InSession 当前是否处于 A 股或港股交易时段
+func InSession(now time.Time) bool {
+	return finsvc.IsTradingSession(finsvc.BoardMain, now) || finsvc.IsTradingSession(finsvc.BoardHK, now)
+}
+
+// Cycle 执行一次检查，收盘后记录当日净值快照，非交易时段不检查挂单与提醒
+// 单个账户或数据获取失败只记录日志，不影响其他检查
+func (d *Daemon) Cycle(database *gorm.DB, now time.Time) ([]Trigger, error) {
+	if !InSession(now) {
+		if d.Snapshot != nil && finsvc.IsAfterMarketClose(now) {
+			d.takeCloseSnapshots(database, now)
+		}
+		return nil, nil
+	}
+
+	var triggers []Trigger
+	if d.SyncAccount != nil {
+		accounts, err := db.ListAccounts(database)
+		if err != nil {
+			return nil, err
+		}
+		for _, account := range accounts {
+			if account.Status == model.AccountStatusClosed {
+				continue
+			}
+			triggered, filled := d.SyncAccount(database, account.ID)
+			triggers = append(triggers, describeOrders(database, SourceConditionalOrder, triggered, now)...)
+			triggers = append(triggers, describeOrders(database, SourceOrderFilled, filled, now)...)
+		}
+	}
+
+	if d.Watch != nil {
+		alerts, err := d.Watch.Check(database, now)
+		if err != nil {
+			log.Errorf("自选股提醒检查失败: %v", err)
+		}
+		for _, a := range alerts {
+			triggers = append(triggers, Trigger{
+				Source:    SourceWatchAlert,
+				StockCode: a.StockCode,
+				StockName: a.StockName,
+				Message:   a.Message,
+				At:        a.At,
+			})
+		}
+	}
+	return triggers, nil
+}
+
+// takeCloseSnapshots 为未记录当日快照的账户记录收盘净值快照
+func (d *Daemon) takeCloseSnapshots(database *gorm.DB, now time.Time) {
+	accounts, err := db.ListAccounts(database)
+	if err != nil {
+		log.Errorf("查询账户失败: %v", err)
+		return
+	}
+	if d.snapshotDates == nil {
+		d.snapshotDates = make(map[uint]string)
+	}
+
+	tradeDate := finsvc.SnapshotTradeDate(now)
+	for _, account := range accounts {
+		if account.Status == model.AccountStatusClosed || d.snapshotDates[account.ID] == tradeDate {
+			continue
+		}
+		if err := d.Snapshot(database, account.ID, now); err != nil {
+			log.Warnf("记录收盘净值快照失败，下个周期重试: 账户=%s, err=%v", account.Name, err)
+			continue
+		}
+		d.snapshotDates[account.ID] = tradeDate
+		log.Infof("已记录收盘净值快照: 账户=%s, 交易日=%s", account.Name, tradeDate)
+	}
+}
+
+// SnapshotRecorded 本守护进程是否已为 now 所在交易日记录过收盘净值快照（任一账户）
+func (d *Daemon) SnapshotRecorded(now time.Time) bool {
+	tradeDate := finsvc.SnapshotTradeDate(now)
+	for _, date := range d.snapshotDates {
+		if date == tradeDate {
+			return true
+		}
+	}
+	return false
+}
+
+// describeOrders 按交易记录生成触发说明
+func describeOrders(database *gorm.DB, source string, transIDs []uint, at time.Time) []Trigger {
+	triggers := make([]Trigger, 0, len(transIDs))
+	for _, id := range transIDs {
+		trans, err := db.GetTransactionByID(database, id)
+		if err != nil || trans == nil {
+			log.Warnf("查询交易记录失败: ID=%d, err=%v", id, err)
+			continue
+		}
+		message := fmt.Sprintf("%s(%s) %s %d 股 @ %s 元，交易 #%d（%s）", trans.StockName, trans.StockCode,
+			trans.Type, trans.Quantity, model.FormatAmount(trans.Price), trans.ID, trans.Status)
+		if trans.Note != "" {
+			message += "，" + trans.Note
+		}
+		triggers = append(triggers, Trigger{
+			Source:    source,
+			StockCode: trans.StockCode,
+			StockName: trans.StockName,
+			Message:   message,
+			At:        at,
+		})
+	}
+	return triggers
+}
+
+// Run 立即检查一次，之后按间隔循环，直到 ctx 结束时返回 nil
+func (d *Daemon) Run(ctx context.Context, database *gorm.DB) error {
+	interval := d.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	now := time.Now()
+	for {
+		triggers, err := d.Cycle(database, now)
+		if err != nil {
+			log.Errorf("盘中监控检查失败: %v", err)
+		}
+		for _, t := range triggers {
+			log.Infof("盘中监控触发: %s", t)
+		}
+		if d.OnCycle != nil {
+			d.OnCycle(ctx, triggers)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case now = <-ticker.C:
+		}
+	}
+}
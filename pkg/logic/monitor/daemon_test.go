@@ -0,0 +1,157 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	msadb "msa/pkg/db"
+	"msa/pkg/logic/watchlist"
+	"msa/pkg/model"
+)
+
+var chinaLocation = time.FixedZone("CST", 8*3600)
+
+// testTradeTime 测试用时间（周一交易时段内）
+var testTradeTime = time.Date(2025, 3, 3, 10, 0, 0, 0, chinaLocation)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	database, err := msadb.InitDBWithPath(filepath.Join(t.TempDir(), "test.sqlite"))
+	if err != nil {
+		t.Fatalf("InitDBWithPath failed: %v", err)
+	}
+	if err := msadb.Migrate(database); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	t.Cleanup(func() { msadb.CloseDB(database) })
+	return database
+}
+
+func TestCycle(t *testing.T) {
+	database := setupTestDB(t)
+	activeID, _ := msadb.CreateNamedAccount(database, "u1", "主账户", model.YuanToHao(100000))
+	closedID, _ := msadb.CreateNamedAccount(database, "u1", "已关闭", model.YuanToHao(100000))
+	closed, _ := msadb.GetAccountByID(database, closedID)
+	closed.Status = model.AccountStatusClosed
+	database.Save(closed)
+
+	transID, _ := msadb.CreateTransaction(database, &model.Transaction{
+		AccountID: activeID, StockCode: "sh600519", StockName: "贵州茅台", Type: model.TransactionTypeSell,
+		Quantity: 100, Price: model.YuanToHao(1500), Status: model.TransactionStatusPending, Note: "条件单 #1 STOP_LOSS 触发",
+	})
+
+	watchlist.Upsert(database, watchlist.EntrySpec{StockCode: "sz000001", StockName: "平安银行"})
+	watchlist.AddAlert(database, watchlist.AlertSpec{StockCode: "sz000001", Type: model.WatchAlertTypeChangePct})
+
+	var synced []uint
+	daemon := &Daemon{
+		SyncAccount: func(_ *gorm.DB, accountID uint) ([]uint, []uint) {
+			synced = append(synced, accountID)
+			return []uint{transID}, nil
+		},
+		Watch: watchlist.NewMonitor(func(string) (*model.StockCurrentResp, error) {
+			return &model.StockCurrentResp{CurrentPrice: "10.60", PrevClose: "10.00"}, nil
+		}, nil),
+	}
+
+	triggers, err := daemon.Cycle(database, testTradeTime)
+	if err != nil || len(triggers) != 2 {
+		t.Fatalf("Unexpected triggers: %+v, %v", triggers, err)
+	}
+	if len(synced) != 1 || synced[0] != activeID {
+		t.Errorf("Expected only active account synced, got %v", synced)
+	}
+	if triggers[0].Source != SourceConditionalOrder || !strings.Contains(triggers[0].Message, "STOP_LOSS") {
+		t.Errorf("Unexpected order trigger: %+v", triggers[0])
+	}
+	if triggers[1].Source != SourceWatchAlert || triggers[1].StockCode != "sz000001" {
+		t.Errorf("Unexpected alert trigger: %+v", triggers[1])
+	}
+
+	// 非交易时段不检查
+	synced = nil
+	triggers, _ = daemon.Cycle(database, time.Date(2025, 3, 3, 20, 0, 0, 0, chinaLocation))
+	if len(triggers) != 0 || len(synced) != 0 {
+		t.Errorf("Expected no check outside trading session, got %+v / %v", triggers, synced)
+	}
+}
+
+func TestCycle_CloseSnapshot(t *testing.T) {
+	database := setupTestDB(t)
+	okID, _ := msadb.CreateNamedAccount(database, "u1", "主账户", model.YuanToHao(100000))
+	failID, _ := msadb.CreateNamedAccount(database, "u1", "行情失败", model.YuanToHao(100000))
+
+	calls := map[uint]int{}
+	failing := true
+	daemon := &Daemon{
+		Snapshot: func(_ *gorm.DB, accountID uint, _ time.Time) error {
+			calls[accountID]++
+			if accountID == failID && failing {
+				return fmt.Errorf("行情获取失败")
+			}
+			return nil
+		},
+	}
+
+	// 盘中不记录快照
+	daemon.Cycle(database, testTradeTime)
+	if len(calls) != 0 {
+		t.Fatalf("Expected no snapshot during session, got %v", calls)
+	}
+
+	// 收盘后每个账户每个交易日只记录一次，失败的账户下个周期重试
+	evening := time.Date(2025, 3, 3, 20, 0, 0, 0, chinaLocation)
+	if daemon.SnapshotRecorded(evening) {
+		t.Fatal("Expected no snapshot recorded before close cycle")
+	}
+	daemon.Cycle(database, evening)
+	if !daemon.SnapshotRecorded(evening) {
+		t.Error("Expected snapshot recorded after close cycle")
+	}
+	failing = false
+	daemon.Cycle(database, evening.Add(time.Minute))
+	daemon.Cycle(database, evening.Add(2*time.Minute))
+	if calls[okID] != 1 || calls[failID] != 2 {
+		t.Errorf("Unexpected snapshot calls: %v", calls)
+	}
+
+	// 下一交易日收盘后再次记录，周末不记录
+	daemon.Cycle(database, evening.AddDate(0, 0, 1))
+	saturday := time.Date(2025, 3, 8, 20, 0, 0, 0, chinaLocation)
+	daemon.Cycle(database, saturday)
+	if calls[okID] != 2 || daemon.SnapshotRecorded(saturday) {
+		t.Errorf("Expected one snapshot per trading day, got %v", calls)
+	}
+}
+
+func TestRun_StopsOnCancel(t *testing.T) {
+	database := setupTestDB(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	cycles := 0
+	daemon := &Daemon{
+		Interval: time.Hour,
+		OnCycle: func(context.Context, []Trigger) {
+			cycles++
+			cancel()
+		},
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- daemon.Run(ctx, database) }()
+	select {
+	case err := <-done:
+		if err != nil || cycles != 1 {
+			t.Errorf("Unexpected result: err=%v, cycles=%d", err, cycles)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not stop after cancel")
+	}
+}
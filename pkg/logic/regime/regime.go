@@ -0,0 +1,390 @@
+package regime
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"msa/pkg/logic/indicator"
+	"msa/pkg/model"
+)
+
+// Regime 市场状态
+type Regime string
+
+const (
+	// RegimeBull 牛市：指数趋势向上且市场广度不弱
+	RegimeBull Regime = "BULL"
+	// RegimeBear 熊市：指数趋势向下且市场广度不强
+	RegimeBear Regime = "BEAR"
+	// RegimeRange 震荡：趋势不明确或与广度背离
+	RegimeRange Regime = "RANGE"
+)
+
+// State 细分市场状态，与 trading-common/references/market-regime-classifier.md 的 8 种状态对应
+type State string
+
+const (
+	StateStrongBull     State = "STRONG_BULL"     // 强牛：综合得分 ≥ 60
+	StateBull           State = "BULL"            // 牛市：30 ~ 60
+	StateWeakBull       State = "WEAK_BULL"       // 弱牛：10 ~ 30
+	StateRangingBullish State = "RANGING_BULLISH" // 震荡偏牛：0 ~ 10
+	StateRangingBearish State = "RANGING_BEARISH" // 震荡偏熊：-10 ~ 0
+	StateWeakBear       State = "WEAK_BEAR"       // 弱熊：-30 ~ -10
+	StateBear           State = "BEAR"            // 熊市：-60 ~ -30
+	StateStrongBear     State = "STRONG_BEAR"     // 强熊：< -60
+)
+
+// 维度标签
+const (
+	TrendUp       = "UP"
+	TrendDown     = "DOWN"
+	TrendSideways = "SIDEWAYS"
+
+	VolatilityLow     = "LOW"
+	VolatilityNormal  = "NORMAL"
+	VolatilityHigh    = "HIGH"
+	VolatilityExtreme = "EXTREME" // 单日涨跌幅超过 ExtremeMovePercent，建议暂停新开仓
+
+	BreadthPositive = "POSITIVE"
+	BreadthNeutral  = "NEUTRAL"
+	BreadthNegative = "NEGATIVE"
+	BreadthUnknown  = "UNKNOWN"
+
+	ConfidenceHigh   = "HIGH"
+	ConfidenceMedium = "MEDIUM"
+	ConfidenceLow    = "LOW"
+)
+
+const (
+	// ExtremeMovePercent 单日涨跌幅超过该值视为极端行情
+	ExtremeMovePercent = 5.0
+	// percentileWindow ATR 百分位的回看K线数（约一年）
+	percentileWindow = 250
+	// slopeBars 均线斜率的计算跨度
+	slopeBars = 5
+	// MinBars 分类所需的最少K线数（MA60 + 斜率跨度）
+	MinBars = 60 + slopeBars
+)
+
+// 综合得分权重：趋势 50%、波动率 20%、广度 30%
+const (
+	trendWeight      = 0.5
+	volatilityWeight = 0.2
+	breadthWeight    = 0.3
+)
+
+// IndexInput 参与分类的指数日K线
+type IndexInput struct {
+	Code string
+	Name string
+	Bars []indicator.Bar
+}
+
+// IndexEvidence 单个指数的判断依据
+type IndexEvidence struct {
+	Code          string   `json:"code"`
+	Name          string   `json:"name"`
+	Date          string   `json:"date"`
+	Close         float64  `json:"close"`
+	ChangePercent float64  `json:"change_percent"`  // 当日涨跌幅（%）
+	MA20          float64  `json:"ma20"`            // 20 日均线
+	MA60          float64  `json:"ma60"`            // 60 日均线
+	MA250         *float64 `json:"ma250,omitempty"` // 年线，K线不足 250 根时缺失
+	VsMA20        float64  `json:"vs_ma20"`         // 收盘价相对 MA20（%）
+	VsMA250       *float64 `json:"vs_ma250,omitempty"`
+	MA20Slope     float64  `json:"ma20_slope"`     // MA20 近 5 日变化（%）
+	MA60Slope     float64  `json:"ma60_slope"`     // MA60 近 5 日变化（%）
+	ATRPercent    float64  `json:"atr_percent"`    // ATR(14) / 收盘价（%）
+	ATRPercentile float64  `json:"atr_percentile"` // ATR% 在近一年中的百分位（0-100）
+	TrendScore    float64  `json:"trend_score"`    // 趋势得分（-100 ~ 100）
+}
+
+// Breadth 市场广度（按行业板块涨跌统计）
+type Breadth struct {
+	Total          int      `json:"total"`
+	Advancers      int      `json:"advancers"`
+	Decliners      int      `json:"decliners"`
+	AdvanceRatio   float64  `json:"advance_ratio"`             // 当日上涨板块占比
+	Advance5Ratio  *float64 `json:"advance5_ratio,omitempty"`  // 近 5 日上涨板块占比
+	Advance20Ratio *float64 `json:"advance20_ratio,omitempty"` // 近 20 日上涨板块占比
+}
+
+// Dimension 单个维度的评分
+type Dimension struct {
+	Label string  `json:"label"`
+	Score float64 `json:"score"` // -100 ~ 100
+}
+
+// Result 分类结果
+type Result struct {
+	Regime        Regime          `json:"regime"`
+	State         State           `json:"state"`
+	Score         float64         `json:"score"` // 综合得分 = 趋势 × 0.5 + 波动率 × 0.2 + 广度 × 0.3
+	Confidence    string          `json:"confidence"`
+	Trend         Dimension       `json:"trend"`
+	Volatility    Dimension       `json:"volatility"`
+	Breadth       Dimension       `json:"breadth"`
+	BreadthDetail *Breadth        `json:"breadth_detail,omitempty"`
+	Indices       []IndexEvidence `json:"indices"`
+	Notes         []string        `json:"notes,omitempty"`
+}
+
+// BreadthFromBoards 根据板块排行统计市场广度，没有有效数据时返回 nil
+func BreadthFromBoards(items []model.BoardItem) *Breadth {
+	var day, day5, day20 counter
+	for _, item := range items {
+		day.add(item.BdZdf)
+		day5.add(item.BdZdf5)
+		day20.add(item.BdZdf20)
+	}
+	if day.total == 0 {
+		return nil
+	}
+	return &Breadth{
+		Total:          day.total,
+		Advancers:      day.up,
+		Decliners:      day.down,
+		AdvanceRatio:   round(day.ratio(), 4),
+		Advance5Ratio:  day5.ratioPtr(),
+		Advance20Ratio: day20.ratioPtr(),
+	}
+}
+
+// counter 涨跌计数
+type counter struct {
+	total, up, down int
+}
+
+func (c *counter) add(value string) {
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return
+	}
+	c.total++
+	switch {
+	case v > 0:
+		c.up++
+	case v < 0:
+		c.down++
+	}
+}
+
+func (c *counter) ratio() float64 {
+	return float64(c.up) / float64(c.total)
+}
+
+func (c *counter) ratioPtr() *float64 {
+	if c.total == 0 {
+		return nil
+	}
+	r := round(c.ratio(), 4)
+	return &r
+}
+
+// Classify 根据指数日K线与市场广度分类市场状态
+// K线不足 MinBars 的指数不参与分类；breadth 为 nil 时广度维度记 0 分并降低置信度
+func Classify(indices []IndexInput, breadth *Breadth) (*Result, error) {
+	result := &Result{BreadthDetail: breadth}
+
+	extreme := false
+	var trendSum, percentileSum float64
+	for _, input := range indices {
+		if len(input.Bars) < MinBars {
+			result.Notes = append(result.Notes, fmt.Sprintf("%s K线不足 %d 根，未参与判断", input.Name, MinBars))
+			continue
+		}
+		evidence := analyzeIndex(input)
+		if math.Abs(evidence.ChangePercent) >= ExtremeMovePercent {
+			extreme = true
+		}
+		if evidence.MA250 == nil {
+			result.Notes = append(result.Notes, fmt.Sprintf("%s K线不足 250 根，未使用年线", input.Name))
+		}
+		trendSum += evidence.TrendScore
+		percentileSum += evidence.ATRPercentile
+		result.Indices = append(result.Indices, evidence)
+	}
+	if len(result.Indices) == 0 {
+		return nil, fmt.Errorf("no index has enough K-line data for regime classification")
+	}
+	n := float64(len(result.Indices))
+
+	// 趋势
+	trendScore := round(trendSum/n, 2)
+	result.Trend = Dimension{Label: TrendSideways, Score: trendScore}
+	switch {
+	case trendScore >= 30:
+		result.Trend.Label = TrendUp
+	case trendScore <= -30:
+		result.Trend.Label = TrendDown
+	}
+
+	// 波动率：高波动为风险信号，记负分
+	percentile := percentileSum / n
+	result.Volatility = Dimension{Label: VolatilityNormal}
+	switch {
+	case extreme:
+		result.Volatility = Dimension{Label: VolatilityExtreme, Score: -40}
+		result.Notes = append(result.Notes, fmt.Sprintf("指数单日涨跌幅超过 %.0f%%，建议暂停新开仓", ExtremeMovePercent))
+	case percentile >= 80:
+		result.Volatility = Dimension{Label: VolatilityHigh, Score: -20}
+	case percentile <= 20:
+		result.Volatility = Dimension{Label: VolatilityLow, Score: 20}
+	}
+
+	// 广度：当日与近 5 日上涨占比各占一半，(占比 - 50%) × 200
+	result.Breadth = Dimension{Label: BreadthUnknown}
+	if breadth != nil {
+		ratio := breadth.AdvanceRatio
+		if breadth.Advance5Ratio != nil {
+			ratio = (ratio + *breadth.Advance5Ratio) / 2
+		}
+		result.Breadth.Score = round(math.Max(-100, math.Min(100, (ratio-0.5)*200)), 2)
+		switch {
+		case ratio >= 0.6:
+			result.Breadth.Label = BreadthPositive
+		case ratio <= 0.4:
+			result.Breadth.Label = BreadthNegative
+		default:
+			result.Breadth.Label = BreadthNeutral
+		}
+	} else {
+		result.Notes = append(result.Notes, "板块涨跌数据缺失，广度维度记 0 分")
+	}
+
+	result.Score = round(result.Trend.Score*trendWeight+result.Volatility.Score*volatilityWeight+result.Breadth.Score*breadthWeight, 2)
+	result.State = stateFromScore(result.Score)
+
+	switch {
+	case result.Trend.Label == TrendUp && result.Breadth.Label != BreadthNegative:
+		result.Regime = RegimeBull
+	case result.Trend.Label == TrendDown && result.Breadth.Label != BreadthPositive:
+		result.Regime = RegimeBear
+	default:
+		result.Regime = RegimeRange
+	}
+
+	switch {
+	case breadth != nil && len(result.Indices) == len(indices) && len(indices) > 1:
+		result.Confidence = ConfidenceHigh
+	case breadth != nil || len(result.Indices) > 1:
+		result.Confidence = ConfidenceMedium
+	default:
+		result.Confidence = ConfidenceLow
+	}
+
+	return result, nil
+}
+
+// analyzeIndex 计算单个指数的趋势与波动证据
+// 趋势得分由四项组成，各项在阈值内记 0 分（均线缠绕视为震荡）：
+// 收盘相对 MA20（±3% 记 ±25，±1% 记 ±10）、MA20 斜率（±0.5% 记 ±25，±0.1% 记 ±10）、
+// MA20 相对 MA60（±1% 记 ±20）、收盘相对年线（±5% 记 ±30，±2% 记 ±15）
+func analyzeIndex(input IndexInput) IndexEvidence {
+	bars := input.Bars
+	closes := indicator.Closes(bars)
+	last := len(bars) - 1
+
+	ma20 := indicator.SMA(closes, 20)
+	ma60 := indicator.SMA(closes, 60)
+	evidence := IndexEvidence{
+		Code:      input.Code,
+		Name:      input.Name,
+		Date:      bars[last].Date,
+		Close:     closes[last],
+		MA20:      round(ma20[last], 2),
+		MA60:      round(ma60[last], 2),
+		VsMA20:    round(pctChange(ma20[last], closes[last]), 2),
+		MA20Slope: round(pctChange(ma20[last-slopeBars], ma20[last]), 2),
+		MA60Slope: round(pctChange(ma60[last-slopeBars], ma60[last]), 2),
+	}
+	if last > 0 {
+		evidence.ChangePercent = round(pctChange(closes[last-1], closes[last]), 2)
+	}
+
+	var score float64
+	score += bandScore(evidence.VsMA20, 1, 3, 10, 25)
+	score += bandScore(evidence.MA20Slope, 0.1, 0.5, 10, 25)
+	score += bandScore(pctChange(ma60[last], ma20[last]), 1, 1, 20, 20)
+	if v, ok := indicator.Last(indicator.SMA(closes, 250)); ok {
+		ma250 := round(v, 2)
+		vs := round(pctChange(v, closes[last]), 2)
+		evidence.MA250, evidence.VsMA250 = &ma250, &vs
+		score += bandScore(vs, 2, 5, 15, 30)
+	}
+	evidence.TrendScore = score
+
+	atr := indicator.ATR(bars, 14)
+	var history []float64
+	for i := max(0, len(bars)-percentileWindow); i < len(bars); i++ {
+		if !math.IsNaN(atr[i]) && closes[i] > 0 {
+			history = append(history, atr[i]/closes[i]*100)
+		}
+	}
+	if len(history) > 0 {
+		current := history[len(history)-1]
+		evidence.ATRPercent = round(current, 2)
+		evidence.ATRPercentile = round(percentileRank(history, current), 2)
+	}
+	return evidence
+}
+
+// bandScore 按阈值分档计分：超过 ±strongAt 记 ±strong，超过 ±weakAt 记 ±weak，其余记 0
+func bandScore(value, weakAt, strongAt, weak, strong float64) float64 {
+	switch {
+	case value > strongAt:
+		return strong
+	case value > weakAt:
+		return weak
+	case value < -strongAt:
+		return -strong
+	case value < -weakAt:
+		return -weak
+	default:
+		return 0
+	}
+}
+
+// stateFromScore 综合得分映射为 8 种细分状态
+func stateFromScore(score float64) State {
+	switch {
+	case score >= 60:
+		return StateStrongBull
+	case score >= 30:
+		return StateBull
+	case score >= 10:
+		return StateWeakBull
+	case score >= 0:
+		return StateRangingBullish
+	case score > -10:
+		return StateRangingBearish
+	case score > -30:
+		return StateWeakBear
+	case score > -60:
+		return StateBear
+	default:
+		return StateStrongBear
+	}
+}
+
+// percentileRank 计算 value 在 values 中的百分位（不大于 value 的占比 × 100）
+func percentileRank(values []float64, value float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	idx := sort.Search(len(sorted), func(i int) bool { return sorted[i] > value })
+	return float64(idx) / float64(len(sorted)) * 100
+}
+
+func pctChange(from, to float64) float64 {
+	if from == 0 || math.IsNaN(from) || math.IsNaN(to) {
+		return 0
+	}
+	return (to - from) / from * 100
+}
+
+func round(v float64, digits int) float64 {
+	p := math.Pow10(digits)
+	return math.Round(v*p) / p
+}
@@ -0,0 +1,155 @@
+package regime
+
+import (
+	"math"
+	"testing"
+
+	"msa/pkg/logic/indicator"
+	"msa/pkg/model"
+)
+
+// trendBars 生成按固定日涨幅变化的K线，振幅为收盘价的 ±1%
+func trendBars(n int, dailyPct float64) []indicator.Bar {
+	bars := make([]indicator.Bar, n)
+	price := 3000.0
+	for i := range bars {
+		price *= 1 + dailyPct/100
+		bars[i] = indicator.Bar{Open: price, High: price * 1.01, Low: price * 0.99, Close: price, Volume: 1}
+	}
+	return bars
+}
+
+// rangeBars 生成围绕 3000 点上下波动的K线
+func rangeBars(n int) []indicator.Bar {
+	bars := make([]indicator.Bar, n)
+	for i := range bars {
+		price := 3000 + 60*math.Sin(float64(i)/3)
+		bars[i] = indicator.Bar{Open: price, High: price * 1.01, Low: price * 0.99, Close: price, Volume: 1}
+	}
+	return bars
+}
+
+func boards(zdf ...string) []model.BoardItem {
+	items := make([]model.BoardItem, len(zdf))
+	for i, v := range zdf {
+		items[i] = model.BoardItem{BdZdf: v, BdZdf5: v}
+	}
+	return items
+}
+
+func TestBreadthFromBoards(t *testing.T) {
+	breadth := BreadthFromBoards([]model.BoardItem{
+		{BdZdf: "1.2", BdZdf5: "3.0"},
+		{BdZdf: "-0.5", BdZdf5: "2.0"},
+		{BdZdf: "0.00", BdZdf5: "-1.0"},
+		{BdZdf: "0.8", BdZdf5: "bad"},
+	})
+	if breadth == nil || breadth.Total != 4 || breadth.Advancers != 2 || breadth.Decliners != 1 || breadth.AdvanceRatio != 0.5 {
+		t.Fatalf("Unexpected breadth: %+v", breadth)
+	}
+	if breadth.Advance5Ratio == nil || math.Abs(*breadth.Advance5Ratio-0.6667) > 1e-4 {
+		t.Errorf("Unexpected 5-day ratio: %v", breadth.Advance5Ratio)
+	}
+	if breadth.Advance20Ratio != nil {
+		t.Errorf("Expected no 20-day ratio, got %v", *breadth.Advance20Ratio)
+	}
+	if BreadthFromBoards(nil) != nil {
+		t.Error("Expected nil breadth without boards")
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name    string
+		bars    []indicator.Bar
+		boards  []model.BoardItem
+		regime  Regime
+		trend   string
+		breadth string
+	}{
+		{"上涨趋势且普涨", trendBars(300, 0.3), boards("1", "2", "0.5", "-0.1"), RegimeBull, TrendUp, BreadthPositive},
+		{"下跌趋势且普跌", trendBars(300, -0.3), boards("-1", "-2", "-0.5", "0.1"), RegimeBear, TrendDown, BreadthNegative},
+		{"横盘震荡", rangeBars(300), boards("1", "-1"), RegimeRange, TrendSideways, BreadthNeutral},
+		{"上涨趋势但普跌", trendBars(300, 0.3), boards("-1", "-2", "-0.5", "-0.1"), RegimeRange, TrendUp, BreadthNegative},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inputs := []IndexInput{
+				{Code: "sh000001", Name: "上证指数", Bars: tt.bars},
+				{Code: "sz399001", Name: "深证成指", Bars: tt.bars},
+			}
+			result, err := Classify(inputs, BreadthFromBoards(tt.boards))
+			if err != nil {
+				t.Fatalf("Classify failed: %v", err)
+			}
+			if result.Regime != tt.regime || result.Trend.Label != tt.trend || result.Breadth.Label != tt.breadth {
+				t.Errorf("Expected %s/%s/%s, got %s/%s/%s (score %.2f)", tt.regime, tt.trend, tt.breadth,
+					result.Regime, result.Trend.Label, result.Breadth.Label, result.Score)
+			}
+			if result.Confidence != ConfidenceHigh || len(result.Indices) != 2 {
+				t.Errorf("Unexpected confidence %s with %d indices", result.Confidence, len(result.Indices))
+			}
+		})
+	}
+}
+
+func TestClassify_Evidence(t *testing.T) {
+	result, err := Classify([]IndexInput{{Code: "sh000001", Name: "上证指数", Bars: trendBars(300, 0.5)}}, nil)
+	if err != nil {
+		t.Fatalf("Classify failed: %v", err)
+	}
+	e := result.Indices[0]
+	// 持续上涨：站上所有均线，各项趋势得分取满分
+	if e.TrendScore != 100 || e.MA250 == nil || e.VsMA20 <= 0 || e.MA20Slope <= 0 || e.MA20 <= e.MA60 {
+		t.Errorf("Unexpected evidence: %+v", e)
+	}
+	if e.ATRPercent <= 0 || e.ATRPercentile <= 0 || e.ATRPercentile > 100 {
+		t.Errorf("Unexpected ATR evidence: %+v", e)
+	}
+	// 缺少广度数据：广度记 0 分，单指数低置信度
+	if result.Breadth.Label != BreadthUnknown || result.Breadth.Score != 0 || result.Confidence != ConfidenceLow {
+		t.Errorf("Unexpected breadth handling: %+v confidence=%s", result.Breadth, result.Confidence)
+	}
+	// 综合得分 = 100 × 0.5 + 波动率 × 0.2
+	if result.Score != 50+result.Volatility.Score*0.2 || result.State != stateFromScore(result.Score) {
+		t.Errorf("Unexpected score %.2f state %s", result.Score, result.State)
+	}
+}
+
+func TestClassify_ExtremeAndShortData(t *testing.T) {
+	bars := trendBars(120, 0.1)
+	last := &bars[len(bars)-1]
+	last.Close = bars[len(bars)-2].Close * 0.94
+
+	result, err := Classify([]IndexInput{
+		{Code: "sh000001", Name: "上证指数", Bars: bars},
+		{Code: "sz399006", Name: "创业板指", Bars: trendBars(30, 0.1)},
+	}, BreadthFromBoards(boards("-3", "-4")))
+	if err != nil {
+		t.Fatalf("Classify failed: %v", err)
+	}
+	if result.Volatility.Label != VolatilityExtreme || result.Volatility.Score != -40 {
+		t.Errorf("Expected extreme volatility, got %+v", result.Volatility)
+	}
+	// K线不足的指数不参与判断，年线缺失给出提示
+	if len(result.Indices) != 1 || len(result.Notes) < 3 || result.Confidence != ConfidenceMedium {
+		t.Errorf("Unexpected result: indices=%d notes=%v confidence=%s", len(result.Indices), result.Notes, result.Confidence)
+	}
+
+	if _, err := Classify([]IndexInput{{Name: "上证指数", Bars: trendBars(10, 0)}}, nil); err == nil {
+		t.Error("Expected error without enough data")
+	}
+}
+
+func TestStateFromScore(t *testing.T) {
+	tests := map[float64]State{
+		75: StateStrongBull, 30: StateBull, 12: StateWeakBull, 0: StateRangingBullish,
+		-5: StateRangingBearish, -10: StateWeakBear, -45: StateBear, -60: StateStrongBear,
+	}
+	for score, want := range tests {
+		if got := stateFromScore(score); got != want {
+			t.Errorf("stateFromScore(%v) = %s, want %s", score, got, want)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package replay
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"msa/pkg/logic/calendar"
+	"msa/pkg/logic/marketdata"
+	"msa/pkg/logic/marketdata/klinecache"
+	"msa/pkg/logic/marketdata/local"
+	"msa/pkg/model"
+)
+
+// Provider 历史回放行情数据源，只返回回放时刻（At）之前可得的数据
+//
+//	K线          经K线缓存从 Base 获取不复权K线，去掉回放日之后的K线；收盘前不含回放日当天的K线
+//	             前复权数据按今天的除权除息调整历史价格，会带入回放日之后的信息，因此忽略 adjust 参数
+//	行情快照     按不复权日K线与回放日录制的分时数据生成：开盘前为昨收，盘中为截至 At 的最新价，收盘后为当日收盘
+//	分钟K线      回放日录制的分时数据，只保留 At 之前的部分
+//	行业         回放日录制的行业数据
+//	板块排行     回放日录制的板块数据，收盘后才可用（录制数据视为收盘快照）
+//
+// 回放日录制数据位于 Dir/<YYYY-MM-DD>/，目录结构与本地数据源（local）相同
+type Provider struct {
+	// Base K线数据源
+	Base marketdata.MarketDataProvider
+	// Cache K线缓存数据库，为 nil 时直接读取 Base
+	Cache *gorm.DB
+	// Dir 按日期录制的行情目录
+	Dir string
+	// At 回放时刻
+	At time.Time
+}
+
+// GetSource 获取数据源标识
+func (p *Provider) GetSource() model.MarketDataSource {
+	return model.MarketDataReplay
+}
+
+// GetQuote 生成回放时刻的行情
+func (p *Provider) GetQuote(stockCode string) (*model.StockCurrentResp, error) {
+	if stockCode == "" {
+		return nil, fmt.Errorf("stock code is empty")
+	}
+	prev, today, err := p.dayBars(stockCode)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &model.StockCurrentResp{Date: p.compactDate()}
+	if recorded, err := p.recorded().GetQuote(stockCode); err == nil {
+		resp.Lot2Share = recorded.Lot2Share
+		resp.PERatio = recorded.PERatio
+		resp.WeekHighIn52 = recorded.WeekHighIn52
+		resp.WeekLowIn52 = recorded.WeekLowIn52
+		resp.Data = p.filterMinuteData(recorded.Data)
+	}
+	if prev != nil {
+		resp.PrevClose = prev.Close
+	}
+
+	switch {
+	case today == nil:
+		// 回放日没有K线（停牌），与实时行情一致当前价为空
+	case p.beforeOpen(stockCode):
+		resp.CurrentPrice = resp.PrevClose
+	case p.afterClose(stockCode):
+		resp.CurrentPrice = today.Close
+		resp.CurrentStartPrice = today.Open
+		resp.CurrentMaxPrice = today.High
+		resp.CurrentMinPrice = today.Low
+		resp.VolumeByLot = today.Volume
+	default:
+		resp.CurrentStartPrice = today.Open
+		p.fillIntraday(stockCode, resp)
+	}
+	return resp, nil
+}
+
+// fillIntraday 按截至回放时刻的分时数据填充盘中价格，没有分时录制时按开盘价
+func (p *Provider) fillIntraday(stockCode string, resp *model.StockCurrentResp) {
+	minute, err := p.GetMinuteBars(stockCode)
+	if err != nil || len(minute.Bars) == 0 {
+		log.Warnf("回放: %s 没有 %s 的分时数据，盘中价格按开盘价", stockCode, calendar.DateOf(p.At))
+		resp.CurrentPrice = resp.CurrentStartPrice
+		resp.CurrentMaxPrice = resp.CurrentStartPrice
+		resp.CurrentMinPrice = resp.CurrentStartPrice
+		return
+	}
+
+	open, _ := strconv.ParseFloat(resp.CurrentStartPrice, 64)
+	high, low := open, open
+	var volume int64
+	for _, bar := range minute.Bars {
+		if open <= 0 {
+			open, high, low = bar.Price, bar.Price, bar.Price
+		}
+		high = max(high, bar.Price)
+		low = min(low, bar.Price)
+		volume += bar.Volume
+	}
+	format := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+	resp.CurrentPrice = format(minute.Bars[len(minute.Bars)-1].Price)
+	resp.CurrentMaxPrice = format(high)
+	resp.CurrentMinPrice = format(low)
+	resp.VolumeByLot = strconv.FormatInt(volume, 10)
+}
+
+// GetQuotes 批量生成回放时刻的行情，获取失败的股票不在结果中
+func (p *Provider) GetQuotes(stockCodes []string) (map[string]*model.StockQuote, error) {
+	quotes := make(map[string]*model.StockQuote, len(stockCodes))
+	for _, code := range stockCodes {
+		resp, err := p.GetQuote(code)
+		if err != nil {
+			log.Warnf("回放: %v", err)
+			continue
+		}
+		quotes[code] = resp.ToStockQuote(code)
+	}
+	return quotes, nil
+}
+
+// GetKLine 获取回放时刻已走完的不复权K线，返回最近 count 根
+// 与行情快照使用的K线一致，adjust 参数被忽略
+func (p *Provider) GetKLine(stockCode, period string, count int, adjust string) ([]model.KLineBar, error) {
+	if adjust != "" {
+		log.Debugf("回放: %s K线忽略复权参数 %q，使用不复权数据", stockCode, adjust)
+	}
+	bars, err := p.history(stockCode, period, count)
+	if err != nil {
+		return nil, err
+	}
+	date, closed := calendar.DateOf(p.At), p.afterClose(stockCode)
+	cut := sort.Search(len(bars), func(i int) bool {
+		return bars[i].Date > date || (bars[i].Date == date && !closed)
+	})
+	bars = bars[:cut]
+	if len(bars) == 0 {
+		return nil, fmt.Errorf("%s 在 %s 之前没有K线数据", stockCode, date)
+	}
+	if count > 0 && len(bars) > count {
+		bars = bars[len(bars)-count:]
+	}
+	return bars, nil
+}
+
+// GetMinuteBars 获取回放日截至回放时刻的分钟K线
+func (p *Provider) GetMinuteBars(stockCode string) (*model.StockMinuteKResp, error) {
+	resp, err := p.recorded().GetMinuteBars(stockCode)
+	if err != nil {
+		return nil, fmt.Errorf("没有 %s 在 %s 的分时录制数据: %w", stockCode, calendar.DateOf(p.At), err)
+	}
+
+	cutoff := p.At.In(calendar.Location).Format("1504")
+	bars := make([]model.StockMinuteBar, 0, len(resp.Bars))
+	for _, bar := range resp.Bars {
+		if bar.Time <= cutoff {
+			bars = append(bars, bar)
+		}
+	}
+	return &model.StockMinuteKResp{StockCode: stockCode, Date: p.compactDate(), Bars: bars, Count: len(bars)}, nil
+}
+
+// GetIndustry 读取回放日录制的行业数据
+func (p *Provider) GetIndustry(stockCode string) (*model.StockIndustryResp, error) {
+	return p.recorded().GetIndustry(stockCode)
+}
+
+// GetBoardRank 读取回放日录制的板块排行，收盘前不可用
+func (p *Provider) GetBoardRank(boardType string, order int, count int) ([]model.BoardItem, error) {
+	if err := p.requireClosed("板块排行"); err != nil {
+		return nil, err
+	}
+	return p.recorded().GetBoardRank(boardType, order, count)
+}
+
+// GetBoardStocks 读取回放日录制的板块成分股，收盘前不可用
+func (p *Provider) GetBoardStocks(boardCode string, count int) ([]model.BoardStock, error) {
+	if err := p.requireClosed("板块成分股"); err != nil {
+		return nil, err
+	}
+	return p.recorded().GetBoardStocks(boardCode, count)
+}
+
+// ClosePrice 回放日收盘价（毫），不受回放时刻限制，用于收盘估值；回放日停牌时使用之前最近的收盘价
+func (p *Provider) ClosePrice(stockCode string) (int64, error) {
+	prev, today, err := p.dayBars(stockCode)
+	if err != nil {
+		return 0, err
+	}
+	bar := today
+	if bar == nil {
+		bar = prev
+	}
+	if bar == nil {
+		return 0, fmt.Errorf("%s 在 %s 之前没有K线数据", stockCode, calendar.DateOf(p.At))
+	}
+	price := parseYuan(bar.Close)
+	if price <= 0 {
+		return 0, fmt.Errorf("%s 收盘价无效: %s", stockCode, bar.Close)
+	}
+	return price, nil
+}
+
+// Actual 回放日及之后 days 个交易日的实际不复权日K线（含回放日之后的数据，只用于事后对照）
+func (p *Provider) Actual(stockCode string, days int) ([]model.KLineBar, error) {
+	bars, err := p.history(stockCode, "day", 0)
+	if err != nil {
+		return nil, err
+	}
+	date := calendar.DateOf(p.At)
+	i := sort.Search(len(bars), func(i int) bool { return bars[i].Date >= date })
+	bars = bars[i:]
+	if len(bars) > days+1 {
+		bars = bars[:days+1]
+	}
+	return bars, nil
+}
+
+// dayBars 回放日之前最后一根与回放日当天的不复权日K线（当天K线只用于按回放时刻生成行情）
+func (p *Provider) dayBars(stockCode string) (prev, today *model.KLineBar, err error) {
+	bars, err := p.history(stockCode, "day", 2)
+	if err != nil {
+		return nil, nil, err
+	}
+	date := calendar.DateOf(p.At)
+	i := sort.Search(len(bars), func(i int) bool { return bars[i].Date >= date })
+	if i > 0 {
+		prev = &bars[i-1]
+	}
+	if i < len(bars) && bars[i].Date == date {
+		today = &bars[i]
+	}
+	if prev == nil && today == nil {
+		return nil, nil, fmt.Errorf("%s 在 %s 之前没有K线数据", stockCode, date)
+	}
+	return prev, today, nil
+}
+
+// history 获取覆盖回放日之前 count 根的不复权K线（含回放日之后的K线），count ≤ 0 时获取最多的K线
+func (p *Provider) history(stockCode, period string, count int) ([]model.KLineBar, error) {
+	if p.Base == nil {
+		return nil, fmt.Errorf("回放K线数据源未设置")
+	}
+	fetch := klinecache.MaxBars
+	if count > 0 {
+		fetch = min(count+tradingDaysAfter(p.At)+1, klinecache.MaxBars)
+	}
+	return klinecache.GetKLine(p.Cache, p.Base, stockCode, period, fetch, "", time.Now())
+}
+
+// recorded 回放日录制数据
+func (p *Provider) recorded() *local.LocalProvider {
+	return &local.LocalProvider{Dir: filepath.Join(p.Dir, calendar.DateOf(p.At))}
+}
+
+// filterMinuteData 保留回放时刻之前的分时原始数据（"HHmm 价格 累计成交量 累计成交额"）
+func (p *Provider) filterMinuteData(data []string) []string {
+	cutoff := p.At.In(calendar.Location).Format("1504")
+	var kept []string
+	for _, raw := range data {
+		if fields := strings.Fields(raw); len(fields) > 0 && fields[0] <= cutoff {
+			kept = append(kept, raw)
+		}
+	}
+	return kept
+}
+
+func (p *Provider) requireClosed(what string) error {
+	if !p.afterClose("") {
+		return fmt.Errorf("回放时刻 %s 尚未收盘，%s只有收盘后的录制数据", p.At.In(calendar.Location).Format("2006-01-02 15:04"), what)
+	}
+	return nil
+}
+
+// beforeOpen 回放时刻是否早于当日开盘
+func (p *Provider) beforeOpen(stockCode string) bool {
+	open, _, ok := calendar.OpenClose(calendar.MarketOf(stockCode), p.At)
+	return ok && p.At.Before(open)
+}
+
+// afterClose 回放时刻是否已收盘（非交易日视为已收盘）
+func (p *Provider) afterClose(stockCode string) bool {
+	_, closeAt, ok := calendar.OpenClose(calendar.MarketOf(stockCode), p.At)
+	return !ok || !p.At.Before(closeAt)
+}
+
+func (p *Provider) compactDate() string {
+	return p.At.In(calendar.Location).Format("20060102")
+}
+
+// tradingDaysAfter t 所在日期之后到今天的A股交易日数
+func tradingDaysAfter(t time.Time) int {
+	n := 0
+	now := time.Now()
+	for day := calendar.NextTradingDay(calendar.MarketCN, t); !day.After(now); day = calendar.NextTradingDay(calendar.MarketCN, day) {
+		n++
+	}
+	return n
+}
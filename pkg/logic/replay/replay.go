@@ -0,0 +1,332 @@
+// Package replay 历史回放：把时钟固定在过去某个交易日的 skill 触发时刻，
+// 行情工具只返回该时刻之前的数据，交易写入临时数据库中的回放账户，逐日记录模型的决策
+package replay
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"msa/pkg/db"
+	"msa/pkg/logic/calendar"
+	"msa/pkg/logic/finsvc"
+	"msa/pkg/logic/marketdata"
+	"msa/pkg/model"
+)
+
+const (
+	// DefaultInitialCapital 回放账户默认初始资金（元）
+	DefaultInitialCapital = 1000000
+	// accountName 回放账户名称
+	accountName = "replay"
+)
+
+// Job 回放的 skill 与执行时刻
+type Job struct {
+	Skill  string
+	Minute int // 执行时刻，自零点起的分钟数（交易所时区）
+}
+
+// Clock 执行时刻 HH:MM
+func (j Job) Clock() string {
+	return fmt.Sprintf("%02d:%02d", j.Minute/60, j.Minute%60)
+}
+
+// ToolCall 一次工具调用
+type ToolCall struct {
+	Name    string `json:"name"`
+	Input   string `json:"input"`
+	Output  string `json:"output,omitempty"`
+	IsError bool   `json:"is_error,omitempty"`
+}
+
+// Transcript 一次 skill 执行的对话记录
+type Transcript struct {
+	Reply string
+	Tools []ToolCall
+}
+
+// ExecuteFunc 在回放时刻执行 skill，question 为发送给模型的问题
+type ExecuteFunc func(ctx context.Context, job Job, question string) (*Transcript, error)
+
+// Options 回放参数
+type Options struct {
+	From, To string // 回放区间（YYYY-MM-DD），只回放其中的A股交易日
+	Jobs     []Job
+	// InitialCapital 回放账户初始资金（元），为 0 时使用 DefaultInitialCapital
+	InitialCapital float64
+	// DBPath 回放数据库路径，为空时使用临时数据库并在结束后删除
+	DBPath string
+	// Provider 回放行情数据源，Run 按执行时刻设置 At
+	Provider *Provider
+}
+
+// Run 逐个交易日、按执行时刻依次回放 skill
+// 回放期间全局时钟、行情数据源与数据库分别替换为回放时刻、Provider 与回放数据库，结束后恢复
+// ctx 取消或执行失败时返回已完成部分的日志与错误
+func Run(ctx context.Context, opts Options, execute ExecuteFunc) (*Log, error) {
+	days, err := replayDays(opts.From, opts.To)
+	if err != nil {
+		return nil, err
+	}
+	if len(opts.Jobs) == 0 {
+		return nil, fmt.Errorf("没有需要回放的 skill")
+	}
+	if opts.Provider == nil {
+		return nil, fmt.Errorf("回放行情数据源未设置")
+	}
+	capital := opts.InitialCapital
+	if capital == 0 {
+		capital = DefaultInitialCapital
+	}
+	if capital < 0 {
+		return nil, fmt.Errorf("初始资金必须大于 0")
+	}
+
+	database, cleanup, err := openDB(opts.DBPath)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	accountID, err := db.CreateNamedAccount(database, accountName, accountName, model.YuanToHao(capital))
+	if err != nil {
+		return nil, err
+	}
+	if err := db.SetCurrentAccount(database, accountID); err != nil {
+		return nil, err
+	}
+
+	prevDB := db.SetGlobalDB(database)
+	prevProvider := marketdata.SetProvider(opts.Provider)
+	defer func() {
+		calendar.SetClock(nil)
+		marketdata.SetProvider(prevProvider)
+		db.SetGlobalDB(prevDB)
+	}()
+
+	jobs := append([]Job(nil), opts.Jobs...)
+	sort.SliceStable(jobs, func(i, j int) bool { return jobs[i].Minute < jobs[j].Minute })
+
+	r := &replayer{db: database, accountID: accountID, provider: opts.Provider, execute: execute}
+	r.log = &Log{From: calendar.DateOf(days[0]), To: calendar.DateOf(days[len(days)-1]), InitialCapital: model.YuanToHao(capital)}
+	for _, day := range days {
+		if err := r.replayDay(ctx, day, jobs); err != nil {
+			return r.log, err
+		}
+	}
+	return r.log, nil
+}
+
+// replayDays 区间内的A股交易日，结束日期须早于今天
+func replayDays(from, to string) ([]time.Time, error) {
+	start, err := time.ParseInLocation(calendar.DateLayout, from, calendar.Location)
+	if err != nil {
+		return nil, fmt.Errorf("起始日期格式无效: %s", from)
+	}
+	end, err := time.ParseInLocation(calendar.DateLayout, to, calendar.Location)
+	if err != nil {
+		return nil, fmt.Errorf("结束日期格式无效: %s", to)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("起始日期 %s 晚于结束日期 %s", from, to)
+	}
+	if to >= calendar.DateOf(time.Now()) {
+		return nil, fmt.Errorf("只能回放今天之前的交易日: %s", to)
+	}
+
+	var days []time.Time
+	day := start
+	if !calendar.IsTradingDay(calendar.MarketCN, day) {
+		day = calendar.NextTradingDay(calendar.MarketCN, day)
+	}
+	for ; !day.After(end); day = calendar.NextTradingDay(calendar.MarketCN, day) {
+		days = append(days, day)
+	}
+	if len(days) == 0 {
+		return nil, fmt.Errorf("%s 至 %s 没有交易日", from, to)
+	}
+	return days, nil
+}
+
+// openDB 打开回放数据库，path 为空时在临时目录创建
+func openDB(path string) (*gorm.DB, func(), error) {
+	dir := ""
+	if path == "" {
+		var err error
+		if dir, err = os.MkdirTemp("", "msa-replay-*"); err != nil {
+			return nil, nil, fmt.Errorf("创建临时目录失败: %w", err)
+		}
+		path = filepath.Join(dir, "replay.sqlite")
+	}
+
+	database, err := db.InitDBWithPath(path)
+	if err == nil {
+		err = db.Migrate(database)
+	}
+	cleanup := func() {
+		if database != nil {
+			db.CloseDB(database)
+		}
+		if dir != "" {
+			os.RemoveAll(dir)
+		}
+	}
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return database, cleanup, nil
+}
+
+// replayer 单次回放的状态
+type replayer struct {
+	db        *gorm.DB
+	accountID uint
+	provider  *Provider
+	execute   ExecuteFunc
+	log       *Log
+}
+
+// setTime 将全局时钟与数据源固定在 at
+func (r *replayer) setTime(at time.Time) {
+	r.provider.At = at
+	calendar.SetClock(func() time.Time { return at })
+}
+
+// replayDay 回放一个交易日：依次执行各 skill，收盘撤销未成交挂单并按收盘价记录净值
+func (r *replayer) replayDay(ctx context.Context, day time.Time, jobs []Job) error {
+	dayLog := DayLog{Date: calendar.DateOf(day), Weekday: weekdayLabel(day)}
+	defer func() { r.log.Days = append(r.log.Days, dayLog) }()
+
+	for _, job := range jobs {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("回放中断: %w", err)
+		}
+		at := day.Add(time.Duration(job.Minute) * time.Minute)
+		r.setTime(at)
+
+		lastID, err := r.lastTransactionID()
+		if err != nil {
+			return err
+		}
+
+		log.Infof("回放 %s %s %s", dayLog.Date, job.Clock(), job.Skill)
+		run := RunLog{Skill: job.Skill, Time: job.Clock()}
+		transcript, execErr := r.execute(ctx, job, Question(job.Skill, at))
+		if transcript != nil {
+			run.Reply = transcript.Reply
+			run.Tools = transcript.Tools
+		}
+		if execErr != nil {
+			run.Error = execErr.Error()
+		}
+		if run.Orders, err = r.ordersSince(lastID); err != nil {
+			return err
+		}
+		dayLog.Runs = append(dayLog.Runs, run)
+		if ctx.Err() != nil {
+			return fmt.Errorf("回放中断: %w", ctx.Err())
+		}
+	}
+
+	return r.closeDay(day, &dayLog)
+}
+
+// closeDay 收盘：当日有效的挂单撤销，按收盘价估值记录净值，补充订单的实际走势
+func (r *replayer) closeDay(day time.Time, dayLog *DayLog) error {
+	_, closeAt, _ := calendar.OpenClose(calendar.MarketCN, day)
+	r.setTime(closeAt)
+
+	if _, err := finsvc.ExpirePendingOrders(r.db, r.accountID, closeAt.Add(time.Second)); err != nil {
+		return err
+	}
+
+	codes, err := finsvc.GetActiveStockCodes(r.db, r.accountID)
+	if err != nil {
+		return err
+	}
+	prices := make(finsvc.PriceMap, len(codes))
+	for _, code := range codes {
+		price, err := r.provider.ClosePrice(code)
+		if err != nil {
+			return err
+		}
+		prices[code] = price
+	}
+	snapshot, err := finsvc.TakePortfolioSnapshot(r.db, r.accountID, prices, closeAt)
+	if err != nil {
+		return err
+	}
+	dayLog.NAV = snapshot.NAV
+	dayLog.Cash = snapshot.AvailableAmt + snapshot.LockedAmt
+	for _, h := range snapshot.Holdings {
+		dayLog.Holdings = append(dayLog.Holdings, Holding{
+			Code: h.StockCode, Name: h.StockName, Quantity: h.Quantity, Price: h.Price, Value: h.Value, Cost: h.Cost,
+		})
+	}
+	r.log.FinalNAV = snapshot.NAV
+
+	for i := range dayLog.Runs {
+		for j := range dayLog.Runs[i].Orders {
+			r.fillOutcome(&dayLog.Runs[i].Orders[j])
+		}
+	}
+	return nil
+}
+
+// lastTransactionID 回放账户最新交易ID
+func (r *replayer) lastTransactionID() (uint, error) {
+	var id uint
+	err := r.db.Model(&model.Transaction{}).Select("COALESCE(MAX(id), 0)").
+		Where("account_id = ?", r.accountID).Scan(&id).Error
+	return id, err
+}
+
+// ordersSince 本次执行提交的订单（部分成交拆分出的记录只保留最终状态）
+func (r *replayer) ordersSince(lastID uint) ([]Order, error) {
+	var list []model.Transaction
+	err := r.db.Where("account_id = ? AND id > ? AND status <> ?", r.accountID, lastID, model.TransactionStatusObsolete).
+		Order("id ASC").Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	orders := make([]Order, 0, len(list))
+	for _, t := range list {
+		orders = append(orders, Order{
+			ID:       t.ID,
+			Time:     t.CreatedAt.In(calendar.Location).Format("15:04"),
+			Code:     t.StockCode,
+			Name:     t.StockName,
+			Side:     t.Type,
+			Quantity: t.Quantity,
+			Price:    t.Price,
+			Status:   t.Status,
+			Note:     t.Note,
+		})
+	}
+	return orders, nil
+}
+
+// fillOutcome 补充订单收盘后的最终状态与实际走势：当日收盘价与下一交易日收盘价
+func (r *replayer) fillOutcome(o *Order) {
+	if t, err := db.GetTransactionByID(r.db, o.ID); err == nil {
+		o.Status = t.Status
+	}
+	bars, err := r.provider.Actual(o.Code, 1)
+	if err != nil || len(bars) == 0 || bars[0].Date != calendar.DateOf(r.provider.At) {
+		log.Warnf("回放: 获取 %s 实际走势失败: %v", o.Code, err)
+		return
+	}
+	o.DayClose = parseYuan(bars[0].Close)
+	if len(bars) > 1 {
+		o.NextDate = bars[1].Date
+		o.NextClose = parseYuan(bars[1].Close)
+	}
+}
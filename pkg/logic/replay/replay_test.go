@@ -0,0 +1,215 @@
+package replay
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	msadb "msa/pkg/db"
+	"msa/pkg/logic/calendar"
+	"msa/pkg/logic/marketdata"
+	"msa/pkg/logic/marketdata/local"
+	"msa/pkg/logic/tools/finance"
+	"msa/pkg/model"
+)
+
+// 回放日 2025-06-05（星期四），前后各有K线
+const replayDate = "2025-06-05"
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+}
+
+// newTestProvider 日K线 06-03 ~ 06-06，回放日录制 4 条分时数据
+func newTestProvider(t *testing.T) *Provider {
+	t.Helper()
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "base", "kline", "sh600000_day.csv"), `date,open,close,high,low,volume
+2025-06-03,9.80,9.90,10.00,9.70,1000
+2025-06-04,9.90,10.00,10.10,9.85,1200
+2025-06-05,10.05,10.50,10.70,10.00,1500
+2025-06-06,10.50,10.80,10.90,10.40,1300
+`)
+	writeFile(t, filepath.Join(dir, "replay", replayDate, "minute", "sh600000.csv"), `time,price,volume,turnover
+0930,10.10,100,101000
+1000,10.20,50,51000
+1030,10.40,80,83200
+1400,10.60,90,95400
+`)
+	return &Provider{Base: &local.LocalProvider{Dir: filepath.Join(dir, "base")}, Dir: filepath.Join(dir, "replay")}
+}
+
+// adjustRecorder 记录K线请求的复权参数
+type adjustRecorder struct {
+	*local.LocalProvider
+	adjusts []string
+}
+
+func (r *adjustRecorder) GetKLine(stockCode, period string, count int, adjust string) ([]model.KLineBar, error) {
+	r.adjusts = append(r.adjusts, adjust)
+	return r.LocalProvider.GetKLine(stockCode, period, count, adjust)
+}
+
+func at(t *testing.T, date string, hour, minute int) time.Time {
+	t.Helper()
+	day, err := time.ParseInLocation(calendar.DateLayout, date, calendar.Location)
+	if err != nil {
+		t.Fatalf("parse date failed: %v", err)
+	}
+	return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+}
+
+func TestProvider_NoLookAhead(t *testing.T) {
+	p := newTestProvider(t)
+
+	// 盘中：K线不含当天，行情与分时截至回放时刻
+	p.At = at(t, replayDate, 10, 15)
+	bars, err := p.GetKLine("sh600000", "day", 10, "qfq")
+	if err != nil {
+		t.Fatalf("GetKLine failed: %v", err)
+	}
+	if len(bars) != 2 || bars[len(bars)-1].Date != "2025-06-04" {
+		t.Errorf("Unexpected intraday bars: %+v", bars)
+	}
+	quote, err := p.GetQuote("sh600000")
+	if err != nil {
+		t.Fatalf("GetQuote failed: %v", err)
+	}
+	if quote.CurrentPrice != "10.20" || quote.PrevClose != "10.00" || quote.CurrentStartPrice != "10.05" ||
+		quote.CurrentMaxPrice != "10.20" || quote.CurrentMinPrice != "10.05" || quote.VolumeByLot != "150" {
+		t.Errorf("Unexpected intraday quote: %+v", quote)
+	}
+	minute, err := p.GetMinuteBars("sh600000")
+	if err != nil {
+		t.Fatalf("GetMinuteBars failed: %v", err)
+	}
+	if minute.Count != 2 || minute.Date != "20250605" {
+		t.Errorf("Unexpected minute bars: %+v", minute)
+	}
+	if _, err := p.GetBoardRank("01", 0, 10); err == nil || !strings.Contains(err.Error(), "尚未收盘") {
+		t.Errorf("Expected board rank unavailable before close, got %v", err)
+	}
+
+	// 开盘前：当前价为昨收
+	p.At = at(t, replayDate, 9, 0)
+	if quote, err = p.GetQuote("sh600000"); err != nil || quote.CurrentPrice != "10.00" || quote.CurrentStartPrice != "" {
+		t.Errorf("Unexpected pre-open quote: %+v, %v", quote, err)
+	}
+
+	// 收盘后：包含当天K线，行情为收盘价
+	p.At = at(t, replayDate, 15, 0)
+	if bars, err = p.GetKLine("sh600000", "day", 10, ""); err != nil || bars[len(bars)-1].Date != replayDate {
+		t.Errorf("Unexpected closed bars: %+v, %v", bars, err)
+	}
+	if quote, err = p.GetQuote("sh600000"); err != nil || quote.CurrentPrice != "10.50" || quote.CurrentMaxPrice != "10.70" {
+		t.Errorf("Unexpected closed quote: %+v, %v", quote, err)
+	}
+
+	actual, err := p.Actual("sh600000", 1)
+	if err != nil || len(actual) != 2 || actual[1].Date != "2025-06-06" {
+		t.Errorf("Unexpected actual bars: %+v, %v", actual, err)
+	}
+}
+
+func TestProvider_UnadjustedKLine(t *testing.T) {
+	p := newTestProvider(t)
+	recorder := &adjustRecorder{LocalProvider: p.Base.(*local.LocalProvider)}
+	p.Base = recorder
+	p.At = at(t, replayDate, 15, 0)
+
+	// 前复权K线含回放日之后的除权调整，回放只使用不复权K线
+	if _, err := p.GetKLine("sh600000", "day", 10, "qfq"); err != nil {
+		t.Fatalf("GetKLine failed: %v", err)
+	}
+	if len(recorder.adjusts) != 1 || recorder.adjusts[0] != "" {
+		t.Errorf("Expected unadjusted request, got %q", recorder.adjusts)
+	}
+}
+
+func TestRun(t *testing.T) {
+	p := newTestProvider(t)
+	dbPath := filepath.Join(t.TempDir(), "replay.sqlite")
+
+	var pinned time.Time
+	var asked string
+	execute := func(ctx context.Context, job Job, question string) (*Transcript, error) {
+		pinned, asked = calendar.Now(), question
+		out, _ := finance.SubmitBuyOrder(ctx, &finance.SubmitBuyOrderParam{
+			StockCode: "sh600000", StockName: "浦发银行", Quantity: 1000, Price: 10.5,
+		})
+		return &Transcript{
+			Reply: "买入浦发银行 1000 股",
+			Tools: []ToolCall{{Name: "get_stock_quote", Input: `{"stock_code":"sh600000"}`}, {Name: "submit_buy_order", Output: out}},
+		}, nil
+	}
+
+	previous := &local.LocalProvider{Dir: t.TempDir()}
+	marketdata.SetProvider(previous)
+	defer marketdata.SetProvider(nil)
+
+	result, err := Run(context.Background(), Options{
+		From: "2025-06-04", To: replayDate, DBPath: dbPath, InitialCapital: 100000, Provider: p,
+		Jobs: []Job{{Skill: "afternoon-trade", Minute: 13*60 + 30}},
+	}, execute)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if !pinned.Equal(at(t, replayDate, 13, 30)) || !strings.Contains(asked, "2025-06-05 13:30") || !strings.Contains(asked, "afternoon-trade") {
+		t.Errorf("Unexpected clock %v or question %q", pinned, asked)
+	}
+	if since := time.Since(calendar.Now()); since < 0 || since > time.Minute {
+		t.Errorf("Clock not restored: %v", calendar.Now())
+	}
+	if msadb.GetDB() != nil {
+		t.Errorf("Global DB not restored")
+	}
+	if restored, _ := marketdata.GetProvider(); restored != previous {
+		t.Errorf("Market data provider not restored: %v", restored)
+	}
+
+	if len(result.Days) != 2 || result.Days[0].Date != "2025-06-04" || result.Days[1].Weekday != "星期四" {
+		t.Fatalf("Unexpected days: %+v", result.Days)
+	}
+
+	// 06-04 无分时数据，按开盘价 9.90 成交
+	first := result.Days[0].Runs[0].Orders
+	if len(first) != 1 || first[0].Status != model.TransactionStatusFilled || first[0].DayClose != 100000 || first[0].NextDate != replayDate {
+		t.Fatalf("Unexpected first-day orders: %+v", first)
+	}
+
+	day := result.Days[1]
+	orders := day.Runs[0].Orders
+	if len(orders) != 1 || orders[0].Side != model.TransactionTypeBuy || orders[0].Time != "13:30" || orders[0].Status != model.TransactionStatusFilled {
+		t.Fatalf("Unexpected orders: %+v", orders)
+	}
+	if orders[0].DayClose != 105000 || orders[0].NextClose != 108000 || orders[0].NextDate != "2025-06-06" {
+		t.Errorf("Unexpected outcome: %+v", orders[0])
+	}
+	if len(day.Holdings) != 1 || day.Holdings[0].Quantity != 2000 || day.Holdings[0].Price != 105000 {
+		t.Errorf("Unexpected holdings: %+v", day.Holdings)
+	}
+	if result.FinalNAV != day.NAV || day.NAV != day.Cash+day.Holdings[0].Value {
+		t.Errorf("Unexpected nav: final=%d day=%+v", result.FinalNAV, day)
+	}
+
+	text := result.Format(true)
+	for _, want := range []string{"## 2025-06-05 星期四", "### 13:30 afternoon-trade", "买入 sh600000 浦发银行 1000 股", "2025-06-06 收盘 10.8000", "get_stock_quote, submit_buy_order", "持仓 sh600000 浦发银行 2000 股"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Format missing %q:\n%s", want, text)
+		}
+	}
+
+	if _, err := Run(context.Background(), Options{From: "2025-06-07", To: "2025-06-08", Provider: p, Jobs: []Job{{Skill: "x"}}}, execute); err == nil {
+		t.Errorf("Expected error for range without trading days")
+	}
+}
@@ -0,0 +1,203 @@
+package replay
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"msa/pkg/logic/calendar"
+	"msa/pkg/model"
+)
+
+// Order 回放中提交的订单，附带事后的实际走势用于对照
+type Order struct {
+	ID       uint                    `json:"id"`
+	Time     string                  `json:"time"`
+	Code     string                  `json:"code"`
+	Name     string                  `json:"name,omitempty"`
+	Side     model.TransactionType   `json:"side"`
+	Quantity int64                   `json:"quantity"`
+	Price    int64                   `json:"price"`  // 委托价或成交价（毫）
+	Status   model.TransactionStatus `json:"status"` // 收盘时的状态，当日未成交的挂单已撤销
+	Note     string                  `json:"note,omitempty"`
+
+	DayClose  int64  `json:"day_close,omitempty"`  // 当日实际收盘价（毫）
+	NextDate  string `json:"next_date,omitempty"`  // 下一交易日
+	NextClose int64  `json:"next_close,omitempty"` // 下一交易日实际收盘价（毫）
+}
+
+// Holding 收盘持仓
+type Holding struct {
+	Code     string `json:"code"`
+	Name     string `json:"name,omitempty"`
+	Quantity int64  `json:"quantity"`
+	Price    int64  `json:"price"` // 收盘价（毫）
+	Value    int64  `json:"value"` // 市值（毫）
+	Cost     int64  `json:"cost"`  // 成本（毫）
+}
+
+// RunLog 一次 skill 执行
+type RunLog struct {
+	Skill  string     `json:"skill"`
+	Time   string     `json:"time"`
+	Reply  string     `json:"reply"`
+	Tools  []ToolCall `json:"tools,omitempty"`
+	Orders []Order    `json:"orders,omitempty"`
+	Error  string     `json:"error,omitempty"`
+}
+
+// DayLog 一个交易日的决策记录
+type DayLog struct {
+	Date     string    `json:"date"`
+	Weekday  string    `json:"weekday"`
+	Runs     []RunLog  `json:"runs"`
+	NAV      int64     `json:"nav"`  // 收盘净值（毫）
+	Cash     int64     `json:"cash"` // 收盘现金（毫）
+	Holdings []Holding `json:"holdings,omitempty"`
+}
+
+// Log 回放决策日志
+type Log struct {
+	From           string   `json:"from"`
+	To             string   `json:"to"`
+	InitialCapital int64    `json:"initial_capital"` // 初始资金（毫）
+	FinalNAV       int64    `json:"final_nav"`       // 最后一个回放日收盘净值（毫）
+	Days           []DayLog `json:"days"`
+}
+
+// TotalReturn 累计收益率（小数）
+func (l *Log) TotalReturn() float64 {
+	if l.InitialCapital <= 0 || l.FinalNAV == 0 {
+		return 0
+	}
+	return float64(l.FinalNAV-l.InitialCapital) / float64(l.InitialCapital)
+}
+
+// Question 回放时发送给模型的问题
+func Question(skill string, at time.Time) string {
+	return fmt.Sprintf("历史回放（当前时间 %s）：请调用 get_skill_content 获取 %s 技能，并严格按其流程执行。"+
+		"行情工具只返回该时刻之前的数据；web_search 等外部信息只能采用该时刻之前发布的内容。交易在回放账户中模拟成交。",
+		at.In(calendar.Location).Format("2006-01-02 15:04"), skill)
+}
+
+// Format 渲染 Markdown 决策日志，showTools 为 true 时列出每次工具调用的参数
+func (l *Log) Format(showTools bool) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# 回放决策日志 %s ~ %s\n\n", l.From, l.To)
+	fmt.Fprintf(&sb, "- 初始资金: %s 元\n", model.FormatAmount(l.InitialCapital))
+	fmt.Fprintf(&sb, "- 期末净值: %s 元（累计收益 %.2f%%）\n", model.FormatAmount(l.FinalNAV), l.TotalReturn()*100)
+
+	for _, day := range l.Days {
+		fmt.Fprintf(&sb, "\n## %s %s\n", day.Date, day.Weekday)
+		for _, run := range day.Runs {
+			fmt.Fprintf(&sb, "\n### %s %s\n\n", run.Time, run.Skill)
+			if run.Error != "" {
+				fmt.Fprintf(&sb, "**执行失败**: %s\n\n", run.Error)
+			}
+			if len(run.Tools) > 0 {
+				sb.WriteString("工具调用: " + toolSummary(run.Tools) + "\n\n")
+				if showTools {
+					for _, call := range run.Tools {
+						mark := ""
+						if call.IsError {
+							mark = "（失败）"
+						}
+						fmt.Fprintf(&sb, "- `%s` %s%s\n", call.Name, call.Input, mark)
+					}
+					sb.WriteString("\n")
+				}
+			}
+			if len(run.Orders) == 0 {
+				sb.WriteString("决策: 未下单\n\n")
+			} else {
+				sb.WriteString("决策:\n\n")
+				for _, o := range run.Orders {
+					sb.WriteString("- " + o.describe() + "\n")
+				}
+				sb.WriteString("\n")
+			}
+			if reply := strings.TrimSpace(run.Reply); reply != "" {
+				sb.WriteString("<details><summary>模型回复</summary>\n\n" + reply + "\n\n</details>\n")
+			}
+		}
+
+		fmt.Fprintf(&sb, "\n收盘净值: %s 元，现金: %s 元\n", model.FormatAmount(day.NAV), model.FormatAmount(day.Cash))
+		for _, h := range day.Holdings {
+			fmt.Fprintf(&sb, "- 持仓 %s %d 股，收盘 %s，市值 %s，成本 %s\n",
+				stockLabel(h.Code, h.Name), h.Quantity, model.FormatAmount(h.Price), model.FormatAmount(h.Value), model.FormatAmount(h.Cost))
+		}
+	}
+	return sb.String()
+}
+
+// describe 订单与实际走势说明
+func (o Order) describe() string {
+	side := "买入"
+	if o.Side == model.TransactionTypeSell {
+		side = "卖出"
+	}
+	text := fmt.Sprintf("%s %s %s %d 股 @ %s %s", o.Time, side, stockLabel(o.Code, o.Name), o.Quantity, model.FormatAmount(o.Price), o.Status)
+	if o.Note != "" && o.Status == model.TransactionStatusRejected {
+		text += "（" + o.Note + "）"
+	}
+	if o.DayClose > 0 {
+		text += fmt.Sprintf("；实际: 当日收盘 %s（%s）", model.FormatAmount(o.DayClose), changeText(o.Price, o.DayClose))
+	}
+	if o.NextClose > 0 {
+		text += fmt.Sprintf("，%s 收盘 %s（%s）", o.NextDate, model.FormatAmount(o.NextClose), changeText(o.Price, o.NextClose))
+	}
+	return text
+}
+
+// toolSummary 按调用顺序汇总工具名称与次数
+func toolSummary(calls []ToolCall) string {
+	var names []string
+	counts := map[string]int{}
+	for _, call := range calls {
+		if counts[call.Name] == 0 {
+			names = append(names, call.Name)
+		}
+		counts[call.Name]++
+	}
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		if counts[name] > 1 {
+			parts = append(parts, fmt.Sprintf("%s×%d", name, counts[name]))
+		} else {
+			parts = append(parts, name)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// changeText 相对委托价的涨跌幅
+func changeText(base, price int64) string {
+	if base <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%+.2f%%", float64(price-base)/float64(base)*100)
+}
+
+// stockLabel 股票代码与名称，名称未知时只显示代码
+func stockLabel(code, name string) string {
+	if name == "" || name == code {
+		return code
+	}
+	return code + " " + name
+}
+
+// weekdayLabel 中文星期
+func weekdayLabel(t time.Time) string {
+	return [...]string{"星期日", "星期一", "星期二", "星期三", "星期四", "星期五", "星期六"}[t.In(calendar.Location).Weekday()]
+}
+
+// parseYuan 解析元为单位的价格并按分取整（毫），无效时为 0
+func parseYuan(s string) int64 {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return int64(math.Round(v*100)) * 100
+}
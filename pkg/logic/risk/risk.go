@@ -0,0 +1,420 @@
+// Package risk 组合风险分析：个股与行业权重、集中度、日收益相关性矩阵、组合波动率与历史 VaR，
+// 以及拟买入订单对这些指标的影响（what-if）
+package risk
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"msa/pkg/logic/finsvc"
+	"msa/pkg/model"
+)
+
+const (
+	// DefaultLookback 默认使用的日收益率数量
+	DefaultLookback = 60
+	// MaxLookback 日收益率数量上限
+	MaxLookback = 250
+	// DefaultConfidence 默认 VaR 置信度
+	DefaultConfidence = 0.95
+	// MaxIndustryWeight 单一申万一级行业占总资产的上限
+	MaxIndustryWeight = 0.5
+	// MaxSubIndustryWeight 单一申万二级行业占总资产的上限
+	MaxSubIndustryWeight = 0.3
+	// HighCorrelation 高度相关的相关系数阈值
+	HighCorrelation = 0.7
+	// UnknownIndustry 行业未知的股票
+	UnknownIndustry = "未分类"
+	// minObservations 个股有效日收益少于该数量时提示样本不足
+	minObservations = 20
+)
+
+// Holding 持仓，金额单位为毫
+type Holding struct {
+	Code        string
+	Name        string
+	Industry    string // 申万一级行业
+	SubIndustry string // 申万二级行业
+	Quantity    int64
+	Price       int64
+	Value       int64
+}
+
+// Input 风险分析输入
+type Input struct {
+	Holdings   []Holding
+	Cash       int64                       // 现金（可用 + 锁定，毫）
+	Bars       map[string][]model.KLineBar // 股票代码 → 日K线，按日期升序
+	Lookback   int                         // 日收益率数量，默认 DefaultLookback
+	Confidence float64                     // VaR 置信度（小数），默认 DefaultConfidence
+}
+
+// WithBuy 返回买入 order 后的输入：同一股票合并持仓，现金扣除 cost（成交金额 + 手续费）
+func (in *Input) WithBuy(order Holding, cost int64) *Input {
+	next := *in
+	next.Holdings = make([]Holding, 0, len(in.Holdings)+1)
+	merged := false
+	for _, h := range in.Holdings {
+		if h.Code == order.Code {
+			h.Quantity += order.Quantity
+			h.Price = order.Price
+			h.Value = h.Quantity * h.Price
+			merged = true
+		}
+		next.Holdings = append(next.Holdings, h)
+	}
+	if !merged {
+		order.Value = order.Quantity * order.Price
+		next.Holdings = append(next.Holdings, order)
+	}
+	next.Cash -= cost
+	return &next
+}
+
+// Report 风险分析结果，权重与收益率均为小数，权重以总资产为分母
+type Report struct {
+	NAV            int64   `json:"nav"`
+	Cash           int64   `json:"cash"`
+	PositionValue  int64   `json:"position_value"`
+	PositionWeight float64 `json:"position_weight"`
+
+	Stocks        []StockRisk      `json:"stocks"`
+	Industries    []IndustryWeight `json:"industries"`
+	SubIndustries []IndustryWeight `json:"sub_industries"`
+	Top1Weight    float64          `json:"top1_weight"`
+	Top3Weight    float64          `json:"top3_weight"`
+	HHI           float64          `json:"hhi"`         // 持仓内部权重的赫芬达尔指数
+	EffectiveN    float64          `json:"effective_n"` // 有效持仓数 = 1 / HHI
+
+	Codes            []string      `json:"codes"`
+	Correlation      [][]float64   `json:"correlation"` // 与 Codes 顺序一致的日收益相关系数矩阵
+	HighCorrelations []Correlation `json:"high_correlations,omitempty"`
+
+	Observations int     `json:"observations"` // 日收益率数量
+	Confidence   float64 `json:"confidence"`
+	Volatility   float64 `json:"volatility"` // 组合年化波动率（相对总资产，现金收益为 0）
+	VaR          float64 `json:"var"`        // 1 日历史 VaR（占总资产）
+	CVaR         float64 `json:"cvar"`       // 1 日条件 VaR（超过 VaR 的平均损失）
+	VaRAmount    int64   `json:"var_amount"`
+	CVaRAmount   int64   `json:"cvar_amount"`
+
+	Alerts   []string `json:"alerts,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// StockRisk 个股权重与波动率
+type StockRisk struct {
+	Code        string  `json:"code"`
+	Name        string  `json:"name,omitempty"`
+	Industry    string  `json:"industry"`
+	SubIndustry string  `json:"sub_industry,omitempty"`
+	Value       int64   `json:"value"`
+	Weight      float64 `json:"weight"`
+	Volatility  float64 `json:"volatility"` // 年化波动率
+}
+
+// IndustryWeight 行业权重
+type IndustryWeight struct {
+	Name   string   `json:"name"`
+	Value  int64    `json:"value"`
+	Weight float64  `json:"weight"`
+	Stocks []string `json:"stocks"`
+}
+
+// Correlation 两只股票的日收益相关系数
+type Correlation struct {
+	A     string  `json:"a"`
+	B     string  `json:"b"`
+	Value float64 `json:"value"`
+}
+
+// Analyze 计算组合风险指标
+func Analyze(in *Input) *Report {
+	lookback := in.Lookback
+	if lookback <= 0 {
+		lookback = DefaultLookback
+	}
+	lookback = min(lookback, MaxLookback)
+	confidence := in.Confidence
+	if confidence <= 0 || confidence >= 1 {
+		confidence = DefaultConfidence
+	}
+
+	holdings := make([]Holding, 0, len(in.Holdings))
+	r := &Report{Cash: in.Cash, Confidence: confidence}
+	for _, h := range in.Holdings {
+		if h.Quantity > 0 {
+			holdings = append(holdings, h)
+			r.PositionValue += h.Value
+		}
+	}
+	sort.SliceStable(holdings, func(i, j int) bool { return holdings[i].Value > holdings[j].Value })
+	r.NAV = r.Cash + r.PositionValue
+	if r.NAV <= 0 {
+		r.Warnings = append(r.Warnings, "总资产为 0，无法计算风险指标")
+		return r
+	}
+	nav := float64(r.NAV)
+	r.PositionWeight = float64(r.PositionValue) / nav
+
+	// 个股与行业权重、集中度
+	weights := make([]float64, len(holdings))
+	for i, h := range holdings {
+		weights[i] = float64(h.Value) / nav
+		if i < 1 {
+			r.Top1Weight += weights[i]
+		}
+		if i < 3 {
+			r.Top3Weight += weights[i]
+		}
+		if r.PositionValue > 0 {
+			share := float64(h.Value) / float64(r.PositionValue)
+			r.HHI += share * share
+		}
+	}
+	if r.HHI > 0 {
+		r.EffectiveN = 1 / r.HHI
+	}
+	r.Industries = groupWeights(holdings, nav, func(h Holding) string { return h.Industry })
+	r.SubIndustries = groupWeights(holdings, nav, func(h Holding) string { return h.SubIndustry })
+
+	// 日收益率、相关性与波动率
+	dates := returnDates(in.Bars, holdings, lookback)
+	r.Observations = len(dates)
+	series := make([][]float64, len(holdings))
+	for i, h := range holdings {
+		var observed int
+		series[i], observed = dailyReturns(in.Bars[h.Code], dates)
+		if observed < minObservations {
+			r.Warnings = append(r.Warnings, fmt.Sprintf("%s 只有 %d 个有效日收益，相关性与波动率仅供参考", h.Code, observed))
+		}
+		r.Stocks = append(r.Stocks, StockRisk{
+			Code: h.Code, Name: h.Name, Industry: h.Industry, SubIndustry: h.SubIndustry,
+			Value: h.Value, Weight: weights[i], Volatility: stddev(series[i]) * math.Sqrt(finsvc.TradingDaysPerYear),
+		})
+		r.Codes = append(r.Codes, h.Code)
+	}
+
+	r.Correlation = make([][]float64, len(holdings))
+	for i := range holdings {
+		r.Correlation[i] = make([]float64, len(holdings))
+		r.Correlation[i][i] = 1
+		for j := 0; j < i; j++ {
+			c := correlation(series[i], series[j])
+			r.Correlation[i][j], r.Correlation[j][i] = c, c
+			if c > HighCorrelation {
+				r.HighCorrelations = append(r.HighCorrelations, Correlation{A: holdings[j].Code, B: holdings[i].Code, Value: c})
+			}
+		}
+	}
+	sort.SliceStable(r.HighCorrelations, func(i, j int) bool { return r.HighCorrelations[i].Value > r.HighCorrelations[j].Value })
+
+	if len(holdings) > 0 && len(dates) >= 2 {
+		portfolio := make([]float64, len(dates))
+		for i := range holdings {
+			for t, ret := range series[i] {
+				portfolio[t] += weights[i] * ret
+			}
+		}
+		r.Volatility = stddev(portfolio) * math.Sqrt(finsvc.TradingDaysPerYear)
+		r.VaR, r.CVaR = historicalVaR(portfolio, confidence)
+		r.VaRAmount = int64(math.Round(r.VaR * nav))
+		r.CVaRAmount = int64(math.Round(r.CVaR * nav))
+	} else if len(holdings) > 0 {
+		r.Warnings = append(r.Warnings, "日K线不足，无法计算波动率与 VaR")
+	}
+
+	r.Alerts = alerts(r)
+	return r
+}
+
+// groupWeights 按行业汇总权重，按权重从高到低排序；行业为空时归入 UnknownIndustry
+func groupWeights(holdings []Holding, nav float64, key func(Holding) string) []IndustryWeight {
+	index := map[string]int{}
+	var list []IndustryWeight
+	for _, h := range holdings {
+		name := key(h)
+		if name == "" {
+			name = UnknownIndustry
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(list)
+			index[name] = i
+			list = append(list, IndustryWeight{Name: name})
+		}
+		list[i].Value += h.Value
+		list[i].Stocks = append(list[i].Stocks, h.Code)
+	}
+	for i := range list {
+		list[i].Weight = float64(list[i].Value) / nav
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Weight > list[j].Weight })
+	return list
+}
+
+// alerts 行业暴露超限与高度相关的持仓
+func alerts(r *Report) []string {
+	var list []string
+	for _, ind := range r.Industries {
+		if ind.Name != UnknownIndustry && ind.Weight > MaxIndustryWeight {
+			list = append(list, industryAlert("一级行业", ind, MaxIndustryWeight))
+		}
+	}
+	for _, ind := range r.SubIndustries {
+		if ind.Name != UnknownIndustry && ind.Weight > MaxSubIndustryWeight {
+			list = append(list, industryAlert("二级行业", ind, MaxSubIndustryWeight))
+		}
+	}
+	for _, c := range r.HighCorrelations {
+		list = append(list, correlationAlert(c))
+	}
+	return list
+}
+
+// AlertsFor 涉及 code 的告警：所属一级、二级行业暴露超限，以及与其高度相关的持仓
+// 用于 what-if 分析中判断拟买入股票会加重哪些风险
+func (r *Report) AlertsFor(code string) []string {
+	var stock *StockRisk
+	for i := range r.Stocks {
+		if r.Stocks[i].Code == code {
+			stock = &r.Stocks[i]
+		}
+	}
+	if stock == nil {
+		return nil
+	}
+
+	var list []string
+	for _, ind := range r.Industries {
+		if ind.Name == stock.Industry && ind.Name != UnknownIndustry && ind.Weight > MaxIndustryWeight {
+			list = append(list, industryAlert("一级行业", ind, MaxIndustryWeight))
+		}
+	}
+	for _, ind := range r.SubIndustries {
+		if ind.Name == stock.SubIndustry && ind.Name != UnknownIndustry && ind.Weight > MaxSubIndustryWeight {
+			list = append(list, industryAlert("二级行业", ind, MaxSubIndustryWeight))
+		}
+	}
+	for _, c := range r.HighCorrelations {
+		if c.A == code || c.B == code {
+			list = append(list, correlationAlert(c))
+		}
+	}
+	return list
+}
+
+func industryAlert(level string, ind IndustryWeight, limit float64) string {
+	return fmt.Sprintf("%s %s 暴露 %.1f%% 超过 %.0f%% 上限", level, ind.Name, ind.Weight*100, limit*100)
+}
+
+func correlationAlert(c Correlation) string {
+	return fmt.Sprintf("%s 与 %s 日收益相关系数 %.2f 超过 %.1f", c.A, c.B, c.Value, HighCorrelation)
+}
+
+// returnDates 持仓K线日期并集中最近 lookback 个日期（每个日期计算一个日收益）
+func returnDates(bars map[string][]model.KLineBar, holdings []Holding, lookback int) []string {
+	seen := map[string]bool{}
+	var dates []string
+	for _, h := range holdings {
+		for _, bar := range bars[h.Code] {
+			if !seen[bar.Date] {
+				seen[bar.Date] = true
+				dates = append(dates, bar.Date)
+			}
+		}
+	}
+	sort.Strings(dates)
+	// 第一个日期没有前收盘，不计算收益
+	if len(dates) > 0 {
+		dates = dates[1:]
+	}
+	if len(dates) > lookback {
+		dates = dates[len(dates)-lookback:]
+	}
+	return dates
+}
+
+// dailyReturns 按 dates 计算收盘价日收益率，当天没有K线（停牌、未上市）时收益为 0；observed 为有效收益数量
+func dailyReturns(bars []model.KLineBar, dates []string) (returns []float64, observed int) {
+	closes := make(map[string]float64, len(bars))
+	prev := make(map[string]float64, len(bars))
+	var last float64
+	for _, bar := range bars {
+		c, err := strconv.ParseFloat(bar.Close, 64)
+		if err != nil || c <= 0 {
+			continue
+		}
+		closes[bar.Date] = c
+		prev[bar.Date] = last
+		last = c
+	}
+
+	returns = make([]float64, len(dates))
+	for i, date := range dates {
+		if c, ok := closes[date]; ok && prev[date] > 0 {
+			returns[i] = c/prev[date] - 1
+			observed++
+		}
+	}
+	return returns, observed
+}
+
+// historicalVaR 历史模拟法：日收益率升序排列后取 (1 - confidence) 分位的损失，CVaR 为该分位及更差收益的平均损失
+func historicalVaR(returns []float64, confidence float64) (float64, float64) {
+	sorted := append([]float64(nil), returns...)
+	sort.Float64s(sorted)
+	// 1 - 0.95 等小数存在浮点误差，取整前减去极小量
+	k := int(math.Ceil((1-confidence)*float64(len(sorted))-1e-9)) - 1
+	k = max(0, min(k, len(sorted)-1))
+
+	var tail float64
+	for _, r := range sorted[:k+1] {
+		tail += r
+	}
+	return math.Max(-sorted[k], 0), math.Max(-tail/float64(k+1), 0)
+}
+
+// stddev 样本标准差
+func stddev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	var mean float64
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	var variance float64
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(variance / float64(len(values)-1))
+}
+
+// correlation Pearson 相关系数，任一序列波动为 0 时为 0
+func correlation(a, b []float64) float64 {
+	n := float64(len(a))
+	if n < 2 {
+		return 0
+	}
+	var meanA, meanB float64
+	for i := range a {
+		meanA += a[i]
+		meanB += b[i]
+	}
+	meanA /= n
+	meanB /= n
+	var cov, varA, varB float64
+	for i := range a {
+		cov += (a[i] - meanA) * (b[i] - meanB)
+		varA += (a[i] - meanA) * (a[i] - meanA)
+		varB += (b[i] - meanB) * (b[i] - meanB)
+	}
+	if varA == 0 || varB == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varA*varB)
+}
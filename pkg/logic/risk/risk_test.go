@@ -0,0 +1,144 @@
+package risk
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"msa/pkg/model"
+)
+
+// series 按收盘价序列生成日K线，日期从 2025-06-01 起连续编号
+func series(closes ...float64) []model.KLineBar {
+	list := make([]model.KLineBar, 0, len(closes))
+	for i, c := range closes {
+		list = append(list, model.KLineBar{Date: fmt.Sprintf("2025-06-%02d", i+1), Close: fmt.Sprintf("%.4f", c)})
+	}
+	return list
+}
+
+func holding(code, industry, sub string, qty int64, price float64) Holding {
+	p := model.YuanToHao(price)
+	return Holding{Code: code, Industry: industry, SubIndustry: sub, Quantity: qty, Price: p, Value: qty * p}
+}
+
+// newInput 两只白酒同涨同跌，一只银行走势相反
+func newInput() *Input {
+	liquor := []float64{100, 102, 101, 104, 103, 106, 105, 108, 107, 110, 108}
+	bank := []float64{10, 9.9, 10, 9.8, 9.9, 9.7, 9.8, 9.6, 9.7, 9.5, 9.6}
+	other := make([]float64, len(liquor))
+	for i, c := range liquor {
+		other[i] = c * 2
+	}
+	return &Input{
+		Holdings: []Holding{
+			holding("sh600519", "食品饮料", "白酒Ⅱ", 100, 108),
+			holding("sz000858", "食品饮料", "白酒Ⅱ", 100, 216),
+			holding("sh600000", "银行", "股份制银行Ⅱ", 1000, 9.6),
+		},
+		Cash: model.YuanToHao(15000),
+		Bars: map[string][]model.KLineBar{
+			"sh600519": series(liquor...),
+			"sz000858": series(other...),
+			"sh600000": series(bank...),
+		},
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	r := Analyze(newInput())
+
+	// 总资产 = 15000 + 10800 + 21600 + 9600
+	if r.NAV != model.YuanToHao(57000) || r.PositionValue != model.YuanToHao(42000) {
+		t.Fatalf("Unexpected nav: %d position: %d", r.NAV, r.PositionValue)
+	}
+	if len(r.Stocks) != 3 || r.Stocks[0].Code != "sz000858" || math.Abs(r.Top1Weight-21600.0/57000) > 1e-9 {
+		t.Errorf("Unexpected stocks: %+v top1=%v", r.Stocks, r.Top1Weight)
+	}
+	if len(r.Industries) != 2 || r.Industries[0].Name != "食品饮料" || math.Abs(r.Industries[0].Weight-32400.0/57000) > 1e-9 {
+		t.Errorf("Unexpected industries: %+v", r.Industries)
+	}
+	if r.EffectiveN <= 1 || r.EffectiveN >= 3 {
+		t.Errorf("Unexpected effective n: %v", r.EffectiveN)
+	}
+
+	if r.Observations != 10 || len(r.Correlation) != 3 {
+		t.Fatalf("Unexpected observations %d or matrix %v", r.Observations, r.Correlation)
+	}
+	if math.Abs(r.Correlation[0][1]-1) > 1e-9 || r.Correlation[0][2] >= 0 || r.Correlation[1][2] != r.Correlation[2][1] {
+		t.Errorf("Unexpected correlation: %v", r.Correlation)
+	}
+	if len(r.HighCorrelations) != 1 {
+		t.Errorf("Unexpected high correlations: %+v", r.HighCorrelations)
+	}
+	if r.Volatility <= 0 || r.VaR <= 0 || r.CVaR < r.VaR || r.VaRAmount != int64(math.Round(r.VaR*float64(r.NAV))) {
+		t.Errorf("Unexpected volatility/var: %v %v %v %d", r.Volatility, r.VaR, r.CVaR, r.VaRAmount)
+	}
+
+	// 食品饮料 56.8% > 50%，白酒Ⅱ 56.8% > 30%，两只白酒高度相关
+	if len(r.Alerts) != 3 || !strings.Contains(r.Alerts[0], "食品饮料") || !strings.Contains(r.Alerts[1], "白酒Ⅱ") {
+		t.Errorf("Unexpected alerts: %v", r.Alerts)
+	}
+	if len(r.Warnings) != 3 {
+		t.Errorf("Expected short-sample warnings: %v", r.Warnings)
+	}
+}
+
+func TestAnalyze_Empty(t *testing.T) {
+	r := Analyze(&Input{Cash: model.YuanToHao(1000)})
+	if r.NAV != model.YuanToHao(1000) || r.PositionWeight != 0 || r.Volatility != 0 || len(r.Alerts) != 0 {
+		t.Errorf("Unexpected cash-only report: %+v", r)
+	}
+
+	r = Analyze(&Input{})
+	if len(r.Warnings) != 1 {
+		t.Errorf("Expected warning for zero nav: %+v", r)
+	}
+}
+
+func TestWithBuy(t *testing.T) {
+	in := newInput()
+	order := holding("sh600519", "食品饮料", "白酒Ⅱ", 100, 110)
+	next := in.WithBuy(order, model.YuanToHao(11005))
+
+	if next.Cash != model.YuanToHao(3995) || len(next.Holdings) != 3 {
+		t.Fatalf("Unexpected what-if input: cash=%d holdings=%+v", next.Cash, next.Holdings)
+	}
+	if h := next.Holdings[0]; h.Quantity != 200 || h.Value != model.YuanToHao(22000) {
+		t.Errorf("Expected merged holding: %+v", h)
+	}
+	if in.Holdings[0].Quantity != 100 || in.Cash != model.YuanToHao(15000) {
+		t.Errorf("Original input modified: %+v", in)
+	}
+
+	next = in.WithBuy(holding("sz300750", "电力设备", "电池", 10, 200), model.YuanToHao(2005))
+	if len(next.Holdings) != 4 || next.Holdings[3].Value != model.YuanToHao(2000) {
+		t.Errorf("Expected new holding: %+v", next.Holdings)
+	}
+	r := Analyze(next)
+	if r.NAV != model.YuanToHao(56995) || len(r.Warnings) != 4 {
+		t.Errorf("Unexpected what-if report: nav=%d warnings=%v", r.NAV, r.Warnings)
+	}
+	if alerts := r.AlertsFor("sz300750"); len(alerts) != 0 {
+		t.Errorf("Unexpected alerts for unrelated stock: %v", alerts)
+	}
+	if alerts := r.AlertsFor("sh600519"); len(alerts) != 3 {
+		t.Errorf("Unexpected alerts for liquor stock: %v", alerts)
+	}
+	if alerts := r.AlertsFor("sh600000"); len(alerts) != 0 {
+		t.Errorf("Unexpected alerts for bank stock: %v", alerts)
+	}
+}
+
+func TestHistoricalVaR(t *testing.T) {
+	returns := make([]float64, 100)
+	for i := range returns {
+		returns[i] = float64(i-50) / 1000
+	}
+	v, c := historicalVaR(returns, 0.95)
+	// 最差 5 个收益 -0.050 ~ -0.046
+	if math.Abs(v-0.046) > 1e-12 || math.Abs(c-0.048) > 1e-12 {
+		t.Errorf("Unexpected var/cvar: %v %v", v, c)
+	}
+}
@@ -0,0 +1,222 @@
+// Package scheduler 按 skill 声明的触发时间段（SkillTrigger.Time）定时执行 skill
+// 每个 skill 每个交易日最多执行一次，执行记录保存在数据库中，重启后不会重复执行
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"msa/pkg/db"
+	"msa/pkg/logic/calendar"
+	"msa/pkg/logic/skills"
+	"msa/pkg/model"
+)
+
+// DefaultInterval 调度检查间隔
+const DefaultInterval = 30 * time.Second
+
+// Job 一个定时执行的 skill
+type Job struct {
+	Skill   string
+	Window  Window
+	Session string // SkillTrigger.Session 标签
+}
+
+// ExecuteFunc 执行 skill，返回执行会话ID
+type ExecuteFunc func(ctx context.Context, job Job) (sessionID string, err error)
+
+// JobsFromSkills 从 skill 的触发条件生成定时任务
+// only 非空时只调度其中的 skill；同一 skill 只取第一个带时间的触发条件，时间格式无效的记录警告后跳过
+// 任务按时间段开始时间排序
+func JobsFromSkills(list []*skills.Skill, only []string) []Job {
+	allowed := make(map[string]bool, len(only))
+	for _, name := range only {
+		allowed[name] = true
+	}
+
+	var jobs []Job
+	for _, sk := range list {
+		if len(allowed) > 0 && !allowed[sk.Name] {
+			continue
+		}
+		for _, trigger := range sk.Metadata.Triggers {
+			if trigger.Time == "" {
+				continue
+			}
+			window, err := ParseWindow(trigger.Time)
+			if err != nil {
+				log.Warnf("跳过 skill %s 的定时触发: %v", sk.Name, err)
+				continue
+			}
+			jobs = append(jobs, Job{Skill: sk.Name, Window: window, Session: trigger.Session})
+			break
+		}
+	}
+	sort.SliceStable(jobs, func(i, j int) bool { return jobs[i].Window.Start < jobs[j].Window.Start })
+	return jobs
+}
+
+// Scheduler 定时 skill 调度器
+type Scheduler struct {
+	Jobs    []Job
+	Execute ExecuteFunc
+	// Holidays 内置交易日历之外的休市日期（YYYY-MM-DD）
+	Holidays map[string]bool
+}
+
+// New 创建调度器
+func New(jobs []Job, holidays []string, execute ExecuteFunc) *Scheduler {
+	extra := make(map[string]bool, len(holidays))
+	for _, day := range holidays {
+		extra[day] = true
+	}
+	return &Scheduler{Jobs: jobs, Execute: execute, Holidays: extra}
+}
+
+// IsTradingDay 判断 t 所在日期是否为交易日：排除周末、内置休市日期与配置的休市日期
+func (s *Scheduler) IsTradingDay(t time.Time) bool {
+	return calendar.IsTradingDay(calendar.MarketCN, t) && !s.Holidays[tradeDate(t)]
+}
+
+// Tick 执行当前时间段内当日尚未执行的 skill，按任务顺序依次执行
+// 执行前先写入执行记录（RUNNING），执行中进程退出也不会在当日重复执行
+func (s *Scheduler) Tick(ctx context.Context, database *gorm.DB, now time.Time) ([]*model.ScheduleRun, error) {
+	if !s.IsTradingDay(now) {
+		return nil, nil
+	}
+
+	date := tradeDate(now)
+	var runs []*model.ScheduleRun
+	for _, job := range s.Jobs {
+		if ctx.Err() != nil {
+			break
+		}
+		if !job.Window.Contains(now) {
+			continue
+		}
+		existing, err := db.GetScheduleRun(database, job.Skill, date)
+		if err != nil {
+			return runs, err
+		}
+		if existing != nil {
+			continue
+		}
+
+		run := &model.ScheduleRun{
+			SkillName: job.Skill,
+			TradeDate: date,
+			Window:    job.Window.String(),
+			Status:    model.ScheduleRunStatusRunning,
+			StartedAt: now,
+		}
+		if err := db.CreateScheduleRun(database, run); err != nil {
+			// 其他进程已经开始执行
+			log.Warnf("写入执行记录失败，跳过 skill %s: %v", job.Skill, err)
+			continue
+		}
+
+		log.Infof("定时执行 skill: %s（%s）", job.Skill, run.Window)
+		sessionID, execErr := s.Execute(ctx, job)
+		finished := time.Now()
+		run.SessionID = sessionID
+		run.FinishedAt = &finished
+		run.Status = model.ScheduleRunStatusSuccess
+		if execErr != nil {
+			run.Status = model.ScheduleRunStatusFailed
+			run.Error = execErr.Error()
+			log.Errorf("定时执行 skill %s 失败: %v", job.Skill, execErr)
+		}
+		if err := db.SaveScheduleRun(database, run); err != nil {
+			return append(runs, run), err
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// Run 立即检查一次，之后按间隔循环，直到 ctx 结束时返回 nil
+// onRun 在每次执行结束后回调，可为 nil
+func (s *Scheduler) Run(ctx context.Context, database *gorm.DB, interval time.Duration, onRun func(*model.ScheduleRun)) error {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	now := time.Now()
+	for {
+		runs, err := s.Tick(ctx, database, now)
+		if err != nil {
+			log.Errorf("定时 skill 调度失败: %v", err)
+		}
+		if onRun != nil {
+			for _, run := range runs {
+				onRun(run)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case now = <-ticker.C:
+		}
+	}
+}
+
+// JobStatus 任务在某个交易日的状态
+type JobStatus struct {
+	Job     Job
+	State   string             // 今日状态说明
+	LastRun *model.ScheduleRun // 最近一次执行记录，可能为 nil
+}
+
+// Status 查询各任务在 now 所在日期的状态与最近一次执行记录
+func (s *Scheduler) Status(database *gorm.DB, now time.Time) ([]JobStatus, error) {
+	last, err := db.GetLastScheduleRuns(database)
+	if err != nil {
+		return nil, err
+	}
+
+	date := tradeDate(now)
+	tradingDay := s.IsTradingDay(now)
+	statuses := make([]JobStatus, 0, len(s.Jobs))
+	for _, job := range s.Jobs {
+		status := JobStatus{Job: job, LastRun: last[job.Skill]}
+		switch {
+		case status.LastRun != nil && status.LastRun.TradeDate == date:
+			status.State = describeRun(status.LastRun)
+		case !tradingDay:
+			status.State = "非交易日"
+		case job.Window.Passed(now):
+			status.State = "已错过"
+		case job.Window.Contains(now):
+			status.State = "待执行"
+		default:
+			status.State = "等待时间段"
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// describeRun 执行记录状态说明
+func describeRun(run *model.ScheduleRun) string {
+	switch run.Status {
+	case model.ScheduleRunStatusSuccess:
+		return "已执行"
+	case model.ScheduleRunStatusFailed:
+		return fmt.Sprintf("执行失败: %s", run.Error)
+	default:
+		return "执行中或已中断"
+	}
+}
+
+// tradeDate 交易所时区的日期
+func tradeDate(t time.Time) string {
+	return calendar.DateOf(t)
+}
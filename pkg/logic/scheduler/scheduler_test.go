@@ -0,0 +1,167 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	msadb "msa/pkg/db"
+	"msa/pkg/logic/calendar"
+	"msa/pkg/logic/skills"
+	"msa/pkg/model"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	database, err := msadb.InitDBWithPath(filepath.Join(t.TempDir(), "test.sqlite"))
+	if err != nil {
+		t.Fatalf("InitDBWithPath failed: %v", err)
+	}
+	if err := msadb.Migrate(database); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	t.Cleanup(func() { msadb.CloseDB(database) })
+	return database
+}
+
+func at(month time.Month, day, hour, minute int) time.Time {
+	return time.Date(2025, month, day, hour, minute, 0, 0, calendar.Location)
+}
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{"13:00-14:30", "13:00-14:30", false},
+		{"9:30-11:30", "09:30-11:30", false},
+		{" 16:00+ ", "16:00+", false},
+		{"14:30-13:00", "", true},
+		{"9-11", "", true},
+		{"25:00+", "", true},
+		{"morning", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			w, err := ParseWindow(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseWindow(%q) error = %v, wantErr %v", tt.text, err, tt.wantErr)
+			}
+			if err == nil && w.String() != tt.want {
+				t.Errorf("ParseWindow(%q) = %s, want %s", tt.text, w, tt.want)
+			}
+		})
+	}
+
+	w, _ := ParseWindow("13:00-14:30")
+	if !w.Contains(at(3, 3, 14, 30)) || w.Contains(at(3, 3, 14, 31)) || !w.Passed(at(3, 3, 14, 31)) {
+		t.Error("Unexpected window boundary")
+	}
+	if !w.Contains(time.Date(2025, 3, 3, 5, 0, 0, 0, time.UTC)) {
+		t.Error("Expected UTC time converted to exchange time zone")
+	}
+}
+
+func TestJobsFromSkills(t *testing.T) {
+	list := []*skills.Skill{
+		{Name: "afternoon-trade", Metadata: skills.SkillMetadata{Triggers: []skills.SkillTrigger{{Time: "13:00-14:30", Session: "afternoon-session"}}}},
+		{Name: "account-management", Metadata: skills.SkillMetadata{Triggers: []skills.SkillTrigger{{Keywords: []string{"开户"}}}}},
+		{Name: "broken", Metadata: skills.SkillMetadata{Triggers: []skills.SkillTrigger{{Time: "later"}}}},
+		{Name: "market-close-summary", Metadata: skills.SkillMetadata{Triggers: []skills.SkillTrigger{{Time: "16:00+"}}}},
+	}
+
+	jobs := JobsFromSkills(list, nil)
+	if len(jobs) != 2 || jobs[0].Skill != "afternoon-trade" || jobs[0].Session != "afternoon-session" || jobs[1].Window.String() != "16:00+" {
+		t.Fatalf("Unexpected jobs: %+v", jobs)
+	}
+	if jobs := JobsFromSkills(list, []string{"market-close-summary"}); len(jobs) != 1 || jobs[0].Skill != "market-close-summary" {
+		t.Errorf("Unexpected filtered jobs: %+v", jobs)
+	}
+}
+
+func TestTick(t *testing.T) {
+	database := setupTestDB(t)
+	afternoon, _ := ParseWindow("13:00-14:30")
+	closing, _ := ParseWindow("16:00+")
+
+	var executed []string
+	fail := false
+	s := New([]Job{{Skill: "afternoon-trade", Window: afternoon}, {Skill: "market-close-summary", Window: closing}},
+		[]string{"2025-03-04"},
+		func(ctx context.Context, job Job) (string, error) {
+			executed = append(executed, job.Skill)
+			if fail {
+				return "sess-failed", errors.New("model unavailable")
+			}
+			return "sess-" + job.Skill, nil
+		})
+	ctx := context.Background()
+
+	// 时间段外不执行；时间段内每个交易日只执行一次
+	if runs, _ := s.Tick(ctx, database, at(3, 3, 11, 0)); len(runs) != 0 {
+		t.Fatalf("Expected no run outside window, got %+v", runs)
+	}
+	runs, err := s.Tick(ctx, database, at(3, 3, 13, 5))
+	if err != nil || len(runs) != 1 || runs[0].Status != model.ScheduleRunStatusSuccess || runs[0].SessionID != "sess-afternoon-trade" {
+		t.Fatalf("Unexpected runs: %+v, %v", runs, err)
+	}
+
+	// 重启后（新调度器）同一交易日不重复执行
+	restarted := New(s.Jobs, nil, s.Execute)
+	if runs, _ := restarted.Tick(ctx, database, at(3, 3, 14, 0)); len(runs) != 0 {
+		t.Errorf("Expected no duplicate run after restart, got %+v", runs)
+	}
+
+	// 配置的休市日、周末与内置休市日不执行
+	for _, day := range []time.Time{at(3, 4, 13, 30), at(3, 8, 13, 30), at(10, 8, 13, 30)} {
+		if runs, _ := s.Tick(ctx, database, day); len(runs) != 0 {
+			t.Errorf("Expected no run on %s, got %+v", day.Format(time.DateOnly), runs)
+		}
+	}
+
+	// 执行失败记录原因，当日不再重试
+	fail = true
+	runs, _ = s.Tick(ctx, database, at(3, 5, 17, 0))
+	if len(runs) != 1 || runs[0].Status != model.ScheduleRunStatusFailed || runs[0].Error != "model unavailable" {
+		t.Fatalf("Unexpected failed run: %+v", runs)
+	}
+	s.Tick(ctx, database, at(3, 5, 18, 0))
+	if len(executed) != 2 {
+		t.Errorf("Unexpected executions: %v", executed)
+	}
+
+	statuses, err := s.Status(database, at(3, 5, 15, 0))
+	if err != nil || len(statuses) != 2 {
+		t.Fatalf("Status failed: %+v, %v", statuses, err)
+	}
+	if statuses[0].State != "已错过" || statuses[0].LastRun.TradeDate != "2025-03-03" {
+		t.Errorf("Unexpected afternoon status: %+v", statuses[0])
+	}
+	if statuses[1].State != "执行失败: model unavailable" {
+		t.Errorf("Unexpected close status: %+v", statuses[1])
+	}
+}
+
+func TestRun_StopsOnCancel(t *testing.T) {
+	database := setupTestDB(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- New(nil, nil, nil).Run(ctx, database, time.Hour, nil) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not stop after cancel")
+	}
+}
@@ -0,0 +1,94 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"msa/pkg/logic/calendar"
+)
+
+// endOfDay 一天最后一分钟
+const endOfDay = 23*60 + 59
+
+// Window skill 触发时间段，Start/End 为交易所时区当天的分钟数（闭区间）
+type Window struct {
+	Start int
+	End   int
+}
+
+// ParseWindow 解析 SkillTrigger.Time，时间按交易所时区解释
+// 支持 "13:00-14:30"（时间段）与 "16:00+"（从该时刻到当天结束）
+func ParseWindow(text string) (Window, error) {
+	text = strings.TrimSpace(text)
+	if strings.HasSuffix(text, "+") {
+		start, err := parseClock(strings.TrimSuffix(text, "+"))
+		if err != nil {
+			return Window{}, fmt.Errorf("触发时间无效 %q: %w", text, err)
+		}
+		return Window{Start: start, End: endOfDay}, nil
+	}
+
+	parts := strings.Split(text, "-")
+	if len(parts) != 2 {
+		return Window{}, fmt.Errorf("触发时间无效 %q: 格式应为 HH:MM-HH:MM 或 HH:MM+", text)
+	}
+	start, err := parseClock(parts[0])
+	if err != nil {
+		return Window{}, fmt.Errorf("触发时间无效 %q: %w", text, err)
+	}
+	end, err := parseClock(parts[1])
+	if err != nil {
+		return Window{}, fmt.Errorf("触发时间无效 %q: %w", text, err)
+	}
+	if end < start {
+		return Window{}, fmt.Errorf("触发时间无效 %q: 结束时间早于开始时间", text)
+	}
+	return Window{Start: start, End: end}, nil
+}
+
+// parseClock 解析 H:MM 为当天分钟数
+func parseClock(text string) (int, error) {
+	hour, minute, ok := strings.Cut(strings.TrimSpace(text), ":")
+	if !ok {
+		return 0, fmt.Errorf("时间 %q 缺少分钟", text)
+	}
+	h, err := strconv.Atoi(hour)
+	if err != nil || h < 0 || h > 23 {
+		return 0, fmt.Errorf("小时无效: %s", hour)
+	}
+	m, err := strconv.Atoi(minute)
+	if err != nil || m < 0 || m > 59 {
+		return 0, fmt.Errorf("分钟无效: %s", minute)
+	}
+	return h*60 + m, nil
+}
+
+// Contains 判断 t（按交易所时区）是否处于时间段内
+func (w Window) Contains(t time.Time) bool {
+	minute := minuteOfDay(t)
+	return minute >= w.Start && minute <= w.End
+}
+
+// Passed 判断 t 所在日期的时间段是否已经结束
+func (w Window) Passed(t time.Time) bool {
+	return minuteOfDay(t) > w.End
+}
+
+// String 格式化为 HH:MM-HH:MM，持续到当天结束时为 HH:MM+
+func (w Window) String() string {
+	if w.End == endOfDay {
+		return formatClock(w.Start) + "+"
+	}
+	return formatClock(w.Start) + "-" + formatClock(w.End)
+}
+
+func minuteOfDay(t time.Time) int {
+	local := t.In(calendar.Location)
+	return local.Hour()*60 + local.Minute()
+}
+
+func formatClock(minute int) string {
+	return fmt.Sprintf("%02d:%02d", minute/60, minute%60)
+}
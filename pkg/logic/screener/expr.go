@@ -0,0 +1,464 @@
+package screener
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expr 筛选表达式
+type Expr interface {
+	// Match 判断股票是否满足条件；字段缺失时比较结果为 false
+	Match(r *Record) bool
+}
+
+// Op 比较运算符
+type Op string
+
+const (
+	OpLT       Op = "<"
+	OpLE       Op = "<="
+	OpGT       Op = ">"
+	OpGE       Op = ">="
+	OpEQ       Op = "="
+	OpNE       Op = "!="
+	OpContains Op = "CONTAINS"
+)
+
+// andExpr 逻辑与
+type andExpr struct{ left, right Expr }
+
+func (e *andExpr) Match(r *Record) bool { return e.left.Match(r) && e.right.Match(r) }
+
+// orExpr 逻辑或
+type orExpr struct{ left, right Expr }
+
+func (e *orExpr) Match(r *Record) bool { return e.left.Match(r) || e.right.Match(r) }
+
+// notExpr 逻辑非
+type notExpr struct{ inner Expr }
+
+func (e *notExpr) Match(r *Record) bool { return !e.inner.Match(r) }
+
+// numberCompare 数值比较，右侧为常数或另一个数值字段
+type numberCompare struct {
+	field      string
+	op         Op
+	value      float64
+	rightField string
+}
+
+func (e *numberCompare) Match(r *Record) bool {
+	left, ok := r.Number(e.field)
+	if !ok {
+		return false
+	}
+	right := e.value
+	if e.rightField != "" {
+		if right, ok = r.Number(e.rightField); !ok {
+			return false
+		}
+	}
+
+	switch e.op {
+	case OpLT:
+		return left < right
+	case OpLE:
+		return left <= right
+	case OpGT:
+		return left > right
+	case OpGE:
+		return left >= right
+	case OpEQ:
+		return left == right
+	case OpNE:
+		return left != right
+	}
+	return false
+}
+
+// textCompare 文本比较；字段有多个值（如多个所属板块）时任一值满足即可，!= 要求所有值都不相等
+type textCompare struct {
+	field string
+	op    Op
+	value string
+}
+
+func (e *textCompare) Match(r *Record) bool {
+	values, ok := r.Text(e.field)
+	if !ok {
+		return false
+	}
+
+	switch e.op {
+	case OpEQ:
+		for _, v := range values {
+			if v == e.value {
+				return true
+			}
+		}
+		return false
+	case OpNE:
+		for _, v := range values {
+			if v == e.value {
+				return false
+			}
+		}
+		return true
+	case OpContains:
+		for _, v := range values {
+			if strings.Contains(v, e.value) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// Parse 解析筛选表达式
+// 语法：比较条件用 AND / OR / NOT 与括号组合，如 pe < 20 AND change_5d > 3 AND industry = "半导体"
+// 比较运算符：< <= > >= = != CONTAINS；关键字不区分大小写，也可写作 && || !
+// 数值字段可与常数或其他数值字段比较（如 price > ma20），文本字段只能与字符串比较
+func Parse(src string) (Expr, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+	return expr, nil
+}
+
+// Fields 获取表达式引用的字段
+func Fields(expr Expr) []string {
+	seen := map[string]bool{}
+	var fields []string
+	add := func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			fields = append(fields, name)
+		}
+	}
+
+	var walk func(Expr)
+	walk = func(e Expr) {
+		switch e := e.(type) {
+		case *andExpr:
+			walk(e.left)
+			walk(e.right)
+		case *orExpr:
+			walk(e.left)
+			walk(e.right)
+		case *notExpr:
+			walk(e.inner)
+		case *numberCompare:
+			add(e.field)
+			add(e.rightField)
+		case *textCompare:
+			add(e.field)
+		}
+	}
+	if expr != nil {
+		walk(expr)
+	}
+	return fields
+}
+
+// BoardHints 获取表达式中 board / industry 等值条件的取值，未指定股票池时用于确定板块
+// 只收集 AND 连接的条件，OR 与 NOT 分支中的取值不能缩小股票池
+func BoardHints(expr Expr) []string {
+	var hints []string
+	var walk func(Expr)
+	walk = func(e Expr) {
+		switch e := e.(type) {
+		case *andExpr:
+			walk(e.left)
+			walk(e.right)
+		case *textCompare:
+			if (e.field == FieldBoard || e.field == FieldIndustry) && e.op == OpEQ {
+				hints = append(hints, e.value)
+			}
+		}
+	}
+	if expr != nil {
+		walk(expr)
+	}
+	return hints
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOp
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// tokenize 词法分析
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	runes := []rune(src)
+	for i := 0; i < len(runes); {
+		c := runes[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokenLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokenRParen, ")", i})
+			i++
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(runes) && runes[end] != c {
+				end++
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			tokens = append(tokens, token{tokenString, string(runes[i+1 : end]), i})
+			i = end + 1
+		case c == '&' || c == '|':
+			if i+1 >= len(runes) || runes[i+1] != c {
+				return nil, fmt.Errorf("unexpected %q at position %d", c, i)
+			}
+			kind := tokenAnd
+			if c == '|' {
+				kind = tokenOr
+			}
+			tokens = append(tokens, token{kind, string(runes[i : i+2]), i})
+			i += 2
+		case strings.ContainsRune("<>=!", c):
+			start, op := i, string(c)
+			if i+1 < len(runes) && (runes[i+1] == '=' || (c == '<' && runes[i+1] == '>')) {
+				op += string(runes[i+1])
+			}
+			i += len(op)
+			switch op {
+			case "!":
+				tokens = append(tokens, token{tokenNot, op, start})
+				continue
+			case "==":
+				op = string(OpEQ)
+			case "<>":
+				op = string(OpNE)
+			}
+			tokens = append(tokens, token{tokenOp, op, start})
+		case unicode.IsDigit(c) || c == '.' || (c == '-' && i+1 < len(runes) && (unicode.IsDigit(runes[i+1]) || runes[i+1] == '.')):
+			end := i + 1
+			for end < len(runes) && (unicode.IsDigit(runes[end]) || runes[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, token{tokenNumber, string(runes[i:end]), i})
+			i = end
+		case c == '_' || unicode.IsLetter(c):
+			end := i + 1
+			for end < len(runes) && (runes[end] == '_' || unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end])) {
+				end++
+			}
+			word := string(runes[i:end])
+			switch strings.ToUpper(word) {
+			case "AND":
+				tokens = append(tokens, token{tokenAnd, word, i})
+			case "OR":
+				tokens = append(tokens, token{tokenOr, word, i})
+			case "NOT":
+				tokens = append(tokens, token{tokenNot, word, i})
+			case string(OpContains):
+				tokens = append(tokens, token{tokenOp, string(OpContains), i})
+			default:
+				tokens = append(tokens, token{tokenIdent, strings.ToLower(word), i})
+			}
+			i = end
+		default:
+			return nil, fmt.Errorf("unexpected %q at position %d", c, i)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+// parser 递归下降语法分析，优先级 NOT > AND > OR
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenAnd {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	switch tok := p.peek(); tok.kind {
+	case tokenNot:
+		p.next()
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notExpr{inner}, nil
+	case tokenLParen:
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokenRParen {
+			return nil, fmt.Errorf("missing ')' at position %d", p.peek().pos)
+		}
+		p.next()
+		return inner, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	left := p.next()
+	if !isOperand(left) {
+		return nil, unexpected(left, "field")
+	}
+	opTok := p.next()
+	if opTok.kind != tokenOp {
+		return nil, unexpected(opTok, "comparison operator")
+	}
+	right := p.next()
+	if !isOperand(right) {
+		return nil, unexpected(right, "value")
+	}
+
+	op := Op(opTok.text)
+	// 常数在左侧时交换左右两侧，统一为 字段 op 值
+	if left.kind != tokenIdent {
+		if right.kind != tokenIdent {
+			return nil, fmt.Errorf("comparison at position %d has no field", left.pos)
+		}
+		if op == OpContains {
+			return nil, fmt.Errorf("CONTAINS at position %d requires a text field on the left", opTok.pos)
+		}
+		left, right = right, left
+		op = flip(op)
+	}
+
+	def, ok := LookupField(left.text)
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q at position %d", left.text, left.pos)
+	}
+
+	if def.Kind == KindText {
+		if right.kind != tokenString {
+			return nil, fmt.Errorf("text field %q must be compared with a quoted string", def.Name)
+		}
+		if op != OpEQ && op != OpNE && op != OpContains {
+			return nil, fmt.Errorf("operator %s is not supported for text field %q", op, def.Name)
+		}
+		return &textCompare{field: def.Name, op: op, value: right.text}, nil
+	}
+
+	if op == OpContains {
+		return nil, fmt.Errorf("CONTAINS is not supported for numeric field %q", def.Name)
+	}
+	switch right.kind {
+	case tokenNumber:
+		value, err := strconv.ParseFloat(right.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", right.text, right.pos)
+		}
+		return &numberCompare{field: def.Name, op: op, value: value}, nil
+	case tokenIdent:
+		rightDef, ok := LookupField(right.text)
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q at position %d", right.text, right.pos)
+		}
+		if rightDef.Kind != KindNumber {
+			return nil, fmt.Errorf("cannot compare numeric field %q with text field %q", def.Name, rightDef.Name)
+		}
+		return &numberCompare{field: def.Name, op: op, rightField: rightDef.Name}, nil
+	default:
+		return nil, fmt.Errorf("numeric field %q must be compared with a number or numeric field", def.Name)
+	}
+}
+
+func isOperand(tok token) bool {
+	return tok.kind == tokenIdent || tok.kind == tokenNumber || tok.kind == tokenString
+}
+
+func unexpected(tok token, want string) error {
+	if tok.kind == tokenEOF {
+		return fmt.Errorf("expected %s at end of expression", want)
+	}
+	return fmt.Errorf("expected %s at position %d, got %q", want, tok.pos, tok.text)
+}
+
+// flip 交换比较两侧后的运算符
+func flip(op Op) Op {
+	switch op {
+	case OpLT:
+		return OpGT
+	case OpLE:
+		return OpGE
+	case OpGT:
+		return OpLT
+	case OpGE:
+		return OpLE
+	}
+	return op
+}
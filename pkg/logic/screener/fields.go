@@ -0,0 +1,141 @@
+package screener
+
+import "sort"
+
+// Kind 字段类型
+type Kind string
+
+const (
+	KindNumber Kind = "number"
+	KindText   Kind = "text"
+)
+
+// Source 字段数据来源，决定筛选时需要获取哪些数据
+type Source string
+
+const (
+	// SourceUniverse 构建股票池时已知（代码、名称、所属板块）
+	SourceUniverse Source = "universe"
+	// SourceQuote 批量行情快照
+	SourceQuote Source = "quote"
+	// SourceKLine 本地缓存的日K线
+	SourceKLine Source = "kline"
+	// SourceIndustry 行业分类接口
+	SourceIndustry Source = "industry"
+)
+
+// 文本字段
+const (
+	FieldCode     = "code"
+	FieldName     = "name"
+	FieldBoard    = "board"
+	FieldIndustry = "industry"
+)
+
+// Field 可用于筛选与排序的字段
+type Field struct {
+	Name        string `json:"name"`
+	Kind        Kind   `json:"kind"`
+	Source      Source `json:"source"`
+	Description string `json:"description"`
+}
+
+var fields = map[string]Field{}
+
+func init() {
+	for _, f := range []Field{
+		{FieldCode, KindText, SourceUniverse, "股票代码"},
+		{FieldName, KindText, SourceUniverse, "股票名称"},
+		{FieldBoard, KindText, SourceUniverse, "股票池所属板块名称"},
+		{FieldIndustry, KindText, SourceIndustry, "申万行业分类（一级、二级）"},
+
+		{"price", KindNumber, SourceQuote, "最新价"},
+		{"change_pct", KindNumber, SourceQuote, "当日涨跌幅（%）"},
+		{"pe", KindNumber, SourceQuote, "市盈率（TTM）"},
+		{"pb", KindNumber, SourceQuote, "市净率"},
+		{"turnover_rate", KindNumber, SourceQuote, "换手率（%）"},
+		{"amplitude", KindNumber, SourceQuote, "振幅（%）"},
+		{"volume", KindNumber, SourceQuote, "成交量（手）"},
+		{"turnover", KindNumber, SourceQuote, "成交额（万元）"},
+		{"market_cap", KindNumber, SourceQuote, "总市值（亿元）"},
+		{"float_cap", KindNumber, SourceQuote, "流通市值（亿元）"},
+
+		{"change_5d", KindNumber, SourceKLine, "近 5 日涨跌幅（%）"},
+		{"change_20d", KindNumber, SourceKLine, "近 20 日涨跌幅（%）"},
+		{"change_60d", KindNumber, SourceKLine, "近 60 日涨跌幅（%）"},
+		{"ma5", KindNumber, SourceKLine, "5 日均线"},
+		{"ma10", KindNumber, SourceKLine, "10 日均线"},
+		{"ma20", KindNumber, SourceKLine, "20 日均线"},
+		{"ma60", KindNumber, SourceKLine, "60 日均线"},
+		{"rsi14", KindNumber, SourceKLine, "14 日 RSI"},
+		{"vol_ratio", KindNumber, SourceKLine, "最新成交量 / 前 5 日均量"},
+		{"atr_pct", KindNumber, SourceKLine, "ATR(14) / 收盘价（%）"},
+	} {
+		fields[f.Name] = f
+	}
+}
+
+// LookupField 查找字段定义
+func LookupField(name string) (Field, bool) {
+	f, ok := fields[name]
+	return f, ok
+}
+
+// barQuoteFields 可由日K线得到的行情字段
+var barQuoteFields = map[string]bool{"price": true, "change_pct": true, "volume": true, "amplitude": true}
+
+// AvailableFromBars 判断字段能否只用日K线得到（BarRecord 提供的字段）
+func AvailableFromBars(name string) bool {
+	f, ok := fields[name]
+	if !ok {
+		return false
+	}
+	return f.Source == SourceKLine || barQuoteFields[name] || name == FieldCode || name == FieldName
+}
+
+// ListFields 获取所有字段，按数据来源与名称排序
+func ListFields() []Field {
+	order := map[Source]int{SourceUniverse: 0, SourceIndustry: 1, SourceQuote: 2, SourceKLine: 3}
+	list := make([]Field, 0, len(fields))
+	for _, f := range fields {
+		list = append(list, f)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if order[list[i].Source] != order[list[j].Source] {
+			return order[list[i].Source] < order[list[j].Source]
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// Record 参与筛选的股票数据
+type Record struct {
+	Code     string
+	Name     string
+	Boards   []string
+	Industry []string
+	// Values 数值字段，获取失败或数据不足的字段不在其中
+	Values map[string]float64
+}
+
+// Number 获取数值字段
+func (r *Record) Number(field string) (float64, bool) {
+	v, ok := r.Values[field]
+	return v, ok
+}
+
+// Text 获取文本字段
+func (r *Record) Text(field string) ([]string, bool) {
+	switch field {
+	case FieldCode:
+		return []string{r.Code}, true
+	case FieldName:
+		return []string{r.Name}, r.Name != ""
+	case FieldBoard:
+		return r.Boards, len(r.Boards) > 0
+	case FieldIndustry:
+		return r.Industry, len(r.Industry) > 0
+	}
+	return nil, false
+}
@@ -0,0 +1,501 @@
+package screener
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"msa/pkg/logic/indicator"
+	"msa/pkg/logic/marketdata"
+	"msa/pkg/model"
+)
+
+const (
+	// DefaultBoardType 默认板块类型（申万行业）
+	DefaultBoardType = "01"
+	// DefaultBoardCount 未指定股票池时取涨幅前 N 的板块
+	DefaultBoardCount = 10
+	// DefaultBoardStockCount 每个板块获取的成分股数量
+	DefaultBoardStockCount = 200
+	// MaxUniverse 股票池上限，超出部分不参与筛选
+	MaxUniverse = 1000
+	// DefaultPageSize 默认每页数量
+	DefaultPageSize = 20
+	// MaxPageSize 每页最大数量
+	MaxPageSize = 100
+	// DefaultSortBy 默认排序字段
+	DefaultSortBy = "change_pct"
+
+	// klineCount 计算K线字段所需的日K线数（60 日涨跌幅需要 61 根）
+	klineCount = 70
+	// fetchConcurrency 逐只获取K线、行业时的并发数
+	fetchConcurrency = 8
+	// boardRankCount 按名称查找板块时获取的板块排行数量
+	boardRankCount = 500
+)
+
+// KLineFunc 获取日K线（前复权，最近 count 根），由调用方决定是否经过本地缓存
+type KLineFunc func(stockCode string, count int) ([]model.KLineBar, error)
+
+// Options 筛选参数
+type Options struct {
+	Expression string   // 筛选表达式，为空时不过滤
+	Codes      []string // 指定股票代码，与板块成分股合并为股票池
+	Boards     []string // 板块代码（板块排行的 bd_code）或板块名称
+	BoardType  string   // 按名称查找板块及默认股票池使用的板块类型，默认 01
+	BoardCount int      // 未指定股票池时取涨幅前 N 的板块，默认 10
+	StockCount int      // 每个板块获取的成分股数量，默认 200
+	SortBy     string   // 排序字段，默认 change_pct
+	Ascending  bool     // 升序排序，默认降序
+	Page       int      // 页码，从 1 开始
+	PageSize   int      // 每页数量，默认 20，最多 100
+}
+
+// Item 筛选结果中的股票
+type Item struct {
+	Rank     int                `json:"rank"`
+	Code     string             `json:"code"`
+	Name     string             `json:"name"`
+	Boards   []string           `json:"boards,omitempty"`
+	Industry []string           `json:"industry,omitempty"`
+	Values   map[string]float64 `json:"values"`
+}
+
+// Result 筛选结果
+type Result struct {
+	Expression string   `json:"expression"`
+	Boards     []string `json:"boards,omitempty"` // 构成股票池的板块
+	Universe   int      `json:"universe"`         // 股票池数量
+	Matched    int      `json:"matched"`          // 满足条件的数量
+	SortBy     string   `json:"sort_by"`
+	Order      string   `json:"order"`
+	Page       int      `json:"page"`
+	PageSize   int      `json:"page_size"`
+	TotalPages int      `json:"total_pages"`
+	Items      []Item   `json:"items"`
+	Warnings   []string `json:"warnings,omitempty"`
+}
+
+// Screen 构建股票池、按需获取数据、筛选并排序分页
+// 只获取表达式与排序字段用到的数据：行情快照总是获取，K线与行业分类仅在引用相关字段时逐只获取
+func Screen(provider marketdata.MarketDataProvider, klines KLineFunc, opts Options) (*Result, error) {
+	opts = opts.withDefaults()
+
+	var expr Expr
+	if strings.TrimSpace(opts.Expression) != "" {
+		var err error
+		if expr, err = Parse(opts.Expression); err != nil {
+			return nil, fmt.Errorf("invalid expression: %w", err)
+		}
+	}
+	sortField, ok := LookupField(opts.SortBy)
+	if !ok || sortField.Kind != KindNumber {
+		return nil, fmt.Errorf("sort_by must be a numeric field, got %q", opts.SortBy)
+	}
+
+	result := &Result{Expression: opts.Expression, SortBy: opts.SortBy, Order: "desc", Page: opts.Page, PageSize: opts.PageSize}
+	if opts.Ascending {
+		result.Order = "asc"
+	}
+
+	records, err := buildUniverse(provider, opts, expr, result)
+	if err != nil {
+		return nil, err
+	}
+	result.Universe = len(records)
+
+	sources := map[Source]bool{SourceQuote: true}
+	for _, name := range append(Fields(expr), opts.SortBy) {
+		f, _ := LookupField(name)
+		sources[f.Source] = true
+	}
+	loadQuotes(provider, records, result)
+	if sources[SourceKLine] {
+		loadKLines(klines, records, result)
+	}
+	if sources[SourceIndustry] {
+		loadIndustry(provider, records, result)
+	}
+
+	var matched []*Record
+	for _, r := range records {
+		if expr == nil || expr.Match(r) {
+			matched = append(matched, r)
+		}
+	}
+	rankRecords(matched, opts.SortBy, opts.Ascending)
+	result.Matched = len(matched)
+	result.TotalPages = (len(matched) + opts.PageSize - 1) / opts.PageSize
+
+	start := (opts.Page - 1) * opts.PageSize
+	end := min(start+opts.PageSize, len(matched))
+	result.Items = []Item{}
+	for i := start; i < end; i++ {
+		r := matched[i]
+		result.Items = append(result.Items, Item{
+			Rank: i + 1, Code: r.Code, Name: r.Name, Boards: r.Boards, Industry: r.Industry, Values: r.Values,
+		})
+	}
+	return result, nil
+}
+
+func (o Options) withDefaults() Options {
+	if o.BoardType == "" {
+		o.BoardType = DefaultBoardType
+	}
+	if o.BoardCount <= 0 {
+		o.BoardCount = DefaultBoardCount
+	}
+	if o.StockCount <= 0 {
+		o.StockCount = DefaultBoardStockCount
+	}
+	o.SortBy = strings.ToLower(strings.TrimSpace(o.SortBy))
+	if o.SortBy == "" {
+		o.SortBy = DefaultSortBy
+	}
+	if o.Page <= 0 {
+		o.Page = 1
+	}
+	if o.PageSize <= 0 {
+		o.PageSize = DefaultPageSize
+	}
+	if o.PageSize > MaxPageSize {
+		o.PageSize = MaxPageSize
+	}
+	return o
+}
+
+// buildUniverse 构建股票池
+// 优先使用指定的代码与板块；都未指定时使用表达式中 board / industry 等值条件对应的板块，再没有时取涨幅前 N 的板块
+func buildUniverse(provider marketdata.MarketDataProvider, opts Options, expr Expr, result *Result) ([]*Record, error) {
+	var records []*Record
+	truncated := false
+	index := map[string]*Record{}
+	add := func(code, name, board string) {
+		code = strings.ToLower(strings.TrimSpace(code))
+		if code == "" {
+			return
+		}
+		r, ok := index[code]
+		if !ok {
+			if len(records) >= MaxUniverse {
+				truncated = true
+				return
+			}
+			r = &Record{Code: code, Name: name, Values: map[string]float64{}}
+			index[code] = r
+			records = append(records, r)
+		}
+		if r.Name == "" {
+			r.Name = name
+		}
+		if board != "" && !containsString(r.Boards, board) {
+			r.Boards = append(r.Boards, board)
+		}
+	}
+
+	for _, code := range opts.Codes {
+		add(code, "", "")
+	}
+
+	var targets []model.BoardItem
+	if len(opts.Boards) > 0 {
+		var err error
+		if targets, err = resolveBoards(provider, opts.BoardType, opts.Boards); err != nil {
+			return nil, err
+		}
+	} else if hints := BoardHints(expr); len(hints) > 0 && len(opts.Codes) == 0 {
+		// 表达式中的板块名称找不到时退回默认股票池
+		var err error
+		if targets, err = resolveBoards(provider, opts.BoardType, hints); err != nil {
+			log.Warnf("screen: 按表达式确定板块失败: %v", err)
+			targets = nil
+		}
+	}
+
+	if len(targets) == 0 && len(opts.Codes) == 0 {
+		items, err := provider.GetBoardRank(opts.BoardType, 0, opts.BoardCount)
+		if err != nil {
+			return nil, fmt.Errorf("get board rank failed: %w", err)
+		}
+		targets = items
+		result.Warnings = append(result.Warnings, fmt.Sprintf("未指定股票池，使用涨幅前 %d 的板块成分股", len(items)))
+	}
+
+	for _, board := range targets {
+		stocks, err := provider.GetBoardStocks(board.BdCode, opts.StockCount)
+		if err != nil {
+			log.Warnf("screen: 获取板块成分股失败 %s(%s): %v", board.BdName, board.BdCode, err)
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s 成分股获取失败: %v", board.BdName, err))
+			continue
+		}
+		result.Boards = append(result.Boards, board.BdName)
+		for _, s := range stocks {
+			add(s.Code, s.Name, board.BdName)
+		}
+	}
+
+	if len(records) == 0 {
+		return nil, fmt.Errorf("stock universe is empty")
+	}
+	if truncated {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("股票池超过 %d 只，超出部分未参与筛选", MaxUniverse))
+	}
+	return records, nil
+}
+
+// resolveBoards 将板块代码或名称解析为板块
+// 名称优先完全匹配，其次包含匹配；看起来像代码（pt 前缀或数字）的直接使用
+func resolveBoards(provider marketdata.MarketDataProvider, boardType string, specs []string) ([]model.BoardItem, error) {
+	var rank []model.BoardItem
+	var rankErr error
+	rankLoaded := false
+
+	var boards []model.BoardItem
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		if isBoardCode(spec) {
+			boards = append(boards, model.BoardItem{BdCode: spec, BdName: spec})
+			continue
+		}
+
+		if !rankLoaded {
+			rank, rankErr = provider.GetBoardRank(boardType, 0, boardRankCount)
+			rankLoaded = true
+		}
+		if rankErr != nil {
+			return nil, fmt.Errorf("get board rank failed: %w", rankErr)
+		}
+		item, ok := findBoard(rank, spec)
+		if !ok {
+			return nil, fmt.Errorf("board %q not found in board type %s", spec, boardType)
+		}
+		boards = append(boards, item)
+	}
+	return boards, nil
+}
+
+func findBoard(rank []model.BoardItem, name string) (model.BoardItem, bool) {
+	for _, item := range rank {
+		if item.BdName == name {
+			return item, true
+		}
+	}
+	for _, item := range rank {
+		if strings.Contains(item.BdName, name) {
+			return item, true
+		}
+	}
+	return model.BoardItem{}, false
+}
+
+func isBoardCode(s string) bool {
+	if strings.HasPrefix(s, "pt") {
+		return true
+	}
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+// loadQuotes 批量获取行情快照并填充行情字段
+func loadQuotes(provider marketdata.MarketDataProvider, records []*Record, result *Result) {
+	codes := make([]string, len(records))
+	for i, r := range records {
+		codes[i] = r.Code
+	}
+	quotes, err := provider.GetQuotes(codes)
+	if err != nil {
+		log.Warnf("screen: 批量获取行情失败: %v", err)
+		result.Warnings = append(result.Warnings, fmt.Sprintf("行情获取失败: %v", err))
+		return
+	}
+
+	missing := 0
+	for _, r := range records {
+		q, ok := quotes[r.Code]
+		if !ok {
+			missing++
+			continue
+		}
+		if q.StockName != "" {
+			r.Name = q.StockName
+		}
+		setNumber(r, "price", q.CurrentPrice)
+		setNumber(r, "change_pct", q.ChangePercent)
+		setNumber(r, "pe", q.PERatio)
+		setNumber(r, "pb", q.PBRatio)
+		setNumber(r, "turnover_rate", q.TurnoverRate)
+		setNumber(r, "amplitude", q.Amplitude)
+		setNumber(r, "volume", q.Volume)
+		setNumber(r, "turnover", q.Turnover)
+		setNumber(r, "market_cap", q.MarketCap)
+		setNumber(r, "float_cap", q.FloatCap)
+	}
+	if missing > 0 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("%d 只股票缺少行情", missing))
+	}
+}
+
+// loadKLines 逐只获取日K线并计算K线字段
+func loadKLines(klines KLineFunc, records []*Record, result *Result) {
+	failed := forEachRecord(records, func(r *Record) error {
+		bars, err := klines(r.Code, klineCount)
+		if err != nil {
+			return err
+		}
+		series, err := indicator.FromKLineBars(bars)
+		if err != nil {
+			return err
+		}
+		setKLineValues(r, series)
+		return nil
+	})
+	if failed > 0 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("%d 只股票K线获取失败", failed))
+	}
+}
+
+// setKLineValues 根据日K线计算涨跌幅、均线、RSI、量比与 ATR%，K线不足的字段不设置
+func setKLineValues(r *Record, bars []indicator.Bar) {
+	if len(bars) == 0 {
+		return
+	}
+	closes := indicator.Closes(bars)
+	last := closes[len(closes)-1]
+
+	for _, n := range []int{5, 20, 60} {
+		if len(closes) > n && closes[len(closes)-1-n] > 0 {
+			r.Values[fmt.Sprintf("change_%dd", n)] = round2((last/closes[len(closes)-1-n] - 1) * 100)
+		}
+	}
+	for _, n := range []int{5, 10, 20, 60} {
+		if v, ok := indicator.Last(indicator.SMA(closes, n)); ok {
+			r.Values[fmt.Sprintf("ma%d", n)] = round2(v)
+		}
+	}
+	if v, ok := indicator.Last(indicator.RSI(closes, 14)); ok {
+		r.Values["rsi14"] = round2(v)
+	}
+	if v, ok := indicator.Last(indicator.ATR(bars, 14)); ok && last > 0 {
+		r.Values["atr_pct"] = round2(v / last * 100)
+	}
+	if len(bars) > 5 {
+		var sum float64
+		for _, b := range bars[len(bars)-6 : len(bars)-1] {
+			sum += b.Volume
+		}
+		if sum > 0 {
+			r.Values["vol_ratio"] = round2(bars[len(bars)-1].Volume / (sum / 5))
+		}
+	}
+}
+
+// BarRecord 用截至某日的日K线构建筛选记录，用于回测等只有历史K线、没有行情快照的场景
+// price、change_pct、volume、amplitude 取最后一根K线，其余K线字段与实时筛选一样只使用最近 klineCount 根K线计算
+func BarRecord(code, name string, bars []indicator.Bar) *Record {
+	r := &Record{Code: code, Name: name, Values: map[string]float64{}}
+	if len(bars) == 0 {
+		return r
+	}
+	if len(bars) > klineCount {
+		bars = bars[len(bars)-klineCount:]
+	}
+	last := bars[len(bars)-1]
+	r.Values["price"] = last.Close
+	r.Values["volume"] = last.Volume
+	if len(bars) > 1 && bars[len(bars)-2].Close > 0 {
+		prevClose := bars[len(bars)-2].Close
+		r.Values["change_pct"] = round2((last.Close/prevClose - 1) * 100)
+		r.Values["amplitude"] = round2((last.High - last.Low) / prevClose * 100)
+	}
+	setKLineValues(r, bars)
+	return r
+}
+
+// loadIndustry 逐只获取申万行业分类
+func loadIndustry(provider marketdata.MarketDataProvider, records []*Record, result *Result) {
+	failed := forEachRecord(records, func(r *Record) error {
+		resp, err := provider.GetIndustry(r.Code)
+		if err != nil {
+			return err
+		}
+		for _, plate := range resp.Data.Gsjj.Plate {
+			r.Industry = append(r.Industry, plate.Name)
+		}
+		return nil
+	})
+	if failed > 0 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("%d 只股票行业分类获取失败", failed))
+	}
+}
+
+// forEachRecord 并发处理每只股票，返回失败数量；fn 只修改自身的 Record
+func forEachRecord(records []*Record, fn func(r *Record) error) int {
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed int
+	)
+	sem := make(chan struct{}, fetchConcurrency)
+	for _, r := range records {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(r *Record) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := fn(r); err != nil {
+				log.Warnf("screen: %s 数据获取失败: %v", r.Code, err)
+				mu.Lock()
+				failed++
+				mu.Unlock()
+			}
+		}(r)
+	}
+	wg.Wait()
+	return failed
+}
+
+// rankRecords 按字段排序，缺少该字段的股票排在最后，相同时按代码排序
+func rankRecords(records []*Record, field string, ascending bool) {
+	sort.SliceStable(records, func(i, j int) bool {
+		a, okA := records[i].Number(field)
+		b, okB := records[j].Number(field)
+		if okA != okB {
+			return okA
+		}
+		if !okA || a == b {
+			return records[i].Code < records[j].Code
+		}
+		if ascending {
+			return a < b
+		}
+		return a > b
+	})
+}
+
+func setNumber(r *Record, field, value string) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err == nil && !math.IsNaN(v) && !math.IsInf(v, 0) {
+		r.Values[field] = v
+	}
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
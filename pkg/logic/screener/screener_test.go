@@ -0,0 +1,258 @@
+package screener
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"msa/pkg/logic/indicator"
+	"msa/pkg/logic/marketdata"
+	"msa/pkg/model"
+)
+
+// fakeProvider 内存中的板块、行情与行业数据
+type fakeProvider struct {
+	marketdata.MarketDataProvider
+	rank     []model.BoardItem
+	stocks   map[string][]model.BoardStock
+	quotes   map[string]*model.StockQuote
+	industry map[string][]string
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (p *fakeProvider) count(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls[name]++
+}
+
+func (p *fakeProvider) GetBoardRank(boardType string, order int, count int) ([]model.BoardItem, error) {
+	p.count("rank")
+	if count > 0 && len(p.rank) > count {
+		return p.rank[:count], nil
+	}
+	return p.rank, nil
+}
+
+func (p *fakeProvider) GetBoardStocks(boardCode string, count int) ([]model.BoardStock, error) {
+	stocks, ok := p.stocks[boardCode]
+	if !ok {
+		return nil, fmt.Errorf("unknown board %s", boardCode)
+	}
+	return stocks, nil
+}
+
+func (p *fakeProvider) GetQuotes(codes []string) (map[string]*model.StockQuote, error) {
+	p.count("quotes")
+	result := map[string]*model.StockQuote{}
+	for _, code := range codes {
+		if q, ok := p.quotes[code]; ok {
+			result[code] = q
+		}
+	}
+	return result, nil
+}
+
+func (p *fakeProvider) GetIndustry(code string) (*model.StockIndustryResp, error) {
+	p.count("industry")
+	resp := &model.StockIndustryResp{}
+	for _, name := range p.industry[code] {
+		resp.Data.Gsjj.Plate = append(resp.Data.Gsjj.Plate, model.IndustryPlate{Name: name})
+	}
+	return resp, nil
+}
+
+func newFakeProvider() *fakeProvider {
+	quote := func(name, price, change, pe string) *model.StockQuote {
+		return &model.StockQuote{StockName: name, CurrentPrice: price, ChangePercent: change, PERatio: pe}
+	}
+	return &fakeProvider{
+		rank: []model.BoardItem{
+			{BdCode: "pt01801081", BdName: "半导体"},
+			{BdCode: "pt01801780", BdName: "银行"},
+		},
+		stocks: map[string][]model.BoardStock{
+			"pt01801081": {{Code: "sh688981", Name: "中芯国际"}, {Code: "sh603501", Name: "韦尔股份"}, {Code: "sz002371", Name: "北方华创"}},
+			"pt01801780": {{Code: "sh601398", Name: "工商银行"}, {Code: "sh600036", Name: "招商银行"}},
+		},
+		quotes: map[string]*model.StockQuote{
+			"sh688981": quote("中芯国际", "90.00", "2.50", "95.0"),
+			"sh603501": quote("韦尔股份", "110.00", "4.10", "18.5"),
+			"sz002371": quote("北方华创", "400.00", "1.20", "-"),
+			"sh601398": quote("工商银行", "7.00", "0.30", "6.2"),
+			"sh600036": quote("招商银行", "42.00", "-0.80", "7.1"),
+		},
+		industry: map[string][]string{
+			"sh688981": {"电子", "半导体"},
+			"sh603501": {"电子", "半导体"},
+			"sz002371": {"电子", "半导体"},
+			"sh601398": {"银行", "国有大型银行"},
+			"sh600036": {"银行", "股份制银行"},
+		},
+		calls: map[string]int{},
+	}
+}
+
+// risingKLines 生成按固定日涨幅上涨的日K线
+func risingKLines(n int, dailyPct float64) []model.KLineBar {
+	bars := make([]model.KLineBar, n)
+	price := 10.0
+	for i := range bars {
+		price *= 1 + dailyPct/100
+		c := fmt.Sprintf("%.4f", price)
+		bars[i] = model.KLineBar{Date: fmt.Sprintf("2025-01-%02d", i%28+1), Open: c, Close: c, High: c, Low: c, Volume: "100"}
+	}
+	return bars
+}
+
+func TestParse(t *testing.T) {
+	r := &Record{Code: "sh603501", Name: "韦尔股份", Boards: []string{"半导体"}, Industry: []string{"电子", "半导体"},
+		Values: map[string]float64{"pe": 18.5, "change_5d": 4, "price": 110, "ma20": 100}}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`pe < 20 AND change_5d > 3 AND industry = "半导体"`, true},
+		{`pe < 20 and change_5d > 5`, false},
+		{`pe > 50 OR (price > ma20 AND NOT industry = "银行")`, true},
+		{`20 > pe && name CONTAINS '韦尔'`, true},
+		{`industry != "半导体"`, false},
+		{`pb < 2`, false},    // 字段缺失时比较为 false
+		{`NOT pb < 2`, true}, // NOT 取反缺失字段的比较
+		{`change_5d >= -3.5`, true},
+		{`code = "sh603501" || pe == 0`, true},
+	}
+	for _, tt := range tests {
+		expr, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		if got := expr.Match(r); got != tt.want {
+			t.Errorf("Parse(%q).Match = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{
+		`pe <`, `pe < "20"`, `industry > "半导体"`, `foo > 1`, `(pe < 20`, `pe < 20 pb > 1`,
+		`industry = 1`, `price > industry`, `1 < 2`, `name CONTAINS`, `pe CONTAINS 1`, `"a" CONTAINS name`, `pe < 20 & pb > 1`,
+	} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Parse(%q) expected error", bad)
+		}
+	}
+}
+
+func TestFieldsAndBoardHints(t *testing.T) {
+	expr, err := Parse(`(pe < 20 OR board = "银行") AND industry = "半导体" AND price > ma20`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(Fields(expr), ","); got != "pe,board,industry,price,ma20" {
+		t.Errorf("Unexpected fields: %s", got)
+	}
+	// OR 分支中的板块不能缩小股票池
+	if hints := BoardHints(expr); len(hints) != 1 || hints[0] != "半导体" {
+		t.Errorf("Unexpected hints: %v", hints)
+	}
+}
+
+func TestScreen(t *testing.T) {
+	provider := newFakeProvider()
+	var klineCalls atomic.Int32
+	klines := func(code string, count int) ([]model.KLineBar, error) {
+		klineCalls.Add(1)
+		if code == "sz002371" {
+			return nil, fmt.Errorf("network down")
+		}
+		if code == "sh688981" {
+			return risingKLines(count, 1), nil
+		}
+		return risingKLines(count, 0.2), nil
+	}
+
+	// 表达式中的行业条件确定股票池，K线字段按需计算
+	result, err := Screen(provider, klines, Options{Expression: `change_5d > 3 AND industry = "半导体"`})
+	if err != nil {
+		t.Fatalf("Screen failed: %v", err)
+	}
+	if result.Universe != 3 || len(result.Boards) != 1 || result.Boards[0] != "半导体" || klineCalls.Load() != 3 {
+		t.Errorf("Unexpected universe: %+v, kline calls %d", result, klineCalls.Load())
+	}
+	if result.Matched != 1 || result.Items[0].Code != "sh688981" || result.Items[0].Values["change_5d"] != 5.1 {
+		t.Errorf("Unexpected matches: %+v", result.Items)
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "K线") {
+		t.Errorf("Unexpected warnings: %v", result.Warnings)
+	}
+
+	// 只用行情字段时不获取K线与行业；未指定股票池时使用涨幅前 N 的板块
+	klineCalls.Store(0)
+	provider.calls = map[string]int{}
+	result, err = Screen(provider, klines, Options{Expression: "pe < 20", SortBy: "pe", Ascending: true, PageSize: 2})
+	if err != nil {
+		t.Fatalf("Screen failed: %v", err)
+	}
+	if klineCalls.Load() != 0 || provider.calls["industry"] != 0 || provider.calls["quotes"] != 1 {
+		t.Errorf("Unexpected data loading: kline=%d calls=%v", klineCalls.Load(), provider.calls)
+	}
+	if result.Universe != 5 || result.Matched != 3 || result.TotalPages != 2 || len(result.Items) != 2 {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+	if result.Items[0].Code != "sh601398" || result.Items[1].Code != "sh600036" || result.Items[0].Rank != 1 {
+		t.Errorf("Unexpected ranking: %+v", result.Items)
+	}
+
+	result, _ = Screen(provider, klines, Options{Expression: "pe < 20", SortBy: "pe", Ascending: true, PageSize: 2, Page: 2})
+	if len(result.Items) != 1 || result.Items[0].Code != "sh603501" || result.Items[0].Rank != 3 {
+		t.Errorf("Unexpected second page: %+v", result.Items)
+	}
+
+	// 指定板块与代码合并为股票池；缺少排序字段的排在最后
+	result, err = Screen(provider, klines, Options{Boards: []string{"银行"}, Codes: []string{"SZ002371"}, SortBy: "pe"})
+	if err != nil {
+		t.Fatalf("Screen failed: %v", err)
+	}
+	if result.Universe != 3 || result.Items[0].Code != "sh600036" || result.Items[2].Code != "sz002371" || result.Items[2].Name != "北方华创" {
+		t.Errorf("Unexpected board+codes result: %+v", result.Items)
+	}
+
+	if _, err := Screen(provider, klines, Options{Boards: []string{"白酒"}}); err == nil {
+		t.Error("Expected error for unknown board")
+	}
+	if _, err := Screen(provider, klines, Options{Expression: "pe <"}); err == nil {
+		t.Error("Expected error for invalid expression")
+	}
+	if _, err := Screen(provider, klines, Options{SortBy: "industry"}); err == nil {
+		t.Error("Expected error for text sort field")
+	}
+}
+
+func TestBarRecord(t *testing.T) {
+	bars, err := indicator.FromKLineBars(risingKLines(100, 1))
+	if err != nil {
+		t.Fatalf("FromKLineBars failed: %v", err)
+	}
+	bars[len(bars)-1].High = bars[len(bars)-1].Close * 1.02
+
+	r := BarRecord("sh600519", "贵州茅台", bars)
+	if r.Values["price"] != bars[len(bars)-1].Close || r.Values["change_pct"] != 1 || r.Values["change_5d"] != 5.1 {
+		t.Errorf("Unexpected values: %+v", r.Values)
+	}
+	if r.Values["amplitude"] != 2.02 || r.Values["ma60"] <= 0 {
+		t.Errorf("Unexpected amplitude or ma60: %+v", r.Values)
+	}
+
+	expr, _ := Parse(`price > ma20 AND name = "贵州茅台"`)
+	if !expr.Match(r) {
+		t.Error("Expected bar record to match")
+	}
+	if !AvailableFromBars("rsi14") || !AvailableFromBars("price") || AvailableFromBars("pe") || AvailableFromBars("industry") {
+		t.Error("Unexpected AvailableFromBars result")
+	}
+}
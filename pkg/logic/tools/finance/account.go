@@ -3,6 +3,7 @@ package finance
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/components/tool/utils"
@@ -12,9 +13,13 @@ import (
 	"msa/pkg/model"
 )
 
+// defaultAccountName 默认账户名称，同时作为所有账户的用户ID
+const defaultAccountName = "default"
+
 // CreateAccountParam 创建账户参数
 type CreateAccountParam struct {
 	InitialAmount float64 `json:"initial_amount" jsonschema:"description=初始金额，单位：元"`
+	Name          string  `json:"name,omitempty" jsonschema:"description=账户名称（可选，默认 default），如 conservative、momentum，用于区分不同策略的账户"`
 }
 
 // CreateAccountTool 创建账户工具
@@ -29,7 +34,7 @@ func (t *CreateAccountTool) GetName() string {
 }
 
 func (t *CreateAccountTool) GetDescription() string {
-	return "创建新的交易账户（全局只能有一个活跃账户）| Create a new trading account (only one active account globally)"
+	return "创建新的交易账户，账户名称唯一，可按策略创建多个账户；第一个账户自动成为当前账户 | Create a new named trading account; multiple accounts can run different strategies, the first one becomes the current account"
 }
 
 func (t *CreateAccountTool) GetToolGroup() model.ToolGroup {
@@ -39,6 +44,8 @@ func (t *CreateAccountTool) GetToolGroup() model.ToolGroup {
 // AccountData 账户数据
 type AccountData struct {
 	ID            int64  `json:"id"`
+	Name          string `json:"name"`
+	IsCurrent     bool   `json:"is_current"`
 	InitialAmount string `json:"initial_amount"`
 	AvailableAmt  string `json:"available_amt"`
 	LockedAmt     string `json:"locked_amt,omitempty"`
@@ -48,7 +55,7 @@ type AccountData struct {
 
 // CreateAccount 创建账户
 func CreateAccount(ctx context.Context, param *CreateAccountParam) (string, error) {
-	return safetool.SafeExecute("create_account", fmt.Sprintf("初始金额: %.2f 元", param.InitialAmount), func() (string, error) {
+	return safetool.SafeExecute("create_account", fmt.Sprintf("账户: %s, 初始金额: %.2f 元", param.Name, param.InitialAmount), func() (string, error) {
 		return doCreateAccount(ctx, param)
 	})
 }
@@ -60,18 +67,23 @@ func doCreateAccount(ctx context.Context, param *CreateAccountParam) (string, er
 		return model.NewErrorResult(err.Error()), nil
 	}
 
-	// 检查是否已存在活跃账户
+	name := strings.TrimSpace(param.Name)
+	if name == "" {
+		name = defaultAccountName
+	}
+
+	// 检查账户名称是否已存在
 	var count int64
 	database.Model(&model.Account{}).
-		Where("status = ?", model.AccountStatusActive).
+		Where("name = ?", name).
 		Count(&count)
 	if count > 0 {
-		err := fmt.Errorf("已存在活跃账户，不支持多账户")
+		err := fmt.Errorf("账户 %s 已存在，请使用其他名称", name)
 		return model.NewErrorResult(err.Error()), nil
 	}
 
 	// 创建账户
-	accountID, err := db.CreateAccount(database, "default", model.YuanToHao(param.InitialAmount))
+	accountID, err := db.CreateNamedAccount(database, defaultAccountName, name, model.YuanToHao(param.InitialAmount))
 	if err != nil {
 		return model.NewErrorResult(err.Error()), nil
 	}
@@ -84,6 +96,8 @@ func doCreateAccount(ctx context.Context, param *CreateAccountParam) (string, er
 
 	data := &AccountData{
 		ID:            int64(account.ID),
+		Name:          account.Name,
+		IsCurrent:     account.IsCurrent,
 		InitialAmount: formatHaoToYuan(account.InitialAmount),
 		AvailableAmt:  formatHaoToYuan(account.AvailableAmt),
 		Status:        string(account.Status),
@@ -93,7 +107,9 @@ func doCreateAccount(ctx context.Context, param *CreateAccountParam) (string, er
 }
 
 // GetAccountParam 查询账户参数
-type GetAccountParam struct{}
+type GetAccountParam struct {
+	Account string `json:"account,omitempty" jsonschema:"description=账户名称（可选，默认使用当前账户）"`
+}
 
 // GetAccountTool 查询账户工具
 type GetAccountTool struct{}
@@ -108,7 +124,7 @@ func (t *GetAccountTool) GetName() string {
 }
 
 func (t *GetAccountTool) GetDescription() string {
-	return "查询当前活跃账户信息 | Get current active account information"
+	return "查询账户信息（默认当前账户）| Get account information (defaults to the current account)"
 }
 
 func (t *GetAccountTool) GetToolGroup() model.ToolGroup {
@@ -129,13 +145,15 @@ func doGetAccount(ctx context.Context, param *GetAccountParam) (string, error) {
 		return model.NewErrorResult(err.Error()), nil
 	}
 
-	account, err := getActiveAccount(database)
+	account, err := ResolveAccount(database, param.Account)
 	if err != nil {
 		return model.NewErrorResult(err.Error()), nil
 	}
 
 	data := &AccountData{
 		ID:            int64(account.ID),
+		Name:          account.Name,
+		IsCurrent:     account.IsCurrent,
 		InitialAmount: formatHaoToYuan(account.InitialAmount),
 		AvailableAmt:  formatHaoToYuan(account.AvailableAmt),
 		LockedAmt:     formatHaoToYuan(account.LockedAmt),
@@ -148,7 +166,8 @@ func doGetAccount(ctx context.Context, param *GetAccountParam) (string, error) {
 
 // UpdateAccountStatusParam 修改账户状态参数
 type UpdateAccountStatusParam struct {
-	Action string `json:"action" jsonschema:"description=操作类型: freeze(冻结)/unfreeze(解冻)/close(关闭)"`
+	Action  string `json:"action" jsonschema:"description=操作类型: freeze(冻结)/unfreeze(解冻)/close(关闭)"`
+	Account string `json:"account,omitempty" jsonschema:"description=账户名称（可选，默认使用当前账户）"`
 }
 
 // UpdateAccountStatusTool 修改账户状态工具
@@ -184,7 +203,7 @@ func doUpdateAccountStatus(ctx context.Context, param *UpdateAccountStatusParam)
 		return model.NewErrorResult(err.Error()), nil
 	}
 
-	account, err := getActiveAccount(database)
+	account, err := ResolveAccount(database, param.Account)
 	if err != nil {
 		return model.NewErrorResult(err.Error()), nil
 	}
@@ -0,0 +1,227 @@
+package finance
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+	msadb "msa/pkg/db"
+	"msa/pkg/logic/attribution"
+	"msa/pkg/logic/calendar"
+	"msa/pkg/logic/marketdata"
+	"msa/pkg/logic/tools/safetool"
+	"msa/pkg/model"
+)
+
+// defaultAttributionTop 默认返回的个股与交易数量
+const defaultAttributionTop = 10
+
+// GetPerformanceAttributionParam 绩效归因参数
+type GetPerformanceAttributionParam struct {
+	Days         int     `json:"days,omitempty" jsonschema:"description=最近 N 个交易日（可选，默认 60；指定 start_date 时忽略）"`
+	StartDate    string  `json:"start_date,omitempty" jsonschema:"description=开始日期 YYYY-MM-DD（可选）"`
+	EndDate      string  `json:"end_date,omitempty" jsonschema:"description=结束日期 YYYY-MM-DD（可选，默认最近一个已收盘的交易日）"`
+	Benchmark    string  `json:"benchmark,omitempty" jsonschema:"description=基准指数代码（可选，默认 sh000300 沪深300）"`
+	RiskFreeRate float64 `json:"risk_free_rate,omitempty" jsonschema:"description=年化无风险利率（%），用于计算 Alpha（可选，默认 0）"`
+	Top          int     `json:"top,omitempty" jsonschema:"description=返回贡献最大与最小的个股和交易各 N 条（可选，默认 10）"`
+	Account      string  `json:"account,omitempty" jsonschema:"description=账户名称（可选，默认使用当前账户）"`
+}
+
+// GetPerformanceAttributionTool 绩效归因工具
+type GetPerformanceAttributionTool struct{}
+
+func (t *GetPerformanceAttributionTool) GetToolInfo() (tool.BaseTool, error) {
+	return utils.InferTool(t.GetName(), t.GetDescription(), GetPerformanceAttribution,
+		utils.WithUnmarshalArguments(unmarshalEmptyParam[GetPerformanceAttributionParam]))
+}
+
+func (t *GetPerformanceAttributionTool) GetName() string {
+	return "get_performance_attribution"
+}
+
+func (t *GetPerformanceAttributionTool) GetDescription() string {
+	return "绩效归因：按成交记录与历史K线分析区间收益来源，返回相对基准指数的 Beta 与 Alpha、按申万行业的配置效应与选股效应（Brinson），以及个股和逐笔交易的收益贡献 | Performance attribution from trade history and cached K-lines: beta and alpha versus a benchmark index, Brinson industry allocation vs selection effects, and per-stock and per-trade contribution"
+}
+
+func (t *GetPerformanceAttributionTool) GetToolGroup() model.ToolGroup {
+	return model.FinanceToolGroup
+}
+
+// AttributionIndustryItem 行业归因
+type AttributionIndustryItem struct {
+	Industry        string  `json:"industry"`
+	Weight          float64 `json:"weight"` // 平均权重（%）
+	PnL             string  `json:"pnl"`
+	Contribution    float64 `json:"contribution"`     // 收益贡献（%）
+	BenchmarkReturn float64 `json:"benchmark_return"` // 行业指数区间收益（%）
+	Allocation      float64 `json:"allocation"`       // 配置效应（%）
+	Selection       float64 `json:"selection"`        // 选股效应（%）
+}
+
+// AttributionStockItem 个股贡献
+type AttributionStockItem struct {
+	StockCode    string  `json:"stock_code"`
+	StockName    string  `json:"stock_name,omitempty"`
+	Industry     string  `json:"industry"`
+	PnL          string  `json:"pnl"`
+	Contribution float64 `json:"contribution"` // 收益贡献（%）
+}
+
+// AttributionTradeItem 逐笔交易贡献
+type AttributionTradeItem struct {
+	TransactionID uint    `json:"transaction_id,omitempty"`
+	StockCode     string  `json:"stock_code"`
+	StockName     string  `json:"stock_name,omitempty"`
+	BuyDate       string  `json:"buy_date"`
+	BuyPrice      string  `json:"buy_price"`
+	Quantity      int64   `json:"quantity"`
+	Opening       bool    `json:"opening,omitempty"` // 区间前买入，成本按期初市值
+	CloseDate     string  `json:"close_date,omitempty"`
+	PnL           string  `json:"pnl"`
+	Return        float64 `json:"return"`       // 收益率（%）
+	Contribution  float64 `json:"contribution"` // 收益贡献（%）
+}
+
+// AttributionData 绩效归因数据，收益率、效应与贡献均为百分比
+type AttributionData struct {
+	StartDate       string                    `json:"start_date"`
+	EndDate         string                    `json:"end_date"`
+	Benchmark       string                    `json:"benchmark"`
+	Days            int                       `json:"days"`
+	StartNAV        string                    `json:"start_nav"`
+	EndNAV          string                    `json:"end_nav"`
+	NetFlow         string                    `json:"net_flow,omitempty"`
+	PnL             string                    `json:"pnl"`
+	PortfolioReturn float64                   `json:"portfolio_return"`
+	BenchmarkReturn float64                   `json:"benchmark_return"`
+	ExcessReturn    float64                   `json:"excess_return"`
+	Beta            float64                   `json:"beta"`
+	Alpha           float64                   `json:"alpha"` // 年化 Alpha（%）
+	Correlation     float64                   `json:"correlation"`
+	Allocation      float64                   `json:"allocation"`
+	Selection       float64                   `json:"selection"`
+	Residual        float64                   `json:"residual"`
+	Industries      []AttributionIndustryItem `json:"industries"`
+	TopStocks       []AttributionStockItem    `json:"top_stocks"`
+	BottomStocks    []AttributionStockItem    `json:"bottom_stocks,omitempty"`
+	TopTrades       []AttributionTradeItem    `json:"top_trades"`
+	BottomTrades    []AttributionTradeItem    `json:"bottom_trades,omitempty"`
+	Warnings        []string                  `json:"warnings,omitempty"`
+}
+
+// GetPerformanceAttribution 绩效归因
+func GetPerformanceAttribution(ctx context.Context, param *GetPerformanceAttributionParam) (string, error) {
+	return safetool.SafeExecute("get_performance_attribution", fmt.Sprintf("%s ~ %s", param.StartDate, param.EndDate), func() (string, error) {
+		return doGetPerformanceAttribution(ctx, param)
+	})
+}
+
+func doGetPerformanceAttribution(ctx context.Context, param *GetPerformanceAttributionParam) (string, error) {
+	database := msadb.GetDB()
+	if database == nil {
+		err := fmt.Errorf("数据库未初始化")
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	account, err := ResolveAccount(database, param.Account)
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	provider, err := marketdata.GetProvider()
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	report, err := attribution.Analyze(database, provider, account.ID, attribution.Options{
+		From:         param.StartDate,
+		To:           param.EndDate,
+		Days:         param.Days,
+		Benchmark:    param.Benchmark,
+		RiskFreeRate: param.RiskFreeRate / 100,
+	}, calendar.Now())
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	top := param.Top
+	if top <= 0 {
+		top = defaultAttributionTop
+	}
+	data := ToAttributionData(report, top)
+	return model.NewSuccessResult(data, fmt.Sprintf("%s ~ %s 收益 %.2f%%，基准 %.2f%%",
+		data.StartDate, data.EndDate, data.PortfolioReturn, data.BenchmarkReturn)), nil
+}
+
+// ToAttributionData 归因结果转换为返回数据，个股与交易只保留贡献最大与最小的各 top 条
+func ToAttributionData(report *attribution.Report, top int) *AttributionData {
+	data := &AttributionData{
+		StartDate:       report.From,
+		EndDate:         report.To,
+		Benchmark:       report.Benchmark,
+		Days:            report.Days,
+		StartNAV:        formatHaoToYuan(report.StartNAV),
+		EndNAV:          formatHaoToYuan(report.EndNAV),
+		PnL:             formatHaoToYuan(report.PnL),
+		PortfolioReturn: toPercent(report.PortfolioReturn),
+		BenchmarkReturn: toPercent(report.BenchmarkReturn),
+		ExcessReturn:    toPercent(report.ExcessReturn),
+		Beta:            math.Round(report.Beta*100) / 100,
+		Alpha:           toPercent(report.Alpha),
+		Correlation:     math.Round(report.Correlation*100) / 100,
+		Allocation:      toPercent(report.Allocation),
+		Selection:       toPercent(report.Selection),
+		Residual:        toPercent(report.Residual),
+		Industries:      make([]AttributionIndustryItem, 0, len(report.Industries)),
+		Warnings:        report.Warnings,
+	}
+	if report.NetFlow != 0 {
+		data.NetFlow = formatHaoToYuan(report.NetFlow)
+	}
+	for _, e := range report.Industries {
+		data.Industries = append(data.Industries, AttributionIndustryItem{
+			Industry:        e.Industry,
+			Weight:          toPercent(e.Weight),
+			PnL:             formatHaoToYuan(e.PnL),
+			Contribution:    toPercent(e.Contribution),
+			BenchmarkReturn: toPercent(e.BenchmarkReturn),
+			Allocation:      toPercent(e.Allocation),
+			Selection:       toPercent(e.Selection),
+		})
+	}
+
+	stocks := make([]AttributionStockItem, 0, len(report.Stocks))
+	for _, s := range report.Stocks {
+		stocks = append(stocks, AttributionStockItem{
+			StockCode: s.Code, StockName: s.Name, Industry: s.Industry,
+			PnL: formatHaoToYuan(s.PnL), Contribution: toPercent(s.Contribution),
+		})
+	}
+	data.TopStocks, data.BottomStocks = splitTop(stocks, top)
+
+	trades := make([]AttributionTradeItem, 0, len(report.Trades))
+	for _, t := range report.Trades {
+		trades = append(trades, AttributionTradeItem{
+			TransactionID: t.TransactionID, StockCode: t.Code, StockName: t.Name,
+			BuyDate: t.BuyDate, BuyPrice: formatHaoToYuan(t.BuyPrice), Quantity: t.Quantity,
+			Opening: t.Opening, CloseDate: t.CloseDate, PnL: formatHaoToYuan(t.PnL),
+			Return: toPercent(t.Return), Contribution: toPercent(t.Contribution),
+		})
+	}
+	data.TopTrades, data.BottomTrades = splitTop(trades, top)
+	return data
+}
+
+// splitTop 按贡献降序排列的列表拆分为前 n 条与后 n 条（从低到高），总数不超过 2n 时全部放在前一部分
+func splitTop[T any](list []T, n int) (head, tail []T) {
+	if len(list) <= 2*n {
+		return list, nil
+	}
+	tail = make([]T, 0, n)
+	for i := len(list) - 1; i >= len(list)-n; i-- {
+		tail = append(tail, list[i])
+	}
+	return list[:n], tail
+}
@@ -0,0 +1,320 @@
+package finance
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+	msadb "msa/pkg/db"
+	"msa/pkg/logic/calendar"
+	"msa/pkg/logic/finsvc"
+	"msa/pkg/logic/tools/safetool"
+	"msa/pkg/model"
+)
+
+// RecordCashFlowParam 记录资金流水参数
+type RecordCashFlowParam struct {
+	Type    string  `json:"type" jsonschema:"description=流水类型: deposit(入金)/withdrawal(出金)/interest(利息)"`
+	Amount  float64 `json:"amount" jsonschema:"description=金额（元），正数"`
+	Note    string  `json:"note,omitempty" jsonschema:"description=备注（可选）"`
+	Account string  `json:"account,omitempty" jsonschema:"description=账户名称（可选，默认使用当前账户）"`
+}
+
+// RecordCashFlowTool 记录资金流水工具
+type RecordCashFlowTool struct{}
+
+func (t *RecordCashFlowTool) GetToolInfo() (tool.BaseTool, error) {
+	return utils.InferTool(t.GetName(), t.GetDescription(), RecordCashFlow)
+}
+
+func (t *RecordCashFlowTool) GetName() string {
+	return "record_cash_flow"
+}
+
+func (t *RecordCashFlowTool) GetDescription() string {
+	return "记录入金、出金或利息，更新可用余额；入金出金计入投入本金，利息计入收益 | Record a deposit, withdrawal or interest entry and update the available balance"
+}
+
+func (t *RecordCashFlowTool) GetToolGroup() model.ToolGroup {
+	return model.FinanceToolGroup
+}
+
+// CashFlowItem 资金流水项
+type CashFlowItem struct {
+	ID        int64  `json:"id"`
+	Type      string `json:"type"`
+	Amount    string `json:"amount"`
+	StockCode string `json:"stock_code,omitempty"`
+	Note      string `json:"note,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+// RecordCashFlow 记录资金流水
+func RecordCashFlow(ctx context.Context, param *RecordCashFlowParam) (string, error) {
+	return safetool.SafeExecute("record_cash_flow", fmt.Sprintf("%s %.2f元", param.Type, param.Amount), func() (string, error) {
+		return doRecordCashFlow(ctx, param)
+	})
+}
+
+func doRecordCashFlow(ctx context.Context, param *RecordCashFlowParam) (string, error) {
+	database := msadb.GetDB()
+	if database == nil {
+		err := fmt.Errorf("数据库未初始化")
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	account, err := getActiveAccount(database, param.Account)
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	var flowType model.CashFlowType
+	switch strings.ToLower(param.Type) {
+	case "deposit":
+		flowType = model.CashFlowTypeDeposit
+	case "withdrawal":
+		flowType = model.CashFlowTypeWithdrawal
+	case "interest":
+		flowType = model.CashFlowTypeInterest
+	default:
+		err := fmt.Errorf("无效的流水类型: %s，支持的类型: deposit/withdrawal/interest（分红请使用 apply_corporate_action）", param.Type)
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	if param.Amount <= 0 {
+		return model.NewErrorResult("金额必须大于 0"), nil
+	}
+
+	flowID, err := finsvc.RecordCashFlow(database, account.ID, flowType, model.YuanToHao(param.Amount), param.Note)
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	account, err = msadb.GetAccountByID(database, account.ID)
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	data := map[string]interface{}{
+		"cash_flow_id":  flowID,
+		"type":          string(flowType),
+		"amount":        param.Amount,
+		"available_amt": formatHaoToYuan(account.AvailableAmt),
+	}
+	return model.NewSuccessResult(data, "资金流水已记录"), nil
+}
+
+// ApplyCorporateActionParam 公司行动参数
+type ApplyCorporateActionParam struct {
+	StockCode    string  `json:"stock_code" jsonschema:"description=股票代码（如 sh600000）"`
+	StockName    string  `json:"stock_name" jsonschema:"description=股票名称"`
+	Type         string  `json:"type" jsonschema:"description=公司行动类型: dividend(现金分红)/bonus(送股转增)/split(拆股合股)"`
+	CashPerShare float64 `json:"cash_per_share,omitempty" jsonschema:"description=每股派息（元），dividend 必填，如 10派5元填 0.5"`
+	Ratio        float64 `json:"ratio,omitempty" jsonschema:"description=bonus 为每股送转股数（10送3转2填 0.5）；split 为每股变为股数（1拆2填 2，10合1填 0.1）"`
+	ExDate       string  `json:"ex_date,omitempty" jsonschema:"description=除权除息日 YYYY-MM-DD（可选，默认今天），按除权除息日之前的持仓计算"`
+	Account      string  `json:"account,omitempty" jsonschema:"description=账户名称（可选，默认使用当前账户）"`
+}
+
+// ApplyCorporateActionTool 公司行动工具
+type ApplyCorporateActionTool struct{}
+
+func (t *ApplyCorporateActionTool) GetToolInfo() (tool.BaseTool, error) {
+	return utils.InferTool(t.GetName(), t.GetDescription(), ApplyCorporateAction)
+}
+
+func (t *ApplyCorporateActionTool) GetName() string {
+	return "apply_corporate_action"
+}
+
+func (t *ApplyCorporateActionTool) GetDescription() string {
+	return "按当前持仓处理除权除息：现金分红增加可用余额并计入已实现盈亏，送转和拆合股调整持仓数量 | Apply a corporate action to the current position: cash dividends credit cash as realized P&L, bonus shares and splits adjust the share count"
+}
+
+func (t *ApplyCorporateActionTool) GetToolGroup() model.ToolGroup {
+	return model.FinanceToolGroup
+}
+
+// CorporateActionItem 公司行动项
+type CorporateActionItem struct {
+	ID             int64   `json:"id"`
+	StockCode      string  `json:"stock_code"`
+	StockName      string  `json:"stock_name"`
+	Type           string  `json:"type"`
+	CashPerShare   string  `json:"cash_per_share,omitempty"`
+	Ratio          float64 `json:"ratio,omitempty"`
+	PositionBefore int64   `json:"position_before"`
+	QuantityDelta  int64   `json:"quantity_delta"`
+	CashAmount     string  `json:"cash_amount,omitempty"`
+	ExDate         string  `json:"ex_date"`
+}
+
+// ApplyCorporateAction 处理公司行动
+func ApplyCorporateAction(ctx context.Context, param *ApplyCorporateActionParam) (string, error) {
+	return safetool.SafeExecute("apply_corporate_action", fmt.Sprintf("%s %s %s", param.StockCode, param.StockName, param.Type), func() (string, error) {
+		return doApplyCorporateAction(ctx, param)
+	})
+}
+
+func doApplyCorporateAction(ctx context.Context, param *ApplyCorporateActionParam) (string, error) {
+	database := msadb.GetDB()
+	if database == nil {
+		err := fmt.Errorf("数据库未初始化")
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	account, err := getActiveAccount(database, param.Account)
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	action := finsvc.CorporateAction{
+		StockCode:    param.StockCode,
+		StockName:    param.StockName,
+		CashPerShare: model.YuanToHao(param.CashPerShare),
+		Ratio:        param.Ratio,
+	}
+	switch strings.ToLower(param.Type) {
+	case "dividend":
+		action.Type = model.CorporateActionTypeDividend
+	case "bonus":
+		action.Type = model.CorporateActionTypeBonus
+	case "split":
+		action.Type = model.CorporateActionTypeSplit
+	default:
+		err := fmt.Errorf("无效的公司行动类型: %s，支持的类型: dividend/bonus/split", param.Type)
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	if param.ExDate != "" {
+		exDate, err := time.ParseInLocation(calendar.DateLayout, param.ExDate, calendar.Location)
+		if err != nil {
+			err := fmt.Errorf("除权除息日格式错误: %s，应为 YYYY-MM-DD", param.ExDate)
+			return model.NewErrorResult(err.Error()), nil
+		}
+		action.ExDate = exDate
+	}
+
+	actionID, err := finsvc.ApplyCorporateAction(database, account.ID, action)
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	var record model.CorporateAction
+	if err := database.First(&record, actionID).Error; err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	return model.NewSuccessResult(toCorporateActionItem(&record), "公司行动已处理"), nil
+}
+
+// GetCashFlowsParam 查询资金流水参数
+type GetCashFlowsParam struct {
+	Account string `json:"account,omitempty" jsonschema:"description=账户名称（可选，默认使用当前账户）"`
+}
+
+// GetCashFlowsTool 查询资金流水工具
+type GetCashFlowsTool struct{}
+
+func (t *GetCashFlowsTool) GetToolInfo() (tool.BaseTool, error) {
+	return utils.InferTool(t.GetName(), t.GetDescription(), GetCashFlows,
+		utils.WithUnmarshalArguments(unmarshalEmptyParam[GetCashFlowsParam]))
+}
+
+func (t *GetCashFlowsTool) GetName() string {
+	return "get_cash_flows"
+}
+
+func (t *GetCashFlowsTool) GetDescription() string {
+	return "查询资金流水（入金、出金、分红、利息）、公司行动记录和净投入本金 | Get cash flows (deposits, withdrawals, dividends, interest), corporate actions and net contribution"
+}
+
+func (t *GetCashFlowsTool) GetToolGroup() model.ToolGroup {
+	return model.FinanceToolGroup
+}
+
+// CashFlowsData 资金流水数据
+type CashFlowsData struct {
+	NetContribution  string                `json:"net_contribution"`
+	CashFlows        []CashFlowItem        `json:"cash_flows"`
+	CorporateActions []CorporateActionItem `json:"corporate_actions"`
+}
+
+// GetCashFlows 查询资金流水
+func GetCashFlows(ctx context.Context, param *GetCashFlowsParam) (string, error) {
+	return safetool.SafeExecute("get_cash_flows", "", func() (string, error) {
+		return doGetCashFlows(ctx, param)
+	})
+}
+
+func doGetCashFlows(ctx context.Context, param *GetCashFlowsParam) (string, error) {
+	database := msadb.GetDB()
+	if database == nil {
+		err := fmt.Errorf("数据库未初始化")
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	account, err := ResolveAccount(database, param.Account)
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	contribution, err := finsvc.GetNetContribution(database, account.ID)
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	flows, err := msadb.GetCashFlowsByAccount(database, account.ID)
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	actions, err := msadb.GetCorporateActionsByAccount(database, account.ID)
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	data := &CashFlowsData{
+		NetContribution:  formatHaoToYuan(contribution),
+		CashFlows:        make([]CashFlowItem, 0, len(flows)),
+		CorporateActions: make([]CorporateActionItem, 0, len(actions)),
+	}
+	for _, flow := range flows {
+		data.CashFlows = append(data.CashFlows, CashFlowItem{
+			ID:        int64(flow.ID),
+			Type:      string(flow.Type),
+			Amount:    formatHaoToYuan(flow.Amount),
+			StockCode: flow.StockCode,
+			Note:      flow.Note,
+			CreatedAt: flow.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+	for _, action := range actions {
+		data.CorporateActions = append(data.CorporateActions, toCorporateActionItem(action))
+	}
+
+	return model.NewSuccessResult(data, fmt.Sprintf("查询到 %d 条资金流水、%d 条公司行动", len(flows), len(actions))), nil
+}
+
+// toCorporateActionItem 公司行动记录转换为返回数据
+func toCorporateActionItem(action *model.CorporateAction) CorporateActionItem {
+	item := CorporateActionItem{
+		ID:             int64(action.ID),
+		StockCode:      action.StockCode,
+		StockName:      action.StockName,
+		Type:           string(action.Type),
+		Ratio:          action.Ratio,
+		PositionBefore: action.PositionBefore,
+		QuantityDelta:  action.QuantityDelta,
+		ExDate:         calendar.DateOf(action.CreatedAt),
+	}
+	if action.CashPerShare > 0 {
+		item.CashPerShare = formatHaoToYuan(action.CashPerShare)
+	}
+	if action.CashAmount > 0 {
+		item.CashAmount = formatHaoToYuan(action.CashAmount)
+	}
+	return item
+}
@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"strconv"
 
+	"msa/pkg/db"
+	"msa/pkg/logic/calendar"
+	"msa/pkg/logic/finsvc"
 	"msa/pkg/logic/tools/stock"
 	"msa/pkg/model"
 
@@ -12,15 +15,42 @@ import (
 	"gorm.io/gorm"
 )
 
-// getActiveAccount 获取当前活跃账户
-// 全局只有一个 ACTIVE 状态的账户
-func getActiveAccount(db *gorm.DB) (*model.Account, error) {
+// getActiveAccount 获取工具操作的账户，账户须为 ACTIVE 状态
+// name 为空时使用当前账户
+func getActiveAccount(db *gorm.DB, name string) (*model.Account, error) {
+	account, err := ResolveAccount(db, name)
+	if err != nil {
+		return nil, err
+	}
+	if account.Status != model.AccountStatusActive {
+		return nil, fmt.Errorf("账户 %s 状态为 %s，无法操作", account.Name, account.Status)
+	}
+	return account, nil
+}
+
+// ResolveAccount 按名称查询账户，不校验账户状态（命令行子命令复用）
+// name 为空时使用当前账户（/switch_account 切换）；未设置当前账户时使用最早创建的 ACTIVE 账户
+func ResolveAccount(db *gorm.DB, name string) (*model.Account, error) {
 	if db == nil {
 		return nil, fmt.Errorf("数据库连接为空")
 	}
+
+	if name != "" {
+		var account model.Account
+		if err := db.Where("name = ?", name).First(&account).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("账户 %s 不存在，可使用 /switch_account 查看所有账户", name)
+			}
+			return nil, fmt.Errorf("查询账户失败: %w", err)
+		}
+		return &account, nil
+	}
+
 	var account model.Account
-	err := db.Where("status = ?", model.AccountStatusActive).
-		First(&account).Error
+	err := db.Where("is_current = ?", true).First(&account).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = db.Where("status = ?", model.AccountStatusActive).Order("id ASC").First(&account).Error
+	}
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, fmt.Errorf("未找到活跃账户，请先创建账户\n创建账户需要提供：initial_amount（初始金额，单位：元）\n例如：创建一个初始资金 10 万元的账户")
@@ -38,12 +68,16 @@ func fetchCurrentPrice(stockCode string) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
+	return parseValuationPrice(stockCode, resp.CurrentPrice, resp.PrevClose)
+}
 
+// parseValuationPrice 解析用于计算市值的价格（毫），当前价为空时降级使用昨收价
+func parseValuationPrice(stockCode, currentPrice, prevClose string) (int64, error) {
 	// 优先使用当前价，停牌时降级使用昨收价
-	priceStr := resp.CurrentPrice
+	priceStr := currentPrice
 	usedFallback := false
 	if priceStr == "" {
-		priceStr = resp.PrevClose
+		priceStr = prevClose
 		usedFallback = true
 	}
 
@@ -60,17 +94,199 @@ func fetchCurrentPrice(stockCode string) (int64, error) {
 		log.Warnf("股票 %s 当前价为空（可能停牌），使用昨收价 %s 计算市值", stockCode, priceStr)
 	}
 
-	return model.YuanToHao(price), nil
+	return finsvc.PriceToHao(stockCode, price), nil
+}
+
+// fetchTradePrice 获取用于撮合的实时成交价（毫）
+// 与 fetchCurrentPrice 不同，停牌（当前价为空）时不降级使用昨收价，避免停牌股票被撮合成交
+func fetchTradePrice(stockCode string) (int64, error) {
+	resp, err := stock.FetchStockData(stockCode)
+	if err != nil {
+		return 0, err
+	}
+	if resp.CurrentPrice == "" {
+		return 0, fmt.Errorf("股票 %s 暂无实时成交价（可能停牌）", stockCode)
+	}
+
+	price, err := strconv.ParseFloat(resp.CurrentPrice, 64)
+	if err != nil {
+		return 0, fmt.Errorf("解析价格失败: %w", err)
+	}
+	return finsvc.PriceToHao(stockCode, price), nil
+}
+
+// fetchPrevClose 获取昨收价（毫），用于涨跌幅校验
+// 获取失败时返回 0，由交易规则跳过涨跌幅校验
+func fetchPrevClose(stockCode string) int64 {
+	resp, err := stock.FetchStockData(stockCode)
+	if err != nil {
+		log.Warnf("获取昨收价失败，跳过涨跌幅校验: stockCode=%s, err=%v", stockCode, err)
+		return 0
+	}
+	prevClose, err := strconv.ParseFloat(resp.PrevClose, 64)
+	if err != nil {
+		log.Warnf("解析昨收价失败，跳过涨跌幅校验: stockCode=%s, prevClose=%q", stockCode, resp.PrevClose)
+		return 0
+	}
+	return finsvc.PriceToHao(stockCode, prevClose)
+}
+
+// resolveLotSize 获取下单使用的每手股数
+// A股固定 100 股；港股每手股数因股而异，必须由调用方提供
+func resolveLotSize(stockCode string, lotSize int64) (int64, error) {
+	if finsvc.DetectBoard(stockCode) != finsvc.BoardHK {
+		return finsvc.DefaultLotSize, nil
+	}
+	if lotSize <= 0 {
+		return 0, fmt.Errorf("港股 %s 下单需提供 lot_size（每手股数）", stockCode)
+	}
+	return lotSize, nil
+}
+
+// matchSubmittedOrder 新订单提交后按实时行情撮合
+// 有盘口数据时按盘口可成交数量部分成交，剩余部分继续挂单
+// 行情获取失败时订单保持挂单，返回订单最新状态与本次成交数量
+func matchSubmittedOrder(database *gorm.DB, transID uint, stockCode string) (model.TransactionStatus, int64, error) {
+	price, err := fetchTradePrice(stockCode)
+	if err != nil {
+		log.Warnf("获取撮合行情失败，订单保持挂单: 交易ID=%d, err=%v", transID, err)
+		return model.TransactionStatusPending, 0, nil
+	}
+
+	trans, err := db.GetTransactionByID(database, transID)
+	if err != nil {
+		return model.TransactionStatusPending, 0, err
+	}
+	var availableQty int64
+	if depth, ok := fetchQuoteDepths([]string{stockCode})[stockCode]; ok {
+		if availableQty = depth.AvailableQuantity(trans.Type, trans.Price); availableQty <= 0 {
+			log.Infof("盘口没有限价内的挂单，订单保持挂单: 交易ID=%d", transID)
+			return model.TransactionStatusPending, 0, nil
+		}
+	}
+
+	filledQty, err := finsvc.MatchOrderWithLiquidity(database, transID, price, availableQty)
+	if err != nil {
+		return model.TransactionStatusPending, 0, err
+	}
+	if filledQty > 0 && filledQty == trans.Quantity {
+		return model.TransactionStatusFilled, filledQty, nil
+	}
+	return model.TransactionStatusPending, filledQty, nil
+}
+
+// pendingRemainderID 部分成交后继续挂单的剩余子记录ID，原记录已标记为 OBSOLETE，撤单需使用该ID
+func pendingRemainderID(database *gorm.DB, transID uint) (uint, error) {
+	children, err := db.GetTransactionsByParent(database, transID)
+	if err != nil {
+		return 0, err
+	}
+	for _, child := range children {
+		if child.Status == model.TransactionStatusPending {
+			return child.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("未找到交易 %d 的剩余挂单", transID)
+}
+
+// fetchQuoteDepths 批量获取盘口深度，用于按盘口挂单量模拟部分成交
+// 获取失败或没有有效盘口的股票不在结果中，撮合时不限制成交数量
+func fetchQuoteDepths(stockCodes []string) map[string]finsvc.QuoteDepth {
+	depths := make(map[string]finsvc.QuoteDepth)
+	quotes, err := stock.FetchStockQuotes(stockCodes)
+	if err != nil {
+		log.Warnf("获取盘口失败，撮合不限制成交数量: %v", err)
+		return depths
+	}
+	for _, stockCode := range stockCodes {
+		if depth, ok := finsvc.DepthFromQuote(stockCode, quotes[stockCode]); ok {
+			depths[stockCode] = depth
+		}
+	}
+	return depths
+}
+
+// OrderSyncResult 挂单同步结果（交易ID）
+type OrderSyncResult struct {
+	Expired   []uint // 跨日撤销的挂单
+	Triggered []uint // 条件单触发生成的卖出订单
+	Filled    []uint // 本次撮合成交的挂单
+}
+
+// SyncPendingOrders 同步账户挂单状态
+// 先撤销跨日未成交的挂单（当日有效），再评估条件单，最后用实时行情撮合剩余挂单（含条件单触发的卖单）
+// 仅记录日志不返回错误，避免影响查询类工具的主流程
+func SyncPendingOrders(database *gorm.DB, accountID uint) OrderSyncResult {
+	var result OrderSyncResult
+	now := calendar.Now()
+	startOfDay := calendar.DayStart(now)
+	if expired, err := finsvc.ExpirePendingOrders(database, accountID, startOfDay); err != nil {
+		log.Warnf("撤销过期挂单失败: %v", err)
+	} else if len(expired) > 0 {
+		log.Infof("已撤销过期挂单: %v", expired)
+		result.Expired = expired
+	}
+
+	result.Triggered = evaluateConditionalOrders(database, accountID)
+
+	orders, err := finsvc.GetPendingOrders(database, accountID)
+	if err != nil || len(orders) == 0 {
+		return result
+	}
+
+	prices := make(finsvc.PriceMap)
+	for _, order := range orders {
+		if _, ok := prices[order.StockCode]; ok {
+			continue
+		}
+		price, err := fetchTradePrice(order.StockCode)
+		if err != nil {
+			log.Warnf("获取挂单行情失败: stockCode=%s, err=%v", order.StockCode, err)
+			continue
+		}
+		prices[order.StockCode] = price
+	}
+
+	codes := make([]string, 0, len(prices))
+	for code := range prices {
+		codes = append(codes, code)
+	}
+	var depths map[string]finsvc.QuoteDepth
+	if len(codes) > 0 {
+		depths = fetchQuoteDepths(codes)
+	}
+
+	filled, err := finsvc.MatchPendingOrders(database, accountID, prices, depths)
+	if err != nil {
+		log.Warnf("撮合挂单失败: %v", err)
+	}
+	result.Filled = filled
+	return result
 }
 
 // fetchAllPrices 批量获取股票价格
-// 返回 map[stockCode]price (毫)
+// 通过批量行情接口一次获取，返回 map[stockCode]price (毫)，键为持仓中记录的代码（大小写、市场前缀由 stock.FetchStockQuotes 统一）
 // 任意一只股票价格获取失败，则返回 error，避免市值计算不完整
 func fetchAllPrices(stockCodes []string) (map[string]int64, error) {
 	prices := make(map[string]int64)
+	if len(stockCodes) == 0 {
+		return prices, nil
+	}
+
+	quotes, err := stock.FetchStockQuotes(stockCodes)
+	if err != nil {
+		return nil, fmt.Errorf("批量获取股票价格失败: %w", err)
+	}
+
 	var failedCodes []string
 	for _, stockCode := range stockCodes {
-		price, err := fetchCurrentPrice(stockCode)
+		quote, ok := quotes[stockCode]
+		if !ok {
+			log.Errorf("获取股票价格失败: stockCode=%s, 行情未返回", stockCode)
+			failedCodes = append(failedCodes, stockCode)
+			continue
+		}
+		price, err := parseValuationPrice(stockCode, quote.CurrentPrice, quote.PrevClose)
 		if err != nil {
 			log.Errorf("获取股票价格失败: stockCode=%s, err=%v", stockCode, err)
 			failedCodes = append(failedCodes, stockCode)
@@ -102,6 +318,10 @@ func getOrderStatusText(status model.TransactionStatus) string {
 		return "待成交"
 	case model.TransactionStatusFilled:
 		return "已成交"
+	case model.TransactionStatusCancelled:
+		return "已撤销"
+	case model.TransactionStatusObsolete:
+		return "已拆分"
 	case model.TransactionStatusRejected:
 		return "已拒绝"
 	default:
@@ -1,8 +1,14 @@
 package finance
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
+	msadb "msa/pkg/db"
+	"msa/pkg/logic/finsvc"
+	"msa/pkg/logic/marketdata"
+	"msa/pkg/logic/marketdata/local"
 	"msa/pkg/model"
 )
 
@@ -39,7 +45,7 @@ func TestFormatHaoToYuan(t *testing.T) {
 
 // TestGetActiveAccount_NoDatabase tests getActiveAccount with nil database
 func TestGetActiveAccount_NoDatabase(t *testing.T) {
-	account, err := getActiveAccount(nil)
+	account, err := getActiveAccount(nil, "")
 	if err == nil {
 		t.Error("getActiveAccount() with nil DB should return error")
 	}
@@ -55,6 +61,72 @@ func TestGetActiveAccount_NoAccount(t *testing.T) {
 	t.Skip("Skipping - requires test database setup")
 }
 
+// TestGetActiveAccount_NamedAccounts tests account resolution by name and current flag
+func TestGetActiveAccount_NamedAccounts(t *testing.T) {
+	db := setupTestDB(t)
+	conservativeID, _ := msadb.CreateNamedAccount(db, "default", "conservative", model.YuanToHao(10000))
+	momentumID, _ := msadb.CreateNamedAccount(db, "default", "momentum", model.YuanToHao(20000))
+
+	// 未指定名称时使用当前账户
+	account, err := getActiveAccount(db, "")
+	if err != nil || account.ID != conservativeID {
+		t.Errorf("getActiveAccount() = %v, %v, want current account %d", account, err, conservativeID)
+	}
+
+	account, err = getActiveAccount(db, "momentum")
+	if err != nil || account.ID != momentumID {
+		t.Errorf("getActiveAccount(momentum) = %v, %v, want %d", account, err, momentumID)
+	}
+
+	if _, err := getActiveAccount(db, "missing"); err == nil {
+		t.Error("getActiveAccount() with unknown name should return error")
+	}
+
+	// 冻结账户不可交易，但仍可查询
+	msadb.UpdateAccountStatus(db, momentumID, model.AccountStatusFrozen)
+	if _, err := getActiveAccount(db, "momentum"); err == nil {
+		t.Error("getActiveAccount() with frozen account should return error")
+	}
+	if account, err := ResolveAccount(db, "momentum"); err != nil || account.ID != momentumID {
+		t.Errorf("ResolveAccount(momentum) = %v, %v, want %d", account, err, momentumID)
+	}
+
+	// 切换当前账户
+	msadb.UpdateAccountStatus(db, momentumID, model.AccountStatusActive)
+	msadb.SetCurrentAccount(db, momentumID)
+	account, _ = getActiveAccount(db, "")
+	if account.ID != momentumID {
+		t.Errorf("getActiveAccount() after switch = %d, want %d", account.ID, momentumID)
+	}
+}
+
+func TestPendingRemainderID(t *testing.T) {
+	db := setupTestDB(t)
+	accountID, _ := msadb.CreateAccount(db, "test", model.YuanToHao(10000))
+	txID, err := finsvc.SubmitBuyOrder(db, accountID, finsvc.Order{
+		StockCode: "sh600000", StockName: "浦发银行", Quantity: 300, Price: model.YuanToHao(10), Time: testTradeTime,
+	})
+	if err != nil {
+		t.Fatalf("SubmitBuyOrder failed: %v", err)
+	}
+	if _, err := pendingRemainderID(db, txID); err == nil {
+		t.Error("pendingRemainderID() before partial fill should return error")
+	}
+
+	_, remainderID, err := finsvc.PartialFillOrder(db, txID, 100)
+	if err != nil {
+		t.Fatalf("PartialFillOrder failed: %v", err)
+	}
+	got, err := pendingRemainderID(db, txID)
+	if err != nil || got != remainderID {
+		t.Fatalf("pendingRemainderID() = %d, %v, want %d", got, err, remainderID)
+	}
+	// 原记录已作废，剩余部分按返回的ID撤单
+	if err := finsvc.CancelOrder(db, got, "test"); err != nil {
+		t.Errorf("CancelOrder(remainder) failed: %v", err)
+	}
+}
+
 // TestFetchCurrentPrice_InvalidCode tests fetchCurrentPrice with invalid code
 func TestFetchCurrentPrice_InvalidCode(t *testing.T) {
 	// Use an invalid stock code
@@ -74,6 +146,40 @@ func TestFetchCurrentPrice_RealAPI_Note(t *testing.T) {
 	// 3. Test error handling and price extraction
 }
 
+// TestFetchAllPrices_LocalProvider tests batch price fetching against recorded quotes
+func TestFetchAllPrices_LocalProvider(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "quote"), 0755)
+	os.WriteFile(filepath.Join(dir, "quote", "sh600519.json"), []byte(`{"current_price": "10.50", "prev_close": "10.00"}`), 0644)
+	// 停牌：当前价为空，使用昨收价
+	os.WriteFile(filepath.Join(dir, "quote", "sz000001.json"), []byte(`{"current_price": "", "prev_close": "12.30"}`), 0644)
+
+	marketdata.SetProvider(&local.LocalProvider{Dir: dir})
+	defer marketdata.SetProvider(nil)
+
+	prices, err := fetchAllPrices([]string{"sh600519", "sz000001"})
+	if err != nil {
+		t.Fatalf("fetchAllPrices() error = %v", err)
+	}
+	if prices["sh600519"] != 105000 || prices["sz000001"] != 123000 {
+		t.Errorf("Unexpected prices: %v", prices)
+	}
+
+	// 持仓代码大小写、缺少市场前缀时仍能估值，价格按持仓代码返回
+	prices, err = fetchAllPrices([]string{"SH600519", "000001"})
+	if err != nil {
+		t.Fatalf("fetchAllPrices() with unnormalized codes error = %v", err)
+	}
+	if prices["SH600519"] != 105000 || prices["000001"] != 123000 {
+		t.Errorf("Unexpected prices for unnormalized codes: %v", prices)
+	}
+
+	// 任意一只失败则整体失败
+	if _, err := fetchAllPrices([]string{"sh600519", "sh601318"}); err == nil {
+		t.Error("fetchAllPrices() should fail when a quote is missing")
+	}
+}
+
 // TestGetOrderStatusText tests the GetOrderStatusText function
 func TestGetOrderStatusText(t *testing.T) {
 	tests := []struct {
@@ -83,6 +189,7 @@ func TestGetOrderStatusText(t *testing.T) {
 	}{
 		{"待成交", model.TransactionStatusPending, "待成交"},
 		{"已成交", model.TransactionStatusFilled, "已成交"},
+		{"已拆分", model.TransactionStatusObsolete, "已拆分"},
 		{"已拒绝", model.TransactionStatusRejected, "已拒绝"},
 		{"未知状态", model.TransactionStatus("unknown"), "未知状态"},
 	}
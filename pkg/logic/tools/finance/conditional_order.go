@@ -0,0 +1,358 @@
+package finance
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	msadb "msa/pkg/db"
+	"msa/pkg/logic/calendar"
+	"msa/pkg/logic/finsvc"
+	"msa/pkg/logic/tools/safetool"
+	"msa/pkg/logic/tools/stock"
+	"msa/pkg/model"
+)
+
+// CreateConditionalOrderParam 创建条件单参数
+type CreateConditionalOrderParam struct {
+	StockCode     string  `json:"stock_code" jsonschema:"description=股票代码（如 sh600000），须有持仓"`
+	StockName     string  `json:"stock_name" jsonschema:"description=股票名称"`
+	Type          string  `json:"type" jsonschema:"description=条件单类型: stop_loss(固定止损)/take_profit(止盈)/trailing_stop(百分比跟踪止损)/atr_stop(ATR止损)"`
+	Quantity      int64   `json:"quantity,omitempty" jsonschema:"description=触发后卖出数量（股，可选，默认全部持仓）"`
+	TriggerPrice  float64 `json:"trigger_price,omitempty" jsonschema:"description=触发价（元/股），stop_loss 与 take_profit 必填"`
+	TrailPercent  float64 `json:"trail_percent,omitempty" jsonschema:"description=跟踪止损回撤比例（%），trailing_stop 必填，如 8 表示从最高价回撤 8% 触发"`
+	ATR           float64 `json:"atr,omitempty" jsonschema:"description=ATR 值（元，可选），atr_stop 未提供时按 14 日K线自动计算"`
+	ATRMultiplier float64 `json:"atr_multiplier,omitempty" jsonschema:"description=ATR 倍数（可选，默认 2），止损价 = 最高价 - 倍数 × ATR"`
+	LotSize       int64   `json:"lot_size,omitempty" jsonschema:"description=每手股数，港股必填，A股固定100股"`
+	Account       string  `json:"account,omitempty" jsonschema:"description=账户名称（可选，默认使用当前账户）"`
+}
+
+// CreateConditionalOrderTool 创建条件单工具
+type CreateConditionalOrderTool struct{}
+
+func (t *CreateConditionalOrderTool) GetToolInfo() (tool.BaseTool, error) {
+	return utils.InferTool(t.GetName(), t.GetDescription(), CreateConditionalOrder)
+}
+
+func (t *CreateConditionalOrderTool) GetName() string {
+	return "create_conditional_order"
+}
+
+func (t *CreateConditionalOrderTool) GetDescription() string {
+	return "在持仓上创建条件单（固定止损、止盈、百分比跟踪止损、ATR 止损），查询持仓或账户时按实时行情评估，触发后自动转为卖出订单 | Attach a conditional order (stop-loss, take-profit, trailing stop, ATR stop) to a position; it is evaluated against live quotes and converted into a sell order when triggered"
+}
+
+func (t *CreateConditionalOrderTool) GetToolGroup() model.ToolGroup {
+	return model.FinanceToolGroup
+}
+
+// ConditionalOrderItem 条件单数据
+type ConditionalOrderItem struct {
+	ID            int64   `json:"id"`
+	StockCode     string  `json:"stock_code"`
+	StockName     string  `json:"stock_name"`
+	Type          string  `json:"type"`
+	Status        string  `json:"status"`
+	Quantity      int64   `json:"quantity"`
+	TriggerPrice  string  `json:"trigger_price"`            // 当前触发价
+	TrailPercent  float64 `json:"trail_percent,omitempty"`  // 跟踪止损回撤比例（%）
+	ATR           string  `json:"atr,omitempty"`            // ATR 值
+	ATRMultiplier float64 `json:"atr_multiplier,omitempty"` // ATR 倍数
+	HighestPrice  string  `json:"highest_price,omitempty"`  // 创建以来最高价
+	TransactionID int64   `json:"transaction_id,omitempty"` // 触发后生成的卖出订单
+	TriggeredAt   string  `json:"triggered_at,omitempty"`   // 触发时间
+	CreatedAt     string  `json:"created_at"`               // 创建时间
+	Note          string  `json:"note,omitempty"`           // 备注
+	Warning       string  `json:"warning,omitempty"`        // 警告信息
+}
+
+// CreateConditionalOrder 创建条件单
+func CreateConditionalOrder(ctx context.Context, param *CreateConditionalOrderParam) (string, error) {
+	return safetool.SafeExecute("create_conditional_order", fmt.Sprintf("stock_code: %s", param.StockCode), func() (string, error) {
+		return doCreateConditionalOrder(ctx, param)
+	})
+}
+
+func doCreateConditionalOrder(ctx context.Context, param *CreateConditionalOrderParam) (string, error) {
+	database := msadb.GetDB()
+	if database == nil {
+		err := fmt.Errorf("数据库未初始化")
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	account, err := getActiveAccount(database, param.Account)
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	if param.StockCode == "" {
+		return model.NewErrorResult("stock_code is required"), nil
+	}
+	orderType := model.ConditionalOrderType(strings.ToUpper(param.Type))
+
+	lotSize, err := resolveLotSize(param.StockCode, param.LotSize)
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	spec := finsvc.ConditionalOrderSpec{
+		StockCode:     param.StockCode,
+		StockName:     param.StockName,
+		Type:          orderType,
+		Quantity:      param.Quantity,
+		LotSize:       lotSize,
+		TriggerPrice:  finsvc.PriceToHao(param.StockCode, param.TriggerPrice),
+		TrailPercent:  param.TrailPercent,
+		ATR:           model.YuanToHao(param.ATR),
+		ATRMultiplier: param.ATRMultiplier,
+	}
+
+	// 当前价用于校验触发价方向，并作为跟踪止损的初始最高价
+	price, err := fetchCurrentPrice(param.StockCode)
+	if err != nil {
+		if orderType == model.ConditionalOrderTypeTrailingStop || orderType == model.ConditionalOrderTypeATRStop {
+			return model.NewErrorResult(fmt.Sprintf("获取当前价失败，无法创建跟踪止损: %v", err)), nil
+		}
+		log.Warnf("获取当前价失败，跳过触发价方向校验: stockCode=%s, err=%v", param.StockCode, err)
+	}
+	spec.ReferencePrice = price
+
+	var warning string
+	if orderType == model.ConditionalOrderTypeATRStop && spec.ATR <= 0 {
+		bars, err := stock.FetchStockHistoryK(param.StockCode, "day", finsvc.DefaultATRPeriod+1, "qfq")
+		if err != nil {
+			return model.NewErrorResult(fmt.Sprintf("获取K线失败，无法计算 ATR: %v", err)), nil
+		}
+		atr, approximate, err := finsvc.CalculateATR(bars, finsvc.DefaultATRPeriod)
+		if err != nil {
+			return model.NewErrorResult(err.Error()), nil
+		}
+		if approximate {
+			warning = "ATR 数据不足，使用近似值"
+		}
+		spec.ATR = atr
+	}
+
+	orderID, err := finsvc.CreateConditionalOrder(database, account.ID, spec)
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	order, err := msadb.GetConditionalOrderByID(database, orderID)
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	item := toConditionalOrderItem(order)
+	item.Warning = warning
+	return model.NewSuccessResult(item, fmt.Sprintf("条件单已创建，触发价 %s 元", item.TriggerPrice)), nil
+}
+
+// ListConditionalOrdersParam 查询条件单参数
+type ListConditionalOrdersParam struct {
+	Status  string `json:"status,omitempty" jsonschema:"description=按状态筛选 ACTIVE/TRIGGERED/CANCELLED（可选，默认全部）"`
+	Account string `json:"account,omitempty" jsonschema:"description=账户名称（可选，默认使用当前账户）"`
+}
+
+// ListConditionalOrdersTool 查询条件单工具
+type ListConditionalOrdersTool struct{}
+
+func (t *ListConditionalOrdersTool) GetToolInfo() (tool.BaseTool, error) {
+	return utils.InferTool(t.GetName(), t.GetDescription(), ListConditionalOrders,
+		utils.WithUnmarshalArguments(unmarshalEmptyParam[ListConditionalOrdersParam]))
+}
+
+func (t *ListConditionalOrdersTool) GetName() string {
+	return "list_conditional_orders"
+}
+
+func (t *ListConditionalOrdersTool) GetDescription() string {
+	return "查询条件单（止损、止盈、跟踪止损），查询前按实时行情评估监控中的条件单 | List conditional orders after evaluating active ones against live quotes"
+}
+
+func (t *ListConditionalOrdersTool) GetToolGroup() model.ToolGroup {
+	return model.FinanceToolGroup
+}
+
+// ConditionalOrdersData 条件单列表数据
+type ConditionalOrdersData struct {
+	Total int                    `json:"total"`
+	Items []ConditionalOrderItem `json:"items"`
+}
+
+// ListConditionalOrders 查询条件单
+func ListConditionalOrders(ctx context.Context, param *ListConditionalOrdersParam) (string, error) {
+	return safetool.SafeExecute("list_conditional_orders", "", func() (string, error) {
+		return doListConditionalOrders(ctx, param)
+	})
+}
+
+func doListConditionalOrders(ctx context.Context, param *ListConditionalOrdersParam) (string, error) {
+	database := msadb.GetDB()
+	if database == nil {
+		err := fmt.Errorf("数据库未初始化")
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	account, err := getActiveAccount(database, param.Account)
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	// 先评估条件单并撮合挂单，返回最新状态
+	SyncPendingOrders(database, account.ID)
+
+	orders, err := msadb.GetConditionalOrdersByAccount(database, account.ID, model.ConditionalOrderStatus(strings.ToUpper(param.Status)))
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	data := &ConditionalOrdersData{
+		Total: len(orders),
+		Items: make([]ConditionalOrderItem, 0, len(orders)),
+	}
+	for _, order := range orders {
+		data.Items = append(data.Items, toConditionalOrderItem(order))
+	}
+
+	return model.NewSuccessResult(data, fmt.Sprintf("查询到 %d 条条件单", len(orders))), nil
+}
+
+// CancelConditionalOrderParam 撤销条件单参数
+type CancelConditionalOrderParam struct {
+	ID      int64  `json:"id" jsonschema:"description=要撤销的条件单ID"`
+	Account string `json:"account,omitempty" jsonschema:"description=账户名称（可选，默认使用当前账户）"`
+}
+
+// CancelConditionalOrderTool 撤销条件单工具
+type CancelConditionalOrderTool struct{}
+
+func (t *CancelConditionalOrderTool) GetToolInfo() (tool.BaseTool, error) {
+	return utils.InferTool(t.GetName(), t.GetDescription(), CancelConditionalOrder)
+}
+
+func (t *CancelConditionalOrderTool) GetName() string {
+	return "cancel_conditional_order"
+}
+
+func (t *CancelConditionalOrderTool) GetDescription() string {
+	return "撤销监控中的条件单 | Cancel an active conditional order"
+}
+
+func (t *CancelConditionalOrderTool) GetToolGroup() model.ToolGroup {
+	return model.FinanceToolGroup
+}
+
+// CancelConditionalOrder 撤销条件单
+func CancelConditionalOrder(ctx context.Context, param *CancelConditionalOrderParam) (string, error) {
+	return safetool.SafeExecute("cancel_conditional_order", fmt.Sprintf("id: %d", param.ID), func() (string, error) {
+		return doCancelConditionalOrder(ctx, param)
+	})
+}
+
+func doCancelConditionalOrder(ctx context.Context, param *CancelConditionalOrderParam) (string, error) {
+	database := msadb.GetDB()
+	if database == nil {
+		err := fmt.Errorf("数据库未初始化")
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	account, err := getActiveAccount(database, param.Account)
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	if param.ID <= 0 {
+		return model.NewErrorResult("id is required"), nil
+	}
+
+	if err := finsvc.CancelConditionalOrder(database, account.ID, uint(param.ID), "用户撤销"); err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	order, err := msadb.GetConditionalOrderByID(database, uint(param.ID))
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	return model.NewSuccessResult(toConditionalOrderItem(order), "条件单已撤销"), nil
+}
+
+// toConditionalOrderItem 条件单转换为返回数据
+func toConditionalOrderItem(order *model.ConditionalOrder) ConditionalOrderItem {
+	item := ConditionalOrderItem{
+		ID:           int64(order.ID),
+		StockCode:    order.StockCode,
+		StockName:    order.StockName,
+		Type:         string(order.Type),
+		Status:       string(order.Status),
+		Quantity:     order.Quantity,
+		TriggerPrice: formatHaoToYuan(order.TriggerPrice),
+		TrailPercent: order.TrailPercent,
+		CreatedAt:    order.CreatedAt.Format("2006-01-02 15:04:05"),
+		Note:         order.Note,
+	}
+	if order.ATR > 0 {
+		item.ATR = formatHaoToYuan(order.ATR)
+		item.ATRMultiplier = order.ATRMultiplier
+	}
+	if order.HighestPrice > 0 {
+		item.HighestPrice = formatHaoToYuan(order.HighestPrice)
+	}
+	if order.TransactionID != nil {
+		item.TransactionID = int64(*order.TransactionID)
+	}
+	if order.TriggeredAt != nil {
+		item.TriggeredAt = order.TriggeredAt.Format("2006-01-02 15:04:05")
+	}
+	return item
+}
+
+// evaluateConditionalOrders 按实时行情与当日分时价格评估账户监控中的条件单
+// 触发后生成的卖出订单由随后的挂单撮合处理；仅记录日志不返回错误，返回触发生成的卖出交易ID
+func evaluateConditionalOrders(database *gorm.DB, accountID uint) []uint {
+	orders, err := msadb.GetConditionalOrdersByAccount(database, accountID, model.ConditionalOrderStatusActive)
+	if err != nil || len(orders) == 0 {
+		return nil
+	}
+
+	quotes := make(map[string]finsvc.ConditionalQuote)
+	for _, order := range orders {
+		if _, ok := quotes[order.StockCode]; ok {
+			continue
+		}
+		resp, err := stock.FetchStockData(order.StockCode)
+		if err != nil || resp.CurrentPrice == "" {
+			log.Warnf("获取条件单行情失败: stockCode=%s, err=%v", order.StockCode, err)
+			continue
+		}
+		price, err := strconv.ParseFloat(resp.CurrentPrice, 64)
+		if err != nil {
+			continue
+		}
+		quote := finsvc.ConditionalQuote{Price: finsvc.PriceToHao(order.StockCode, price)}
+		if prevClose, err := strconv.ParseFloat(resp.PrevClose, 64); err == nil {
+			quote.PrevClose = finsvc.PriceToHao(order.StockCode, prevClose)
+		}
+		if minute, err := stock.FetchStockMinuteData(order.StockCode); err == nil {
+			quote.Path = finsvc.MinutePricePath(minute)
+		} else {
+			log.Warnf("获取分时数据失败，仅按最新价评估条件单: stockCode=%s, err=%v", order.StockCode, err)
+		}
+		quotes[order.StockCode] = quote
+	}
+
+	transIDs, err := finsvc.EvaluateConditionalOrders(database, accountID, quotes, calendar.Now())
+	if err != nil {
+		log.Warnf("评估条件单失败: %v", err)
+	}
+	if len(transIDs) > 0 {
+		log.Infof("条件单触发卖出订单: %v", transIDs)
+	}
+	return transIDs
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/glebarez/sqlite"
 	"gorm.io/gorm"
@@ -12,6 +13,13 @@ import (
 	"msa/pkg/logic/finsvc"
 )
 
+var (
+	// testTradeTime 测试用下单时间（周一交易时段内，UTC+8）
+	testTradeTime = time.Date(2025, 3, 3, 10, 0, 0, 0, time.FixedZone("CST", 8*3600))
+	// testNextTradeTime 测试用下一交易日时间，用于满足 T+1 卖出
+	testNextTradeTime = testTradeTime.AddDate(0, 0, 1)
+)
+
 // setupTestDB 创建测试用的临时数据库
 func setupTestDB(t *testing.T) *gorm.DB {
 	t.Helper()
@@ -26,7 +34,8 @@ func setupTestDB(t *testing.T) *gorm.DB {
 	}
 
 	// 自动迁移
-	if err := db.AutoMigrate(&model.Account{}, &model.Transaction{}); err != nil {
+	if err := db.AutoMigrate(&model.Account{}, &model.Transaction{}, &model.CashFlow{}, &model.CorporateAction{},
+		&model.PortfolioSnapshot{}, &model.PortfolioSnapshotHolding{}, &model.ConditionalOrder{}); err != nil {
 		t.Fatalf("Failed to migrate test database: %v", err)
 	}
 
@@ -89,7 +98,7 @@ func TestIntegration_BuyOrderFlow(t *testing.T) {
 		StockName: "贵州茅台",
 		Quantity:  100,
 		Price:     model.YuanToHao(10.50),
-		Fee:       model.YuanToHao(0.05),
+		Time:      testTradeTime,
 	}
 	txID, err := finsvc.SubmitBuyOrder(db, account.ID, order)
 	if err != nil {
@@ -128,7 +137,7 @@ func TestIntegration_BuyOrderFlow(t *testing.T) {
 		t.Fatalf("Failed to get account after fill: %v", err)
 	}
 
-	expectedBalance := model.YuanToHao(10000) - model.YuanToHao(10.50)*100 - model.YuanToHao(0.05) // 手续费 0.05 元
+	expectedBalance := model.YuanToHao(10000) - model.YuanToHao(10.50)*100 - model.YuanToHao(5.01) // 手续费：佣金 5 元 + 过户费 0.01 元
 	if account.AvailableAmt != expectedBalance {
 		t.Errorf("Expected balance %d, got %d", expectedBalance, account.AvailableAmt)
 	}
@@ -156,13 +165,13 @@ func TestIntegration_SellOrderFlow(t *testing.T) {
 
 	// 先买入建立持仓
 	price := model.YuanToHao(10.50)
-	quantity := int64(100)
+	quantity := int64(200)
 	buyOrder := finsvc.Order{
 		StockCode: "600519",
 		StockName: "贵州茅台",
 		Quantity:  quantity,
 		Price:     price,
-		Fee:       model.YuanToHao(0.05),
+		Time:      testTradeTime,
 	}
 	txID1, err := finsvc.SubmitBuyOrder(db, account.ID, buyOrder)
 	if err != nil {
@@ -194,7 +203,7 @@ func TestIntegration_SellOrderFlow(t *testing.T) {
 		StockName: "贵州茅台",
 		Quantity:  quantity / 2,
 		Price:     sellPrice,
-		Fee:       model.YuanToHao(0.05),
+		Time:      testNextTradeTime,
 	}
 	txID2, err := finsvc.SubmitSellOrder(db, account.ID, sellOrder)
 	if err != nil {
@@ -224,7 +233,8 @@ func TestIntegration_SellOrderFlow(t *testing.T) {
 	}
 
 	// 余额 = 初始 - 买入成本 - 买入手续费 + 卖出收入 - 卖出手续费
-	expectedBalance := model.YuanToHao(10000) - price*quantity - model.YuanToHao(0.05) + sellPrice*(quantity/2) - model.YuanToHao(0.05)
+	// 买入手续费 = 佣金 5 + 过户费 0.02；卖出手续费 = 佣金 5 + 印花税 0.55 + 过户费 0.01
+	expectedBalance := model.YuanToHao(10000) - price*quantity - 50200 + sellPrice*(quantity/2) - 55600
 	if account.AvailableAmt != expectedBalance {
 		t.Errorf("Expected balance %d, got %d", expectedBalance, account.AvailableAmt)
 	}
@@ -251,7 +261,7 @@ func TestIntegration_InsufficientBalance(t *testing.T) {
 		StockName: "贵州茅台",
 		Quantity:  1000,
 		Price:     model.YuanToHao(10),
-		Fee:       model.YuanToHao(0.05),
+		Time:      testTradeTime,
 	}
 	_, err = finsvc.SubmitBuyOrder(db, account.ID, order)
 	// SubmitBuyOrder 在余额不足时不返回错误，而是创建 REJECTED 记录
@@ -303,7 +313,7 @@ func TestIntegration_AccountTotalValue(t *testing.T) {
 		StockName: "贵州茅台",
 		Quantity:  quantity,
 		Price:     price,
-		Fee:       model.YuanToHao(0.05),
+		Time:      testTradeTime,
 	}
 	txID, err := finsvc.SubmitBuyOrder(db, accountID, order)
 	if err != nil {
@@ -324,10 +334,10 @@ func TestIntegration_AccountTotalValue(t *testing.T) {
 	}
 
 	// 总价值 = 可用余额 + 持仓市值
-	// 可用余额 = 10000 - 1000 - 0.05 = 8999.95 元 = 89999500 毫
+	// 可用余额 = 10000 - 1000 - 5.01 = 8994.99 元 = 89949900 毫
 	// 持仓市值 = 100 * 11 = 1100 元 = 11000000 毫
-	// 总价值 = 89999500 + 11000000 = 100999500 毫
-	expectedValue := model.YuanToHao(10000) - price*quantity - model.YuanToHao(0.05) + model.YuanToHao(11.00)*quantity
+	// 总价值 = 89949900 + 11000000 = 100949900 毫
+	expectedValue := model.YuanToHao(10000) - price*quantity - model.YuanToHao(5.01) + model.YuanToHao(11.00)*quantity
 	if result.TotalValue != expectedValue {
 		t.Errorf("Expected total value %d, got %d", expectedValue, result.TotalValue)
 	}
@@ -0,0 +1,182 @@
+package finance
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+	"gorm.io/gorm"
+	msadb "msa/pkg/db"
+	"msa/pkg/logic/finsvc"
+	"msa/pkg/logic/tools/safetool"
+	"msa/pkg/model"
+)
+
+// defaultEquityCurveDays 默认返回最近的快照数量
+const defaultEquityCurveDays = 30
+
+// RecordPortfolioSnapshot 撮合挂单后按实时价格记录账户当日净值快照
+// 收盘后调用时价格即为收盘价；任意持仓价格获取失败时不记录
+func RecordPortfolioSnapshot(database *gorm.DB, accountID uint, at time.Time) (*model.PortfolioSnapshot, error) {
+	SyncPendingOrders(database, accountID)
+
+	stockCodes, err := finsvc.GetActiveStockCodes(database, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	priceMap := make(finsvc.PriceMap)
+	if len(stockCodes) > 0 {
+		prices, err := fetchAllPrices(stockCodes)
+		if err != nil {
+			return nil, err
+		}
+		for code, price := range prices {
+			priceMap[code] = price
+		}
+	}
+
+	return finsvc.TakePortfolioSnapshot(database, accountID, priceMap, at)
+}
+
+// GetEquityCurveParam 查询净值曲线参数
+type GetEquityCurveParam struct {
+	Days         int     `json:"days,omitempty" jsonschema:"description=最近 N 个交易日（可选，默认 30；指定 start_date 时忽略）"`
+	StartDate    string  `json:"start_date,omitempty" jsonschema:"description=开始日期 YYYY-MM-DD（可选）"`
+	EndDate      string  `json:"end_date,omitempty" jsonschema:"description=结束日期 YYYY-MM-DD（可选）"`
+	RiskFreeRate float64 `json:"risk_free_rate,omitempty" jsonschema:"description=年化无风险利率（%），用于计算夏普比率（可选，默认 0）"`
+	Account      string  `json:"account,omitempty" jsonschema:"description=账户名称（可选，默认使用当前账户）"`
+}
+
+// GetEquityCurveTool 查询净值曲线工具
+type GetEquityCurveTool struct{}
+
+func (t *GetEquityCurveTool) GetToolInfo() (tool.BaseTool, error) {
+	return utils.InferTool(t.GetName(), t.GetDescription(), GetEquityCurve,
+		utils.WithUnmarshalArguments(unmarshalEmptyParam[GetEquityCurveParam]))
+}
+
+func (t *GetEquityCurveTool) GetName() string {
+	return "get_equity_curve"
+}
+
+func (t *GetEquityCurveTool) GetDescription() string {
+	return "查询账户每日收盘净值曲线，返回日收益率、累计收益率、最大回撤、年化波动率和夏普比率 | Get the daily closing NAV curve with daily/cumulative returns, max drawdown, annualized volatility and Sharpe ratio"
+}
+
+func (t *GetEquityCurveTool) GetToolGroup() model.ToolGroup {
+	return model.FinanceToolGroup
+}
+
+// EquityPointItem 净值曲线数据点
+type EquityPointItem struct {
+	TradeDate        string  `json:"trade_date"`
+	NAV              string  `json:"nav"`
+	NetFlow          string  `json:"net_flow,omitempty"` // 当日净入金
+	DailyReturn      float64 `json:"daily_return"`       // 日收益率（%）
+	CumulativeReturn float64 `json:"cumulative_return"`  // 累计收益率（%）
+	Drawdown         float64 `json:"drawdown"`           // 回撤（%）
+}
+
+// EquityCurveData 净值曲线数据
+type EquityCurveData struct {
+	Total            int               `json:"total"`
+	CumulativeReturn float64           `json:"cumulative_return"` // 区间累计收益率（%）
+	MaxDrawdown      float64           `json:"max_drawdown"`      // 最大回撤（%）
+	Volatility       float64           `json:"volatility"`        // 年化波动率（%）
+	Sharpe           float64           `json:"sharpe"`            // 年化夏普比率
+	LatestNAV        string            `json:"latest_nav,omitempty"`
+	PreviousNAV      string            `json:"previous_nav,omitempty"` // 前一交易日净值，用于与昨日对比
+	Points           []EquityPointItem `json:"points"`
+}
+
+// GetEquityCurve 查询净值曲线
+func GetEquityCurve(ctx context.Context, param *GetEquityCurveParam) (string, error) {
+	return safetool.SafeExecute("get_equity_curve", "", func() (string, error) {
+		return doGetEquityCurve(ctx, param)
+	})
+}
+
+func doGetEquityCurve(ctx context.Context, param *GetEquityCurveParam) (string, error) {
+	database := msadb.GetDB()
+	if database == nil {
+		err := fmt.Errorf("数据库未初始化")
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	account, err := ResolveAccount(database, param.Account)
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	for _, date := range []string{param.StartDate, param.EndDate} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse(finsvc.SnapshotDateLayout, date); err != nil {
+			return model.NewErrorResult(fmt.Sprintf("日期格式错误: %s，应为 YYYY-MM-DD", date)), nil
+		}
+	}
+
+	snapshots, err := msadb.GetPortfolioSnapshots(database, account.ID, param.StartDate, param.EndDate)
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+	if len(snapshots) == 0 {
+		return model.NewSuccessResult(&EquityCurveData{Points: []EquityPointItem{}}, "暂无净值快照，收盘后查询账户总览会自动记录"), nil
+	}
+
+	days := param.Days
+	if days <= 0 {
+		days = defaultEquityCurveDays
+	}
+	if param.StartDate == "" && len(snapshots) > days {
+		snapshots = snapshots[len(snapshots)-days:]
+	}
+
+	curve := finsvc.ComputeEquityCurve(snapshots, param.RiskFreeRate/100)
+	data := toEquityCurveData(curve)
+
+	return model.NewSuccessResult(data, fmt.Sprintf("获取 %d 个交易日净值", data.Total)), nil
+}
+
+// toEquityCurveData 净值曲线转换为返回数据，收益率以百分比表示
+func toEquityCurveData(curve *finsvc.EquityCurve) *EquityCurveData {
+	data := &EquityCurveData{
+		Total:            len(curve.Points),
+		CumulativeReturn: toPercent(curve.CumulativeReturn),
+		MaxDrawdown:      toPercent(curve.MaxDrawdown),
+		Volatility:       toPercent(curve.Volatility),
+		Sharpe:           math.Round(curve.Sharpe*100) / 100,
+		Points:           make([]EquityPointItem, 0, len(curve.Points)),
+	}
+	for _, p := range curve.Points {
+		item := EquityPointItem{
+			TradeDate:        p.TradeDate,
+			NAV:              formatHaoToYuan(p.NAV),
+			DailyReturn:      toPercent(p.DailyReturn),
+			CumulativeReturn: toPercent(p.CumulativeReturn),
+			Drawdown:         toPercent(p.Drawdown),
+		}
+		if p.NetFlow != 0 {
+			item.NetFlow = formatHaoToYuan(p.NetFlow)
+		}
+		data.Points = append(data.Points, item)
+	}
+
+	if n := len(curve.Points); n > 0 {
+		data.LatestNAV = formatHaoToYuan(curve.Points[n-1].NAV)
+		if n > 1 {
+			data.PreviousNAV = formatHaoToYuan(curve.Points[n-2].NAV)
+		}
+	}
+	return data
+}
+
+// toPercent 小数转百分比，保留两位小数
+func toPercent(v float64) float64 {
+	return math.Round(v*10000) / 100
+}
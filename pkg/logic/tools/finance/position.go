@@ -7,16 +7,20 @@ import (
 
 	"encoding/json"
 	msadb "msa/pkg/db"
+	"msa/pkg/logic/calendar"
 	"msa/pkg/logic/finsvc"
 	"msa/pkg/logic/tools/safetool"
 	"msa/pkg/model"
 
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/components/tool/utils"
+	log "github.com/sirupsen/logrus"
 )
 
 // GetPositionsParam 查询持仓参数
-type GetPositionsParam struct{}
+type GetPositionsParam struct {
+	Account string `json:"account,omitempty" jsonschema:"description=账户名称（可选，默认使用当前账户）"`
+}
 
 // GetPositionsTool 查询持仓列表工具
 type GetPositionsTool struct{}
@@ -40,14 +44,16 @@ func (t *GetPositionsTool) GetToolGroup() model.ToolGroup {
 
 // PositionItem 持仓项
 type PositionItem struct {
-	StockCode    string `json:"stock_code"`
-	StockName    string `json:"stock_name"`
-	Quantity     int64  `json:"quantity"`
-	Cost         string `json:"cost"`
-	CurrentPrice string `json:"current_price"`
-	Value        string `json:"value"`
-	PnL          string `json:"pnl"`
-	PnLStatus    string `json:"pnl_status"`
+	StockCode     string `json:"stock_code"`
+	StockName     string `json:"stock_name"`
+	Quantity      int64  `json:"quantity"`
+	Cost          string `json:"cost"`     // 剩余持仓成本（含买入手续费）
+	AvgCost       string `json:"avg_cost"` // 每股平均成本
+	CurrentPrice  string `json:"current_price"`
+	Value         string `json:"value"`
+	UnrealizedPnL string `json:"unrealized_pnl"` // 浮动盈亏 = 市值 - 剩余成本
+	RealizedPnL   string `json:"realized_pnl"`   // 已实现盈亏（卖出与分红）
+	PnLStatus     string `json:"pnl_status"`
 }
 
 // PositionsData 持仓数据
@@ -70,11 +76,14 @@ func doGetPositions(ctx context.Context, param *GetPositionsParam) (string, erro
 		return model.NewErrorResult(err.Error()), nil
 	}
 
-	account, err := getActiveAccount(database)
+	account, err := getActiveAccount(database, param.Account)
 	if err != nil {
 		return model.NewErrorResult(err.Error()), nil
 	}
 
+	// 先撮合挂单，成交后的持仓和资金才能计入
+	SyncPendingOrders(database, account.ID)
+
 	// 获取当前实际有持仓（净持仓 > 0）的股票代码，排除已平仓股票
 	stockCodes, err := finsvc.GetActiveStockCodes(database, account.ID)
 	if err != nil {
@@ -111,18 +120,20 @@ func doGetPositions(ctx context.Context, param *GetPositionsParam) (string, erro
 	items := make([]PositionItem, 0, len(positions))
 	for _, pos := range positions {
 		pnlStatus := "亏损"
-		if pos.PnL >= 0 {
+		if pos.UnrealizedPnL >= 0 {
 			pnlStatus = "盈利"
 		}
 		items = append(items, PositionItem{
-			StockCode:    pos.StockCode,
-			StockName:    pos.StockName,
-			Quantity:     pos.Quantity,
-			Cost:         formatHaoToYuan(pos.Cost),
-			CurrentPrice: formatHaoToYuan(pos.CurrentPrice),
-			Value:        formatHaoToYuan(pos.Value),
-			PnL:          formatHaoToYuan(pos.PnL),
-			PnLStatus:    pnlStatus,
+			StockCode:     pos.StockCode,
+			StockName:     pos.StockName,
+			Quantity:      pos.Quantity,
+			Cost:          formatHaoToYuan(pos.Cost),
+			AvgCost:       formatHaoToYuan(pos.AvgCost),
+			CurrentPrice:  formatHaoToYuan(pos.CurrentPrice),
+			Value:         formatHaoToYuan(pos.Value),
+			UnrealizedPnL: formatHaoToYuan(pos.UnrealizedPnL),
+			RealizedPnL:   formatHaoToYuan(pos.RealizedPnL),
+			PnLStatus:     pnlStatus,
 		})
 	}
 
@@ -135,7 +146,9 @@ func doGetPositions(ctx context.Context, param *GetPositionsParam) (string, erro
 }
 
 // GetAccountSummaryParam 查询账户总览参数
-type GetAccountSummaryParam struct{}
+type GetAccountSummaryParam struct {
+	Account string `json:"account,omitempty" jsonschema:"description=账户名称（可选，默认使用当前账户）"`
+}
 
 // GetAccountSummaryTool 查询账户总览工具
 type GetAccountSummaryTool struct{}
@@ -159,16 +172,21 @@ func (t *GetAccountSummaryTool) GetToolGroup() model.ToolGroup {
 
 // AccountSummaryData 账户总览数据
 type AccountSummaryData struct {
-	TotalAssets   string   `json:"total_assets"`
-	AvailableAmt  string   `json:"available_amt"`
-	LockedAmt     string   `json:"locked_amt"`
-	PositionValue string   `json:"position_value"`
-	InitialAmount string   `json:"initial_amount"`
-	TotalPnL      string   `json:"total_pnl"`
-	PnLRatio      float64  `json:"pnl_ratio"`
-	PnLStatus     string   `json:"pnl_status"`
-	FailedStocks  []string `json:"failed_stocks,omitempty"` // 价格获取失败的股票
-	Warning       string   `json:"warning,omitempty"`       // 警告信息
+	TotalAssets     string   `json:"total_assets"`
+	AvailableAmt    string   `json:"available_amt"`
+	LockedAmt       string   `json:"locked_amt"`
+	PositionValue   string   `json:"position_value"`
+	InitialAmount   string   `json:"initial_amount"`
+	NetContribution string   `json:"net_contribution"` // 净投入本金（初始金额 + 入金 - 出金）
+	TotalPnL        string   `json:"total_pnl"`
+	PnLRatio        float64  `json:"pnl_ratio"`
+	PnLStatus       string   `json:"pnl_status"`
+	RealizedPnL     string   `json:"realized_pnl"`            // 已实现盈亏（含已清仓股票）
+	UnrealizedPnL   string   `json:"unrealized_pnl"`          // 当前持仓浮动盈亏
+	CostMethod      string   `json:"cost_method"`             // 成本计算方法：FIFO / AVERAGE
+	SnapshotDate    string   `json:"snapshot_date,omitempty"` // 收盘后查询时记录的净值快照交易日
+	FailedStocks    []string `json:"failed_stocks,omitempty"` // 价格获取失败的股票
+	Warning         string   `json:"warning,omitempty"`       // 警告信息
 }
 
 // GetAccountSummary 获取账户总览
@@ -185,11 +203,14 @@ func doGetAccountSummary(ctx context.Context, param *GetAccountSummaryParam) (st
 		return model.NewErrorResult(err.Error()), nil
 	}
 
-	account, err := getActiveAccount(database)
+	account, err := getActiveAccount(database, param.Account)
 	if err != nil {
 		return model.NewErrorResult(err.Error()), nil
 	}
 
+	// 先撮合挂单，成交后的持仓和资金才能计入
+	SyncPendingOrders(database, account.ID)
+
 	// 获取当前实际有持仓（净持仓 > 0）的股票代码，排除已平仓股票
 	stockCodes, err := finsvc.GetActiveStockCodes(database, account.ID)
 	if err != nil {
@@ -214,11 +235,15 @@ func doGetAccountSummary(ctx context.Context, param *GetAccountSummaryParam) (st
 		return model.NewErrorResult(err.Error()), nil
 	}
 
-	// 计算总盈亏
-	pnl := result.TotalValue - account.InitialAmount
+	// 计算总盈亏（相对净投入本金，入金出金不计入盈亏）
+	contribution, err := finsvc.GetNetContribution(database, account.ID)
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+	pnl := result.TotalValue - contribution
 	pnlRatio := 0.0
-	if account.InitialAmount > 0 {
-		pnlRatio = float64(pnl) / float64(account.InitialAmount) * 100
+	if contribution > 0 {
+		pnlRatio = float64(pnl) / float64(contribution) * 100
 	}
 
 	pnlStatus := "亏损"
@@ -226,6 +251,16 @@ func doGetAccountSummary(ctx context.Context, param *GetAccountSummaryParam) (st
 		pnlStatus = "盈利"
 	}
 
+	// 收盘后记录当日净值快照，供 get_equity_curve 计算收益曲线
+	var snapshotDate string
+	if now := calendar.Now(); finsvc.IsAfterMarketClose(now) && len(result.FailedStocks) == 0 {
+		if snapshot, err := finsvc.TakePortfolioSnapshot(database, account.ID, priceMap, now); err != nil {
+			log.Warnf("记录净值快照失败: %v", err)
+		} else {
+			snapshotDate = snapshot.TradeDate
+		}
+	}
+
 	// 生成警告信息
 	var warning string
 	if len(result.FailedStocks) > 0 {
@@ -237,16 +272,21 @@ func doGetAccountSummary(ctx context.Context, param *GetAccountSummaryParam) (st
 	}
 
 	data := &AccountSummaryData{
-		TotalAssets:   formatHaoToYuan(result.TotalValue),
-		AvailableAmt:  formatHaoToYuan(result.AvailableAmt),
-		LockedAmt:     formatHaoToYuan(result.LockedAmt),
-		PositionValue: formatHaoToYuan(result.PositionValue),
-		InitialAmount: formatHaoToYuan(account.InitialAmount),
-		TotalPnL:      formatHaoToYuan(pnl),
-		PnLRatio:      pnlRatio,
-		PnLStatus:     pnlStatus,
-		FailedStocks:  result.FailedStocks,
-		Warning:       warning,
+		TotalAssets:     formatHaoToYuan(result.TotalValue),
+		AvailableAmt:    formatHaoToYuan(result.AvailableAmt),
+		LockedAmt:       formatHaoToYuan(result.LockedAmt),
+		PositionValue:   formatHaoToYuan(result.PositionValue),
+		InitialAmount:   formatHaoToYuan(account.InitialAmount),
+		NetContribution: formatHaoToYuan(contribution),
+		TotalPnL:        formatHaoToYuan(pnl),
+		PnLRatio:        pnlRatio,
+		PnLStatus:       pnlStatus,
+		RealizedPnL:     formatHaoToYuan(result.RealizedPnL),
+		UnrealizedPnL:   formatHaoToYuan(result.UnrealizedPnL),
+		CostMethod:      string(finsvc.GetCostMethod()),
+		SnapshotDate:    snapshotDate,
+		FailedStocks:    result.FailedStocks,
+		Warning:         warning,
 	}
 
 	return model.NewSuccessResult(data, "获取账户总览成功"), nil
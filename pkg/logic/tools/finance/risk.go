@@ -0,0 +1,358 @@
+package finance
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+	log "github.com/sirupsen/logrus"
+
+	msadb "msa/pkg/db"
+	"msa/pkg/logic/finsvc"
+	"msa/pkg/logic/marketdata"
+	"msa/pkg/logic/risk"
+	"msa/pkg/logic/tools/safetool"
+	"msa/pkg/logic/tools/stock"
+	"msa/pkg/model"
+)
+
+// GetPortfolioRiskParam 组合风险分析参数
+type GetPortfolioRiskParam struct {
+	Days       int     `json:"days,omitempty" jsonschema:"description=计算相关性、波动率与 VaR 的日收益数量（可选，默认 60，最多 250）"`
+	Confidence float64 `json:"confidence,omitempty" jsonschema:"description=VaR 置信度（%，可选，默认 95）"`
+	StockCode  string  `json:"stock_code,omitempty" jsonschema:"description=拟买入股票代码（可选，填写后返回 what-if 分析）"`
+	StockName  string  `json:"stock_name,omitempty" jsonschema:"description=拟买入股票名称（可选）"`
+	Quantity   int64   `json:"quantity,omitempty" jsonschema:"description=拟买入数量（股，填写 stock_code 时必填）"`
+	Price      float64 `json:"price,omitempty" jsonschema:"description=拟买入价格（元/股，可选，默认当前价）"`
+	Account    string  `json:"account,omitempty" jsonschema:"description=账户名称（可选，默认使用当前账户）"`
+}
+
+// GetPortfolioRiskTool 组合风险分析工具
+type GetPortfolioRiskTool struct{}
+
+func (t *GetPortfolioRiskTool) GetToolInfo() (tool.BaseTool, error) {
+	return utils.InferTool(t.GetName(), t.GetDescription(), GetPortfolioRisk,
+		utils.WithUnmarshalArguments(unmarshalEmptyParam[GetPortfolioRiskParam]))
+}
+
+func (t *GetPortfolioRiskTool) GetName() string {
+	return "get_portfolio_risk"
+}
+
+func (t *GetPortfolioRiskTool) GetDescription() string {
+	return "组合风险分析：个股与申万行业权重、集中度、持仓日收益相关性矩阵、组合波动率与历史 VaR；传入拟买入的 stock_code 与 quantity 时返回买入后的 what-if 对比与相关告警，买入前调用 | Portfolio risk: stock and industry weights, concentration, pairwise return correlation, volatility and historical VaR; pass a proposed buy to get a what-if comparison and related alerts before submit_buy_order"
+}
+
+func (t *GetPortfolioRiskTool) GetToolGroup() model.ToolGroup {
+	return model.FinanceToolGroup
+}
+
+// RiskStockItem 个股风险
+type RiskStockItem struct {
+	StockCode   string  `json:"stock_code"`
+	StockName   string  `json:"stock_name,omitempty"`
+	Industry    string  `json:"industry"`
+	SubIndustry string  `json:"sub_industry,omitempty"`
+	Value       string  `json:"value"`
+	Weight      float64 `json:"weight"`     // 占总资产（%）
+	Volatility  float64 `json:"volatility"` // 年化波动率（%）
+}
+
+// RiskIndustryItem 行业权重
+type RiskIndustryItem struct {
+	Industry string   `json:"industry"`
+	Value    string   `json:"value"`
+	Weight   float64  `json:"weight"` // 占总资产（%）
+	Stocks   []string `json:"stocks"`
+}
+
+// RiskCorrelationItem 高度相关的持仓
+type RiskCorrelationItem struct {
+	StockA      string  `json:"stock_a"`
+	StockB      string  `json:"stock_b"`
+	Correlation float64 `json:"correlation"`
+}
+
+// PortfolioRiskData 组合风险数据，权重、波动率与 VaR 均为百分比
+type PortfolioRiskData struct {
+	TotalAssets      string                `json:"total_assets"`
+	Cash             string                `json:"cash"`
+	PositionValue    string                `json:"position_value"`
+	PositionWeight   float64               `json:"position_weight"`
+	Stocks           []RiskStockItem       `json:"stocks"`
+	Industries       []RiskIndustryItem    `json:"industries"`
+	SubIndustries    []RiskIndustryItem    `json:"sub_industries"`
+	Top1Weight       float64               `json:"top1_weight"`
+	Top3Weight       float64               `json:"top3_weight"`
+	HHI              float64               `json:"hhi"`
+	EffectiveN       float64               `json:"effective_n"`
+	Codes            []string              `json:"codes"`
+	Correlation      [][]float64           `json:"correlation"`
+	HighCorrelations []RiskCorrelationItem `json:"high_correlations,omitempty"`
+	Observations     int                   `json:"observations"`
+	Confidence       float64               `json:"confidence"`
+	Volatility       float64               `json:"volatility"` // 组合年化波动率（%）
+	VaR              float64               `json:"var"`        // 1 日历史 VaR（%）
+	VaRAmount        string                `json:"var_amount"`
+	CVaR             float64               `json:"cvar"`
+	CVaRAmount       string                `json:"cvar_amount"`
+	Alerts           []string              `json:"alerts,omitempty"`
+	Warnings         []string              `json:"warnings,omitempty"`
+}
+
+// RiskWhatIfData 拟买入订单的 what-if 分析
+type RiskWhatIfData struct {
+	StockCode       string             `json:"stock_code"`
+	StockName       string             `json:"stock_name,omitempty"`
+	Quantity        int64              `json:"quantity"`
+	Price           string             `json:"price"`
+	Cost            string             `json:"cost"`             // 成交金额 + 手续费
+	InsufficientAmt bool               `json:"insufficient_amt"` // 现金不足以支付
+	StockWeight     float64            `json:"stock_weight"`     // 买入后该股占总资产（%）
+	IndustryWeight  float64            `json:"industry_weight"`  // 买入后所属一级行业占总资产（%）
+	MaxCorrelation  float64            `json:"max_correlation"`  // 与现有持仓的最大相关系数
+	CorrelatedWith  string             `json:"correlated_with,omitempty"`
+	Alerts          []string           `json:"alerts,omitempty"` // 买入后涉及该股的行业超限与高相关告警
+	Pass            bool               `json:"pass"`             // 没有告警且现金充足
+	After           *PortfolioRiskData `json:"after"`
+}
+
+// PortfolioRiskResult 组合风险分析结果
+type PortfolioRiskResult struct {
+	Current *PortfolioRiskData `json:"current"`
+	WhatIf  *RiskWhatIfData    `json:"what_if,omitempty"`
+}
+
+// GetPortfolioRisk 组合风险分析
+func GetPortfolioRisk(ctx context.Context, param *GetPortfolioRiskParam) (string, error) {
+	return safetool.SafeExecute("get_portfolio_risk", param.StockCode, func() (string, error) {
+		return doGetPortfolioRisk(ctx, param)
+	})
+}
+
+func doGetPortfolioRisk(ctx context.Context, param *GetPortfolioRiskParam) (string, error) {
+	database := msadb.GetDB()
+	if database == nil {
+		err := fmt.Errorf("数据库未初始化")
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	account, err := getActiveAccount(database, param.Account)
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+	param.StockCode = strings.ToLower(strings.TrimSpace(param.StockCode))
+	if param.StockCode != "" && param.Quantity <= 0 {
+		return model.NewErrorResult("填写 stock_code 时 quantity 必须大于 0"), nil
+	}
+
+	// 先撮合挂单，成交后的持仓和资金才能计入
+	SyncPendingOrders(database, account.ID)
+
+	stockCodes, err := finsvc.GetActiveStockCodes(database, account.ID)
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+	quoteCodes := stockCodes
+	if param.StockCode != "" && param.Price <= 0 {
+		quoteCodes = append(append([]string(nil), stockCodes...), param.StockCode)
+	}
+	prices, err := fetchAllPrices(quoteCodes)
+	if err != nil {
+		return model.NewErrorResult(fmt.Sprintf("获取持仓价格失败，无法计算市值: %v", err)), nil
+	}
+	priceMap := make(finsvc.PriceMap)
+	for code, price := range prices {
+		priceMap[code] = price
+	}
+
+	positions, err := finsvc.GetAllPositions(database, account.ID, priceMap)
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+	value, err := finsvc.GetAccountTotalValue(database, account.ID, priceMap)
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	provider, err := marketdata.GetProvider()
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	days := param.Days
+	if days <= 0 {
+		days = risk.DefaultLookback
+	}
+	days = min(days, risk.MaxLookback)
+	confidence := risk.DefaultConfidence
+	if param.Confidence > 0 && param.Confidence < 100 {
+		confidence = param.Confidence / 100
+	}
+
+	in := &risk.Input{
+		Cash:       value.AvailableAmt + value.LockedAmt,
+		Bars:       map[string][]model.KLineBar{},
+		Lookback:   days,
+		Confidence: confidence,
+	}
+	var warnings []string
+	load := func(code, name string, quantity, price int64) risk.Holding {
+		h := risk.Holding{Code: code, Name: name, Quantity: quantity, Price: price, Value: quantity * price}
+		if resp, err := provider.GetIndustry(code); err != nil {
+			log.Warnf("get_portfolio_risk: 获取 %s 行业失败: %v", code, err)
+			warnings = append(warnings, fmt.Sprintf("%s 行业分类获取失败，归入%s", code, risk.UnknownIndustry))
+		} else if level1, level2 := resp.IndustryLevels(); level1 != nil {
+			h.Industry = level1.Name
+			if level2 != nil {
+				h.SubIndustry = level2.Name
+			}
+		}
+		bars, err := stock.FetchStockHistoryK(code, "day", days+1, "qfq")
+		if err != nil {
+			log.Warnf("get_portfolio_risk: 获取 %s K线失败: %v", code, err)
+			warnings = append(warnings, fmt.Sprintf("%s 日K线获取失败，日收益按 0 计算", code))
+		}
+		in.Bars[code] = bars
+		return h
+	}
+	for _, pos := range positions {
+		in.Holdings = append(in.Holdings, load(pos.StockCode, pos.StockName, pos.Quantity, pos.CurrentPrice))
+	}
+
+	report := risk.Analyze(in)
+	report.Warnings = slices.Concat(warnings, report.Warnings)
+	result := &PortfolioRiskResult{Current: toPortfolioRiskData(report)}
+	message := fmt.Sprintf("%d 只持仓，年化波动率 %.2f%%，%d 条告警", len(report.Stocks), result.Current.Volatility, len(report.Alerts))
+
+	if param.StockCode != "" {
+		price := finsvc.PriceToHao(param.StockCode, param.Price)
+		if param.Price <= 0 {
+			price = prices[param.StockCode]
+		}
+		// 已持有时沿用持仓的名称与行业，否则获取行业与K线
+		order := risk.Holding{Code: param.StockCode, Name: param.StockName}
+		held := false
+		for _, h := range in.Holdings {
+			if h.Code == param.StockCode {
+				order, held = h, true
+			}
+		}
+		if !held {
+			order = load(param.StockCode, param.StockName, 0, 0)
+		}
+		order.Quantity, order.Price = param.Quantity, price
+		amount := param.Quantity * price
+		cost := amount + finsvc.CalculateFees(model.TransactionTypeBuy, param.StockCode, amount).Total()
+
+		after := risk.Analyze(in.WithBuy(order, cost))
+		after.Warnings = slices.Concat(warnings, after.Warnings)
+		result.WhatIf = toRiskWhatIf(after, order, cost, value.AvailableAmt)
+		message += fmt.Sprintf("；买入 %s %d 股后 %d 条相关告警", param.StockCode, param.Quantity, len(result.WhatIf.Alerts))
+	}
+
+	return model.NewSuccessResult(result, message), nil
+}
+
+// toRiskWhatIf 买入后的风险报告转换为 what-if 数据
+func toRiskWhatIf(after *risk.Report, order risk.Holding, cost, available int64) *RiskWhatIfData {
+	data := &RiskWhatIfData{
+		StockCode:       order.Code,
+		StockName:       order.Name,
+		Quantity:        order.Quantity,
+		Price:           formatHaoToYuan(order.Price),
+		Cost:            formatHaoToYuan(cost),
+		InsufficientAmt: cost > available,
+		After:           toPortfolioRiskData(after),
+	}
+	for _, s := range after.Stocks {
+		if s.Code == order.Code {
+			data.StockWeight = toPercent(s.Weight)
+		}
+	}
+	industry := order.Industry
+	if industry == "" {
+		industry = risk.UnknownIndustry
+	}
+	for _, ind := range after.Industries {
+		if ind.Name == industry {
+			data.IndustryWeight = toPercent(ind.Weight)
+		}
+	}
+	for i, code := range after.Codes {
+		if code != order.Code {
+			continue
+		}
+		for j, other := range after.Codes {
+			if j != i && (data.CorrelatedWith == "" || after.Correlation[i][j] > data.MaxCorrelation) {
+				data.MaxCorrelation = math.Round(after.Correlation[i][j]*100) / 100
+				data.CorrelatedWith = other
+			}
+		}
+	}
+
+	data.Alerts = after.AlertsFor(order.Code)
+	data.Pass = len(data.Alerts) == 0 && !data.InsufficientAmt
+	return data
+}
+
+// toPortfolioRiskData 风险报告转换为返回数据
+func toPortfolioRiskData(r *risk.Report) *PortfolioRiskData {
+	data := &PortfolioRiskData{
+		TotalAssets:    formatHaoToYuan(r.NAV),
+		Cash:           formatHaoToYuan(r.Cash),
+		PositionValue:  formatHaoToYuan(r.PositionValue),
+		PositionWeight: toPercent(r.PositionWeight),
+		Stocks:         make([]RiskStockItem, 0, len(r.Stocks)),
+		Industries:     toRiskIndustryItems(r.Industries),
+		SubIndustries:  toRiskIndustryItems(r.SubIndustries),
+		Top1Weight:     toPercent(r.Top1Weight),
+		Top3Weight:     toPercent(r.Top3Weight),
+		HHI:            math.Round(r.HHI*10000) / 10000,
+		EffectiveN:     math.Round(r.EffectiveN*100) / 100,
+		Codes:          r.Codes,
+		Correlation:    make([][]float64, 0, len(r.Correlation)),
+		Observations:   r.Observations,
+		Confidence:     toPercent(r.Confidence),
+		Volatility:     toPercent(r.Volatility),
+		VaR:            toPercent(r.VaR),
+		VaRAmount:      formatHaoToYuan(r.VaRAmount),
+		CVaR:           toPercent(r.CVaR),
+		CVaRAmount:     formatHaoToYuan(r.CVaRAmount),
+		Alerts:         r.Alerts,
+		Warnings:       r.Warnings,
+	}
+	for _, s := range r.Stocks {
+		data.Stocks = append(data.Stocks, RiskStockItem{
+			StockCode: s.Code, StockName: s.Name, Industry: s.Industry, SubIndustry: s.SubIndustry,
+			Value: formatHaoToYuan(s.Value), Weight: toPercent(s.Weight), Volatility: toPercent(s.Volatility),
+		})
+	}
+	for _, row := range r.Correlation {
+		rounded := make([]float64, len(row))
+		for i, v := range row {
+			rounded[i] = math.Round(v*100) / 100
+		}
+		data.Correlation = append(data.Correlation, rounded)
+	}
+	for _, c := range r.HighCorrelations {
+		data.HighCorrelations = append(data.HighCorrelations, RiskCorrelationItem{
+			StockA: c.A, StockB: c.B, Correlation: math.Round(c.Value*100) / 100,
+		})
+	}
+	return data
+}
+
+func toRiskIndustryItems(list []risk.IndustryWeight) []RiskIndustryItem {
+	items := make([]RiskIndustryItem, 0, len(list))
+	for _, ind := range list {
+		items = append(items, RiskIndustryItem{
+			Industry: ind.Name, Value: formatHaoToYuan(ind.Value), Weight: toPercent(ind.Weight), Stocks: ind.Stocks,
+		})
+	}
+	return items
+}
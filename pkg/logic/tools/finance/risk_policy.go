@@ -0,0 +1,127 @@
+package finance
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+
+	msadb "msa/pkg/db"
+	"msa/pkg/logic/calendar"
+	"msa/pkg/logic/finsvc"
+	"msa/pkg/logic/marketdata"
+	"msa/pkg/logic/tools/safetool"
+	"msa/pkg/model"
+)
+
+// GetRiskPolicyParam 查询风控限制参数
+type GetRiskPolicyParam struct {
+	Account string `json:"account,omitempty" jsonschema:"description=账户名称（可选，默认使用当前账户），用于统计当日已用额度"`
+}
+
+// GetRiskPolicyTool 查询风控限制工具
+type GetRiskPolicyTool struct{}
+
+func (t *GetRiskPolicyTool) GetToolInfo() (tool.BaseTool, error) {
+	return utils.InferTool(t.GetName(), t.GetDescription(), GetRiskPolicy,
+		utils.WithUnmarshalArguments(unmarshalEmptyParam[GetRiskPolicyParam]))
+}
+
+func (t *GetRiskPolicyTool) GetName() string {
+	return "get_risk_policy"
+}
+
+func (t *GetRiskPolicyTool) GetDescription() string {
+	return "查询系统强制执行的风控限制（单股仓位上限、行业仓位上限、当日成交额上限、当日委托笔数上限、黑名单、最低现金保留）及当日已用额度，违反限制的订单会被直接拒绝（卖出只受当日委托笔数与成交额限制，条件单触发的卖出不受限制），下单前调用 | Get the hard risk limits enforced on orders (max position, max industry, daily turnover, orders per day, blacklist, min cash reserve; sells are limited by orders per day and daily turnover only; conditional-order sells are exempt) and today's usage; call before placing orders"
+}
+
+func (t *GetRiskPolicyTool) GetToolGroup() model.ToolGroup {
+	return model.FinanceToolGroup
+}
+
+// RiskPolicyData 风控限制数据，比例为占总资产的百分比，0 表示不限制
+type RiskPolicyData struct {
+	Enabled             bool     `json:"enabled"`
+	MaxPositionPct      float64  `json:"max_position_pct"`
+	MaxIndustryPct      float64  `json:"max_industry_pct"`
+	MaxDailyTurnoverPct float64  `json:"max_daily_turnover_pct"`
+	MaxOrdersPerDay     int      `json:"max_orders_per_day"`
+	Blacklist           []string `json:"blacklist"`
+	MinCashReservePct   float64  `json:"min_cash_reserve_pct"`
+	Account             string   `json:"account,omitempty"`
+	TodayOrders         int      `json:"today_orders"`
+	RemainingOrders     *int     `json:"remaining_orders,omitempty"` // 设置了委托笔数上限时的剩余笔数
+	TodayTurnover       string   `json:"today_turnover"`
+	Warning             string   `json:"warning,omitempty"`
+}
+
+// GetRiskPolicy 查询风控限制
+func GetRiskPolicy(ctx context.Context, param *GetRiskPolicyParam) (string, error) {
+	return safetool.SafeExecute("get_risk_policy", param.Account, func() (string, error) {
+		return doGetRiskPolicy(ctx, param)
+	})
+}
+
+func doGetRiskPolicy(ctx context.Context, param *GetRiskPolicyParam) (string, error) {
+	policy := finsvc.GetRiskPolicy()
+	data := &RiskPolicyData{
+		Enabled:             !policy.IsEmpty(),
+		MaxPositionPct:      policy.MaxPositionPct,
+		MaxIndustryPct:      policy.MaxIndustryPct,
+		MaxDailyTurnoverPct: policy.MaxDailyTurnoverPct,
+		MaxOrdersPerDay:     policy.MaxOrdersPerDay,
+		Blacklist:           append([]string{}, policy.Blacklist...),
+		MinCashReservePct:   policy.MinCashReservePct,
+		TodayTurnover:       formatHaoToYuan(0),
+	}
+
+	// 当日已用额度，账户不可用时只返回限制
+	if database := msadb.GetDB(); database == nil {
+		data.Warning = "数据库未初始化，未统计当日已用额度"
+	} else if account, err := ResolveAccount(database, param.Account); err != nil {
+		data.Warning = fmt.Sprintf("未统计当日已用额度: %v", err)
+	} else {
+		usage, err := finsvc.GetRiskUsage(database, account.ID, calendar.Now())
+		if err != nil {
+			return model.NewErrorResult(err.Error()), nil
+		}
+		data.Account = account.Name
+		data.TodayOrders = usage.Orders
+		data.TodayTurnover = formatHaoToYuan(usage.Turnover)
+		if policy.MaxOrdersPerDay > 0 {
+			remaining := max(policy.MaxOrdersPerDay-usage.Orders, 0)
+			data.RemainingOrders = &remaining
+		}
+	}
+
+	if !data.Enabled {
+		return model.NewSuccessResult(data, "未配置风控限制"), nil
+	}
+	return model.NewSuccessResult(data, "风控限制由系统强制执行，违反限制的订单会被拒绝，卖出只受当日委托笔数与成交额限制，条件单触发的卖出不受限制"), nil
+}
+
+// RiskDataSource 风控估值数据源，价格取实时行情，行业取申万一级行业
+// 启动时经 finsvc.SetRiskDataSource 注入，供风控校验补充订单未携带的持仓价格与行业
+type RiskDataSource struct{}
+
+// Prices 批量获取持仓估值价格
+func (RiskDataSource) Prices(stockCodes []string) (finsvc.PriceMap, error) {
+	return fetchAllPrices(stockCodes)
+}
+
+// Industry 获取股票所属申万一级行业，数据源未返回时为空
+func (RiskDataSource) Industry(stockCode string) (string, error) {
+	provider, err := marketdata.GetProvider()
+	if err != nil {
+		return "", err
+	}
+	resp, err := provider.GetIndustry(stockCode)
+	if err != nil {
+		return "", err
+	}
+	if level1, _ := resp.IndustryLevels(); level1 != nil {
+		return level1.Name, nil
+	}
+	return "", nil
+}
@@ -18,8 +18,9 @@ type SubmitBuyOrderParam struct {
 	StockCode string  `json:"stock_code" jsonschema:"description=股票代码（如 sh600000）"`
 	StockName string  `json:"stock_name" jsonschema:"description=股票名称"`
 	Quantity  int64   `json:"quantity" jsonschema:"description=买入数量（股）"`
-	Price     float64 `json:"price" jsonschema:"description=买入价格（元/股）"`
-	Fee       float64 `json:"fee" jsonschema:"description=手续费（元），默认5元"`
+	Price     float64 `json:"price" jsonschema:"description=买入限价（元/股），行情价不高于限价时成交"`
+	LotSize   int64   `json:"lot_size,omitempty" jsonschema:"description=每手股数，港股必填，A股固定100股"`
+	Account   string  `json:"account,omitempty" jsonschema:"description=账户名称（可选，默认使用当前账户）"`
 }
 
 // SubmitBuyOrderTool 提交买入订单工具
@@ -34,7 +35,7 @@ func (t *SubmitBuyOrderTool) GetName() string {
 }
 
 func (t *SubmitBuyOrderTool) GetDescription() string {
-	return "提交买入订单并自动成交| Submit buy order and auto-fill"
+	return "提交限价买入订单，手续费按费率自动计算，需符合交易规则（整手、涨跌幅、交易时段），实时行情触及限价时成交，否则挂单等待（可用 cancel_order 撤单）| Submit a limit buy order; fees are computed from the fee schedule; must satisfy trading rules (board lot, price limits, sessions); fills when the quote crosses the limit price, otherwise stays pending"
 }
 
 func (t *SubmitBuyOrderTool) GetToolGroup() model.ToolGroup {
@@ -43,13 +44,35 @@ func (t *SubmitBuyOrderTool) GetToolGroup() model.ToolGroup {
 
 // OrderData 订单数据
 type OrderData struct {
-	TransactionID int64   `json:"transaction_id"`
-	StockCode     string  `json:"stock_code"`
-	StockName     string  `json:"stock_name"`
-	Quantity      int64   `json:"quantity"`
-	Price         float64 `json:"price"`
-	Fee           float64 `json:"fee"`
-	TotalAmount   float64 `json:"total_amount"`
+	TransactionID int64     `json:"transaction_id"`
+	StockCode     string    `json:"stock_code"`
+	StockName     string    `json:"stock_name"`
+	Quantity      int64     `json:"quantity"`
+	Price         float64   `json:"price"`
+	Fee           float64   `json:"fee"`
+	FeeDetail     FeeDetail `json:"fee_detail"`
+	TotalAmount   float64   `json:"total_amount"`
+	Status        string    `json:"status"`
+	FilledQty     int64     `json:"filled_quantity,omitempty"`          // 部分成交时已成交数量，剩余部分继续挂单
+	RemainderID   int64     `json:"remainder_transaction_id,omitempty"` // 部分成交时剩余挂单的交易ID，撤单使用该ID
+}
+
+// FeeDetail 手续费明细（元）
+type FeeDetail struct {
+	Commission  string `json:"commission"`
+	StampDuty   string `json:"stamp_duty"`
+	TransferFee string `json:"transfer_fee"`
+	Levy        string `json:"levy"`
+}
+
+// toFeeDetail 交易记录手续费明细转换为返回数据
+func toFeeDetail(trans *model.Transaction) FeeDetail {
+	return FeeDetail{
+		Commission:  formatHaoToYuan(trans.Commission),
+		StampDuty:   formatHaoToYuan(trans.StampDuty),
+		TransferFee: formatHaoToYuan(trans.TransferFee),
+		Levy:        formatHaoToYuan(trans.Levy),
+	}
 }
 
 // SubmitBuyOrder 提交买入订单
@@ -67,15 +90,14 @@ func doSubmitBuyOrder(ctx context.Context, param *SubmitBuyOrderParam) (string,
 		return model.NewErrorResult(err.Error()), nil
 	}
 
-	account, err := getActiveAccount(database)
+	account, err := getActiveAccount(database, param.Account)
 	if err != nil {
 		return model.NewErrorResult(err.Error()), nil
 	}
 
-	// 默认手续费 5 元
-	fee := param.Fee
-	if fee == 0 {
-		fee = 5.0
+	lotSize, err := resolveLotSize(param.StockCode, param.LotSize)
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
 	}
 
 	// 构建订单
@@ -83,9 +105,10 @@ func doSubmitBuyOrder(ctx context.Context, param *SubmitBuyOrderParam) (string,
 		StockCode: param.StockCode,
 		StockName: param.StockName,
 		Quantity:  param.Quantity,
-		Price:     model.YuanToHao(param.Price),
-		Fee:       model.YuanToHao(fee),
+		Price:     finsvc.PriceToHao(param.StockCode, param.Price),
 		Note:      "",
+		PrevClose: fetchPrevClose(param.StockCode),
+		LotSize:   lotSize,
 	}
 
 	// 提交订单
@@ -94,7 +117,7 @@ func doSubmitBuyOrder(ctx context.Context, param *SubmitBuyOrderParam) (string,
 		return model.NewErrorResult(err.Error()), nil
 	}
 
-	// 检查是否被拒绝（余额不足）
+	// 检查是否被拒绝（不符合交易规则、违反风控限制或余额不足）
 	trans, err := db.GetTransactionByID(database, transID)
 	if err != nil {
 		return model.NewErrorResult(err.Error()), nil
@@ -105,9 +128,10 @@ func doSubmitBuyOrder(ctx context.Context, param *SubmitBuyOrderParam) (string,
 		return model.NewErrorResult(err.Error()), nil
 	}
 
-	// 自动成交
-	if err := finsvc.FillOrder(database, transID); err != nil {
-		return model.NewErrorResult(fmt.Sprintf("订单创建成功但成交失败: %v", err)), nil
+	// 按实时行情撮合
+	status, filledQty, err := matchSubmittedOrder(database, transID, param.StockCode)
+	if err != nil {
+		return model.NewErrorResult(fmt.Sprintf("订单创建成功但撮合失败（交易ID=%d）: %v", transID, err)), nil
 	}
 
 	data := &OrderData{
@@ -116,10 +140,25 @@ func doSubmitBuyOrder(ctx context.Context, param *SubmitBuyOrderParam) (string,
 		StockName:     param.StockName,
 		Quantity:      param.Quantity,
 		Price:         param.Price,
-		Fee:           fee,
-		TotalAmount:   float64(param.Quantity)*param.Price + fee,
+		Fee:           formatHaoToYuanFloat(trans.Fee),
+		FeeDetail:     toFeeDetail(trans),
+		TotalAmount:   formatHaoToYuanFloat(trans.GetTotalAmount()),
+		Status:        string(status),
 	}
 
+	if status != model.TransactionStatusFilled {
+		if filledQty > 0 {
+			remainderID, err := pendingRemainderID(database, transID)
+			if err != nil {
+				return model.NewErrorResult(fmt.Sprintf("订单部分成交但查询剩余挂单失败（交易ID=%d）: %v", transID, err)), nil
+			}
+			data.FilledQty = filledQty
+			data.RemainderID = int64(remainderID)
+			return model.NewSuccessResult(data, fmt.Sprintf("买入订单部分成交 %d 股，剩余 %d 股继续挂单（交易ID=%d，可用 cancel_order 撤单）",
+				filledQty, param.Quantity-filledQty, remainderID)), nil
+		}
+		return model.NewSuccessResult(data, "买入挂单已提交，行情未触及限价，资金已锁定"), nil
+	}
 	return model.NewSuccessResult(data, "买入订单已成交"), nil
 }
 
@@ -128,8 +167,9 @@ type SubmitSellOrderParam struct {
 	StockCode string  `json:"stock_code" jsonschema:"description=股票代码（如 sh600000）"`
 	StockName string  `json:"stock_name" jsonschema:"description=股票名称"`
 	Quantity  int64   `json:"quantity" jsonschema:"description=卖出数量（股）"`
-	Price     float64 `json:"price" jsonschema:"description=卖出价格（元/股）"`
-	Fee       float64 `json:"fee" jsonschema:"description=手续费（元），默认5元"`
+	Price     float64 `json:"price" jsonschema:"description=卖出限价（元/股），行情价不低于限价时成交"`
+	LotSize   int64   `json:"lot_size,omitempty" jsonschema:"description=每手股数，港股必填，A股固定100股"`
+	Account   string  `json:"account,omitempty" jsonschema:"description=账户名称（可选，默认使用当前账户）"`
 }
 
 // SubmitSellOrderTool 提交卖出订单工具
@@ -144,7 +184,7 @@ func (t *SubmitSellOrderTool) GetName() string {
 }
 
 func (t *SubmitSellOrderTool) GetDescription() string {
-	return "提交卖出订单并自动成交| Submit sell order and auto-fill"
+	return "提交限价卖出订单，手续费（含印花税）按费率自动计算，需符合交易规则（整手/零股一次性卖出、A股T+1、涨跌幅、交易时段），实时行情触及限价时成交，否则挂单等待（可用 cancel_order 撤单）| Submit a limit sell order; fees (incl. stamp duty) are computed from the fee schedule; must satisfy trading rules (board lot, T+1 for A-shares, price limits, sessions); fills when the quote crosses the limit price, otherwise stays pending"
 }
 
 func (t *SubmitSellOrderTool) GetToolGroup() model.ToolGroup {
@@ -166,41 +206,25 @@ func doSubmitSellOrder(ctx context.Context, param *SubmitSellOrderParam) (string
 		return model.NewErrorResult(err.Error()), nil
 	}
 
-	account, err := getActiveAccount(database)
+	account, err := getActiveAccount(database, param.Account)
 	if err != nil {
 		return model.NewErrorResult(err.Error()), nil
 	}
 
-	// 验证持仓充足
-	position, err := finsvc.GetPosition(database, account.ID, param.StockCode)
+	lotSize, err := resolveLotSize(param.StockCode, param.LotSize)
 	if err != nil {
 		return model.NewErrorResult(err.Error()), nil
 	}
 
-	if position == 0 {
-		err := fmt.Errorf("无持仓：请先查询持仓")
-		return model.NewErrorResult(err.Error()), nil
-	}
-
-	if position < param.Quantity {
-		err := fmt.Errorf("持仓不足：当前持仓 %d 股，要卖出 %d 股", position, param.Quantity)
-		return model.NewErrorResult(err.Error()), nil
-	}
-
-	// 默认手续费 5 元
-	fee := param.Fee
-	if fee == 0 {
-		fee = 5.0
-	}
-
 	// 构建订单
 	order := finsvc.Order{
 		StockCode: param.StockCode,
 		StockName: param.StockName,
 		Quantity:  param.Quantity,
-		Price:     model.YuanToHao(param.Price),
-		Fee:       model.YuanToHao(fee),
+		Price:     finsvc.PriceToHao(param.StockCode, param.Price),
 		Note:      "",
+		PrevClose: fetchPrevClose(param.StockCode),
+		LotSize:   lotSize,
 	}
 
 	// 提交订单
@@ -209,9 +233,21 @@ func doSubmitSellOrder(ctx context.Context, param *SubmitSellOrderParam) (string
 		return model.NewErrorResult(err.Error()), nil
 	}
 
-	// 自动成交
-	if err := finsvc.FillOrder(database, transID); err != nil {
-		return model.NewErrorResult(fmt.Sprintf("订单创建成功但成交失败: %v", err)), nil
+	// 检查是否被拒绝（持仓不足或不符合交易规则）
+	trans, err := db.GetTransactionByID(database, transID)
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	if trans.Status == model.TransactionStatusRejected {
+		err := fmt.Errorf("订单被拒绝：%s", trans.Note)
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	// 按实时行情撮合
+	status, filledQty, err := matchSubmittedOrder(database, transID, param.StockCode)
+	if err != nil {
+		return model.NewErrorResult(fmt.Sprintf("订单创建成功但撮合失败（交易ID=%d）: %v", transID, err)), nil
 	}
 
 	data := &OrderData{
@@ -220,10 +256,25 @@ func doSubmitSellOrder(ctx context.Context, param *SubmitSellOrderParam) (string
 		StockName:     param.StockName,
 		Quantity:      param.Quantity,
 		Price:         param.Price,
-		Fee:           fee,
-		TotalAmount:   float64(param.Quantity)*param.Price - fee,
+		Fee:           formatHaoToYuanFloat(trans.Fee),
+		FeeDetail:     toFeeDetail(trans),
+		TotalAmount:   formatHaoToYuanFloat(trans.Amount - trans.Fee),
+		Status:        string(status),
 	}
 
+	if status != model.TransactionStatusFilled {
+		if filledQty > 0 {
+			remainderID, err := pendingRemainderID(database, transID)
+			if err != nil {
+				return model.NewErrorResult(fmt.Sprintf("订单部分成交但查询剩余挂单失败（交易ID=%d）: %v", transID, err)), nil
+			}
+			data.FilledQty = filledQty
+			data.RemainderID = int64(remainderID)
+			return model.NewSuccessResult(data, fmt.Sprintf("卖出订单部分成交 %d 股，剩余 %d 股继续挂单（交易ID=%d，可用 cancel_order 撤单）",
+				filledQty, param.Quantity-filledQty, remainderID)), nil
+		}
+		return model.NewSuccessResult(data, "卖出挂单已提交，行情未触及限价"), nil
+	}
 	return model.NewSuccessResult(data, "卖出订单已成交"), nil
 }
 
@@ -235,6 +286,7 @@ type GetTransactionsParam struct {
 	Limit     *int    `json:"limit,omitempty" jsonschema:"description=返回数量限制（可选，默认50）"`
 	DateFrom  *string `json:"date_from,omitempty" jsonschema:"description=起始日期 YYYY-MM-DD（可选）"`
 	DateTo    *string `json:"date_to,omitempty" jsonschema:"description=截止日期 YYYY-MM-DD（可选）"`
+	Account   string  `json:"account,omitempty" jsonschema:"description=账户名称（可选，默认使用当前账户）"`
 }
 
 // GetTransactionsTool 查询交易记录工具
@@ -249,7 +301,7 @@ func (t *GetTransactionsTool) GetName() string {
 }
 
 func (t *GetTransactionsTool) GetDescription() string {
-	return "查询交易记录（可按股票、类型、状态筛选）| Get transaction records (can filter by stock, type, status)"
+	return "查询交易记录（可按股票、类型、状态筛选），部分成交的订单以树形展示：原记录 OBSOLETE，children 为成交与剩余挂单 | Get transaction records (can filter by stock, type, status); partially filled orders are shown as a tree"
 }
 
 func (t *GetTransactionsTool) GetToolGroup() model.ToolGroup {
@@ -264,16 +316,19 @@ type TransactionData struct {
 
 // TransactionItem 交易记录项
 type TransactionItem struct {
-	ID        int64  `json:"id"`
-	StockCode string `json:"stock_code"`
-	StockName string `json:"stock_name"`
-	Type      string `json:"type"`
-	Quantity  int64  `json:"quantity"`
-	Price     string `json:"price"`
-	Amount    string `json:"amount"`
-	Fee       string `json:"fee"`
-	Status    string `json:"status"`
-	CreatedAt string `json:"created_at"`
+	ID        int64             `json:"id"`
+	StockCode string            `json:"stock_code"`
+	StockName string            `json:"stock_name"`
+	Type      string            `json:"type"`
+	Quantity  int64             `json:"quantity"`
+	Price     string            `json:"price"`
+	Amount    string            `json:"amount"`
+	Fee       string            `json:"fee"`
+	FeeDetail FeeDetail         `json:"fee_detail"`
+	Status    string            `json:"status"`
+	CreatedAt string            `json:"created_at"`
+	ParentID  *int64            `json:"parent_id,omitempty"` // 部分成交时关联的原记录ID
+	Children  []TransactionItem `json:"children,omitempty"`
 }
 
 // GetTransactions 查询交易记录
@@ -290,11 +345,14 @@ func doGetTransactions(ctx context.Context, param *GetTransactionsParam) (string
 		return model.NewErrorResult(err.Error()), nil
 	}
 
-	account, err := getActiveAccount(database)
+	account, err := getActiveAccount(database, param.Account)
 	if err != nil {
 		return model.NewErrorResult(err.Error()), nil
 	}
 
+	// 先撮合挂单，保证返回的订单状态是最新的
+	SyncPendingOrders(database, account.ID)
+
 	// 构建查询
 	query := database.Model(&model.Transaction{}).Where("account_id = ?", account.ID)
 
@@ -326,21 +384,11 @@ func doGetTransactions(ctx context.Context, param *GetTransactionsParam) (string
 		return model.NewErrorResult(err.Error()), nil
 	}
 
-	// 构建返回数据
-	items := make([]TransactionItem, 0, len(transactions))
-	for _, trans := range transactions {
-		items = append(items, TransactionItem{
-			ID:        int64(trans.ID),
-			StockCode: trans.StockCode,
-			StockName: trans.StockName,
-			Type:      string(trans.Type),
-			Quantity:  trans.Quantity,
-			Price:     formatHaoToYuan(trans.Price),
-			Amount:    formatHaoToYuan(trans.Amount),
-			Fee:       formatHaoToYuan(trans.Fee),
-			Status:    string(trans.Status),
-			CreatedAt: trans.CreatedAt.Format("2006-01-02 15:04:05"),
-		})
+	// 构建返回数据，部分成交的子记录挂在原记录下
+	roots := finsvc.BuildTransactionTree(transactions)
+	items := make([]TransactionItem, 0, len(roots))
+	for _, node := range roots {
+		items = append(items, toTransactionItem(node))
 	}
 
 	data := &TransactionData{
@@ -350,3 +398,104 @@ func doGetTransactions(ctx context.Context, param *GetTransactionsParam) (string
 
 	return model.NewSuccessResult(data, fmt.Sprintf("获取 %d 条交易记录", len(transactions))), nil
 }
+
+// toTransactionItem 交易记录树节点转换为返回项
+func toTransactionItem(node *finsvc.TransactionNode) TransactionItem {
+	item := TransactionItem{
+		ID:        int64(node.ID),
+		StockCode: node.StockCode,
+		StockName: node.StockName,
+		Type:      string(node.Type),
+		Quantity:  node.Quantity,
+		Price:     formatHaoToYuan(node.Price),
+		Amount:    formatHaoToYuan(node.Amount),
+		Fee:       formatHaoToYuan(node.Fee),
+		FeeDetail: toFeeDetail(node.Transaction),
+		Status:    string(node.Status),
+		CreatedAt: node.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+	if node.ParentID != nil {
+		parentID := int64(*node.ParentID)
+		item.ParentID = &parentID
+	}
+	for _, child := range node.Children {
+		item.Children = append(item.Children, toTransactionItem(child))
+	}
+	return item
+}
+
+// CancelOrderParam 撤销订单参数
+type CancelOrderParam struct {
+	TransactionID int64  `json:"transaction_id" jsonschema:"description=要撤销的挂单交易ID"`
+	Account       string `json:"account,omitempty" jsonschema:"description=账户名称（可选，默认使用当前账户）"`
+}
+
+// CancelOrderTool 撤销挂单工具
+type CancelOrderTool struct{}
+
+func (t *CancelOrderTool) GetToolInfo() (tool.BaseTool, error) {
+	return utils.InferTool(t.GetName(), t.GetDescription(), CancelOrder)
+}
+
+func (t *CancelOrderTool) GetName() string {
+	return "cancel_order"
+}
+
+func (t *CancelOrderTool) GetDescription() string {
+	return "撤销挂单中的订单，买单锁定资金返还到可用余额 | Cancel a pending order and release its locked funds"
+}
+
+func (t *CancelOrderTool) GetToolGroup() model.ToolGroup {
+	return model.FinanceToolGroup
+}
+
+// CancelOrder 撤销挂单
+func CancelOrder(ctx context.Context, param *CancelOrderParam) (string, error) {
+	return safetool.SafeExecute("cancel_order", fmt.Sprintf("transaction_id: %d", param.TransactionID), func() (string, error) {
+		return doCancelOrder(ctx, param)
+	})
+}
+
+func doCancelOrder(ctx context.Context, param *CancelOrderParam) (string, error) {
+	database := msadb.GetDB()
+	if database == nil {
+		err := fmt.Errorf("数据库未初始化")
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	account, err := getActiveAccount(database, param.Account)
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	if param.TransactionID <= 0 {
+		return model.NewErrorResult("transaction_id is required"), nil
+	}
+
+	trans, err := db.GetTransactionByID(database, uint(param.TransactionID))
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+	if trans.AccountID != account.ID {
+		err := fmt.Errorf("交易 %d 不属于当前账户", param.TransactionID)
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	if err := finsvc.CancelOrder(database, trans.ID, "用户撤单"); err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	data := &OrderData{
+		TransactionID: int64(trans.ID),
+		StockCode:     trans.StockCode,
+		StockName:     trans.StockName,
+		Quantity:      trans.Quantity,
+		Price:         formatHaoToYuanFloat(trans.Price),
+		Fee:           formatHaoToYuanFloat(trans.Fee),
+		FeeDetail:     toFeeDetail(trans),
+		TotalAmount:   formatHaoToYuanFloat(trans.GetTotalAmount()),
+		Status:        string(model.TransactionStatusCancelled),
+	}
+
+	return model.NewSuccessResult(data, "订单已撤销"), nil
+}
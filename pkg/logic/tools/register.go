@@ -7,6 +7,7 @@ import (
 	skilltools "msa/pkg/logic/tools/skill"
 	"msa/pkg/logic/tools/stock"
 	"msa/pkg/logic/tools/todo"
+	"msa/pkg/logic/tools/watch"
 )
 
 var _ MsaTool = (*skilltools.SkillContentTool)(nil)
@@ -20,6 +21,13 @@ var _ MsaTool = (*stock.HistoryK)(nil)
 var _ MsaTool = (*stock.Industry)(nil)
 var _ MsaTool = (*stock.BoardRank)(nil)
 var _ MsaTool = (*stock.MinuteK)(nil)
+var _ MsaTool = (*stock.TechnicalIndicators)(nil)
+var _ MsaTool = (*stock.MarketRegime)(nil)
+var _ MsaTool = (*stock.ScreenStocks)(nil)
+var _ MsaTool = (*stock.TradingCalendar)(nil)
+var _ MsaTool = (*watch.AddToWatchlistTool)(nil)
+var _ MsaTool = (*watch.GetWatchlistTool)(nil)
+var _ MsaTool = (*watch.RemoveFromWatchlistTool)(nil)
 var _ MsaTool = (*search.SearchTool)(nil)
 var _ MsaTool = (*search.FetcherTool)(nil)
 
@@ -31,6 +39,17 @@ var _ MsaTool = (*finance.GetAccountSummaryTool)(nil)
 var _ MsaTool = (*finance.SubmitBuyOrderTool)(nil)
 var _ MsaTool = (*finance.SubmitSellOrderTool)(nil)
 var _ MsaTool = (*finance.GetTransactionsTool)(nil)
+var _ MsaTool = (*finance.CancelOrderTool)(nil)
+var _ MsaTool = (*finance.RecordCashFlowTool)(nil)
+var _ MsaTool = (*finance.ApplyCorporateActionTool)(nil)
+var _ MsaTool = (*finance.GetCashFlowsTool)(nil)
+var _ MsaTool = (*finance.GetEquityCurveTool)(nil)
+var _ MsaTool = (*finance.GetPerformanceAttributionTool)(nil)
+var _ MsaTool = (*finance.GetPortfolioRiskTool)(nil)
+var _ MsaTool = (*finance.GetRiskPolicyTool)(nil)
+var _ MsaTool = (*finance.CreateConditionalOrderTool)(nil)
+var _ MsaTool = (*finance.ListConditionalOrdersTool)(nil)
+var _ MsaTool = (*finance.CancelConditionalOrderTool)(nil)
 
 var _ MsaTool = (*todo.CheckTodoTool)(nil)
 var _ MsaTool = (*todo.CreateTodoTool)(nil)
@@ -43,6 +62,7 @@ var _ MsaTool = (*knowledge.WriteKnowledgeTool)(nil)
 
 func init() {
 	registerStock()
+	registerWatch()
 	registerSearch()
 	registerFinance()
 	registerSkill()
@@ -58,6 +78,16 @@ func registerStock() {
 	RegisterTool(&stock.Industry{})
 	RegisterTool(&stock.BoardRank{})
 	RegisterTool(&stock.MinuteK{})
+	RegisterTool(&stock.TechnicalIndicators{})
+	RegisterTool(&stock.MarketRegime{})
+	RegisterTool(&stock.ScreenStocks{})
+	RegisterTool(&stock.TradingCalendar{})
+}
+
+func registerWatch() {
+	RegisterTool(&watch.AddToWatchlistTool{})
+	RegisterTool(&watch.GetWatchlistTool{})
+	RegisterTool(&watch.RemoveFromWatchlistTool{})
 }
 
 func registerSearch() {
@@ -74,6 +104,17 @@ func registerFinance() {
 	RegisterTool(&finance.SubmitBuyOrderTool{})
 	RegisterTool(&finance.SubmitSellOrderTool{})
 	RegisterTool(&finance.GetTransactionsTool{})
+	RegisterTool(&finance.CancelOrderTool{})
+	RegisterTool(&finance.RecordCashFlowTool{})
+	RegisterTool(&finance.ApplyCorporateActionTool{})
+	RegisterTool(&finance.GetCashFlowsTool{})
+	RegisterTool(&finance.GetEquityCurveTool{})
+	RegisterTool(&finance.GetPerformanceAttributionTool{})
+	RegisterTool(&finance.GetPortfolioRiskTool{})
+	RegisterTool(&finance.GetRiskPolicyTool{})
+	RegisterTool(&finance.CreateConditionalOrderTool{})
+	RegisterTool(&finance.ListConditionalOrdersTool{})
+	RegisterTool(&finance.CancelConditionalOrderTool{})
 }
 
 func registerSkill() {
@@ -2,15 +2,14 @@ package stock
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/components/tool/utils"
 	log "github.com/sirupsen/logrus"
+	"msa/pkg/logic/marketdata"
 	"msa/pkg/logic/tools/safetool"
 	"msa/pkg/model"
-	mas_utils "msa/pkg/utils"
 )
 
 type BoardRankParam struct {
@@ -50,22 +49,16 @@ func doGetBoardRank(ctx context.Context, param *BoardRankParam) (string, error)
 		param.Count = 20
 	}
 
-	apiURL := fmt.Sprintf("%s?l=%d&p=1&t=%s/averatio&ordertype=&o=%d",
-		model.FinanceBoardRank, param.Count, param.BoardType, param.Order)
-
-	resp, err := mas_utils.GetRestyClient().R().Get(apiURL)
+	provider, err := marketdata.GetProvider()
 	if err != nil {
-		return model.NewErrorResult(fmt.Sprintf("HTTP request failed: %v", err)), nil
+		return model.NewErrorResult(err.Error()), nil
 	}
 
-	var brResp model.BoardRankResp
-	if err := json.Unmarshal(resp.Body(), &brResp); err != nil {
-		return model.NewErrorResult(fmt.Sprintf("JSON解析失败: %v", err)), nil
-	}
-	if brResp.Code != 0 {
-		return model.NewErrorResult(fmt.Sprintf("API error: code=%d, msg=%s", brResp.Code, brResp.Msg)), nil
+	items, err := provider.GetBoardRank(param.BoardType, param.Order, param.Count)
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
 	}
 
-	log.Infof("get_board_rank: type=%s order=%d count=%d", param.BoardType, param.Order, len(brResp.Data))
-	return model.NewSuccessResult(brResp.Data, fmt.Sprintf("获取板块排行成功, %d条", len(brResp.Data))), nil
+	log.Infof("get_board_rank: type=%s order=%d count=%d", param.BoardType, param.Order, len(items))
+	return model.NewSuccessResult(items, fmt.Sprintf("获取板块排行成功, %d条", len(items))), nil
 }
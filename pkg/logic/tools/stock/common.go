@@ -1,79 +1,94 @@
 package stock
 
 import (
-	"encoding/json"
 	"fmt"
+	"strings"
+
+	"msa/pkg/logic/marketdata"
 	"msa/pkg/model"
-	mas_utils "msa/pkg/utils"
 
 	log "github.com/sirupsen/logrus"
 )
 
-// FetchStockData 获取股票数据的公共逻辑
-// 返回StockCurrentResp和error
+// FetchStockData 获取股票实时行情的公共逻辑
+// 通过当前配置的行情数据源获取，返回StockCurrentResp和error
 func FetchStockData(stockCode string) (*model.StockCurrentResp, error) {
 	if stockCode == "" {
 		return nil, fmt.Errorf("stock code is empty")
 	}
 
-	// 发起HTTP请求
-	getResp, err := mas_utils.GetRestyClient().R().Get(model.FinanceSearchCurrentKLine + stockCode)
+	provider, err := marketdata.GetProvider()
 	if err != nil {
 		return nil, err
 	}
-	log.Infof("API Response: %v", getResp.String())
 
-	// 解析响应
-	var rawResp map[string]interface{}
-	if err := json.Unmarshal(getResp.Body(), &rawResp); err != nil {
-		return nil, fmt.Errorf("JSON解析失败: %w", err)
+	resp, err := provider.GetQuote(stockCode)
+	if err != nil {
+		return nil, err
 	}
+	log.Infof("行情数据源: %s, 股票代码: %s", provider.GetSource(), stockCode)
+	return resp, nil
+}
 
-	// 检查返回码
-	if code, ok := rawResp["code"].(float64); ok && int(code) != 0 {
-		return nil, fmt.Errorf("API返回错误, code=%d, msg=%v", int(code), rawResp["msg"])
+// FetchStockQuotes 批量获取行情快照，代码按 NormalizeQuoteCode 统一后请求，同一股票只请求一次
+// 返回 map[stockCode]quote，键为调用方传入的代码（SH600000、600000 与 sh600000 都能查到），获取失败的股票不在结果中
+func FetchStockQuotes(stockCodes []string) (map[string]*model.StockQuote, error) {
+	aliases := make(map[string][]string, len(stockCodes))
+	codes := make([]string, 0, len(stockCodes))
+	for _, code := range stockCodes {
+		normalized := NormalizeQuoteCode(code)
+		if normalized == "" {
+			continue
+		}
+		if _, ok := aliases[normalized]; !ok {
+			codes = append(codes, normalized)
+		}
+		aliases[normalized] = append(aliases[normalized], code)
 	}
-
-	// 检查data字段
-	dataRaw, ok := rawResp["data"]
-	if !ok || dataRaw == nil {
-		return nil, fmt.Errorf("请求失败，没有返回数据 data")
+	if len(codes) == 0 {
+		return nil, fmt.Errorf("stock code is empty")
 	}
 
-	// 将data转换为map
-	dataMap, ok := dataRaw.(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("data字段格式错误,期望map[string]interface{}, 实际%T", dataRaw)
+	provider, err := marketdata.GetProvider()
+	if err != nil {
+		return nil, err
 	}
 
-	// 创建StockKLineDataWrapper
-	wrapper := &model.StockKLineDataWrapper{
-		StockData: make(map[string]*model.StockKLineDetail),
+	fetched, err := provider.GetQuotes(codes)
+	if err != nil {
+		return nil, err
 	}
-
-	// 解析每个股票的数据
-	for code, stockData := range dataMap {
-		stockDataBytes, err := json.Marshal(stockData)
-		if err != nil {
-			return nil, fmt.Errorf("序列化股票数据失败: %w", err)
+	quotes := make(map[string]*model.StockQuote, len(stockCodes))
+	for code, quote := range fetched {
+		for _, alias := range aliases[NormalizeQuoteCode(code)] {
+			quotes[alias] = quote
 		}
-
-		var detail model.StockKLineDetail
-		if err := json.Unmarshal(stockDataBytes, &detail); err != nil {
-			return nil, fmt.Errorf("解析股票详细数据失败: %w", err)
-		}
-
-		wrapper.StockData[code] = &detail
-		log.Infof("成功解析股票代码: %s", code)
 	}
+	log.Infof("行情数据源: %s, 批量行情 %d/%d 只", provider.GetSource(), len(fetched), len(codes))
+	return quotes, nil
+}
 
-	// 获取股票代码并转换为StockCurrentResp
-	actualStockCode := wrapper.GetStockCode()
-	log.Infof("股票代码: %s", actualStockCode)
-
-	stockCurrentResp, err := wrapper.GetStockCurrentResp(actualStockCode)
-	if err != nil {
-		return nil, fmt.Errorf("获取股票行情数据失败: %w", err)
+// NormalizeQuoteCode 统一行情代码写法：去空白、小写，缺少市场前缀的 A 股 6 位代码与港股 5 位代码补全前缀
+// 6/9 开头为上交所（sh），0/2/3 开头为深交所（sz），4/8 开头为北交所（bj）
+func NormalizeQuoteCode(stockCode string) string {
+	code := strings.ToLower(strings.TrimSpace(stockCode))
+	if strings.IndexFunc(code, func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+		return code
+	}
+	switch {
+	case len(code) == 5:
+		return "hk" + code
+	case len(code) != 6:
+		return code
+	}
+	switch code[0] {
+	case '6', '9':
+		return "sh" + code
+	case '0', '2', '3':
+		return "sz" + code
+	case '4', '8':
+		return "bj" + code
+	default:
+		return code
 	}
-	return stockCurrentResp, nil
 }
@@ -1,7 +1,19 @@
 package stock
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"msa/pkg/logic/marketdata"
+	"msa/pkg/logic/marketdata/local"
+	"msa/pkg/logic/regime"
+	"msa/pkg/logic/screener"
 )
 
 // TestFetchStockData_EmptyCode tests fetchStockData with empty stock code
@@ -49,3 +61,281 @@ func TestCompanyCode_Struct(t *testing.T) {
 	// This test verifies the struct compiles correctly
 	// Actual testing requires HTTP mocking as mentioned above
 }
+
+// TestFetchStockData_LocalProvider tests FetchStockData against recorded fixtures
+func TestFetchStockData_LocalProvider(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "quote"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	quote := `{"date": "20250303", "current_price": "10.50", "data": ["0930 10.10 100 101000.00"]}`
+	if err := os.WriteFile(filepath.Join(dir, "quote", "sh600519.json"), []byte(quote), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	marketdata.SetProvider(&local.LocalProvider{Dir: dir})
+	defer marketdata.SetProvider(nil)
+
+	result, err := FetchStockData("sh600519")
+	if err != nil {
+		t.Fatalf("FetchStockData() error = %v", err)
+	}
+	if result.CurrentPrice != "10.50" {
+		t.Errorf("CurrentPrice = %v, want 10.50", result.CurrentPrice)
+	}
+
+	minute, err := FetchStockMinuteData("sh600519")
+	if err != nil {
+		t.Fatalf("FetchStockMinuteData() error = %v", err)
+	}
+	if minute.Count != 1 || minute.Bars[0].Price != 10.10 {
+		t.Errorf("Unexpected minute data: %+v", minute)
+	}
+}
+
+// TestFetchStockQuotes_NormalizedCodes tests that quotes are keyed by the caller's code spelling
+func TestFetchStockQuotes_NormalizedCodes(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "quote"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	quote := `{"current_price": "10.50", "prev_close": "10.00"}`
+	if err := os.WriteFile(filepath.Join(dir, "quote", "sh600000.json"), []byte(quote), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	marketdata.SetProvider(&local.LocalProvider{Dir: dir})
+	defer marketdata.SetProvider(nil)
+
+	codes := []string{"SH600000", "600000", " sh600000 "}
+	quotes, err := FetchStockQuotes(codes)
+	if err != nil {
+		t.Fatalf("FetchStockQuotes() error = %v", err)
+	}
+	for _, code := range codes {
+		if q, ok := quotes[code]; !ok || q.CurrentPrice != "10.50" {
+			t.Errorf("quote for %q missing: %+v", code, q)
+		}
+	}
+
+	for code, want := range map[string]string{
+		"000001": "sz000001", "300750": "sz300750", "688981": "sh688981", "830799": "bj830799",
+		"00700": "hk00700", "HK00700": "hk00700", "sz000001": "sz000001",
+	} {
+		if got := NormalizeQuoteCode(code); got != want {
+			t.Errorf("NormalizeQuoteCode(%q) = %q, want %q", code, got, want)
+		}
+	}
+}
+
+// TestGetTechnicalIndicators_LocalProvider tests indicator output over recorded daily K-lines
+func TestGetTechnicalIndicators_LocalProvider(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "kline"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// 70 根连续上涨的日K线
+	csv := "date,open,close,high,low,volume\n"
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 70; i++ {
+		price := 10 + float64(i)*0.1
+		csv += fmt.Sprintf("%s,%.2f,%.2f,%.2f,%.2f,1000\n",
+			start.AddDate(0, 0, i).Format("2006-01-02"), price-0.05, price, price+0.1, price-0.1)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "kline", "sh600519_day_qfq.csv"), []byte(csv), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	marketdata.SetProvider(&local.LocalProvider{Dir: dir})
+	defer marketdata.SetProvider(nil)
+
+	output, err := GetTechnicalIndicators(context.Background(), &TechnicalIndicatorsParam{StockCode: "sh600519"})
+	if err != nil {
+		t.Fatalf("GetTechnicalIndicators() error = %v", err)
+	}
+	var result struct {
+		Success bool                    `json:"success"`
+		Data    TechnicalIndicatorsData `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Unmarshal failed: %v\n%s", err, output)
+	}
+	data := result.Data
+	if !result.Success || data.Result == nil || data.Bars != 70 || data.Date != "2025-03-11" {
+		t.Fatalf("Unexpected result: %s", output)
+	}
+	// 最新收盘 16.9，MA5 = 16.7
+	if data.MA["ma5"] != 16.7 || data.MA["ma60"] == 0 || data.MACD == nil || data.MACD.DIF <= 0 {
+		t.Errorf("Unexpected indicators: %s", output)
+	}
+	if data.RSI["rsi6"] != 100 || data.ATR == nil || *data.ATR != 0.2 {
+		t.Errorf("Unexpected RSI/ATR: %s", output)
+	}
+
+	// 自定义周期
+	output, _ = GetTechnicalIndicators(context.Background(), &TechnicalIndicatorsParam{StockCode: "sh600519", MAPeriods: []int{3}})
+	var custom TechnicalIndicatorsData
+	if err := json.Unmarshal([]byte(output), &struct {
+		Data *TechnicalIndicatorsData `json:"data"`
+	}{&custom}); err != nil {
+		t.Fatal(err)
+	}
+	if len(custom.MA) != 1 || custom.MA["ma3"] != 16.8 {
+		t.Errorf("Unexpected custom MA: %+v", custom.MA)
+	}
+}
+
+// TestGetMarketRegime_LocalProvider tests regime classification over recorded index K-lines and board rank
+func TestGetMarketRegime_LocalProvider(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"kline", "board_rank"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 300 根每日上涨 0.5% 的指数日K线；深证成指缺失
+	csv := "date,open,close,high,low,volume\n"
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	price := 3000.0
+	for i := 0; i < 300; i++ {
+		price *= 1.005
+		csv += fmt.Sprintf("%s,%.2f,%.2f,%.2f,%.2f,1000\n",
+			start.AddDate(0, 0, i).Format("2006-01-02"), price, price, price*1.01, price*0.99)
+	}
+	for _, code := range []string{"sh000001", "sz399006"} {
+		if err := os.WriteFile(filepath.Join(dir, "kline", code+"_day_qfq.csv"), []byte(csv), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rank := `[{"bd_name":"银行","bd_zdf":"1.20","bd_zdf5":"3.1"},{"bd_name":"煤炭","bd_zdf":"0.80","bd_zdf5":"2.0"},{"bd_name":"钢铁","bd_zdf":"-0.30","bd_zdf5":"1.5"}]`
+	if err := os.WriteFile(filepath.Join(dir, "board_rank", "01.json"), []byte(rank), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	marketdata.SetProvider(&local.LocalProvider{Dir: dir})
+	defer marketdata.SetProvider(nil)
+
+	output, err := GetMarketRegime(context.Background(), &MarketRegimeParam{})
+	if err != nil {
+		t.Fatalf("GetMarketRegime() error = %v", err)
+	}
+	var result struct {
+		Success bool          `json:"success"`
+		Data    regime.Result `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Unmarshal failed: %v\n%s", err, output)
+	}
+	data := result.Data
+	if !result.Success || data.Regime != regime.RegimeBull || data.Trend.Label != regime.TrendUp || data.Breadth.Label != regime.BreadthPositive {
+		t.Fatalf("Unexpected result: %s", output)
+	}
+	if len(data.Indices) != 2 || data.BreadthDetail == nil || data.BreadthDetail.Advancers != 2 {
+		t.Errorf("Unexpected evidence: %s", output)
+	}
+	// 获取失败的指数在 notes 中说明，置信度降为 MEDIUM
+	if data.Confidence != regime.ConfidenceMedium || len(data.Notes) == 0 || !strings.Contains(data.Notes[0], "深证成指") {
+		t.Errorf("Unexpected notes/confidence: %s", output)
+	}
+
+	// 指数K线全部缺失时返回错误
+	output, _ = GetMarketRegime(context.Background(), &MarketRegimeParam{IndexCodes: []string{"sz399001"}})
+	if !strings.Contains(output, `"success": false`) {
+		t.Errorf("Expected failure without index data: %s", output)
+	}
+}
+
+// TestScreenStocks_LocalProvider tests screening board constituents over recorded quotes
+func TestScreenStocks_LocalProvider(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"board_rank/01.json":           `[{"bd_name":"银行","bd_code":"pt01801780","bd_zdf":"0.5"}]`,
+		"board_stocks/pt01801780.json": `[{"code":"sh601398","name":"工商银行"},{"code":"sh600036","name":"招商银行"},{"code":"sh601166","name":"兴业银行"}]`,
+		"quote/sh601398.json":          `{"current_price":"7.00","prev_close":"6.93","pe_ratio":"6.2"}`,
+		"quote/sh600036.json":          `{"current_price":"42.00","prev_close":"42.34","pe_ratio":"7.1"}`,
+		"quote/sh601166.json":          `{"current_price":"20.00","prev_close":"19.80","pe_ratio":"5.0"}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	marketdata.SetProvider(&local.LocalProvider{Dir: dir})
+	defer marketdata.SetProvider(nil)
+
+	output, err := ScreenStocksTool(context.Background(), &ScreenStocksParam{
+		Expression: `change_pct > 0 AND board = "银行"`, SortBy: "pe", Order: "asc",
+	})
+	if err != nil {
+		t.Fatalf("ScreenStocksTool() error = %v", err)
+	}
+	var result struct {
+		Success bool            `json:"success"`
+		Data    screener.Result `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Unmarshal failed: %v\n%s", err, output)
+	}
+	data := result.Data
+	if !result.Success || data.Universe != 3 || data.Matched != 2 || len(data.Items) != 2 {
+		t.Fatalf("Unexpected result: %s", output)
+	}
+	if data.Items[0].Code != "sh601166" || data.Items[0].Name != "兴业银行" || data.Items[1].Code != "sh601398" {
+		t.Errorf("Unexpected ranking: %s", output)
+	}
+
+	output, _ = ScreenStocksTool(context.Background(), &ScreenStocksParam{Expression: "pe <"})
+	if !strings.Contains(output, `"success": false`) || !strings.Contains(output, "invalid expression") {
+		t.Errorf("Expected expression error: %s", output)
+	}
+}
+
+func TestGetTradingCalendar(t *testing.T) {
+	cst := time.FixedZone("CST", 8*3600)
+	// 周日查询：当天休市，最近交易日为周五
+	now := time.Date(2025, 9, 28, 10, 0, 0, 0, cst)
+
+	output, _ := doGetTradingCalendar(&TradingCalendarParam{}, now)
+	var result struct {
+		Success bool                `json:"success"`
+		Data    TradingCalendarData `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Unmarshal failed: %v\n%s", err, output)
+	}
+	data := result.Data
+	if !result.Success || data.IsTradingDay || data.Weekday != "星期日" || data.SessionPhase != "closed" {
+		t.Fatalf("Unexpected result: %s", output)
+	}
+	if data.LatestTradingDay != "2025-09-26" || data.NextTradingDay != "2025-09-29" {
+		t.Errorf("Unexpected trading days: %s", output)
+	}
+	// 后续交易日跳过国庆长假
+	if strings.Join(data.UpcomingTradingDays, ",") != "2025-09-29,2025-09-30,2025-10-09,2025-10-10,2025-10-13" {
+		t.Errorf("Unexpected upcoming days: %v", data.UpcomingTradingDays)
+	}
+
+	output, _ = doGetTradingCalendar(&TradingCalendarParam{Market: "hkex", Date: "2025-12-24", Days: 1}, now)
+	data = TradingCalendarData{}
+	json.Unmarshal([]byte(output), &struct {
+		Data *TradingCalendarData `json:"data"`
+	}{&data})
+	if data.Market != "HK" || !data.IsHalfDay || len(data.Sessions) != 1 || data.SessionPhase != "" || data.NextTradingDay != "2025-12-29" {
+		t.Errorf("Unexpected HK half day result: %s", output)
+	}
+
+	output, _ = doGetTradingCalendar(&TradingCalendarParam{Date: "2030-01-02"}, now)
+	if !strings.Contains(output, "未收录") {
+		t.Errorf("Expected coverage note: %s", output)
+	}
+	for _, param := range []*TradingCalendarParam{{Market: "NYSE"}, {Date: "2025/01/02"}} {
+		if output, _ := doGetTradingCalendar(param, now); !strings.Contains(output, `"success": false`) {
+			t.Errorf("Expected error for %+v: %s", param, output)
+		}
+	}
+}
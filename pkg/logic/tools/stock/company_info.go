@@ -3,6 +3,7 @@ package stock
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/components/tool/utils"
@@ -11,8 +12,12 @@ import (
 	"msa/pkg/model"
 )
 
+// MaxQuoteCodes 单次查询行情的最大股票数量
+const MaxQuoteCodes = 100
+
 type CompanyInfoParam struct {
-	StockCode string `json:"stock_code" jsonschema:"description=stock_code of a stock code"`
+	StockCodes []string `json:"stock_codes,omitempty" jsonschema:"description=股票代码列表（如 sh600519、sz000001、hk00700），一次最多 100 只"`
+	StockCode  string   `json:"stock_code,omitempty" jsonschema:"description=单只股票代码（可选，与 stock_codes 合并查询）"`
 }
 
 type CompanyInfo struct {
@@ -27,15 +32,22 @@ func (ck *CompanyInfo) GetName() string {
 }
 
 func (ck *CompanyInfo) GetDescription() string {
-	return "获取A股及港股股票的实时行情数据，包括当前价、昨收、开盘价、成交量、最高价、最低价、市盈率、振幅、52周高低价等 | Get the real-time quote data of A-share and Hong Kong stocks, including current price, previous close, open price, volume, high/low prices, P/E ratio, amplitude, 52-week high/low, etc."
+	return "批量获取A股及港股实时行情，返回最新价、昨收、涨跌幅、成交量、成交额、五档买卖盘、涨停价、跌停价、市盈率、52周高低价等 | Get real-time quotes for a list of A-share and Hong Kong stocks, including last price, previous close, change %, volume, turnover, five-level bid/ask, limit-up/limit-down prices, P/E ratio and 52-week high/low"
 }
 
 func (ck *CompanyInfo) GetToolGroup() model.ToolGroup {
 	return model.StockToolGroup
 }
 
+// StockQuotesData 批量行情数据
+type StockQuotesData struct {
+	Total       int                 `json:"total"`
+	Quotes      []*model.StockQuote `json:"quotes"`
+	FailedCodes []string            `json:"failed_codes,omitempty"` // 获取失败的股票代码
+}
+
 func GetStockCompanyInfo(ctx context.Context, param *CompanyInfoParam) (string, error) {
-	return safetool.SafeExecute("get_stock_quote", fmt.Sprintf("stock_code: %s", param.StockCode), func() (string, error) {
+	return safetool.SafeExecute("get_stock_quote", fmt.Sprintf("stock_codes: %v, stock_code: %s", param.StockCodes, param.StockCode), func() (string, error) {
 		return doGetStockCompanyInfo(ctx, param)
 	})
 }
@@ -48,15 +60,46 @@ func doGetStockCompanyInfo(ctx context.Context, param *CompanyInfoParam) (string
 		return model.NewErrorResult(err.Error()), nil
 	}
 
-	// 调用公共函数获取股票数据
-	stockCurrentResp, err := FetchStockData(param.StockCode)
+	// 合并去重，保持调用方顺序
+	var codes []string
+	seen := make(map[string]bool)
+	for _, code := range append(param.StockCodes, param.StockCode) {
+		code = strings.TrimSpace(code)
+		if code == "" || seen[code] {
+			continue
+		}
+		seen[code] = true
+		codes = append(codes, code)
+	}
+	if len(codes) == 0 {
+		return model.NewErrorResult("stock_codes is required"), nil
+	}
+	if len(codes) > MaxQuoteCodes {
+		return model.NewErrorResult(fmt.Sprintf("一次最多查询 %d 只股票，当前 %d 只", MaxQuoteCodes, len(codes))), nil
+	}
+
+	quotes, err := FetchStockQuotes(codes)
 	if err != nil {
 		return model.NewErrorResult(err.Error()), nil
 	}
 
-	// 输出详细的行情信息
-	log.Infof("日期: %s", stockCurrentResp.Date)
-	log.Infof("当前价: %s", stockCurrentResp.CurrentPrice)
+	data := &StockQuotesData{Quotes: make([]*model.StockQuote, 0, len(codes))}
+	for _, code := range codes {
+		if quote, ok := quotes[code]; ok {
+			data.Quotes = append(data.Quotes, quote)
+		} else {
+			data.FailedCodes = append(data.FailedCodes, code)
+		}
+	}
+	data.Total = len(data.Quotes)
 
-	return model.NewSuccessResult(stockCurrentResp, fmt.Sprintf("获取股票行情成功, 当前价: %s", stockCurrentResp.CurrentPrice)), nil
+	if data.Total == 0 {
+		return model.NewErrorResult(fmt.Sprintf("获取行情失败: %v", data.FailedCodes)), nil
+	}
+
+	message := fmt.Sprintf("获取股票行情成功, %d只", data.Total)
+	if len(data.FailedCodes) > 0 {
+		message += fmt.Sprintf("，%d只获取失败: %v", len(data.FailedCodes), data.FailedCodes)
+	}
+	return model.NewSuccessResult(data, message), nil
 }
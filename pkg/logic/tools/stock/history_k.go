@@ -2,16 +2,17 @@ package stock
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"net/url"
+	"time"
 
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/components/tool/utils"
 	log "github.com/sirupsen/logrus"
+	"msa/pkg/db"
+	"msa/pkg/logic/marketdata"
+	"msa/pkg/logic/marketdata/klinecache"
 	"msa/pkg/logic/tools/safetool"
 	"msa/pkg/model"
-	mas_utils "msa/pkg/utils"
 )
 
 type HistoryKParam struct {
@@ -55,50 +56,29 @@ func doGetStockHistoryK(ctx context.Context, param *HistoryKParam) (string, erro
 		param.Adjust = "qfq"
 	}
 
-	paramStr := fmt.Sprintf("%s,%s,,,%d,%s", param.StockCode, param.Period, param.Count, param.Adjust)
-	apiURL := model.FinanceKLineAPI + url.QueryEscape(paramStr)
-
-	resp, err := mas_utils.GetRestyClient().R().Get(apiURL)
+	bars, err := FetchStockHistoryK(param.StockCode, param.Period, param.Count, param.Adjust)
 	if err != nil {
-		return model.NewErrorResult(fmt.Sprintf("HTTP request failed: %v", err)), nil
+		return model.NewErrorResult(err.Error()), nil
 	}
+	return model.NewSuccessResult(bars, fmt.Sprintf("获取%s %s K线数据成功, %d条", param.StockCode, param.Period, len(bars))), nil
+}
 
-	var klineResp model.StockKLineResp
-	if err := json.Unmarshal(resp.Body(), &klineResp); err != nil {
-		return model.NewErrorResult(fmt.Sprintf("JSON解析失败: %v", err)), nil
-	}
-	if klineResp.Code != 0 {
-		return model.NewErrorResult(fmt.Sprintf("API error: code=%d, msg=%s", klineResp.Code, klineResp.Msg)), nil
+// FetchStockHistoryK 获取历史K线数据
+// period: day/week/month；adjust: qfq/hfq/空（不复权）
+// 数据库可用时使用本地K线缓存，只请求缓存之后的新K线
+func FetchStockHistoryK(stockCode, period string, count int, adjust string) ([]model.KLineBar, error) {
+	provider, err := marketdata.GetProvider()
+	if err != nil {
+		return nil, err
 	}
 
-	for _, stockData := range klineResp.Data {
-		var rawData [][]string
-		switch param.Period {
-		case "day":
-			if len(stockData.QfqDay) > 0 {
-				rawData = stockData.QfqDay
-			} else {
-				rawData = stockData.Day
-			}
-		case "week":
-			if len(stockData.QfqWeek) > 0 {
-				rawData = stockData.QfqWeek
-			} else {
-				rawData = stockData.Week
-			}
-		case "month":
-			if len(stockData.QfqMonth) > 0 {
-				rawData = stockData.QfqMonth
-			} else {
-				rawData = stockData.Month
-			}
-		}
-		bars := model.ToKLineBars(rawData)
-		if len(bars) > 0 {
-			log.Infof("get_stock_history_k: period=%s count=%d first=%s last=%s",
-				param.Period, len(bars), bars[0].Date, bars[len(bars)-1].Date)
-			return model.NewSuccessResult(bars, fmt.Sprintf("获取%s %s K线数据成功, %d条", param.StockCode, param.Period, len(bars))), nil
-		}
+	bars, err := klinecache.GetKLine(db.GetDB(), provider, stockCode, period, count, adjust, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if len(bars) > 0 {
+		log.Infof("get_stock_history_k: source=%s period=%s count=%d first=%s last=%s",
+			provider.GetSource(), period, len(bars), bars[0].Date, bars[len(bars)-1].Date)
 	}
-	return model.NewErrorResult("no K-line data found"), nil
+	return bars, nil
 }
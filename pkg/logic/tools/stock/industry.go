@@ -2,15 +2,14 @@ package stock
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/components/tool/utils"
 	log "github.com/sirupsen/logrus"
+	"msa/pkg/logic/marketdata"
 	"msa/pkg/logic/tools/safetool"
 	"msa/pkg/model"
-	mas_utils "msa/pkg/utils"
 )
 
 type IndustryParam struct {
@@ -42,18 +41,14 @@ func doGetStockIndustry(ctx context.Context, param *IndustryParam) (string, erro
 		return model.NewErrorResult("stock_code is required"), nil
 	}
 
-	apiURL := model.FinanceStockIndustry + param.StockCode
-	resp, err := mas_utils.GetRestyClient().R().Get(apiURL)
+	provider, err := marketdata.GetProvider()
 	if err != nil {
-		return model.NewErrorResult(fmt.Sprintf("HTTP request failed: %v", err)), nil
+		return model.NewErrorResult(err.Error()), nil
 	}
 
-	var indResp model.StockIndustryResp
-	if err := json.Unmarshal(resp.Body(), &indResp); err != nil {
-		return model.NewErrorResult(fmt.Sprintf("JSON解析失败: %v", err)), nil
-	}
-	if indResp.Code != 0 {
-		return model.NewErrorResult(fmt.Sprintf("API error: code=%d, msg=%s", indResp.Code, indResp.Msg)), nil
+	indResp, err := provider.GetIndustry(param.StockCode)
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
 	}
 
 	result := map[string]interface{}{
@@ -0,0 +1,140 @@
+package stock
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+	log "github.com/sirupsen/logrus"
+	"msa/pkg/logic/indicator"
+	"msa/pkg/logic/marketdata"
+	"msa/pkg/logic/regime"
+	"msa/pkg/logic/tools/safetool"
+	"msa/pkg/model"
+)
+
+const (
+	// regimeKLineCount 市场状态判断使用的日K线数，覆盖年线与一年的 ATR 百分位
+	regimeKLineCount = 300
+	// regimeBreadthBoardType 市场广度使用申万行业板块
+	regimeBreadthBoardType = "01"
+	// regimeBreadthBoardCount 统计广度的板块数量
+	regimeBreadthBoardCount = 100
+)
+
+// regimeIndex 参与市场状态判断的指数
+type regimeIndex struct {
+	Code string
+	Name string
+}
+
+// defaultRegimeIndices 市场状态判断默认使用的指数
+var defaultRegimeIndices = []regimeIndex{
+	{"sh000001", "上证指数"},
+	{"sz399001", "深证成指"},
+	{"sz399006", "创业板指"},
+}
+
+type MarketRegimeParam struct {
+	IndexCodes []string `json:"index_codes,omitempty" jsonschema:"description=index codes used for classification，default: [sh000001、sz399001、sz399006]"`
+}
+
+type MarketRegime struct{}
+
+func (m *MarketRegime) GetToolInfo() (tool.BaseTool, error) {
+	return utils.InferTool(m.GetName(), m.GetDescription(), GetMarketRegime)
+}
+
+func (m *MarketRegime) GetName() string { return "get_market_regime" }
+
+func (m *MarketRegime) GetDescription() string {
+	return "根据上证指数、深证成指、创业板指日K线与行业板块涨跌，从趋势、波动率、市场广度三个维度判断市场状态（BULL/BEAR/RANGE 及 8 级细分状态），并返回均线斜率、ATR 百分位、涨跌板块比例等依据 | Classify the market regime (BULL/BEAR/RANGE plus an 8-level state) from index daily K-lines and industry board breadth across trend, volatility and breadth, returning the evidence such as MA slopes, ATR percentile and advance/decline ratio"
+}
+
+func (m *MarketRegime) GetToolGroup() model.ToolGroup { return model.StockToolGroup }
+
+func GetMarketRegime(ctx context.Context, param *MarketRegimeParam) (string, error) {
+	if param == nil {
+		param = &MarketRegimeParam{}
+	}
+	return safetool.SafeExecute("get_market_regime", fmt.Sprintf("index_codes: %v", param.IndexCodes), func() (string, error) {
+		return doGetMarketRegime(ctx, param)
+	})
+}
+
+func doGetMarketRegime(ctx context.Context, param *MarketRegimeParam) (string, error) {
+	var indices []regimeIndex
+	for _, code := range param.IndexCodes {
+		code = strings.ToLower(strings.TrimSpace(code))
+		if code != "" {
+			indices = append(indices, regimeIndex{Code: code, Name: regimeIndexName(code)})
+		}
+	}
+	if len(indices) == 0 {
+		indices = defaultRegimeIndices
+	}
+
+	var inputs []regime.IndexInput
+	var failures []string
+	for _, index := range indices {
+		klines, err := FetchStockHistoryK(index.Code, "day", regimeKLineCount, "qfq")
+		if err == nil {
+			var bars []indicator.Bar
+			if bars, err = indicator.FromKLineBars(klines); err == nil {
+				inputs = append(inputs, regime.IndexInput{Code: index.Code, Name: index.Name, Bars: bars})
+				continue
+			}
+		}
+		log.Warnf("get_market_regime: 获取指数K线失败 %s: %v", index.Code, err)
+		failures = append(failures, fmt.Sprintf("%s K线获取失败: %v", index.Name, err))
+		// 获取失败的指数仍计入指数总数，降低判断置信度
+		inputs = append(inputs, regime.IndexInput{Code: index.Code, Name: index.Name})
+	}
+	if len(failures) == len(indices) {
+		return model.NewErrorResult(fmt.Sprintf("获取指数K线失败: %s", strings.Join(failures, "；"))), nil
+	}
+
+	breadth, err := fetchBreadth()
+	if err != nil {
+		log.Warnf("get_market_regime: 获取板块排行失败: %v", err)
+		failures = append(failures, fmt.Sprintf("板块排行获取失败: %v", err))
+	}
+
+	result, err := regime.Classify(inputs, breadth)
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+	result.Notes = append(failures, result.Notes...)
+
+	return model.NewSuccessResult(result, fmt.Sprintf("市场状态: %s (%s)，综合得分 %.2f，置信度 %s",
+		result.Regime, result.State, result.Score, result.Confidence)), nil
+}
+
+// fetchBreadth 根据申万行业板块涨跌统计市场广度
+func fetchBreadth() (*regime.Breadth, error) {
+	provider, err := marketdata.GetProvider()
+	if err != nil {
+		return nil, err
+	}
+	items, err := provider.GetBoardRank(regimeBreadthBoardType, 0, regimeBreadthBoardCount)
+	if err != nil {
+		return nil, err
+	}
+	breadth := regime.BreadthFromBoards(items)
+	if breadth == nil {
+		return nil, fmt.Errorf("no board change data")
+	}
+	return breadth, nil
+}
+
+// regimeIndexName 获取默认指数名称，非默认指数使用代码
+func regimeIndexName(code string) string {
+	for _, index := range defaultRegimeIndices {
+		if index.Code == code {
+			return index.Name
+		}
+	}
+	return code
+}
@@ -3,76 +3,31 @@ package stock
 import (
 	"context"
 	"fmt"
-	"strconv"
-	"strings"
 
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/components/tool/utils"
 	log "github.com/sirupsen/logrus"
+	"msa/pkg/logic/marketdata"
 	"msa/pkg/logic/tools/safetool"
 	"msa/pkg/model"
 )
 
 // FetchStockMinuteData 获取并解析分钟K线数据
-// 复用 FetchStockData() 返回的 StockCurrentResp.Data 字段
 func FetchStockMinuteData(stockCode string) (*model.StockMinuteKResp, error) {
-	resp, err := FetchStockData(stockCode)
-	if err != nil {
-		return nil, err
+	if stockCode == "" {
+		return nil, fmt.Errorf("stock code is empty")
 	}
 
-	result := &model.StockMinuteKResp{
-		StockCode: stockCode,
-		Date:      resp.Date,
-	}
-
-	if len(resp.Data) == 0 {
-		log.Infof("get_stock_minute_k: no minute data for %s", stockCode)
-		return result, nil
+	provider, err := marketdata.GetProvider()
+	if err != nil {
+		return nil, err
 	}
 
-	bars := make([]model.StockMinuteBar, 0, len(resp.Data))
-	var prevVolume float64
-	var prevTurnover float64
-
-	for _, raw := range resp.Data {
-		parts := strings.Split(strings.TrimSpace(raw), " ")
-		if len(parts) < 4 {
-			log.Warnf("get_stock_minute_k: unexpected data format: %s", raw)
-			continue
-		}
-
-		timeStr := parts[0]
-		price, err1 := strconv.ParseFloat(parts[1], 64)
-		cumVolume, err2 := strconv.ParseFloat(parts[2], 64)
-		cumTurnover, err3 := strconv.ParseFloat(parts[3], 64)
-		if err1 != nil || err2 != nil || err3 != nil {
-			log.Warnf("get_stock_minute_k: parse error for %s: %v %v %v", raw, err1, err2, err3)
-			continue
-		}
-
-		// 累计值差分得到每分钟实际值
-		volume := cumVolume
-		turnover := cumTurnover
-		if len(bars) > 0 {
-			volume = cumVolume - prevVolume
-			turnover = cumTurnover - prevTurnover
-		}
-
-		bars = append(bars, model.StockMinuteBar{
-			Time:     timeStr,
-			Price:    price,
-			Volume:   int64(volume),
-			Turnover: turnover,
-		})
-
-		prevVolume = cumVolume
-		prevTurnover = cumTurnover
+	result, err := provider.GetMinuteBars(stockCode)
+	if err != nil {
+		return nil, err
 	}
-
-	result.Bars = bars
-	result.Count = len(bars)
-	log.Infof("get_stock_minute_k: stock=%s date=%s bars=%d", stockCode, resp.Date, result.Count)
+	log.Infof("get_stock_minute_k: source=%s stock=%s date=%s bars=%d", provider.GetSource(), stockCode, result.Date, result.Count)
 	return result, nil
 }
 
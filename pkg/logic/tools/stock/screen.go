@@ -0,0 +1,85 @@
+package stock
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+	log "github.com/sirupsen/logrus"
+	"msa/pkg/logic/marketdata"
+	"msa/pkg/logic/screener"
+	"msa/pkg/logic/tools/safetool"
+	"msa/pkg/model"
+)
+
+type ScreenStocksParam struct {
+	Expression string   `json:"expression,omitempty" jsonschema:"description=filter expression combining comparisons with AND / OR / NOT and parentheses，e.g. pe < 20 AND change_5d > 3 AND industry = \"半导体\"。numeric fields: price、change_pct、pe、pb、turnover_rate、amplitude、volume、turnover、market_cap、float_cap、change_5d、change_20d、change_60d、ma5、ma10、ma20、ma60、rsi14、vol_ratio、atr_pct；text fields: code、name、board、industry (use = != CONTAINS with quoted strings)"`
+	Boards     []string `json:"boards,omitempty" jsonschema:"description=board names or bd_code from get_board_rank whose constituents form the universe"`
+	Codes      []string `json:"codes,omitempty" jsonschema:"description=extra stock codes added to the universe"`
+	BoardType  string   `json:"board_type,omitempty" jsonschema:"description=board type used to look up board names: 01=Shenwan industry、02=concept、03=region，default: 01"`
+	SortBy     string   `json:"sort_by,omitempty" jsonschema:"description=numeric field to rank by，default: change_pct"`
+	Order      string   `json:"order,omitempty" jsonschema:"description=sort order: desc or asc，default: desc"`
+	Page       int      `json:"page,omitempty" jsonschema:"description=page number starting from 1，default: 1"`
+	PageSize   int      `json:"page_size,omitempty" jsonschema:"description=results per page，default: 20，max: 100"`
+}
+
+type ScreenStocks struct{}
+
+func (s *ScreenStocks) GetToolInfo() (tool.BaseTool, error) {
+	return utils.InferTool(s.GetName(), s.GetDescription(), ScreenStocksTool)
+}
+
+func (s *ScreenStocks) GetName() string { return "screen_stocks" }
+
+func (s *ScreenStocks) GetDescription() string {
+	return "按条件表达式筛选股票：以板块成分股（或指定代码）为股票池，结合行情、行业分类与日K线计算的涨跌幅、均线、RSI 等字段过滤，返回排序后的分页结果。未指定股票池时使用表达式中的板块/行业，再没有时使用涨幅前 10 的行业板块 | Screen stocks with a filter expression over a universe built from board constituents or given codes, using quote, industry and daily K-line fields; returns a ranked, paginated list"
+}
+
+func (s *ScreenStocks) GetToolGroup() model.ToolGroup { return model.StockToolGroup }
+
+func ScreenStocksTool(ctx context.Context, param *ScreenStocksParam) (string, error) {
+	if param == nil {
+		param = &ScreenStocksParam{}
+	}
+	return safetool.SafeExecute("screen_stocks", fmt.Sprintf("expression: %s, boards: %v", param.Expression, param.Boards), func() (string, error) {
+		return doScreenStocks(ctx, param)
+	})
+}
+
+func doScreenStocks(ctx context.Context, param *ScreenStocksParam) (string, error) {
+	order := strings.ToLower(strings.TrimSpace(param.Order))
+	if order != "" && order != "asc" && order != "desc" {
+		return model.NewErrorResult("order must be asc or desc"), nil
+	}
+
+	result, err := RunScreen(screener.Options{
+		Expression: param.Expression,
+		Codes:      param.Codes,
+		Boards:     param.Boards,
+		BoardType:  param.BoardType,
+		SortBy:     param.SortBy,
+		Ascending:  order == "asc",
+		Page:       param.Page,
+		PageSize:   param.PageSize,
+	})
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	log.Infof("screen_stocks: expression=%q universe=%d matched=%d", param.Expression, result.Universe, result.Matched)
+	return model.NewSuccessResult(result, fmt.Sprintf("股票池 %d 只，符合条件 %d 只，第 %d/%d 页",
+		result.Universe, result.Matched, result.Page, max(result.TotalPages, 1))), nil
+}
+
+// RunScreen 使用当前行情数据源筛选股票，日K线经过本地缓存
+func RunScreen(opts screener.Options) (*screener.Result, error) {
+	provider, err := marketdata.GetProvider()
+	if err != nil {
+		return nil, err
+	}
+	return screener.Screen(provider, func(stockCode string, count int) ([]model.KLineBar, error) {
+		return FetchStockHistoryK(stockCode, "day", count, "qfq")
+	}, opts)
+}
@@ -0,0 +1,139 @@
+package stock
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+	"msa/pkg/logic/indicator"
+	"msa/pkg/logic/tools/safetool"
+	"msa/pkg/model"
+)
+
+const (
+	// defaultIndicatorBars 计算指标默认使用的K线数，保证 MA60、MACD 与背离窗口有足够数据
+	defaultIndicatorBars = 120
+	// maxIndicatorBars 计算指标最多使用的K线数
+	maxIndicatorBars = 640
+	// minIndicatorBars 少于该数量时提示指标可信度不足
+	minIndicatorBars = 60
+)
+
+type TechnicalIndicatorsParam struct {
+	StockCode  string `json:"stock_code" jsonschema:"description=stock code (e.g. sh600519)"`
+	Period     string `json:"period" jsonschema:"description=K-line period: day/week/month/minute (当日分时)，default: day"`
+	Count      int    `json:"count" jsonschema:"description=number of bars used for calculation (max 640)，default: 120"`
+	Adjust     string `json:"adjust" jsonschema:"description=adjust type: qfq (前复权)/hfq (后复权)/empty (不复权)，default: qfq"`
+	MAPeriods  []int  `json:"ma_periods,omitempty" jsonschema:"description=MA periods，default: [5、10、20、60]"`
+	EMAPeriods []int  `json:"ema_periods,omitempty" jsonschema:"description=EMA periods，default: [12、26]"`
+	RSIPeriods []int  `json:"rsi_periods,omitempty" jsonschema:"description=RSI periods (the first one drives overbought/oversold and divergence signals)，default: [6、14]"`
+	MACDFast   int    `json:"macd_fast,omitempty" jsonschema:"description=MACD fast EMA period，default: 12"`
+	MACDSlow   int    `json:"macd_slow,omitempty" jsonschema:"description=MACD slow EMA period，default: 26"`
+	MACDSignal int    `json:"macd_signal,omitempty" jsonschema:"description=MACD signal (DEA) period，default: 9"`
+	BOLLPeriod int    `json:"boll_period,omitempty" jsonschema:"description=BOLL period，default: 20"`
+	ATRPeriod  int    `json:"atr_period,omitempty" jsonschema:"description=ATR period，default: 14"`
+}
+
+// TechnicalIndicatorsData 技术指标结果
+type TechnicalIndicatorsData struct {
+	StockCode string `json:"stock_code"`
+	Period    string `json:"period"`
+	*indicator.Result
+}
+
+type TechnicalIndicators struct{}
+
+func (t *TechnicalIndicators) GetToolInfo() (tool.BaseTool, error) {
+	return utils.InferTool(t.GetName(), t.GetDescription(), GetTechnicalIndicators)
+}
+
+func (t *TechnicalIndicators) GetName() string { return "get_technical_indicators" }
+
+func (t *TechnicalIndicators) GetDescription() string {
+	return "计算股票技术指标（MA、EMA、MACD、RSI、KDJ、BOLL、ATR、VWAP、OBV），只返回最新值及金叉/死叉、超买/超卖、布林突破、背离信号，周期可配置 | Compute technical indicators (MA, EMA, MACD, RSI, KDJ, BOLL, ATR, VWAP, OBV) for a stock, returns latest values plus crossover, overbought/oversold, band-break and divergence signals with configurable periods"
+}
+
+func (t *TechnicalIndicators) GetToolGroup() model.ToolGroup { return model.StockToolGroup }
+
+func GetTechnicalIndicators(ctx context.Context, param *TechnicalIndicatorsParam) (string, error) {
+	return safetool.SafeExecute("get_technical_indicators", fmt.Sprintf("stock_code: %s", param.StockCode), func() (string, error) {
+		return doGetTechnicalIndicators(ctx, param)
+	})
+}
+
+func doGetTechnicalIndicators(ctx context.Context, param *TechnicalIndicatorsParam) (string, error) {
+	if param == nil || param.StockCode == "" {
+		return model.NewErrorResult("stock_code is required"), nil
+	}
+	if param.Period == "" {
+		param.Period = "day"
+	}
+	if param.Count <= 0 {
+		param.Count = defaultIndicatorBars
+	}
+	if param.Count > maxIndicatorBars {
+		param.Count = maxIndicatorBars
+	}
+	if param.Adjust == "" {
+		param.Adjust = "qfq"
+	}
+
+	bars, err := fetchIndicatorBars(param)
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	result, err := indicator.Compute(bars, indicator.Config{
+		MAPeriods:  positivePeriods(param.MAPeriods),
+		EMAPeriods: positivePeriods(param.EMAPeriods),
+		RSIPeriods: positivePeriods(param.RSIPeriods),
+		MACDFast:   param.MACDFast,
+		MACDSlow:   param.MACDSlow,
+		MACDSignal: param.MACDSignal,
+		BOLLPeriod: param.BOLLPeriod,
+		ATRPeriod:  param.ATRPeriod,
+	})
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+	if len(bars) < minIndicatorBars {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("仅有 %d 根K线，长周期指标可能未收敛或缺失", len(bars)))
+	}
+
+	data := &TechnicalIndicatorsData{StockCode: param.StockCode, Period: param.Period, Result: result}
+	return model.NewSuccessResult(data, fmt.Sprintf("计算%s %s 技术指标成功，K线%d根，信号%d个", param.StockCode, param.Period, len(bars), len(result.Signals))), nil
+}
+
+// fetchIndicatorBars 获取计算指标所需的K线
+// minute 使用当日分时数据，其余周期使用历史K线
+func fetchIndicatorBars(param *TechnicalIndicatorsParam) ([]indicator.Bar, error) {
+	if param.Period == "minute" {
+		minute, err := FetchStockMinuteData(param.StockCode)
+		if err != nil {
+			return nil, err
+		}
+		bars := indicator.FromMinuteBars(minute.Bars)
+		if len(bars) > param.Count {
+			bars = bars[len(bars)-param.Count:]
+		}
+		return bars, nil
+	}
+
+	klines, err := FetchStockHistoryK(param.StockCode, param.Period, param.Count, param.Adjust)
+	if err != nil {
+		return nil, err
+	}
+	return indicator.FromKLineBars(klines)
+}
+
+// positivePeriods 过滤非正数周期
+func positivePeriods(periods []int) []int {
+	var result []int
+	for _, p := range periods {
+		if p > 0 {
+			result = append(result, p)
+		}
+	}
+	return result
+}
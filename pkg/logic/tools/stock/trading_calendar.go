@@ -0,0 +1,147 @@
+package stock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+
+	"msa/pkg/logic/calendar"
+	"msa/pkg/logic/tools/safetool"
+	"msa/pkg/model"
+)
+
+const (
+	// defaultUpcomingDays 默认返回的后续交易日数
+	defaultUpcomingDays = 5
+	// maxUpcomingDays 最多返回的后续交易日数
+	maxUpcomingDays = 30
+)
+
+var weekdayNames = [...]string{"星期日", "星期一", "星期二", "星期三", "星期四", "星期五", "星期六"}
+
+type TradingCalendarParam struct {
+	Market string `json:"market,omitempty" jsonschema:"description=market: CN (沪深) or HK (港股)，default: CN"`
+	Date   string `json:"date,omitempty" jsonschema:"description=date to query (YYYY-MM-DD)，default: today"`
+	Days   int    `json:"days,omitempty" jsonschema:"description=number of upcoming trading days to list (max 30)，default: 5"`
+}
+
+// TradingCalendarData 交易日历查询结果
+type TradingCalendarData struct {
+	Market              string   `json:"market"`
+	Date                string   `json:"date"`
+	Weekday             string   `json:"weekday"`
+	IsTradingDay        bool     `json:"is_trading_day"`
+	IsHalfDay           bool     `json:"is_half_day"`
+	Holiday             string   `json:"holiday,omitempty"`
+	Sessions            []string `json:"sessions,omitempty"`            // 可成交时段
+	SessionPhase        string   `json:"session_phase,omitempty"`       // 查询当天时的当前交易阶段
+	SessionPhaseLabel   string   `json:"session_phase_label,omitempty"` // 交易阶段中文名称
+	LatestTradingDay    string   `json:"latest_trading_day"`            // 当天或之前最近的交易日
+	PrevTradingDay      string   `json:"prev_trading_day"`
+	NextTradingDay      string   `json:"next_trading_day"`
+	UpcomingTradingDays []string `json:"upcoming_trading_days"`
+	Note                string   `json:"note,omitempty"`
+}
+
+type TradingCalendar struct{}
+
+func (c *TradingCalendar) GetToolInfo() (tool.BaseTool, error) {
+	return utils.InferTool(c.GetName(), c.GetDescription(), GetTradingCalendar,
+		utils.WithUnmarshalArguments(unmarshalEmptyParam[TradingCalendarParam]))
+}
+
+func (c *TradingCalendar) GetName() string { return "get_trading_calendar" }
+
+func (c *TradingCalendar) GetDescription() string {
+	return "查询沪深或港股交易日历：指定日期是否为交易日、休市节假日与半日市、交易时段、当前交易阶段，以及最近/上一个/下一个交易日和后续交易日列表 | Query the SSE/SZSE or HKEX trading calendar: whether a date is a trading day, holidays and half days, trading sessions, the current session phase, and the latest/previous/next and upcoming trading days"
+}
+
+func (c *TradingCalendar) GetToolGroup() model.ToolGroup { return model.MarketToolGroup }
+
+func GetTradingCalendar(ctx context.Context, param *TradingCalendarParam) (string, error) {
+	if param == nil {
+		param = &TradingCalendarParam{}
+	}
+	return safetool.SafeExecute("get_trading_calendar", fmt.Sprintf("market: %s, date: %s", param.Market, param.Date), func() (string, error) {
+		return doGetTradingCalendar(param, calendar.Now())
+	})
+}
+
+func doGetTradingCalendar(param *TradingCalendarParam, now time.Time) (string, error) {
+	market, err := calendar.ParseMarket(param.Market)
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	day := now
+	today := calendar.DateOf(now)
+	if date := strings.TrimSpace(param.Date); date != "" && date != today {
+		if day, err = time.ParseInLocation(calendar.DateLayout, date, calendar.Location); err != nil {
+			return model.NewErrorResult(fmt.Sprintf("日期格式无效: %s（格式: YYYY-MM-DD）", param.Date)), nil
+		}
+	}
+
+	days := param.Days
+	if days <= 0 {
+		days = defaultUpcomingDays
+	}
+	days = min(days, maxUpcomingDays)
+
+	data := &TradingCalendarData{
+		Market:           string(market),
+		Date:             calendar.DateOf(day),
+		Weekday:          weekdayNames[day.In(calendar.Location).Weekday()],
+		IsTradingDay:     calendar.IsTradingDay(market, day),
+		IsHalfDay:        calendar.IsHalfDay(market, day),
+		Holiday:          calendar.HolidayName(market, day),
+		LatestTradingDay: calendar.DateOf(calendar.LatestTradingDay(market, day)),
+		PrevTradingDay:   calendar.DateOf(calendar.PrevTradingDay(market, day)),
+	}
+	for _, s := range calendar.Sessions(market, day) {
+		data.Sessions = append(data.Sessions, s.Open.Format("15:04")+"-"+s.Close.Format("15:04"))
+	}
+	if data.Date == today {
+		phase := calendar.PhaseAt(market, now)
+		data.SessionPhase = string(phase)
+		data.SessionPhaseLabel = phase.Label()
+	}
+	next := day
+	for i := 0; i < days; i++ {
+		next = calendar.NextTradingDay(market, next)
+		data.UpcomingTradingDays = append(data.UpcomingTradingDays, calendar.DateOf(next))
+	}
+	data.NextTradingDay = data.UpcomingTradingDays[0]
+	if !calendar.Covered(market, day) || !calendar.Covered(market, next) {
+		data.Note = "交易日历未收录该年份的休市安排，只排除了周末，请以交易所公告为准"
+	}
+
+	status := "交易日"
+	switch {
+	case data.IsHalfDay:
+		status = "半日市"
+	case !data.IsTradingDay && data.Holiday != "":
+		status = "休市（" + data.Holiday + "）"
+	case !data.IsTradingDay:
+		status = "休市（周末）"
+	}
+	return model.NewSuccessResult(data, fmt.Sprintf("%s %s %s %s，最近交易日 %s，下一交易日 %s",
+		data.Market, data.Date, data.Weekday, status, data.LatestTradingDay, data.NextTradingDay)), nil
+}
+
+// unmarshalEmptyParam 兼容空参数调用
+func unmarshalEmptyParam[T any](ctx context.Context, arguments string) (interface{}, error) {
+	inst := new(T)
+	s := strings.TrimSpace(arguments)
+	if s == "" || s == "{}" {
+		return inst, nil
+	}
+	if err := json.Unmarshal([]byte(arguments), inst); err != nil {
+		return nil, err
+	}
+	return inst, nil
+}
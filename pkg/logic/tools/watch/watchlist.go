@@ -0,0 +1,401 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	msadb "msa/pkg/db"
+	"msa/pkg/logic/tools/safetool"
+	"msa/pkg/logic/tools/stock"
+	"msa/pkg/logic/watchlist"
+	"msa/pkg/model"
+)
+
+// WatchAlertParam 提醒规则参数
+type WatchAlertParam struct {
+	Type   string  `json:"type" jsonschema:"description=提醒类型: price_above(价格上穿)/price_below(价格下穿)/change_pct(当日涨跌幅超过阈值)/volume_spike(成交量达到5日均量倍数)"`
+	Price  float64 `json:"price,omitempty" jsonschema:"description=目标价（元/股），price_above 与 price_below 必填"`
+	Value  float64 `json:"value,omitempty" jsonschema:"description=change_pct 的涨跌幅阈值（%，默认 5）或 volume_spike 的放量倍数（默认 2）"`
+	Repeat bool    `json:"repeat,omitempty" jsonschema:"description=价格提醒触发后是否继续监控（默认只提醒一次）"`
+	Note   string  `json:"note,omitempty" jsonschema:"description=提醒备注"`
+}
+
+// AddToWatchlistParam 加入自选股参数
+type AddToWatchlistParam struct {
+	StockCode       string            `json:"stock_code" jsonschema:"description=股票代码（如 sh600519）"`
+	StockName       string            `json:"stock_name,omitempty" jsonschema:"description=股票名称"`
+	Notes           string            `json:"notes,omitempty" jsonschema:"description=关注理由等备注（已在自选股中时覆盖原备注）"`
+	Tags            string            `json:"tags,omitempty" jsonschema:"description=标签，多个用逗号分隔；传 - 清空标签"`
+	TargetBuyPrice  float64           `json:"target_buy_price,omitempty" jsonschema:"description=目标买入价（元/股），价格下穿时提醒"`
+	TargetSellPrice float64           `json:"target_sell_price,omitempty" jsonschema:"description=目标卖出价（元/股），价格上穿时提醒"`
+	Alerts          []WatchAlertParam `json:"alerts,omitempty" jsonschema:"description=额外的提醒规则"`
+}
+
+// AddToWatchlistTool 加入自选股工具
+type AddToWatchlistTool struct{}
+
+func (t *AddToWatchlistTool) GetToolInfo() (tool.BaseTool, error) {
+	return utils.InferTool(t.GetName(), t.GetDescription(), AddToWatchlist)
+}
+
+func (t *AddToWatchlistTool) GetName() string {
+	return "add_to_watchlist"
+}
+
+func (t *AddToWatchlistTool) GetDescription() string {
+	return "加入自选股或更新自选股的备注、标签、目标价，并可添加提醒规则（价格穿越、涨跌幅、放量），提醒在交易时段由后台监控推送 | Add a stock to the persistent watchlist or update its notes, tags and target prices, optionally with alert rules (price cross, % move, volume spike) monitored during trading hours"
+}
+
+func (t *AddToWatchlistTool) GetToolGroup() model.ToolGroup {
+	return model.StockToolGroup
+}
+
+// WatchAlertItem 提醒规则数据
+type WatchAlertItem struct {
+	ID           int64   `json:"id"`
+	Type         string  `json:"type"`
+	Status       string  `json:"status"`
+	Description  string  `json:"description"`
+	Price        string  `json:"price,omitempty"` // 价格提醒的目标价
+	Value        float64 `json:"value,omitempty"` // 涨跌幅阈值（%）或放量倍数
+	Repeat       bool    `json:"repeat,omitempty"`
+	Origin       string  `json:"origin,omitempty"` // TARGET_BUY / TARGET_SELL 表示由目标价生成
+	TriggerCount int     `json:"trigger_count,omitempty"`
+	TriggeredAt  string  `json:"triggered_at,omitempty"` // 最近一次触发时间
+	Note         string  `json:"note,omitempty"`
+}
+
+// WatchlistItem 自选股数据
+type WatchlistItem struct {
+	StockCode       string           `json:"stock_code"`
+	StockName       string           `json:"stock_name"`
+	Notes           string           `json:"notes,omitempty"`
+	Tags            []string         `json:"tags,omitempty"`
+	TargetBuyPrice  string           `json:"target_buy_price,omitempty"`
+	TargetSellPrice string           `json:"target_sell_price,omitempty"`
+	AddedPrice      string           `json:"added_price,omitempty"`
+	AddedAt         string           `json:"added_at"`
+	CurrentPrice    string           `json:"current_price,omitempty"`
+	ChangePercent   string           `json:"change_percent,omitempty"`    // 当日涨跌幅（%）
+	SinceAddedPct   *float64         `json:"since_added_pct,omitempty"`   // 加入以来涨跌幅（%）
+	ToBuyTargetPct  *float64         `json:"to_buy_target_pct,omitempty"` // 现价距目标买入价（%），负数表示需要下跌
+	ToSellTargetPct *float64         `json:"to_sell_target_pct,omitempty"`
+	Alerts          []WatchAlertItem `json:"alerts,omitempty"`
+}
+
+// AddToWatchlist 加入自选股
+func AddToWatchlist(ctx context.Context, param *AddToWatchlistParam) (string, error) {
+	return safetool.SafeExecute("add_to_watchlist", fmt.Sprintf("stock_code: %s", param.StockCode), func() (string, error) {
+		return doAddToWatchlist(msadb.GetDB(), param, fetchCurrentPrice)
+	})
+}
+
+func doAddToWatchlist(database *gorm.DB, param *AddToWatchlistParam, priceOf func(string) int64) (string, error) {
+	if database == nil {
+		err := fmt.Errorf("数据库未初始化")
+		return model.NewErrorResult(err.Error()), nil
+	}
+	if strings.TrimSpace(param.StockCode) == "" {
+		return model.NewErrorResult("stock_code is required"), nil
+	}
+
+	// 当前价作为加入价与价格穿越的起点，获取失败时首次监控再记录
+	price := priceOf(watchlist.NormalizeCode(param.StockCode))
+	item, created, err := watchlist.Upsert(database, watchlist.EntrySpec{
+		StockCode:       param.StockCode,
+		StockName:       strings.TrimSpace(param.StockName),
+		Notes:           strings.TrimSpace(param.Notes),
+		Tags:            param.Tags,
+		TargetBuyPrice:  model.YuanToHao(param.TargetBuyPrice),
+		TargetSellPrice: model.YuanToHao(param.TargetSellPrice),
+		CurrentPrice:    price,
+	})
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	var failed []string
+	for _, a := range param.Alerts {
+		_, err := watchlist.AddAlert(database, watchlist.AlertSpec{
+			StockCode:    item.StockCode,
+			Type:         model.WatchAlertType(strings.ToUpper(strings.TrimSpace(a.Type))),
+			Price:        model.YuanToHao(a.Price),
+			Value:        a.Value,
+			Repeat:       a.Repeat,
+			CurrentPrice: price,
+			Note:         a.Note,
+		})
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", a.Type, err))
+		}
+	}
+
+	alerts, err := msadb.GetWatchAlerts(database, item.StockCode, "")
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+	data := toWatchlistItem(item, alerts)
+	if price > 0 {
+		data.CurrentPrice = formatHaoToYuan(price)
+	}
+
+	msg := fmt.Sprintf("已更新自选股 %s，提醒规则 %d 条", item.StockName, len(alerts))
+	if created {
+		msg = fmt.Sprintf("已加入自选股 %s，提醒规则 %d 条", item.StockName, len(alerts))
+	}
+	if len(failed) > 0 {
+		msg += "；以下提醒未创建: " + strings.Join(failed, "；")
+	}
+	return model.NewSuccessResult(data, msg), nil
+}
+
+// GetWatchlistParam 查询自选股参数
+type GetWatchlistParam struct {
+	Tag       string `json:"tag,omitempty" jsonschema:"description=按标签筛选（可选）"`
+	WithQuote *bool  `json:"with_quote,omitempty" jsonschema:"description=是否附带实时行情与距目标价的距离（默认 true）"`
+}
+
+// GetWatchlistTool 查询自选股工具
+type GetWatchlistTool struct{}
+
+func (t *GetWatchlistTool) GetToolInfo() (tool.BaseTool, error) {
+	return utils.InferTool(t.GetName(), t.GetDescription(), GetWatchlist,
+		utils.WithUnmarshalArguments(unmarshalEmptyParam[GetWatchlistParam]))
+}
+
+func (t *GetWatchlistTool) GetName() string {
+	return "get_watchlist"
+}
+
+func (t *GetWatchlistTool) GetDescription() string {
+	return "查询自选股：备注、标签、目标价、提醒规则，以及实时价格、加入以来涨跌幅与距目标价的距离 | Get the watchlist with notes, tags, target prices, alert rules, live prices and distance to targets"
+}
+
+func (t *GetWatchlistTool) GetToolGroup() model.ToolGroup {
+	return model.StockToolGroup
+}
+
+// WatchlistData 自选股列表数据
+type WatchlistData struct {
+	Total int             `json:"total"`
+	Items []WatchlistItem `json:"items"`
+}
+
+// GetWatchlist 查询自选股
+func GetWatchlist(ctx context.Context, param *GetWatchlistParam) (string, error) {
+	return safetool.SafeExecute("get_watchlist", fmt.Sprintf("tag: %s", param.Tag), func() (string, error) {
+		return doGetWatchlist(msadb.GetDB(), param, stock.FetchStockQuotes)
+	})
+}
+
+func doGetWatchlist(database *gorm.DB, param *GetWatchlistParam, fetchQuotes func([]string) (map[string]*model.StockQuote, error)) (string, error) {
+	if database == nil {
+		err := fmt.Errorf("数据库未初始化")
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	entries, err := watchlist.List(database, param.Tag)
+	if err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	data := &WatchlistData{Total: len(entries), Items: make([]WatchlistItem, 0, len(entries))}
+	if len(entries) == 0 {
+		return model.NewSuccessResult(data, "自选股为空"), nil
+	}
+
+	var quotes map[string]*model.StockQuote
+	if param.WithQuote == nil || *param.WithQuote {
+		codes := make([]string, 0, len(entries))
+		for _, e := range entries {
+			codes = append(codes, e.Item.StockCode)
+		}
+		quotes, err = fetchQuotes(codes)
+		if err != nil {
+			log.Warnf("获取自选股行情失败: %v", err)
+		}
+	}
+
+	for _, e := range entries {
+		item := toWatchlistItem(e.Item, e.Alerts)
+		if q, ok := quotes[e.Item.StockCode]; ok && q != nil {
+			applyQuote(&item, e.Item, q)
+		}
+		data.Items = append(data.Items, item)
+	}
+	return model.NewSuccessResult(data, fmt.Sprintf("自选股共 %d 只", len(entries))), nil
+}
+
+// applyQuote 填充实时价格与距目标价的距离
+func applyQuote(item *WatchlistItem, w *model.Watchlist, q *model.StockQuote) {
+	if w.StockName == w.StockCode && q.StockName != "" {
+		item.StockName = q.StockName
+	}
+	item.ChangePercent = q.ChangePercent
+	price, err := strconv.ParseFloat(strings.TrimSpace(q.CurrentPrice), 64)
+	if err != nil || price <= 0 {
+		return
+	}
+	item.CurrentPrice = q.CurrentPrice
+
+	pctTo := func(target int64) *float64 {
+		if target <= 0 {
+			return nil
+		}
+		v := roundPct((model.HaoToYuan(target) - price) / price * 100)
+		return &v
+	}
+	item.ToBuyTargetPct = pctTo(w.TargetBuyPrice)
+	item.ToSellTargetPct = pctTo(w.TargetSellPrice)
+	if w.AddedPrice > 0 {
+		added := model.HaoToYuan(w.AddedPrice)
+		v := roundPct((price - added) / added * 100)
+		item.SinceAddedPct = &v
+	}
+}
+
+// RemoveFromWatchlistParam 移出自选股参数
+type RemoveFromWatchlistParam struct {
+	StockCode string `json:"stock_code,omitempty" jsonschema:"description=要移出自选股的股票代码，同时删除其全部提醒规则"`
+	AlertID   int64  `json:"alert_id,omitempty" jsonschema:"description=只删除指定ID的提醒规则（与 stock_code 二选一）；删除目标价提醒时同时清除该目标价"`
+}
+
+// RemoveFromWatchlistTool 移出自选股工具
+type RemoveFromWatchlistTool struct{}
+
+func (t *RemoveFromWatchlistTool) GetToolInfo() (tool.BaseTool, error) {
+	return utils.InferTool(t.GetName(), t.GetDescription(), RemoveFromWatchlist)
+}
+
+func (t *RemoveFromWatchlistTool) GetName() string {
+	return "remove_from_watchlist"
+}
+
+func (t *RemoveFromWatchlistTool) GetDescription() string {
+	return "将股票移出自选股（同时删除其提醒规则），或只删除某条提醒规则 | Remove a stock and its alerts from the watchlist, or delete a single alert rule"
+}
+
+func (t *RemoveFromWatchlistTool) GetToolGroup() model.ToolGroup {
+	return model.StockToolGroup
+}
+
+// RemoveFromWatchlist 移出自选股或删除提醒规则
+func RemoveFromWatchlist(ctx context.Context, param *RemoveFromWatchlistParam) (string, error) {
+	return safetool.SafeExecute("remove_from_watchlist", fmt.Sprintf("stock_code: %s, alert_id: %d", param.StockCode, param.AlertID), func() (string, error) {
+		return doRemoveFromWatchlist(msadb.GetDB(), param)
+	})
+}
+
+func doRemoveFromWatchlist(database *gorm.DB, param *RemoveFromWatchlistParam) (string, error) {
+	if database == nil {
+		err := fmt.Errorf("数据库未初始化")
+		return model.NewErrorResult(err.Error()), nil
+	}
+
+	if param.AlertID > 0 {
+		alert, err := watchlist.RemoveAlert(database, uint(param.AlertID))
+		if err != nil {
+			return model.NewErrorResult(err.Error()), nil
+		}
+		return model.NewSuccessResult(toWatchAlertItem(alert), fmt.Sprintf("已删除 %s 的提醒规则 %d", alert.StockCode, alert.ID)), nil
+	}
+
+	if strings.TrimSpace(param.StockCode) == "" {
+		return model.NewErrorResult("stock_code or alert_id is required"), nil
+	}
+	if err := watchlist.Remove(database, param.StockCode); err != nil {
+		return model.NewErrorResult(err.Error()), nil
+	}
+	return model.NewSuccessResult(nil, fmt.Sprintf("已将 %s 移出自选股", watchlist.NormalizeCode(param.StockCode))), nil
+}
+
+// toWatchlistItem 自选股转换为返回数据
+func toWatchlistItem(w *model.Watchlist, alerts []*model.WatchAlert) WatchlistItem {
+	item := WatchlistItem{
+		StockCode: w.StockCode,
+		StockName: w.StockName,
+		Notes:     w.Notes,
+		Tags:      w.TagList(),
+		AddedAt:   w.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+	if w.TargetBuyPrice > 0 {
+		item.TargetBuyPrice = formatHaoToYuan(w.TargetBuyPrice)
+	}
+	if w.TargetSellPrice > 0 {
+		item.TargetSellPrice = formatHaoToYuan(w.TargetSellPrice)
+	}
+	if w.AddedPrice > 0 {
+		item.AddedPrice = formatHaoToYuan(w.AddedPrice)
+	}
+	for _, alert := range alerts {
+		item.Alerts = append(item.Alerts, toWatchAlertItem(alert))
+	}
+	return item
+}
+
+// toWatchAlertItem 提醒规则转换为返回数据
+func toWatchAlertItem(alert *model.WatchAlert) WatchAlertItem {
+	item := WatchAlertItem{
+		ID:           int64(alert.ID),
+		Type:         string(alert.Type),
+		Status:       string(alert.Status),
+		Description:  watchlist.DescribeAlert(alert),
+		Value:        alert.Value,
+		Repeat:       alert.Repeat,
+		Origin:       alert.Origin,
+		TriggerCount: alert.TriggerCount,
+		Note:         alert.Note,
+	}
+	if alert.Price > 0 {
+		item.Price = formatHaoToYuan(alert.Price)
+	}
+	if alert.TriggeredAt != nil {
+		item.TriggeredAt = alert.TriggeredAt.Format("2006-01-02 15:04:05")
+	}
+	return item
+}
+
+// fetchCurrentPrice 获取当前价（毫），获取失败返回 0
+func fetchCurrentPrice(stockCode string) int64 {
+	resp, err := stock.FetchStockData(stockCode)
+	if err != nil {
+		log.Warnf("获取当前价失败: stockCode=%s, err=%v", stockCode, err)
+		return 0
+	}
+	price, err := strconv.ParseFloat(strings.TrimSpace(resp.CurrentPrice), 64)
+	if err != nil {
+		return 0
+	}
+	return model.YuanToHao(price)
+}
+
+// formatHaoToYuan 毫转元字符串
+func formatHaoToYuan(hao int64) string {
+	return strconv.FormatFloat(model.HaoToYuan(hao), 'f', -1, 64)
+}
+
+// roundPct 百分比保留两位小数
+func roundPct(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// unmarshalEmptyParam 兼容空参数调用
+func unmarshalEmptyParam[T any](ctx context.Context, arguments string) (interface{}, error) {
+	inst := new(T)
+	s := strings.TrimSpace(arguments)
+	if s == "" || s == "{}" {
+		return inst, nil
+	}
+	if err := json.Unmarshal([]byte(arguments), inst); err != nil {
+		return nil, err
+	}
+	return inst, nil
+}
@@ -0,0 +1,103 @@
+package watch
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/tool"
+	"gorm.io/gorm"
+
+	msadb "msa/pkg/db"
+	"msa/pkg/model"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	database, err := msadb.InitDBWithPath(filepath.Join(t.TempDir(), "test.sqlite"))
+	if err != nil {
+		t.Fatalf("InitDBWithPath failed: %v", err)
+	}
+	if err := msadb.Migrate(database); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	t.Cleanup(func() { msadb.CloseDB(database) })
+	return database
+}
+
+func decodeData[T any](t *testing.T, result string) T {
+	t.Helper()
+	var resp struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+		Data    T      `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(result), &resp); err != nil {
+		t.Fatalf("invalid result %s: %v", result, err)
+	}
+	if !resp.Success {
+		t.Fatalf("unexpected failure: %s", result)
+	}
+	return resp.Data
+}
+
+func TestWatchlistTools(t *testing.T) {
+	for _, info := range []interface {
+		GetName() string
+		GetToolInfo() (tool.BaseTool, error)
+	}{&AddToWatchlistTool{}, &GetWatchlistTool{}, &RemoveFromWatchlistTool{}} {
+		if _, err := info.GetToolInfo(); err != nil {
+			t.Errorf("%s GetToolInfo failed: %v", info.GetName(), err)
+		}
+	}
+
+	if result, _ := doAddToWatchlist(nil, &AddToWatchlistParam{StockCode: "sh600519"}, nil); !strings.Contains(result, `"success": false`) {
+		t.Errorf("expected failure without database: %s", result)
+	}
+
+	database := setupTestDB(t)
+	price := func(string) int64 { return model.YuanToHao(1600) }
+	result, _ := doAddToWatchlist(database, &AddToWatchlistParam{
+		StockCode: "sh600519", StockName: "贵州茅台", Tags: "白酒，消费", TargetBuyPrice: 1500,
+		Alerts: []WatchAlertParam{{Type: "volume_spike", Value: 3}, {Type: "price_above"}},
+	}, price)
+	added := decodeData[WatchlistItem](t, result)
+	if added.AddedPrice != "1600" || len(added.Tags) != 2 || len(added.Alerts) != 2 || added.Alerts[0].Origin != model.WatchAlertOriginTargetBuy {
+		t.Errorf("unexpected added item: %+v", added)
+	}
+	if !strings.Contains(result, "以下提醒未创建") {
+		t.Errorf("expected invalid alert reported: %s", result)
+	}
+
+	quotes := func(codes []string) (map[string]*model.StockQuote, error) {
+		return map[string]*model.StockQuote{"sh600519": {StockName: "贵州茅台", CurrentPrice: "1650.00", ChangePercent: "1.20"}}, nil
+	}
+	list := decodeData[WatchlistData](t, mustResult(doGetWatchlist(database, &GetWatchlistParam{Tag: "消费"}, quotes)))
+	if list.Total != 1 {
+		t.Fatalf("unexpected list: %+v", list)
+	}
+	item := list.Items[0]
+	if item.CurrentPrice != "1650.00" || *item.SinceAddedPct != 3.13 || *item.ToBuyTargetPct != -9.09 || item.ToSellTargetPct != nil {
+		t.Errorf("unexpected quote fields: %+v", item)
+	}
+
+	// 删除目标价提醒后再移出自选股
+	decodeData[WatchAlertItem](t, mustResult(doRemoveFromWatchlist(database, &RemoveFromWatchlistParam{AlertID: added.Alerts[0].ID})))
+	list = decodeData[WatchlistData](t, mustResult(doGetWatchlist(database, &GetWatchlistParam{}, quotes)))
+	if list.Items[0].TargetBuyPrice != "" || len(list.Items[0].Alerts) != 1 {
+		t.Errorf("unexpected item after alert removal: %+v", list.Items[0])
+	}
+	mustResult(doRemoveFromWatchlist(database, &RemoveFromWatchlistParam{StockCode: "SH600519"}))
+	if result, _ := doRemoveFromWatchlist(database, &RemoveFromWatchlistParam{StockCode: "sh600519"}); !strings.Contains(result, `"success": false`) {
+		t.Errorf("expected failure for missing stock: %s", result)
+	}
+}
+
+func mustResult(result string, err error) string {
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
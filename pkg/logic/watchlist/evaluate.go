@@ -0,0 +1,174 @@
+package watchlist
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"msa/pkg/db"
+	"msa/pkg/logic/finsvc"
+	"msa/pkg/model"
+)
+
+// chinaLocation 交易所所在时区（UTC+8），涨跌幅与放量规则按该时区的自然日去重
+var chinaLocation = time.FixedZone("CST", 8*3600)
+
+// Observation 提醒规则评估使用的行情
+type Observation struct {
+	Price     int64   // 最新价（毫）
+	PrevClose int64   // 昨收价（毫），用于计算当日涨跌幅
+	Volume    float64 // 当日成交量（手）
+	AvgVolume float64 // 近 5 日平均成交量（手），0 表示未知
+	// Path 当日分时价格（按时间升序），价格穿越只检查上次评估之后的价格点
+	Path []finsvc.PricePoint
+}
+
+// ChangePct 当日涨跌幅（%），昨收未知时返回 false
+func (o Observation) ChangePct() (float64, bool) {
+	if o.PrevClose <= 0 || o.Price <= 0 {
+		return 0, false
+	}
+	return float64(o.Price-o.PrevClose) / float64(o.PrevClose) * 100, true
+}
+
+// Triggered 一次提醒触发
+type Triggered struct {
+	AlertID   uint
+	StockCode string
+	StockName string
+	Type      model.WatchAlertType
+	Price     int64 // 触发时价格（毫）
+	At        time.Time
+	Message   string
+}
+
+// Evaluate 按行情评估所有监控中的提醒规则，返回本次触发的提醒
+// 价格穿越以上次观察价为起点判断，首次观察只记录价格；一次性规则触发后不再监控
+// 涨跌幅与放量规则每个交易日最多触发一次
+func Evaluate(database *gorm.DB, observations map[string]Observation, at time.Time) ([]Triggered, error) {
+	alerts, err := db.GetWatchAlerts(database, "", model.WatchAlertStatusActive)
+	if err != nil {
+		log.Errorf("查询提醒规则失败: %v", err)
+		return nil, err
+	}
+	if len(alerts) == 0 {
+		return nil, nil
+	}
+
+	items, err := db.ListWatchlist(database)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(items))
+	for _, item := range items {
+		names[item.StockCode] = item.StockName
+	}
+
+	var triggered []Triggered
+	for _, alert := range alerts {
+		obs, ok := observations[alert.StockCode]
+		if !ok || obs.Price <= 0 {
+			continue
+		}
+
+		detail, fired := checkAlert(alert, obs, at)
+		alert.LastPrice = obs.Price
+		checkedAt := at
+		alert.CheckedAt = &checkedAt
+		if fired {
+			alert.TriggeredAt = &checkedAt
+			alert.TriggerCount++
+			if !alert.Repeat && isPriceAlert(alert.Type) {
+				alert.Status = model.WatchAlertStatusTriggered
+			}
+
+			name := names[alert.StockCode]
+			if name == "" {
+				name = alert.StockCode
+			}
+			message := fmt.Sprintf("%s(%s) %s", name, alert.StockCode, detail)
+			if alert.Note != "" {
+				message += "，" + alert.Note
+			}
+			log.Infof("自选股提醒触发: ID=%d, %s", alert.ID, message)
+			triggered = append(triggered, Triggered{
+				AlertID:   alert.ID,
+				StockCode: alert.StockCode,
+				StockName: name,
+				Type:      alert.Type,
+				Price:     obs.Price,
+				At:        at,
+				Message:   message,
+			})
+		}
+
+		if err := db.SaveWatchAlert(database, alert); err != nil {
+			log.Errorf("更新提醒规则失败: %v", err)
+			return triggered, err
+		}
+	}
+	return triggered, nil
+}
+
+func isPriceAlert(t model.WatchAlertType) bool {
+	return t == model.WatchAlertTypePriceAbove || t == model.WatchAlertTypePriceBelow
+}
+
+// checkAlert 判断单条规则是否触发，返回触发说明
+func checkAlert(alert *model.WatchAlert, obs Observation, at time.Time) (string, bool) {
+	switch alert.Type {
+	case model.WatchAlertTypePriceAbove, model.WatchAlertTypePriceBelow:
+		return checkCross(alert, obs, at)
+	case model.WatchAlertTypeChangePct:
+		pct, ok := obs.ChangePct()
+		if ok && math.Abs(pct) >= alert.Value && !triggeredOnDay(alert, at) {
+			return fmt.Sprintf("现价 %s 元，当日涨跌幅 %+.2f%%，超过 ±%.2f%%", formatPrice(obs.Price), pct, alert.Value), true
+		}
+	case model.WatchAlertTypeVolumeSpike:
+		if obs.AvgVolume > 0 && obs.Volume >= alert.Value*obs.AvgVolume && !triggeredOnDay(alert, at) {
+			return fmt.Sprintf("现价 %s 元，成交量 %.0f 手，为 5 日均量的 %.1f 倍", formatPrice(obs.Price), obs.Volume, obs.Volume/obs.AvgVolume), true
+		}
+	}
+	return "", false
+}
+
+// checkCross 按时间顺序用上次评估之后的分时价格与最新价判断价格穿越
+func checkCross(alert *model.WatchAlert, obs Observation, at time.Time) (string, bool) {
+	points := make([]finsvc.PricePoint, 0, len(obs.Path)+1)
+	for _, p := range obs.Path {
+		if (alert.CheckedAt == nil || p.At.After(*alert.CheckedAt)) && p.At.Before(at) {
+			points = append(points, p)
+		}
+	}
+	points = append(points, finsvc.PricePoint{At: at, Price: obs.Price})
+
+	last := alert.LastPrice
+	for i, p := range points {
+		crossed := last > 0 && (alert.Type == model.WatchAlertTypePriceAbove && last < alert.Price && p.Price >= alert.Price ||
+			alert.Type == model.WatchAlertTypePriceBelow && last > alert.Price && p.Price <= alert.Price)
+		if crossed {
+			verb := "上穿"
+			if alert.Type == model.WatchAlertTypePriceBelow {
+				verb = "下穿"
+			}
+			detail := fmt.Sprintf("现价 %s 元，%s %s 元", formatPrice(obs.Price), verb, formatPrice(alert.Price))
+			if i < len(points)-1 {
+				detail += fmt.Sprintf("（%s 分时价 %s 元）", p.At.In(chinaLocation).Format("15:04"), formatPrice(p.Price))
+			}
+			return detail, true
+		}
+		last = p.Price
+	}
+	return "", false
+}
+
+// triggeredOnDay 规则是否已在 at 所在的交易日触发过
+func triggeredOnDay(alert *model.WatchAlert, at time.Time) bool {
+	if alert.TriggeredAt == nil {
+		return false
+	}
+	return alert.TriggeredAt.In(chinaLocation).Format(time.DateOnly) == at.In(chinaLocation).Format(time.DateOnly)
+}
@@ -0,0 +1,201 @@
+package watchlist
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"msa/pkg/db"
+	"msa/pkg/logic/finsvc"
+	"msa/pkg/model"
+)
+
+// DefaultInterval 监控默认检查间隔
+const DefaultInterval = time.Minute
+
+// volumeAverageDays 放量规则比较的均量天数
+const volumeAverageDays = 5
+
+// QuoteFunc 获取实时行情
+type QuoteFunc func(stockCode string) (*model.StockCurrentResp, error)
+
+// KLineFunc 获取最近 count 根日K线
+type KLineFunc func(stockCode string, count int) ([]model.KLineBar, error)
+
+// MinuteFunc 获取当日分时数据
+type MinuteFunc func(stockCode string) (*model.StockMinuteKResp, error)
+
+// Monitor 定时按实时行情评估自选股提醒规则
+// 只在股票所属市场的交易时段内检查；均量每只股票每天只计算一次
+type Monitor struct {
+	quote  QuoteFunc
+	klines KLineFunc
+	minute MinuteFunc
+
+	mu        sync.Mutex
+	avgVolume map[string]float64 // key: 日期|股票代码
+}
+
+// NewMonitor 创建监控器
+func NewMonitor(quote QuoteFunc, klines KLineFunc) *Monitor {
+	return &Monitor{quote: quote, klines: klines, avgVolume: map[string]float64{}}
+}
+
+// WithMinute 设置分时数据来源：价格穿越同时检查两次评估之间的分时价格，检查间隔较长时不漏掉盘中穿越
+func (m *Monitor) WithMinute(minute MinuteFunc) *Monitor {
+	m.minute = minute
+	return m
+}
+
+// Check 获取监控中规则涉及股票的行情并评估一次，非交易时段的股票跳过
+func (m *Monitor) Check(database *gorm.DB, now time.Time) ([]Triggered, error) {
+	alerts, err := db.GetWatchAlerts(database, "", model.WatchAlertStatusActive)
+	if err != nil {
+		return nil, err
+	}
+
+	// 按股票汇总需要的数据：放量规则需要均量，价格规则需要分时
+	needs := map[string]*dataNeeds{}
+	var codes []string
+	for _, alert := range alerts {
+		need, seen := needs[alert.StockCode]
+		if !seen {
+			need = &dataNeeds{}
+			needs[alert.StockCode] = need
+			codes = append(codes, alert.StockCode)
+		}
+		need.volume = need.volume || alert.Type == model.WatchAlertTypeVolumeSpike
+		need.path = need.path || isPriceAlert(alert.Type)
+	}
+
+	observations := make(map[string]Observation, len(codes))
+	for _, code := range codes {
+		if !finsvc.IsTradingSession(finsvc.DetectBoard(code), now) {
+			continue
+		}
+		obs, err := m.observe(code, *needs[code], now)
+		if err != nil {
+			log.Warnf("获取自选股行情失败，跳过: stockCode=%s, err=%v", code, err)
+			continue
+		}
+		observations[code] = obs
+	}
+	if len(observations) == 0 {
+		return nil, nil
+	}
+	return Evaluate(database, observations, now)
+}
+
+// dataNeeds 单只股票除实时行情外需要获取的数据
+type dataNeeds struct {
+	volume bool // 近 5 日均量
+	path   bool // 当日分时价格
+}
+
+// observe 获取单只股票的行情，并按需计算近 5 日均量、获取分时价格
+func (m *Monitor) observe(stockCode string, need dataNeeds, now time.Time) (Observation, error) {
+	resp, err := m.quote(stockCode)
+	if err != nil {
+		return Observation{}, err
+	}
+	obs := Observation{
+		Price:     parseHao(resp.CurrentPrice),
+		PrevClose: parseHao(resp.PrevClose),
+		Volume:    parseNumber(resp.VolumeByLot),
+	}
+	if need.volume && m.klines != nil {
+		obs.AvgVolume = m.averageVolume(stockCode, now)
+	}
+	if need.path && m.minute != nil {
+		if minute, err := m.minute(stockCode); err == nil {
+			obs.Path = finsvc.MinutePricePath(minute)
+		} else {
+			log.Warnf("获取分时数据失败，仅按最新价判断价格穿越: stockCode=%s, err=%v", stockCode, err)
+		}
+	}
+	return obs, nil
+}
+
+// averageVolume 今日之前 5 个交易日的平均成交量（手），获取失败返回 0
+func (m *Monitor) averageVolume(stockCode string, now time.Time) float64 {
+	today := now.In(chinaLocation).Format(time.DateOnly)
+	key := today + "|" + stockCode
+
+	m.mu.Lock()
+	avg, ok := m.avgVolume[key]
+	m.mu.Unlock()
+	if ok {
+		return avg
+	}
+
+	bars, err := m.klines(stockCode, volumeAverageDays+1)
+	if err != nil {
+		log.Warnf("获取K线失败，无法计算均量: stockCode=%s, err=%v", stockCode, err)
+		return 0
+	}
+	var sum float64
+	var n int
+	for i := len(bars) - 1; i >= 0 && n < volumeAverageDays; i-- {
+		// 盘中K线已包含今日，均量只统计之前的交易日
+		if bars[i].Date >= today {
+			continue
+		}
+		sum += parseNumber(bars[i].Volume)
+		n++
+	}
+	if n > 0 {
+		avg = sum / float64(n)
+	}
+
+	m.mu.Lock()
+	m.avgVolume[key] = avg
+	m.mu.Unlock()
+	return avg
+}
+
+// Run 按间隔循环检查，直到 ctx 结束；每次触发的提醒交给 emit
+// 数据库未初始化时跳过检查
+func (m *Monitor) Run(ctx context.Context, interval time.Duration, emit func(Triggered)) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			database := db.GetDB()
+			if database == nil {
+				continue
+			}
+			triggered, err := m.Check(database, now)
+			if err != nil {
+				log.Errorf("自选股提醒检查失败: %v", err)
+			}
+			for _, t := range triggered {
+				emit(t)
+			}
+		}
+	}
+}
+
+// parseHao 解析元为单位的价格为毫，无法解析返回 0
+func parseHao(value string) int64 {
+	return model.YuanToHao(parseNumber(value))
+}
+
+func parseNumber(value string) float64 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
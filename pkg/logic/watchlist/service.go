@@ -0,0 +1,328 @@
+// Package watchlist 自选股与提醒规则：维护自选股、按行情评估价格穿越、涨跌幅与放量规则
+package watchlist
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"msa/pkg/db"
+	"msa/pkg/model"
+)
+
+const (
+	// DefaultChangePct 涨跌幅规则默认阈值（%）
+	DefaultChangePct = 5.0
+	// DefaultVolumeMultiple 放量规则默认倍数
+	DefaultVolumeMultiple = 2.0
+)
+
+// EntrySpec 加入或更新自选股参数
+// 字符串为空、价格为 0 的字段保持不变；Tags 为 "-" 时清空标签
+type EntrySpec struct {
+	StockCode       string
+	StockName       string
+	Notes           string
+	Tags            string
+	TargetBuyPrice  int64 // 目标买入价（毫），生成向下穿越提醒
+	TargetSellPrice int64 // 目标卖出价（毫），生成向上穿越提醒
+	CurrentPrice    int64 // 当前价（毫），新加入时记为加入价，并作为价格穿越的起点
+}
+
+// AlertSpec 创建提醒规则参数
+type AlertSpec struct {
+	StockCode    string
+	Type         model.WatchAlertType
+	Price        int64   // 价格穿越规则的目标价（毫）
+	Value        float64 // 涨跌幅阈值（%）或放量倍数，为 0 时使用默认值
+	Repeat       bool
+	CurrentPrice int64 // 当前价（毫），作为价格穿越的起点
+	Note         string
+}
+
+// Entry 自选股及其提醒规则
+type Entry struct {
+	Item   *model.Watchlist
+	Alerts []*model.WatchAlert
+}
+
+// NormalizeCode 统一股票代码写法（去空白、小写）
+func NormalizeCode(stockCode string) string {
+	return strings.ToLower(strings.TrimSpace(stockCode))
+}
+
+// Upsert 加入自选股，已存在时更新备注、标签与目标价
+// 目标价变化时同步替换对应的价格穿越提醒，返回是否为新加入
+func Upsert(database *gorm.DB, spec EntrySpec) (*model.Watchlist, bool, error) {
+	code := NormalizeCode(spec.StockCode)
+	if code == "" {
+		return nil, false, fmt.Errorf("股票代码不能为空")
+	}
+	if spec.TargetBuyPrice < 0 || spec.TargetSellPrice < 0 {
+		return nil, false, fmt.Errorf("目标价不能为负数")
+	}
+
+	var item *model.Watchlist
+	created := false
+	err := database.Transaction(func(tx *gorm.DB) error {
+		existing, err := db.GetWatchlistByCode(tx, code)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			existing = &model.Watchlist{StockCode: code, StockName: code, AddedPrice: spec.CurrentPrice}
+			created = true
+		}
+		item = existing
+
+		if spec.StockName != "" {
+			item.StockName = spec.StockName
+		}
+		if spec.Notes != "" {
+			item.Notes = spec.Notes
+		}
+		switch strings.TrimSpace(spec.Tags) {
+		case "":
+		case "-":
+			item.Tags = ""
+		default:
+			item.Tags = strings.Join(model.SplitTags(spec.Tags), ",")
+		}
+		if spec.TargetBuyPrice > 0 {
+			item.TargetBuyPrice = spec.TargetBuyPrice
+		}
+		if spec.TargetSellPrice > 0 {
+			item.TargetSellPrice = spec.TargetSellPrice
+		}
+		if err := db.SaveWatchlist(tx, item); err != nil {
+			return err
+		}
+
+		if spec.TargetBuyPrice > 0 {
+			if err := syncTargetAlert(tx, code, model.WatchAlertOriginTargetBuy, spec.TargetBuyPrice, spec.CurrentPrice); err != nil {
+				return err
+			}
+		}
+		if spec.TargetSellPrice > 0 {
+			return syncTargetAlert(tx, code, model.WatchAlertOriginTargetSell, spec.TargetSellPrice, spec.CurrentPrice)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Errorf("更新自选股失败: stockCode=%s, err=%v", code, err)
+		return nil, false, err
+	}
+
+	log.Infof("自选股已更新: %s(%s), 新加入=%v", item.StockName, code, created)
+	return item, created, nil
+}
+
+// syncTargetAlert 按目标价创建或替换由目标价生成的提醒
+// 目标买入价在价格向下穿越时提醒，目标卖出价在向上穿越时提醒
+func syncTargetAlert(tx *gorm.DB, stockCode, origin string, price, currentPrice int64) error {
+	alertType := model.WatchAlertTypePriceBelow
+	note := "到达目标买入价"
+	if origin == model.WatchAlertOriginTargetSell {
+		alertType = model.WatchAlertTypePriceAbove
+		note = "到达目标卖出价"
+	}
+
+	alerts, err := db.GetWatchAlerts(tx, stockCode, "")
+	if err != nil {
+		return err
+	}
+	for _, alert := range alerts {
+		if alert.Origin != origin {
+			continue
+		}
+		alert.Price = price
+		alert.Status = model.WatchAlertStatusActive
+		alert.LastPrice = currentPrice
+		return db.SaveWatchAlert(tx, alert)
+	}
+
+	_, err = db.CreateWatchAlert(tx, &model.WatchAlert{
+		StockCode: stockCode,
+		Type:      alertType,
+		Status:    model.WatchAlertStatusActive,
+		Price:     price,
+		Origin:    origin,
+		LastPrice: currentPrice,
+		Note:      note,
+	})
+	return err
+}
+
+// AddAlert 为自选股添加提醒规则
+func AddAlert(database *gorm.DB, spec AlertSpec) (*model.WatchAlert, error) {
+	code := NormalizeCode(spec.StockCode)
+	item, err := db.GetWatchlistByCode(database, code)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, fmt.Errorf("股票 %s 不在自选股中，请先加入自选股", code)
+	}
+
+	alert := &model.WatchAlert{
+		StockCode: code,
+		Type:      model.WatchAlertType(strings.ToUpper(string(spec.Type))),
+		Status:    model.WatchAlertStatusActive,
+		Price:     spec.Price,
+		Value:     spec.Value,
+		Repeat:    spec.Repeat,
+		LastPrice: spec.CurrentPrice,
+		Note:      spec.Note,
+	}
+	switch alert.Type {
+	case model.WatchAlertTypePriceAbove, model.WatchAlertTypePriceBelow:
+		if alert.Price <= 0 {
+			return nil, fmt.Errorf("价格提醒需要大于 0 的目标价")
+		}
+		alert.Value = 0
+	case model.WatchAlertTypeChangePct:
+		if alert.Value < 0 {
+			return nil, fmt.Errorf("涨跌幅阈值不能为负数")
+		}
+		if alert.Value == 0 {
+			alert.Value = DefaultChangePct
+		}
+		alert.Price = 0
+	case model.WatchAlertTypeVolumeSpike:
+		if alert.Value == 0 {
+			alert.Value = DefaultVolumeMultiple
+		}
+		if alert.Value <= 1 {
+			return nil, fmt.Errorf("放量倍数必须大于 1")
+		}
+		alert.Price = 0
+	default:
+		return nil, fmt.Errorf("不支持的提醒类型: %s", spec.Type)
+	}
+
+	if _, err := db.CreateWatchAlert(database, alert); err != nil {
+		log.Errorf("创建提醒规则失败: %v", err)
+		return nil, err
+	}
+	log.Infof("提醒规则已创建: ID=%d, 股票=%s, 类型=%s", alert.ID, code, alert.Type)
+	return alert, nil
+}
+
+// Remove 从自选股中删除股票及其提醒规则
+func Remove(database *gorm.DB, stockCode string) error {
+	code := NormalizeCode(stockCode)
+	item, err := db.GetWatchlistByCode(database, code)
+	if err != nil {
+		return err
+	}
+	if item == nil {
+		return fmt.Errorf("股票 %s 不在自选股中", code)
+	}
+
+	if err := database.Transaction(func(tx *gorm.DB) error {
+		return db.DeleteWatchlist(tx, code)
+	}); err != nil {
+		log.Errorf("删除自选股失败: %v", err)
+		return err
+	}
+	log.Infof("自选股已删除: %s", code)
+	return nil
+}
+
+// RemoveAlert 删除提醒规则，删除目标价生成的提醒时同时清除对应目标价
+func RemoveAlert(database *gorm.DB, alertID uint) (*model.WatchAlert, error) {
+	alert, err := db.GetWatchAlertByID(database, alertID)
+	if err != nil {
+		return nil, fmt.Errorf("提醒规则 %d 不存在", alertID)
+	}
+
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := db.DeleteWatchAlert(tx, alert.ID); err != nil {
+			return err
+		}
+		if alert.Origin == "" {
+			return nil
+		}
+		item, err := db.GetWatchlistByCode(tx, alert.StockCode)
+		if err != nil || item == nil {
+			return err
+		}
+		switch alert.Origin {
+		case model.WatchAlertOriginTargetBuy:
+			item.TargetBuyPrice = 0
+		case model.WatchAlertOriginTargetSell:
+			item.TargetSellPrice = 0
+		}
+		return db.SaveWatchlist(tx, item)
+	})
+	if err != nil {
+		log.Errorf("删除提醒规则失败: %v", err)
+		return nil, err
+	}
+	log.Infof("提醒规则已删除: ID=%d, 股票=%s", alert.ID, alert.StockCode)
+	return alert, nil
+}
+
+// List 查询自选股及其提醒规则，tag 非空时只返回带该标签的股票
+func List(database *gorm.DB, tag string) ([]Entry, error) {
+	items, err := db.ListWatchlist(database)
+	if err != nil {
+		return nil, err
+	}
+	alerts, err := db.GetWatchAlerts(database, "", "")
+	if err != nil {
+		return nil, err
+	}
+	byCode := map[string][]*model.WatchAlert{}
+	for _, alert := range alerts {
+		byCode[alert.StockCode] = append(byCode[alert.StockCode], alert)
+	}
+
+	tag = strings.TrimSpace(tag)
+	entries := make([]Entry, 0, len(items))
+	for _, item := range items {
+		if tag != "" && !hasTag(item, tag) {
+			continue
+		}
+		entries = append(entries, Entry{Item: item, Alerts: byCode[item.StockCode]})
+	}
+	return entries, nil
+}
+
+func hasTag(item *model.Watchlist, tag string) bool {
+	for _, t := range item.TagList() {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// DescribeAlert 提醒规则的中文描述
+func DescribeAlert(alert *model.WatchAlert) string {
+	var desc string
+	switch alert.Type {
+	case model.WatchAlertTypePriceAbove:
+		desc = fmt.Sprintf("价格上穿 %s 元", formatPrice(alert.Price))
+	case model.WatchAlertTypePriceBelow:
+		desc = fmt.Sprintf("价格下穿 %s 元", formatPrice(alert.Price))
+	case model.WatchAlertTypeChangePct:
+		desc = fmt.Sprintf("当日涨跌幅超过 ±%.2f%%", alert.Value)
+	case model.WatchAlertTypeVolumeSpike:
+		desc = fmt.Sprintf("成交量达到 5 日均量 %.1f 倍", alert.Value)
+	default:
+		desc = string(alert.Type)
+	}
+	if alert.Repeat {
+		desc += "（重复提醒）"
+	}
+	return desc
+}
+
+// formatPrice 价格（毫）格式化为元，去掉末尾多余的 0
+func formatPrice(hao int64) string {
+	return strconv.FormatFloat(model.HaoToYuan(hao), 'f', -1, 64)
+}
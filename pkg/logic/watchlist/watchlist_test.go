@@ -0,0 +1,227 @@
+package watchlist
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	msadb "msa/pkg/db"
+	"msa/pkg/logic/finsvc"
+	"msa/pkg/model"
+)
+
+// testTradeTime 测试用时间（周一交易时段内，UTC+8）
+var testTradeTime = time.Date(2025, 3, 3, 10, 0, 0, 0, chinaLocation)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	database, err := msadb.InitDBWithPath(filepath.Join(t.TempDir(), "test.sqlite"))
+	if err != nil {
+		t.Fatalf("InitDBWithPath failed: %v", err)
+	}
+	if err := msadb.Migrate(database); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	t.Cleanup(func() { msadb.CloseDB(database) })
+	return database
+}
+
+func TestUpsertAndRemove(t *testing.T) {
+	database := setupTestDB(t)
+
+	item, created, err := Upsert(database, EntrySpec{
+		StockCode: " SH600519 ", StockName: "贵州茅台", Tags: "白酒, 消费,白酒",
+		TargetBuyPrice: model.YuanToHao(1500), CurrentPrice: model.YuanToHao(1600),
+	})
+	if err != nil || !created {
+		t.Fatalf("Upsert failed: %v, created=%v", err, created)
+	}
+	if item.StockCode != "sh600519" || item.Tags != "白酒,消费" || item.AddedPrice != model.YuanToHao(1600) {
+		t.Errorf("Unexpected item: %+v", item)
+	}
+
+	// 更新目标价替换原有提醒，未提供的字段保持不变
+	_, created, err = Upsert(database, EntrySpec{StockCode: "sh600519", TargetBuyPrice: model.YuanToHao(1450), TargetSellPrice: model.YuanToHao(1800)})
+	if err != nil || created {
+		t.Fatalf("Upsert update failed: %v, created=%v", err, created)
+	}
+	entries, _ := List(database, "消费")
+	if len(entries) != 1 || entries[0].Item.StockName != "贵州茅台" || len(entries[0].Alerts) != 2 {
+		t.Fatalf("Unexpected entries: %+v", entries)
+	}
+	buy := entries[0].Alerts[0]
+	if buy.Type != model.WatchAlertTypePriceBelow || buy.Price != model.YuanToHao(1450) || buy.Origin != model.WatchAlertOriginTargetBuy {
+		t.Errorf("Unexpected target buy alert: %+v", buy)
+	}
+	if entries, _ := List(database, "银行"); len(entries) != 0 {
+		t.Errorf("Expected no entries for other tag, got %d", len(entries))
+	}
+
+	// 删除目标价提醒时清除目标价
+	if _, err := RemoveAlert(database, buy.ID); err != nil {
+		t.Fatalf("RemoveAlert failed: %v", err)
+	}
+	stored, _ := msadb.GetWatchlistByCode(database, "sh600519")
+	if stored.TargetBuyPrice != 0 || stored.TargetSellPrice != model.YuanToHao(1800) {
+		t.Errorf("Unexpected targets after RemoveAlert: %+v", stored)
+	}
+
+	if _, err := AddAlert(database, AlertSpec{StockCode: "sz000001", Type: model.WatchAlertTypeChangePct}); err == nil {
+		t.Error("Expected error for stock not in watchlist")
+	}
+	if _, err := AddAlert(database, AlertSpec{StockCode: "sh600519", Type: model.WatchAlertTypeVolumeSpike, Value: 0.5}); err == nil {
+		t.Error("Expected error for volume multiple <= 1")
+	}
+	if _, err := AddAlert(database, AlertSpec{StockCode: "sh600519", Type: "UNKNOWN"}); err == nil {
+		t.Error("Expected error for unknown type")
+	}
+	alert, err := AddAlert(database, AlertSpec{StockCode: "sh600519", Type: "change_pct"})
+	if err != nil || alert.Value != DefaultChangePct {
+		t.Errorf("Unexpected change alert: %+v, %v", alert, err)
+	}
+
+	if err := Remove(database, "sh600519"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if alerts, _ := msadb.GetWatchAlerts(database, "", ""); len(alerts) != 0 {
+		t.Errorf("Expected alerts removed, got %d", len(alerts))
+	}
+	if err := Remove(database, "sh600519"); err == nil {
+		t.Error("Expected error for removing missing stock")
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	database := setupTestDB(t)
+	Upsert(database, EntrySpec{StockCode: "sh600519", StockName: "贵州茅台", TargetSellPrice: model.YuanToHao(1800)})
+	repeat, _ := AddAlert(database, AlertSpec{StockCode: "sh600519", Type: model.WatchAlertTypePriceBelow, Price: model.YuanToHao(1500), Repeat: true})
+	AddAlert(database, AlertSpec{StockCode: "sh600519", Type: model.WatchAlertTypeChangePct, Value: 3})
+	AddAlert(database, AlertSpec{StockCode: "sh600519", Type: model.WatchAlertTypeVolumeSpike})
+
+	obs := func(price, prevClose, volume, avg float64) map[string]Observation {
+		return map[string]Observation{"sh600519": {
+			Price: model.YuanToHao(price), PrevClose: model.YuanToHao(prevClose), Volume: volume, AvgVolume: avg,
+		}}
+	}
+	types := func(list []Triggered) string {
+		var names []string
+		for _, tr := range list {
+			names = append(names, string(tr.Type))
+		}
+		return strings.Join(names, ",")
+	}
+
+	// 首次观察只记录价格，不判断穿越
+	got, err := Evaluate(database, obs(1850, 1840, 100, 100), testTradeTime)
+	if err != nil || len(got) != 0 {
+		t.Fatalf("Expected no trigger on first observation, got %s, %v", types(got), err)
+	}
+
+	// 下穿 1800 不触发上穿规则；涨跌幅与放量触发
+	got, _ = Evaluate(database, obs(1750, 1840, 250, 100), testTradeTime.Add(time.Minute))
+	if types(got) != "CHANGE_PCT,VOLUME_SPIKE" {
+		t.Fatalf("Unexpected triggers: %s", types(got))
+	}
+	if !strings.Contains(got[0].Message, "贵州茅台(sh600519)") || !strings.Contains(got[0].Message, "-4.89%") {
+		t.Errorf("Unexpected message: %s", got[0].Message)
+	}
+
+	// 同一交易日不重复触发涨跌幅与放量；上穿目标卖出价触发后停止监控
+	got, _ = Evaluate(database, obs(1810, 1840, 300, 100), testTradeTime.Add(2*time.Minute))
+	if types(got) != "PRICE_ABOVE" {
+		t.Fatalf("Unexpected triggers: %s", types(got))
+	}
+	got, _ = Evaluate(database, obs(1750, 1840, 300, 100), testTradeTime.Add(3*time.Minute))
+	got2, _ := Evaluate(database, obs(1850, 1840, 300, 100), testTradeTime.Add(4*time.Minute))
+	if len(got) != 0 || len(got2) != 0 {
+		t.Fatalf("Expected no triggers, got %s / %s", types(got), types(got2))
+	}
+
+	// 重复规则每次穿越都触发；次日涨跌幅规则重新生效
+	nextDay := testTradeTime.AddDate(0, 0, 1)
+	got, _ = Evaluate(database, obs(1490, 1540, 100, 0), nextDay)
+	if types(got) != "PRICE_BELOW,CHANGE_PCT" {
+		t.Fatalf("Unexpected next day triggers: %s", types(got))
+	}
+	Evaluate(database, obs(1520, 1540, 100, 0), nextDay.Add(time.Minute))
+	got, _ = Evaluate(database, obs(1500, 1540, 100, 0), nextDay.Add(2*time.Minute))
+	if types(got) != "PRICE_BELOW" {
+		t.Fatalf("Expected repeat trigger, got %s", types(got))
+	}
+	stored, _ := msadb.GetWatchAlertByID(database, repeat.ID)
+	if stored.Status != model.WatchAlertStatusActive || stored.TriggerCount != 2 {
+		t.Errorf("Unexpected repeat alert: %+v", stored)
+	}
+}
+
+func TestEvaluate_MinutePath(t *testing.T) {
+	database := setupTestDB(t)
+	Upsert(database, EntrySpec{StockCode: "sz000001", StockName: "平安银行"})
+	AddAlert(database, AlertSpec{StockCode: "sz000001", Type: model.WatchAlertTypePriceBelow, Price: model.YuanToHao(10), Repeat: true, CurrentPrice: model.YuanToHao(11)})
+
+	point := func(minute int, price float64) finsvc.PricePoint {
+		return finsvc.PricePoint{At: testTradeTime.Add(time.Duration(minute) * time.Minute), Price: model.YuanToHao(price)}
+	}
+	path := []finsvc.PricePoint{point(5, 10.2), point(10, 9.8), point(15, 10.4)}
+	obs := map[string]Observation{"sz000001": {Price: model.YuanToHao(10.5), Path: path}}
+
+	// 两次检查之间的分时下穿也能触发
+	got, err := Evaluate(database, obs, testTradeTime.Add(20*time.Minute))
+	if err != nil || len(got) != 1 || !strings.Contains(got[0].Message, "10:10 分时价 9.8 元") {
+		t.Fatalf("Unexpected triggers: %+v, %v", got, err)
+	}
+
+	// 已检查过的分时价格不重复判断
+	got, _ = Evaluate(database, obs, testTradeTime.Add(25*time.Minute))
+	if len(got) != 0 {
+		t.Errorf("Expected no trigger for checked path, got %+v", got)
+	}
+}
+
+func TestMonitorCheck(t *testing.T) {
+	database := setupTestDB(t)
+	Upsert(database, EntrySpec{StockCode: "sh600519", StockName: "贵州茅台"})
+	Upsert(database, EntrySpec{StockCode: "hk00700", StockName: "腾讯控股"})
+	AddAlert(database, AlertSpec{StockCode: "sh600519", Type: model.WatchAlertTypeVolumeSpike, Value: 3})
+	AddAlert(database, AlertSpec{StockCode: "hk00700", Type: model.WatchAlertTypeChangePct})
+
+	quoteCalls, klineCalls := 0, 0
+	monitor := NewMonitor(func(code string) (*model.StockCurrentResp, error) {
+		quoteCalls++
+		if code == "hk00700" {
+			return nil, fmt.Errorf("network down")
+		}
+		return &model.StockCurrentResp{CurrentPrice: "1600.00", PrevClose: "1590.00", VolumeByLot: "35000"}, nil
+	}, func(code string, count int) ([]model.KLineBar, error) {
+		klineCalls++
+		bars := make([]model.KLineBar, 0, count)
+		for i := 0; i < count-1; i++ {
+			bars = append(bars, model.KLineBar{Date: fmt.Sprintf("2025-02-%02d", 20+i), Volume: "10000"})
+		}
+		// 盘中的今日K线不计入均量
+		return append(bars, model.KLineBar{Date: "2025-03-03", Volume: "35000"}), nil
+	})
+
+	got, err := monitor.Check(database, testTradeTime)
+	if err != nil || len(got) != 1 || got[0].Type != model.WatchAlertTypeVolumeSpike {
+		t.Fatalf("Unexpected check result: %+v, %v", got, err)
+	}
+	if !strings.Contains(got[0].Message, "3.5 倍") {
+		t.Errorf("Unexpected message: %s", got[0].Message)
+	}
+
+	// 均量当天缓存；非交易时段不获取行情
+	monitor.Check(database, testTradeTime.Add(time.Minute))
+	if klineCalls != 1 || quoteCalls != 4 {
+		t.Errorf("Unexpected calls: kline=%d quote=%d", klineCalls, quoteCalls)
+	}
+	monitor.Check(database, time.Date(2025, 3, 1, 10, 0, 0, 0, chinaLocation))
+	if quoteCalls != 4 {
+		t.Errorf("Expected no quotes outside trading session, got %d", quoteCalls)
+	}
+}
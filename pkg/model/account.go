@@ -20,9 +20,12 @@ const (
 
 // Account 账户模型
 // 所有金额字段以毫为单位存储，显示时需要转换
+// 同一用户可拥有多个以名称区分的账户（如按策略分仓），IsCurrent 标记工具默认操作的账户
 type Account struct {
 	gorm.Model
-	UserID        string        `gorm:"type:TEXT;uniqueIndex;not null" db:"user_id"`
+	UserID        string        `gorm:"type:TEXT;index;not null" db:"user_id"`
+	Name          string        `gorm:"type:TEXT;uniqueIndex;not null" db:"name"`           // 账户名称（唯一）
+	IsCurrent     bool          `gorm:"not null;default:false" db:"is_current"`             // 是否为当前账户
 	InitialAmount int64         `gorm:"type:INTEGER;not null" db:"initial_amount"`          // 投入金额（毫）
 	AvailableAmt  int64         `gorm:"type:INTEGER;not null;default:0" db:"available_amt"` // 可用金额（毫）
 	LockedAmt     int64         `gorm:"type:INTEGER;not null;default:0" db:"locked_amt"`    // 锁定金额（毫）
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+// CashFlowType 资金流水类型
+type CashFlowType string
+
+const (
+	// CashFlowTypeDeposit 入金
+	CashFlowTypeDeposit CashFlowType = "DEPOSIT"
+	// CashFlowTypeWithdrawal 出金
+	CashFlowTypeWithdrawal CashFlowType = "WITHDRAWAL"
+	// CashFlowTypeDividend 现金分红
+	CashFlowTypeDividend CashFlowType = "DIVIDEND"
+	// CashFlowTypeInterest 利息
+	CashFlowTypeInterest CashFlowType = "INTEREST"
+)
+
+// CashFlow 资金流水模型（交易以外的资金变动）
+// 金额以毫为单位，流入为正、流出为负
+type CashFlow struct {
+	gorm.Model
+	AccountID uint         `gorm:"type:INTEGER;not null;index" db:"account_id"`
+	Type      CashFlowType `gorm:"type:TEXT;not null;index" db:"type"`
+	Amount    int64        `gorm:"type:INTEGER;not null" db:"amount"` // 金额（毫），流入为正、流出为负
+	StockCode string       `gorm:"type:TEXT;index" db:"stock_code"`   // 分红对应的股票代码
+	Note      string       `gorm:"type:TEXT" db:"note"`               // 备注
+}
+
+// IsContribution 是否为本金变动（入金/出金），分红与利息计入收益
+func (c *CashFlow) IsContribution() bool {
+	return c.Type == CashFlowTypeDeposit || c.Type == CashFlowTypeWithdrawal
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ConditionalOrderType 条件单类型
+type ConditionalOrderType string
+
+const (
+	// ConditionalOrderTypeStopLoss 固定止损：价格跌破触发价时卖出
+	ConditionalOrderTypeStopLoss ConditionalOrderType = "STOP_LOSS"
+	// ConditionalOrderTypeTakeProfit 止盈：价格涨到触发价时卖出
+	ConditionalOrderTypeTakeProfit ConditionalOrderType = "TAKE_PROFIT"
+	// ConditionalOrderTypeTrailingStop 百分比跟踪止损：触发价 = 最高价 × (1 - 回撤比例)
+	ConditionalOrderTypeTrailingStop ConditionalOrderType = "TRAILING_STOP"
+	// ConditionalOrderTypeATRStop ATR 止损：触发价 = 最高价 - 倍数 × ATR
+	ConditionalOrderTypeATRStop ConditionalOrderType = "ATR_STOP"
+)
+
+// ConditionalOrderStatus 条件单状态
+type ConditionalOrderStatus string
+
+const (
+	// ConditionalOrderStatusActive 监控中（触发后卖出订单成交前仍为监控中）
+	ConditionalOrderStatusActive ConditionalOrderStatus = "ACTIVE"
+	// ConditionalOrderStatusTriggered 已触发且卖出订单已全部成交
+	ConditionalOrderStatusTriggered ConditionalOrderStatus = "TRIGGERED"
+	// ConditionalOrderStatusCancelled 已撤销
+	ConditionalOrderStatusCancelled ConditionalOrderStatus = "CANCELLED"
+)
+
+// ConditionalOrder 条件单模型
+// 挂在持仓上，按行情评估，触发后转为卖出订单；价格字段以毫为单位
+type ConditionalOrder struct {
+	gorm.Model
+	AccountID     uint                   `gorm:"type:INTEGER;not null;index" db:"account_id"`
+	StockCode     string                 `gorm:"type:TEXT;not null;index" db:"stock_code"`
+	StockName     string                 `gorm:"type:TEXT;not null" db:"stock_name"`
+	Type          ConditionalOrderType   `gorm:"type:TEXT;not null" db:"type"`
+	Status        ConditionalOrderStatus `gorm:"type:TEXT;not null;index;default:'ACTIVE'" db:"status"`
+	Quantity      int64                  `gorm:"type:INTEGER;not null" db:"quantity"`                 // 触发后卖出数量
+	LotSize       int64                  `gorm:"type:INTEGER;not null;default:0" db:"lot_size"`       // 每手股数（港股）
+	TriggerPrice  int64                  `gorm:"type:INTEGER;not null" db:"trigger_price"`            // 触发价（毫），跟踪止损与 ATR 止损随最高价上移
+	TrailPercent  float64                `gorm:"type:REAL;not null;default:0" db:"trail_percent"`     // 跟踪止损回撤比例（%）
+	ATR           int64                  `gorm:"column:atr;type:INTEGER;not null;default:0" db:"atr"` // ATR 值（毫）
+	ATRMultiplier float64                `gorm:"column:atr_multiplier;type:REAL;not null;default:0" db:"atr_multiplier"`
+	HighestPrice  int64                  `gorm:"type:INTEGER;not null;default:0" db:"highest_price"` // 创建以来最高价（毫）
+	CheckedAt     time.Time              `gorm:"not null" db:"checked_at"`                           // 最近一次评估的行情时间
+	TriggeredAt   *time.Time             `db:"triggered_at"`
+	TransactionID *uint                  `gorm:"type:INTEGER" db:"transaction_id"` // 触发后生成的卖出订单，未成交即撤销或过期时清空
+	Note          string                 `gorm:"type:TEXT" db:"note"`              // 备注
+}
+
+// IsStop 是否为下跌触发的止损类条件单
+func (o *ConditionalOrder) IsStop() bool {
+	return o.Type != ConditionalOrderTypeTakeProfit
+}
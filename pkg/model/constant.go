@@ -7,4 +7,5 @@ const (
 	FinanceKLineAPI                  = "https://web.ifzq.gtimg.cn/appstock/app/fqkline/get?param="
 	FinanceStockIndustry             = FinanceUrl + "ifzqgtimg/appstock/app/stockinfo/jiankuang?code="
 	FinanceBoardRank                 = FinanceUrl + "ifzqgtimg/appstock/app/mktHs/rank"
+	FinanceBatchQuote                = "https://qt.gtimg.cn/q="
 )
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+// CorporateActionType 公司行动类型
+type CorporateActionType string
+
+const (
+	// CorporateActionTypeDividend 现金分红
+	CorporateActionTypeDividend CorporateActionType = "DIVIDEND"
+	// CorporateActionTypeBonus 送股/转增（送转）
+	CorporateActionTypeBonus CorporateActionType = "BONUS"
+	// CorporateActionTypeSplit 拆股/合股
+	CorporateActionTypeSplit CorporateActionType = "SPLIT"
+)
+
+// CorporateAction 公司行动记录模型
+// 记录除权除息日对账户持仓的调整：送转/拆合股调整持仓数量，现金分红计入已实现盈亏
+type CorporateAction struct {
+	gorm.Model
+	AccountID      uint                `gorm:"type:INTEGER;not null;index:idx_action_account_stock,priority:1" db:"account_id"`
+	StockCode      string              `gorm:"type:TEXT;not null;index:idx_action_account_stock,priority:2" db:"stock_code"`
+	StockName      string              `gorm:"type:TEXT;not null" db:"stock_name"`
+	Type           CorporateActionType `gorm:"type:TEXT;not null" db:"type"`
+	CashPerShare   int64               `gorm:"type:INTEGER;not null;default:0" db:"cash_per_share"` // 每股派息（毫）
+	Ratio          float64             `gorm:"type:REAL;not null;default:0" db:"ratio"`             // 送转：每股送转股数；拆合股：每股变为股数
+	PositionBefore int64               `gorm:"type:INTEGER;not null" db:"position_before"`          // 除权前持仓数量
+	QuantityDelta  int64               `gorm:"type:INTEGER;not null;default:0" db:"quantity_delta"` // 持仓数量变化
+	CashAmount     int64               `gorm:"type:INTEGER;not null;default:0" db:"cash_amount"`    // 分红金额（毫）
+	Note           string              `gorm:"type:TEXT" db:"note"`                                 // 备注
+}
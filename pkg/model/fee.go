@@ -0,0 +1,43 @@
+package model
+
+// FeeSchedule 交易费率配置
+// 费率为小数（0.00025 表示万分之 2.5），最低收费单位为元
+type FeeSchedule struct {
+	CommissionRate    float64 `json:"commissionRate"`    // 佣金费率（买卖双向）
+	MinCommission     float64 `json:"minCommission"`     // 单笔最低佣金（元）
+	StampDutyRate     float64 `json:"stampDutyRate"`     // A股印花税费率（仅卖出）
+	TransferFeeRate   float64 `json:"transferFeeRate"`   // 沪市过户费费率（买卖双向）
+	HKStampDutyRate   float64 `json:"hkStampDutyRate"`   // 港股印花税费率（买卖双向，不足 1 港元按 1 港元计）
+	HKTradingLevyRate float64 `json:"hkTradingLevyRate"` // 港股证监会交易征费费率
+	HKTradingFeeRate  float64 `json:"hkTradingFeeRate"`  // 港交所交易费费率
+	HKFRCLevyRate     float64 `json:"hkFrcLevyRate"`     // 港股会财局交易征费费率
+}
+
+// DefaultFeeSchedule 默认费率
+// 佣金万 2.5（最低 5 元），A股印花税 0.05%（卖出），沪市过户费 0.001%，
+// 港股印花税 0.1%、证监会征费 0.0027%、交易费 0.00565%、会财局征费 0.00015%
+func DefaultFeeSchedule() FeeSchedule {
+	return FeeSchedule{
+		CommissionRate:    0.00025,
+		MinCommission:     5,
+		StampDutyRate:     0.0005,
+		TransferFeeRate:   0.00001,
+		HKStampDutyRate:   0.001,
+		HKTradingLevyRate: 0.000027,
+		HKTradingFeeRate:  0.0000565,
+		HKFRCLevyRate:     0.0000015,
+	}
+}
+
+// FeeBreakdown 交易费用明细（毫）
+type FeeBreakdown struct {
+	Commission  int64 // 佣金
+	StampDuty   int64 // 印花税
+	TransferFee int64 // 过户费
+	Levy        int64 // 港股交易征费、交易费等
+}
+
+// Total 费用合计（毫）
+func (f FeeBreakdown) Total() int64 {
+	return f.Commission + f.StampDuty + f.TransferFee + f.Levy
+}
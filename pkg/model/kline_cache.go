@@ -0,0 +1,40 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// KLineSeries K线缓存序列，每只股票每个周期、复权方式一条，记录缓存范围与同步时间
+type KLineSeries struct {
+	gorm.Model
+	StockCode       string    `gorm:"type:TEXT;not null;uniqueIndex:idx_kline_series,priority:1" db:"stock_code"`
+	Period          string    `gorm:"type:TEXT;not null;uniqueIndex:idx_kline_series,priority:2" db:"period"` // day/week/month
+	Adjust          string    `gorm:"type:TEXT;not null;uniqueIndex:idx_kline_series,priority:3" db:"adjust"` // qfq/hfq/空（不复权）
+	FirstDate       string    `gorm:"type:TEXT;not null" db:"first_date"`                                     // 缓存中最早的K线日期
+	LastDate        string    `gorm:"type:TEXT;not null" db:"last_date"`                                      // 缓存中最新的K线日期
+	BarCount        int       `gorm:"type:INTEGER;not null" db:"bar_count"`                                   // 缓存K线数
+	HistoryComplete bool      `gorm:"type:INTEGER;not null;default:0" db:"history_complete"`                  // 已缓存至上市首根K线，不会再有更早的数据
+	SyncedAt        time.Time `gorm:"not null" db:"synced_at"`                                                // 最近一次向数据源同步的时间
+}
+
+// KLineCacheBar 缓存的单根K线
+// 价格与成交量保留数据源原始文本，读取时与接口返回完全一致；缓存数据直接覆盖，不使用软删除
+type KLineCacheBar struct {
+	ID        uint   `gorm:"primaryKey"`
+	StockCode string `gorm:"type:TEXT;not null;uniqueIndex:idx_kline_bar,priority:1" db:"stock_code"`
+	Period    string `gorm:"type:TEXT;not null;uniqueIndex:idx_kline_bar,priority:2" db:"period"`
+	Adjust    string `gorm:"type:TEXT;not null;uniqueIndex:idx_kline_bar,priority:3" db:"adjust"`
+	Date      string `gorm:"type:TEXT;not null;uniqueIndex:idx_kline_bar,priority:4" db:"date"`
+	Open      string `gorm:"type:TEXT;not null" db:"open"`
+	Close     string `gorm:"type:TEXT;not null" db:"close"`
+	High      string `gorm:"type:TEXT;not null" db:"high"`
+	Low       string `gorm:"type:TEXT;not null" db:"low"`
+	Volume    string `gorm:"type:TEXT;not null" db:"volume"`
+}
+
+// ToKLineBar 转换为K线
+func (b *KLineCacheBar) ToKLineBar() KLineBar {
+	return KLineBar{Date: b.Date, Open: b.Open, Close: b.Close, High: b.High, Low: b.Low, Volume: b.Volume}
+}
@@ -0,0 +1,21 @@
+package model
+
+// MarketDataSource 行情数据源
+type MarketDataSource string
+
+const (
+	// MarketDataTencent 腾讯证券实时行情接口（默认）
+	MarketDataTencent MarketDataSource = "tencent"
+	// MarketDataLocal 本地录制的 JSON/CSV 行情文件，用于离线运行、测试与回放
+	MarketDataLocal MarketDataSource = "local"
+	// MarketDataReplay 历史回放数据源，按回放时刻截断行情，只能由回放流程注入，不可在配置中选择
+	MarketDataReplay MarketDataSource = "replay"
+)
+
+// MarketDataConfig 行情数据源配置
+type MarketDataConfig struct {
+	// Source 数据源，为空时使用 tencent
+	Source MarketDataSource `json:"source"`
+	// Dir 本地数据源的数据目录，为空时使用 ~/.msa/marketdata
+	Dir string `json:"dir,omitempty"`
+}
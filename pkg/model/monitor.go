@@ -0,0 +1,12 @@
+package model
+
+// MonitorConfig 盘中监控（msa monitor）配置
+// 时长使用 Go duration 格式，如 30s、1m、15m
+type MonitorConfig struct {
+	// Interval 检查间隔，为空时使用 1m
+	Interval string `json:"interval,omitempty"`
+	// Skill 有提醒触发时调用的 skill，为空时只记录触发
+	Skill string `json:"skill,omitempty"`
+	// SkillCooldown 两次调用 skill 的最小间隔，为空时使用 15m
+	SkillCooldown string `json:"skillCooldown,omitempty"`
+}
@@ -0,0 +1,31 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+// PortfolioSnapshot 账户每日净值快照
+// 收盘后记录，每个账户每个交易日一条，重复记录时覆盖当日快照
+type PortfolioSnapshot struct {
+	gorm.Model
+	AccountID       uint                       `gorm:"type:INTEGER;not null;uniqueIndex:idx_snapshot_account_date,priority:1" db:"account_id"`
+	TradeDate       string                     `gorm:"type:TEXT;not null;uniqueIndex:idx_snapshot_account_date,priority:2" db:"trade_date"` // 交易日（YYYY-MM-DD，交易所时区）
+	AvailableAmt    int64                      `gorm:"type:INTEGER;not null" db:"available_amt"`                                            // 可用金额（毫）
+	LockedAmt       int64                      `gorm:"type:INTEGER;not null" db:"locked_amt"`                                               // 锁定金额（毫）
+	PositionValue   int64                      `gorm:"type:INTEGER;not null" db:"position_value"`                                           // 持仓市值（毫）
+	NAV             int64                      `gorm:"column:nav;type:INTEGER;not null" db:"nav"`                                           // 净值（毫）= 可用 + 锁定 + 持仓市值
+	NetContribution int64                      `gorm:"type:INTEGER;not null" db:"net_contribution"`                                         // 净投入本金（毫），用于剔除入金出金对收益率的影响
+	Holdings        []PortfolioSnapshotHolding `gorm:"foreignKey:SnapshotID;constraint:OnDelete:CASCADE"`
+}
+
+// PortfolioSnapshotHolding 快照中的单只持仓
+type PortfolioSnapshotHolding struct {
+	gorm.Model
+	SnapshotID uint   `gorm:"type:INTEGER;not null;index" db:"snapshot_id"`
+	StockCode  string `gorm:"type:TEXT;not null" db:"stock_code"`
+	StockName  string `gorm:"type:TEXT;not null" db:"stock_name"`
+	Quantity   int64  `gorm:"type:INTEGER;not null" db:"quantity"` // 持仓数量
+	Price      int64  `gorm:"type:INTEGER;not null" db:"price"`    // 收盘价（毫）
+	Value      int64  `gorm:"type:INTEGER;not null" db:"value"`    // 持仓市值（毫）
+	Cost       int64  `gorm:"type:INTEGER;not null" db:"cost"`     // 持仓成本（毫）
+}
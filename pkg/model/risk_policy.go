@@ -0,0 +1,48 @@
+package model
+
+// RiskRule 风控规则，取值与 RiskPolicy 的配置字段名一致，记录在被拒绝订单的备注中
+type RiskRule string
+
+const (
+	// RiskRuleMaxPositionPct 单只股票仓位上限
+	RiskRuleMaxPositionPct RiskRule = "maxPositionPct"
+	// RiskRuleMaxIndustryPct 单一行业仓位上限
+	RiskRuleMaxIndustryPct RiskRule = "maxIndustryPct"
+	// RiskRuleMaxDailyTurnoverPct 当日成交额上限
+	RiskRuleMaxDailyTurnoverPct RiskRule = "maxDailyTurnoverPct"
+	// RiskRuleMaxOrdersPerDay 当日委托笔数上限
+	RiskRuleMaxOrdersPerDay RiskRule = "maxOrdersPerDay"
+	// RiskRuleBlacklist 禁止买入的股票
+	RiskRuleBlacklist RiskRule = "blacklist"
+	// RiskRuleMinCashReservePct 最低现金保留比例
+	RiskRuleMinCashReservePct RiskRule = "minCashReservePct"
+)
+
+// RiskPolicy 下单前风控硬限制，由交易服务在创建订单前强制校验
+// 卖出订单只受当日委托笔数与当日委托金额限制
+// 比例均为占总资产的百分数（20 表示 20%），零值表示不限制
+type RiskPolicy struct {
+	// MaxPositionPct 买入后单只股票市值占总资产上限
+	MaxPositionPct float64 `json:"maxPositionPct,omitempty"`
+	// MaxIndustryPct 买入后单一申万一级行业市值占总资产上限
+	MaxIndustryPct float64 `json:"maxIndustryPct,omitempty"`
+	// MaxDailyTurnoverPct 当日买卖委托金额合计（含本单）占总资产上限
+	MaxDailyTurnoverPct float64 `json:"maxDailyTurnoverPct,omitempty"`
+	// MaxOrdersPerDay 当日委托笔数上限（含本单，不含被拒绝的订单）
+	MaxOrdersPerDay int `json:"maxOrdersPerDay,omitempty"`
+	// Blacklist 禁止买入的股票代码（如 sh600000），卖出不受限制
+	Blacklist []string `json:"blacklist,omitempty"`
+	// MinCashReservePct 买入后可用现金占总资产下限
+	MinCashReservePct float64 `json:"minCashReservePct,omitempty"`
+}
+
+// IsEmpty 是否未设置任何限制
+func (p RiskPolicy) IsEmpty() bool {
+	return p.MaxPositionPct <= 0 && p.MaxIndustryPct <= 0 && p.MaxDailyTurnoverPct <= 0 &&
+		p.MaxOrdersPerDay <= 0 && len(p.Blacklist) == 0 && p.MinCashReservePct <= 0
+}
+
+// NeedsValuation 是否有需要按总资产计算的限制（需要持仓估值价格）
+func (p RiskPolicy) NeedsValuation() bool {
+	return p.MaxPositionPct > 0 || p.MaxIndustryPct > 0 || p.MaxDailyTurnoverPct > 0 || p.MinCashReservePct > 0
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ScheduleRunStatus 定时 skill 执行状态
+type ScheduleRunStatus string
+
+const (
+	// ScheduleRunStatusRunning 执行中（进程中断时保持该状态，当日不再执行）
+	ScheduleRunStatusRunning ScheduleRunStatus = "RUNNING"
+	// ScheduleRunStatusSuccess 执行完成
+	ScheduleRunStatusSuccess ScheduleRunStatus = "SUCCESS"
+	// ScheduleRunStatusFailed 执行失败
+	ScheduleRunStatusFailed ScheduleRunStatus = "FAILED"
+)
+
+// ScheduleRun 定时 skill 执行记录
+// 每个 skill 每个交易日一条，执行前先写入，重启后不会重复执行
+type ScheduleRun struct {
+	gorm.Model
+	SkillName  string            `gorm:"type:TEXT;not null;uniqueIndex:idx_schedule_run,priority:1" db:"skill_name"`
+	TradeDate  string            `gorm:"type:TEXT;not null;uniqueIndex:idx_schedule_run,priority:2" db:"trade_date"` // 交易日 YYYY-MM-DD
+	Window     string            `gorm:"type:TEXT;not null" db:"window"`                                             // 触发时间段
+	Status     ScheduleRunStatus `gorm:"type:TEXT;not null;default:'RUNNING'" db:"status"`
+	SessionID  string            `gorm:"type:TEXT" db:"session_id"` // 执行会话ID，可用 msa --resume 查看
+	StartedAt  time.Time         `gorm:"not null" db:"started_at"`
+	FinishedAt *time.Time        `db:"finished_at"`
+	Error      string            `gorm:"type:TEXT" db:"error"` // 失败原因
+}
+
+// ScheduleConfig 定时 skill（msa schedule）配置
+type ScheduleConfig struct {
+	// Skills 参与调度的 skill，为空时调度所有声明了触发时间段的 skill
+	Skills []string `json:"skills,omitempty"`
+	// Holidays 额外的休市日期（YYYY-MM-DD），补充内置交易日历
+	Holidays []string `json:"holidays,omitempty"`
+}
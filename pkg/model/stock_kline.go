@@ -1,5 +1,7 @@
 package model
 
+import "strings"
+
 // KLineBar 单根K线数据
 type KLineBar struct {
 	Date   string `json:"date"`
@@ -65,6 +67,38 @@ type IndustryPlate struct {
 	Level string `json:"level"`
 }
 
+// IndustryLevels 申万一级与二级行业分类，plate 没有 level 标记时按顺序取前两个分类；缺少时为 nil
+func (r *StockIndustryResp) IndustryLevels() (level1, level2 *IndustryPlate) {
+	plates := r.Data.Gsjj.Plate
+	for i := range plates {
+		switch plates[i].Level {
+		case "1":
+			if level1 == nil {
+				level1 = &plates[i]
+			}
+		case "2":
+			if level2 == nil {
+				level2 = &plates[i]
+			}
+		}
+	}
+	if level1 == nil && len(plates) > 0 {
+		level1 = &plates[0]
+	}
+	if level2 == nil && len(plates) > 1 && &plates[1] != level1 {
+		level2 = &plates[1]
+	}
+	return level1, level2
+}
+
+// BoardCode 行业板块代码（pt 前缀），可用于查询板块成分股与板块K线
+func (p *IndustryPlate) BoardCode() string {
+	if p.ID == "" || strings.HasPrefix(p.ID, "pt") {
+		return p.ID
+	}
+	return "pt" + p.ID
+}
+
 // BoardRankResp mktHs/rank API 响应
 type BoardRankResp struct {
 	Code int         `json:"code"`
@@ -88,3 +122,18 @@ type BoardItem struct {
 	BdZdf5  string `json:"bd_zdf5"`
 	BdZdf20 string `json:"bd_zdf20"`
 }
+
+// BoardStock 板块成分股
+type BoardStock struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+	Zxj  string `json:"zxj"` // 最新价
+	Zdf  string `json:"zdf"` // 涨跌幅（%）
+}
+
+// BoardStocksResp mktHs/rank 板块成分股响应
+type BoardStocksResp struct {
+	Code int          `json:"code"`
+	Msg  string       `json:"msg"`
+	Data []BoardStock `json:"data"`
+}
@@ -1,5 +1,12 @@
 package model
 
+import (
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
 // StockMinuteBar 单根分钟K线数据
 type StockMinuteBar struct {
 	Time     string  `json:"time"`     // 时间, 格式 HHmm (如 "0930")
@@ -15,3 +22,54 @@ type StockMinuteKResp struct {
 	Bars      []StockMinuteBar `json:"bars"`  // 分钟K线数组
 	Count     int              `json:"count"` // 数据条数
 }
+
+// ParseMinuteBars 解析行情分时原始数据（"HHmm 价格 累计成交量 累计成交额"）为分钟K线
+// 累计值差分得到每分钟实际值
+func ParseMinuteBars(stockCode, date string, data []string) *StockMinuteKResp {
+	result := &StockMinuteKResp{
+		StockCode: stockCode,
+		Date:      date,
+	}
+
+	bars := make([]StockMinuteBar, 0, len(data))
+	var prevVolume float64
+	var prevTurnover float64
+
+	for _, raw := range data {
+		parts := strings.Split(strings.TrimSpace(raw), " ")
+		if len(parts) < 4 {
+			log.Warnf("get_stock_minute_k: unexpected data format: %s", raw)
+			continue
+		}
+
+		timeStr := parts[0]
+		price, err1 := strconv.ParseFloat(parts[1], 64)
+		cumVolume, err2 := strconv.ParseFloat(parts[2], 64)
+		cumTurnover, err3 := strconv.ParseFloat(parts[3], 64)
+		if err1 != nil || err2 != nil || err3 != nil {
+			log.Warnf("get_stock_minute_k: parse error for %s: %v %v %v", raw, err1, err2, err3)
+			continue
+		}
+
+		volume := cumVolume
+		turnover := cumTurnover
+		if len(bars) > 0 {
+			volume = cumVolume - prevVolume
+			turnover = cumTurnover - prevTurnover
+		}
+
+		bars = append(bars, StockMinuteBar{
+			Time:     timeStr,
+			Price:    price,
+			Volume:   int64(volume),
+			Turnover: turnover,
+		})
+
+		prevVolume = cumVolume
+		prevTurnover = cumTurnover
+	}
+
+	result.Bars = bars
+	result.Count = len(bars)
+	return result
+}
@@ -0,0 +1,111 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// QuoteLevel 盘口档位
+type QuoteLevel struct {
+	Price  string `json:"price"`  // 价格
+	Volume string `json:"volume"` // 挂单量（手）
+}
+
+// StockQuote 实时行情快照
+type StockQuote struct {
+	StockCode     string       `json:"stock_code"`
+	StockName     string       `json:"stock_name"`
+	CurrentPrice  string       `json:"current_price"`             // 最新价
+	PrevClose     string       `json:"prev_close"`                // 昨收
+	OpenPrice     string       `json:"open_price"`                // 今开
+	HighPrice     string       `json:"high_price"`                // 最高
+	LowPrice      string       `json:"low_price"`                 // 最低
+	Change        string       `json:"change"`                    // 涨跌额
+	ChangePercent string       `json:"change_percent"`            // 涨跌幅（%）
+	Volume        string       `json:"volume"`                    // 成交量（手）
+	Turnover      string       `json:"turnover"`                  // 成交额（万元）
+	TurnoverRate  string       `json:"turnover_rate,omitempty"`   // 换手率（%）
+	PERatio       string       `json:"pe_ratio,omitempty"`        // 市盈率
+	Amplitude     string       `json:"amplitude,omitempty"`       // 振幅（%）
+	FloatCap      string       `json:"float_cap,omitempty"`       // 流通市值（亿元）
+	MarketCap     string       `json:"market_cap,omitempty"`      // 总市值（亿元）
+	PBRatio       string       `json:"pb_ratio,omitempty"`        // 市净率
+	LimitUp       string       `json:"limit_up,omitempty"`        // 涨停价
+	LimitDown     string       `json:"limit_down,omitempty"`      // 跌停价
+	WeekHighIn52  string       `json:"week_high_in_52,omitempty"` // 52周最高价
+	WeekLowIn52   string       `json:"week_low_in_52,omitempty"`  // 52周最低价
+	Bids          []QuoteLevel `json:"bids,omitempty"`            // 买一至买五
+	Asks          []QuoteLevel `json:"asks,omitempty"`            // 卖一至卖五
+	Time          string       `json:"time,omitempty"`            // 行情时间 YYYYMMDDHHmmss
+}
+
+// ParseStockQuote 解析行情接口 "~" 分隔的字段数组
+// 字段位置与 minute/query 接口的 qt 数组一致：3 最新价、4 昨收、5 今开、6 成交量、9-18 买盘、19-28 卖盘、
+// 30 时间、31 涨跌、32 涨跌幅、33 最高、34 最低、37 成交额、38 换手率、39 市盈率、43 振幅、44/45 流通/总市值、46 市净率、47 涨停、48 跌停、67/68 52周高低
+func ParseStockQuote(stockCode string, fields []string) (*StockQuote, error) {
+	if len(fields) < 35 {
+		return nil, fmt.Errorf("行情字段不足: %s, %d", stockCode, len(fields))
+	}
+
+	field := func(i int) string {
+		if i < len(fields) {
+			return fields[i]
+		}
+		return ""
+	}
+
+	quote := &StockQuote{
+		StockCode:     stockCode,
+		StockName:     field(1),
+		CurrentPrice:  field(3),
+		PrevClose:     field(4),
+		OpenPrice:     field(5),
+		Volume:        field(6),
+		Time:          field(30),
+		Change:        field(31),
+		ChangePercent: field(32),
+		HighPrice:     field(33),
+		LowPrice:      field(34),
+		Turnover:      field(37),
+		TurnoverRate:  field(38),
+		PERatio:       field(39),
+		Amplitude:     field(43),
+		FloatCap:      field(44),
+		MarketCap:     field(45),
+		PBRatio:       field(46),
+		LimitUp:       field(47),
+		LimitDown:     field(48),
+		WeekHighIn52:  field(67),
+		WeekLowIn52:   field(68),
+	}
+	for i := 0; i < 5; i++ {
+		quote.Bids = append(quote.Bids, QuoteLevel{Price: field(9 + 2*i), Volume: field(10 + 2*i)})
+		quote.Asks = append(quote.Asks, QuoteLevel{Price: field(19 + 2*i), Volume: field(20 + 2*i)})
+	}
+	return quote, nil
+}
+
+// ToStockQuote 由单只股票行情转换为行情快照（缺少盘口与涨跌停价）
+func (r *StockCurrentResp) ToStockQuote(stockCode string) *StockQuote {
+	quote := &StockQuote{
+		StockCode:    stockCode,
+		CurrentPrice: r.CurrentPrice,
+		PrevClose:    r.PrevClose,
+		OpenPrice:    r.CurrentStartPrice,
+		HighPrice:    r.CurrentMaxPrice,
+		LowPrice:     r.CurrentMinPrice,
+		Volume:       r.VolumeByLot,
+		PERatio:      r.PERatio,
+		Amplitude:    r.Amplitude,
+		WeekHighIn52: r.WeekHighIn52,
+		WeekLowIn52:  r.WeekLowIn52,
+	}
+
+	price, err1 := strconv.ParseFloat(r.CurrentPrice, 64)
+	prevClose, err2 := strconv.ParseFloat(r.PrevClose, 64)
+	if err1 == nil && err2 == nil && prevClose > 0 {
+		quote.Change = strconv.FormatFloat(price-prevClose, 'f', 2, 64)
+		quote.ChangePercent = strconv.FormatFloat((price-prevClose)/prevClose*100, 'f', 2, 64)
+	}
+	return quote
+}
@@ -34,20 +34,43 @@ const (
 // 所有金额字段以毫为单位存储
 type Transaction struct {
 	gorm.Model
-	AccountID uint              `gorm:"type:INTEGER;not null;index:idx_account_stock,priority:1;index" db:"account_id"`
-	ParentID  *uint             `gorm:"type:INTEGER;index" db:"parent_id"` // 部分成交时关联原记录
-	StockCode string            `gorm:"type:TEXT;not null;index:idx_account_stock,priority:2" db:"stock_code"`
-	StockName string            `gorm:"type:TEXT;not null" db:"stock_name"`
-	Type      TransactionType   `gorm:"type:TEXT;not null;index" db:"type"`
-	Quantity  int64             `gorm:"type:INTEGER;not null" db:"quantity"`      // 交易数量
-	Price     int64             `gorm:"type:INTEGER;not null" db:"price"`         // 交易价格（毫）
-	Amount    int64             `gorm:"type:INTEGER;not null" db:"amount"`        // 交易金额（毫）= quantity × price
-	Fee       int64             `gorm:"type:INTEGER;not null;default:0" db:"fee"` // 手续费（毫）
-	Status    TransactionStatus `gorm:"type:TEXT;not null;index;default:'PENDING'" db:"status"`
-	Note      string            `gorm:"type:TEXT" db:"note"` // 备注
+	AccountID   uint              `gorm:"type:INTEGER;not null;index:idx_account_stock,priority:1;index" db:"account_id"`
+	ParentID    *uint             `gorm:"type:INTEGER;index" db:"parent_id"` // 部分成交时关联原记录
+	StockCode   string            `gorm:"type:TEXT;not null;index:idx_account_stock,priority:2" db:"stock_code"`
+	StockName   string            `gorm:"type:TEXT;not null" db:"stock_name"`
+	Type        TransactionType   `gorm:"type:TEXT;not null;index" db:"type"`
+	Quantity    int64             `gorm:"type:INTEGER;not null" db:"quantity"`               // 交易数量
+	Price       int64             `gorm:"type:INTEGER;not null" db:"price"`                  // 交易价格（毫）
+	Amount      int64             `gorm:"type:INTEGER;not null" db:"amount"`                 // 交易金额（毫）= quantity × price
+	Fee         int64             `gorm:"type:INTEGER;not null;default:0" db:"fee"`          // 手续费合计（毫）
+	Commission  int64             `gorm:"type:INTEGER;not null;default:0" db:"commission"`   // 佣金
+	StampDuty   int64             `gorm:"type:INTEGER;not null;default:0" db:"stamp_duty"`   // 印花税
+	TransferFee int64             `gorm:"type:INTEGER;not null;default:0" db:"transfer_fee"` // 过户费
+	Levy        int64             `gorm:"type:INTEGER;not null;default:0" db:"levy"`         // 港股交易征费、交易费等
+	Status      TransactionStatus `gorm:"type:TEXT;not null;index;default:'PENDING'" db:"status"`
+	Note        string            `gorm:"type:TEXT" db:"note"` // 备注
 }
 
 // GetTotalAmount 获取总金额（交易金额 + 手续费）
 func (t *Transaction) GetTotalAmount() int64 {
 	return t.Amount + t.Fee
 }
+
+// GetFeeBreakdown 获取手续费明细
+func (t *Transaction) GetFeeBreakdown() FeeBreakdown {
+	return FeeBreakdown{
+		Commission:  t.Commission,
+		StampDuty:   t.StampDuty,
+		TransferFee: t.TransferFee,
+		Levy:        t.Levy,
+	}
+}
+
+// SetFeeBreakdown 设置手续费明细，并同步手续费合计
+func (t *Transaction) SetFeeBreakdown(fees FeeBreakdown) {
+	t.Commission = fees.Commission
+	t.StampDuty = fees.StampDuty
+	t.TransferFee = fees.TransferFee
+	t.Levy = fees.Levy
+	t.Fee = fees.Total()
+}
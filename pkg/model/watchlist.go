@@ -0,0 +1,91 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Watchlist 自选股模型
+// 每只股票一条记录，目标价以毫为单位，0 表示未设置
+type Watchlist struct {
+	gorm.Model
+	StockCode       string `gorm:"type:TEXT;not null;uniqueIndex" db:"stock_code"`
+	StockName       string `gorm:"type:TEXT;not null" db:"stock_name"`
+	Notes           string `gorm:"type:TEXT" db:"notes"`                                   // 关注理由等备注
+	Tags            string `gorm:"type:TEXT" db:"tags"`                                    // 标签，逗号分隔
+	TargetBuyPrice  int64  `gorm:"type:INTEGER;not null;default:0" db:"target_buy_price"`  // 目标买入价（毫）
+	TargetSellPrice int64  `gorm:"type:INTEGER;not null;default:0" db:"target_sell_price"` // 目标卖出价（毫）
+	AddedPrice      int64  `gorm:"type:INTEGER;not null;default:0" db:"added_price"`       // 加入时价格（毫）
+}
+
+// TagList 获取标签列表
+func (w *Watchlist) TagList() []string {
+	return SplitTags(w.Tags)
+}
+
+// SplitTags 拆分逗号分隔的标签，去除空白与重复
+func SplitTags(tags string) []string {
+	var list []string
+	seen := map[string]bool{}
+	for _, tag := range strings.FieldsFunc(tags, func(r rune) bool { return r == ',' || r == '，' }) {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		list = append(list, tag)
+	}
+	return list
+}
+
+// WatchAlertType 自选股提醒规则类型
+type WatchAlertType string
+
+const (
+	// WatchAlertTypePriceAbove 价格向上穿越目标价
+	WatchAlertTypePriceAbove WatchAlertType = "PRICE_ABOVE"
+	// WatchAlertTypePriceBelow 价格向下穿越目标价
+	WatchAlertTypePriceBelow WatchAlertType = "PRICE_BELOW"
+	// WatchAlertTypeChangePct 当日涨跌幅绝对值达到阈值（%）
+	WatchAlertTypeChangePct WatchAlertType = "CHANGE_PCT"
+	// WatchAlertTypeVolumeSpike 当日成交量达到近 5 日均量的倍数
+	WatchAlertTypeVolumeSpike WatchAlertType = "VOLUME_SPIKE"
+)
+
+// WatchAlertStatus 提醒规则状态
+type WatchAlertStatus string
+
+const (
+	// WatchAlertStatusActive 监控中
+	WatchAlertStatusActive WatchAlertStatus = "ACTIVE"
+	// WatchAlertStatusTriggered 已触发（一次性规则）
+	WatchAlertStatusTriggered WatchAlertStatus = "TRIGGERED"
+)
+
+// 提醒规则来源
+const (
+	// WatchAlertOriginTargetBuy 由目标买入价生成
+	WatchAlertOriginTargetBuy = "TARGET_BUY"
+	// WatchAlertOriginTargetSell 由目标卖出价生成
+	WatchAlertOriginTargetSell = "TARGET_SELL"
+)
+
+// WatchAlert 自选股提醒规则
+// 价格穿越按上次观察价判断，涨跌幅与放量规则每个交易日最多触发一次
+type WatchAlert struct {
+	gorm.Model
+	StockCode    string           `gorm:"type:TEXT;not null;index" db:"stock_code"`
+	Type         WatchAlertType   `gorm:"type:TEXT;not null" db:"type"`
+	Status       WatchAlertStatus `gorm:"type:TEXT;not null;index;default:'ACTIVE'" db:"status"`
+	Price        int64            `gorm:"type:INTEGER;not null;default:0" db:"price"`      // 价格穿越规则的目标价（毫）
+	Value        float64          `gorm:"type:REAL;not null;default:0" db:"value"`         // 涨跌幅阈值（%）或放量倍数
+	Repeat       bool             `gorm:"not null;default:false" db:"repeat"`              // 价格穿越规则触发后是否继续监控
+	Origin       string           `gorm:"type:TEXT" db:"origin"`                           // 规则来源，目标价生成的规则随目标价更新
+	LastPrice    int64            `gorm:"type:INTEGER;not null;default:0" db:"last_price"` // 上次观察价（毫），用于判断穿越
+	CheckedAt    *time.Time       `db:"checked_at"`                                        // 最近一次评估时间
+	TriggeredAt  *time.Time       `db:"triggered_at"`                                      // 最近一次触发时间
+	TriggerCount int              `gorm:"type:INTEGER;not null;default:0" db:"trigger_count"`
+	Note         string           `gorm:"type:TEXT" db:"note"` // 备注
+}
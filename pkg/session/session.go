@@ -12,6 +12,10 @@ const (
 	ModeTUI Mode = "tui"
 	// ModeCLI CLI 模式
 	ModeCLI Mode = "cli"
+	// ModeSchedule 定时 skill 模式
+	ModeSchedule Mode = "schedule"
+	// ModeReplay 历史回放模式
+	ModeReplay Mode = "replay"
 )
 
 // Session 会话信息
@@ -397,6 +397,11 @@ func (c *Chat) View() string {
 // handleEvent handles pipeline events from the Runner.
 func (c *Chat) handleEvent(e event.Event) (tea.Model, tea.Cmd) {
 	switch e.Type {
+	case event.EventAlert:
+		// 后台提醒不属于对话流，不读取下一个流式片段
+		c.addMessage(model.RoleSystem, "🔔 "+e.Text, model.StreamMsgTypeText, "")
+		return c, c.Flush()
+
 	case event.EventError:
 		log.Errorf("事件错误: %v", e.Err)
 		c.clearStreamState()